@@ -12,6 +12,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/xataio/pgstream/internal/health"
 	"github.com/xataio/pgstream/pkg/otel"
+	pgsnapshotgenerator "github.com/xataio/pgstream/pkg/snapshot/generator/postgres/data"
 	"github.com/xataio/pgstream/pkg/stream"
 	"github.com/xataio/pgstream/pkg/wal/processor/batch"
 	"github.com/xataio/pgstream/pkg/wal/processor/transformer"
@@ -160,6 +161,28 @@ func ParseTransformerConfig(filename string) (*transformer.Config, error) {
 	return yamlConfig.Transformations.parseTransformationConfig()
 }
 
+// parseSubsetConfig parses the snapshot subset rules from the yaml file on
+// input.
+func parseSubsetConfig(filename string) (*pgsnapshotgenerator.SubsetConfig, error) {
+	if filename == "" {
+		return nil, nil
+	}
+
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	yamlConfig := struct {
+		Subset SnapshotSubsetConfig `mapstructure:"subset" yaml:"subset"`
+	}{}
+	if err := yaml.Unmarshal(buf, &yamlConfig); err != nil {
+		return nil, fmt.Errorf("invalid format for subset config in file %q: %w", filename, err)
+	}
+
+	return yamlConfig.Subset.parseSubsetConfig(), nil
+}
+
 func applyPostgresBulkBatchDefaults(batchCfg *batch.Config) {
 	if batchCfg.MaxBatchSize == 0 {
 		batchCfg.MaxBatchSize = defaultPostgresBulkBatchSize
@@ -4,6 +4,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/viper"
 	"github.com/xataio/pgstream/internal/health"
@@ -18,12 +19,15 @@ import (
 	"github.com/xataio/pgstream/pkg/wal/listener/snapshot/adapter"
 	snapshotbuilder "github.com/xataio/pgstream/pkg/wal/listener/snapshot/builder"
 	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+	filewriter "github.com/xataio/pgstream/pkg/wal/processor/file"
 	"github.com/xataio/pgstream/pkg/wal/processor/filter"
+	"github.com/xataio/pgstream/pkg/wal/processor/incremental"
 	"github.com/xataio/pgstream/pkg/wal/processor/injector"
 	kafkaprocessor "github.com/xataio/pgstream/pkg/wal/processor/kafka"
 	"github.com/xataio/pgstream/pkg/wal/processor/postgres"
 	"github.com/xataio/pgstream/pkg/wal/processor/search"
 	"github.com/xataio/pgstream/pkg/wal/processor/search/store"
+	"github.com/xataio/pgstream/pkg/wal/processor/toast"
 	"github.com/xataio/pgstream/pkg/wal/processor/transformer"
 	"github.com/xataio/pgstream/pkg/wal/processor/webhook/notifier"
 	"github.com/xataio/pgstream/pkg/wal/processor/webhook/subscription/server"
@@ -47,7 +51,9 @@ func init() {
 	viper.BindEnv("PGSTREAM_POSTGRES_LISTENER_BACKOFF_MAX_RETRIES")
 	viper.BindEnv("PGSTREAM_POSTGRES_LISTENER_DISABLE_RETRIES")
 	viper.BindEnv("PGSTREAM_POSTGRES_REPLICATION_SLOT_NAME")
+	viper.BindEnv("PGSTREAM_POSTGRES_PUBLISH_VIA_PARTITION_ROOT")
 	viper.BindEnv("PGSTREAM_POSTGRES_REPLICATION_PLUGIN_INCLUDE_XIDS")
+	viper.BindEnv("PGSTREAM_POSTGRES_REPLICATION_PLUGIN_FILTER_ORIGINS")
 
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_BATCH_BYTES")
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_SCHEMA_WORKERS")
@@ -58,6 +64,7 @@ func init() {
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_MAX_CONNECTIONS")
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_STORE_URL")
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_STORE_REPEATABLE")
+	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_STORE_RETRY_FAILED")
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_MODE")
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_INCLUDE_GLOBAL_DB_OBJECTS")
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_ROLE")
@@ -68,7 +75,19 @@ func init() {
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_NO_OWNER")
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_NO_PRIVILEGES")
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_EXCLUDED_SECURITY_LABELS")
+	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_NATIVE_DUMP")
+	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_INDEX_BUILD_WORKERS")
 	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_DISABLE_PROGRESS_TRACKING")
+	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_ROWS_PER_SECOND")
+	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_BYTES_PER_SECOND")
+	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_MAX_REPLICATION_LAG")
+	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_MAX_ACTIVE_CONNECTIONS")
+	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_PROBE_QUERY")
+	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_PROBE_THRESHOLD")
+	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_PROBE_INTERVAL")
+	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_SUBSET_RULES_FILE")
+	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_READER")
+	viper.BindEnv("PGSTREAM_POSTGRES_SNAPSHOT_TABLE_READERS")
 
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_TARGET_URL")
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_BATCH_TIMEOUT")
@@ -83,13 +102,45 @@ func init() {
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_DISABLE_TRIGGERS")
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_ON_CONFLICT_ACTION")
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_BULK_INGEST_ENABLED")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_BULK_INGEST_MODE")
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_EXP_BACKOFF_INITIAL_INTERVAL")
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_EXP_BACKOFF_MAX_INTERVAL")
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_EXP_BACKOFF_MAX_RETRIES")
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_BACKOFF_INTERVAL")
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_BACKOFF_MAX_RETRIES")
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_DISABLE_RETRIES")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_CONCURRENT_DDL")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_TABLE_ROUTING")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_TABLE_CONFLICT_POLICIES")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_APPLY_MODE")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_REPLICATION_ORIGIN")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_CONFLICT_RESOLUTION")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_APPLY_WORKERS")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_EXACTLY_ONCE")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_APPLY_PROGRESS_NAME")
 	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_IGNORE_DDL")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_SEQUENCE_SYNC_ENABLED")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_SEQUENCE_SYNC_SOURCE_URL")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_SEQUENCE_SYNC_INTERVAL")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_SEQUENCE_SYNC_MARGIN")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_ROLE_SYNC_ENABLED")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_ROLE_SYNC_SOURCE_URL")
+	viper.BindEnv("PGSTREAM_POSTGRES_WRITER_ROLE_SYNC_INTERVAL")
+
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_TARGET_URL")
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_TABLE")
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_BATCH_TIMEOUT")
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_BATCH_BYTES")
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_BATCH_SIZE")
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_MAX_QUEUE_BYTES")
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_MONTHLY_PARTITIONING")
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_RETENTION")
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_EXP_BACKOFF_INITIAL_INTERVAL")
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_EXP_BACKOFF_MAX_INTERVAL")
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_EXP_BACKOFF_MAX_RETRIES")
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_BACKOFF_INTERVAL")
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_BACKOFF_MAX_RETRIES")
+	viper.BindEnv("PGSTREAM_AUDIT_WRITER_DISABLE_RETRIES")
 
 	viper.BindEnv("PGSTREAM_KAFKA_READER_SERVERS")
 	viper.BindEnv("PGSTREAM_KAFKA_WRITER_SERVERS")
@@ -142,12 +193,21 @@ func init() {
 	viper.BindEnv("PGSTREAM_WEBHOOK_SUBSCRIPTION_SERVER_WRITE_TIMEOUT")
 
 	viper.BindEnv("PGSTREAM_STDOUT_WRITER_ENABLED")
+	viper.BindEnv("PGSTREAM_FILE_WRITER_DIR")
+	viper.BindEnv("PGSTREAM_FILE_WRITER_FORMAT")
 
 	viper.BindEnv("PGSTREAM_INJECTOR_STORE_POSTGRES_URL")
 	viper.BindEnv("PGSTREAM_TRANSFORMER_RULES_FILE")
 	viper.BindEnv("PGSTREAM_FILTER_INCLUDE_TABLES")
 	viper.BindEnv("PGSTREAM_FILTER_EXCLUDE_TABLES")
 	viper.BindEnv("PGSTREAM_PROCESSOR_SANITIZE_STRIP_NULL_CHAR_BYTES")
+	viper.BindEnv("PGSTREAM_UNCHANGED_TOAST_ENABLED")
+	viper.BindEnv("PGSTREAM_UNCHANGED_TOAST_FETCH_UNCHANGED")
+	viper.BindEnv("PGSTREAM_UNCHANGED_TOAST_SOURCE_URL")
+	viper.BindEnv("PGSTREAM_INCREMENTAL_SNAPSHOT_ENABLED")
+	viper.BindEnv("PGSTREAM_INCREMENTAL_SNAPSHOT_CHUNK_SIZE")
+	viper.BindEnv("PGSTREAM_INCREMENTAL_SNAPSHOT_SOURCE_URL")
+	viper.BindEnv("PGSTREAM_INCREMENTAL_SNAPSHOT_AUTO_NEW_TABLES")
 
 	viper.BindEnv("PGSTREAM_KAFKA_TLS_ENABLED")
 	viper.BindEnv("PGSTREAM_KAFKA_TLS_CA_CERT_FILE")
@@ -232,12 +292,14 @@ func parsePostgresListenerConfig() (*stream.PostgresListenerConfig, error) {
 			PostgresURL:         pgURL,
 			ReplicationSlotName: viper.GetString("PGSTREAM_POSTGRES_REPLICATION_SLOT_NAME"),
 			PluginArguments: pgreplication.PluginArguments{
-				IncludeXIDs:  viper.GetBool("PGSTREAM_POSTGRES_REPLICATION_PLUGIN_INCLUDE_XIDS"),
-				AddTables:    viper.GetString("PGSTREAM_POSTGRES_REPLICATION_PLUGIN_ADD_TABLES"),
-				FilterTables: viper.GetString("PGSTREAM_POSTGRES_REPLICATION_PLUGIN_FILTER_TABLES"),
+				IncludeXIDs:   viper.GetBool("PGSTREAM_POSTGRES_REPLICATION_PLUGIN_INCLUDE_XIDS"),
+				AddTables:     viper.GetString("PGSTREAM_POSTGRES_REPLICATION_PLUGIN_ADD_TABLES"),
+				FilterTables:  viper.GetString("PGSTREAM_POSTGRES_REPLICATION_PLUGIN_FILTER_TABLES"),
+				FilterOrigins: viper.GetString("PGSTREAM_POSTGRES_REPLICATION_PLUGIN_FILTER_ORIGINS"),
 			},
 		},
-		RetryPolicy: parseBackoffConfig("PGSTREAM_POSTGRES_LISTENER"),
+		RetryPolicy:             parseBackoffConfig("PGSTREAM_POSTGRES_LISTENER"),
+		PublishViaPartitionRoot: viper.GetBool("PGSTREAM_POSTGRES_PUBLISH_VIA_PARTITION_ROOT"),
 	}
 
 	if filterConfig := parseFilterConfig(); filterConfig != nil {
@@ -295,6 +357,22 @@ func parseSnapshotConfig(pgURL string) (*snapshotbuilder.SnapshotListenerConfig,
 			SnapshotWorkers: viper.GetUint("PGSTREAM_POSTGRES_SNAPSHOT_WORKERS"),
 			MaxConnections:  viper.GetUint("PGSTREAM_POSTGRES_SNAPSHOT_MAX_CONNECTIONS"),
 		}
+		dataSnapshotCfg.Throttle, err = parseSnapshotThrottleConfig()
+		if err != nil {
+			return nil, err
+		}
+		dataSnapshotCfg.Subset, err = parseSubsetConfig(viper.GetString("PGSTREAM_POSTGRES_SNAPSHOT_SUBSET_RULES_FILE"))
+		if err != nil {
+			return nil, err
+		}
+		dataSnapshotCfg.Reader, err = parseSnapshotReader(viper.GetString("PGSTREAM_POSTGRES_SNAPSHOT_READER"))
+		if err != nil {
+			return nil, err
+		}
+		dataSnapshotCfg.TableReaders, err = parseSnapshotTableReaders(viper.GetStringSlice("PGSTREAM_POSTGRES_SNAPSHOT_TABLE_READERS"))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	cfg := &snapshotbuilder.SnapshotListenerConfig{
@@ -305,11 +383,13 @@ func parseSnapshotConfig(pgURL string) (*snapshotbuilder.SnapshotListenerConfig,
 			ExcludedTables: viper.GetStringSlice("PGSTREAM_POSTGRES_SNAPSHOT_EXCLUDED_TABLES"),
 		},
 		DisableProgressTracking: viper.GetBool("PGSTREAM_POSTGRES_SNAPSHOT_DISABLE_PROGRESS_TRACKING"),
+		PublishViaPartitionRoot: viper.GetBool("PGSTREAM_POSTGRES_PUBLISH_VIA_PARTITION_ROOT"),
 	}
 
 	if storeURL := viper.GetString("PGSTREAM_POSTGRES_SNAPSHOT_STORE_URL"); storeURL != "" {
 		cfg.Recorder = &snapshotbuilder.SnapshotRecorderConfig{
 			RepeatableSnapshots: viper.GetBool("PGSTREAM_POSTGRES_SNAPSHOT_STORE_REPEATABLE"),
+			RetryFailed:         viper.GetBool("PGSTREAM_POSTGRES_SNAPSHOT_STORE_RETRY_FAILED"),
 			SnapshotStoreURL:    storeURL,
 			SnapshotWorkers:     viper.GetUint("PGSTREAM_POSTGRES_SNAPSHOT_WORKERS"),
 		}
@@ -318,6 +398,65 @@ func parseSnapshotConfig(pgURL string) (*snapshotbuilder.SnapshotListenerConfig,
 	return cfg, nil
 }
 
+func parseSnapshotThrottleConfig() (*pgsnapshotgenerator.ThrottleConfig, error) {
+	bytesPerSecond, err := getByteSize("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_BYTES_PER_SECOND")
+	if err != nil {
+		return nil, err
+	}
+	cfg := &pgsnapshotgenerator.ThrottleConfig{
+		RowsPerSecond:        viper.GetUint("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_ROWS_PER_SECOND"),
+		BytesPerSecond:       uint64(bytesPerSecond),
+		MaxReplicationLag:    viper.GetDuration("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_MAX_REPLICATION_LAG"),
+		MaxActiveConnections: viper.GetUint("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_MAX_ACTIVE_CONNECTIONS"),
+		ProbeQuery:           viper.GetString("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_PROBE_QUERY"),
+		ProbeThreshold:       viper.GetFloat64("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_PROBE_THRESHOLD"),
+		ProbeInterval:        viper.GetDuration("PGSTREAM_POSTGRES_SNAPSHOT_THROTTLE_PROBE_INTERVAL"),
+	}
+	if *cfg == (pgsnapshotgenerator.ThrottleConfig{}) {
+		return nil, nil
+	}
+	return cfg, nil
+}
+
+// parseSnapshotTableReaders parses a list of table readers in the format
+// "table=key:value;key:value", where the keys are reader, index and columns.
+// Column lists are comma separated.
+func parseSnapshotTableReaders(tableReaders []string) (map[string]pgsnapshotgenerator.TableReaderConfig, error) {
+	if len(tableReaders) == 0 {
+		return nil, nil
+	}
+	readers := make(map[string]pgsnapshotgenerator.TableReaderConfig, len(tableReaders))
+	for _, tr := range tableReaders {
+		table, settings, found := strings.Cut(tr, "=")
+		if !found || table == "" || settings == "" {
+			return nil, fmt.Errorf("%w: %q", errInvalidSnapshotTableReaderFormat, tr)
+		}
+		readerCfg := pgsnapshotgenerator.TableReaderConfig{}
+		for _, setting := range strings.Split(settings, ";") {
+			key, value, found := strings.Cut(setting, ":")
+			if !found || value == "" {
+				return nil, fmt.Errorf("%w: %q", errInvalidSnapshotTableReaderFormat, tr)
+			}
+			switch key {
+			case "reader":
+				reader, err := parseSnapshotReader(value)
+				if err != nil {
+					return nil, err
+				}
+				readerCfg.Reader = reader
+			case "index":
+				readerCfg.Index = value
+			case "columns":
+				readerCfg.Columns = strings.Split(value, ",")
+			default:
+				return nil, fmt.Errorf("%w: unknown key %q in %q", errInvalidSnapshotTableReaderFormat, key, tr)
+			}
+		}
+		readers[table] = readerCfg
+	}
+	return readers, nil
+}
+
 func parseSchemaSnapshotConfig(pgurl string) (*snapshotbuilder.SchemaSnapshotConfig, error) {
 	pgTargetURL := viper.GetString("PGSTREAM_POSTGRES_WRITER_TARGET_URL")
 
@@ -325,10 +464,15 @@ func parseSchemaSnapshotConfig(pgurl string) (*snapshotbuilder.SchemaSnapshotCon
 	if err != nil {
 		return nil, err
 	}
+	tableRoutes, err := parseTableRoutes(viper.GetStringSlice("PGSTREAM_POSTGRES_WRITER_TABLE_ROUTING"))
+	if err != nil {
+		return nil, err
+	}
 	return &snapshotbuilder.SchemaSnapshotConfig{
 		DumpRestore: &pgdumprestore.Config{
 			SourcePGURL:            pgurl,
 			TargetPGURL:            pgTargetURL,
+			TargetDir:              viper.GetString("PGSTREAM_FILE_WRITER_DIR"),
 			CleanTargetDB:          viper.GetBool("PGSTREAM_POSTGRES_SNAPSHOT_CLEAN_TARGET_DB"),
 			CreateTargetDB:         viper.GetBool("PGSTREAM_POSTGRES_SNAPSHOT_CREATE_TARGET_DB"),
 			IncludeGlobalDBObjects: viper.GetBool("PGSTREAM_POSTGRES_SNAPSHOT_INCLUDE_GLOBAL_DB_OBJECTS"),
@@ -338,6 +482,9 @@ func parseSchemaSnapshotConfig(pgurl string) (*snapshotbuilder.SchemaSnapshotCon
 			NoOwner:                viper.GetBool("PGSTREAM_POSTGRES_SNAPSHOT_NO_OWNER"),
 			NoPrivileges:           viper.GetBool("PGSTREAM_POSTGRES_SNAPSHOT_NO_PRIVILEGES"),
 			ExcludedSecurityLabels: viper.GetStringSlice("PGSTREAM_POSTGRES_SNAPSHOT_EXCLUDED_SECURITY_LABELS"),
+			NativeDump:             viper.GetBool("PGSTREAM_POSTGRES_SNAPSHOT_NATIVE_DUMP"),
+			IndexBuildWorkers:      viper.GetUint("PGSTREAM_POSTGRES_SNAPSHOT_INDEX_BUILD_WORKERS"),
+			TableRoutes:            tableRoutes,
 		},
 	}, nil
 }
@@ -404,16 +551,25 @@ func parseProcessorConfig() (stream.ProcessorConfig, error) {
 		return stream.ProcessorConfig{}, err
 	}
 
+	auditCfg, err := parseAuditProcessorConfig()
+	if err != nil {
+		return stream.ProcessorConfig{}, err
+	}
+
 	return stream.ProcessorConfig{
-		Kafka:       kafkaCfg,
-		Search:      searchCfg,
-		Webhook:     webhookCfg,
-		Postgres:    postgresCfg,
-		Stdout:      parseStdoutProcessorConfig(),
-		Injector:    parseInjectorConfig(),
-		Transformer: transformerCfg,
-		Filter:      parseFilterConfig(),
-		Sanitize:    parseSanitizeConfig(),
+		Kafka:               kafkaCfg,
+		Search:              searchCfg,
+		Webhook:             webhookCfg,
+		Postgres:            postgresCfg,
+		Audit:               auditCfg,
+		Stdout:              parseStdoutProcessorConfig(),
+		File:                parseFileProcessorConfig(),
+		Injector:            parseInjectorConfig(),
+		Transformer:         transformerCfg,
+		Filter:              parseFilterConfig(),
+		Sanitize:            parseSanitizeConfig(),
+		Toast:               parseUnchangedToastConfig(),
+		IncrementalSnapshot: parseIncrementalSnapshotConfig(),
 	}, nil
 }
 
@@ -424,6 +580,19 @@ func parseStdoutProcessorConfig() *stream.StdoutProcessorConfig {
 	return &stream.StdoutProcessorConfig{}
 }
 
+func parseFileProcessorConfig() *stream.FileProcessorConfig {
+	dir := viper.GetString("PGSTREAM_FILE_WRITER_DIR")
+	if dir == "" {
+		return nil
+	}
+	return &stream.FileProcessorConfig{
+		Writer: filewriter.Config{
+			Dir:    dir,
+			Format: viper.GetString("PGSTREAM_FILE_WRITER_FORMAT"),
+		},
+	}
+}
+
 func parseKafkaProcessorConfig() (*stream.KafkaProcessorConfig, error) {
 	kafkaTopic := viper.GetString("PGSTREAM_KAFKA_TOPIC_NAME")
 	kafkaServers := viper.GetStringSlice("PGSTREAM_KAFKA_WRITER_SERVERS")
@@ -559,6 +728,16 @@ func parsePostgresProcessorConfig() (*stream.PostgresProcessorConfig, error) {
 		return nil, err
 	}
 
+	tableRoutes, err := parseTableRoutes(viper.GetStringSlice("PGSTREAM_POSTGRES_WRITER_TABLE_ROUTING"))
+	if err != nil {
+		return nil, err
+	}
+
+	conflictPolicies, err := parseTableConflictPolicies(viper.GetStringSlice("PGSTREAM_POSTGRES_WRITER_TABLE_CONFLICT_POLICIES"))
+	if err != nil {
+		return nil, err
+	}
+
 	bulkIngestEnabled := viper.GetBool("PGSTREAM_POSTGRES_WRITER_BULK_INGEST_ENABLED")
 	cfg := &stream.PostgresProcessorConfig{
 		BatchWriter: postgres.Config{
@@ -576,11 +755,23 @@ func parsePostgresProcessorConfig() (*stream.PostgresProcessorConfig, error) {
 					ConvergenceThreshold: viper.GetFloat64("PGSTREAM_POSTGRES_WRITER_BATCH_AUTO_TUNE_CONVERGENCE_THRESHOLD"),
 				},
 			},
-			DisableTriggers:   viper.GetBool("PGSTREAM_POSTGRES_WRITER_DISABLE_TRIGGERS"),
-			OnConflictAction:  viper.GetString("PGSTREAM_POSTGRES_WRITER_ON_CONFLICT_ACTION"),
-			BulkIngestEnabled: bulkIngestEnabled,
-			RetryPolicy:       parseBackoffConfig("PGSTREAM_POSTGRES_WRITER"),
-			IgnoreDDL:         viper.GetBool("PGSTREAM_POSTGRES_WRITER_IGNORE_DDL"),
+			DisableTriggers:       viper.GetBool("PGSTREAM_POSTGRES_WRITER_DISABLE_TRIGGERS"),
+			OnConflictAction:      viper.GetString("PGSTREAM_POSTGRES_WRITER_ON_CONFLICT_ACTION"),
+			BulkIngestEnabled:     bulkIngestEnabled,
+			BulkIngestMode:        viper.GetString("PGSTREAM_POSTGRES_WRITER_BULK_INGEST_MODE"),
+			RetryPolicy:           parseBackoffConfig("PGSTREAM_POSTGRES_WRITER"),
+			IgnoreDDL:             viper.GetBool("PGSTREAM_POSTGRES_WRITER_IGNORE_DDL"),
+			ConcurrentDDL:         viper.GetBool("PGSTREAM_POSTGRES_WRITER_CONCURRENT_DDL"),
+			TableRoutes:           tableRoutes,
+			ApplyMode:             viper.GetString("PGSTREAM_POSTGRES_WRITER_APPLY_MODE"),
+			ReplicationOrigin:     viper.GetString("PGSTREAM_POSTGRES_WRITER_REPLICATION_ORIGIN"),
+			ConflictResolution:    viper.GetString("PGSTREAM_POSTGRES_WRITER_CONFLICT_RESOLUTION"),
+			ApplyWorkers:          viper.GetInt("PGSTREAM_POSTGRES_WRITER_APPLY_WORKERS"),
+			ExactlyOnce:           viper.GetBool("PGSTREAM_POSTGRES_WRITER_EXACTLY_ONCE"),
+			ApplyProgressName:     viper.GetString("PGSTREAM_POSTGRES_WRITER_APPLY_PROGRESS_NAME"),
+			TableConflictPolicies: conflictPolicies,
+			SequenceSync:          parseSequenceSyncConfig(),
+			RoleSync:              parseRoleSyncConfig(),
 		},
 	}
 
@@ -591,6 +782,132 @@ func parsePostgresProcessorConfig() (*stream.PostgresProcessorConfig, error) {
 	return cfg, nil
 }
 
+func parseSequenceSyncConfig() postgres.SequenceSyncConfig {
+	if !viper.GetBool("PGSTREAM_POSTGRES_WRITER_SEQUENCE_SYNC_ENABLED") {
+		return postgres.SequenceSyncConfig{}
+	}
+
+	sourceURL := viper.GetString("PGSTREAM_POSTGRES_WRITER_SEQUENCE_SYNC_SOURCE_URL")
+	if sourceURL == "" {
+		sourceURL = viper.GetString("PGSTREAM_POSTGRES_LISTENER_URL")
+	}
+	return postgres.SequenceSyncConfig{
+		SourceURL: sourceURL,
+		Interval:  viper.GetDuration("PGSTREAM_POSTGRES_WRITER_SEQUENCE_SYNC_INTERVAL"),
+		Margin:    viper.GetInt64("PGSTREAM_POSTGRES_WRITER_SEQUENCE_SYNC_MARGIN"),
+	}
+}
+
+// parseRoleSyncConfig returns the role sync configuration, which follows the
+// roles snapshot mode of the schema snapshot. Roles are not synced if the
+// roles snapshot is disabled.
+func parseRoleSyncConfig() postgres.RoleSyncConfig {
+	if !viper.GetBool("PGSTREAM_POSTGRES_WRITER_ROLE_SYNC_ENABLED") {
+		return postgres.RoleSyncConfig{}
+	}
+
+	mode := viper.GetString("PGSTREAM_POSTGRES_SNAPSHOT_ROLES_SNAPSHOT_MODE")
+	if mode == disabledRolesSnapshotMode {
+		return postgres.RoleSyncConfig{}
+	}
+
+	sourceURL := viper.GetString("PGSTREAM_POSTGRES_WRITER_ROLE_SYNC_SOURCE_URL")
+	if sourceURL == "" {
+		sourceURL = viper.GetString("PGSTREAM_POSTGRES_LISTENER_URL")
+	}
+	return postgres.RoleSyncConfig{
+		SourceURL: sourceURL,
+		Mode:      mode,
+		Interval:  viper.GetDuration("PGSTREAM_POSTGRES_WRITER_ROLE_SYNC_INTERVAL"),
+	}
+}
+
+func parseAuditProcessorConfig() (*stream.AuditProcessorConfig, error) {
+	targetURL := viper.GetString("PGSTREAM_AUDIT_WRITER_TARGET_URL")
+	if targetURL == "" {
+		return nil, nil
+	}
+
+	maxBatchBytes, err := getByteSize("PGSTREAM_AUDIT_WRITER_BATCH_BYTES")
+	if err != nil {
+		return nil, err
+	}
+	maxQueueBytes, err := getByteSize("PGSTREAM_AUDIT_WRITER_MAX_QUEUE_BYTES")
+	if err != nil {
+		return nil, err
+	}
+
+	return &stream.AuditProcessorConfig{
+		Writer: postgres.AuditConfig{
+			URL:   targetURL,
+			Table: viper.GetString("PGSTREAM_AUDIT_WRITER_TABLE"),
+			BatchConfig: batch.Config{
+				BatchTimeout:  viper.GetDuration("PGSTREAM_AUDIT_WRITER_BATCH_TIMEOUT"),
+				MaxBatchBytes: maxBatchBytes,
+				MaxBatchSize:  viper.GetInt64("PGSTREAM_AUDIT_WRITER_BATCH_SIZE"),
+				MaxQueueBytes: maxQueueBytes,
+			},
+			RetryPolicy:         parseBackoffConfig("PGSTREAM_AUDIT_WRITER"),
+			MonthlyPartitioning: viper.GetBool("PGSTREAM_AUDIT_WRITER_MONTHLY_PARTITIONING"),
+			Retention:           viper.GetDuration("PGSTREAM_AUDIT_WRITER_RETENTION"),
+		},
+	}, nil
+}
+
+// parseTableRoutes parses a list of table routes in the format
+// "source=target" into a map of source to target patterns.
+func parseTableRoutes(routes []string) (map[string]string, error) {
+	if len(routes) == 0 {
+		return nil, nil
+	}
+	tableRoutes := make(map[string]string, len(routes))
+	for _, route := range routes {
+		source, target, found := strings.Cut(route, "=")
+		if !found || source == "" || target == "" {
+			return nil, fmt.Errorf("%w: %q", errInvalidTableRouteFormat, route)
+		}
+		tableRoutes[source] = target
+	}
+	return tableRoutes, nil
+}
+
+// parseTableConflictPolicies parses a list of table conflict policies in the
+// format "table=key:value;key:value", where the keys are action, columns,
+// constraint and update. Column lists are comma separated.
+func parseTableConflictPolicies(policies []string) (map[string]postgres.TableConflictPolicy, error) {
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	tablePolicies := make(map[string]postgres.TableConflictPolicy, len(policies))
+	for _, p := range policies {
+		table, settings, found := strings.Cut(p, "=")
+		if !found || table == "" || settings == "" {
+			return nil, fmt.Errorf("%w: %q", errInvalidTableConflictPolicyFormat, p)
+		}
+		policy := postgres.TableConflictPolicy{}
+		for _, setting := range strings.Split(settings, ";") {
+			key, value, found := strings.Cut(setting, ":")
+			if !found || value == "" {
+				return nil, fmt.Errorf("%w: %q", errInvalidTableConflictPolicyFormat, p)
+			}
+			switch key {
+			case "action":
+				policy.OnConflictAction = value
+			case "columns":
+				policy.ConflictColumns = strings.Split(value, ",")
+			case "constraint":
+				policy.ConflictConstraint = value
+			case "update":
+				policy.UpdateColumns = strings.Split(value, ",")
+			default:
+				return nil, fmt.Errorf("%w: unknown key %q in %q", errInvalidTableConflictPolicyFormat, key, p)
+			}
+		}
+		tablePolicies[table] = policy
+	}
+	return tablePolicies, nil
+}
+
 func parseBackoffConfig(prefix string) backoff.Config {
 	return backoff.Config{
 		DisableRetries: viper.GetBool(fmt.Sprintf("%s_DISABLE_RETRIES", prefix)),
@@ -662,6 +979,35 @@ func parseSanitizeConfig() *stream.SanitizeConfig {
 	}
 }
 
+func parseUnchangedToastConfig() *toast.Config {
+	if !viper.GetBool("PGSTREAM_UNCHANGED_TOAST_ENABLED") {
+		return nil
+	}
+	url := viper.GetString("PGSTREAM_UNCHANGED_TOAST_SOURCE_URL")
+	if url == "" {
+		url = viper.GetString("PGSTREAM_POSTGRES_LISTENER_URL")
+	}
+	return &toast.Config{
+		URL:            url,
+		FetchUnchanged: viper.GetBool("PGSTREAM_UNCHANGED_TOAST_FETCH_UNCHANGED"),
+	}
+}
+
+func parseIncrementalSnapshotConfig() *incremental.Config {
+	if !viper.GetBool("PGSTREAM_INCREMENTAL_SNAPSHOT_ENABLED") {
+		return nil
+	}
+	url := viper.GetString("PGSTREAM_INCREMENTAL_SNAPSHOT_SOURCE_URL")
+	if url == "" {
+		url = viper.GetString("PGSTREAM_POSTGRES_LISTENER_URL")
+	}
+	return &incremental.Config{
+		URL:                   url,
+		ChunkSize:             viper.GetUint("PGSTREAM_INCREMENTAL_SNAPSHOT_CHUNK_SIZE"),
+		AutoSnapshotNewTables: viper.GetBool("PGSTREAM_INCREMENTAL_SNAPSHOT_AUTO_NEW_TABLES"),
+	}
+}
+
 func parseTLSConfig(prefix string) tls.Config {
 	return tls.Config{
 		Enabled:        viper.GetBool(fmt.Sprintf("%s_TLS_ENABLED", prefix)),
@@ -19,12 +19,17 @@ import (
 	"github.com/xataio/pgstream/pkg/wal/listener/snapshot/adapter"
 	snapshotbuilder "github.com/xataio/pgstream/pkg/wal/listener/snapshot/builder"
 	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+	"github.com/xataio/pgstream/pkg/wal/processor/ddlpolicy"
+	filewriter "github.com/xataio/pgstream/pkg/wal/processor/file"
 	"github.com/xataio/pgstream/pkg/wal/processor/filter"
+	"github.com/xataio/pgstream/pkg/wal/processor/incremental"
 	"github.com/xataio/pgstream/pkg/wal/processor/injector"
 	kafkaprocessor "github.com/xataio/pgstream/pkg/wal/processor/kafka"
+	"github.com/xataio/pgstream/pkg/wal/processor/merger"
 	"github.com/xataio/pgstream/pkg/wal/processor/postgres"
 	"github.com/xataio/pgstream/pkg/wal/processor/search"
 	"github.com/xataio/pgstream/pkg/wal/processor/search/store"
+	"github.com/xataio/pgstream/pkg/wal/processor/toast"
 	"github.com/xataio/pgstream/pkg/wal/processor/transformer"
 	"github.com/xataio/pgstream/pkg/wal/processor/webhook/notifier"
 	"github.com/xataio/pgstream/pkg/wal/processor/webhook/subscription/server"
@@ -80,16 +85,24 @@ type TargetConfig struct {
 	Search   *SearchConfig         `mapstructure:"search" yaml:"search"`
 	Webhooks *WebhooksConfig       `mapstructure:"webhooks" yaml:"webhooks"`
 	Stdout   *StdoutTargetConfig   `mapstructure:"stdout" yaml:"stdout"`
+	Audit    *AuditTargetConfig    `mapstructure:"audit" yaml:"audit"`
+	File     *FileTargetConfig     `mapstructure:"file" yaml:"file"`
 }
 
 type StdoutTargetConfig struct{}
 
+type FileTargetConfig struct {
+	Dir    string `mapstructure:"dir" yaml:"dir"`
+	Format string `mapstructure:"format" yaml:"format"`
+}
+
 type PostgresConfig struct {
-	URL         string             `mapstructure:"url" yaml:"url"`
-	Mode        string             `mapstructure:"mode" yaml:"mode"`
-	Snapshot    *SnapshotConfig    `mapstructure:"snapshot" yaml:"snapshot"`
-	Replication *ReplicationConfig `mapstructure:"replication" yaml:"replication"`
-	RetryPolicy BackoffConfig      `mapstructure:"retry_policy" yaml:"retry_policy"`
+	URL                     string             `mapstructure:"url" yaml:"url"`
+	Mode                    string             `mapstructure:"mode" yaml:"mode"`
+	Snapshot                *SnapshotConfig    `mapstructure:"snapshot" yaml:"snapshot"`
+	Replication             *ReplicationConfig `mapstructure:"replication" yaml:"replication"`
+	RetryPolicy             BackoffConfig      `mapstructure:"retry_policy" yaml:"retry_policy"`
+	PublishViaPartitionRoot bool               `mapstructure:"publish_via_partition_root" yaml:"publish_via_partition_root"`
 }
 
 type SnapshotConfig struct {
@@ -105,14 +118,46 @@ type SnapshotConfig struct {
 
 type SnapshotRecorderConfig struct {
 	RepeatableSnapshots bool   `mapstructure:"repeatable_snapshots" yaml:"repeatable_snapshots"`
+	RetryFailed         bool   `mapstructure:"retry_failed" yaml:"retry_failed"`
 	PostgresURL         string `mapstructure:"postgres_url" yaml:"postgres_url"`
 }
 
 type SnapshotDataConfig struct {
-	SchemaWorkers  int      `mapstructure:"schema_workers" yaml:"schema_workers"`
-	TableWorkers   int      `mapstructure:"table_workers" yaml:"table_workers"`
-	BatchBytes     byteSize `mapstructure:"batch_bytes" yaml:"batch_bytes"`
-	MaxConnections uint     `mapstructure:"max_connections" yaml:"max_connections"`
+	SchemaWorkers  int                     `mapstructure:"schema_workers" yaml:"schema_workers"`
+	TableWorkers   int                     `mapstructure:"table_workers" yaml:"table_workers"`
+	BatchBytes     byteSize                `mapstructure:"batch_bytes" yaml:"batch_bytes"`
+	MaxConnections uint                    `mapstructure:"max_connections" yaml:"max_connections"`
+	Throttle       *SnapshotThrottleConfig `mapstructure:"throttle" yaml:"throttle"`
+	Subset         *SnapshotSubsetConfig   `mapstructure:"subset" yaml:"subset"`
+	Reader         string                  `mapstructure:"reader" yaml:"reader"`
+	TableReaders   []SnapshotTableReader   `mapstructure:"table_readers" yaml:"table_readers"`
+}
+
+type SnapshotTableReader struct {
+	Table   string   `mapstructure:"table" yaml:"table"`
+	Reader  string   `mapstructure:"reader" yaml:"reader"`
+	Index   string   `mapstructure:"index" yaml:"index"`
+	Columns []string `mapstructure:"columns" yaml:"columns"`
+}
+
+type SnapshotSubsetConfig struct {
+	Rules []SnapshotSubsetRule `mapstructure:"rules" yaml:"rules"`
+}
+
+type SnapshotSubsetRule struct {
+	Table   string  `mapstructure:"table" yaml:"table"`
+	Where   string  `mapstructure:"where" yaml:"where"`
+	Percent float64 `mapstructure:"percent" yaml:"percent"`
+}
+
+type SnapshotThrottleConfig struct {
+	RowsPerSecond        uint     `mapstructure:"rows_per_second" yaml:"rows_per_second"`
+	BytesPerSecond       byteSize `mapstructure:"bytes_per_second" yaml:"bytes_per_second"`
+	MaxReplicationLag    int      `mapstructure:"max_replication_lag" yaml:"max_replication_lag"`
+	MaxActiveConnections uint     `mapstructure:"max_active_connections" yaml:"max_active_connections"`
+	ProbeQuery           string   `mapstructure:"probe_query" yaml:"probe_query"`
+	ProbeThreshold       float64  `mapstructure:"probe_threshold" yaml:"probe_threshold"`
+	ProbeInterval        int      `mapstructure:"probe_interval" yaml:"probe_interval"`
 }
 
 type SnapshotSchemaConfig struct {
@@ -129,6 +174,8 @@ type PgDumpPgRestoreConfig struct {
 	NoPrivileges           bool     `mapstructure:"no_privileges" yaml:"no_privileges"`
 	DumpFile               string   `mapstructure:"dump_file" yaml:"dump_file"`
 	ExcludedSecurityLabels []string `mapstructure:"excluded_security_labels" yaml:"excluded_security_labels"`
+	NativeDump             bool     `mapstructure:"native_dump" yaml:"native_dump"`
+	IndexBuildWorkers      uint     `mapstructure:"index_build_workers" yaml:"index_build_workers"`
 }
 
 type ReplicationConfig struct {
@@ -137,9 +184,10 @@ type ReplicationConfig struct {
 }
 
 type PluginConfig struct {
-	IncludeXIDs  bool   `mapstructure:"include_xids" yaml:"include_xids"`
-	AddTables    string `mapstructure:"add_tables" yaml:"add_tables"`
-	FilterTables string `mapstructure:"filter_tables" yaml:"filter_tables"`
+	IncludeXIDs   bool   `mapstructure:"include_xids" yaml:"include_xids"`
+	AddTables     string `mapstructure:"add_tables" yaml:"add_tables"`
+	FilterTables  string `mapstructure:"filter_tables" yaml:"filter_tables"`
+	FilterOrigins string `mapstructure:"filter_origins" yaml:"filter_origins"`
 }
 
 type KafkaConfig struct {
@@ -186,13 +234,59 @@ type ConstantBackoffConfig struct {
 }
 
 type PostgresTargetConfig struct {
-	URL              string            `mapstructure:"url" yaml:"url"`
-	Batch            *BatchConfig      `mapstructure:"batch" yaml:"batch"`
-	BulkIngest       *BulkIngestConfig `mapstructure:"bulk_ingest" yaml:"bulk_ingest"`
-	DisableTriggers  bool              `mapstructure:"disable_triggers" yaml:"disable_triggers"`
-	OnConflictAction string            `mapstructure:"on_conflict_action" yaml:"on_conflict_action"`
-	RetryPolicy      BackoffConfig     `mapstructure:"retry_policy" yaml:"retry_policy"`
-	IgnoreDDL        bool              `mapstructure:"ignore_ddl" yaml:"ignore_ddl"`
+	URL                   string                      `mapstructure:"url" yaml:"url"`
+	Batch                 *BatchConfig                `mapstructure:"batch" yaml:"batch"`
+	BulkIngest            *BulkIngestConfig           `mapstructure:"bulk_ingest" yaml:"bulk_ingest"`
+	DisableTriggers       bool                        `mapstructure:"disable_triggers" yaml:"disable_triggers"`
+	OnConflictAction      string                      `mapstructure:"on_conflict_action" yaml:"on_conflict_action"`
+	RetryPolicy           BackoffConfig               `mapstructure:"retry_policy" yaml:"retry_policy"`
+	IgnoreDDL             bool                        `mapstructure:"ignore_ddl" yaml:"ignore_ddl"`
+	ConcurrentDDL         bool                        `mapstructure:"concurrent_ddl" yaml:"concurrent_ddl"`
+	TableRouting          []TableRouteConfig          `mapstructure:"table_routing" yaml:"table_routing"`
+	ApplyMode             string                      `mapstructure:"apply_mode" yaml:"apply_mode"`
+	ReplicationOrigin     string                      `mapstructure:"replication_origin" yaml:"replication_origin"`
+	ConflictResolution    string                      `mapstructure:"conflict_resolution" yaml:"conflict_resolution"`
+	ApplyWorkers          int                         `mapstructure:"apply_workers" yaml:"apply_workers"`
+	ExactlyOnce           bool                        `mapstructure:"exactly_once" yaml:"exactly_once"`
+	ApplyProgressName     string                      `mapstructure:"apply_progress_name" yaml:"apply_progress_name"`
+	TableConflictPolicies []TableConflictPolicyConfig `mapstructure:"table_conflict_policies" yaml:"table_conflict_policies"`
+	SequenceSync          *SequenceSyncConfig         `mapstructure:"sequence_sync" yaml:"sequence_sync"`
+	RoleSync              *RoleSyncConfig             `mapstructure:"role_sync" yaml:"role_sync"`
+}
+
+type SequenceSyncConfig struct {
+	Enabled   bool   `mapstructure:"enabled" yaml:"enabled"`
+	SourceURL string `mapstructure:"source_url" yaml:"source_url"`
+	Interval  int    `mapstructure:"interval" yaml:"interval"`
+	Margin    int64  `mapstructure:"margin" yaml:"margin"`
+}
+
+type RoleSyncConfig struct {
+	Enabled   bool   `mapstructure:"enabled" yaml:"enabled"`
+	SourceURL string `mapstructure:"source_url" yaml:"source_url"`
+	Interval  int    `mapstructure:"interval" yaml:"interval"`
+}
+
+type AuditTargetConfig struct {
+	URL                 string        `mapstructure:"url" yaml:"url"`
+	Table               string        `mapstructure:"table" yaml:"table"`
+	Batch               *BatchConfig  `mapstructure:"batch" yaml:"batch"`
+	RetryPolicy         BackoffConfig `mapstructure:"retry_policy" yaml:"retry_policy"`
+	MonthlyPartitioning bool          `mapstructure:"monthly_partitioning" yaml:"monthly_partitioning"`
+	RetentionDays       int           `mapstructure:"retention_days" yaml:"retention_days"`
+}
+
+type TableRouteConfig struct {
+	Source string `mapstructure:"source" yaml:"source"`
+	Target string `mapstructure:"target" yaml:"target"`
+}
+
+type TableConflictPolicyConfig struct {
+	Table              string   `mapstructure:"table" yaml:"table"`
+	OnConflictAction   string   `mapstructure:"on_conflict_action" yaml:"on_conflict_action"`
+	ConflictColumns    []string `mapstructure:"conflict_columns" yaml:"conflict_columns"`
+	ConflictConstraint string   `mapstructure:"conflict_constraint" yaml:"conflict_constraint"`
+	UpdateColumns      []string `mapstructure:"update_columns" yaml:"update_columns"`
 }
 
 type KafkaTargetConfig struct {
@@ -244,7 +338,8 @@ type BatchAutoTuneConfig struct {
 }
 
 type BulkIngestConfig struct {
-	Enabled bool `mapstructure:"enabled" yaml:"enabled"`
+	Enabled bool   `mapstructure:"enabled" yaml:"enabled"`
+	Mode    string `mapstructure:"mode" yaml:"mode"`
 }
 
 type WebhooksConfig struct {
@@ -282,11 +377,51 @@ type SanitizeConfig struct {
 	StripNullCharBytes bool `mapstructure:"strip_null_char_bytes" yaml:"strip_null_char_bytes"`
 }
 
+type UnchangedToastConfig struct {
+	Enabled        bool   `mapstructure:"enabled" yaml:"enabled"`
+	FetchUnchanged bool   `mapstructure:"fetch_unchanged" yaml:"fetch_unchanged"`
+	SourceURL      string `mapstructure:"source_url" yaml:"source_url"`
+}
+
+type IncrementalSnapshotConfig struct {
+	Enabled               bool   `mapstructure:"enabled" yaml:"enabled"`
+	ChunkSize             uint   `mapstructure:"chunk_size" yaml:"chunk_size"`
+	SourceURL             string `mapstructure:"source_url" yaml:"source_url"`
+	AutoSnapshotNewTables bool   `mapstructure:"auto_snapshot_new_tables" yaml:"auto_snapshot_new_tables"`
+}
+
 type ModifiersConfig struct {
-	Injector        *InjectorConfig        `mapstructure:"injector" yaml:"injector"`
-	Transformations *TransformationsConfig `mapstructure:"transformations" yaml:"transformations"`
-	Filter          *FilterConfig          `mapstructure:"filter" yaml:"filter"`
-	Sanitize        *SanitizeConfig        `mapstructure:"sanitize" yaml:"sanitize"`
+	Injector            *InjectorConfig            `mapstructure:"injector" yaml:"injector"`
+	Transformations     *TransformationsConfig     `mapstructure:"transformations" yaml:"transformations"`
+	Filter              *FilterConfig              `mapstructure:"filter" yaml:"filter"`
+	Sanitize            *SanitizeConfig            `mapstructure:"sanitize" yaml:"sanitize"`
+	Merge               *MergeConfig               `mapstructure:"merge" yaml:"merge"`
+	DDLPolicy           *DDLPolicyConfig           `mapstructure:"ddl_policy" yaml:"ddl_policy"`
+	UnchangedToast      *UnchangedToastConfig      `mapstructure:"unchanged_toast" yaml:"unchanged_toast"`
+	IncrementalSnapshot *IncrementalSnapshotConfig `mapstructure:"incremental_snapshot" yaml:"incremental_snapshot"`
+}
+
+type DDLPolicyConfig struct {
+	ApprovalURL          string                `mapstructure:"approval_url" yaml:"approval_url"`
+	ApprovalPollInterval int                   `mapstructure:"approval_poll_interval" yaml:"approval_poll_interval"`
+	Rules                []DDLPolicyRuleConfig `mapstructure:"rules" yaml:"rules"`
+}
+
+type DDLPolicyRuleConfig struct {
+	CommandTag string `mapstructure:"command_tag" yaml:"command_tag"`
+	ObjectType string `mapstructure:"object_type" yaml:"object_type"`
+	Statement  string `mapstructure:"statement" yaml:"statement"`
+	Action     string `mapstructure:"action" yaml:"action"`
+}
+
+type MergeConfig struct {
+	Tables []MergeTableConfig `mapstructure:"tables" yaml:"tables"`
+}
+
+type MergeTableConfig struct {
+	Source              string `mapstructure:"source" yaml:"source"`
+	Target              string `mapstructure:"target" yaml:"target"`
+	DiscriminatorColumn string `mapstructure:"discriminator_column" yaml:"discriminator_column"`
 }
 
 type InjectorConfig struct {
@@ -363,7 +498,11 @@ var (
 	errUnsupportedRolesSnapshotMode            = errors.New("unsupported roles snapshot mode, must be one of 'enabled', 'disabled', or 'no_passwords'")
 	errInvalidInjectorConfig                   = errors.New("injector config can't infer source url, must be provided")
 	errInvalidSnapshotRecorderConfig           = errors.New("snapshot recorder config requires a postgres url")
+	errInvalidTableRouteFormat                 = errors.New("invalid table route, must be in the format 'source=target'")
+	errInvalidTableConflictPolicyFormat        = errors.New("invalid table conflict policy, must be in the format 'table=key:value;key:value'")
 	errInvalidSampleRatio                      = errors.New("trace sample ratio must be a value between 0.0 and 1.0")
+	errUnsupportedSnapshotReader               = errors.New("unsupported snapshot reader, must be one of 'auto', 'ctid' or 'keyset'")
+	errInvalidSnapshotTableReaderFormat        = errors.New("invalid snapshot table reader, must be in the format 'table=key:value;key:value'")
 	errSchemaSnapshotNotConfigured             = errors.New("schema snapshot config must be provided when snapshot mode is 'full' or 'schema'")
 )
 
@@ -431,11 +570,17 @@ func (c *YAMLConfig) parseProcessorConfig() (stream.ProcessorConfig, error) {
 	streamCfg := stream.ProcessorConfig{
 		Kafka:    c.parseKafkaProcessorConfig(),
 		Postgres: c.parsePostgresProcessorConfig(),
+		Audit:    c.parseAuditProcessorConfig(),
 		Webhook:  c.parseWebhookProcessorConfig(),
 		Stdout:   c.parseStdoutProcessorConfig(),
+		File:     c.parseFileProcessorConfig(),
 		Filter:   c.parseFilterConfig(),
 		Sanitize: c.parseSanitizeConfig(),
+		Merger:   c.parseMergerConfig(),
+		Toast:    c.parseUnchangedToastConfig(),
 	}
+	streamCfg.DDLPolicy = c.parseDDLPolicyConfig()
+	streamCfg.IncrementalSnapshot = c.parseIncrementalSnapshotConfig()
 
 	var err error
 	streamCfg.Injector, err = c.parseInjectorConfig()
@@ -468,8 +613,9 @@ func (c *YAMLConfig) parsePostgresListenerConfig() (*stream.PostgresListenerConf
 	}
 
 	streamCfg := &stream.PostgresListenerConfig{
-		URL:         c.Source.Postgres.URL,
-		RetryPolicy: c.Source.Postgres.RetryPolicy.parseBackoffConfig(),
+		URL:                     c.Source.Postgres.URL,
+		RetryPolicy:             c.Source.Postgres.RetryPolicy.parseBackoffConfig(),
+		PublishViaPartitionRoot: c.Source.Postgres.PublishViaPartitionRoot,
 	}
 
 	if c.Source.Postgres.Mode == replicationMode || c.Source.Postgres.Mode == snapshotAndReplicationMode {
@@ -481,6 +627,7 @@ func (c *YAMLConfig) parsePostgresListenerConfig() (*stream.PostgresListenerConf
 				pluginArgs.IncludeXIDs = c.Source.Postgres.Replication.Plugin.IncludeXIDs
 				pluginArgs.AddTables = c.Source.Postgres.Replication.Plugin.AddTables
 				pluginArgs.FilterTables = c.Source.Postgres.Replication.Plugin.FilterTables
+				pluginArgs.FilterOrigins = c.Source.Postgres.Replication.Plugin.FilterOrigins
 			}
 		}
 		streamCfg.Replication = pgreplication.Config{
@@ -520,6 +667,7 @@ func (c *YAMLConfig) parseSnapshotConfig() (*snapshotbuilder.SnapshotListenerCon
 			ExcludedTables: snapshotConfig.ExcludedTables,
 		},
 		DisableProgressTracking: snapshotConfig.DisableProgressTracking,
+		PublishViaPartitionRoot: c.Source.Postgres.PublishViaPartitionRoot,
 	}
 
 	if snapshotConfig.Recorder != nil {
@@ -529,6 +677,7 @@ func (c *YAMLConfig) parseSnapshotConfig() (*snapshotbuilder.SnapshotListenerCon
 
 		streamCfg.Recorder = &snapshotbuilder.SnapshotRecorderConfig{
 			RepeatableSnapshots: snapshotConfig.Recorder.RepeatableSnapshots,
+			RetryFailed:         snapshotConfig.Recorder.RetryFailed,
 			SnapshotStoreURL:    snapshotConfig.Recorder.PostgresURL,
 			SnapshotWorkers:     uint(snapshotConfig.SnapshotWorkers),
 		}
@@ -543,7 +692,11 @@ func (c *YAMLConfig) parseSnapshotConfig() (*snapshotbuilder.SnapshotListenerCon
 	}
 
 	if snapshotConfig.Mode == fullSnapshotMode || snapshotConfig.Mode == dataSnapshotMode {
-		streamCfg.Data = c.parseDataSnapshotConfig()
+		var err error
+		streamCfg.Data, err = c.parseDataSnapshotConfig()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if snapshotConfig.Mode == fullSnapshotMode || snapshotConfig.Mode == schemaSnapshotMode {
@@ -560,7 +713,7 @@ func (c *YAMLConfig) parseSnapshotConfig() (*snapshotbuilder.SnapshotListenerCon
 	return streamCfg, nil
 }
 
-func (c *YAMLConfig) parseDataSnapshotConfig() *pgsnapshotgenerator.Config {
+func (c *YAMLConfig) parseDataSnapshotConfig() (*pgsnapshotgenerator.Config, error) {
 	snapshotCfg := c.Source.Postgres.Snapshot
 	streamCfg := &pgsnapshotgenerator.Config{
 		URL:             c.Source.Postgres.URL,
@@ -572,9 +725,79 @@ func (c *YAMLConfig) parseDataSnapshotConfig() *pgsnapshotgenerator.Config {
 		streamCfg.SchemaWorkers = uint(snapshotCfg.Data.SchemaWorkers)
 		streamCfg.TableWorkers = uint(snapshotCfg.Data.TableWorkers)
 		streamCfg.MaxConnections = snapshotCfg.Data.MaxConnections
+		streamCfg.Throttle = snapshotCfg.Data.Throttle.parseThrottleConfig()
+		streamCfg.Subset = snapshotCfg.Data.Subset.parseSubsetConfig()
+
+		var err error
+		streamCfg.Reader, err = parseSnapshotReader(snapshotCfg.Data.Reader)
+		if err != nil {
+			return nil, err
+		}
+		streamCfg.TableReaders, err = snapshotCfg.Data.parseTableReaders()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return streamCfg
+	return streamCfg, nil
+}
+
+func (c *SnapshotDataConfig) parseTableReaders() (map[string]pgsnapshotgenerator.TableReaderConfig, error) {
+	if len(c.TableReaders) == 0 {
+		return nil, nil
+	}
+	readers := make(map[string]pgsnapshotgenerator.TableReaderConfig, len(c.TableReaders))
+	for _, tr := range c.TableReaders {
+		reader, err := parseSnapshotReader(tr.Reader)
+		if err != nil {
+			return nil, err
+		}
+		readers[tr.Table] = pgsnapshotgenerator.TableReaderConfig{
+			Reader:  reader,
+			Index:   tr.Index,
+			Columns: tr.Columns,
+		}
+	}
+	return readers, nil
+}
+
+func parseSnapshotReader(reader string) (pgsnapshotgenerator.TableReader, error) {
+	switch r := pgsnapshotgenerator.TableReader(reader); r {
+	case "", pgsnapshotgenerator.TableReaderAuto, pgsnapshotgenerator.TableReaderCtid, pgsnapshotgenerator.TableReaderKeyset:
+		return r, nil
+	default:
+		return "", errUnsupportedSnapshotReader
+	}
+}
+
+func (c *SnapshotThrottleConfig) parseThrottleConfig() *pgsnapshotgenerator.ThrottleConfig {
+	if c == nil {
+		return nil
+	}
+	return &pgsnapshotgenerator.ThrottleConfig{
+		RowsPerSecond:        c.RowsPerSecond,
+		BytesPerSecond:       uint64(c.BytesPerSecond),
+		MaxReplicationLag:    time.Duration(c.MaxReplicationLag) * time.Second,
+		MaxActiveConnections: c.MaxActiveConnections,
+		ProbeQuery:           c.ProbeQuery,
+		ProbeThreshold:       c.ProbeThreshold,
+		ProbeInterval:        time.Duration(c.ProbeInterval) * time.Second,
+	}
+}
+
+func (c *SnapshotSubsetConfig) parseSubsetConfig() *pgsnapshotgenerator.SubsetConfig {
+	if c == nil || len(c.Rules) == 0 {
+		return nil
+	}
+	rules := make([]pgsnapshotgenerator.SubsetRule, 0, len(c.Rules))
+	for _, rule := range c.Rules {
+		rules = append(rules, pgsnapshotgenerator.SubsetRule{
+			Table:   rule.Table,
+			Where:   rule.Where,
+			Percent: rule.Percent,
+		})
+	}
+	return &pgsnapshotgenerator.SubsetConfig{Rules: rules}
 }
 
 func (c *YAMLConfig) parseSchemaSnapshotConfig() (*snapshotbuilder.SchemaSnapshotConfig, error) {
@@ -584,13 +807,21 @@ func (c *YAMLConfig) parseSchemaSnapshotConfig() (*snapshotbuilder.SchemaSnapsho
 	}
 
 	targetURL := ""
+	var tableRoutes map[string]string
 	if c.Target.Postgres != nil {
 		targetURL = c.Target.Postgres.URL
+		tableRoutes = c.Target.Postgres.parseTableRoutes()
+	}
+	targetDir := ""
+	if c.Target.File != nil {
+		targetDir = c.Target.File.Dir
 	}
 	streamSchemaCfg := &snapshotbuilder.SchemaSnapshotConfig{
 		DumpRestore: &pgdumprestore.Config{
 			SourcePGURL: c.Source.Postgres.URL,
 			TargetPGURL: targetURL,
+			TargetDir:   targetDir,
+			TableRoutes: tableRoutes,
 		},
 	}
 
@@ -603,6 +834,8 @@ func (c *YAMLConfig) parseSchemaSnapshotConfig() (*snapshotbuilder.SchemaSnapsho
 		streamSchemaCfg.DumpRestore.NoPrivileges = schemaSnapshotCfg.PgDumpPgRestore.NoPrivileges
 		streamSchemaCfg.DumpRestore.DumpDebugFile = schemaSnapshotCfg.PgDumpPgRestore.DumpFile
 		streamSchemaCfg.DumpRestore.ExcludedSecurityLabels = schemaSnapshotCfg.PgDumpPgRestore.ExcludedSecurityLabels
+		streamSchemaCfg.DumpRestore.NativeDump = schemaSnapshotCfg.PgDumpPgRestore.NativeDump
+		streamSchemaCfg.DumpRestore.IndexBuildWorkers = schemaSnapshotCfg.PgDumpPgRestore.IndexBuildWorkers
 
 		var err error
 		streamSchemaCfg.DumpRestore.RolesSnapshotMode, err = getRolesSnapshotMode(schemaSnapshotCfg.PgDumpPgRestore.RolesSnapshotMode)
@@ -661,6 +894,18 @@ func (c *YAMLConfig) parseStdoutProcessorConfig() *stream.StdoutProcessorConfig
 	return &stream.StdoutProcessorConfig{}
 }
 
+func (c *YAMLConfig) parseFileProcessorConfig() *stream.FileProcessorConfig {
+	if c.Target.File == nil {
+		return nil
+	}
+	return &stream.FileProcessorConfig{
+		Writer: filewriter.Config{
+			Dir:    c.Target.File.Dir,
+			Format: c.Target.File.Format,
+		},
+	}
+}
+
 func (c *YAMLConfig) parsePostgresProcessorConfig() *stream.PostgresProcessorConfig {
 	if c.Target.Postgres == nil {
 		return nil
@@ -668,17 +913,29 @@ func (c *YAMLConfig) parsePostgresProcessorConfig() *stream.PostgresProcessorCon
 
 	cfg := &stream.PostgresProcessorConfig{
 		BatchWriter: postgres.Config{
-			URL:              c.Target.Postgres.URL,
-			BatchConfig:      c.Target.Postgres.Batch.parseBatchConfig(),
-			DisableTriggers:  c.Target.Postgres.DisableTriggers,
-			OnConflictAction: c.Target.Postgres.OnConflictAction,
-			RetryPolicy:      c.Target.Postgres.RetryPolicy.parseBackoffConfig(),
-			IgnoreDDL:        c.Target.Postgres.IgnoreDDL,
+			URL:                   c.Target.Postgres.URL,
+			BatchConfig:           c.Target.Postgres.Batch.parseBatchConfig(),
+			DisableTriggers:       c.Target.Postgres.DisableTriggers,
+			OnConflictAction:      c.Target.Postgres.OnConflictAction,
+			RetryPolicy:           c.Target.Postgres.RetryPolicy.parseBackoffConfig(),
+			ConcurrentDDL:         c.Target.Postgres.ConcurrentDDL,
+			IgnoreDDL:             c.Target.Postgres.IgnoreDDL,
+			TableRoutes:           c.Target.Postgres.parseTableRoutes(),
+			ApplyMode:             c.Target.Postgres.ApplyMode,
+			ReplicationOrigin:     c.Target.Postgres.ReplicationOrigin,
+			ConflictResolution:    c.Target.Postgres.ConflictResolution,
+			ApplyWorkers:          c.Target.Postgres.ApplyWorkers,
+			ExactlyOnce:           c.Target.Postgres.ExactlyOnce,
+			ApplyProgressName:     c.Target.Postgres.ApplyProgressName,
+			TableConflictPolicies: c.Target.Postgres.parseTableConflictPolicies(),
+			SequenceSync:          c.parseSequenceSyncConfig(),
+			RoleSync:              c.parseRoleSyncConfig(),
 		},
 	}
 
 	if c.Target.Postgres.BulkIngest != nil {
 		cfg.BatchWriter.BulkIngestEnabled = c.Target.Postgres.BulkIngest.Enabled
+		cfg.BatchWriter.BulkIngestMode = c.Target.Postgres.BulkIngest.Mode
 		if cfg.BatchWriter.BulkIngestEnabled {
 			applyPostgresBulkBatchDefaults(&cfg.BatchWriter.BatchConfig)
 		}
@@ -687,6 +944,96 @@ func (c *YAMLConfig) parsePostgresProcessorConfig() *stream.PostgresProcessorCon
 	return cfg
 }
 
+func (c *YAMLConfig) parseAuditProcessorConfig() *stream.AuditProcessorConfig {
+	if c.Target.Audit == nil {
+		return nil
+	}
+
+	return &stream.AuditProcessorConfig{
+		Writer: postgres.AuditConfig{
+			URL:                 c.Target.Audit.URL,
+			Table:               c.Target.Audit.Table,
+			BatchConfig:         c.Target.Audit.Batch.parseBatchConfig(),
+			RetryPolicy:         c.Target.Audit.RetryPolicy.parseBackoffConfig(),
+			MonthlyPartitioning: c.Target.Audit.MonthlyPartitioning,
+			Retention:           time.Duration(c.Target.Audit.RetentionDays) * 24 * time.Hour,
+		},
+	}
+}
+
+func (c *YAMLConfig) parseSequenceSyncConfig() postgres.SequenceSyncConfig {
+	cfg := c.Target.Postgres.SequenceSync
+	if cfg == nil || !cfg.Enabled {
+		return postgres.SequenceSyncConfig{}
+	}
+
+	sourceURL := cfg.SourceURL
+	if sourceURL == "" && c.Source.Postgres != nil {
+		sourceURL = c.Source.Postgres.URL
+	}
+	return postgres.SequenceSyncConfig{
+		SourceURL: sourceURL,
+		Interval:  time.Duration(cfg.Interval) * time.Second,
+		Margin:    cfg.Margin,
+	}
+}
+
+// parseRoleSyncConfig returns the role sync configuration, which follows the
+// roles snapshot mode of the schema snapshot. Roles are not synced if the
+// roles snapshot is disabled.
+func (c *YAMLConfig) parseRoleSyncConfig() postgres.RoleSyncConfig {
+	cfg := c.Target.Postgres.RoleSync
+	if cfg == nil || !cfg.Enabled {
+		return postgres.RoleSyncConfig{}
+	}
+
+	mode := ""
+	if c.Source.Postgres != nil && c.Source.Postgres.Snapshot != nil &&
+		c.Source.Postgres.Snapshot.Schema != nil && c.Source.Postgres.Snapshot.Schema.PgDumpPgRestore != nil {
+		mode = c.Source.Postgres.Snapshot.Schema.PgDumpPgRestore.RolesSnapshotMode
+	}
+	if mode == disabledRolesSnapshotMode {
+		return postgres.RoleSyncConfig{}
+	}
+
+	sourceURL := cfg.SourceURL
+	if sourceURL == "" && c.Source.Postgres != nil {
+		sourceURL = c.Source.Postgres.URL
+	}
+	return postgres.RoleSyncConfig{
+		SourceURL: sourceURL,
+		Mode:      mode,
+		Interval:  time.Duration(cfg.Interval) * time.Second,
+	}
+}
+
+func (c *PostgresTargetConfig) parseTableRoutes() map[string]string {
+	if len(c.TableRouting) == 0 {
+		return nil
+	}
+	routes := make(map[string]string, len(c.TableRouting))
+	for _, route := range c.TableRouting {
+		routes[route.Source] = route.Target
+	}
+	return routes
+}
+
+func (c *PostgresTargetConfig) parseTableConflictPolicies() map[string]postgres.TableConflictPolicy {
+	if len(c.TableConflictPolicies) == 0 {
+		return nil
+	}
+	policies := make(map[string]postgres.TableConflictPolicy, len(c.TableConflictPolicies))
+	for _, policy := range c.TableConflictPolicies {
+		policies[policy.Table] = postgres.TableConflictPolicy{
+			OnConflictAction:   policy.OnConflictAction,
+			ConflictColumns:    policy.ConflictColumns,
+			ConflictConstraint: policy.ConflictConstraint,
+			UpdateColumns:      policy.UpdateColumns,
+		}
+	}
+	return policies
+}
+
 func (c *YAMLConfig) parseSearchProcessorConfig() (*stream.SearchProcessorConfig, error) {
 	if c.Target.Search == nil {
 		return nil, nil
@@ -761,6 +1108,48 @@ func (c *YAMLConfig) parseTransformationConfig() (*transformer.Config, error) {
 	return c.Modifiers.Transformations.parseTransformationConfig()
 }
 
+func (c YAMLConfig) parseMergerConfig() *merger.Config {
+	if c.Modifiers.Merge == nil || len(c.Modifiers.Merge.Tables) == 0 {
+		return nil
+	}
+	tables := make([]merger.TableConfig, 0, len(c.Modifiers.Merge.Tables))
+	for _, t := range c.Modifiers.Merge.Tables {
+		tables = append(tables, merger.TableConfig{
+			Source:              t.Source,
+			Target:              t.Target,
+			DiscriminatorColumn: t.DiscriminatorColumn,
+		})
+	}
+	return &merger.Config{
+		Tables: tables,
+	}
+}
+
+func (c YAMLConfig) parseDDLPolicyConfig() *ddlpolicy.Config {
+	if c.Modifiers.DDLPolicy == nil || len(c.Modifiers.DDLPolicy.Rules) == 0 {
+		return nil
+	}
+	rules := make([]ddlpolicy.RuleConfig, 0, len(c.Modifiers.DDLPolicy.Rules))
+	for _, r := range c.Modifiers.DDLPolicy.Rules {
+		rules = append(rules, ddlpolicy.RuleConfig{
+			CommandTag: r.CommandTag,
+			ObjectType: r.ObjectType,
+			Statement:  r.Statement,
+			Action:     r.Action,
+		})
+	}
+	// approvals are stored in the source database by default
+	approvalURL := c.Modifiers.DDLPolicy.ApprovalURL
+	if approvalURL == "" && c.Source.Postgres != nil {
+		approvalURL = c.Source.Postgres.URL
+	}
+	return &ddlpolicy.Config{
+		Rules:                rules,
+		ApprovalURL:          approvalURL,
+		ApprovalPollInterval: time.Duration(c.Modifiers.DDLPolicy.ApprovalPollInterval) * time.Second,
+	}
+}
+
 func (c YAMLConfig) parseFilterConfig() *filter.Config {
 	if c.Modifiers.Filter == nil {
 		return nil
@@ -780,6 +1169,35 @@ func (c YAMLConfig) parseSanitizeConfig() *stream.SanitizeConfig {
 	}
 }
 
+func (c *YAMLConfig) parseUnchangedToastConfig() *toast.Config {
+	if c.Modifiers.UnchangedToast == nil || !c.Modifiers.UnchangedToast.Enabled {
+		return nil
+	}
+	url := c.Modifiers.UnchangedToast.SourceURL
+	if url == "" && c.Source.Postgres != nil {
+		url = c.Source.Postgres.URL
+	}
+	return &toast.Config{
+		URL:            url,
+		FetchUnchanged: c.Modifiers.UnchangedToast.FetchUnchanged,
+	}
+}
+
+func (c *YAMLConfig) parseIncrementalSnapshotConfig() *incremental.Config {
+	if c.Modifiers.IncrementalSnapshot == nil || !c.Modifiers.IncrementalSnapshot.Enabled {
+		return nil
+	}
+	url := c.Modifiers.IncrementalSnapshot.SourceURL
+	if url == "" && c.Source.Postgres != nil {
+		url = c.Source.Postgres.URL
+	}
+	return &incremental.Config{
+		URL:                   url,
+		ChunkSize:             c.Modifiers.IncrementalSnapshot.ChunkSize,
+		AutoSnapshotNewTables: c.Modifiers.IncrementalSnapshot.AutoSnapshotNewTables,
+	}
+}
+
 func (c TransformationsConfig) parseTransformationConfig() (*transformer.Config, error) {
 	if c.TransformerRules == nil && !c.InferFromSecurityLabels {
 		// transformation configuration provided, but no rules defined
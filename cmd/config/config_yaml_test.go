@@ -9,6 +9,8 @@ import (
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 	"github.com/xataio/pgstream/pkg/otel"
+	"github.com/xataio/pgstream/pkg/wal/processor/ddlpolicy"
+	"github.com/xataio/pgstream/pkg/wal/processor/postgres"
 )
 
 func TestYAMLConfig_toStreamConfig(t *testing.T) {
@@ -92,6 +94,26 @@ func TestYAMLConfig_toStreamConfig_ErrorCases(t *testing.T) {
 
 			wantErr: errInvalidSnapshotRecorderConfig,
 		},
+		{
+			name: "err - invalid snapshot table reader",
+			config: YAMLConfig{
+				Source: SourceConfig{
+					Postgres: &PostgresConfig{
+						Mode: snapshotMode,
+						Snapshot: &SnapshotConfig{
+							Mode: dataSnapshotMode,
+							Data: &SnapshotDataConfig{
+								TableReaders: []SnapshotTableReader{
+									{Table: "public.events", Reader: "invalid"},
+								},
+							},
+						},
+					},
+				},
+			},
+
+			wantErr: errUnsupportedSnapshotReader,
+		},
 		{
 			name: "err - invalid roles snapshot mode",
 			config: YAMLConfig{
@@ -155,6 +177,136 @@ func TestYAMLConfig_toStreamConfig_ErrorCases(t *testing.T) {
 	}
 }
 
+func TestYAMLConfig_parseDDLPolicyConfig(t *testing.T) {
+	t.Parallel()
+
+	rules := []DDLPolicyRuleConfig{
+		{CommandTag: "DROP TABLE", Action: "pause"},
+		{CommandTag: "ALTER TABLE", Statement: "ALTER COLUMN .* TYPE", Action: "fail"},
+	}
+	wantRules := []ddlpolicy.RuleConfig{
+		{CommandTag: "DROP TABLE", Action: "pause"},
+		{CommandTag: "ALTER TABLE", Statement: "ALTER COLUMN .* TYPE", Action: "fail"},
+	}
+
+	tests := []struct {
+		name   string
+		config YAMLConfig
+
+		wantConfig *ddlpolicy.Config
+	}{
+		{
+			name:       "no policy",
+			config:     YAMLConfig{},
+			wantConfig: nil,
+		},
+		{
+			name: "approvals stored in the source database by default",
+			config: YAMLConfig{
+				Source: SourceConfig{
+					Postgres: &PostgresConfig{URL: "source-url"},
+				},
+				Modifiers: ModifiersConfig{
+					DDLPolicy: &DDLPolicyConfig{Rules: rules},
+				},
+			},
+			wantConfig: &ddlpolicy.Config{
+				Rules:       wantRules,
+				ApprovalURL: "source-url",
+			},
+		},
+		{
+			name: "approval url and poll interval",
+			config: YAMLConfig{
+				Source: SourceConfig{
+					Postgres: &PostgresConfig{URL: "source-url"},
+				},
+				Modifiers: ModifiersConfig{
+					DDLPolicy: &DDLPolicyConfig{
+						ApprovalURL:          "approval-url",
+						ApprovalPollInterval: 10,
+						Rules:                rules,
+					},
+				},
+			},
+			wantConfig: &ddlpolicy.Config{
+				Rules:                wantRules,
+				ApprovalURL:          "approval-url",
+				ApprovalPollInterval: 10 * time.Second,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.wantConfig, tc.config.parseDDLPolicyConfig())
+		})
+	}
+}
+
+func TestYAMLConfig_parseRoleSyncConfig(t *testing.T) {
+	t.Parallel()
+
+	newConfig := func(rolesSnapshotMode string) YAMLConfig {
+		return YAMLConfig{
+			Source: SourceConfig{
+				Postgres: &PostgresConfig{
+					URL: "source-url",
+					Snapshot: &SnapshotConfig{
+						Schema: &SnapshotSchemaConfig{
+							PgDumpPgRestore: &PgDumpPgRestoreConfig{RolesSnapshotMode: rolesSnapshotMode},
+						},
+					},
+				},
+			},
+			Target: TargetConfig{
+				Postgres: &PostgresTargetConfig{
+					RoleSync: &RoleSyncConfig{Enabled: true, Interval: 30},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		config YAMLConfig
+
+		wantConfig postgres.RoleSyncConfig
+	}{
+		{
+			name: "role sync not enabled",
+			config: YAMLConfig{
+				Target: TargetConfig{Postgres: &PostgresTargetConfig{}},
+			},
+			wantConfig: postgres.RoleSyncConfig{},
+		},
+		{
+			name:   "roles snapshot mode followed",
+			config: newConfig("enabled"),
+			wantConfig: postgres.RoleSyncConfig{
+				SourceURL: "source-url",
+				Mode:      "enabled",
+				Interval:  30 * time.Second,
+			},
+		},
+		{
+			name:       "roles snapshot disabled",
+			config:     newConfig("disabled"),
+			wantConfig: postgres.RoleSyncConfig{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.wantConfig, tc.config.parseRoleSyncConfig())
+		})
+	}
+}
+
 func TestInstrumentationConfig_toOtelConfig(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/xataio/pgstream/cmd/config"
+	"github.com/xataio/pgstream/pkg/wal/processor/ddlpolicy"
+)
+
+var ddlCmd = &cobra.Command{
+	Use:   "ddl",
+	Short: "Manages the DDL changes paused by the DDL policy pending approval",
+}
+
+var ddlListCmd = &cobra.Command{
+	Use:    "list",
+	Short:  "Lists the DDL changes paused by the DDL policy",
+	PreRun: ddlFlagBinding,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		status := ddlpolicy.ApprovalStatus(cmd.Flags().Lookup("status").Value.String())
+		switch status {
+		case "", ddlpolicy.ApprovalPending, ddlpolicy.ApprovalApproved, ddlpolicy.ApprovalRejected:
+		default:
+			return fmt.Errorf("invalid status %q, must be one of pending, approved or rejected", status)
+		}
+
+		return withApprovalStore(func(ctx context.Context, store *ddlpolicy.ApprovalStore) error {
+			approvals, err := store.List(ctx, status)
+			if err != nil {
+				return err
+			}
+			return print(cmd, ddlApprovals(approvals))
+		})
+	},
+	Example: `
+	pgstream ddl list -c pg2pg.yaml
+	pgstream ddl list --postgres-url <postgres-url> --status pending --json
+	`,
+}
+
+var ddlApproveCmd = &cobra.Command{
+	Use:    "approve <id>",
+	Short:  "Approves a paused DDL change, allowing it to be replicated",
+	Args:   cobra.ExactArgs(1),
+	PreRun: ddlFlagBinding,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return decideDDLApproval(args[0], "approved", (*ddlpolicy.ApprovalStore).Approve)
+	},
+	Example: `
+	pgstream ddl approve 1 -c pg2pg.yaml
+	`,
+}
+
+var ddlRejectCmd = &cobra.Command{
+	Use:    "reject <id>",
+	Short:  "Rejects a paused DDL change, skipping it",
+	Args:   cobra.ExactArgs(1),
+	PreRun: ddlFlagBinding,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return decideDDLApproval(args[0], "rejected", (*ddlpolicy.ApprovalStore).Reject)
+	},
+	Example: `
+	pgstream ddl reject 1 -c pg2pg.yaml
+	`,
+}
+
+type ddlApprovals []*ddlpolicy.Approval
+
+func (a ddlApprovals) PrettyPrint() string {
+	if len(a) == 0 {
+		return "no DDL changes found"
+	}
+	var sb strings.Builder
+	for _, approval := range a {
+		fmt.Fprintf(&sb, "[%d] %s (%s) at %s\n\t%s\n", approval.ID, approval.CommandTag, approval.Status, approval.Position, approval.DDL)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func decideDDLApproval(idArg, decision string, decide func(*ddlpolicy.ApprovalStore, context.Context, int64) error) error {
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid DDL approval id %q: %w", idArg, err)
+	}
+
+	sp, _ := pterm.DefaultSpinner.WithText(fmt.Sprintf("updating DDL change %d...", id)).Start()
+	err = withApprovalStore(func(ctx context.Context, store *ddlpolicy.ApprovalStore) error {
+		return decide(store, ctx, id)
+	})
+	if err != nil {
+		sp.Fail(err.Error())
+		return err
+	}
+	sp.Success(fmt.Sprintf("DDL change %d %s", id, decision))
+	return nil
+}
+
+func withApprovalStore(fn func(context.Context, *ddlpolicy.ApprovalStore) error) error {
+	ctx := context.Background()
+	url, err := ddlApprovalURL()
+	if err != nil {
+		return err
+	}
+
+	store, err := ddlpolicy.NewApprovalStore(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer store.Close(ctx)
+
+	return fn(ctx, store)
+}
+
+// ddlApprovalURL returns the URL of the database where the DDL approvals are
+// stored, which defaults to the source database.
+func ddlApprovalURL() (string, error) {
+	streamConfig, err := config.ParseStreamConfig()
+	if err != nil {
+		return "", fmt.Errorf("parsing stream config: %w", err)
+	}
+	if streamConfig.Processor.DDLPolicy != nil && streamConfig.Processor.DDLPolicy.ApprovalURL != "" {
+		return streamConfig.Processor.DDLPolicy.ApprovalURL, nil
+	}
+	if url := streamConfig.SourcePostgresURL(); url != "" {
+		return url, nil
+	}
+	return "", errors.New("postgres URL required to manage DDL approvals")
+}
+
+func ddlFlagBinding(cmd *cobra.Command, _ []string) {
+	// to be able to overwrite configuration with flags when yaml config file is
+	// provided
+	viper.BindPFlag("source.postgres.url", cmd.Flags().Lookup("postgres-url"))
+
+	// to be able to overwrite configuration with flags when env config file is
+	// provided or when no configuration is provided
+	viper.BindPFlag("PGSTREAM_POSTGRES_LISTENER_URL", cmd.Flags().Lookup("postgres-url"))
+}
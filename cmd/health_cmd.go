@@ -11,6 +11,9 @@ import (
 	"github.com/xataio/pgstream/internal/health"
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	loglib "github.com/xataio/pgstream/pkg/log"
+	snapshotlib "github.com/xataio/pgstream/pkg/snapshot"
+	snapshotstore "github.com/xataio/pgstream/pkg/snapshot/store"
+	pgsnapshotstore "github.com/xataio/pgstream/pkg/snapshot/store/postgres"
 )
 
 const (
@@ -28,8 +31,9 @@ const (
 // fails the command immediately rather than silently degrading.
 //
 // sourcePostgresURL, when non-empty, wires the /ready endpoint to ping the
-// source database.
-func startHealthServer(ctx context.Context, logger loglib.Logger, sourcePostgresURL string) (func(), error) {
+// source database. snapshotStoreURL, when non-empty, exposes the status of the
+// snapshot requests recorded in the snapshot store on /snapshots.
+func startHealthServer(ctx context.Context, logger loglib.Logger, sourcePostgresURL, snapshotStoreURL string) (func(), error) {
 	cfg, err := config.ParseHealthConfig()
 	if err != nil {
 		return nil, fmt.Errorf("parsing health config: %w", err)
@@ -52,11 +56,28 @@ func startHealthServer(ctx context.Context, logger loglib.Logger, sourcePostgres
 		opts = append(opts, health.WithReadinessCheck(pool.Ping))
 	}
 
+	var snapshotStore *pgsnapshotstore.Store
+	if snapshotStoreURL != "" {
+		snapshotStore, err = pgsnapshotstore.New(ctx, snapshotStoreURL)
+		if err != nil {
+			if pool != nil {
+				pool.Close(context.Background())
+			}
+			return nil, fmt.Errorf("creating health snapshot store: %w", err)
+		}
+		opts = append(opts, health.WithSnapshotStatus(func(ctx context.Context, status snapshotlib.Status) ([]*snapshotlib.TableStatus, error) {
+			return snapshotstore.GetTableStatuses(ctx, snapshotStore, status)
+		}))
+	}
+
 	srv := health.NewServer(*cfg, opts...)
 	if err := srv.Listen(); err != nil {
 		if pool != nil {
 			pool.Close(context.Background())
 		}
+		if snapshotStore != nil {
+			snapshotStore.Close()
+		}
 		return nil, fmt.Errorf("binding health server: %w", err)
 	}
 	go func() {
@@ -76,5 +97,10 @@ func startHealthServer(ctx context.Context, logger loglib.Logger, sourcePostgres
 				logger.Warn(err, "closing health readiness pool")
 			}
 		}
+		if snapshotStore != nil {
+			if err := snapshotStore.Close(); err != nil {
+				logger.Warn(err, "closing health snapshot store")
+			}
+		}
 	}, nil
 }
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/xataio/pgstream/cmd/config"
+	"github.com/xataio/pgstream/internal/log/zerolog"
+	"github.com/xataio/pgstream/pkg/stream"
+)
+
+var loadCmd = &cobra.Command{
+	Use:    "load <dir>",
+	Short:  "Load restores a snapshot archive directory into a PostgreSQL database",
+	Long:   "Load restores a snapshot archive directory, produced by a snapshot to the file target, into a PostgreSQL database. The archive checksums are verified before restoring the schema and loading the table data with the bulk ingest writer.",
+	Args:   cobra.ExactArgs(1),
+	PreRun: loadFlagBinding,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withSignalWatcher(func(ctx context.Context) error {
+			return load(ctx, args[0])
+		})(cmd, args)
+	},
+	Example: `  # Load a snapshot archive into a target PostgreSQL database
+  pgstream load ./snapshot --target-url <target-url>
+  # Load a snapshot archive using the target postgres settings of a YAML configuration file
+  pgstream load ./snapshot --config config.yaml`,
+}
+
+func load(ctx context.Context, dir string) error {
+	logger := zerolog.NewLogger(loggerConfigFromViper())
+	zerolog.SetGlobalLogger(logger)
+
+	streamConfig, err := config.ParseStreamConfig()
+	if err != nil {
+		return fmt.Errorf("parsing stream config: %w", err)
+	}
+	if streamConfig.Processor.Postgres == nil || streamConfig.Processor.Postgres.BatchWriter.URL == "" {
+		return errors.New("target postgres URL required to load a snapshot archive")
+	}
+
+	provider, err := newInstrumentationProvider()
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+
+	return stream.Load(ctx, zerolog.NewStdLogger(logger), &stream.LoadConfig{
+		Dir:      dir,
+		Postgres: streamConfig.Processor.Postgres.BatchWriter,
+	}, provider.NewInstrumentation("load"))
+}
+
+func loadFlagBinding(cmd *cobra.Command, _ []string) {
+	// to be able to overwrite configuration with flags when yaml config file is
+	// provided
+	viper.BindPFlag("target.postgres.url", cmd.Flags().Lookup("target-url"))
+	// the archive is loaded with the bulk ingest writer, default to disabling
+	// triggers as with snapshots if not set
+	if !viper.IsSet("target.postgres.disable_triggers") {
+		viper.Set("target.postgres.disable_triggers", true)
+	}
+
+	// to be able to overwrite configuration with flags when env config file is
+	// provided or when no configuration is provided
+	viper.BindPFlag("PGSTREAM_POSTGRES_WRITER_TARGET_URL", cmd.Flags().Lookup("target-url"))
+	if !viper.IsSet("PGSTREAM_POSTGRES_WRITER_DISABLE_TRIGGERS") {
+		viper.Set("PGSTREAM_POSTGRES_WRITER_DISABLE_TRIGGERS", true)
+	}
+}
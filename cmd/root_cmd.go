@@ -76,12 +76,17 @@ func Prepare() *cobra.Command {
 
 	// snapshot cmd
 	snapshotCmd.Flags().String("postgres-url", "", "Source postgres database to perform the snapshot from")
-	snapshotCmd.Flags().String("target", "", "Target type. One of postgres, opensearch, elasticsearch, kafka")
+	snapshotCmd.Flags().String("target", "", "Target type. One of postgres, opensearch, elasticsearch, kafka, file")
 	snapshotCmd.Flags().String("target-url", "", "Target URL")
 	snapshotCmd.Flags().StringSlice("tables", nil, "List of tables to snapshot, in the format <schema>.<table>. If not specified, the schema `public` will be assumed. Wildcards are supported")
 	snapshotCmd.Flags().Bool("reset", false, "Whether to reset the target before snapshotting (only for postgres target)")
 	snapshotCmd.Flags().Bool("profile", false, "Whether to produce CPU and memory profile files, as well as exposing a /debug/pprof endpoint on localhost:6060")
 	snapshotCmd.Flags().String("dump-file", "", "File where the pg_dump output will be written")
+	snapshotCmd.Flags().Bool("retry-failed", false, "Only snapshot the tables whose latest snapshot failed, as recorded by the snapshot recorder")
+	snapshotCmd.Flags().Bool("incremental", false, "Request an incremental snapshot of the tables to the pipeline replicating from the source database, instead of performing a one-time snapshot. Requires incremental snapshots to be enabled on the pipeline")
+
+	// load cmd
+	loadCmd.Flags().String("target-url", "", "Target postgres URL where the snapshot archive will be loaded")
 
 	// run cmd
 	runCmd.Flags().String("source", "", "Source type. One of postgres, kafka")
@@ -98,6 +103,12 @@ func Prepare() *cobra.Command {
 	runCmd.Flags().Bool("data-only", false, "When used with --snapshot-tables, skip schema restore and only snapshot data (use when schema is already present on target)")
 	runCmd.Flags().Bool("with-injector", false, "Whether to enable the injection of pgstream metadata to the WAL events. Required for search targets.")
 
+	// snapshot status cmd
+	snapshotStatusCmd.Flags().String("store-url", "", "Postgres URL where the snapshot recorder keeps the snapshot requests. Defaults to the configured snapshot recorder postgres URL")
+	snapshotStatusCmd.Flags().String("status", "", "Only list the snapshot tables with the given status. One of requested, 'in progress', completed, failed")
+	snapshotStatusCmd.Flags().Bool("json", false, "Output the snapshot status in JSON format")
+	snapshotCmd.AddCommand(snapshotStatusCmd)
+
 	// status cmd
 	statusCmd.Flags().String("postgres-url", "", "Source postgres URL where pgstream has been initialised")
 	statusCmd.Flags().String("replication-slot", "", "Name of the postgres replication slot created by pgstream on the source url")
@@ -120,6 +131,21 @@ func Prepare() *cobra.Command {
 	checkCmd.Flags().String("target-url", "", "Target URL to run checks against")
 	checkCmd.Flags().Bool("json", false, "Output the check report in JSON format")
 
+	// ddl cmd
+	ddlCmd.PersistentFlags().String("postgres-url", "", "Postgres URL where the DDL approvals are stored. Defaults to the source postgres URL")
+	ddlListCmd.Flags().String("status", "", "Only list the DDL changes with the given status. One of pending, approved, rejected")
+	ddlListCmd.Flags().Bool("json", false, "Output the DDL changes in JSON format")
+	ddlCmd.AddCommand(ddlListCmd)
+	ddlCmd.AddCommand(ddlApproveCmd)
+	ddlCmd.AddCommand(ddlRejectCmd)
+
+	// sequences cmd
+	sequencesSyncCmd.Flags().String("postgres-url", "", "Source postgres URL to read the sequence values from")
+	sequencesSyncCmd.Flags().String("target-url", "", "Target postgres URL where the sequences will be synced")
+	sequencesSyncCmd.Flags().StringSlice("schemas", nil, "List of schemas to sync the sequences for. All the schemas are synced if not specified")
+	sequencesSyncCmd.Flags().Int64("margin", 0, "Safety margin added to the source sequence values")
+	sequencesCmd.AddCommand(sequencesSyncCmd)
+
 	// Flag binding for root cmd
 	rootFlagBinding(rootCmd)
 
@@ -129,9 +155,12 @@ func Prepare() *cobra.Command {
 	rootCmd.AddCommand(tearDownCmd)
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(loadCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(validateCmd)
 	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(ddlCmd)
+	rootCmd.AddCommand(sequencesCmd)
 	return rootCmd
 }
 
@@ -70,7 +70,7 @@ func run(ctx context.Context) error {
 	}
 
 	stdLogger := zerolog.NewStdLogger(logger)
-	stopHealth, err := startHealthServer(ctx, stdLogger, streamConfig.SourcePostgresURL())
+	stopHealth, err := startHealthServer(ctx, stdLogger, streamConfig.SourcePostgresURL(), streamConfig.SnapshotStoreURL())
 	if err != nil {
 		return err
 	}
@@ -220,6 +220,9 @@ func targetFlagBinding(cmd *cobra.Command) error {
 		viper.BindPFlag("PGSTREAM_KAFKA_WRITER_SERVERS", cmd.Flags().Lookup("target-url"))
 		viper.Set("PGSTREAM_KAFKA_TOPIC_NAME", defaultKafkaTopicName)
 		viper.Set("PGSTREAM_KAFKA_TOPIC_AUTO_CREATE", true)
+	case "file":
+		viper.BindPFlag("target.file.dir", cmd.Flags().Lookup("target-url"))
+		viper.BindPFlag("PGSTREAM_FILE_WRITER_DIR", cmd.Flags().Lookup("target-url"))
 	default:
 		return errUnsupportedTarget
 	}
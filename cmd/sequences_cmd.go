@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/xataio/pgstream/cmd/config"
+	pgwriter "github.com/xataio/pgstream/pkg/wal/processor/postgres"
+)
+
+var sequencesCmd = &cobra.Command{
+	Use:   "sequences",
+	Short: "Manages the sequences of the postgres target",
+}
+
+var sequencesSyncCmd = &cobra.Command{
+	Use:    "sync",
+	Short:  "Syncs the values of the source sequences to the postgres target",
+	PreRun: sequencesFlagBinding,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sp, _ := pterm.DefaultSpinner.WithText("syncing sequences...").Start()
+
+		synced, err := func() (int, error) {
+			streamConfig, err := config.ParseStreamConfig()
+			if err != nil {
+				return 0, fmt.Errorf("parsing stream config: %w", err)
+			}
+
+			sourceURL := streamConfig.SourcePostgresURL()
+			if sourceURL == "" {
+				return 0, errors.New("source postgres URL required to sync sequences")
+			}
+			if streamConfig.Processor.Postgres == nil || streamConfig.Processor.Postgres.BatchWriter.URL == "" {
+				return 0, errors.New("target postgres URL required to sync sequences")
+			}
+			writerCfg := streamConfig.Processor.Postgres.BatchWriter
+
+			margin := writerCfg.SequenceSync.Margin
+			if cmd.Flags().Changed("margin") {
+				if margin, err = cmd.Flags().GetInt64("margin"); err != nil {
+					return 0, err
+				}
+			}
+
+			schemas, err := cmd.Flags().GetStringSlice("schemas")
+			if err != nil {
+				return 0, err
+			}
+
+			return pgwriter.SyncSequences(context.Background(), &pgwriter.SequenceSyncConfig{
+				SourceURL: sourceURL,
+				Margin:    margin,
+			}, writerCfg.URL, schemas)
+		}()
+		if err != nil {
+			sp.Fail(err.Error())
+			return err
+		}
+
+		sp.Success(fmt.Sprintf("synced %d sequences", synced))
+		return nil
+	},
+	Example: `
+	pgstream sequences sync -c pg2pg.yaml
+	pgstream sequences sync --postgres-url <source-postgres-url> --target-url <target-postgres-url> --margin 1000
+	pgstream sequences sync -c pg2pg.env --schemas public,sales
+	`,
+}
+
+func sequencesFlagBinding(cmd *cobra.Command, _ []string) {
+	// to be able to overwrite configuration with flags when yaml config file is
+	// provided
+	viper.BindPFlag("source.postgres.url", cmd.Flags().Lookup("postgres-url"))
+	viper.BindPFlag("target.postgres.url", cmd.Flags().Lookup("target-url"))
+
+	// to be able to overwrite configuration with flags when env config file is
+	// provided or when no configuration is provided
+	viper.BindPFlag("PGSTREAM_POSTGRES_LISTENER_URL", cmd.Flags().Lookup("postgres-url"))
+	viper.BindPFlag("PGSTREAM_POSTGRES_WRITER_TARGET_URL", cmd.Flags().Lookup("target-url"))
+}
@@ -4,13 +4,21 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/xataio/pgstream/cmd/config"
 	"github.com/xataio/pgstream/internal/log/zerolog"
+	snapshotlib "github.com/xataio/pgstream/pkg/snapshot"
+	snapshotstore "github.com/xataio/pgstream/pkg/snapshot/store"
+	pgsnapshotstore "github.com/xataio/pgstream/pkg/snapshot/store/postgres"
 	"github.com/xataio/pgstream/pkg/stream"
+	"github.com/xataio/pgstream/pkg/wal/processor/incremental"
 )
 
 var snapshotCmd = &cobra.Command{
@@ -18,13 +26,92 @@ var snapshotCmd = &cobra.Command{
 	Short:   "Snapshot performs a one-time data snapshot of a PostgreSQL database",
 	Long:    "Snapshot performs a one-time data snapshot of a PostgreSQL database. For continuous replication or combined snapshot+replication, use the 'run' command with --snapshot-tables flag.",
 	PreRunE: snapshotFlagBinding,
-	RunE:    withProfiling(withSignalWatcher(snapshot)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if incrementalSnapshot, _ := cmd.Flags().GetBool("incremental"); incrementalSnapshot {
+			return requestIncrementalSnapshot(cmd)
+		}
+		return withProfiling(withSignalWatcher(snapshot))(cmd, args)
+	},
 	Example: `  # Snapshot specific tables from a PostgreSQL database to a target PostgreSQL database
   pgstream snapshot --postgres-url <postgres-url> --target postgres --target-url <target-url> --tables <schema.table> --reset
   # Snapshot using a YAML configuration file (requires source.postgres.mode: 'snapshot')
   pgstream snapshot --config config.yaml --log-level info
   # Snapshot using an environment configuration file
-  pgstream snapshot --config config.env`,
+  pgstream snapshot --config config.env
+  # Request an incremental snapshot of specific tables to the pipeline replicating from the source database
+  pgstream snapshot --incremental --postgres-url <postgres-url> --tables <schema.table>
+  # Snapshot again only the tables whose latest snapshot failed, as recorded by the snapshot recorder
+  pgstream snapshot --config config.yaml --retry-failed`,
+}
+
+var snapshotStatusCmd = &cobra.Command{
+	Use:    "status",
+	Short:  "Lists the status of the snapshot requests recorded by the snapshot recorder",
+	Long:   "Lists the status of the tables of the latest snapshot requests recorded by the snapshot recorder, most recent first, along with their timings, row and byte counts and errors.",
+	PreRun: snapshotStatusFlagBinding,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		status, err := snapshotlib.ParseStatus(cmd.Flags().Lookup("status").Value.String())
+		if err != nil {
+			return err
+		}
+
+		storeURL := snapshotStoreURL()
+		if storeURL == "" {
+			return errors.New("snapshot recorder postgres URL required to list the snapshot status")
+		}
+
+		ctx := context.Background()
+		store, err := pgsnapshotstore.New(ctx, storeURL)
+		if err != nil {
+			return fmt.Errorf("creating snapshot store: %w", err)
+		}
+		defer store.Close()
+
+		tables, err := snapshotstore.GetTableStatuses(ctx, store, status)
+		if err != nil {
+			return err
+		}
+		return print(cmd, snapshotTableStatuses(tables))
+	},
+	Example: `
+	pgstream snapshot status -c pg2pg.yaml
+	pgstream snapshot status --store-url <postgres-url> --status failed --json
+	`,
+}
+
+type snapshotTableStatuses []*snapshotlib.TableStatus
+
+func (s snapshotTableStatuses) PrettyPrint() string {
+	if len(s) == 0 {
+		return "no snapshot requests found"
+	}
+	var sb strings.Builder
+	for _, table := range s {
+		fmt.Fprintf(&sb, "[%d] %s.%s (%s) requested at %s, updated at %s", table.RequestID, table.Schema, table.Table, table.Status,
+			table.RequestedAt.Format(time.RFC3339), table.UpdatedAt.Format(time.RFC3339))
+		if table.StartedAt != nil && table.FinishedAt != nil {
+			fmt.Fprintf(&sb, "\n\t%d rows, %d bytes in %s", table.Rows, table.Bytes, table.FinishedAt.Sub(*table.StartedAt).Round(time.Millisecond))
+		}
+		for _, err := range table.Errors {
+			fmt.Fprintf(&sb, "\n\terror: %s", err)
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// snapshotStoreURL returns the URL of the database where the snapshot recorder
+// keeps the snapshot requests.
+func snapshotStoreURL() string {
+	if url := viper.GetString("source.postgres.snapshot.recorder.postgres_url"); url != "" {
+		return url
+	}
+	return viper.GetString("PGSTREAM_POSTGRES_SNAPSHOT_STORE_URL")
+}
+
+func snapshotStatusFlagBinding(cmd *cobra.Command, _ []string) {
+	viper.BindPFlag("source.postgres.snapshot.recorder.postgres_url", cmd.Flags().Lookup("store-url"))
+	viper.BindPFlag("PGSTREAM_POSTGRES_SNAPSHOT_STORE_URL", cmd.Flags().Lookup("store-url"))
 }
 
 func snapshot(ctx context.Context) error {
@@ -43,7 +130,7 @@ func snapshot(ctx context.Context) error {
 	defer provider.Close()
 
 	stdLogger := zerolog.NewStdLogger(logger)
-	stopHealth, err := startHealthServer(ctx, stdLogger, streamConfig.SourcePostgresURL())
+	stopHealth, err := startHealthServer(ctx, stdLogger, streamConfig.SourcePostgresURL(), streamConfig.SnapshotStoreURL())
 	if err != nil {
 		return err
 	}
@@ -52,6 +139,41 @@ func snapshot(ctx context.Context) error {
 	return stream.Snapshot(ctx, stdLogger, streamConfig, provider.NewInstrumentation("snapshot"))
 }
 
+// requestIncrementalSnapshot requests an incremental snapshot of the tables on
+// input to the running pipelines replicating from the source database, which
+// need to have incremental snapshots enabled.
+func requestIncrementalSnapshot(cmd *cobra.Command) error {
+	sp, _ := pterm.DefaultSpinner.WithText("requesting incremental snapshot...").Start()
+
+	id, err := func() (string, error) {
+		tables, err := cmd.Flags().GetStringSlice("tables")
+		if err != nil {
+			return "", err
+		}
+		if len(tables) == 0 {
+			return "", errors.New("tables required for incremental snapshot")
+		}
+
+		streamConfig, err := config.ParseStreamConfig()
+		if err != nil {
+			return "", fmt.Errorf("parsing stream config: %w", err)
+		}
+		sourceURL := streamConfig.SourcePostgresURL()
+		if sourceURL == "" {
+			return "", errors.New("source postgres URL required for incremental snapshot")
+		}
+
+		return incremental.RequestSnapshot(context.Background(), sourceURL, tables)
+	}()
+	if err != nil {
+		sp.Fail(err.Error())
+		return err
+	}
+
+	sp.Success(fmt.Sprintf("incremental snapshot requested (signal id: %s)", id))
+	return nil
+}
+
 func snapshotFlagBinding(cmd *cobra.Command, args []string) error {
 	// to be able to overwrite configuration with flags when yaml config file is
 	// provided
@@ -59,6 +181,12 @@ func snapshotFlagBinding(cmd *cobra.Command, args []string) error {
 	viper.BindPFlag("source.postgres.snapshot.tables", cmd.Flags().Lookup("tables"))
 	viper.BindPFlag("source.postgres.snapshot.schema.pgdump_pgrestore.clean_target_db", cmd.Flags().Lookup("reset"))
 	viper.BindPFlag("source.postgres.snapshot.schema.pgdump_pgrestore.dump_file", cmd.Flags().Lookup("dump-file"))
+	viper.BindPFlag("source.postgres.snapshot.recorder.retry_failed", cmd.Flags().Lookup("retry-failed"))
+	// the failed tables are only known to the snapshot recorder
+	if retryFailed, _ := cmd.Flags().GetBool("retry-failed"); retryFailed && snapshotStoreURL() == "" {
+		return errors.New("snapshot recorder postgres URL required to retry the failed snapshot tables")
+	}
+
 	// if not explicitly set, default to repeatable for snapshot command if the
 	// snapshot recorder is provided
 	if viper.IsSet("source.postgres.snapshot.recorder") &&
@@ -82,6 +210,7 @@ func snapshotFlagBinding(cmd *cobra.Command, args []string) error {
 	viper.BindPFlag("PGSTREAM_POSTGRES_SNAPSHOT_TABLES", cmd.Flags().Lookup("tables"))
 	viper.BindPFlag("PGSTREAM_POSTGRES_SNAPSHOT_CLEAN_TARGET_DB", cmd.Flags().Lookup("reset"))
 	viper.BindPFlag("PGSTREAM_POSTGRES_SNAPSHOT_SCHEMA_DUMP_FILE", cmd.Flags().Lookup("dump-file"))
+	viper.BindPFlag("PGSTREAM_POSTGRES_SNAPSHOT_STORE_RETRY_FAILED", cmd.Flags().Lookup("retry-failed"))
 	// if not explicitly set, default to repeatable for snapshot command if the
 	// snapshot recorder is provided
 	if viper.GetString("PGSTREAM_POSTGRES_SNAPSHOT_STORE_URL") != "" &&
@@ -2,7 +2,9 @@
 
 // Package health exposes a small HTTP server with /health (liveness) and
 // /ready (readiness) endpoints so that operators and orchestrators can
-// monitor a running pgstream process.
+// monitor a running pgstream process. When a snapshot status function is
+// registered, the /snapshots endpoint reports the status of the snapshot
+// requests.
 package health
 
 import (
@@ -14,12 +16,14 @@ import (
 
 	"github.com/xataio/pgstream/internal/json"
 	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/snapshot"
 )
 
 const (
 	DefaultAddress = "localhost:9910"
 
 	readinessCheckTimeout = 2 * time.Second
+	snapshotStatusTimeout = 5 * time.Second
 	readHeaderTimeout     = 5 * time.Second
 )
 
@@ -35,10 +39,15 @@ type Server struct {
 	address        string
 	version        string
 	readinessCheck func(ctx context.Context) error
+	snapshotStatus SnapshotStatusFn
 	listener       net.Listener
 	httpServer     *http.Server
 }
 
+// SnapshotStatusFn returns the status of the snapshot tables, filtered by the
+// status on input if not empty.
+type SnapshotStatusFn func(ctx context.Context, status snapshot.Status) ([]*snapshot.TableStatus, error)
+
 var errNotListening = errors.New("health server not initialised: call Listen first")
 
 type Option func(*Server)
@@ -84,6 +93,15 @@ func WithReadinessCheck(fn func(ctx context.Context) error) Option {
 	}
 }
 
+// WithSnapshotStatus registers the /snapshots endpoint, which lists the status
+// of the snapshot tables returned by the function on input. The results can be
+// filtered with the status query parameter.
+func WithSnapshotStatus(fn SnapshotStatusFn) Option {
+	return func(s *Server) {
+		s.snapshotStatus = fn
+	}
+}
+
 // Listen binds the configured address synchronously and prepares the HTTP
 // server. After it returns successfully, Serve must be called (typically
 // from a goroutine) to start accepting requests. Splitting bind from serve
@@ -98,6 +116,9 @@ func (s *Server) Listen() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.handleHealth)
 	mux.HandleFunc("/ready", s.handleReady)
+	if s.snapshotStatus != nil {
+		mux.HandleFunc("/snapshots", s.handleSnapshots)
+	}
 
 	s.listener = ln
 	s.httpServer = &http.Server{
@@ -151,6 +172,24 @@ func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, s.okPayload())
 }
 
+func (s *Server) handleSnapshots(w http.ResponseWriter, r *http.Request) {
+	status, err := snapshot.ParseStatus(r.URL.Query().Get("status"))
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), snapshotStatusTimeout)
+	defer cancel()
+	tables, err := s.snapshotStatus(ctx, status)
+	if err != nil {
+		s.logger.Warn(err, "health server: getting snapshot status")
+		s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"tables": tables})
+}
+
 func (s *Server) okPayload() map[string]string {
 	payload := map[string]string{"status": "ok"}
 	if s.version != "" {
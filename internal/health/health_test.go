@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/snapshot"
 )
 
 func TestNewServer_DefaultAddress(t *testing.T) {
@@ -140,3 +141,66 @@ func TestListen_BindError(t *testing.T) {
 	second := NewServer(Config{Address: first.listener.Addr().String()})
 	require.Error(t, second.Listen())
 }
+
+func TestHandleSnapshots(t *testing.T) {
+	t.Parallel()
+
+	testTables := []*snapshot.TableStatus{
+		{RequestID: 1, Schema: "public", Table: "users", Status: snapshot.StatusFailed, Errors: []string{"oh noes"}},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		statusFn SnapshotStatusFn
+
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:  "ok",
+			query: "?status=failed",
+			statusFn: func(_ context.Context, status snapshot.Status) ([]*snapshot.TableStatus, error) {
+				require.Equal(t, snapshot.StatusFailed, status)
+				return testTables, nil
+			},
+
+			wantCode: http.StatusOK,
+			wantBody: `{"tables":[{"request_id":1,"schema":"public","table":"users","status":"failed","requested_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z","rows":0,"bytes":0,"errors":["oh noes"]}]}`,
+		},
+		{
+			name:  "invalid status",
+			query: "?status=unknown",
+			statusFn: func(_ context.Context, _ snapshot.Status) ([]*snapshot.TableStatus, error) {
+				return nil, errors.New("unexpected call to statusFn")
+			},
+
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"` + snapshot.ErrInvalidStatus.Error() + `"}`,
+		},
+		{
+			name: "error getting status",
+			statusFn: func(_ context.Context, _ snapshot.Status) ([]*snapshot.TableStatus, error) {
+				return nil, errors.New("oh noes")
+			},
+
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"error":"oh noes"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			s := NewServer(Config{}, WithSnapshotStatus(tc.statusFn))
+
+			req := httptest.NewRequest(http.MethodGet, "/snapshots"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			s.handleSnapshots(rec, req)
+
+			require.Equal(t, tc.wantCode, rec.Code)
+			body, _ := io.ReadAll(rec.Body)
+			require.JSONEq(t, tc.wantBody, string(body))
+		})
+	}
+}
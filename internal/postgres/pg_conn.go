@@ -14,11 +14,21 @@ type Conn struct {
 }
 
 func NewConn(ctx context.Context, url string) (*Conn, error) {
+	return newConn(ctx, url, "")
+}
+
+// newConn connects to the database on input instead of the one in the
+// connection string, if provided.
+func newConn(ctx context.Context, url, database string) (*Conn, error) {
 	pgCfg, err := ParseConfig(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed parsing postgres connection string: %w", MapError(err))
 	}
 
+	if database != "" {
+		pgCfg.Database = database
+	}
+
 	configureTCPKeepalive(pgCfg)
 
 	conn, err := pgx.ConnectConfig(ctx, pgCfg)
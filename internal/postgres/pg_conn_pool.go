@@ -50,6 +50,32 @@ func WithMaxConnections(maxConns int32) PoolOption {
 	}
 }
 
+// WithReplicationOrigin sets up the replication origin on input for the
+// sessions of the pool, creating it if it doesn't exist, so that the changes
+// they make can be identified (and filtered out) by logical decoding. Only one
+// session can use a replication origin at a time, so the pool is limited to a
+// single connection.
+func WithReplicationOrigin(origin string) PoolOption {
+	return func(cfg *pgxpool.Config) {
+		cfg.MaxConns = 1
+		afterConnect := cfg.AfterConnect
+		cfg.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			if afterConnect != nil {
+				if err := afterConnect(ctx, conn); err != nil {
+					return err
+				}
+			}
+			if _, err := conn.Exec(ctx, "SELECT pg_replication_origin_create($1) WHERE NOT EXISTS (SELECT 1 FROM pg_replication_origin WHERE roname = $1)", origin); err != nil {
+				return fmt.Errorf("creating replication origin %s: %w", origin, MapError(err))
+			}
+			if _, err := conn.Exec(ctx, "SELECT pg_replication_origin_session_setup($1)", origin); err != nil {
+				return fmt.Errorf("setting up replication origin %s: %w", origin, MapError(err))
+			}
+			return nil
+		}
+	}
+}
+
 func (c *Pool) QueryRow(ctx context.Context, dest []any, query string, args ...any) error {
 	row := c.Pool.QueryRow(ctx, query, args...)
 	return MapError(row.Scan(dest...))
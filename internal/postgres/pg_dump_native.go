@@ -0,0 +1,615 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// nativeCatalog holds the database objects introspected from the system
+// catalog, which are rendered into a plain text dump equivalent to the one
+// produced by pg_dump.
+type nativeCatalog struct {
+	database      *nativeDatabase
+	schemas       []*nativeSchema
+	extensions    []*nativeExtension
+	types         []*nativeType
+	functions     []*nativeFunction
+	sequences     []*nativeSequence
+	tables        []*nativeTable
+	policies      []*nativePolicy
+	views         []*nativeView
+	indexes       []*nativeIndex
+	constraints   []*nativeConstraint
+	triggers      []*nativeTrigger
+	eventTriggers []*nativeEventTrigger
+}
+
+// nativeObject holds the attributes shared by the catalog objects. The ident
+// is the quoted, and qualified when applicable, object identifier, while the
+// schema and name are the raw catalog names used for filtering. The owner is
+// quoted, and the access privileges are formatted as grantee=PRIVILEGE, with a
+// trailing * for privileges with grant option. Nil access privileges stand for
+// the default ones.
+type nativeObject struct {
+	schema  string
+	name    string
+	ident   string
+	owner   string
+	acl     []string
+	comment string
+}
+
+type nativeDatabase struct {
+	ident    string
+	owner    string
+	encoding string
+	collate  string
+	ctype    string
+}
+
+type nativeSchema struct {
+	nativeObject
+}
+
+type nativeExtension struct {
+	nativeObject
+	schemaIdent string
+}
+
+type nativeType struct {
+	nativeObject
+	// e enum, c composite, d domain, r range
+	kind string
+	// definition following the type name, such as AS ENUM ('a', 'b')
+	definition string
+}
+
+type nativeFunction struct {
+	nativeObject
+	// f function, p procedure, w window function
+	kind         string
+	identityArgs string
+	definition   string
+	// dependsOnRelations is true when the function depends on relations,
+	// either through table row types in its arguments or result or through
+	// the tables referenced by a SQL-standard (BEGIN ATOMIC) body, in which
+	// case the function is created after the tables.
+	dependsOnRelations bool
+}
+
+type nativeSequence struct {
+	nativeObject
+	dataType  string
+	start     int64
+	increment int64
+	minValue  int64
+	maxValue  int64
+	cache     int64
+	cycle     bool
+	// owning table and column, if any
+	ownedBySchema string
+	ownedByTable  string
+	ownedBy       string
+}
+
+type nativeTable struct {
+	nativeObject
+	oid int64
+	// r table, p partitioned table
+	kind           string
+	unlogged       bool
+	options        []string
+	partitionKey   string
+	partitionOf    string
+	partitionBound string
+	inherits       []string
+	columns        []*nativeColumn
+	checks         []string
+	// replica identity clause, empty for the default one
+	replicaIdentity  string
+	rowSecurity      bool
+	forceRowSecurity bool
+}
+
+type nativeColumn struct {
+	ident     string
+	dataType  string
+	collation string
+	// default expression, or generation expression for generated columns
+	defaultExpr string
+	notNull     bool
+	// a always, d by default
+	identity string
+	// s stored, v virtual
+	generated string
+	comment   string
+}
+
+type nativePolicy struct {
+	schema     string
+	table      string
+	tableIdent string
+	ident      string
+	permissive bool
+	command    string
+	roles      []string
+	using      string
+	withCheck  string
+}
+
+type nativeView struct {
+	nativeObject
+	oid          int64
+	materialized bool
+	options      []string
+	definition   string
+	dependencies []int64
+}
+
+type nativeIndex struct {
+	schema     string
+	table      string
+	ident      string
+	definition string
+	comment    string
+}
+
+type nativeConstraint struct {
+	schema      string
+	table       string
+	tableIdent  string
+	partitioned bool
+	ident       string
+	// p primary key, u unique, x exclusion, f foreign key, c check
+	kind       string
+	definition string
+	refSchema  string
+	refTable   string
+	comment    string
+}
+
+// isKey returns true for primary key and unique constraints.
+func (c *nativeConstraint) isKey() bool {
+	return c.kind == "p" || c.kind == "u"
+}
+
+type nativeTrigger struct {
+	schema     string
+	table      string
+	tableIdent string
+	ident      string
+	definition string
+	// O origin, D disabled, R replica, A always
+	enabled string
+	comment string
+}
+
+type nativeEventTrigger struct {
+	nativeObject
+	event    string
+	tags     []string
+	function string
+	// O origin, D disabled, R replica, A always
+	enabled string
+}
+
+const (
+	// nativeUserSchemaFilter excludes the system schemas, with the namespace
+	// aliased as n.
+	nativeUserSchemaFilter = `n.nspname <> 'information_schema' AND n.nspname !~ '^pg_'`
+
+	nativeDatabaseQuery = `SELECT quote_ident(d.datname), quote_ident(pg_get_userbyid(d.datdba)), pg_encoding_to_char(d.encoding), d.datcollate::text, d.datctype::text
+	FROM pg_database d WHERE d.datname = current_database()`
+
+	nativeExtensionsQuery = `SELECT e.extname, quote_ident(e.extname), n.nspname, quote_ident(n.nspname), COALESCE(obj_description(e.oid, 'pg_extension'), '')
+	FROM pg_extension e JOIN pg_namespace n ON n.oid = e.extnamespace
+	WHERE e.extname <> 'plpgsql'
+	ORDER BY e.extname`
+
+	nativeTableColumnsQuery = `SELECT a.attrelid::bigint, quote_ident(a.attname), format_type(a.atttypid, a.atttypmod),
+	CASE WHEN a.attcollation <> 0 AND a.attcollation <> t.typcollation THEN a.attcollation::regcollation::text ELSE '' END,
+	COALESCE(pg_get_expr(d.adbin, d.adrelid), ''), a.attnotnull, a.attidentity::text, a.attgenerated::text, COALESCE(col_description(a.attrelid, a.attnum), '')
+	FROM pg_attribute a
+	JOIN pg_class c ON c.oid = a.attrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	JOIN pg_type t ON t.oid = a.atttypid
+	LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+	WHERE c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped AND a.attislocal AND ` + nativeUserSchemaFilter + `
+	ORDER BY a.attrelid, a.attnum`
+
+	nativeTableChecksQuery = `SELECT c.conrelid::bigint, 'CONSTRAINT ' || quote_ident(c.conname) || ' ' || pg_get_constraintdef(c.oid)
+	FROM pg_constraint c
+	JOIN pg_class t ON t.oid = c.conrelid
+	JOIN pg_namespace n ON n.oid = t.relnamespace
+	WHERE c.contype = 'c' AND c.conislocal AND c.convalidated AND t.relkind IN ('r', 'p') AND ` + nativeUserSchemaFilter + `
+	ORDER BY c.conrelid, c.conname`
+
+	nativePoliciesQuery = `SELECT n.nspname, c.relname, quote_ident(n.nspname) || '.' || quote_ident(c.relname), quote_ident(p.polname), p.polpermissive, p.polcmd::text,
+	ARRAY(SELECT CASE WHEN r = 0 THEN 'PUBLIC' ELSE quote_ident(pg_get_userbyid(r)) END FROM unnest(p.polroles) r),
+	COALESCE(pg_get_expr(p.polqual, p.polrelid), ''), COALESCE(pg_get_expr(p.polwithcheck, p.polrelid), '')
+	FROM pg_policy p
+	JOIN pg_class c ON c.oid = p.polrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE ` + nativeUserSchemaFilter + `
+	ORDER BY n.nspname, c.relname, p.polname`
+
+	// nativeIndexesQuery excludes the indexes backing constraints, which are
+	// created along with them, and the indexes of partitions attached to the
+	// partitioned table indexes, which are created when the partitioned table
+	// index is.
+	nativeIndexesQuery = `SELECT n.nspname, t.relname, quote_ident(n.nspname) || '.' || quote_ident(ic.relname), pg_get_indexdef(i.indexrelid), COALESCE(obj_description(i.indexrelid, 'pg_class'), '')
+	FROM pg_index i
+	JOIN pg_class ic ON ic.oid = i.indexrelid
+	JOIN pg_class t ON t.oid = i.indrelid
+	JOIN pg_namespace n ON n.oid = t.relnamespace
+	WHERE t.relkind IN ('r', 'p', 'm') AND NOT ic.relispartition AND ` + nativeUserSchemaFilter + `
+	AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = i.indexrelid AND c.contype IN ('p', 'u', 'x'))
+	AND NOT EXISTS (SELECT 1 FROM pg_depend e WHERE e.classid = 'pg_class'::regclass AND e.objid = t.oid AND e.deptype = 'e')
+	ORDER BY n.nspname, t.relname, ic.relname`
+
+	// nativeConstraintsQuery returns the constraints that are added once the
+	// tables exist. Check constraints are created inline with the table,
+	// unless they are not valid. Constraints inherited by partitions are
+	// created along with the partitioned table constraints.
+	nativeConstraintsQuery = `SELECT n.nspname, t.relname, quote_ident(n.nspname) || '.' || quote_ident(t.relname), t.relkind = 'p', quote_ident(c.conname), c.contype::text, pg_get_constraintdef(c.oid),
+	COALESCE(rn.nspname, ''), COALESCE(r.relname, ''), COALESCE(obj_description(c.oid, 'pg_constraint'), '')
+	FROM pg_constraint c
+	JOIN pg_class t ON t.oid = c.conrelid
+	JOIN pg_namespace n ON n.oid = t.relnamespace
+	LEFT JOIN pg_class r ON r.oid = c.confrelid
+	LEFT JOIN pg_namespace rn ON rn.oid = r.relnamespace
+	WHERE (c.contype IN ('p', 'u', 'x', 'f') OR (c.contype = 'c' AND c.conislocal AND NOT c.convalidated))
+	AND c.conparentid = 0 AND t.relkind IN ('r', 'p') AND ` + nativeUserSchemaFilter + `
+	AND NOT EXISTS (SELECT 1 FROM pg_depend e WHERE e.classid = 'pg_class'::regclass AND e.objid = t.oid AND e.deptype = 'e')
+	ORDER BY c.contype = 'f', n.nspname, t.relname, c.conname`
+
+	nativeTriggersQuery = `SELECT n.nspname, t.relname, quote_ident(n.nspname) || '.' || quote_ident(t.relname), quote_ident(tg.tgname), pg_get_triggerdef(tg.oid), tg.tgenabled::text, COALESCE(obj_description(tg.oid, 'pg_trigger'), '')
+	FROM pg_trigger tg
+	JOIN pg_class t ON t.oid = tg.tgrelid
+	JOIN pg_namespace n ON n.oid = t.relnamespace
+	WHERE NOT tg.tgisinternal AND tg.tgparentid = 0 AND ` + nativeUserSchemaFilter + `
+	AND NOT EXISTS (SELECT 1 FROM pg_depend e WHERE e.classid = 'pg_class'::regclass AND e.objid = t.oid AND e.deptype = 'e')
+	ORDER BY n.nspname, t.relname, tg.tgname`
+
+	nativeEventTriggersQuery = `SELECT e.evtname, quote_ident(e.evtname), quote_ident(pg_get_userbyid(e.evtowner)), COALESCE(obj_description(e.oid, 'pg_event_trigger'), ''),
+	e.evtevent::text, COALESCE(e.evttags, '{}'::text[]), quote_ident(n.nspname) || '.' || quote_ident(p.proname), e.evtenabled::text
+	FROM pg_event_trigger e
+	JOIN pg_proc p ON p.oid = e.evtfoid
+	JOIN pg_namespace n ON n.oid = p.pronamespace
+	WHERE NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_event_trigger'::regclass AND d.objid = e.oid AND d.deptype = 'e')
+	ORDER BY e.evtname`
+
+	nativeSequenceNamesQuery = `SELECT n.nspname, c.relname, quote_ident(n.nspname) || '.' || quote_ident(c.relname)
+	FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE c.relkind = 'S' AND ` + nativeUserSchemaFilter + `
+	ORDER BY n.nspname, c.relname`
+
+	nativeSequenceValueQuery = "SELECT last_value, is_called FROM %s"
+)
+
+var (
+	nativeSchemasQuery = fmt.Sprintf(`SELECT n.nspname, quote_ident(n.nspname), quote_ident(pg_get_userbyid(n.nspowner)), %s, COALESCE(obj_description(n.oid, 'pg_namespace'), '')
+	FROM pg_namespace n
+	WHERE %s AND %s
+	ORDER BY n.nspname`, aclColumn("n.nspacl"), nativeUserSchemaFilter, notExtensionMember("pg_namespace", "n.oid"))
+
+	// nativeTypesQuery returns the enum, composite, domain and range types,
+	// in creation order. The type definition is built along with the type.
+	nativeTypesQuery = fmt.Sprintf(`SELECT n.nspname, t.typname, quote_ident(n.nspname) || '.' || quote_ident(t.typname), quote_ident(pg_get_userbyid(t.typowner)), %s, COALESCE(obj_description(t.oid, 'pg_type'), ''),
+	t.typtype::text,
+	CASE t.typtype
+	WHEN 'e' THEN 'AS ENUM (' || COALESCE((SELECT string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder) FROM pg_enum e WHERE e.enumtypid = t.oid), '') || ')'
+	WHEN 'c' THEN 'AS (' || COALESCE((SELECT string_agg(quote_ident(a.attname) || ' ' || format_type(a.atttypid, a.atttypmod) ||
+		CASE WHEN a.attcollation <> 0 AND a.attcollation <> at.typcollation THEN ' COLLATE ' || a.attcollation::regcollation::text ELSE '' END, ', ' ORDER BY a.attnum)
+		FROM pg_attribute a JOIN pg_type at ON at.oid = a.atttypid WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped), '') || ')'
+	WHEN 'r' THEN (SELECT 'AS RANGE (subtype = ' || format_type(r.rngsubtype, NULL) ||
+		CASE WHEN r.rngsubdiff <> 0 THEN ', subtype_diff = ' || r.rngsubdiff::regproc::text ELSE '' END || ')' FROM pg_range r WHERE r.rngtypid = t.oid)
+	ELSE 'AS ' || format_type(t.typbasetype, t.typtypmod) ||
+		CASE WHEN t.typcollation <> 0 AND t.typcollation <> (SELECT bt.typcollation FROM pg_type bt WHERE bt.oid = t.typbasetype) THEN ' COLLATE ' || t.typcollation::regcollation::text ELSE '' END ||
+		COALESCE(' DEFAULT ' || t.typdefault, '') ||
+		CASE WHEN t.typnotnull THEN ' NOT NULL' ELSE '' END ||
+		COALESCE((SELECT string_agg(' CONSTRAINT ' || quote_ident(c.conname) || ' ' || pg_get_constraintdef(c.oid), '' ORDER BY c.conname) FROM pg_constraint c WHERE c.contypid = t.oid AND c.contype = 'c'), '')
+	END
+	FROM pg_type t
+	JOIN pg_namespace n ON n.oid = t.typnamespace
+	WHERE (t.typtype IN ('e', 'd', 'r') OR (t.typtype = 'c' AND (SELECT c.relkind FROM pg_class c WHERE c.oid = t.typrelid) = 'c'))
+	AND %s AND %s
+	ORDER BY t.oid`, aclColumn("t.typacl"), nativeUserSchemaFilter, notExtensionMember("pg_type", "t.oid"))
+
+	// nativeFunctionsQuery returns the functions and procedures, in creation
+	// order. Aggregates are not supported.
+	nativeFunctionsQuery = fmt.Sprintf(`SELECT n.nspname, p.proname, quote_ident(n.nspname) || '.' || quote_ident(p.proname), quote_ident(pg_get_userbyid(p.proowner)), %s, COALESCE(obj_description(p.oid, 'pg_proc'), ''),
+	p.prokind::text, pg_get_function_identity_arguments(p.oid), pg_get_functiondef(p.oid),
+	EXISTS (SELECT 1 FROM pg_depend d
+		LEFT JOIN pg_type t ON d.refclassid = 'pg_type'::regclass AND t.oid = d.refobjid
+		LEFT JOIN pg_type et ON et.oid = t.typelem
+		JOIN pg_class c ON (d.refclassid = 'pg_class'::regclass AND c.oid = d.refobjid) OR c.oid IN (t.typrelid, et.typrelid)
+		WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND c.relkind <> 'c')
+	FROM pg_proc p
+	JOIN pg_namespace n ON n.oid = p.pronamespace
+	WHERE p.prokind <> 'a' AND %s AND %s
+	ORDER BY p.oid`, aclColumn("p.proacl"), nativeUserSchemaFilter, notExtensionMember("pg_proc", "p.oid"))
+
+	// nativeSequencesQuery returns the sequences along with their owning
+	// column, if any. Identity sequences are created along with their column.
+	nativeSequencesQuery = fmt.Sprintf(`SELECT n.nspname, c.relname, quote_ident(n.nspname) || '.' || quote_ident(c.relname), quote_ident(pg_get_userbyid(c.relowner)), %s, COALESCE(obj_description(c.oid, 'pg_class'), ''),
+	format_type(s.seqtypid, NULL), s.seqstart, s.seqincrement, s.seqmin, s.seqmax, s.seqcache, s.seqcycle,
+	COALESCE(tn.nspname, ''), COALESCE(t.relname, ''), COALESCE(quote_ident(tn.nspname) || '.' || quote_ident(t.relname) || '.' || quote_ident(a.attname), '')
+	FROM pg_sequence s
+	JOIN pg_class c ON c.oid = s.seqrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	LEFT JOIN pg_depend d ON d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.refclassid = 'pg_class'::regclass AND d.deptype = 'a'
+	LEFT JOIN pg_class t ON t.oid = d.refobjid
+	LEFT JOIN pg_namespace tn ON tn.oid = t.relnamespace
+	LEFT JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+	WHERE %s AND NOT EXISTS (SELECT 1 FROM pg_depend i WHERE i.classid = 'pg_class'::regclass AND i.objid = c.oid AND i.deptype IN ('i', 'e'))
+	ORDER BY n.nspname, c.relname`, aclColumn("c.relacl"), nativeUserSchemaFilter)
+
+	// nativeTablesQuery returns the tables, with the partitions after their
+	// partitioned tables.
+	nativeTablesQuery = fmt.Sprintf(`SELECT c.oid::bigint, n.nspname, c.relname, quote_ident(n.nspname) || '.' || quote_ident(c.relname), quote_ident(pg_get_userbyid(c.relowner)), %s, COALESCE(obj_description(c.oid, 'pg_class'), ''),
+	c.relkind::text, c.relpersistence = 'u', COALESCE(c.reloptions, '{}'::text[]),
+	CASE WHEN c.relkind = 'p' THEN pg_get_partkeydef(c.oid) ELSE '' END,
+	COALESCE((SELECT quote_ident(pn.nspname) || '.' || quote_ident(pc.relname) FROM pg_inherits i JOIN pg_class pc ON pc.oid = i.inhparent JOIN pg_namespace pn ON pn.oid = pc.relnamespace WHERE i.inhrelid = c.oid AND c.relispartition), ''),
+	CASE WHEN c.relispartition THEN pg_get_expr(c.relpartbound, c.oid) ELSE '' END,
+	ARRAY(SELECT quote_ident(pn.nspname) || '.' || quote_ident(pc.relname) FROM pg_inherits i JOIN pg_class pc ON pc.oid = i.inhparent JOIN pg_namespace pn ON pn.oid = pc.relnamespace WHERE i.inhrelid = c.oid AND NOT c.relispartition ORDER BY i.inhseqno),
+	CASE c.relreplident
+	WHEN 'n' THEN 'NOTHING'
+	WHEN 'f' THEN 'FULL'
+	WHEN 'i' THEN COALESCE((SELECT 'USING INDEX ' || quote_ident(ic.relname) FROM pg_index i JOIN pg_class ic ON ic.oid = i.indexrelid WHERE i.indrelid = c.oid AND i.indisreplident), '')
+	ELSE '' END,
+	c.relrowsecurity, c.relforcerowsecurity
+	FROM pg_class c
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE c.relkind IN ('r', 'p') AND %s AND %s
+	ORDER BY c.relispartition, CASE WHEN c.relispartition THEN (SELECT count(*) FROM pg_partition_ancestors(c.oid)) ELSE 0 END, c.oid`,
+		aclColumn("c.relacl"), nativeUserSchemaFilter, notExtensionMember("pg_class", "c.oid"))
+
+	// nativeViewsQuery returns the views and materialized views, along with
+	// the relations they depend on.
+	nativeViewsQuery = fmt.Sprintf(`SELECT c.oid::bigint, n.nspname, c.relname, quote_ident(n.nspname) || '.' || quote_ident(c.relname), quote_ident(pg_get_userbyid(c.relowner)), %s, COALESCE(obj_description(c.oid, 'pg_class'), ''),
+	c.relkind = 'm', COALESCE(c.reloptions, '{}'::text[]), pg_get_viewdef(c.oid),
+	ARRAY(SELECT DISTINCT d.refobjid::bigint FROM pg_rewrite r JOIN pg_depend d ON d.classid = 'pg_rewrite'::regclass AND d.objid = r.oid
+		WHERE r.ev_class = c.oid AND d.refclassid = 'pg_class'::regclass AND d.refobjid <> c.oid)
+	FROM pg_class c
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE c.relkind IN ('v', 'm') AND %s AND %s
+	ORDER BY c.oid`, aclColumn("c.relacl"), nativeUserSchemaFilter, notExtensionMember("pg_class", "c.oid"))
+)
+
+// aclColumn returns the expression formatting the access privileges column
+// on input as grantee=PRIVILEGE items, keeping nil access privileges as nil.
+func aclColumn(column string) string {
+	return fmt.Sprintf(`CASE WHEN %[1]s IS NULL THEN NULL ELSE ARRAY(SELECT CASE WHEN a.grantee = 0 THEN 'PUBLIC' ELSE quote_ident(pg_get_userbyid(a.grantee)) END || '=' || a.privilege_type || CASE WHEN a.is_grantable THEN '*' ELSE '' END FROM aclexplode(%[1]s) a) END`, column)
+}
+
+// notExtensionMember returns the condition excluding the objects created by
+// extensions, which are created along with the extension.
+func notExtensionMember(catalog, oid string) string {
+	return fmt.Sprintf("NOT EXISTS (SELECT 1 FROM pg_depend e WHERE e.classid = '%s'::regclass AND e.objid = %s AND e.deptype = 'e')", catalog, oid)
+}
+
+// RunNativePGDump produces a plain text dump with the given options by
+// introspecting the system catalog, without requiring the pg_dump binary. The
+// schema objects supported are schemas, extensions, types, functions,
+// sequences, tables, views, indexes, constraints, policies and triggers. Data
+// only dumps are limited to sequence values.
+func RunNativePGDump(ctx context.Context, opts PGDumpOptions) ([]byte, error) {
+	filter, err := newNativeDumpFilter(opts)
+	if err != nil {
+		return nil, fmt.Errorf("error running native pg_dump: %w", err)
+	}
+
+	conn, err := NewConn(ctx, opts.ConnectionString)
+	if err != nil {
+		return nil, fmt.Errorf("error running native pg_dump: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	if opts.Role != "" {
+		if _, err := conn.Exec(ctx, "SET ROLE "+QuoteIdentifier(opts.Role)); err != nil {
+			return nil, fmt.Errorf("error running native pg_dump: setting role: %w", err)
+		}
+	}
+
+	var dump []byte
+	err = conn.ExecInTxWithOptions(ctx, func(tx Tx) error {
+		// make sure all the object names in the definitions are qualified,
+		// since the dump is restored with an empty search path
+		if _, err := tx.Exec(ctx, "SELECT pg_catalog.set_config('search_path', '', true)"); err != nil {
+			return fmt.Errorf("setting search path: %w", err)
+		}
+
+		if opts.DataOnly {
+			dump, err = dumpNativeSequenceValues(ctx, tx, filter)
+			return err
+		}
+
+		catalog, err := loadNativeCatalog(ctx, tx, opts.Create)
+		if err != nil {
+			return err
+		}
+		dump = catalog.render(opts, filter)
+		return nil
+	}, TxOptions{IsolationLevel: RepeatableRead, AccessMode: ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("error running native pg_dump: %w", err)
+	}
+
+	return dump, nil
+}
+
+func loadNativeCatalog(ctx context.Context, tx Tx, withDatabase bool) (*nativeCatalog, error) {
+	c := &nativeCatalog{}
+	if withDatabase {
+		c.database = &nativeDatabase{}
+		if err := tx.QueryRow(ctx, []any{&c.database.ident, &c.database.owner, &c.database.encoding, &c.database.collate, &c.database.ctype}, nativeDatabaseQuery); err != nil {
+			return nil, fmt.Errorf("retrieving database: %w", err)
+		}
+	}
+
+	loaders := []struct {
+		objects string
+		query   string
+		scan    func(rows Rows) error
+	}{
+		{objects: "schemas", query: nativeSchemasQuery, scan: func(rows Rows) error {
+			s := &nativeSchema{}
+			c.schemas = append(c.schemas, s)
+			return rows.Scan(&s.name, &s.ident, &s.owner, &s.acl, &s.comment)
+		}},
+		{objects: "extensions", query: nativeExtensionsQuery, scan: func(rows Rows) error {
+			e := &nativeExtension{}
+			c.extensions = append(c.extensions, e)
+			return rows.Scan(&e.name, &e.ident, &e.schema, &e.schemaIdent, &e.comment)
+		}},
+		{objects: "types", query: nativeTypesQuery, scan: func(rows Rows) error {
+			t := &nativeType{}
+			c.types = append(c.types, t)
+			return rows.Scan(&t.schema, &t.name, &t.ident, &t.owner, &t.acl, &t.comment, &t.kind, &t.definition)
+		}},
+		{objects: "functions", query: nativeFunctionsQuery, scan: func(rows Rows) error {
+			f := &nativeFunction{}
+			c.functions = append(c.functions, f)
+			return rows.Scan(&f.schema, &f.name, &f.ident, &f.owner, &f.acl, &f.comment, &f.kind, &f.identityArgs, &f.definition, &f.dependsOnRelations)
+		}},
+		{objects: "sequences", query: nativeSequencesQuery, scan: func(rows Rows) error {
+			s := &nativeSequence{}
+			c.sequences = append(c.sequences, s)
+			return rows.Scan(&s.schema, &s.name, &s.ident, &s.owner, &s.acl, &s.comment,
+				&s.dataType, &s.start, &s.increment, &s.minValue, &s.maxValue, &s.cache, &s.cycle,
+				&s.ownedBySchema, &s.ownedByTable, &s.ownedBy)
+		}},
+		{objects: "tables", query: nativeTablesQuery, scan: func(rows Rows) error {
+			t := &nativeTable{}
+			c.tables = append(c.tables, t)
+			return rows.Scan(&t.oid, &t.schema, &t.name, &t.ident, &t.owner, &t.acl, &t.comment,
+				&t.kind, &t.unlogged, &t.options, &t.partitionKey, &t.partitionOf, &t.partitionBound, &t.inherits,
+				&t.replicaIdentity, &t.rowSecurity, &t.forceRowSecurity)
+		}},
+		{objects: "table columns", query: nativeTableColumnsQuery, scan: func(rows Rows) error {
+			var oid int64
+			col := &nativeColumn{}
+			if err := rows.Scan(&oid, &col.ident, &col.dataType, &col.collation, &col.defaultExpr, &col.notNull, &col.identity, &col.generated, &col.comment); err != nil {
+				return err
+			}
+			if t := c.table(oid); t != nil {
+				t.columns = append(t.columns, col)
+			}
+			return nil
+		}},
+		{objects: "table checks", query: nativeTableChecksQuery, scan: func(rows Rows) error {
+			var oid int64
+			var check string
+			if err := rows.Scan(&oid, &check); err != nil {
+				return err
+			}
+			if t := c.table(oid); t != nil {
+				t.checks = append(t.checks, check)
+			}
+			return nil
+		}},
+		{objects: "policies", query: nativePoliciesQuery, scan: func(rows Rows) error {
+			p := &nativePolicy{}
+			c.policies = append(c.policies, p)
+			return rows.Scan(&p.schema, &p.table, &p.tableIdent, &p.ident, &p.permissive, &p.command, &p.roles, &p.using, &p.withCheck)
+		}},
+		{objects: "views", query: nativeViewsQuery, scan: func(rows Rows) error {
+			v := &nativeView{}
+			c.views = append(c.views, v)
+			return rows.Scan(&v.oid, &v.schema, &v.name, &v.ident, &v.owner, &v.acl, &v.comment, &v.materialized, &v.options, &v.definition, &v.dependencies)
+		}},
+		{objects: "indexes", query: nativeIndexesQuery, scan: func(rows Rows) error {
+			i := &nativeIndex{}
+			c.indexes = append(c.indexes, i)
+			return rows.Scan(&i.schema, &i.table, &i.ident, &i.definition, &i.comment)
+		}},
+		{objects: "constraints", query: nativeConstraintsQuery, scan: func(rows Rows) error {
+			con := &nativeConstraint{}
+			c.constraints = append(c.constraints, con)
+			return rows.Scan(&con.schema, &con.table, &con.tableIdent, &con.partitioned, &con.ident, &con.kind, &con.definition, &con.refSchema, &con.refTable, &con.comment)
+		}},
+		{objects: "triggers", query: nativeTriggersQuery, scan: func(rows Rows) error {
+			t := &nativeTrigger{}
+			c.triggers = append(c.triggers, t)
+			return rows.Scan(&t.schema, &t.table, &t.tableIdent, &t.ident, &t.definition, &t.enabled, &t.comment)
+		}},
+		{objects: "event triggers", query: nativeEventTriggersQuery, scan: func(rows Rows) error {
+			e := &nativeEventTrigger{}
+			c.eventTriggers = append(c.eventTriggers, e)
+			return rows.Scan(&e.name, &e.ident, &e.owner, &e.comment, &e.event, &e.tags, &e.function, &e.enabled)
+		}},
+	}
+
+	for _, loader := range loaders {
+		if err := queryNativeCatalog(ctx, tx, loader.query, loader.scan); err != nil {
+			return nil, fmt.Errorf("retrieving %s: %w", loader.objects, err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *nativeCatalog) table(oid int64) *nativeTable {
+	for _, t := range c.tables {
+		if t.oid == oid {
+			return t
+		}
+	}
+	return nil
+}
+
+func queryNativeCatalog(ctx context.Context, tx Tx, query string, scan func(rows Rows) error) error {
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// dumpNativeSequenceValues returns the statements setting the current value
+// of the sequences selected by the filter.
+func dumpNativeSequenceValues(ctx context.Context, tx Tx, filter *nativeDumpFilter) ([]byte, error) {
+	sequences := []*nativeSequence{}
+	err := queryNativeCatalog(ctx, tx, nativeSequenceNamesQuery, func(rows Rows) error {
+		s := &nativeSequence{}
+		if err := rows.Scan(&s.schema, &s.name, &s.ident); err != nil {
+			return err
+		}
+		if filter.includeRelation(s.schema, s.name) {
+			sequences = append(sequences, s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("retrieving sequences: %w", err)
+	}
+
+	w := &nativeDumpWriter{}
+	w.header()
+	for _, s := range sequences {
+		var lastValue int64
+		var isCalled bool
+		if err := tx.QueryRow(ctx, []any{&lastValue, &isCalled}, fmt.Sprintf(nativeSequenceValueQuery, s.ident)); err != nil {
+			return nil, fmt.Errorf("retrieving sequence %s value: %w", s.ident, err)
+		}
+		w.statement("SELECT pg_catalog.setval(%s, %d, %t);", quoteLiteral(s.ident), lastValue, isCalled)
+	}
+
+	return []byte(w.String()), nil
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
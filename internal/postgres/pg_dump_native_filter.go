@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// nativeDumpFilter selects the catalog objects included in a native dump,
+// following the pg_dump schema and table selection options.
+type nativeDumpFilter struct {
+	schemas         []*dumpPattern
+	excludedSchemas []*dumpPattern
+	tables          []*dumpPattern
+	excludedTables  []*dumpPattern
+}
+
+// dumpPattern matches qualified object names. A nil schema matches any
+// schema.
+type dumpPattern struct {
+	schema *regexp.Regexp
+	name   *regexp.Regexp
+}
+
+func newNativeDumpFilter(opts PGDumpOptions) (*nativeDumpFilter, error) {
+	f := &nativeDumpFilter{}
+	var err error
+	if f.schemas, err = newSchemaPatterns(opts.Schemas); err != nil {
+		return nil, err
+	}
+	if f.excludedSchemas, err = newSchemaPatterns(opts.ExcludeSchemas); err != nil {
+		return nil, err
+	}
+	if f.tables, err = newTablePatterns(opts.Tables); err != nil {
+		return nil, err
+	}
+	if f.excludedTables, err = newTablePatterns(opts.ExcludeTables); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// global returns true when the dump is not restricted to schemas or tables,
+// in which case database wide objects, such as extensions or event triggers,
+// are included.
+func (f *nativeDumpFilter) global() bool {
+	return len(f.schemas) == 0 && len(f.tables) == 0
+}
+
+// relationsOnly returns true when the dump is restricted to tables, in which
+// case only relations are included, as with the pg_dump table option.
+func (f *nativeDumpFilter) relationsOnly() bool {
+	return len(f.tables) > 0
+}
+
+func (f *nativeDumpFilter) includeSchema(schema string) bool {
+	for _, p := range f.excludedSchemas {
+		if p.name.MatchString(schema) {
+			return false
+		}
+	}
+	if len(f.schemas) == 0 {
+		return true
+	}
+	for _, p := range f.schemas {
+		if p.name.MatchString(schema) {
+			return true
+		}
+	}
+	return false
+}
+
+// includeNonRelation returns true if the non relation object (type,
+// function...) in the schema on input is included in the dump.
+func (f *nativeDumpFilter) includeNonRelation(schema string) bool {
+	return !f.relationsOnly() && f.includeSchema(schema)
+}
+
+func (f *nativeDumpFilter) includeRelation(schema, name string) bool {
+	if !f.includeSchema(schema) {
+		return false
+	}
+	for _, p := range f.excludedTables {
+		if p.match(schema, name) {
+			return false
+		}
+	}
+	if len(f.tables) == 0 {
+		return true
+	}
+	for _, p := range f.tables {
+		if p.match(schema, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *dumpPattern) match(schema, name string) bool {
+	return (p.schema == nil || p.schema.MatchString(schema)) && p.name.MatchString(name)
+}
+
+func newSchemaPatterns(patterns []string) ([]*dumpPattern, error) {
+	schemaPatterns := make([]*dumpPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		parts, err := parseDumpPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) != 1 {
+			return nil, fmt.Errorf("invalid schema pattern %s: %w", pattern, errUnexpectedQualifiedName)
+		}
+		schemaPatterns = append(schemaPatterns, &dumpPattern{name: parts[0]})
+	}
+	return schemaPatterns, nil
+}
+
+func newTablePatterns(patterns []string) ([]*dumpPattern, error) {
+	tablePatterns := make([]*dumpPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		parts, err := parseDumpPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		switch len(parts) {
+		case 1:
+			tablePatterns = append(tablePatterns, &dumpPattern{name: parts[0]})
+		case 2:
+			tablePatterns = append(tablePatterns, &dumpPattern{schema: parts[0], name: parts[1]})
+		default:
+			return nil, fmt.Errorf("invalid table pattern %s: %w", pattern, errUnexpectedQualifiedName)
+		}
+	}
+	return tablePatterns, nil
+}
+
+// parseDumpPattern converts a pg_dump object name pattern into a regular
+// expression per dot separated name. As in pg_dump, unquoted names are folded
+// to lower case and use * and ? as wildcards, while quoted names are matched
+// literally.
+func parseDumpPattern(pattern string) ([]*regexp.Regexp, error) {
+	parts := []string{}
+	current := strings.Builder{}
+	inQuotes := false
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '"':
+			if inQuotes && i+1 < len(runes) && runes[i+1] == '"' {
+				current.WriteString(regexp.QuoteMeta(`"`))
+				i++
+				continue
+			}
+			inQuotes = !inQuotes
+		case inQuotes:
+			current.WriteString(regexp.QuoteMeta(string(r)))
+		case r == '.':
+			parts = append(parts, current.String())
+			current.Reset()
+		case r == '*':
+			current.WriteString(".*")
+		case r == '?':
+			current.WriteString(".")
+		default:
+			current.WriteString(regexp.QuoteMeta(strings.ToLower(string(r))))
+		}
+	}
+	parts = append(parts, current.String())
+
+	regexps := make([]*regexp.Regexp, 0, len(parts))
+	for _, part := range parts {
+		re, err := regexp.Compile("^(?:" + part + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps, nil
+}
@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNativeDumpFilter(t *testing.T) {
+	t.Parallel()
+
+	type relation struct {
+		schema string
+		name   string
+	}
+
+	tests := []struct {
+		name string
+		opts PGDumpOptions
+
+		wantGlobal            bool
+		wantSchemas           map[string]bool
+		wantRelations         map[relation]bool
+		wantNonRelationSchema map[string]bool
+	}{
+		{
+			name: "no filters",
+			opts: PGDumpOptions{},
+
+			wantGlobal:    true,
+			wantSchemas:   map[string]bool{"public": true, "other": true},
+			wantRelations: map[relation]bool{{"public", "users"}: true},
+		},
+		{
+			name: "schemas and excluded tables",
+			opts: PGDumpOptions{
+				Schemas:       []string{`"Sales"`, "public"},
+				ExcludeTables: []string{`"Sales"."Orders"`, "public.audit_*"},
+			},
+
+			wantGlobal:  false,
+			wantSchemas: map[string]bool{"Sales": true, "sales": false, "public": true, "other": false},
+			wantRelations: map[relation]bool{
+				{"Sales", "Orders"}:       false,
+				{"Sales", "Customers"}:    true,
+				{"public", "audit_2024"}:  false,
+				{"public", "audit"}:       true,
+				{"other", "audit_2024"}:   false,
+				{"public", "Audit_2024"}:  true,
+				{"public", "users"}:       true,
+				{"other", "users"}:        false,
+				{"public", "audit_2024x"}: false,
+			},
+			wantNonRelationSchema: map[string]bool{"Sales": true, "other": false},
+		},
+		{
+			name: "excluded schemas with wildcards",
+			opts: PGDumpOptions{
+				ExcludeSchemas: []string{`"pgstream"`, "tmp_?"},
+			},
+
+			wantGlobal:  true,
+			wantSchemas: map[string]bool{"pgstream": false, "tmp_1": false, "tmp_10": true, "public": true},
+		},
+		{
+			name: "tables",
+			opts: PGDumpOptions{
+				Tables: []string{`"public"."users_id_seq"`, "orders_id_seq"},
+			},
+
+			wantGlobal: false,
+			wantRelations: map[relation]bool{
+				{"public", "users_id_seq"}:  true,
+				{"public", "users"}:         false,
+				{"other", "orders_id_seq"}:  true,
+				{"public", "Users_id_seq"}:  false,
+				{"public", "orders_id_seq"}: true,
+			},
+			wantNonRelationSchema: map[string]bool{"public": false},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := newNativeDumpFilter(tc.opts)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.wantGlobal, f.global())
+			for schema, want := range tc.wantSchemas {
+				require.Equal(t, want, f.includeSchema(schema), schema)
+			}
+			for rel, want := range tc.wantRelations {
+				require.Equal(t, want, f.includeRelation(rel.schema, rel.name), rel)
+			}
+			for schema, want := range tc.wantNonRelationSchema {
+				require.Equal(t, want, f.includeNonRelation(schema), schema)
+			}
+		})
+	}
+}
+
+func TestNewNativeDumpFilter_error(t *testing.T) {
+	t.Parallel()
+
+	_, err := newNativeDumpFilter(PGDumpOptions{Schemas: []string{"public.users"}})
+	require.ErrorIs(t, err, errUnexpectedQualifiedName)
+
+	_, err = newNativeDumpFilter(PGDumpOptions{ExcludeTables: []string{"db.public.users"}})
+	require.ErrorIs(t, err, errUnexpectedQualifiedName)
+}
@@ -0,0 +1,596 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// nativeDumpWriter writes the dump statements, separated by blank lines as in
+// the pg_dump plain text format.
+type nativeDumpWriter struct {
+	strings.Builder
+}
+
+// nativeRenderer renders the catalog objects selected by the filter into a
+// plain text dump, following the pg_dump ordering and formatting so that the
+// dump can be processed the same way.
+type nativeRenderer struct {
+	w      *nativeDumpWriter
+	opts   PGDumpOptions
+	filter *nativeDumpFilter
+}
+
+const nativeDumpHeader = `--
+-- PostgreSQL database dump generated from the system catalog
+--
+
+SET statement_timeout = 0;
+SET lock_timeout = 0;
+SET client_encoding = 'UTF8';
+SET standard_conforming_strings = on;
+SELECT pg_catalog.set_config('search_path', '', false);
+SET check_function_bodies = false;
+SET client_min_messages = warning;`
+
+var (
+	aclDefaultPublicObjects = []string{"FUNCTION", "PROCEDURE", "TYPE", "DOMAIN"}
+
+	policyCommands = map[string]string{
+		"r": "SELECT",
+		"a": "INSERT",
+		"w": "UPDATE",
+		"d": "DELETE",
+	}
+
+	triggerEnabledClauses = map[string]string{
+		"D": "DISABLE",
+		"R": "ENABLE REPLICA",
+		"A": "ENABLE ALWAYS",
+	}
+)
+
+func (w *nativeDumpWriter) header() {
+	w.statement(nativeDumpHeader)
+}
+
+func (w *nativeDumpWriter) statement(format string, args ...any) {
+	fmt.Fprintf(w, format, args...)
+	w.WriteString("\n\n")
+}
+
+func (c *nativeCatalog) render(opts PGDumpOptions, filter *nativeDumpFilter) []byte {
+	r := &nativeRenderer{
+		w:      &nativeDumpWriter{},
+		opts:   opts,
+		filter: filter,
+	}
+	s := c.selected(filter)
+
+	r.w.header()
+	switch {
+	case opts.Create && s.database != nil:
+		// as with pg_dump, cleaning up a database that is created only
+		// requires dropping it
+		r.renderDatabase(s.database)
+	case opts.Clean:
+		r.renderDrops(s)
+	}
+
+	for _, schema := range s.schemas {
+		r.renderSchema(schema)
+	}
+	for _, ext := range s.extensions {
+		r.w.statement("CREATE EXTENSION IF NOT EXISTS %s WITH SCHEMA %s;", ext.ident, ext.schemaIdent)
+		r.comment("EXTENSION", ext.ident, ext.comment)
+	}
+	for _, t := range s.types {
+		r.renderType(t)
+	}
+	for _, f := range s.functions {
+		if !f.dependsOnRelations {
+			r.renderFunction(f)
+		}
+	}
+	for _, seq := range s.sequences {
+		r.renderSequence(seq)
+	}
+	for _, t := range s.tables {
+		r.renderTable(t)
+	}
+	for _, f := range s.functions {
+		if f.dependsOnRelations {
+			r.renderFunction(f)
+		}
+	}
+	for _, seq := range s.sequences {
+		if seq.ownedBy != "" && filter.includeRelation(seq.ownedBySchema, seq.ownedByTable) {
+			r.w.statement("ALTER SEQUENCE %s OWNED BY %s;", seq.ident, seq.ownedBy)
+		}
+	}
+	for _, p := range s.policies {
+		r.renderPolicy(p)
+	}
+	// views can rely on the functional dependency of the columns on the
+	// primary key (grouping by it while selecting other columns), so the
+	// primary and unique constraints must exist before them
+	for _, con := range s.constraints {
+		if con.isKey() {
+			r.renderConstraint(con)
+		}
+	}
+	for _, v := range s.views {
+		r.renderView(v)
+	}
+	for _, i := range s.indexes {
+		// indexes on partitioned tables are created on all their partitions
+		r.w.statement("%s;", strings.Replace(i.definition, " ON ONLY ", " ON ", 1))
+		r.comment("INDEX", i.ident, i.comment)
+	}
+	for _, con := range s.constraints {
+		if !con.isKey() {
+			r.renderConstraint(con)
+		}
+	}
+	for _, t := range s.tables {
+		if t.replicaIdentity != "" {
+			r.w.statement("ALTER TABLE ONLY %s REPLICA IDENTITY %s;", t.ident, t.replicaIdentity)
+		}
+	}
+	for _, t := range s.triggers {
+		r.w.statement("%s;", t.definition)
+		if clause, found := triggerEnabledClauses[t.enabled]; found {
+			r.w.statement("ALTER TABLE %s %s TRIGGER %s;", t.tableIdent, clause, t.ident)
+		}
+		r.comment("TRIGGER", t.ident+" ON "+t.tableIdent, t.comment)
+	}
+	for _, e := range s.eventTriggers {
+		r.renderEventTrigger(e)
+	}
+
+	return []byte(r.w.String())
+}
+
+// selected returns the catalog objects selected by the filter, with the views
+// sorted in dependency order.
+func (c *nativeCatalog) selected(f *nativeDumpFilter) *nativeCatalog {
+	s := &nativeCatalog{database: c.database}
+	for _, schema := range c.schemas {
+		// the public schema is created by default
+		if schema.name != "public" && f.includeNonRelation(schema.name) {
+			s.schemas = append(s.schemas, schema)
+		}
+	}
+	if f.global() {
+		s.extensions = c.extensions
+		s.eventTriggers = c.eventTriggers
+	}
+	for _, t := range c.types {
+		if f.includeNonRelation(t.schema) {
+			s.types = append(s.types, t)
+		}
+	}
+	for _, fn := range c.functions {
+		if f.includeNonRelation(fn.schema) {
+			s.functions = append(s.functions, fn)
+		}
+	}
+	for _, seq := range c.sequences {
+		if f.includeRelation(seq.schema, seq.name) {
+			s.sequences = append(s.sequences, seq)
+		}
+	}
+	for _, t := range c.tables {
+		if f.includeRelation(t.schema, t.name) {
+			s.tables = append(s.tables, t)
+		}
+	}
+	for _, p := range c.policies {
+		if f.includeRelation(p.schema, p.table) {
+			s.policies = append(s.policies, p)
+		}
+	}
+	views := []*nativeView{}
+	for _, v := range c.views {
+		if f.includeRelation(v.schema, v.name) {
+			views = append(views, v)
+		}
+	}
+	s.views = sortViews(views)
+	for _, i := range c.indexes {
+		if f.includeRelation(i.schema, i.table) {
+			s.indexes = append(s.indexes, i)
+		}
+	}
+	for _, con := range c.constraints {
+		// foreign keys referencing tables not in the dump can't be restored
+		if f.includeRelation(con.schema, con.table) && (con.kind != "f" || f.includeRelation(con.refSchema, con.refTable)) {
+			s.constraints = append(s.constraints, con)
+		}
+	}
+	for _, t := range c.triggers {
+		if f.includeRelation(t.schema, t.table) {
+			s.triggers = append(s.triggers, t)
+		}
+	}
+	return s
+}
+
+func (r *nativeRenderer) renderDatabase(db *nativeDatabase) {
+	if r.opts.Clean {
+		r.w.statement("DROP DATABASE IF EXISTS %s;", db.ident)
+	}
+	r.w.statement("CREATE DATABASE %s WITH TEMPLATE = template0 ENCODING = %s LC_COLLATE = %s LC_CTYPE = %s;",
+		db.ident, quoteLiteral(db.encoding), quoteLiteral(db.collate), quoteLiteral(db.ctype))
+	r.owner("DATABASE", db.ident, db.owner)
+	r.w.statement(`\connect %s`, db.ident)
+	r.w.header()
+}
+
+// renderDrops renders the statements dropping the objects in the dump, in
+// reverse creation order.
+func (r *nativeRenderer) renderDrops(s *nativeCatalog) {
+	for _, e := range slices.Backward(s.eventTriggers) {
+		r.w.statement("DROP EVENT TRIGGER IF EXISTS %s;", e.ident)
+	}
+	for _, t := range slices.Backward(s.triggers) {
+		r.w.statement("DROP TRIGGER IF EXISTS %s ON %s;", t.ident, t.tableIdent)
+	}
+	for _, con := range slices.Backward(s.constraints) {
+		r.w.statement("ALTER TABLE IF EXISTS %s%s DROP CONSTRAINT IF EXISTS %s;", only(con.partitioned), con.tableIdent, con.ident)
+	}
+	for _, i := range slices.Backward(s.indexes) {
+		r.w.statement("DROP INDEX IF EXISTS %s;", i.ident)
+	}
+	for _, v := range slices.Backward(s.views) {
+		r.w.statement("DROP %s IF EXISTS %s;", v.objectType(), v.ident)
+	}
+	for _, p := range slices.Backward(s.policies) {
+		r.w.statement("DROP POLICY IF EXISTS %s ON %s;", p.ident, p.tableIdent)
+	}
+	for _, f := range slices.Backward(s.functions) {
+		if f.dependsOnRelations {
+			r.w.statement("DROP %s IF EXISTS %s;", f.objectType(), f.signature())
+		}
+	}
+	for _, t := range slices.Backward(s.tables) {
+		r.w.statement("DROP TABLE IF EXISTS %s;", t.ident)
+	}
+	for _, seq := range slices.Backward(s.sequences) {
+		r.w.statement("DROP SEQUENCE IF EXISTS %s;", seq.ident)
+	}
+	for _, f := range slices.Backward(s.functions) {
+		if !f.dependsOnRelations {
+			r.w.statement("DROP %s IF EXISTS %s;", f.objectType(), f.signature())
+		}
+	}
+	for _, t := range slices.Backward(s.types) {
+		r.w.statement("DROP %s IF EXISTS %s;", t.objectType(), t.ident)
+	}
+	for _, ext := range slices.Backward(s.extensions) {
+		r.w.statement("DROP EXTENSION IF EXISTS %s;", ext.ident)
+	}
+	for _, schema := range slices.Backward(s.schemas) {
+		r.w.statement("DROP SCHEMA IF EXISTS %s;", schema.ident)
+	}
+}
+
+func (r *nativeRenderer) renderSchema(s *nativeSchema) {
+	r.w.statement("CREATE SCHEMA %s;", s.ident)
+	r.owner("SCHEMA", s.ident, s.owner)
+	r.comment("SCHEMA", s.ident, s.comment)
+	r.privileges("SCHEMA", s.ident, s.owner, s.acl)
+}
+
+func (r *nativeRenderer) renderType(t *nativeType) {
+	r.w.statement("CREATE %s %s %s;", t.objectType(), t.ident, t.definition)
+	r.owner(t.objectType(), t.ident, t.owner)
+	r.comment(t.objectType(), t.ident, t.comment)
+	r.privileges(t.objectType(), t.ident, t.owner, t.acl)
+}
+
+func (r *nativeRenderer) renderFunction(f *nativeFunction) {
+	r.w.statement("%s;", strings.TrimRight(f.definition, " \n"))
+	r.owner(f.objectType(), f.signature(), f.owner)
+	r.comment(f.objectType(), f.signature(), f.comment)
+	r.privileges(f.objectType(), f.signature(), f.owner, f.acl)
+}
+
+func (r *nativeRenderer) renderSequence(s *nativeSequence) {
+	lines := []string{
+		"CREATE SEQUENCE " + s.ident,
+		"    AS " + s.dataType,
+		fmt.Sprintf("    START WITH %d", s.start),
+		fmt.Sprintf("    INCREMENT BY %d", s.increment),
+		fmt.Sprintf("    MINVALUE %d", s.minValue),
+		fmt.Sprintf("    MAXVALUE %d", s.maxValue),
+		fmt.Sprintf("    CACHE %d", s.cache),
+	}
+	if s.cycle {
+		lines = append(lines, "    CYCLE")
+	}
+	r.w.statement("%s;", strings.Join(lines, "\n"))
+	r.owner("SEQUENCE", s.ident, s.owner)
+	r.comment("SEQUENCE", s.ident, s.comment)
+	r.privileges("SEQUENCE", s.ident, s.owner, s.acl)
+}
+
+func (r *nativeRenderer) renderTable(t *nativeTable) {
+	elements := make([]string, 0, len(t.columns)+len(t.checks))
+	for _, col := range t.columns {
+		elements = append(elements, "    "+col.definition())
+	}
+	for _, check := range t.checks {
+		elements = append(elements, "    "+check)
+	}
+
+	stmt := strings.Builder{}
+	stmt.WriteString("CREATE ")
+	if t.unlogged {
+		stmt.WriteString("UNLOGGED ")
+	}
+	stmt.WriteString("TABLE " + t.ident)
+	switch {
+	case t.partitionOf != "":
+		stmt.WriteString(" PARTITION OF " + t.partitionOf)
+		if len(elements) > 0 {
+			stmt.WriteString(" (\n" + strings.Join(elements, ",\n") + "\n)")
+		}
+		stmt.WriteString("\n" + t.partitionBound)
+	default:
+		stmt.WriteString(" (\n" + strings.Join(elements, ",\n") + "\n)")
+		if len(t.inherits) > 0 {
+			stmt.WriteString("\nINHERITS (" + strings.Join(t.inherits, ", ") + ")")
+		}
+	}
+	if t.partitionKey != "" {
+		stmt.WriteString("\nPARTITION BY " + t.partitionKey)
+	}
+	if len(t.options) > 0 {
+		stmt.WriteString("\nWITH (" + strings.Join(t.options, ", ") + ")")
+	}
+	r.w.statement("%s;", stmt.String())
+
+	r.owner("TABLE", t.ident, t.owner)
+	r.comment("TABLE", t.ident, t.comment)
+	for _, col := range t.columns {
+		r.comment("COLUMN", t.ident+"."+col.ident, col.comment)
+	}
+	r.privileges("TABLE", t.ident, t.owner, t.acl)
+	if t.rowSecurity {
+		r.w.statement("ALTER TABLE %s ENABLE ROW LEVEL SECURITY;", t.ident)
+	}
+	if t.forceRowSecurity {
+		r.w.statement("ALTER TABLE %s FORCE ROW LEVEL SECURITY;", t.ident)
+	}
+}
+
+func (r *nativeRenderer) renderPolicy(p *nativePolicy) {
+	stmt := strings.Builder{}
+	fmt.Fprintf(&stmt, "CREATE POLICY %s ON %s", p.ident, p.tableIdent)
+	if !p.permissive {
+		stmt.WriteString(" AS RESTRICTIVE")
+	}
+	if command, found := policyCommands[p.command]; found {
+		stmt.WriteString(" FOR " + command)
+	}
+	if len(p.roles) > 0 && !slices.Equal(p.roles, []string{"PUBLIC"}) {
+		stmt.WriteString(" TO " + strings.Join(p.roles, ", "))
+	}
+	if p.using != "" {
+		stmt.WriteString(" USING (" + p.using + ")")
+	}
+	if p.withCheck != "" {
+		stmt.WriteString(" WITH CHECK (" + p.withCheck + ")")
+	}
+	r.w.statement("%s;", stmt.String())
+}
+
+func (r *nativeRenderer) renderView(v *nativeView) {
+	with := ""
+	if len(v.options) > 0 {
+		with = " WITH (" + strings.Join(v.options, ", ") + ")"
+	}
+	definition := strings.TrimRight(v.definition, " \n;")
+	if v.materialized {
+		// as with pg_dump schema only dumps, materialized views are not
+		// populated
+		r.w.statement("CREATE MATERIALIZED VIEW %s%s AS\n%s\n  WITH NO DATA;", v.ident, with, definition)
+	} else {
+		r.w.statement("CREATE VIEW %s%s AS\n%s;", v.ident, with, definition)
+	}
+	r.owner(v.objectType(), v.ident, v.owner)
+	r.comment(v.objectType(), v.ident, v.comment)
+	r.privileges("TABLE", v.ident, v.owner, v.acl)
+}
+
+func (r *nativeRenderer) renderConstraint(con *nativeConstraint) {
+	r.w.statement("ALTER TABLE %s%s\n    ADD CONSTRAINT %s %s;", only(con.partitioned), con.tableIdent, con.ident, con.definition)
+	r.comment("CONSTRAINT", con.ident+" ON "+con.tableIdent, con.comment)
+}
+
+func (r *nativeRenderer) renderEventTrigger(e *nativeEventTrigger) {
+	when := ""
+	if len(e.tags) > 0 {
+		tags := make([]string, 0, len(e.tags))
+		for _, tag := range e.tags {
+			tags = append(tags, quoteLiteral(tag))
+		}
+		when = " WHEN TAG IN (" + strings.Join(tags, ", ") + ")"
+	}
+	r.w.statement("CREATE EVENT TRIGGER %s ON %s%s EXECUTE FUNCTION %s();", e.ident, e.event, when, e.function)
+	if clause, found := triggerEnabledClauses[e.enabled]; found {
+		r.w.statement("ALTER EVENT TRIGGER %s %s;", e.ident, clause)
+	}
+	r.owner("EVENT TRIGGER", e.ident, e.owner)
+	r.comment("EVENT TRIGGER", e.ident, e.comment)
+}
+
+func (r *nativeRenderer) owner(objectType, ident, owner string) {
+	if r.opts.NoOwner || owner == "" {
+		return
+	}
+	r.w.statement("ALTER %s %s OWNER TO %s;", objectType, ident, owner)
+}
+
+func (r *nativeRenderer) comment(objectType, ident, comment string) {
+	if comment == "" {
+		return
+	}
+	r.w.statement("COMMENT ON %s %s IS %s;", objectType, ident, quoteLiteral(comment))
+}
+
+func (r *nativeRenderer) privileges(objectType, ident, owner string, acl []string) {
+	if r.opts.NoPrivileges {
+		return
+	}
+	for _, stmt := range privilegeStatements(objectType, ident, owner, acl) {
+		r.w.statement("%s", stmt)
+	}
+}
+
+// privilegeStatements returns the statements granting the access privileges
+// on input for the object. Nil access privileges are the default ones, which
+// require no statements. The owner privileges are implicit, and the default
+// PUBLIC privileges on functions and types are revoked when they are not part
+// of the access privileges.
+func privilegeStatements(objectType, ident, owner string, acl []string) []string {
+	if acl == nil {
+		return nil
+	}
+
+	type grant struct {
+		privileges          []string
+		grantablePrivileges []string
+	}
+	grantees := []string{}
+	grants := map[string]*grant{}
+	for _, item := range acl {
+		sep := strings.LastIndex(item, "=")
+		if sep == -1 {
+			continue
+		}
+		grantee, privilege := item[:sep], item[sep+1:]
+		if grantee == owner {
+			continue
+		}
+		g, found := grants[grantee]
+		if !found {
+			g = &grant{}
+			grants[grantee] = g
+			grantees = append(grantees, grantee)
+		}
+		if grantable, isGrantable := strings.CutSuffix(privilege, "*"); isGrantable {
+			g.grantablePrivileges = append(g.grantablePrivileges, grantable)
+			continue
+		}
+		g.privileges = append(g.privileges, privilege)
+	}
+
+	stmts := []string{}
+	if _, found := grants["PUBLIC"]; !found && slices.Contains(aclDefaultPublicObjects, objectType) {
+		stmts = append(stmts, fmt.Sprintf("REVOKE ALL ON %s %s FROM PUBLIC;", objectType, ident))
+	}
+	for _, grantee := range grantees {
+		g := grants[grantee]
+		if len(g.privileges) > 0 {
+			stmts = append(stmts, fmt.Sprintf("GRANT %s ON %s %s TO %s;", strings.Join(g.privileges, ","), objectType, ident, grantee))
+		}
+		if len(g.grantablePrivileges) > 0 {
+			stmts = append(stmts, fmt.Sprintf("GRANT %s ON %s %s TO %s WITH GRANT OPTION;", strings.Join(g.grantablePrivileges, ","), objectType, ident, grantee))
+		}
+	}
+	return stmts
+}
+
+// sortViews returns the views on input sorted so that views are created
+// after the views they depend on.
+func sortViews(views []*nativeView) []*nativeView {
+	viewsByOID := make(map[int64]*nativeView, len(views))
+	for _, v := range views {
+		viewsByOID[v.oid] = v
+	}
+
+	sorted := make([]*nativeView, 0, len(views))
+	visited := make(map[int64]bool, len(views))
+	var visit func(v *nativeView)
+	visit = func(v *nativeView) {
+		if visited[v.oid] {
+			return
+		}
+		visited[v.oid] = true
+		for _, dep := range v.dependencies {
+			if depView, found := viewsByOID[dep]; found {
+				visit(depView)
+			}
+		}
+		sorted = append(sorted, v)
+	}
+	for _, v := range views {
+		visit(v)
+	}
+	return sorted
+}
+
+func (c *nativeColumn) definition() string {
+	def := c.ident + " " + c.dataType
+	if c.collation != "" {
+		def += " COLLATE " + c.collation
+	}
+	switch c.generated {
+	case "s":
+		def += " GENERATED ALWAYS AS (" + c.defaultExpr + ") STORED"
+	case "v":
+		def += " GENERATED ALWAYS AS (" + c.defaultExpr + ") VIRTUAL"
+	default:
+		if c.defaultExpr != "" {
+			def += " DEFAULT " + c.defaultExpr
+		}
+	}
+	if c.notNull {
+		def += " NOT NULL"
+	}
+	switch c.identity {
+	case "a":
+		def += " GENERATED ALWAYS AS IDENTITY"
+	case "d":
+		def += " GENERATED BY DEFAULT AS IDENTITY"
+	}
+	return def
+}
+
+func (t *nativeType) objectType() string {
+	if t.kind == "d" {
+		return "DOMAIN"
+	}
+	return "TYPE"
+}
+
+func (f *nativeFunction) objectType() string {
+	if f.kind == "p" {
+		return "PROCEDURE"
+	}
+	return "FUNCTION"
+}
+
+func (f *nativeFunction) signature() string {
+	return f.ident + "(" + f.identityArgs + ")"
+}
+
+func (v *nativeView) objectType() string {
+	if v.materialized {
+		return "MATERIALIZED VIEW"
+	}
+	return "VIEW"
+}
+
+// only returns the ONLY clause for table alterations that shouldn't recurse
+// into child tables, which is not allowed for partitioned tables.
+func only(partitioned bool) string {
+	if partitioned {
+		return ""
+	}
+	return "ONLY "
+}
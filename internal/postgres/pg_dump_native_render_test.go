@@ -0,0 +1,276 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNativeCatalog_render(t *testing.T) {
+	t.Parallel()
+
+	testCatalog := func() *nativeCatalog {
+		return &nativeCatalog{
+			database: &nativeDatabase{ident: "test", owner: "postgres", encoding: "UTF8", collate: "C", ctype: "C"},
+			schemas: []*nativeSchema{
+				{nativeObject: nativeObject{name: "public", ident: "public", owner: "pg_database_owner"}},
+				{nativeObject: nativeObject{name: "Sales", ident: `"Sales"`, owner: "postgres", comment: "sales data"}},
+			},
+			types: []*nativeType{
+				{nativeObject: nativeObject{schema: "Sales", name: "status", ident: `"Sales".status`, owner: "postgres", acl: []string{"postgres=USAGE"}}, kind: "e", definition: "AS ENUM ('new', 'done')"},
+			},
+			sequences: []*nativeSequence{
+				{
+					nativeObject: nativeObject{schema: "Sales", name: "orders_id_seq", ident: `"Sales".orders_id_seq`, owner: "postgres"},
+					dataType:     "integer", start: 1, increment: 1, minValue: 1, maxValue: 2147483647, cache: 1,
+					ownedBySchema: "Sales", ownedByTable: "orders", ownedBy: `"Sales".orders.id`,
+				},
+			},
+			tables: []*nativeTable{
+				{
+					nativeObject: nativeObject{schema: "Sales", name: "orders", ident: `"Sales".orders`, owner: "postgres", acl: []string{"postgres=SELECT", "postgres=INSERT", "reader=SELECT", "PUBLIC=SELECT"}},
+					columns: []*nativeColumn{
+						{ident: "id", dataType: "integer", defaultExpr: `nextval('"Sales".orders_id_seq'::regclass)`, notNull: true},
+						{ident: "status", dataType: `"Sales".status`, comment: "order status"},
+					},
+					checks:          []string{"CONSTRAINT positive_id CHECK ((id > 0))"},
+					replicaIdentity: "FULL",
+				},
+			},
+			views: []*nativeView{
+				{nativeObject: nativeObject{schema: "Sales", name: "open_orders", ident: `"Sales".open_orders`, owner: "postgres"}, definition: " SELECT id, status\n   FROM \"Sales\".orders\n  GROUP BY id;"},
+			},
+			indexes: []*nativeIndex{
+				{schema: "Sales", table: "orders", ident: `"Sales".orders_status_idx`, definition: `CREATE INDEX orders_status_idx ON ONLY "Sales".orders USING btree (status)`},
+			},
+			constraints: []*nativeConstraint{
+				{schema: "Sales", table: "orders", tableIdent: `"Sales".orders`, ident: "orders_pkey", kind: "p", definition: "PRIMARY KEY (id)"},
+				{schema: "Sales", table: "orders", tableIdent: `"Sales".orders`, ident: "orders_customer_fkey", kind: "f", definition: `FOREIGN KEY (id) REFERENCES "Sales".customers(id)`, refSchema: "Sales", refTable: "customers"},
+			},
+			triggers: []*nativeTrigger{
+				{schema: "Sales", table: "orders", tableIdent: `"Sales".orders`, ident: "orders_audit", definition: `CREATE TRIGGER orders_audit AFTER INSERT ON "Sales".orders FOR EACH ROW EXECUTE FUNCTION "Sales".audit()`, enabled: "A"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts PGDumpOptions
+
+		wantDump string
+	}{
+		{
+			name: "schema with excluded table",
+			opts: PGDumpOptions{
+				Schemas:       []string{`"Sales"`},
+				ExcludeTables: []string{`"Sales".customers`},
+			},
+
+			wantDump: nativeDumpHeader + `
+
+CREATE SCHEMA "Sales";
+
+ALTER SCHEMA "Sales" OWNER TO postgres;
+
+COMMENT ON SCHEMA "Sales" IS 'sales data';
+
+CREATE TYPE "Sales".status AS ENUM ('new', 'done');
+
+ALTER TYPE "Sales".status OWNER TO postgres;
+
+REVOKE ALL ON TYPE "Sales".status FROM PUBLIC;
+
+CREATE SEQUENCE "Sales".orders_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    MINVALUE 1
+    MAXVALUE 2147483647
+    CACHE 1;
+
+ALTER SEQUENCE "Sales".orders_id_seq OWNER TO postgres;
+
+CREATE TABLE "Sales".orders (
+    id integer DEFAULT nextval('"Sales".orders_id_seq'::regclass) NOT NULL,
+    status "Sales".status,
+    CONSTRAINT positive_id CHECK ((id > 0))
+);
+
+ALTER TABLE "Sales".orders OWNER TO postgres;
+
+COMMENT ON COLUMN "Sales".orders.status IS 'order status';
+
+GRANT SELECT ON TABLE "Sales".orders TO reader;
+
+GRANT SELECT ON TABLE "Sales".orders TO PUBLIC;
+
+ALTER SEQUENCE "Sales".orders_id_seq OWNED BY "Sales".orders.id;
+
+ALTER TABLE ONLY "Sales".orders
+    ADD CONSTRAINT orders_pkey PRIMARY KEY (id);
+
+CREATE VIEW "Sales".open_orders AS
+ SELECT id, status
+   FROM "Sales".orders
+  GROUP BY id;
+
+ALTER VIEW "Sales".open_orders OWNER TO postgres;
+
+CREATE INDEX orders_status_idx ON "Sales".orders USING btree (status);
+
+ALTER TABLE ONLY "Sales".orders REPLICA IDENTITY FULL;
+
+CREATE TRIGGER orders_audit AFTER INSERT ON "Sales".orders FOR EACH ROW EXECUTE FUNCTION "Sales".audit();
+
+ALTER TABLE "Sales".orders ENABLE ALWAYS TRIGGER orders_audit;
+
+`,
+		},
+		{
+			name: "tables with no owner, no privileges and clean",
+			opts: PGDumpOptions{
+				Tables:       []string{`"Sales".orders`, `"Sales".customers`},
+				NoOwner:      true,
+				NoPrivileges: true,
+				Clean:        true,
+			},
+
+			wantDump: nativeDumpHeader + `
+
+DROP TRIGGER IF EXISTS orders_audit ON "Sales".orders;
+
+ALTER TABLE IF EXISTS ONLY "Sales".orders DROP CONSTRAINT IF EXISTS orders_customer_fkey;
+
+ALTER TABLE IF EXISTS ONLY "Sales".orders DROP CONSTRAINT IF EXISTS orders_pkey;
+
+DROP INDEX IF EXISTS "Sales".orders_status_idx;
+
+DROP TABLE IF EXISTS "Sales".orders;
+
+CREATE TABLE "Sales".orders (
+    id integer DEFAULT nextval('"Sales".orders_id_seq'::regclass) NOT NULL,
+    status "Sales".status,
+    CONSTRAINT positive_id CHECK ((id > 0))
+);
+
+COMMENT ON COLUMN "Sales".orders.status IS 'order status';
+
+ALTER TABLE ONLY "Sales".orders
+    ADD CONSTRAINT orders_pkey PRIMARY KEY (id);
+
+CREATE INDEX orders_status_idx ON "Sales".orders USING btree (status);
+
+ALTER TABLE ONLY "Sales".orders
+    ADD CONSTRAINT orders_customer_fkey FOREIGN KEY (id) REFERENCES "Sales".customers(id);
+
+ALTER TABLE ONLY "Sales".orders REPLICA IDENTITY FULL;
+
+CREATE TRIGGER orders_audit AFTER INSERT ON "Sales".orders FOR EACH ROW EXECUTE FUNCTION "Sales".audit();
+
+ALTER TABLE "Sales".orders ENABLE ALWAYS TRIGGER orders_audit;
+
+`,
+		},
+		{
+			name: "create database",
+			opts: PGDumpOptions{
+				Tables:  []string{"none"},
+				Create:  true,
+				Clean:   true,
+				NoOwner: true,
+			},
+
+			wantDump: nativeDumpHeader + `
+
+DROP DATABASE IF EXISTS test;
+
+CREATE DATABASE test WITH TEMPLATE = template0 ENCODING = 'UTF8' LC_COLLATE = 'C' LC_CTYPE = 'C';
+
+\connect test
+
+` + nativeDumpHeader + `
+
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			filter, err := newNativeDumpFilter(tc.opts)
+			require.NoError(t, err)
+
+			dump := testCatalog().render(tc.opts, filter)
+			require.Equal(t, tc.wantDump, string(dump))
+		})
+	}
+}
+
+func TestPrivilegeStatements(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		objectType string
+		acl        []string
+
+		wantStmts []string
+	}{
+		{
+			name:       "default privileges",
+			objectType: "FUNCTION",
+			acl:        nil,
+
+			wantStmts: nil,
+		},
+		{
+			name:       "function without public execute",
+			objectType: "FUNCTION",
+			acl:        []string{"owner=EXECUTE", "app=EXECUTE"},
+
+			wantStmts: []string{
+				"REVOKE ALL ON FUNCTION obj FROM PUBLIC;",
+				"GRANT EXECUTE ON FUNCTION obj TO app;",
+			},
+		},
+		{
+			name:       "table with grant option",
+			objectType: "TABLE",
+			acl:        []string{"owner=SELECT", "app=SELECT", "app=INSERT", "app=UPDATE*", `"My Role"=DELETE`},
+
+			wantStmts: []string{
+				"GRANT SELECT,INSERT ON TABLE obj TO app;",
+				"GRANT UPDATE ON TABLE obj TO app WITH GRANT OPTION;",
+				`GRANT DELETE ON TABLE obj TO "My Role";`,
+			},
+		},
+		{
+			name:       "owner only",
+			objectType: "SCHEMA",
+			acl:        []string{"owner=USAGE", "owner=CREATE"},
+
+			wantStmts: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.wantStmts, privilegeStatements(tc.objectType, "obj", "owner", tc.acl))
+		})
+	}
+}
+
+func TestSortViews(t *testing.T) {
+	t.Parallel()
+
+	v1 := &nativeView{oid: 1, dependencies: []int64{3}}
+	v2 := &nativeView{oid: 2}
+	v3 := &nativeView{oid: 3, dependencies: []int64{2, 100}}
+
+	require.Equal(t, []*nativeView{v2, v3, v1}, sortViews([]*nativeView{v1, v2, v3}))
+}
@@ -20,7 +20,7 @@ func Test_pgdump_pgrestore(t *testing.T) {
 
 	ctx := context.Background()
 
-	run := func(t *testing.T, format string) {
+	run := func(t *testing.T, format string, pgDumpFn PGDumpFn, pgRestoreFn PGRestoreFn) {
 		var sourcePGURL, targetPGURL string
 		cleanup, err := testcontainers.SetupPostgresContainer(ctx, &sourcePGURL, testcontainers.Postgres17)
 		require.NoError(t, err)
@@ -53,6 +53,9 @@ func Test_pgdump_pgrestore(t *testing.T) {
 		require.NoError(t, err)
 		_, err = sourceConn.Exec(ctx, fmt.Sprintf("create table %s.%s(id serial primary key, name text)", testSchema, testTable2))
 		require.NoError(t, err)
+		// SQL-standard function body, with semicolons inside
+		_, err = sourceConn.Exec(ctx, fmt.Sprintf("create function %s.add_one(i integer) returns integer language sql begin atomic select 1; select i + 1; end", testSchema))
+		require.NoError(t, err)
 		// insert data
 		_, err = sourceConn.Exec(ctx, fmt.Sprintf("insert into %s.%s(name) values('a'),('b'),('c')", testSchema, testTable1))
 		require.NoError(t, err)
@@ -67,7 +70,7 @@ func Test_pgdump_pgrestore(t *testing.T) {
 			Create:           true,
 		}
 
-		dump, err := RunPGDump(context.TODO(), pgdumpOpts)
+		dump, err := pgDumpFn(context.TODO(), pgdumpOpts)
 		require.NoError(t, err)
 
 		pgrestoreOpts := PGRestoreOptions{
@@ -78,7 +81,7 @@ func Test_pgdump_pgrestore(t *testing.T) {
 			Create:           true,
 		}
 
-		_, err = RunPGRestore(context.TODO(), pgrestoreOpts, dump)
+		_, err = pgRestoreFn(context.TODO(), pgrestoreOpts, dump)
 		require.NoError(t, err)
 
 		targetConn, err := NewConn(ctx, targetPGURL)
@@ -93,12 +96,20 @@ func Test_pgdump_pgrestore(t *testing.T) {
 		// test table 2 should not exist
 		err = targetConn.QueryRow(ctx, []any{&count}, fmt.Sprintf("select count(*) from %s.%s", testSchema, testTable2))
 		require.Error(t, err)
+		// the function should be restored whole
+		var result int
+		err = targetConn.QueryRow(ctx, []any{&result}, fmt.Sprintf("select %s.add_one(1)", testSchema))
+		require.NoError(t, err)
+		require.Equal(t, 2, result)
 	}
 
 	t.Run("custom format - pg_restore", func(t *testing.T) {
-		run(t, "c")
+		run(t, "c", RunPGDump, RunPGRestore)
 	})
 	t.Run("plain format - psql", func(t *testing.T) {
-		run(t, "p")
+		run(t, "p", RunPGDump, RunPGRestore)
+	})
+	t.Run("plain format - native", func(t *testing.T) {
+		run(t, "p", RunNativePGDump, RunNativePGRestore)
 	})
 }
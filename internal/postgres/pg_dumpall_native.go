@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type nativeRole struct {
+	ident       string
+	current     bool
+	superuser   bool
+	inherit     bool
+	createRole  bool
+	createDB    bool
+	login       bool
+	replication bool
+	bypassRLS   bool
+	connLimit   int32
+	validUntil  string
+	password    string
+	comment     string
+}
+
+type nativeRoleMembership struct {
+	role        string
+	member      string
+	adminOption bool
+	grantor     string
+}
+
+var errNativeDumpAllUnsupported = errors.New("native pg_dumpall only supports roles dumps")
+
+const (
+	// nativeRolesQuery returns the non predefined roles. The password is only
+	// available to superusers, and therefore queried separately.
+	nativeRolesQuery = `SELECT quote_ident(r.rolname), r.rolname = current_user, r.rolsuper, r.rolinherit, r.rolcreaterole, r.rolcreatedb, r.rolcanlogin, r.rolreplication, r.rolbypassrls,
+	r.rolconnlimit, COALESCE(r.rolvaliduntil::text, ''), %s, COALESCE(shobj_description(r.oid, 'pg_authid'), '')
+	FROM pg_roles r
+	WHERE r.rolname !~ '^pg_'
+	ORDER BY r.rolname`
+
+	nativeRolePasswordColumn = `COALESCE((SELECT a.rolpassword FROM pg_authid a WHERE a.oid = r.oid), '')`
+
+	nativeRoleMembershipsQuery = `SELECT quote_ident(r.rolname), quote_ident(m.rolname), a.admin_option, COALESCE(quote_ident(g.rolname), '')
+	FROM pg_auth_members a
+	JOIN pg_roles r ON r.oid = a.roleid
+	JOIN pg_roles m ON m.oid = a.member
+	LEFT JOIN pg_roles g ON g.oid = a.grantor
+	WHERE m.rolname !~ '^pg_'
+	ORDER BY r.rolname, m.rolname`
+)
+
+// RunNativePGDumpAll produces a plain text roles dump with the given options
+// by introspecting the system catalog, without requiring the pg_dumpall
+// binary. Only roles dumps are supported.
+func RunNativePGDumpAll(ctx context.Context, opts PGDumpAllOptions) ([]byte, error) {
+	if !opts.RolesOnly {
+		return nil, errNativeDumpAllUnsupported
+	}
+
+	conn, err := NewConn(ctx, opts.ConnectionString)
+	if err != nil {
+		return nil, fmt.Errorf("error running native pg_dumpall: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	if opts.Role != "" {
+		if _, err := conn.Exec(ctx, "SET ROLE "+QuoteIdentifier(opts.Role)); err != nil {
+			return nil, fmt.Errorf("error running native pg_dumpall: setting role: %w", err)
+		}
+	}
+
+	passwordColumn := nativeRolePasswordColumn
+	if opts.NoPasswords {
+		passwordColumn = "''"
+	}
+
+	roles := []*nativeRole{}
+	memberships := []*nativeRoleMembership{}
+	err = conn.ExecInTxWithOptions(ctx, func(tx Tx) error {
+		err := queryNativeCatalog(ctx, tx, fmt.Sprintf(nativeRolesQuery, passwordColumn), func(rows Rows) error {
+			r := &nativeRole{}
+			roles = append(roles, r)
+			return rows.Scan(&r.ident, &r.current, &r.superuser, &r.inherit, &r.createRole, &r.createDB, &r.login, &r.replication, &r.bypassRLS,
+				&r.connLimit, &r.validUntil, &r.password, &r.comment)
+		})
+		if err != nil {
+			return fmt.Errorf("retrieving roles: %w", err)
+		}
+
+		err = queryNativeCatalog(ctx, tx, nativeRoleMembershipsQuery, func(rows Rows) error {
+			m := &nativeRoleMembership{}
+			memberships = append(memberships, m)
+			return rows.Scan(&m.role, &m.member, &m.adminOption, &m.grantor)
+		})
+		if err != nil {
+			return fmt.Errorf("retrieving role memberships: %w", err)
+		}
+		return nil
+	}, TxOptions{IsolationLevel: RepeatableRead, AccessMode: ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("error running native pg_dumpall: %w", err)
+	}
+
+	return renderNativeRoles(roles, memberships, opts.Clean), nil
+}
+
+// renderNativeRoles renders the roles and their memberships following the
+// pg_dumpall format, one statement per line.
+func renderNativeRoles(roles []*nativeRole, memberships []*nativeRoleMembership, clean bool) []byte {
+	w := strings.Builder{}
+	w.WriteString("--\n-- Roles\n--\n\n")
+
+	if clean {
+		for _, r := range roles {
+			// as with pg_dumpall, the role used to dump is not dropped
+			if !r.current {
+				fmt.Fprintf(&w, "DROP ROLE IF EXISTS %s;\n", r.ident)
+			}
+		}
+	}
+
+	for _, r := range roles {
+		fmt.Fprintf(&w, "CREATE ROLE %s;\n", r.ident)
+		fmt.Fprintf(&w, "ALTER ROLE %s WITH %s;\n", r.ident, r.attributes())
+		if r.comment != "" {
+			fmt.Fprintf(&w, "COMMENT ON ROLE %s IS %s;\n", r.ident, quoteLiteral(r.comment))
+		}
+	}
+
+	if len(memberships) > 0 {
+		w.WriteString("\n--\n-- Role memberships\n--\n\n")
+	}
+	for _, m := range memberships {
+		fmt.Fprintf(&w, "GRANT %s TO %s", m.role, m.member)
+		if m.adminOption {
+			w.WriteString(" WITH ADMIN OPTION")
+		}
+		if m.grantor != "" {
+			fmt.Fprintf(&w, " GRANTED BY %s", m.grantor)
+		}
+		w.WriteString(";\n")
+	}
+
+	return []byte(w.String())
+}
+
+func (r *nativeRole) attributes() string {
+	attribute := func(enabled bool, name string) string {
+		if enabled {
+			return name
+		}
+		return "NO" + name
+	}
+
+	attrs := []string{
+		attribute(r.superuser, "SUPERUSER"),
+		attribute(r.inherit, "INHERIT"),
+		attribute(r.createRole, "CREATEROLE"),
+		attribute(r.createDB, "CREATEDB"),
+		attribute(r.login, "LOGIN"),
+		attribute(r.replication, "REPLICATION"),
+		attribute(r.bypassRLS, "BYPASSRLS"),
+	}
+	if r.connLimit != -1 {
+		attrs = append(attrs, fmt.Sprintf("CONNECTION LIMIT %d", r.connLimit))
+	}
+	if r.password != "" {
+		attrs = append(attrs, "PASSWORD "+quoteLiteral(r.password))
+	}
+	if r.validUntil != "" {
+		attrs = append(attrs, "VALID UNTIL "+quoteLiteral(r.validUntil))
+	}
+	return strings.Join(attrs, " ")
+}
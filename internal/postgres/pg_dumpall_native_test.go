@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderNativeRoles(t *testing.T) {
+	t.Parallel()
+
+	roles := []*nativeRole{
+		{ident: "postgres", current: true, superuser: true, inherit: true, createRole: true, createDB: true, login: true, replication: true, bypassRLS: true, connLimit: -1},
+		{ident: `"App User"`, inherit: true, login: true, connLimit: 10, password: "SCRAM-SHA-256$4096:abc", validUntil: "2030-01-01 00:00:00+00", comment: "app's role"},
+	}
+	memberships := []*nativeRoleMembership{
+		{role: "pg_read_all_data", member: `"App User"`, adminOption: true, grantor: "postgres"},
+	}
+
+	tests := []struct {
+		name  string
+		clean bool
+
+		wantDump string
+	}{
+		{
+			name:  "roles",
+			clean: false,
+
+			wantDump: `--
+-- Roles
+--
+
+CREATE ROLE postgres;
+ALTER ROLE postgres WITH SUPERUSER INHERIT CREATEROLE CREATEDB LOGIN REPLICATION BYPASSRLS;
+CREATE ROLE "App User";
+ALTER ROLE "App User" WITH NOSUPERUSER INHERIT NOCREATEROLE NOCREATEDB LOGIN NOREPLICATION NOBYPASSRLS CONNECTION LIMIT 10 PASSWORD 'SCRAM-SHA-256$4096:abc' VALID UNTIL '2030-01-01 00:00:00+00';
+COMMENT ON ROLE "App User" IS 'app''s role';
+
+--
+-- Role memberships
+--
+
+GRANT pg_read_all_data TO "App User" WITH ADMIN OPTION GRANTED BY postgres;
+`,
+		},
+		{
+			name:  "clean",
+			clean: true,
+
+			wantDump: `--
+-- Roles
+--
+
+DROP ROLE IF EXISTS "App User";
+CREATE ROLE postgres;
+ALTER ROLE postgres WITH SUPERUSER INHERIT CREATEROLE CREATEDB LOGIN REPLICATION BYPASSRLS;
+CREATE ROLE "App User";
+ALTER ROLE "App User" WITH NOSUPERUSER INHERIT NOCREATEROLE NOCREATEDB LOGIN NOREPLICATION NOBYPASSRLS CONNECTION LIMIT 10 PASSWORD 'SCRAM-SHA-256$4096:abc' VALID UNTIL '2030-01-01 00:00:00+00';
+COMMENT ON ROLE "App User" IS 'app''s role';
+
+--
+-- Role memberships
+--
+
+GRANT pg_read_all_data TO "App User" WITH ADMIN OPTION GRANTED BY postgres;
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.wantDump, string(renderNativeRoles(roles, memberships, tc.clean)))
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const psqlConnectCmd = `\connect`
+
+// RunNativePGRestore restores the plain text dump on input by executing its
+// statements, without requiring the pg_restore or psql binaries. As with psql,
+// the restore continues after a statement fails, and the errors are returned
+// once all statements have been executed. The \connect meta command is
+// supported, any other meta command is ignored.
+func RunNativePGRestore(ctx context.Context, opts PGRestoreOptions, dump []byte) (string, error) {
+	connectionString := opts.ConnectionString
+	// if the database is being created, make sure the connection string does
+	// not include it, since it doesn't exist yet.
+	if opts.Create {
+		var err error
+		connectionString, err = removeDatabaseFromConnectionString(connectionString)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	conn, err := NewConn(ctx, connectionString)
+	if err != nil {
+		return "", fmt.Errorf("error restoring dump: %w", err)
+	}
+	defer func() {
+		conn.Close(context.Background())
+	}()
+
+	errs := &PGRestoreErrors{}
+	for _, stmt := range splitSQLStatements(string(dump)) {
+		if isMetaCommand(stmt) {
+			database, isConnect := parseConnectCommand(stmt)
+			if !isConnect {
+				continue
+			}
+			dbConn, err := newConn(ctx, connectionString, database)
+			if err != nil {
+				return "", fmt.Errorf("error restoring dump: %w", err)
+			}
+			conn.Close(ctx)
+			conn = dbConn
+			continue
+		}
+
+		if _, err := conn.Exec(ctx, stmt); err != nil {
+			if ctx.Err() != nil {
+				return "", fmt.Errorf("error restoring dump: %w", ctx.Err())
+			}
+			errs.addError(parseErrorLine(err.Error()))
+		}
+	}
+
+	if errs.HasErrors() {
+		return "", fmt.Errorf("error restoring dump: %w", errs)
+	}
+
+	return "", nil
+}
+
+// splitSQLStatements splits the SQL script on input into statements, taking
+// into account quoted strings and identifiers, dollar quoted strings and
+// comments. The psql meta commands, starting with a backslash, are returned
+// as a separate statement per line. As psql does, semicolons inside BEGIN ...
+// END blocks, such as the BEGIN ATOMIC bodies of SQL-standard functions, don't
+// end the statement.
+func splitSQLStatements(script string) []string {
+	stmts := []string{}
+	current := strings.Builder{}
+	hasContent := false
+	// number of words in the current statement, and depth of the BEGIN/CASE
+	// ... END blocks open
+	wordCount, beginDepth := 0, 0
+	flush := func() {
+		if hasContent {
+			stmts = append(stmts, strings.TrimSpace(current.String()))
+		}
+		current.Reset()
+		hasContent = false
+		wordCount, beginDepth = 0, 0
+	}
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && !hasContent:
+			// meta command, until the end of the line
+			end := i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			current.Reset()
+			stmts = append(stmts, strings.TrimSpace(string(runes[i:end])))
+			i = end
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			// line comment, until the end of the line
+			end := i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			current.WriteString(string(runes[i:end]))
+			i = end - 1
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			// block comment, which can be nested
+			end, depth := i+2, 1
+			for end < len(runes) && depth > 0 {
+				switch {
+				case runes[end] == '/' && end+1 < len(runes) && runes[end+1] == '*':
+					depth++
+					end++
+				case runes[end] == '*' && end+1 < len(runes) && runes[end+1] == '/':
+					depth--
+					end++
+				}
+				end++
+			}
+			current.WriteString(string(runes[i:end]))
+			i = end - 1
+		case r == '\'' || r == '"':
+			// escape string constants allow backslash escaped quotes
+			escapes := r == '\'' && i > 0 && (runes[i-1] == 'E' || runes[i-1] == 'e') && (i == 1 || !isIdentifierRune(runes[i-2]))
+			end := i + 1
+			for end < len(runes) {
+				if escapes && runes[end] == '\\' {
+					end += 2
+					continue
+				}
+				if runes[end] == r {
+					// doubled quotes are escaped quotes
+					if end+1 < len(runes) && runes[end+1] == r {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end+1, len(runes))
+			current.WriteString(string(runes[i:end]))
+			hasContent = true
+			i = end - 1
+		case r == '$' && (i == 0 || !isIdentifierRune(runes[i-1])):
+			tag, isTag := dollarQuoteTag(runes[i:])
+			if !isTag {
+				current.WriteRune(r)
+				hasContent = true
+				continue
+			}
+			end := indexRunes(runes, i+len(tag), tag)
+			if end == -1 {
+				end = len(runes)
+			} else {
+				end += len(tag)
+			}
+			current.WriteString(string(runes[i:end]))
+			hasContent = true
+			i = end - 1
+		case r == ';':
+			current.WriteRune(r)
+			if beginDepth == 0 {
+				flush()
+			}
+		case (unicode.IsLetter(r) || r == '_') && (i == 0 || !isIdentifierRune(runes[i-1])):
+			end := i + 1
+			for end < len(runes) && (isIdentifierRune(runes[end]) || runes[end] == '$') {
+				end++
+			}
+			word := string(runes[i:end])
+			wordCount++
+			switch {
+			case strings.EqualFold(word, "begin"), strings.EqualFold(word, "case"):
+				// a BEGIN starting the statement is a transaction block
+				if wordCount > 1 {
+					beginDepth++
+				}
+			case strings.EqualFold(word, "end"):
+				if beginDepth > 0 {
+					beginDepth--
+				}
+			}
+			current.WriteString(word)
+			hasContent = true
+			i = end - 1
+		default:
+			current.WriteRune(r)
+			if !unicode.IsSpace(r) {
+				hasContent = true
+			}
+		}
+	}
+	flush()
+
+	return stmts
+}
+
+// dollarQuoteTag returns the dollar quote tag at the start of the text on
+// input, such as $$ or $body$.
+func dollarQuoteTag(runes []rune) ([]rune, bool) {
+	for i := 1; i < len(runes); i++ {
+		switch {
+		case runes[i] == '$':
+			return runes[:i+1], true
+		case unicode.IsDigit(runes[i]) && i == 1,
+			!isIdentifierRune(runes[i]):
+			// positional parameters such as $1 are not dollar quotes
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// indexRunes returns the index of the first occurrence of the substring in the
+// runes on input, starting at the given index, or -1 if not found.
+func indexRunes(runes []rune, start int, substr []rune) int {
+	for i := start; i+len(substr) <= len(runes); i++ {
+		if slices.Equal(runes[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isIdentifierRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isMetaCommand(stmt string) bool {
+	return strings.HasPrefix(stmt, `\`)
+}
+
+// parseConnectCommand returns the database of the \connect meta command on
+// input.
+func parseConnectCommand(stmt string) (string, bool) {
+	cmd, database, _ := strings.Cut(stmt, " ")
+	database = strings.TrimSpace(database)
+	if (cmd != psqlConnectCmd && cmd != `\c`) || database == "" {
+		return "", false
+	}
+	return UnquoteIdentifier(database), true
+}
@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitSQLStatements(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+
+		wantStmts []string
+	}{
+		{
+			name:   "multiple statements and comments",
+			script: "--\n-- comment; with semicolon\n--\n\nSET lock_timeout = 0;\n/* block; /* nested; */ comment */\nCREATE TABLE public.t (\n    id integer\n);\n\n-- trailing comment\n",
+
+			wantStmts: []string{
+				"--\n-- comment; with semicolon\n--\n\nSET lock_timeout = 0;",
+				"/* block; /* nested; */ comment */\nCREATE TABLE public.t (\n    id integer\n);",
+			},
+		},
+		{
+			name:   "quoted strings and identifiers",
+			script: `COMMENT ON TABLE "my;table" IS 'it''s; a table';SELECT E'escaped\'; quote';`,
+
+			wantStmts: []string{
+				`COMMENT ON TABLE "my;table" IS 'it''s; a table';`,
+				`SELECT E'escaped\'; quote';`,
+			},
+		},
+		{
+			name:   "dollar quoted function body",
+			script: "CREATE FUNCTION public.f() RETURNS trigger\n    LANGUAGE plpgsql\n    AS $body$\nBEGIN\n  PERFORM 1; RETURN $$nested;$$;\nEND;\n$body$;\nSELECT $1;",
+
+			wantStmts: []string{
+				"CREATE FUNCTION public.f() RETURNS trigger\n    LANGUAGE plpgsql\n    AS $body$\nBEGIN\n  PERFORM 1; RETURN $$nested;$$;\nEND;\n$body$;",
+				"SELECT $1;",
+			},
+		},
+		{
+			name:   "sql standard function body",
+			script: "CREATE FUNCTION public.greatest_sum(a integer, b integer) RETURNS integer\n    LANGUAGE sql\nBEGIN ATOMIC\n SELECT (a + b);\n SELECT CASE\n  WHEN (a > b) THEN a\n  ELSE b\n END AS \"case\";\nEND;\nSELECT 1;",
+
+			wantStmts: []string{
+				"CREATE FUNCTION public.greatest_sum(a integer, b integer) RETURNS integer\n    LANGUAGE sql\nBEGIN ATOMIC\n SELECT (a + b);\n SELECT CASE\n  WHEN (a > b) THEN a\n  ELSE b\n END AS \"case\";\nEND;",
+				"SELECT 1;",
+			},
+		},
+		{
+			name:   "transaction block and case expression",
+			script: "BEGIN;\nSELECT CASE WHEN true THEN 1 END;\nCOMMIT;",
+
+			wantStmts: []string{
+				"BEGIN;",
+				"SELECT CASE WHEN true THEN 1 END;",
+				"COMMIT;",
+			},
+		},
+		{
+			name:   "meta commands",
+			script: "CREATE DATABASE test;\n\\connect test\n\nSET lock_timeout = 0;\n",
+
+			wantStmts: []string{
+				"CREATE DATABASE test;",
+				`\connect test`,
+				"SET lock_timeout = 0;",
+			},
+		},
+		{
+			name:   "statement without trailing semicolon",
+			script: "SELECT 1",
+
+			wantStmts: []string{"SELECT 1"},
+		},
+		{
+			name:   "empty script",
+			script: "\n-- only comments\n",
+
+			wantStmts: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.wantStmts, splitSQLStatements(tc.script))
+		})
+	}
+}
+
+func TestParseConnectCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		stmt string
+
+		wantDatabase  string
+		wantIsConnect bool
+	}{
+		{
+			name: "connect",
+			stmt: `\connect test`,
+
+			wantDatabase:  "test",
+			wantIsConnect: true,
+		},
+		{
+			name: "quoted database",
+			stmt: `\c "My DB"`,
+
+			wantDatabase:  "My DB",
+			wantIsConnect: true,
+		},
+		{
+			name: "other meta command",
+			stmt: `\restrict key`,
+
+			wantIsConnect: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			database, isConnect := parseConnectCommand(tc.stmt)
+			require.Equal(t, tc.wantIsConnect, isConnect)
+			if isConnect {
+				require.Equal(t, tc.wantDatabase, database)
+			}
+		})
+	}
+}
+
+func TestSplitSQLStatements_renderedDump(t *testing.T) {
+	t.Parallel()
+
+	// pg_get_functiondef returns the SQL-standard function bodies with their
+	// statements, and without a trailing semicolon
+	functionDef := "CREATE OR REPLACE FUNCTION public.add(a integer, b integer)\n RETURNS integer\n LANGUAGE sql\nBEGIN ATOMIC\n SELECT (a + b);\nEND\n"
+	catalog := &nativeCatalog{
+		database: &nativeDatabase{ident: "test", owner: "postgres", encoding: "UTF8", collate: "C", ctype: "C"},
+		schemas: []*nativeSchema{
+			{nativeObject: nativeObject{name: "public", ident: "public", owner: "pg_database_owner"}},
+		},
+		functions: []*nativeFunction{
+			{nativeObject: nativeObject{schema: "public", name: "add", ident: "public.add", owner: "postgres"}, kind: "f", identityArgs: "a integer, b integer", definition: functionDef},
+		},
+	}
+
+	opts := PGDumpOptions{Schemas: []string{"public"}}
+	filter, err := newNativeDumpFilter(opts)
+	require.NoError(t, err)
+
+	stmts := splitSQLStatements(string(catalog.render(opts, filter)))
+	require.Contains(t, stmts, "CREATE OR REPLACE FUNCTION public.add(a integer, b integer)\n RETURNS integer\n LANGUAGE sql\nBEGIN ATOMIC\n SELECT (a + b);\nEND;")
+	require.Contains(t, stmts, "ALTER FUNCTION public.add(a integer, b integer) OWNER TO postgres;")
+}
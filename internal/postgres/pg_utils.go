@@ -92,10 +92,39 @@ func UnquoteIdentifier(s string) string {
 	return strings.ReplaceAll(inner, `""`, `"`)
 }
 
+// QuoteLiteral quotes a string to be used as a literal in a query, escaping
+// any embedded quotes and backslashes.
+func QuoteLiteral(s string) string {
+	return pq.QuoteLiteral(s)
+}
+
 func QuoteQualifiedIdentifier(schema, table string) string {
 	return QuoteIdentifier(schema) + "." + QuoteIdentifier(table)
 }
 
+// NormaliseQualifiedIdentifier returns the schema qualified name on input in
+// the form returned by QuoteQualifiedIdentifier, so that names written in
+// different ways (as rendered by regclass, for example) can be compared.
+// Unquoted parts are folded to lower case, as postgres does, and unqualified
+// names are qualified with the default schema if any.
+// - public.users_id_seq -> "public"."users_id_seq"
+// - users_id_seq (default schema app) -> "app"."users_id_seq"
+// - "Public"."Seq" -> "Public"."Seq"
+func NormaliseQualifiedIdentifier(identity, defaultSchema string) string {
+	parts := splitQualifiedIdentifier(identity)
+	switch len(parts) {
+	case 1:
+		if defaultSchema == "" {
+			return QuoteIdentifier(normaliseIdentifier(parts[0]))
+		}
+		return QuoteQualifiedIdentifier(defaultSchema, normaliseIdentifier(parts[0]))
+	case 2:
+		return QuoteQualifiedIdentifier(normaliseIdentifier(parts[0]), normaliseIdentifier(parts[1]))
+	default:
+		return identity
+	}
+}
+
 func IsQuotedIdentifier(s string) bool {
 	return len(s) > 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`)
 }
@@ -277,6 +306,57 @@ func DiscoverAllSchemaTables(ctx context.Context, conn Querier, schema string) (
 	return tableNames, nil
 }
 
+const GetPartitionRootQuery = `SELECT rn.nspname, r.relname FROM pg_class c
+	JOIN pg_class r ON r.oid = pg_partition_root(c.oid)
+	JOIN pg_namespace rn ON rn.oid = r.relnamespace
+	WHERE c.oid = to_regclass($1) AND c.relispartition`
+
+// GetPartitionRoot returns the root partitioned table of the table on input,
+// or nil if the table is not a partition.
+func GetPartitionRoot(ctx context.Context, conn Querier, schema, table string) (*QualifiedName, error) {
+	root := &QualifiedName{}
+	err := conn.QueryRow(ctx, []any{&root.schema, &root.name}, GetPartitionRootQuery, QuoteQualifiedIdentifier(schema, table))
+	if err != nil {
+		if errors.Is(err, ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting partition root for table %s.%s: %w", schema, table, err)
+	}
+	return root, nil
+}
+
+const DiscoverPartitionLeavesQuery = `SELECT n.nspname, c.relname FROM pg_partition_tree(to_regclass($1)) t
+	JOIN pg_class c ON c.oid = t.relid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE t.isleaf AND t.level > 0
+	ORDER BY n.nspname, c.relname`
+
+// DiscoverPartitionLeaves returns the leaf partitions of the partitioned table
+// on input, at any level of the partition tree. It returns an empty list if
+// the table is not partitioned.
+func DiscoverPartitionLeaves(ctx context.Context, conn Querier, schema, table string) ([]*QualifiedName, error) {
+	rows, err := conn.Query(ctx, DiscoverPartitionLeavesQuery, QuoteQualifiedIdentifier(schema, table))
+	if err != nil {
+		return nil, fmt.Errorf("discovering partitions for table %s.%s: %w", schema, table, err)
+	}
+	defer rows.Close()
+
+	leaves := []*QualifiedName{}
+	for rows.Next() {
+		leaf := &QualifiedName{}
+		if err := rows.Scan(&leaf.schema, &leaf.name); err != nil {
+			return nil, fmt.Errorf("scanning partition name: %w", err)
+		}
+		leaves = append(leaves, leaf)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return leaves, nil
+}
+
 func ParseConfig(pgurl string) (*pgx.ConnConfig, error) {
 	pgCfg, err := pgx.ParseConfig(pgurl)
 	if err != nil {
@@ -208,6 +208,57 @@ func Test_QuoteQualifiedIdentifier(t *testing.T) {
 	}
 }
 
+func Test_NormaliseQualifiedIdentifier(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		identity      string
+		defaultSchema string
+
+		want string
+	}{
+		{
+			name:     "unquoted qualified name",
+			identity: "public.users_id_seq",
+			want:     `"public"."users_id_seq"`,
+		},
+		{
+			name:     "quoted qualified name",
+			identity: `"public"."users_id_seq"`,
+			want:     `"public"."users_id_seq"`,
+		},
+		{
+			name:     "unquoted names are folded to lower case",
+			identity: `Public."Users_id_seq"`,
+			want:     `"public"."Users_id_seq"`,
+		},
+		{
+			name:          "unqualified name with default schema",
+			identity:      "users_id_seq",
+			defaultSchema: "app",
+			want:          `"app"."users_id_seq"`,
+		},
+		{
+			name:     "unqualified name without default schema",
+			identity: "users_id_seq",
+			want:     `"users_id_seq"`,
+		},
+		{
+			name:     "quoted name with a dot",
+			identity: `"my.schema"."seq"`,
+			want:     `"my.schema"."seq"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.want, NormaliseQualifiedIdentifier(tc.identity, tc.defaultSchema))
+		})
+	}
+}
+
 func Test_newIdentifier(t *testing.T) {
 	t.Parallel()
 
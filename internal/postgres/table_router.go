@@ -0,0 +1,807 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TableRoute maps a source table pattern to a target table name template.
+// Both sides are schema qualified table names. If no schema is provided, the
+// public schema will be assumed.
+//
+// The source pattern supports "*" wildcards on either the schema or the
+// table. When a part of the target (schema or table) has as many "*" as the
+// same part of the source, they're replaced by the text matched by those
+// wildcards, so "public.*" -> "replica_public.*" and "*.*" ->
+// "replica_{schema}.*" keep the table names unchanged. Otherwise, they're
+// replaced in order by the text matched by the remaining source wildcards, so
+// "tenant_*.orders" -> "merged.orders_*" routes tenant_a.orders to
+// merged.orders_a. The "{schema}" and "{table}" placeholders expand to the
+// full source schema and table names. Tables in the postgres system schemas
+// are never routed.
+type TableRoute struct {
+	Source string
+	Target string
+}
+
+// TableRouter resolves the target name of source tables based on a list of
+// routes. When multiple routes match, the most specific one wins (exact names
+// before wildcards), followed by the order in which they were provided. A nil
+// TableRouter routes every table to itself.
+type TableRouter struct {
+	routes []*tableRoute
+}
+
+type tableRoute struct {
+	schema       *routePattern
+	table        *routePattern
+	targetSchema string
+	targetTable  string
+}
+
+type routePattern struct {
+	raw   string
+	regex *regexp.Regexp
+}
+
+const (
+	schemaPlaceholder = "{schema}"
+	tablePlaceholder  = "{table}"
+)
+
+var ErrInvalidTableRoute = errors.New("invalid table route")
+
+// NewTableRouter returns a table router for the routes on input. It returns
+// nil if no routes are provided.
+func NewTableRouter(routes []TableRoute) (*TableRouter, error) {
+	if len(routes) == 0 {
+		return nil, nil
+	}
+
+	router := &TableRouter{
+		routes: make([]*tableRoute, 0, len(routes)),
+	}
+	for _, r := range routes {
+		route, err := newTableRoute(r)
+		if err != nil {
+			return nil, err
+		}
+		router.routes = append(router.routes, route)
+	}
+
+	sort.SliceStable(router.routes, func(i, j int) bool {
+		return router.routes[i].specificity() > router.routes[j].specificity()
+	})
+
+	return router, nil
+}
+
+// TableRoutesFromMap converts a map of source patterns to target names into
+// a list of table routes, sorted by source pattern so that the precedence of
+// equally specific routes is deterministic.
+func TableRoutesFromMap(routes map[string]string) []TableRoute {
+	if len(routes) == 0 {
+		return nil
+	}
+	sources := make([]string, 0, len(routes))
+	for source := range routes {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	tableRoutes := make([]TableRoute, 0, len(routes))
+	for _, source := range sources {
+		tableRoutes = append(tableRoutes, TableRoute{Source: source, Target: routes[source]})
+	}
+	return tableRoutes
+}
+
+func newTableRoute(r TableRoute) (*tableRoute, error) {
+	sourceSchema, sourceTable, err := parseTableName(r.Source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: source %q: %w", ErrInvalidTableRoute, r.Source, err)
+	}
+	targetSchema, targetTable, err := parseTableName(r.Target)
+	if err != nil {
+		return nil, fmt.Errorf("%w: target %q: %w", ErrInvalidTableRoute, r.Target, err)
+	}
+	if sourceSchema == "" || sourceTable == "" || targetSchema == "" || targetTable == "" {
+		return nil, fmt.Errorf("%w: empty name in %q -> %q", ErrInvalidTableRoute, r.Source, r.Target)
+	}
+
+	route := &tableRoute{
+		schema:       newRoutePattern(sourceSchema),
+		table:        newRoutePattern(sourceTable),
+		targetSchema: targetSchema,
+		targetTable:  targetTable,
+	}
+
+	if strings.Count(r.Target, wildcard) > strings.Count(r.Source, wildcard) {
+		return nil, fmt.Errorf("%w: target %q uses more wildcards than source %q", ErrInvalidTableRoute, r.Target, r.Source)
+	}
+
+	return route, nil
+}
+
+func newRoutePattern(p string) *routePattern {
+	if !strings.Contains(p, wildcard) {
+		return &routePattern{raw: p}
+	}
+	parts := strings.Split(p, wildcard)
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return &routePattern{
+		raw:   p,
+		regex: regexp.MustCompile("^" + strings.Join(parts, "(.*)") + "$"),
+	}
+}
+
+// match returns whether the name matches the pattern, along with the values
+// captured by the pattern wildcards.
+func (p *routePattern) match(name string) ([]string, bool) {
+	if p.regex == nil {
+		return nil, p.raw == name
+	}
+	matches := p.regex.FindStringSubmatch(name)
+	if matches == nil {
+		return nil, false
+	}
+	return matches[1:], true
+}
+
+func (p *routePattern) isWildcard() bool {
+	return p.regex != nil
+}
+
+func (p *routePattern) matchesAll() bool {
+	return p.raw == wildcard
+}
+
+// fixedLen returns the number of characters of the pattern that are not
+// wildcards.
+func (p *routePattern) fixedLen() int {
+	return len(p.raw) - strings.Count(p.raw, wildcard)
+}
+
+func (r *tableRoute) specificity() int {
+	score := 0
+	if !r.schema.isWildcard() {
+		score += 2000
+	}
+	if !r.table.isWildcard() {
+		score += 1000
+	}
+	// among wildcard patterns, prefer the ones with the longest fixed part
+	return score + min(r.schema.fixedLen()+r.table.fixedLen(), 999)
+}
+
+func (r *tableRoute) route(schema, table string) (string, string, bool) {
+	schemaCaptures, ok := r.schema.match(schema)
+	if !ok {
+		return "", "", false
+	}
+	tableCaptures, ok := r.table.match(table)
+	if !ok {
+		return "", "", false
+	}
+
+	// the target parts with as many wildcards as the source ones take their
+	// captures, the others take the remaining captures in order
+	schemaMatched := strings.Count(r.targetSchema, wildcard) == len(schemaCaptures)
+	tableMatched := strings.Count(r.targetTable, wildcard) == len(tableCaptures)
+	remaining := []string{}
+	if !schemaMatched {
+		remaining = append(remaining, schemaCaptures...)
+	}
+	if !tableMatched {
+		remaining = append(remaining, tableCaptures...)
+	}
+
+	var targetSchema, targetTable string
+	if schemaMatched {
+		targetSchema, _ = expandRouteTemplate(r.targetSchema, schemaCaptures, schema, table)
+	} else {
+		targetSchema, remaining = expandRouteTemplate(r.targetSchema, remaining, schema, table)
+	}
+	if tableMatched {
+		targetTable, _ = expandRouteTemplate(r.targetTable, tableCaptures, schema, table)
+	} else {
+		targetTable, _ = expandRouteTemplate(r.targetTable, remaining, schema, table)
+	}
+	return targetSchema, targetTable, true
+}
+
+// expandRouteTemplate replaces the placeholders in the template, and each of
+// its wildcards with the next capture. It returns the expanded template along
+// with the captures that were not used.
+func expandRouteTemplate(template string, captures []string, schema, table string) (string, []string) {
+	result := strings.ReplaceAll(template, schemaPlaceholder, schema)
+	result = strings.ReplaceAll(result, tablePlaceholder, table)
+	for len(captures) > 0 && strings.Contains(result, wildcard) {
+		result = strings.Replace(result, wildcard, captures[0], 1)
+		captures = captures[1:]
+	}
+	return result, captures
+}
+
+// Route returns the target schema and table names for the source table on
+// input. Tables that don't match any route are returned unchanged.
+func (r *TableRouter) Route(schema, table string) (string, string) {
+	if targetSchema, targetTable, found := r.Lookup(schema, table); found {
+		return targetSchema, targetTable
+	}
+	return schema, table
+}
+
+// Lookup returns the target schema and table names for the source table on
+// input, and whether any of the routes matched it.
+func (r *TableRouter) Lookup(schema, table string) (string, string, bool) {
+	if r == nil || isSystemSchema(schema) {
+		return "", "", false
+	}
+	for _, route := range r.routes {
+		if targetSchema, targetTable, ok := route.route(schema, table); ok {
+			return targetSchema, targetTable, true
+		}
+	}
+	return "", "", false
+}
+
+// RouteSchema returns the target schema for schema level objects (schemas,
+// types, functions...) of the source schema on input. Only routes that apply
+// to all the tables in a schema (wildcard table) are considered.
+func (r *TableRouter) RouteSchema(schema string) string {
+	if r == nil || isSystemSchema(schema) {
+		return schema
+	}
+	for _, route := range r.routes {
+		if !route.table.matchesAll() || strings.Contains(route.targetSchema, tablePlaceholder) {
+			continue
+		}
+		captures, ok := route.schema.match(schema)
+		if !ok || strings.Count(route.targetSchema, wildcard) > len(captures) {
+			continue
+		}
+		targetSchema, _ := expandRouteTemplate(route.targetSchema, captures, schema, "")
+		return targetSchema
+	}
+	return schema
+}
+
+// RouteIdentity routes a schema qualified object identity, as produced by
+// postgres (for example `public.users` or `public."Users".id`). Any parts
+// after the table name are kept as they are.
+func (r *TableRouter) RouteIdentity(identity string) string {
+	if r == nil {
+		return identity
+	}
+	parts := splitQualifiedIdentifier(identity)
+	if len(parts) < 2 {
+		return identity
+	}
+	schema, table := normaliseIdentifier(parts[0]), normaliseIdentifier(parts[1])
+	targetSchema, targetTable := r.Route(schema, table)
+	if targetSchema == schema && targetTable == table {
+		return identity
+	}
+	routed := append([]string{QuoteIdentifier(targetSchema), QuoteIdentifier(targetTable)}, parts[2:]...)
+	return strings.Join(routed, ".")
+}
+
+// RewriteSQL rewrites the schema qualified object names in the SQL statements
+// on input according to the router routes. Qualified names are only routed as
+// relations where the statement expects one (after TABLE, FROM, REFERENCES...,
+// or when qualified with a column name), while the schema of functions and
+// types is routed using the schema level routes. Other qualified names, such
+// as column references, are left untouched. Names that appear in comments,
+// string literals or dollar quoted bodies are left untouched as well, with
+// the exception of regclass literals (such as `'public.seq'::regclass`) and
+// sequence function arguments. Unqualified names following the SCHEMA keyword
+// are routed using the schema level routes.
+func (r *TableRouter) RewriteSQL(sql string) string {
+	return r.RewriteSQLInSchema(sql, "")
+}
+
+// RewriteSQLInSchema rewrites the SQL statements on input like RewriteSQL,
+// and additionally routes the unqualified relation names of the DDL
+// statements as if they belonged to the schema on input, qualifying them with
+// their target schema. Unqualified names within queries, such as view
+// definitions, are left untouched.
+func (r *TableRouter) RewriteSQLInSchema(sql, schema string) string {
+	if r == nil || len(r.routes) == 0 {
+		return sql
+	}
+	rw := &sqlRewriter{router: r, sql: sql, schema: schema}
+	return rw.rewrite()
+}
+
+type sqlRewriter struct {
+	router *TableRouter
+	sql    string
+	// schema used to resolve the unqualified relation names, if any
+	schema string
+	out    strings.Builder
+	// last significant words and symbols seen, most recent last
+	prevTokens []string
+	// set when the next identifier is expected to be a schema name
+	expectSchema bool
+	// set within a list of relations, where the identifier following a comma
+	// is a relation as well. aliasWords counts the words seen since the last
+	// relation, to allow for aliases.
+	relationList bool
+	aliasWords   int
+	// set once the current statement has a query, where the ON and LIKE
+	// keywords are followed by expressions rather than relations
+	inQuery bool
+}
+
+const maxPrevTokens = 5
+
+var (
+	// relationKeywords are the keywords followed by a relation name
+	relationKeywords = map[string]struct{}{
+		"TABLE": {}, "VIEW": {}, "SEQUENCE": {}, "INDEX": {}, "INTO": {},
+		"FROM": {}, "JOIN": {}, "UPDATE": {}, "ONLY": {}, "REFERENCES": {},
+		"PARTITION": {}, "OF": {}, "COPY": {}, "TRUNCATE": {},
+	}
+	// schemaObjectKeywords are the keywords followed by the name of a schema
+	// level object, such as a function or a type
+	schemaObjectKeywords = map[string]struct{}{
+		"FUNCTION": {}, "PROCEDURE": {}, "ROUTINE": {}, "AGGREGATE": {},
+		"TYPE": {}, "DOMAIN": {}, "RETURNS": {}, "SETOF": {}, "AS": {},
+	}
+	// nonRelationWords are the keywords that can follow the relation keywords
+	// without being a relation name
+	nonRelationWords = map[string]struct{}{
+		"ONLY": {}, "IF": {}, "NOT": {}, "EXISTS": {}, "CONCURRENTLY": {}, "ALL": {},
+		"TABLE": {}, "COLUMN": {}, "SCHEMA": {}, "FUNCTION": {}, "PROCEDURE": {},
+		"ROUTINE": {}, "AGGREGATE": {}, "SEQUENCE": {}, "TYPE": {}, "DOMAIN": {},
+		"CONSTRAINT": {}, "INDEX": {}, "VIEW": {}, "MATERIALIZED": {}, "FOREIGN": {},
+		"DATABASE": {}, "LARGE": {}, "TABLESPACE": {}, "LANGUAGE": {}, "EXTENSION": {},
+		"EVENT": {}, "TRIGGER": {}, "RULE": {}, "POLICY": {}, "ROLE": {}, "CAST": {},
+		"COLLATION": {}, "CONVERSION": {}, "OPERATOR": {}, "PUBLICATION": {},
+		"SUBSCRIPTION": {}, "SERVER": {}, "STATISTICS": {}, "TEXT": {}, "ACCESS": {},
+		"DELETE": {}, "UPDATE": {}, "INSERT": {}, "TRUNCATE": {}, "COMMIT": {},
+		"CONFLICT": {}, "ADD": {}, "DROP": {}, "ALTER": {}, "RENAME": {}, "OWNER": {},
+		"SET": {}, "RESET": {}, "ENABLE": {}, "DISABLE": {}, "VALIDATE": {},
+		"ATTACH": {}, "DETACH": {}, "CASCADE": {}, "RESTRICT": {}, "INHERIT": {},
+		"NO": {}, "CLUSTER": {}, "REPLICA": {}, "FORCE": {}, "OF": {}, "PARTITION": {},
+	}
+	// columnDefinitionStarts are the tokens preceding the column name of a
+	// column definition, which is followed by the column type
+	columnDefinitionStarts = map[string]struct{}{
+		"(": {}, ",": {}, "COLUMN": {}, "ADD": {}, "EXISTS": {},
+	}
+)
+
+func (w *sqlRewriter) rewrite() string {
+	w.out.Grow(len(w.sql))
+	i := 0
+	for i < len(w.sql) {
+		c := w.sql[i]
+		switch {
+		case c == '-' && i+1 < len(w.sql) && w.sql[i+1] == '-':
+			end := strings.IndexByte(w.sql[i:], '\n')
+			if end < 0 {
+				end = len(w.sql) - i
+			}
+			w.out.WriteString(w.sql[i : i+end])
+			i += end
+		case c == '/' && i+1 < len(w.sql) && w.sql[i+1] == '*':
+			end := w.blockCommentEnd(i)
+			w.out.WriteString(w.sql[i:end])
+			i = end
+		case c == '\'':
+			i = w.rewriteStringLiteral(i)
+		case c == '$' && !w.followsIdentifierChar(i):
+			end, ok := w.dollarQuoteEnd(i)
+			if !ok {
+				w.out.WriteByte(c)
+				i++
+				continue
+			}
+			w.out.WriteString(w.sql[i:end])
+			w.pushToken("$$")
+			i = end
+		case c == '"' || isIdentifierStart(c):
+			i = w.rewriteIdentifierChain(i)
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			w.out.WriteByte(c)
+			i++
+		default:
+			w.out.WriteByte(c)
+			w.pushToken(string(c))
+			w.expectSchema = false
+			if c == ';' {
+				w.inQuery = false
+			}
+			i++
+		}
+	}
+	return w.out.String()
+}
+
+func (w *sqlRewriter) pushToken(t string) {
+	if len(w.prevTokens) == maxPrevTokens {
+		w.prevTokens = w.prevTokens[1:]
+	}
+	w.prevTokens = append(w.prevTokens, t)
+
+	switch {
+	case t == ",":
+		w.aliasWords = 0
+	case isWordToken(t) && w.aliasWords < 2:
+		w.aliasWords++
+	default:
+		w.relationList = false
+	}
+	if t == "SELECT" {
+		w.inQuery = true
+	}
+}
+
+// isRelationPosition returns true if the statement expects a relation name
+// at the current position.
+func (w *sqlRewriter) isRelationPosition() bool {
+	prev := w.prevToken(1)
+	if _, found := relationKeywords[prev]; found {
+		return true
+	}
+	switch prev {
+	case "ON", "LIKE":
+		return !w.inQuery
+	case "EXISTS":
+		switch w.keywordBeforeIfExists() {
+		case "TABLE", "VIEW", "SEQUENCE", "INDEX":
+			return true
+		}
+	case ",":
+		return w.relationList
+	case "(":
+		return w.prevToken(2) == "INHERITS"
+	}
+	return false
+}
+
+// isUnqualifiedRelationPosition returns true if the unqualified word on input
+// is a relation name of a DDL statement.
+func (w *sqlRewriter) isUnqualifiedRelationPosition(word string, isQuoted bool) bool {
+	if _, found := nonRelationWords[word]; (found && !isQuoted) || w.inQuery {
+		return false
+	}
+	switch prev := w.prevToken(1); prev {
+	case "TABLE", "VIEW", "SEQUENCE", "ONLY", "REFERENCES", "PARTITION", "ON", "LIKE":
+		return true
+	case "INDEX":
+		// the index name of CREATE INDEX can't be qualified
+		return !isIndexDefinition(w.prevToken(2))
+	case "OF":
+		return w.prevToken(2) == "PARTITION"
+	case "EXISTS":
+		switch w.keywordBeforeIfExists() {
+		case "TABLE", "VIEW", "SEQUENCE":
+			return true
+		case "INDEX":
+			if w.prevToken(2) == "IF" {
+				return !isIndexDefinition(w.prevToken(4))
+			}
+			return !isIndexDefinition(w.prevToken(5))
+		}
+	case ",":
+		return w.relationList
+	case "(":
+		return w.prevToken(2) == "INHERITS"
+	}
+	return false
+}
+
+func isIndexDefinition(prevKeyword string) bool {
+	return prevKeyword == "CREATE" || prevKeyword == "UNIQUE"
+}
+
+// isSchemaObjectPosition returns true if the statement expects the name of a
+// schema level object at the current position, which is either a function
+// call (the name is followed by a parenthesis), a type cast, or the name
+// following a function or type keyword, including the column types of the
+// column definitions.
+func (w *sqlRewriter) isSchemaObjectPosition(end int) bool {
+	rest := strings.TrimLeft(w.sql[end:], " \t\n\r")
+	if strings.HasPrefix(rest, "(") {
+		return true
+	}
+	prev := w.prevToken(1)
+	if _, found := schemaObjectKeywords[prev]; found {
+		return true
+	}
+	switch prev {
+	case ":":
+		return true
+	case "EXISTS":
+		_, found := schemaObjectKeywords[w.keywordBeforeIfExists()]
+		return found
+	}
+	_, found := columnDefinitionStarts[w.prevToken(2)]
+	return found && !w.inQuery && isWordToken(prev)
+}
+
+// keywordBeforeIfExists returns the keyword preceding an IF EXISTS or IF NOT
+// EXISTS clause, which is the kind of the object that follows.
+func (w *sqlRewriter) keywordBeforeIfExists() string {
+	switch {
+	case w.prevToken(2) == "IF":
+		return w.prevToken(3)
+	case w.prevToken(2) == "NOT" && w.prevToken(3) == "IF":
+		return w.prevToken(4)
+	default:
+		return ""
+	}
+}
+
+func (w *sqlRewriter) prevToken(n int) string {
+	if n > len(w.prevTokens) {
+		return ""
+	}
+	return w.prevTokens[len(w.prevTokens)-n]
+}
+
+func (w *sqlRewriter) followsIdentifierChar(i int) bool {
+	return i > 0 && isIdentifierChar(w.sql[i-1])
+}
+
+func (w *sqlRewriter) blockCommentEnd(start int) int {
+	depth := 0
+	i := start
+	for i < len(w.sql) {
+		switch {
+		case strings.HasPrefix(w.sql[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(w.sql[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(w.sql)
+}
+
+func (w *sqlRewriter) dollarQuoteEnd(start int) (int, bool) {
+	tagEnd := start + 1
+	for tagEnd < len(w.sql) && w.sql[tagEnd] != '$' {
+		if !isIdentifierChar(w.sql[tagEnd]) {
+			return 0, false
+		}
+		tagEnd++
+	}
+	if tagEnd >= len(w.sql) {
+		return 0, false
+	}
+	tag := w.sql[start : tagEnd+1]
+	if len(tag) > 2 && tag[1] >= '0' && tag[1] <= '9' {
+		// positional parameter ($1), not a dollar quote
+		return 0, false
+	}
+	closing := strings.Index(w.sql[tagEnd+1:], tag)
+	if closing < 0 {
+		return len(w.sql), true
+	}
+	return tagEnd + 1 + closing + len(tag), true
+}
+
+func (w *sqlRewriter) rewriteStringLiteral(start int) int {
+	escaped := start > 0 && (w.sql[start-1] == 'E' || w.sql[start-1] == 'e') &&
+		(start == 1 || !isIdentifierChar(w.sql[start-2]))
+	i := start + 1
+	for i < len(w.sql) {
+		if escaped && w.sql[i] == '\\' {
+			i += 2
+			continue
+		}
+		if w.sql[i] == '\'' {
+			if i+1 < len(w.sql) && w.sql[i+1] == '\'' {
+				i += 2
+				continue
+			}
+			i++
+			break
+		}
+		i++
+	}
+	end := min(i, len(w.sql))
+	literal := w.sql[start:end]
+
+	if !escaped && w.isRegclassLiteral(end) && len(literal) >= 2 {
+		content := strings.ReplaceAll(literal[1:len(literal)-1], "''", "'")
+		if routed := w.router.RouteIdentity(content); routed != content {
+			literal = "'" + strings.ReplaceAll(routed, "'", "''") + "'"
+		}
+	}
+
+	w.out.WriteString(literal)
+	w.pushToken("'")
+	w.expectSchema = false
+	return end
+}
+
+var sequenceFunctions = map[string]struct{}{
+	"NEXTVAL": {},
+	"CURRVAL": {},
+	"SETVAL":  {},
+}
+
+// isRegclassLiteral returns true if the string literal ending at the position
+// on input refers to a relation, either because it's cast to regclass or
+// because it's the first argument of a sequence function.
+func (w *sqlRewriter) isRegclassLiteral(end int) bool {
+	rest := strings.TrimLeft(w.sql[end:], " \t\n\r")
+	if len(rest) >= len("::regclass") && strings.EqualFold(rest[:len("::regclass")], "::regclass") {
+		return true
+	}
+	if w.prevToken(1) != "(" {
+		return false
+	}
+	_, found := sequenceFunctions[w.prevToken(2)]
+	return found
+}
+
+func (w *sqlRewriter) rewriteIdentifierChain(start int) int {
+	parts := []string{}
+	i := start
+	for {
+		end := identifierEnd(w.sql, i)
+		parts = append(parts, w.sql[i:end])
+		i = end
+		if i+1 < len(w.sql) && w.sql[i] == '.' && (w.sql[i+1] == '"' || isIdentifierStart(w.sql[i+1])) {
+			i++
+			continue
+		}
+		break
+	}
+	original := w.sql[start:i]
+
+	switch {
+	case len(parts) >= 3, len(parts) == 2 && w.isRelationPosition():
+		// names with more than two parts are columns qualified with their
+		// schema and table
+		w.out.WriteString(w.router.RouteIdentity(original))
+		w.expectSchema = false
+		w.pushToken(strings.ToUpper(parts[len(parts)-1]))
+		if len(parts) == 2 {
+			w.relationList = true
+			w.aliasWords = 0
+		}
+	case len(parts) == 2 && w.isSchemaObjectPosition(i):
+		schema := normaliseIdentifier(parts[0])
+		if routed := w.router.RouteSchema(schema); routed != schema {
+			w.out.WriteString(QuoteIdentifier(routed) + "." + parts[1])
+		} else {
+			w.out.WriteString(original)
+		}
+		w.expectSchema = false
+		w.pushToken(strings.ToUpper(parts[1]))
+	case len(parts) == 2:
+		w.out.WriteString(original)
+		w.expectSchema = false
+		w.pushToken(strings.ToUpper(parts[1]))
+		w.relationList = false
+	default:
+		word := strings.ToUpper(parts[0])
+		isQuoted := strings.HasPrefix(parts[0], `"`)
+		switch {
+		case !isQuoted && word == "SCHEMA":
+			w.out.WriteString(original)
+			w.expectSchema = true
+		case !isQuoted && w.expectSchema && (word == "IF" || word == "NOT" || word == "EXISTS"):
+			w.out.WriteString(original)
+		case !isQuoted && w.expectSchema && word == "AUTHORIZATION":
+			w.out.WriteString(original)
+			w.expectSchema = false
+		case w.expectSchema:
+			schema := normaliseIdentifier(parts[0])
+			if routed := w.router.RouteSchema(schema); routed != schema {
+				w.out.WriteString(QuoteIdentifier(routed))
+			} else {
+				w.out.WriteString(original)
+			}
+			w.expectSchema = false
+		case w.schema != "" && w.isUnqualifiedRelationPosition(word, isQuoted):
+			table := normaliseIdentifier(parts[0])
+			targetSchema, targetTable := w.router.Route(w.schema, table)
+			if targetSchema != w.schema || targetTable != table {
+				w.out.WriteString(QuoteIdentifier(targetSchema) + "." + QuoteIdentifier(targetTable))
+			} else {
+				w.out.WriteString(original)
+			}
+			w.pushToken(word)
+			w.relationList = true
+			w.aliasWords = 0
+			return i
+		default:
+			w.out.WriteString(original)
+		}
+		w.pushToken(word)
+	}
+	return i
+}
+
+func identifierEnd(sql string, start int) int {
+	if sql[start] != '"' {
+		i := start
+		for i < len(sql) && isIdentifierChar(sql[i]) {
+			i++
+		}
+		return i
+	}
+	i := start + 1
+	for i < len(sql) {
+		if sql[i] == '"' {
+			if i+1 < len(sql) && sql[i+1] == '"' {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return len(sql)
+}
+
+// isSystemSchema returns true for the postgres system schemas, which are never
+// routed.
+func isSystemSchema(schema string) bool {
+	return schema == "pg_catalog" || schema == "information_schema" ||
+		strings.HasPrefix(schema, "pg_toast") || strings.HasPrefix(schema, "pg_temp")
+}
+
+// splitQualifiedIdentifier splits a qualified identifier on the dots that are
+// not part of a quoted identifier.
+func splitQualifiedIdentifier(s string) []string {
+	parts := []string{}
+	start := 0
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case '.':
+			if !inQuotes {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// normaliseIdentifier returns the name postgres resolves the identifier to:
+// quoted identifiers are unquoted, and unquoted ones are folded to lower case.
+func normaliseIdentifier(s string) string {
+	if IsQuotedIdentifier(s) {
+		return UnquoteIdentifier(s)
+	}
+	return strings.ToLower(s)
+}
+
+// isWordToken returns true if the token is a keyword or an identifier, as
+// opposed to a symbol.
+func isWordToken(t string) bool {
+	return t != "" && (t[0] == '"' || isIdentifierStart(t[0]))
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentifierChar(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9') || c == '$'
+}
@@ -0,0 +1,386 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTableRouter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		routes []TableRoute
+
+		wantNil bool
+		wantErr error
+	}{
+		{
+			name:    "ok - no routes",
+			routes:  nil,
+			wantNil: true,
+		},
+		{
+			name: "ok - valid routes",
+			routes: []TableRoute{
+				{Source: "public.*", Target: "replica_public.*"},
+				{Source: "users", Target: "app.users"},
+			},
+		},
+		{
+			name: "error - invalid source",
+			routes: []TableRoute{
+				{Source: "a.b.c", Target: "replica.*"},
+			},
+			wantErr: ErrInvalidTableRoute,
+		},
+		{
+			name: "error - target with more wildcards than source",
+			routes: []TableRoute{
+				{Source: "public.users", Target: "replica.*"},
+			},
+			wantErr: ErrInvalidTableRoute,
+		},
+		{
+			name: "error - empty target",
+			routes: []TableRoute{
+				{Source: "public.users", Target: "replica."},
+			},
+			wantErr: ErrInvalidTableRoute,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, err := NewTableRouter(tc.routes)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr == nil {
+				require.Equal(t, tc.wantNil, router == nil)
+			}
+		})
+	}
+}
+
+func TestTableRouter_Route(t *testing.T) {
+	t.Parallel()
+
+	routes := []TableRoute{
+		{Source: "public.*", Target: "replica_public.*"},
+		{Source: "public.users", Target: "accounts.app_users"},
+		{Source: "tenant_*.orders", Target: "merged.orders_*"},
+		{Source: "tenant_*.*", Target: "merged.{schema}_{table}"},
+		{Source: "*.audit_*", Target: "audit.*_*"},
+	}
+	router, err := NewTableRouter(routes)
+	require.NoError(t, err)
+	catchAllRouter, err := NewTableRouter([]TableRoute{
+		{Source: "*.*", Target: "replica_{schema}.*"},
+		{Source: "archive_*.*", Target: "archive.*"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		router *TableRouter
+		schema string
+		table  string
+
+		wantSchema string
+		wantTable  string
+	}{
+		{
+			name:       "nil router",
+			router:     nil,
+			schema:     "public",
+			table:      "users",
+			wantSchema: "public",
+			wantTable:  "users",
+		},
+		{
+			name:       "exact route takes precedence over wildcard",
+			router:     router,
+			schema:     "public",
+			table:      "users",
+			wantSchema: "accounts",
+			wantTable:  "app_users",
+		},
+		{
+			name:       "schema wildcard route",
+			router:     router,
+			schema:     "public",
+			table:      "orders",
+			wantSchema: "replica_public",
+			wantTable:  "orders",
+		},
+		{
+			name:       "partial wildcard capture",
+			router:     router,
+			schema:     "tenant_a",
+			table:      "orders",
+			wantSchema: "merged",
+			wantTable:  "orders_a",
+		},
+		{
+			name:       "placeholders",
+			router:     router,
+			schema:     "tenant_b",
+			table:      "items",
+			wantSchema: "merged",
+			wantTable:  "tenant_b_items",
+		},
+		{
+			name:       "wildcard schema with table capture",
+			router:     router,
+			schema:     "sales",
+			table:      "audit_log",
+			wantSchema: "audit",
+			wantTable:  "sales_log",
+		},
+		{
+			name:       "catch all route with schema placeholder",
+			router:     catchAllRouter,
+			schema:     "public",
+			table:      "users",
+			wantSchema: "replica_public",
+			wantTable:  "users",
+		},
+		{
+			name:       "wildcard table with fixed target schema",
+			router:     catchAllRouter,
+			schema:     "archive_2024",
+			table:      "orders",
+			wantSchema: "archive",
+			wantTable:  "orders",
+		},
+		{
+			name:       "system schemas are not routed",
+			router:     catchAllRouter,
+			schema:     "pg_catalog",
+			table:      "pg_class",
+			wantSchema: "pg_catalog",
+			wantTable:  "pg_class",
+		},
+		{
+			name:       "no matching route",
+			router:     router,
+			schema:     "sales",
+			table:      "customers",
+			wantSchema: "sales",
+			wantTable:  "customers",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			schema, table := tc.router.Route(tc.schema, tc.table)
+			require.Equal(t, tc.wantSchema, schema)
+			require.Equal(t, tc.wantTable, table)
+		})
+	}
+}
+
+func TestTableRouter_RouteSchema(t *testing.T) {
+	t.Parallel()
+
+	router, err := NewTableRouter([]TableRoute{
+		{Source: "public.users", Target: "accounts.users"},
+		{Source: "public.*", Target: "replica_public.*"},
+		{Source: "tenant_*.*", Target: "merged_*.*"},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "replica_public", router.RouteSchema("public"))
+	require.Equal(t, "merged_a", router.RouteSchema("tenant_a"))
+	require.Equal(t, "sales", router.RouteSchema("sales"))
+}
+
+func TestTableRouter_RewriteSQL(t *testing.T) {
+	t.Parallel()
+
+	router, err := NewTableRouter([]TableRoute{
+		{Source: "public.*", Target: "replica_public.*"},
+		{Source: "sales.Orders", Target: "sales.orders_v2"},
+	})
+	require.NoError(t, err)
+	catchAllRouter, err := NewTableRouter([]TableRoute{
+		{Source: "*.*", Target: "replica_{schema}.*"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		router *TableRouter
+		sql    string
+
+		wantSQL string
+	}{
+		{
+			name:    "qualified table name",
+			sql:     "ALTER TABLE public.users ADD COLUMN email text;",
+			wantSQL: `ALTER TABLE "replica_public"."users" ADD COLUMN email text;`,
+		},
+		{
+			name:    "quoted identifiers",
+			sql:     `ALTER TABLE ONLY sales."Orders" ADD CONSTRAINT orders_pkey PRIMARY KEY (id);`,
+			wantSQL: `ALTER TABLE ONLY "sales"."orders_v2" ADD CONSTRAINT orders_pkey PRIMARY KEY (id);`,
+		},
+		{
+			name:    "unquoted identifiers are folded to lower case",
+			sql:     `DROP TABLE sales.Orders;`,
+			wantSQL: `DROP TABLE sales.Orders;`,
+		},
+		{
+			name:    "column qualified name",
+			sql:     "COMMENT ON COLUMN public.users.email IS 'public.users email';",
+			wantSQL: `COMMENT ON COLUMN "replica_public"."users".email IS 'public.users email';`,
+		},
+		{
+			name:    "schema keyword",
+			sql:     "CREATE SCHEMA IF NOT EXISTS public; GRANT USAGE ON SCHEMA public TO reader;",
+			wantSQL: `CREATE SCHEMA IF NOT EXISTS "replica_public"; GRANT USAGE ON SCHEMA "replica_public" TO reader;`,
+		},
+		{
+			name:    "regclass literal",
+			sql:     "CREATE TABLE public.users (id integer DEFAULT nextval('public.users_id_seq'::regclass));",
+			wantSQL: `CREATE TABLE "replica_public"."users" (id integer DEFAULT nextval('"replica_public"."users_id_seq"'::regclass));`,
+		},
+		{
+			name:    "setval argument",
+			sql:     "SELECT pg_catalog.setval('public.users_id_seq', 42, true);",
+			wantSQL: `SELECT pg_catalog.setval('"replica_public"."users_id_seq"', 42, true);`,
+		},
+		{
+			name:    "comments and dollar quoted bodies are not rewritten",
+			sql:     "-- public.users\nCREATE FUNCTION public.f() RETURNS int AS $fn$ SELECT count(*) FROM public.users $fn$ LANGUAGE sql; /* public.users */",
+			wantSQL: "-- public.users\nCREATE FUNCTION \"replica_public\".f() RETURNS int AS $fn$ SELECT count(*) FROM public.users $fn$ LANGUAGE sql; /* public.users */",
+		},
+		{
+			name:    "unrouted names are left untouched",
+			sql:     "CREATE TABLE other.users (id int REFERENCES other.accounts(id));",
+			wantSQL: "CREATE TABLE other.users (id int REFERENCES other.accounts(id));",
+		},
+		{
+			name:    "relation lists",
+			sql:     "DROP TABLE public.users, public.orders; TRUNCATE public.users;",
+			wantSQL: `DROP TABLE "replica_public"."users", "replica_public"."orders"; TRUNCATE "replica_public"."users";`,
+		},
+		{
+			name:    "if exists",
+			sql:     "DROP TABLE IF EXISTS public.users; DROP FUNCTION IF EXISTS public.f(int);",
+			wantSQL: `DROP TABLE IF EXISTS "replica_public"."users"; DROP FUNCTION IF EXISTS "replica_public".f(int);`,
+		},
+		{
+			name:    "index, trigger and partition relations",
+			sql:     "CREATE INDEX idx ON public.users (name); CREATE TRIGGER t BEFORE INSERT ON public.users FOR EACH ROW EXECUTE FUNCTION public.audit(); ALTER TABLE public.events ATTACH PARTITION public.events_2024 FOR VALUES IN (2024);",
+			wantSQL: `CREATE INDEX idx ON "replica_public"."users" (name); CREATE TRIGGER t BEFORE INSERT ON "replica_public"."users" FOR EACH ROW EXECUTE FUNCTION "replica_public".audit(); ALTER TABLE "replica_public"."events" ATTACH PARTITION "replica_public"."events_2024" FOR VALUES IN (2024);`,
+		},
+		{
+			name:    "column types",
+			sql:     "CREATE TABLE public.users (id int REFERENCES public.accounts(id), status public.mood); ALTER TABLE public.users ADD COLUMN kind public.mood, ALTER COLUMN status TYPE public.mood2;",
+			wantSQL: `CREATE TABLE "replica_public"."users" (id int REFERENCES "replica_public"."accounts"(id), status "replica_public".mood); ALTER TABLE "replica_public"."users" ADD COLUMN kind "replica_public".mood, ALTER COLUMN status TYPE "replica_public".mood2;`,
+		},
+		{
+			name:    "column references in queries are not routed",
+			sql:     "CREATE VIEW public.v AS SELECT u.id, public.f(u.id) FROM public.users u, public.accounts AS a JOIN public.orders o ON u.id = o.user_id GROUP BY u.id, u.name;",
+			wantSQL: `CREATE VIEW "replica_public"."v" AS SELECT u.id, "replica_public".f(u.id) FROM "replica_public"."users" u, "replica_public"."accounts" AS a JOIN "replica_public"."orders" o ON u.id = o.user_id GROUP BY u.id, u.name;`,
+		},
+		{
+			name:    "system schema functions are not routed",
+			router:  catchAllRouter,
+			sql:     "SELECT pg_catalog.set_config('search_path', '', false); ALTER TABLE public.users ALTER COLUMN id SET DEFAULT pg_catalog.nextval('public.users_id_seq'::regclass);",
+			wantSQL: `SELECT pg_catalog.set_config('search_path', '', false); ALTER TABLE "replica_public"."users" ALTER COLUMN id SET DEFAULT pg_catalog.nextval('"replica_public"."users_id_seq"'::regclass);`,
+		},
+		{
+			name:    "catch all route keeps the table names",
+			router:  catchAllRouter,
+			sql:     "CREATE TABLE sales.orders (id int REFERENCES public.users(id));",
+			wantSQL: `CREATE TABLE "replica_sales"."orders" (id int REFERENCES "replica_public"."users"(id));`,
+		},
+		{
+			name:    "positional parameters",
+			sql:     "UPDATE public.users SET name = $1 WHERE id = $2",
+			wantSQL: `UPDATE "replica_public"."users" SET name = $1 WHERE id = $2`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := router
+			if tc.router != nil {
+				r = tc.router
+			}
+			require.Equal(t, tc.wantSQL, r.RewriteSQL(tc.sql))
+		})
+	}
+}
+
+func TestTableRouter_RewriteSQLInSchema(t *testing.T) {
+	t.Parallel()
+
+	router, err := NewTableRouter([]TableRoute{
+		{Source: "public.users", Target: "accounts.app_users"},
+		{Source: "public.orders", Target: "sales.orders"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		sql    string
+		schema string
+
+		wantSQL string
+	}{
+		{
+			name:    "unqualified table",
+			sql:     "ALTER TABLE users ADD COLUMN email text;",
+			schema:  "public",
+			wantSQL: `ALTER TABLE "accounts"."app_users" ADD COLUMN email text;`,
+		},
+		{
+			name:    "unqualified tables in lists and references",
+			sql:     `DROP TABLE IF EXISTS users, "orders" CASCADE; CREATE TABLE items (id int, order_id int REFERENCES orders(id) ON DELETE CASCADE);`,
+			schema:  "public",
+			wantSQL: `DROP TABLE IF EXISTS "accounts"."app_users", "sales"."orders" CASCADE; CREATE TABLE items (id int, order_id int REFERENCES "sales"."orders"(id) ON DELETE CASCADE);`,
+		},
+		{
+			name:    "index names are not qualified",
+			sql:     "CREATE UNIQUE INDEX IF NOT EXISTS users_email ON users (email); ALTER TABLE ONLY users ADD CONSTRAINT c UNIQUE (email);",
+			schema:  "public",
+			wantSQL: `CREATE UNIQUE INDEX IF NOT EXISTS users_email ON "accounts"."app_users" (email); ALTER TABLE ONLY "accounts"."app_users" ADD CONSTRAINT c UNIQUE (email);`,
+		},
+		{
+			name:    "trigger columns and queries are left untouched",
+			sql:     "CREATE TRIGGER t BEFORE UPDATE OF users ON orders FOR EACH ROW EXECUTE FUNCTION f(); CREATE VIEW v AS SELECT * FROM users;",
+			schema:  "public",
+			wantSQL: `CREATE TRIGGER t BEFORE UPDATE OF users ON "sales"."orders" FOR EACH ROW EXECUTE FUNCTION f(); CREATE VIEW v AS SELECT * FROM users;`,
+		},
+		{
+			name:    "unrouted schema",
+			sql:     "ALTER TABLE users ADD COLUMN email text;",
+			schema:  "other",
+			wantSQL: "ALTER TABLE users ADD COLUMN email text;",
+		},
+		{
+			name:    "no schema",
+			sql:     "ALTER TABLE users ADD COLUMN email text;",
+			wantSQL: "ALTER TABLE users ADD COLUMN email text;",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.wantSQL, router.RewriteSQLInSchema(tc.sql, tc.schema))
+		})
+	}
+}
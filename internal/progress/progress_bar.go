@@ -89,6 +89,8 @@ func NewBytesBar(totalBytes int64, description string) Bar {
 			progressbar.OptionEnableColorCodes(true),
 			progressbar.OptionShowBytes(true),
 			progressbar.OptionShowTotalBytes(true),
+			// show the current throughput in bytes per second
+			progressbar.OptionShowIts(),
 			progressbar.OptionShowElapsedTimeOnFinish(),
 			progressbar.OptionSetDescription(description),
 			progressbar.OptionOnCompletion(func() {
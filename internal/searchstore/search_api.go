@@ -39,8 +39,11 @@ type IndexWithIDRequest struct {
 }
 
 type BulkItem struct {
-	Index  *BulkIndex      `json:"index,omitempty"`
-	Delete *BulkIndex      `json:"delete,omitempty"`
+	Index  *BulkIndex `json:"index,omitempty"`
+	Delete *BulkIndex `json:"delete,omitempty"`
+	// Update only updates the fields in the document, keeping the rest of
+	// the existing document. The document is created if it doesn't exist.
+	Update *BulkIndex      `json:"update,omitempty"`
 	Doc    map[string]any  `json:"-"`
 	Status int             `json:"-"`
 	Error  json.RawMessage `json:"-"`
@@ -62,6 +65,10 @@ type BulkResponseItem struct {
 		Status int             `json:"status"`
 		Error  json.RawMessage `json:"error"`
 	} `json:"delete"`
+	Update struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"update"`
 }
 
 type Highlight struct {
@@ -285,6 +292,13 @@ func EncodeBulkItems(buffer *bytes.Buffer, items []BulkItem) error {
 			continue
 		}
 
+		if item.Update != nil {
+			if err := encoder.Encode(map[string]any{"doc": item.Doc, "doc_as_upsert": true}); err != nil {
+				return fmt.Errorf("bulk item [%v]: encode item partial document action %w", item, err)
+			}
+			continue
+		}
+
 		if item.Doc == nil {
 			buffer.WriteString("{}\n")
 			continue
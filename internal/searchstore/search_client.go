@@ -70,6 +70,12 @@ func VerifyResponse(bodyBytes []byte, items []BulkItem) (failed []BulkItem, err
 				items[i].Error = respItem.Delete.Error
 				failed = append(failed, items[i])
 			}
+		} else if items[i].Update != nil {
+			if respItem.Update.Status > 299 {
+				items[i].Status = respItem.Update.Status
+				items[i].Error = respItem.Update.Error
+				failed = append(failed, items[i])
+			}
 		}
 	}
 
@@ -9,6 +9,8 @@
 // migrations/postgres/core/2_create_emit_ddl_function_and_triggers.up.sql
 // migrations/postgres/core/3_fix_duplicate_ddl_emission.down.sql
 // migrations/postgres/core/3_fix_duplicate_ddl_emission.up.sql
+// migrations/postgres/core/4_emit_privilege_and_comment_ddl.down.sql
+// migrations/postgres/core/4_emit_privilege_and_comment_ddl.up.sql
 package pgcoremigrations
 
 import (
@@ -205,6 +207,46 @@ func _3_fix_duplicate_ddl_emissionUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __4_emit_privilege_and_comment_ddlDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd4\x58\x51\x8f\xe2\x38\x12\x7e\xe7\x57\xd4\xc3\xac\x02\x12\xcd\xde\xbe\x76\x8b\x93\x18\x30\x33\xd9\x63\xc2\x08\x68\xed\x9e\x4e\xa7\xc8\x24\x45\xf0\xb4\xe3\x64\x6c\xd3\xd3\xe8\xf6\xfe\xfb\xa9\x9c\x38\x24\x81\x6d\xf5\x8e\x34\x0f\xd7\x8d\x04\x89\xab\xca\xe5\xaf\xaa\x3e\xbb\x7c\x77\x07\x1b\x7c\x46\x6d\xc1\x16\x60\x9e\x44\x59\x0a\x95\x41\xa9\xc5\xb3\x90\x98\x21\x24\x47\xae\x32\x34\xc0\x55\x0a\x49\x91\xe7\xa8\xac\x81\x42\x81\x49\x8e\x98\x73\x33\x18\xcc\x37\x6c\xb6\x63\xb0\xde\xc0\x86\x7d\x5e\xcd\xe6\x0c\x96\x8f\xd1\x7c\x17\xae\x23\x28\x33\x63\x35\xf2\x7c\x82\xb9\xb0\x71\x9a\xca\xe1\x08\x36\x6c\xf7\xb8\x89\xb6\x80\xcf\xa8\x6c\x6c\xb5\xc8\x32\xd4\x03\x00\x80\xd5\x2c\xfa\xf0\x38\xfb\xc0\xa0\x94\x65\x66\xbe\xca\x01\x00\xc0\x96\xcd\x1f\x37\xe1\xee\x9f\xb0\x60\xcb\x30\x62\x1b\x27\xb9\x65\x3b\x30\xc8\x75\x72\x8c\x4b\x6e\x8f\x30\x85\x32\x8b\x13\x6e\xb9\x2c\xb2\x71\x99\xc5\x16\xf3\x72\x30\xdb\xc2\xbb\x77\x83\x05\x9b\xaf\x66\x1b\xe6\x6c\x69\x4c\xe2\xca\xed\x58\xf1\x1c\xc1\xe2\x8b\x7d\x70\x23\x69\x2a\x63\x5a\x1c\x2d\xb2\xfb\xb6\xe4\x67\x59\xf0\x14\xbe\x98\x42\xed\xab\xd7\xc2\xc4\xe6\x6c\x2c\xe6\xb5\x31\xd8\x17\x85\x44\xae\xaa\x51\x7e\x38\x60\x62\x31\x8d\x8b\xfd\x17\x4c\xac\x69\x6b\x16\xfb\x2f\xb1\xc6\xa4\xd0\x29\x54\x5f\x0f\x83\xf7\xec\x43\x18\x39\xc5\xbb\x3b\xd8\x3e\x89\x12\xc4\x01\x92\x42\x1d\x44\x76\xd2\x98\xfa\xa0\xc0\x62\xb1\x02\xab\x79\xf2\x24\x54\xe6\xc4\xc3\x25\x0c\x2f\xab\x9e\x24\x27\xad\x09\x50\x83\xd6\x0a\x95\x0d\x83\x06\x7b\x52\x8f\x65\x91\x05\x63\x08\x76\x9b\x47\x16\x8c\x60\xda\xfc\xda\x7d\x64\xd5\xec\xf4\x5f\x85\xa6\x72\x95\x45\x0b\x08\x97\x0f\x03\xef\xda\x9c\x97\xf6\xa4\x11\xec\x11\x81\x27\xf6\xc4\xa5\x73\xc9\x83\xb6\x47\xca\x1a\x7c\xc1\xe4\x64\x31\xbd\xc2\xf4\x7e\x0a\xde\xc1\xaf\x27\xd4\xe7\xe1\xe8\x62\x79\x81\x16\x75\x2e\x14\xc2\xb7\xa3\x48\x8e\x75\x62\x81\x3d\x0a\x53\x83\xe9\x93\x4f\x4a\x4c\x2c\x70\x29\x1b\x90\xa1\x06\xd9\x23\x62\xb3\xd8\xe5\x15\xad\xd0\x7c\x95\x71\xaa\x8b\x32\xe8\x2e\xf2\xee\x0e\x96\x85\x06\x3f\x3a\x86\x42\xc9\x33\x50\x82\xc2\xa1\xd0\x60\xf5\x09\x61\xb1\x59\x7f\x86\xda\x77\x03\x43\xf7\xb8\x9b\xbd\x5f\xb1\x71\x35\x14\x46\x0b\xf6\xfb\x18\xd0\x26\x93\x51\xdb\xb0\x8b\xdf\x6c\xb5\x63\x9b\x8b\xb6\x3d\x72\x0b\x47\x5e\x96\xa8\x28\x98\x34\x27\x98\x22\x47\x7b\x24\xc4\x86\x52\x3c\x61\xad\xe2\x66\xa8\x26\x98\xaf\x57\x8f\x9f\xa2\x8e\xed\x3d\x26\xfc\x64\xb0\x0d\x6b\x8c\x2a\x85\x6f\x42\x4a\x38\x72\x95\x4a\x0a\x4e\x61\xd0\x81\x55\xea\xe2\x59\xa4\x08\x7b\xb4\x16\x35\xe4\x68\x79\xca\x2d\x6f\x0c\x56\x58\x59\x9e\x41\xb4\xde\xc1\x2a\xfc\x07\x83\xc0\xcd\xfc\x53\x0f\xae\x7e\x5e\x5c\xe5\x46\xed\x1e\x61\xea\x0c\x38\xfc\xcd\x18\x32\xb4\x3e\x94\xae\xd8\xfa\x21\x24\x20\xca\x5e\x04\xe9\xb3\x65\x2b\x36\xdf\xd5\xaa\x55\x9d\x86\xd1\x6e\xdd\x2f\xde\x46\x7e\xb9\x59\x7f\xa2\xf2\xef\xf0\x49\x5c\x5b\xf7\x45\x38\xbc\x40\xb9\x0a\x3f\x85\x3b\xf8\xa5\xeb\xfd\xbc\x76\xcc\xe5\xc2\xc9\xa0\xbe\x3b\xf0\x84\xe2\xd3\xf3\x12\x86\xf8\x92\xc8\x53\x8a\x20\x94\x45\xad\xb8\x84\xcf\x1f\xfc\x60\x27\x5c\x4b\x21\x09\xf8\xfd\xd9\x83\x60\x0b\xf0\xba\x15\x7b\x5c\x6c\x12\x63\x15\xdc\xd8\x71\x9b\xc6\xae\xb3\xeb\xd1\x20\x04\x85\x16\x99\x50\x5c\x06\x70\x10\x28\xd3\xb6\x59\x6f\xcf\x65\xdc\x37\xd4\x08\x22\x2f\xa5\x48\x84\x95\xe7\x66\x21\xdc\x40\x8a\x25\xaa\x14\x55\x22\xf0\x0a\x76\x47\x56\x31\xcf\xb2\xe1\x22\xdc\xee\xc2\x68\xbe\x6b\x24\xe8\x53\x0d\xef\x4f\x42\x7a\x68\x87\x9d\x71\xfa\x04\xf6\x5c\x62\x30\xf6\x33\x4e\x2a\xb9\x98\xde\x8e\xaf\x85\x45\x8a\xca\x0a\x7b\xbe\x56\xf0\x23\x37\x94\x2a\x48\x5b\x2a\xad\xc4\xb8\x21\x5e\x88\xb4\x6b\x5e\xa4\x1d\xa1\xd1\xe0\xfa\x97\xcb\xb9\x3e\x97\x37\xa3\x2e\xe9\xba\x4b\xaf\x01\xf4\xb0\xc1\x3a\x82\x61\x7b\xe9\xd0\x5b\xd6\x65\x2a\xff\xf7\x9a\xf4\xb8\x5d\x10\x6e\x54\xa4\x63\xf0\xc9\xd0\x31\xf5\x57\x0b\x82\xfe\x7f\xfb\xc8\x36\xac\x31\x07\xd3\x8a\x06\x29\xe9\xd6\x54\x11\xb5\x12\xe0\x4b\x93\x4f\xe4\x47\x0a\x42\x75\xb8\xb2\xb1\x39\xf2\x68\x37\x6f\xaa\x19\xea\xb7\x93\xd6\x62\x1c\x01\x85\x11\xd0\x8e\x55\x95\x01\x6d\x54\x97\x42\xa0\x27\xa1\x0e\x85\xce\xb9\x15\x85\xaa\x39\x20\x68\xfb\x3f\x8b\x16\x7f\x6a\xb9\xa2\xb6\xfa\x4c\xf0\x53\xf0\x97\xb5\xc8\xa1\xd7\x75\x5b\x61\xbb\xac\x25\xc5\x03\x3f\x49\x0b\xcf\x5c\x9e\x30\xf0\xbb\x1d\x5b\x6d\x7b\x9b\x54\x8f\xcd\x6f\xef\x55\xd5\x09\xeb\xe7\x6a\x97\x78\x2b\xbf\x26\x1a\xb9\xc5\xf4\x67\x4e\x3c\xf4\xe3\x78\xf6\xe2\xff\xf7\x93\xec\x0f\x24\xd7\x8e\x16\x6d\xef\x7c\x2f\xeb\xc3\xac\xfb\x09\x49\x21\x4f\xb9\x32\x63\x10\xaa\x22\xe7\xea\x05\xa4\x68\xb9\x90\xaf\xf0\xe3\x77\xf3\x62\x92\x77\x72\xe6\x06\x5f\xf9\xa2\xef\x0a\x37\x54\xf0\x0a\x1f\x92\xed\xb7\x70\x61\x92\xdf\xe4\x41\xf8\xe3\x0f\x98\xcf\xb6\xec\x4a\xed\xb7\x8f\x2c\x6a\xfb\x42\x4b\xa9\x12\xdd\xa1\x48\x35\xda\x86\xb3\x3e\x58\x5e\xce\xff\x19\xda\xd8\x09\xc4\xfe\x24\x32\x6c\x5c\x18\x0d\x7a\x93\x01\x5b\x6d\x19\x04\xff\xf9\x6f\x70\x7f\xef\x36\x9c\x8e\x00\x8b\x16\xcd\xf3\xff\x19\x5d\x0b\x15\xe3\x8b\x45\x65\x44\xa1\xde\x42\xd9\xb7\x6b\x6b\x44\x81\x68\x9e\x2a\x5e\x4d\xf2\x1f\xc1\xa9\x49\xfe\x1a\x33\xde\xe2\xc4\x24\xff\x3e\x2e\x25\x39\xd2\xfd\x13\x84\xbc\xe9\xb7\x50\x6d\x4b\xed\xee\x0e\x98\x67\x94\xfa\x24\x54\x11\x00\xe8\xe2\x1b\x50\xe0\x0c\x0c\x8f\xfc\x19\xe9\xb7\x46\x29\x52\xf8\x3b\xfc\x6d\x04\xfb\x93\x85\x27\xc4\xb2\x22\xa9\x14\x0f\x42\x51\x27\x46\xf2\x3d\xa7\xc8\x89\x61\xdf\xb3\x69\x5d\xea\x4e\x82\xfd\x1e\x6e\x77\xdb\x5e\xf2\x35\xe9\xf7\x4b\x13\x7a\xd2\x00\xdb\x93\xfa\x75\x1d\x52\x13\xed\xb0\x34\x25\x4f\x10\x14\x25\xaa\x9d\xd8\x73\x79\x79\x37\x05\x35\x29\x7a\xa5\xec\x13\x43\x4d\x94\x71\xa2\x54\xda\xc1\x24\xa0\xaf\x46\x1d\xa6\x6d\x54\x7d\xee\xf6\xec\x54\x01\xb2\x93\x36\x44\x2d\x91\xd1\xe8\x76\xbf\xe8\x5b\x59\x55\x78\x8e\xae\xec\x1f\x04\xed\x45\x9a\x86\x84\x0d\x0c\x70\x4f\xd8\x95\xd4\xed\x06\xfb\x7e\x7a\xa1\x92\xfe\xe0\xb0\xb7\x61\xd5\xfe\x84\xcb\xfe\x4e\x06\xe1\x16\xa2\xc7\xd5\x8a\x6e\x2a\xae\x66\x78\x73\x1f\xfc\x9e\x38\xde\x75\xc1\xbf\x6e\xe9\x86\xa3\xbe\x1e\xf8\x26\xec\xd1\x35\xc4\xad\xb2\xf2\x2a\xbb\x23\x76\x7a\x65\x61\x0d\x4a\xd7\xe4\x5b\x2e\x94\x71\x0d\x2d\x19\xac\xb7\x9c\x87\x9a\x4c\x0c\x48\x61\xac\xef\xe4\x4c\xb7\x87\x6b\x5f\x4d\xdc\x4f\x5f\xdd\x7f\x82\x34\x95\x74\xfc\xbd\xb0\xc9\x78\xd0\xdb\x39\x1c\x70\xc1\xb8\x8f\x58\x4b\xae\xd6\x8c\x2d\x27\xf2\xa8\x5a\xc7\xd6\x70\xed\x71\x30\x86\xf9\x7a\xb6\x62\xdb\x39\x1b\xf6\xc9\x78\x0c\xc1\xbf\xfe\xed\x19\xbd\x62\x9a\xd6\x25\x00\xa3\xfe\xdb\x7a\xa0\xdc\x95\x13\x70\xca\x0e\xab\xb9\x32\x3c\x21\x9a\xe2\x12\x64\x91\x89\x84\x4b\xc8\xd1\x18\x9e\x61\x1b\xe1\x80\x8e\xac\x01\x94\x5c\xf3\x9c\x6e\x14\x20\xe7\x4f\x48\xcd\x90\x30\x5d\x23\x63\x40\x65\x4e\x9a\x0e\x1e\xa5\x2e\x4a\xd4\x50\xe8\x14\xdd\xb3\x8b\x22\xed\x4e\xb5\x0b\xa6\x3d\x41\xa9\xf1\x20\x5e\xe0\x72\xb1\x42\xb8\x82\x30\xc4\x12\xae\x0f\xab\x13\xfc\x4c\xd9\x61\xd0\xfb\xe8\x2e\xcb\x68\x65\x49\xa1\xcc\x29\x47\x0d\x46\xa4\x95\xe7\x9f\xd9\x66\xb9\xde\xb8\xf2\xaf\x17\x16\xbb\x8b\xb2\x5a\xf3\x12\x42\x5a\x5a\x0b\xed\x8e\x07\x97\xf7\xad\x9c\xb8\xbf\xa7\x5b\x2c\x0f\x32\x8b\x16\x0f\x83\x77\xef\x1e\x06\x03\x77\x58\x6f\xae\xe7\xc2\xa5\xa7\xa7\xc6\x22\x6d\xd4\xcd\xdd\x9f\x0f\xdd\xd0\xe2\x8b\x1d\x3d\x0c\xfe\x37\x00\x65\x87\xeb\x95\x2b\x14\x00\x00")
+
+func _4_emit_privilege_and_comment_ddlDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__4_emit_privilege_and_comment_ddlDownSql,
+		"4_emit_privilege_and_comment_ddl.down.sql",
+	)
+}
+
+func _4_emit_privilege_and_comment_ddlDownSql() (*asset, error) {
+	bytes, err := _4_emit_privilege_and_comment_ddlDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "4_emit_privilege_and_comment_ddl.down.sql", size: 5163, mode: os.FileMode(420), modTime: time.Unix(1792349593, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __4_emit_privilege_and_comment_ddlUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd4\x5a\x59\x73\xdb\xb8\xb2\x7e\xd7\xaf\xe8\x3b\xe5\x29\x92\x09\xa5\x99\xbc\xda\xa5\xa4\x14\x89\x4e\x34\xa3\x48\x2e\x49\x9e\xa5\xe2\x0c\x0b\x26\x5b\x12\xc6\xdc\x42\x40\x5e\xee\xcd\x3d\xbf\xfd\x54\x63\xe1\x26\xd9\x71\x52\x67\x1e\x8e\xe3\xc8\x22\x80\x6e\x34\xbe\x5e\x01\xb0\xdf\x87\x20\xe5\x12\x8a\x92\xdf\xf2\x04\xb7\x08\xd1\x8e\x65\x5b\x14\xc0\xb2\x18\xa2\x3c\x4d\x31\x93\xa2\xd7\xef\xc3\xbb\xe5\x68\xbe\xf6\x61\x19\xfc\xb6\xf8\x35\x50\xbd\xa3\xd9\x3a\x58\xc2\x24\x38\x1f\x5d\xce\xd6\x70\xb1\x9c\xfe\x36\x9d\x05\xef\x82\x15\xc4\x79\xe6\x48\x28\xb1\xc8\x4b\x09\x72\x87\x20\xa2\x1d\xa6\x0c\xf2\x0d\x3d\x11\x33\xb6\xd9\x60\x24\x31\x86\xfc\xfa\x6f\x8c\xa4\xf0\x15\xc3\x0c\xb9\xdc\x61\x09\x71\x5e\xcd\x0c\x79\x66\xa8\x85\x0f\x22\x27\xfa\x07\xb8\xc3\x12\x41\xdc\xf0\xa2\xc0\x78\x40\xec\xd6\xd4\xca\x4a\x84\x2c\xbf\x03\x4c\xb9\x24\xd6\x77\x5c\xee\x68\x7c\x3d\x99\x11\xe3\x6e\x87\x19\xb0\x5b\xc6\x13\x76\x9d\xa0\x9e\x9a\x06\xe7\x7b\x49\xcc\xf2\x0c\x21\x27\x39\xee\xb8\x40\x33\x29\xd3\xeb\x90\xac\xdc\xa2\x14\x10\xb1\x0c\x58\x51\x24\x0f\xd4\x9a\x0e\x7a\x44\xb6\x44\x91\x27\xb7\x28\xa8\x09\x4a\x4c\x98\xe4\x79\x26\x80\x69\xe0\x20\x2f\x2d\x74\x42\x32\x89\xb4\x38\xc5\x82\x13\x45\x3e\x80\xf5\x0e\x01\x6f\x31\x53\x22\xc8\x92\x6f\xb7\x0a\x08\x14\x6d\x28\xd3\x1a\x05\x5a\xaf\x64\x37\x98\xc1\xa6\xcc\x53\x6a\xab\x59\x3b\xc2\xc8\x4a\xdc\x12\x2e\xe4\x00\x2e\xac\x86\x15\xa6\x77\xbb\x3c\xb1\x7a\x11\x47\x54\xc5\x33\x21\x91\xc5\x83\xde\x78\x19\x8c\xd6\x01\x2c\x96\xb0\x0c\x2e\x66\xa3\x71\x00\xe7\x97\xf3\xf1\x7a\xba\x98\x43\xb1\x15\xb2\x44\x96\x0e\xb6\x28\xc3\xca\x80\x42\xa3\x52\x37\x8e\x13\x90\x78\x2f\xbd\xde\x32\x58\x5f\x2e\xe7\x2b\xf8\x5b\xe4\xd9\x75\x6f\x36\x9a\xbf\xbb\x1c\xbd\x0b\xa0\x48\x8a\xad\xf8\x9c\xf4\x56\xeb\xd1\xdb\x59\xd0\x5b\x05\x6b\x10\xc8\xca\x68\x17\x16\x4c\xee\x60\x08\xc5\x36\x8c\x98\x64\x49\xbe\xf5\x8b\x6d\x28\x31\x2d\x7a\xa3\x15\x9c\x9c\xf4\x26\xc1\x78\x36\x5a\x06\x3d\x00\x30\xeb\x0c\x69\x91\x6a\xba\xb3\x66\x6b\xc6\x52\x6c\xb4\xd2\x63\x58\xb0\x52\x0a\xd5\xf8\xf1\x93\x1e\x5c\x62\x02\x25\x46\x79\x19\xeb\x67\xb3\x02\x2d\x2f\x9c\x0e\xc1\xf9\xf8\xc9\x39\x3d\x55\x8f\x67\xbd\xb7\xc1\xbb\xe9\xfc\x60\xea\xd3\x21\xb8\x25\x6e\xf1\xbe\x08\x53\x26\xa3\x1d\x2d\xdf\x07\xe7\x2a\x5d\xcc\xaf\xc4\x4b\x77\xf0\xe2\x8d\x47\x7f\xdf\x9c\xae\x17\x5f\xce\x97\x8b\x0f\xde\x95\x70\x7c\x70\xb8\x70\x3c\xef\xe3\x2b\x23\xc8\xf4\xbc\xc5\x73\xba\x82\xf9\xe5\x6c\x06\xeb\xf7\x81\x9e\x90\xfe\x69\x30\x55\x87\x26\x0a\xe6\x13\x98\x9e\x9f\xf5\xd4\x43\xbf\x0f\x23\xa2\x20\x44\x57\x30\x9d\xc3\x6a\xfc\x3e\xf8\x30\x52\x36\x6e\xbe\x5a\x23\x36\x06\x9c\xb7\x6d\xe1\x98\x1c\xff\x7a\x01\xce\x5f\xee\x68\x36\xbb\x12\x2f\xaf\xee\x5e\x5e\x89\x97\x53\x5a\x94\xf7\x46\x73\xbc\x12\x4e\x5b\xc4\xf3\xc5\xd2\xd2\x13\xe2\x60\xe0\xb2\xff\x56\xc1\x2c\x18\xaf\x41\x96\x3c\x75\x85\x07\x84\x06\x18\xe8\x44\x91\x70\x19\xca\x3c\x94\xe4\x9a\x6e\x8b\x8c\x7e\xcd\xb0\x12\x8b\x84\x45\xe8\x36\x84\xf4\xbf\x22\xe2\x4b\x42\x9b\xfe\x73\xc7\xf3\x0f\xf8\x3a\xbe\xfb\x66\xe8\xbe\x39\xfd\xf8\xd7\x0f\x9f\x5e\xfc\xa0\x3f\xbd\x17\xea\xef\x89\xe7\xb4\x86\x7b\x20\xaa\xe7\xd9\x62\x71\xd1\xea\xac\x6d\xa3\xf9\xd3\x30\xbb\xd3\x21\x14\xac\x14\x18\xf2\x18\x33\x69\x17\x40\x03\xbc\xb3\x16\x5d\xf0\xc7\x38\xb8\x50\x6e\xf6\xfb\xfb\x60\x0e\x8b\xf5\xfb\x60\xb9\x6a\xc3\x6c\x7f\xc6\x8b\xf9\x7a\x3a\xbf\x0c\x3a\x0c\xe6\x93\x76\x83\xb5\xea\xd3\x61\xf5\xf5\xcb\x17\x6d\xe3\xe1\xf5\x9e\x27\xb1\xf1\xdc\x43\xd4\x1d\xf9\x50\x20\x61\xa7\x83\x83\x73\x04\x3f\xb5\x1c\x2e\x1f\x1c\x1f\x36\x79\x99\x32\xe9\x3a\x3f\x4e\x1d\xbf\xb1\xf4\x8f\xaf\x3e\x1d\x03\xde\xb2\x6c\x8f\x6c\x8d\x6b\x20\x43\xb6\x4e\xa0\x9f\x1d\x73\x87\xe9\xb9\x6b\x16\xe6\x37\x3d\xd6\x3b\xee\x26\x8b\x2c\x79\xa8\xd3\x9e\x0a\x8a\x8d\xa8\x5d\x22\x94\x3a\xa0\xc7\x67\xb0\xd9\x67\x91\x6a\xf7\x81\x90\xd0\xb9\x51\xa5\x08\x61\xb9\x51\x30\x6e\x26\x9e\x7c\x2f\x2d\xca\xc7\x3c\xea\x7f\xb4\x4b\xa9\xc0\xa7\xcc\xf4\xe3\x5f\xee\x95\xf8\xf4\xd2\xbd\x12\x2f\xfc\x2b\xf1\xc2\x3c\x7a\x2f\x4e\x9c\x6f\xf1\xfe\x27\x1c\xef\x3b\x9d\xee\x69\x87\xb3\xf2\x3f\xea\x5b\xcf\xf4\x2b\xeb\x53\x2d\x7f\x3a\xf4\xa5\xda\x44\x9e\xe7\x47\xcf\xf4\xa1\x43\xff\x51\xbe\x53\x3d\xe9\x02\xa3\x4a\xca\xe5\x3e\x13\x4a\xc7\xc0\x1a\x19\xca\xa4\x2e\xa0\xd4\xa5\x72\xf4\x3e\xfb\xbc\x67\x09\xdf\x70\x8c\x9b\x9c\x68\x0d\xda\xbe\x92\x3c\xbf\xc1\x18\xf6\x05\xf0\x4c\x65\xef\xbd\xc0\xd2\x86\x61\x1f\x8a\x12\x37\x58\x96\x3c\xdb\x42\xb1\xbf\x4e\x78\xd4\x55\x65\x34\xc8\x79\xec\x43\x34\x28\x31\x21\xae\xe6\xeb\x0d\xcf\x62\x1f\xb2\x41\x26\x0a\x63\x03\xeb\x05\xd5\x23\x15\xb9\x8a\xb7\x24\x78\xc2\x84\x80\x9a\xed\x2f\x8b\x29\xe5\x74\x05\xa2\x28\x58\x84\x90\xc1\x62\x0e\x19\x4d\x03\xc3\x7a\x1e\xd5\x57\x51\xfd\xfe\x3e\x58\x06\x75\x27\x0c\x9b\x8e\xcc\xca\x92\x3d\x84\x09\x66\x5b\xb9\x73\xeb\x76\x1f\x5e\x79\x4d\x17\x1f\xcd\x27\xb5\xec\x94\xb4\x5c\xa7\x24\x73\x2a\xe8\xe3\x96\x3e\x52\xfa\x58\xd1\xc7\xc6\xf1\x3a\x94\xe3\xd1\x2a\x68\x34\x19\xa1\xe6\xf0\xc4\xe4\xf0\x1a\x5e\x29\xbf\x6a\xe0\xf4\x5c\xc1\xa1\x0f\x9d\xf8\x04\x10\xcc\x56\x01\xcc\x17\xeb\xba\x24\xe2\x22\x14\x0f\x42\x62\x1a\x6a\x85\xba\xd5\x44\x4d\xf1\x83\xf9\xa4\x7a\x5a\x2c\x27\xc1\x12\xde\xfe\xd9\x12\xc9\xd1\xaa\x77\x60\x12\xac\xc6\x0d\xad\x56\x54\xb3\xe9\x87\xe9\x1a\x5e\x35\xac\x75\x7a\xae\x24\x39\x5f\x5c\xce\x27\xcf\xb4\xf5\x3a\x80\x3c\x9e\x27\xdc\xaf\x25\x0a\x9b\x24\x48\x1d\x64\x6f\x56\x9f\xad\x41\x95\x72\x9c\x5b\x1d\xd8\xc0\xb9\xe5\x78\xe7\x3c\x32\x28\xb5\x83\x52\x26\xb1\xe4\x2c\xe1\xff\x8b\x31\x3c\x45\xb1\xb2\x14\x02\x3f\xef\x31\x8b\xf0\xb1\x81\x1b\x3b\x70\x93\x97\xc8\xb7\x19\xa8\xe0\x77\x38\x5a\xa9\xd6\x39\xd2\x19\xcc\x27\x7e\xef\xeb\x39\x70\xa0\xd2\x20\xe1\x61\x54\xe7\x5b\x70\x48\xc5\xdd\x70\x59\x65\xc3\x26\x41\x7b\x48\xce\x63\xd3\x9f\xf3\x1a\x5c\x8f\x74\x74\xe0\x09\x6a\xa9\x0d\x5d\xb4\x7c\xcb\xd3\x00\xb4\xaa\x78\xb5\xce\x30\x45\xc9\x62\x26\x99\x6b\x66\xf1\x7a\x87\x88\xfc\xdf\xff\xdb\xfc\x5a\x75\x06\xf3\x49\x23\xd7\x52\x2c\x37\x56\xf5\xac\x1c\xad\x62\xee\xc9\xc9\x59\xef\x59\x5b\x0e\xca\xb5\x61\x1c\x27\xae\x67\x52\xe2\x4a\xef\x9e\x42\x13\xa5\xd5\xbc\x07\x3b\x0d\x6a\x5c\x05\xe3\xcb\xe5\x74\xfd\x27\x6d\x5c\xa7\xf3\x60\xa9\x46\x7e\xe7\xde\xa3\xc4\xc8\xf8\x77\x77\xa7\x11\xc7\x49\x48\x7b\x58\xaa\x14\xda\xad\x05\x7b\x48\x72\x16\x83\xd9\x4d\x50\x73\x37\x58\xc0\x75\x9e\x27\xc8\x32\xdd\x6b\xb7\xaf\xc6\xeb\x44\x93\x32\xbf\xfe\x3b\xd4\x3b\x97\x6a\x03\x53\xa7\xcd\x7e\x1f\x56\x37\xbc\x00\xbe\x81\x28\xcf\x36\x7c\xbb\x2f\x31\x06\x99\xab\xad\x33\x4c\x26\x33\x90\x25\x8b\x6e\x78\xb6\xb5\x25\x8a\x5b\xaf\x7a\x10\xed\xcb\x12\x33\x19\x0a\x94\x92\x67\x5b\xd7\xa9\xb0\x27\xf2\x30\xc9\xb7\x14\x8e\xd7\xcb\xcb\xc0\xf1\x60\x58\x7d\x6b\x45\x1c\xad\x9a\x23\x85\x4a\xbf\x0f\x63\x56\xc8\x3d\x6d\x61\x69\x87\x1e\xc9\x3d\x4b\x94\x48\x16\xb4\x6b\xa4\xcc\x87\xf7\x18\xed\x25\xc6\x07\x98\x9e\x0e\xc1\x0a\xf8\x79\x8f\xe5\x83\xeb\xd5\x9c\x27\x28\xb1\x4c\x79\x86\x70\xb7\xe3\xd1\xce\xa4\x54\x90\x3b\x2e\x0c\x98\xf6\x74\x23\x49\x30\x92\xc0\x92\xa4\x02\xf9\xa0\x68\xdb\x86\xca\xae\x68\x85\xe2\x73\x12\xc6\x65\x5e\x74\x4a\xb2\x7e\x1f\xce\xf3\x12\x6c\xaf\x0f\x39\x55\x96\x64\xa0\x54\x0a\x83\x2c\xf7\x08\x93\xe5\xe2\x02\x8c\xec\x02\x5c\xf5\xa8\x8a\x27\x5f\x77\x4d\xe7\x93\xe0\x0f\x1f\x50\x46\x83\xda\xd9\xac\xfe\xf4\x39\x4b\x45\x2d\x77\x4c\xc2\x8e\x15\x05\x66\xa4\x4c\x9a\x13\x44\x9e\xa2\xdc\x11\x62\x6e\xc2\x6f\xd0\x90\xa8\x19\xf4\x04\xe3\xc5\xec\xf2\xc3\xbc\xc5\xfb\x1a\x23\xb6\x17\xd8\x84\x35\xc4\x8c\xca\xd7\x24\x81\x1d\xcb\xe2\x84\x94\x93\x0b\x54\x60\x15\x65\x7e\xcb\x63\x84\x6b\x94\x12\x4b\xb0\x21\xa2\x62\x48\x5b\xd7\x6d\x28\xd9\x56\x65\x9f\xd9\xf4\xd7\x00\x1c\x35\xf3\x8f\x1d\xb8\xba\x76\x71\x60\x1b\x46\x3c\xc2\x54\x31\x50\xf8\x0b\x1f\xb6\x28\xad\x2a\x95\xb3\x75\x55\x48\x40\x14\x1d\x0d\x36\xca\xa5\xa6\x9f\x9a\x9a\xa8\xe5\xbc\x07\xf5\x51\x2b\x9e\x84\x86\xbb\x75\x42\xd7\x7b\x22\x05\x93\x75\x1b\xc1\x94\x2d\x50\x61\xd7\xdf\xb0\x88\xf4\xd3\x91\x12\x5c\xbc\x8f\x92\x7d\x8c\xc0\x33\x89\x65\xc6\x12\xb8\x78\x67\x3b\x5b\xea\x3a\xe7\x09\x01\x7f\xfd\x50\xd9\x73\x0e\x96\x56\x97\x1a\x35\x4f\x3a\x2d\xc9\x19\xd5\xe8\x8d\x30\x76\x68\x5d\x97\x02\xc1\xc9\x4b\xbe\xe5\x19\x4b\x1c\xd8\x70\x4c\xe2\x26\x5b\xcb\x4f\x59\x9c\x3a\x74\xe3\x69\x91\xf0\x88\xcb\xe4\xa1\x5a\x08\x13\x10\x63\x81\x59\x8c\x59\xc4\xf1\x00\x76\x15\xac\x42\xb6\xdd\xba\x93\xe9\x6a\x3d\x9d\x8f\xd7\xd5\x08\xfa\xfd\x96\xed\xa7\x99\x71\xa0\xc7\x85\x54\x6f\x3c\xbd\x13\xed\x10\xd8\x9e\xa7\x76\xa1\x96\xa4\x61\x18\x47\x86\xeb\x1c\xdc\x60\xdf\xc8\xc4\xf4\xeb\xf5\x0e\xbf\x29\x9b\xeb\xc6\xf2\xaa\x57\x15\xe5\xed\xa5\x1b\x00\x2d\x6c\x54\x8b\x9b\xdc\xa9\x97\x6e\xd4\x5d\x2d\xab\x9e\xca\xfe\x3c\x35\xda\x37\x66\xa4\x97\x48\xbd\xb4\x99\xb0\xc6\xd0\x62\xf5\xad\x0e\x61\x2a\x8f\x65\x50\xb1\x83\xa1\x0e\x83\xd5\xbe\xdb\x10\x01\xde\x57\xf6\x44\x72\xc4\xb4\x15\x6a\xc6\xca\x8a\xa7\x67\xd1\xae\x5a\xf4\x0c\x47\xf4\xa5\x02\x90\x2a\x74\xac\x1b\x50\xa2\xaa\x1d\x81\x9e\x78\xa6\x2b\x34\x9e\x67\x26\x06\x1c\x6c\x2b\x1e\xe3\xac\x43\x9b\xa9\x09\x7e\x74\xbe\x99\x8a\x04\x7a\x9a\xb6\xa1\xb6\x7a\x2d\x31\x6e\xd8\x3e\x91\x70\xcb\x92\x3d\x3a\x36\xdb\x05\xb3\x55\x27\x49\x75\xa2\xf9\xf1\x5c\xa5\x2b\xac\x9f\x74\x96\x78\x6e\x7c\x8d\x4a\x64\x12\xe3\x9f\x18\xc5\xa1\x7f\x2e\xce\xd6\xf2\x7f\x7f\x90\xfd\x07\x83\x6b\x8b\x8a\xd2\x3b\x55\xcc\xba\x9e\x50\xc5\x33\x44\x79\xb2\x4f\x33\xe1\x03\xcf\x74\x70\xd6\x0d\x10\xa3\x64\x3c\x79\x22\x3e\x7e\x77\x5c\x8c\xd2\x96\xcd\x1c\x89\x57\xd6\xe9\xdb\x83\xab\x50\xf0\x44\x3c\x24\xde\xcf\x89\x85\x51\x7a\x34\x0e\x1e\xdd\x95\x18\xef\x9d\x37\x65\xa1\xa5\x68\x43\x57\x28\x92\x8f\x36\xe1\x7c\xce\x66\xa5\x12\xc1\xeb\x75\x26\x7b\x7c\xcb\x62\x8a\x8f\xea\xf9\xbf\x2c\x5c\xf3\x2c\xc4\x7b\x89\x99\xe0\x79\xf6\x9c\x90\x7d\xdc\xb7\x3c\x52\x44\xf7\x40\x27\xfd\x27\x62\x6a\x94\x3e\x15\x19\x8f\xc5\xc4\x28\xfd\xbe\x58\x4a\xe3\x88\xf6\x11\x84\x2c\xeb\xe7\x84\xda\x06\x19\xdd\x96\xda\x88\x62\x2a\x21\xe3\xf5\x65\x7e\x67\xce\x86\xdd\x1d\xbb\x45\xfa\x5e\x62\xc2\x63\x78\x0d\x3f\x7b\x70\xbd\x97\x70\x83\x58\xe8\x20\x15\xe3\x86\x67\xb4\x13\xa3\xf1\x1d\xa1\x48\x08\xb7\x2b\xd9\xd0\xb8\xba\x1a\x11\xfc\x31\x5d\xad\x57\x1d\xe3\xab\xcc\xef\x55\xa5\x7a\xa2\x00\xd9\x19\xf5\xc8\x19\x9f\x1c\xc8\x87\xa2\x6e\x1b\xea\x43\xbf\x0e\xad\x4e\xb8\xf5\xe9\xd4\x97\x2f\xe0\x0c\x1c\xf2\xf0\x8a\x1c\x86\x4d\x54\xad\xed\x76\xf8\x68\x05\xc9\x41\x13\xa2\xc6\x10\xcf\x3b\xbe\x5f\xd4\xd7\xa8\x14\x67\xcd\x3d\xea\xc1\x2d\xb3\xf5\x56\xe3\x3b\x8d\x0b\xe3\xe6\x71\xbe\xe5\x77\x78\x69\xaa\x6f\x82\x1b\x97\xa0\xfa\xbe\x1a\x36\xbc\x14\x52\x5d\x0b\x73\x7b\x55\x4a\xf7\xce\x47\x76\x88\x0d\x07\xa3\xad\x94\xa3\x57\xaa\xb7\x45\xca\xb4\xd4\x22\xc8\x59\xf4\x1a\xba\xfb\xe5\x6e\xbc\x51\xc7\xdc\x5f\xbd\x65\xb5\x53\x1a\xe0\x8e\x1d\x4c\x9c\x0e\x0f\x78\xf7\x5f\xff\xdc\x7f\xfd\xda\x46\xfa\xe3\x98\x57\x77\xc6\x47\xdf\x0a\x68\xdc\xcd\xeb\x2b\xf2\x28\xdf\x27\x31\x29\xe5\xba\x86\x9b\x8e\xba\x2d\xbb\xee\x05\x09\x33\xe4\x3e\xec\xb3\x04\x85\xa8\x95\xc5\xae\xdb\xfd\x7a\x23\xcf\xd5\x88\xcc\xb2\xb3\xaa\x00\x26\x8e\xdd\xf3\x57\x1a\xea\x82\x61\xaf\x55\xbf\xa6\x1c\x1f\x9c\xc7\x5e\x71\x20\x15\x8e\x17\x1f\x3e\x04\xf3\x75\x57\x87\xc6\x11\x3b\x3e\xf0\x1f\x2c\x85\x4c\x69\xdd\x8e\x0f\x46\x8d\x4f\xd4\x4b\x8f\x00\xb1\x58\xd7\x60\x3c\x7e\x7a\xdd\xa1\xf4\x9e\xbf\xa5\xb7\xe9\xf7\xe0\x54\x83\x6f\x20\xcb\x8d\xaa\x40\x87\x0a\xba\x2b\xa1\x17\x25\xf8\x06\xb8\x74\xe8\xdd\x09\x53\x7b\xe9\x51\x15\x8b\x83\xf3\xb2\xd3\xe1\x37\xc8\x7e\xf6\x35\x48\x08\x8e\xc5\xf2\xf0\x54\xee\xdb\xd6\x6c\xbe\x5b\x63\x7d\x4b\xa5\x9c\x32\xd3\x5f\x56\xf4\xee\x84\x39\x05\x24\x57\x50\xe7\x5e\x8d\xec\x69\x49\xe8\x95\x90\xe6\x91\x18\x97\x02\x13\x75\x96\x27\x19\xa7\x57\x4b\x92\x44\x31\x34\x95\xe5\x99\x8d\x7b\x40\xd7\x73\xf6\xc0\x46\xb4\x8f\x6a\x9a\x27\x90\xa7\xc3\x27\xcb\x4c\x27\x8e\x13\xc7\x6f\x1e\x0f\xd5\xd5\x9f\xb1\x37\x65\x0c\x8e\xdf\x45\xb1\x31\xce\x50\x92\x93\x39\xbe\xf1\xb6\x46\xb7\x91\xd8\xf1\x61\xbc\x18\xcd\x82\xd5\x38\x70\xbb\x71\xaa\x7d\x97\xdb\x33\x17\xc2\x16\x23\xf5\xf2\x92\xb4\x40\xa9\x20\x45\xe1\x80\xd1\xd1\x66\x26\x98\xba\xb6\x65\x09\x24\xf9\x96\x47\x2c\x81\x14\x85\x60\xdb\x2a\x20\x11\xc2\x0e\xed\x4c\x1d\xba\x95\x67\x29\x1d\x1c\x42\xca\x6e\xe8\x9d\x1c\x3a\x2d\x6c\x31\xf1\x01\x33\xb1\xd7\x17\x72\x65\x5e\x60\x09\x79\x19\xa3\x7a\x56\x5a\xa4\xe3\x30\x1b\x27\x9b\x13\xd0\x45\x1e\xbf\x87\xfa\xfc\x94\x70\x05\x2e\xa8\x18\x50\xc7\x2d\xc6\xf8\xd5\xcb\x44\x02\xad\x8c\xea\x46\x9a\x56\x16\xe5\x99\xd8\xa7\x74\x35\xc8\x63\x2d\xf9\x45\xb0\x3c\x5f\x2c\x55\x96\x37\x0b\x0b\x29\xb4\x86\x86\xb2\x56\x21\x2d\xad\x81\x76\x4b\x82\xba\xbd\x61\x13\xa7\xa7\x74\x58\x6d\x41\x0e\xe6\x93\xb3\xde\xc9\xc9\x59\xef\xdf\x03\x00\xbc\x75\x81\xb2\x26\x26\x00\x00")
+
+func _4_emit_privilege_and_comment_ddlUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__4_emit_privilege_and_comment_ddlUpSql,
+		"4_emit_privilege_and_comment_ddl.up.sql",
+	)
+}
+
+func _4_emit_privilege_and_comment_ddlUpSql() (*asset, error) {
+	bytes, err := _4_emit_privilege_and_comment_ddlUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "4_emit_privilege_and_comment_ddl.up.sql", size: 9766, mode: os.FileMode(420), modTime: time.Unix(1792349593, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -263,6 +305,8 @@ var _bindata = map[string]func() (*asset, error){
 	"2_create_emit_ddl_function_and_triggers.up.sql":   _2_create_emit_ddl_function_and_triggersUpSql,
 	"3_fix_duplicate_ddl_emission.down.sql":            _3_fix_duplicate_ddl_emissionDownSql,
 	"3_fix_duplicate_ddl_emission.up.sql":              _3_fix_duplicate_ddl_emissionUpSql,
+	"4_emit_privilege_and_comment_ddl.down.sql":        _4_emit_privilege_and_comment_ddlDownSql,
+	"4_emit_privilege_and_comment_ddl.up.sql":          _4_emit_privilege_and_comment_ddlUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -312,6 +356,8 @@ var _bintree = &bintree{nil, map[string]*bintree{
 	"2_create_emit_ddl_function_and_triggers.up.sql":   &bintree{_2_create_emit_ddl_function_and_triggersUpSql, map[string]*bintree{}},
 	"3_fix_duplicate_ddl_emission.down.sql":            &bintree{_3_fix_duplicate_ddl_emissionDownSql, map[string]*bintree{}},
 	"3_fix_duplicate_ddl_emission.up.sql":              &bintree{_3_fix_duplicate_ddl_emissionUpSql, map[string]*bintree{}},
+	"4_emit_privilege_and_comment_ddl.down.sql":        &bintree{_4_emit_privilege_and_comment_ddlDownSql, map[string]*bintree{}},
+	"4_emit_privilege_and_comment_ddl.up.sql":          &bintree{_4_emit_privilege_and_comment_ddlUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
@@ -0,0 +1,277 @@
+-- Emit privilege changes and comments
+-- GRANT, REVOKE and ALTER DEFAULT PRIVILEGES don't report the schema of the
+-- affected objects, and neither do comments on schemas, so they were skipped.
+-- They are now emitted with the affected schema when available, and without
+-- one otherwise, so that the targets can apply them.
+
+-- Resolves the relations a GRANT or REVOKE statement applies to. The event
+-- trigger doesn't report them, so they are taken from the statement's target
+-- list. Privileges on whole schemas report the schema instead.
+CREATE OR REPLACE FUNCTION pgstream.get_privilege_objects(ddl text)
+RETURNS jsonb
+LANGUAGE plpgsql
+STABLE
+SET search_path = pg_catalog,pg_temp
+AS $$
+DECLARE
+    target_list text;
+    target_name text;
+    name_parts text[];
+    rel record;
+    objects jsonb := '[]'::jsonb;
+BEGIN
+    target_list := (regexp_match(ddl, '\mON\s+(.*?)\s+(?:TO|FROM)\s', 'is'))[1];
+    IF target_list IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    -- ALL TABLES IN SCHEMA and SCHEMA targets apply to whole schemas
+    IF target_list ~* '^(ALL\s+\w+\s+IN\s+)?SCHEMA\s' THEN
+        FOR target_name IN
+            SELECT trim(s) FROM regexp_split_to_table(
+                regexp_replace(target_list, '^(ALL\s+\w+\s+IN\s+)?SCHEMA\s+', '', 'i'),
+                ',(?=(?:[^"]*"[^"]*")*[^"]*$)'
+            ) s
+        LOOP
+            BEGIN
+                name_parts := parse_ident(target_name);
+            EXCEPTION WHEN OTHERS THEN
+                CONTINUE;
+            END;
+            objects := objects || jsonb_build_object(
+                'type', 'schema',
+                'identity', format('%I', name_parts[1]),
+                'schema', name_parts[1]
+            );
+        END LOOP;
+        RETURN NULLIF(objects, '[]'::jsonb);
+    END IF;
+
+    -- Only privileges on relations are resolved; functions, types and others
+    -- are emitted without objects
+    IF target_list !~* '^(TABLE\s+)?[^(\s]+(\s*,\s*[^(\s]+)*$' THEN
+        RETURN NULL;
+    END IF;
+
+    FOR target_name IN
+        SELECT trim(s) FROM regexp_split_to_table(
+            regexp_replace(target_list, '^TABLE\s+', '', 'i'),
+            ',(?=(?:[^"]*"[^"]*")*[^"]*$)'
+        ) s
+    LOOP
+        BEGIN
+            name_parts := parse_ident(target_name);
+        EXCEPTION WHEN OTHERS THEN
+            CONTINUE;
+        END;
+
+        -- The trigger runs with a pg_catalog search path, so unqualified
+        -- names are looked up in the user schemas, preferring public
+        SELECT c.oid, c.relname, c.relkind, n.nspname INTO rel
+        FROM pg_class c
+        JOIN pg_namespace n ON n.oid = c.relnamespace
+        WHERE c.relname = name_parts[array_length(name_parts, 1)]
+          AND c.relkind IN ('r', 'p', 'v', 'm', 'S', 'f')
+          AND CASE
+              WHEN array_length(name_parts, 1) > 1 THEN n.nspname = name_parts[array_length(name_parts, 1) - 1]
+              ELSE NOT pgstream.is_system_schema(n.nspname)
+          END
+        ORDER BY n.nspname = 'public' DESC, n.nspname
+        LIMIT 1;
+
+        IF NOT FOUND THEN
+            CONTINUE;
+        END IF;
+
+        objects := objects || (jsonb_build_object(
+            'type', CASE rel.relkind
+                WHEN 'v' THEN 'view'
+                WHEN 'm' THEN 'materialized view'
+                WHEN 'S' THEN 'sequence'
+                WHEN 'f' THEN 'foreign table'
+                ELSE 'table'
+            END,
+            'identity', format('%I.%I', rel.nspname, rel.relname),
+            'schema', rel.nspname,
+            'oid', rel.oid,
+            'pgstream_id', CASE
+                WHEN rel.relkind IN ('r', 'p') THEN
+                    (SELECT id::text FROM pgstream.table_ids WHERE oid = rel.oid)
+                ELSE NULL
+            END
+        ) || CASE
+            WHEN rel.relkind IN ('r', 'p') THEN pgstream.get_table_metadata(rel.oid)
+            ELSE '{}'::jsonb
+        END);
+    END LOOP;
+
+    RETURN NULLIF(objects, '[]'::jsonb);
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION pgstream.emit_ddl() RETURNS event_trigger
+    LANGUAGE plpgsql
+    SECURITY DEFINER
+    SET search_path = pg_catalog,pg_temp
+AS $$
+DECLARE
+    rec_schema_name text;
+    ddl_command text;
+    ddl_payload jsonb;
+    is_system_schema boolean;
+    affected_objects jsonb;
+    obj_record record;
+BEGIN
+    -- Skip if configured to skip DDL tracking
+    IF (pg_catalog.current_setting('pgstream.skip_log', 'TRUE') = 'TRUE') THEN
+        RETURN;
+    END IF;
+
+    -- Capture the actual DDL command being executed
+    ddl_command := current_query();
+
+    -- Determine which schema this affects and collect all affected objects
+    IF tg_event = 'sql_drop' THEN
+        -- For sql_drop, only emit for true DROP commands (DROP TABLE, DROP INDEX, etc.)
+        -- Skip ALTER commands that happen to drop something (like ALTER TABLE DROP COLUMN)
+        -- because ddl_command_end will handle those and provide better metadata
+        IF tg_tag NOT LIKE 'DROP %' THEN
+            RETURN;
+        END IF;
+
+        -- For DROP events, get schema name and collect all dropped objects
+        SELECT schema_name INTO rec_schema_name
+        FROM pg_event_trigger_dropped_objects()
+        LIMIT 1;
+
+        -- Collect only user-facing dropped objects (exclude internal PG objects)
+        -- Filter by schema to exclude system objects (pg_toast, pg_catalog, etc.)
+        -- Include pgstream_id for tables from table_ids mapping
+        -- Use 'original' field to exclude objects that were implicitly dropped as dependencies
+        SELECT jsonb_agg(DISTINCT
+            jsonb_build_object(
+                'type', dropped.object_type,
+                'identity', dropped.object_identity,
+                'schema', dropped.schema_name,
+                'oid', dropped.objid,
+                'pgstream_id', COALESCE(table_ids.id::text, NULL)
+            )
+        )
+        INTO affected_objects
+        FROM (
+            SELECT DISTINCT ON (object_type, object_identity)
+                object_type, object_identity, schema_name, objid, original
+            FROM pg_event_trigger_dropped_objects()
+            WHERE original = true  -- Only objects explicitly named in DROP command
+        ) dropped
+        LEFT JOIN pgstream.table_ids ON dropped.objid = table_ids.oid AND dropped.object_type = 'table'
+        WHERE dropped.schema_name NOT IN ('pg_toast', 'pg_catalog', 'information_schema')
+          AND dropped.schema_name NOT LIKE 'pg_temp%'
+          AND dropped.schema_name NOT LIKE 'pg_toast_temp%'
+          AND dropped.object_type NOT IN ('default value');
+
+        -- Clean up table_ids for dropped tables
+        IF tg_tag = 'DROP TABLE' THEN
+            DELETE FROM "pgstream"."table_ids"
+            WHERE oid IN (
+                SELECT objid
+                FROM pg_event_trigger_dropped_objects()
+                WHERE object_type = 'table'
+            );
+        END IF;
+
+    ELSIF tg_event = 'ddl_command_end' THEN
+        -- For CREATE/ALTER events, get schema name and collect all created/altered objects
+        SELECT schema_name INTO rec_schema_name
+        FROM pg_event_trigger_ddl_commands()
+        LIMIT 1;
+
+        -- Collect only user-facing objects (exclude internal PG objects)
+        -- Filter by schema to exclude system objects
+        -- For tables and table columns, create or fetch pgstream_id from table_ids mapping and include column details
+        SELECT jsonb_agg(
+            jsonb_build_object(
+                'type', cmd.object_type,
+                'identity', cmd.object_identity,
+                'schema', cmd.schema_name,
+                'oid', cmd.objid,
+                'pgstream_id', CASE
+                    WHEN cmd.object_type IN ('table', 'table column') THEN
+                        COALESCE(
+                            table_ids.id::text,
+                            pgstream.create_table_mapping(cmd.objid)::text
+                        )
+                    ELSE NULL
+                END
+            ) || CASE
+                WHEN cmd.object_type IN ('table', 'table column') THEN pgstream.get_table_metadata(cmd.objid)
+                ELSE '{}'::jsonb
+            END
+        )
+        INTO affected_objects
+        FROM (
+            SELECT DISTINCT ON (object_type, object_identity)
+                object_type, object_identity, schema_name, objid, in_extension
+            FROM pg_event_trigger_ddl_commands()
+        ) cmd
+        LEFT JOIN pgstream.table_ids ON cmd.objid = table_ids.oid AND cmd.object_type IN ('table', 'table column')
+        WHERE cmd.schema_name NOT IN ('pg_toast', 'pg_catalog', 'information_schema')
+          AND cmd.schema_name NOT LIKE 'pg_temp%'
+          AND cmd.schema_name NOT LIKE 'pg_toast_temp%'
+          AND NOT cmd.in_extension
+          AND cmd.object_type NOT IN ('default value')
+          -- Exclude implicit table row types (have typrelid > 0) but keep user-defined types
+          AND NOT (cmd.object_type = 'type' AND EXISTS (
+              SELECT 1 FROM pg_type t
+              JOIN pg_namespace n ON t.typnamespace = n.oid
+              WHERE n.nspname || '.' || t.typname = cmd.object_identity
+                AND t.typrelid > 0
+          ));
+    END IF;
+
+    -- GRANT and REVOKE don't report the affected objects, so they are resolved
+    -- from the statement, and the schema of the first one is reported.
+    IF tg_event = 'ddl_command_end' AND tg_tag IN ('GRANT', 'REVOKE') THEN
+        affected_objects := pgstream.get_privilege_objects(ddl_command);
+        rec_schema_name := affected_objects->0->>'schema';
+    END IF;
+
+    -- Privilege changes and comments on schemas that couldn't be resolved are
+    -- emitted without a schema, unless they are about a schema, which is then
+    -- reported as the affected schema.
+    IF rec_schema_name IS NULL AND tg_tag IN ('GRANT', 'REVOKE', 'ALTER DEFAULT PRIVILEGES', 'COMMENT') THEN
+        SELECT object_identity INTO rec_schema_name
+        FROM pg_event_trigger_ddl_commands()
+        WHERE object_type = 'schema'
+        LIMIT 1;
+
+        IF rec_schema_name IS NOT NULL AND pgstream.is_system_schema(rec_schema_name) THEN
+            RETURN;
+        END IF;
+    ELSE
+        -- Skip if no schema identified or if it's a system schema
+        is_system_schema := pgstream.is_system_schema(rec_schema_name);
+        IF rec_schema_name IS NULL OR is_system_schema THEN
+            RETURN;
+        END IF;
+    END IF;
+
+    -- Build the JSON payload with DDL information
+    -- The DDL command itself contains all the details; objects list provides metadata
+    ddl_payload := jsonb_build_object(
+        'ddl', ddl_command,
+        'schema_name', rec_schema_name,
+        'command_tag', tg_tag,
+        'objects', COALESCE(affected_objects, '[]'::jsonb)
+    );
+
+    -- Emit the DDL change as a transactional logical message
+    -- The 'true' parameter makes this transactional, ensuring proper ordering with data changes
+    -- The prefix 'pgstream.ddl' is used to identify these messages on the consumer side
+    PERFORM pg_logical_emit_message(
+        true,
+        'pgstream.ddl',
+        ddl_payload::text
+    );
+END;
+$$;
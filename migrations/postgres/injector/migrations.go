@@ -13,6 +13,8 @@
 // migrations/postgres/injector/4_fix_duplicate_ddl_emission.up.sql
 // migrations/postgres/injector/5_handle_duplicate_table_mapping.down.sql
 // migrations/postgres/injector/5_handle_duplicate_table_mapping.up.sql
+// migrations/postgres/injector/6_emit_privilege_and_comment_ddl.down.sql
+// migrations/postgres/injector/6_emit_privilege_and_comment_ddl.up.sql
 package pginjectormigrations
 
 import (
@@ -289,6 +291,46 @@ func _5_handle_duplicate_table_mappingUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __6_emit_privilege_and_comment_ddlDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd4\x59\x5f\x8f\xe2\x38\x12\x7f\xe7\x53\x94\x46\xb3\x0a\x48\x69\xf6\xf6\xb5\x5b\x9c\xc4\x80\x99\xcd\x1e\x13\x46\x40\xeb\xf6\x74\x3a\x45\x26\x29\x82\xa7\x1d\x27\x63\x9b\xee\x46\xb7\xf7\xdd\x4f\x76\x9c\xe0\x04\xba\xd5\xdb\xd2\x3c\x6c\x13\x89\x10\x57\x95\xcb\xbf\xfa\x9b\xea\x9b\x1b\x58\xe3\x23\x4a\x0d\xba\x04\xf5\xc0\xaa\x8a\x89\x1c\x2a\xc9\x1e\x19\xc7\x1c\x21\x3d\x50\x91\xa3\x02\x2a\x32\x48\xcb\xa2\x40\xa1\x15\x94\x02\x54\x7a\xc0\x82\xaa\xc1\x60\xb6\x26\xd3\x2d\x81\xd5\x1a\xd6\xe4\xeb\x72\x3a\x23\xb0\xb8\x8f\x67\xdb\x68\x15\x43\x95\x2b\x2d\x91\x16\x63\x2c\x98\x4e\xb2\x8c\x0f\x47\xb0\x26\xdb\xfb\x75\xbc\x01\x7c\x44\xa1\x13\x2d\x59\x9e\xa3\x1c\x00\x00\x2c\xa7\xf1\xe7\xfb\xe9\x67\x02\x15\xaf\x72\xf5\x9d\x0f\x00\x00\x36\x64\x76\xbf\x8e\xb6\xff\x82\x39\x59\x44\x31\x59\x5b\xca\x0d\xd9\x82\x42\x2a\xd3\x43\x52\x51\x7d\x80\x09\x54\x79\x92\x52\x4d\x79\x99\x87\x55\x9e\x68\x2c\xaa\xc1\x74\x03\x1f\x3f\x0e\xe6\x64\xb6\x9c\xae\x89\x95\x25\x31\x4d\x6a\xb5\x13\x41\x0b\x04\x8d\xcf\xfa\xce\xae\x64\x19\x4f\xcc\xe1\xcc\x21\xbb\x4f\x2b\x7a\xe2\x25\xcd\xe0\x9b\x2a\xc5\xae\x7e\xcc\x54\xa2\x4e\x4a\x63\xe1\x84\xc1\xae\x2c\x39\x52\x51\xaf\xd2\xfd\x1e\x53\x8d\x59\x52\xee\xbe\x61\xaa\x95\xcf\x59\xee\xbe\x25\x12\xd3\x52\x66\x50\x7f\xdd\x0d\x3e\x91\xcf\x51\x6c\x19\x6f\x6e\x60\xf3\xc0\x2a\x60\x7b\x48\x4b\xb1\x67\xf9\x51\x62\xd6\x18\x05\xe6\xf3\x25\x68\x49\xd3\x07\x26\x72\x4b\x1e\x2d\x60\x78\x3e\xf5\x38\x3d\x4a\x69\x00\x55\xa8\x35\x13\xf9\x30\x68\xb1\x37\xec\x09\x2f\xf3\x20\x84\x60\xbb\xbe\x27\xc1\x08\x26\xed\xdd\xf6\x57\x52\xef\x6e\x3e\xb5\x69\x6a\x55\x49\x3c\x87\x68\x71\x37\x68\x54\x9b\xd1\x4a\x1f\x25\x82\x3e\x20\xd0\x54\x1f\x29\xb7\x2a\x35\xa0\xed\xd0\x78\x0d\x3e\x63\x7a\xd4\x98\x5d\x60\x7a\x3b\x81\x46\xc1\xef\x47\x94\xa7\xe1\xe8\x2c\x79\x8e\x1a\x65\xc1\x04\xc2\xd3\x81\xa5\x07\xe7\x58\xa0\x0f\x4c\x39\x30\x1b\xe7\xe3\x1c\x53\x0d\x94\xf3\x16\x64\x70\x20\x37\x88\xe8\x3c\xb1\x7e\x65\x4e\xa8\xbe\xf3\x24\x93\x65\x15\x74\x0f\x79\x73\x03\x8b\x52\x42\xb3\x1a\x42\x29\xf8\x09\x8c\x83\xc2\xbe\x94\xa0\xe5\x11\x61\xbe\x5e\x7d\x05\xa7\xbb\x82\xa1\xfd\xb9\x9d\x7e\x5a\x92\xb0\x5e\x8a\xe2\x39\xf9\x3d\x04\xd4\xe9\x78\xe4\x0b\xb6\xf6\x9b\x2e\xb7\x64\x7d\xe6\xd6\x07\xaa\xe1\x40\xab\x0a\x85\x31\xa6\xd9\x13\x54\x59\xa0\x3e\x18\xc4\x86\x9c\x3d\xa0\x63\xb1\x3b\xd4\x1b\xcc\x56\xcb\xfb\x2f\x71\x47\xf6\x0e\x53\x7a\x54\xe8\xc3\x9a\xa0\xc8\xe0\x89\x71\x0e\x07\x2a\x32\x6e\x8c\x53\x2a\xb4\x60\x55\xb2\x7c\x64\x19\xc2\x0e\xb5\x46\x09\x05\x6a\x9a\x51\x4d\x5b\x81\x35\x56\x9a\xe6\x10\xaf\xb6\xb0\x8c\xfe\x41\x20\xb0\x3b\xff\xd4\x83\xab\xef\x17\x17\xbe\xe1\xd4\x33\x98\x5a\x01\x16\x7f\x15\x42\x8e\xba\x31\xa5\x0d\xb6\xbe\x09\x0d\x10\x55\xcf\x82\xe6\xda\x90\x25\x99\x6d\x1d\x6b\x1d\xa7\x51\xbc\x5d\xf5\x83\xb7\xa5\x5f\xac\x57\x5f\x4c\xf8\x77\xf2\x49\xe2\xa4\x37\x41\x38\x3c\x43\xb9\x8c\xbe\x44\x5b\xf8\xa5\xab\xfd\xcc\x29\x66\x7d\xe1\xa8\x50\xde\xec\x69\x6a\xec\xd3\xd3\x12\x86\xf8\x9c\xf2\x63\x86\xc0\x84\x46\x29\x28\x87\xaf\x9f\x9b\xc5\x8e\xb9\x16\x8c\x1b\xe0\x77\xa7\x06\x04\x5d\x42\xc3\x5b\x67\x8f\xb3\x4c\x93\xb1\x4a\xaa\x74\xe8\xa7\xb1\x4b\xef\x8a\x44\xcd\xde\x44\x76\xc2\xb2\xda\x65\xe9\x8e\xa3\x82\xbd\x2c\x8b\xfa\x3e\x61\x99\x82\x82\xda\x4c\xee\x0b\xb8\x57\x08\x41\x29\x59\xce\x04\xe5\x01\xec\x19\xf2\xcc\xd7\xab\x51\xc8\xba\xec\x13\x4a\x04\x56\x54\x9c\xa5\x4c\xf3\x53\x8b\x04\x55\x90\x61\x85\x22\x43\x91\x32\xbc\xb0\x9b\xcd\x76\x09\xcd\xf3\xe1\x3c\xda\x6c\xa3\x78\xb6\x6d\x29\xcc\x55\x2f\xef\x8e\x8c\x37\xb6\x19\x76\xd6\xcd\x15\xe8\x53\x85\x41\xd8\xec\x38\xae\xe9\x12\xf3\x34\xbc\x24\x66\x19\x0a\xcd\xf4\xe9\x92\xa1\x59\xb9\xc2\x54\xdb\xc4\x63\xf1\x3c\xeb\x0a\x79\xc9\xb2\xae\x78\x96\x5d\xa1\xf2\xec\x12\x84\x30\x5b\x4d\x97\x64\x33\x23\xc3\xd6\x24\x63\x96\xdd\xde\x9a\xea\x12\x42\x7c\xbf\x5c\x9e\x6d\x6b\x3e\xa3\xc1\xe5\x9d\xf5\xfb\x7e\x3d\x69\x57\xad\xe3\x77\xd1\x73\x36\x68\x90\x87\x55\x0c\x43\x1f\x3d\xe8\x21\x73\xde\xaa\xf9\x7b\x8d\x3a\xf4\x83\xd2\xae\xb2\x2c\x84\xc6\x9f\x3a\xa2\xfe\x6c\x50\x9a\xcf\x3f\x7f\x25\x6b\xd2\x8a\x83\x49\x9d\x8a\x8d\xdf\xae\x4c\x54\x3a\x26\xc0\xe7\xd6\x25\x8d\x1e\x19\x30\xd1\xc9\xd7\xad\xcc\x51\x63\xb0\xf6\xc9\x92\x2c\xb6\xf0\xdb\x2a\xf2\x1a\x93\x73\xbc\xac\xe2\xae\x81\x8d\x02\xad\xe5\x4a\x96\xc1\x34\x9e\xfb\x14\x0d\x4c\xa6\xd4\x58\x29\x41\xbb\x4f\x7d\x92\x2b\xae\x65\x93\x6d\x14\x83\xa9\xce\x75\xc8\x9b\xa2\x7c\x0e\x7a\xf3\x8b\x89\x7d\x29\x0b\xaa\x59\x29\x5c\xbe\x0b\x7c\x9c\x7c\x2d\xfa\x92\xeb\x34\xee\xfa\x9f\x9f\x82\x3f\xcd\x65\x14\x7a\x9d\xd7\x3f\x77\x7b\x96\x0c\xf7\xf4\xc8\x35\x3c\x52\x7e\xc4\xa0\xa9\xec\x2e\xe7\xcc\x4c\x67\x04\xc7\xca\xcb\x4c\x26\x67\x39\x81\x2e\x77\x5d\x29\x4b\x13\x57\x8f\x6c\x51\xbc\x52\x93\xe6\x64\x49\xb6\xa4\x76\xb4\x0f\x8d\x39\x3f\x8c\x3f\xb4\xdb\x7c\xb8\xe6\x5c\x2c\xb3\x2a\x77\x96\xbc\xc0\xb1\x2e\x7d\xb1\xf8\x1e\x67\xf6\xf6\x7c\xd5\x55\xcc\x35\x7a\xa1\xb2\x92\xe5\xa6\xd7\xd2\xf4\x6a\xff\xf5\xce\xa6\xee\xc7\x7f\xae\x7b\x8a\xb7\x56\xe3\x54\x22\xd5\x98\xfd\x4c\x4d\xd5\xfa\x71\x55\xf9\xac\xff\xfb\x4b\xf2\x0f\x2c\xc5\x1d\xae\x73\x65\x35\x60\x59\xbb\x41\x5a\xf2\x63\x21\x54\xe8\xf0\x82\x52\xc2\x1e\x75\x7a\xe8\xd6\xe4\xab\x85\xd8\xb6\x65\xcc\x95\xf0\x5a\x0e\x64\xa8\x29\xe3\xaf\xd4\xd0\x77\xd7\xce\xb4\xe8\x04\xeb\x95\x6a\xd5\x64\xf5\x2e\x71\x9b\xeb\x5f\xa9\x99\x46\xb6\x67\xf3\x2b\xb2\xeb\x7a\x99\x16\x6f\xae\x95\xd3\x0d\xb9\xa0\x71\x21\x14\xfb\xea\x99\xd3\xd9\x08\x76\x71\x14\x42\xe0\x1b\xa6\xff\x42\xd3\xff\x6b\x6b\xf2\x8b\x14\xe6\xba\x52\xb0\x5f\xa5\x6f\xce\x32\xae\x9d\x22\xa9\xf9\x9d\xd9\x87\x2d\x0a\xa3\x5a\xd6\x8b\xa2\x46\x83\x6b\x8f\xc9\x72\x43\x6c\xbb\x70\xb1\x4a\xe2\x79\xe7\xd9\x08\xfe\xf8\xe3\x3a\x92\xef\x43\xf1\x5c\x24\x73\xd4\xcd\xa1\xdc\x7b\x84\x77\xaa\x8b\xdd\xac\xc6\xc1\x7f\xff\x17\xdc\xde\xda\x6e\x6f\xf0\x92\xd2\x7f\xb1\x46\x87\x89\x04\x9f\x35\x0a\xc5\x4a\x31\x78\x43\x7d\xb8\x9e\xeb\x46\xc6\x10\x6f\xee\x48\xd2\xe2\xb5\x6e\xc4\xad\xbe\xc9\xa4\xed\x96\x75\x55\x4a\x8b\x1f\xd1\x98\xa4\xc5\x6b\xed\xc5\xb5\xc6\x22\x2d\xde\xd7\x90\x18\x3a\xc3\xfb\x82\x51\xae\xa1\xf3\x52\xbf\xe2\xb1\xdd\xdc\x00\x69\x8a\x8a\x7b\xf3\x71\x89\x5f\x96\x4f\x60\x30\x56\x30\x3c\xd0\x47\x34\xf7\x12\x39\xcb\xe0\xef\xf0\xb7\x11\xec\x8e\x1a\x1e\x10\xab\xba\x4e\x65\xb8\x67\xc2\x8c\x6e\x0c\x7d\x4f\x29\xa3\xc4\xb0\xaf\xd9\xc4\xa5\x6d\x4b\x41\x7e\x8f\x36\xdb\xcd\x45\x8f\xe2\x3c\xfe\x97\xd6\xdb\x2c\x67\x3f\x9b\x38\x57\xb2\x58\xaa\x8a\xa6\x08\xc2\xc4\x86\x1e\xeb\x53\x75\x7e\x36\x01\x31\x2e\x2f\x1a\x9d\xda\x31\xc4\x58\x28\x4b\x6a\xb2\x49\x30\x0e\xcc\x57\xcb\x0e\x13\x1f\xd5\x26\x5c\x7a\x72\x6a\x03\xe9\xb1\x0f\x91\x47\x32\x72\xed\x4e\xa7\xd5\xf1\x66\x5f\xa2\x6c\xca\x74\x2d\x7f\xcf\x4c\x3b\x22\xcd\x58\x8c\xe9\x40\x01\x6d\x6a\x76\x4d\x75\x7d\x22\x77\x3b\x39\x07\x54\x7f\x71\xd8\xeb\x59\x9c\x3e\xd1\xa2\xdf\xcc\x40\xb4\xb1\x99\xd7\x8c\x36\x2f\x76\x78\xf3\xe0\xec\x93\xa9\xd7\x76\x6c\xf6\xdb\xc6\x8c\x44\xdd\x3c\xf1\x89\xe9\x83\x9d\xa0\x79\x61\xd5\xb0\x6c\x0f\xd8\x19\xae\x31\xad\x90\xdb\xa9\xa0\xa6\x4c\x28\x3b\x01\x33\x02\x5d\xfb\x70\xe7\xf2\x97\x02\xce\x94\x6e\x46\x3f\xaa\x3b\xf4\xf1\x67\x99\xb7\x93\x57\x7b\x89\x20\xcb\xb8\x79\xdd\x3d\x27\xb0\x70\xd0\xeb\x02\x2c\x70\x41\xd8\x47\xcc\xa3\x73\x9c\xa6\x97\x0f\x42\xd7\xd4\x7b\xcb\x4e\x63\xff\x3d\xb9\x9f\xff\x43\x08\xfe\xfd\x9f\xa6\x88\xd4\x99\xc6\x9b\x1a\x12\x33\xb0\xd3\x0d\x50\x76\x46\x0d\xd4\x78\x87\x96\x54\x28\x9a\x9a\x34\x45\x39\xf0\x32\x67\x29\xe5\x50\xa0\x52\x34\x47\x1f\xe1\xc0\xbc\x5f\x06\x50\x51\x49\x0b\x33\x82\x84\x82\x3e\xa0\x19\x7e\x30\xd5\x15\x12\x02\x0a\x75\x94\xa6\x87\xab\x64\x59\xa1\x84\x52\x66\x68\x7f\x5b\x2b\x9a\xc1\x9a\x53\x41\xf9\x1b\x54\x12\xf7\xec\xf9\xdc\xeb\x8c\x0d\xae\xc0\x94\xc9\x12\x76\xee\xe2\x1c\xfc\x64\xbc\x43\x61\xa3\xa3\x9d\xae\x9b\x93\xa5\xa5\x50\xc7\x02\x25\x28\x96\xd5\x9a\x7f\x25\xeb\xc5\x6a\x6d\xc3\xdf\x1d\x2c\xb1\x93\x75\xc7\x79\x36\xa1\x39\x9a\x87\x76\x47\x83\xf3\x73\xcf\x27\xbc\xde\x64\x74\x37\x20\xf1\xfc\x6e\xf0\xf1\xe3\xdd\x60\x60\xdf\xc1\xda\x79\x7e\xb4\x68\xd2\x53\x2b\xd1\xf4\x06\xed\x3f\x0b\x1a\xd3\x0d\x35\x3e\xeb\xd1\xdd\xe0\xff\x03\x00\xc9\x82\xa3\x8b\x5c\x18\x00\x00")
+
+func _6_emit_privilege_and_comment_ddlDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__6_emit_privilege_and_comment_ddlDownSql,
+		"6_emit_privilege_and_comment_ddl.down.sql",
+	)
+}
+
+func _6_emit_privilege_and_comment_ddlDownSql() (*asset, error) {
+	bytes, err := _6_emit_privilege_and_comment_ddlDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "6_emit_privilege_and_comment_ddl.down.sql", size: 6236, mode: os.FileMode(420), modTime: time.Unix(1792349593, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __6_emit_privilege_and_comment_ddlUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd4\x3a\x6b\x73\xda\x48\x90\xdf\xf9\x15\x7d\xa9\x6c\x49\x24\x82\xdd\x7c\xb5\x8b\xa4\x08\xc8\x09\xbb\x04\x5c\x80\xf7\x51\x71\x56\x35\x96\x1a\x98\xb5\x5e\xd1\x0c\x76\x7c\x97\xbb\xdf\x7e\xd5\xf3\xd0\x0b\x99\x38\xa9\xdb\x0f\x67\x3b\x04\xcd\x4c\xf7\xf4\xf4\x7b\x5a\x3d\x18\x80\x9f\x70\x09\x79\xc1\xef\x78\x8c\x3b\x84\x70\xcf\xd2\x1d\x0a\x60\x69\x04\x61\x96\x24\x98\x4a\xd1\x1b\x0c\xe0\xdd\x6a\xbc\xd8\x78\xb0\xf2\x7f\x5f\xfe\xe6\xab\xd9\xf1\x7c\xe3\xaf\x60\xea\x5f\x8c\xaf\xe6\x1b\xb8\x5c\xcd\x7e\x9f\xcd\xfd\x77\xfe\x1a\xa2\x2c\x75\x24\x14\x98\x67\x85\x04\xb9\x47\x10\xe1\x1e\x13\x06\xd9\x96\x9e\x08\x19\xdb\x6e\x31\x94\x18\x41\x76\xf3\x0f\x86\x52\x78\x0a\x61\x8a\x5c\xee\xb1\x80\x28\x2b\x77\x86\x2c\x35\xd0\xc2\x03\x91\x11\xfc\x03\xdc\x63\x81\x20\x6e\x79\x9e\x63\x34\x24\x74\x1b\x1a\x65\x05\x42\x9a\xdd\x03\x26\x5c\x12\xea\x7b\x2e\xf7\xb4\xbe\xda\xcc\x90\x71\xbf\xc7\x14\xd8\x1d\xe3\x31\xbb\x89\x51\x6f\x4d\x8b\xb3\x83\x24\x64\x59\x8a\x90\x11\x1d\xf7\x5c\xa0\xd9\x94\xe9\x73\x48\x56\xec\x50\x0a\x08\x59\x0a\x2c\xcf\xe3\x07\x1a\x4d\x86\x3d\x02\x5b\xa1\xc8\xe2\x3b\x14\x34\x04\x05\xc6\x4c\xf2\x2c\x15\xc0\x34\xe3\x20\x2b\x2c\xeb\x84\x64\x12\xe9\x70\x0a\x05\x27\x88\x6c\x08\x9b\x3d\x02\xde\x61\xaa\x48\x90\x05\xdf\xed\x14\x23\x50\x34\x59\x99\x54\x5c\xa0\xf3\x4a\x76\x8b\x29\x6c\x8b\x2c\xa1\xb1\x0a\xb5\x23\x0c\xad\x84\x2d\xe6\x42\x0e\xe1\xd2\x4a\x58\xf1\xf4\x7e\x9f\xc5\x56\x2e\xa2\x43\x54\x3c\x15\x12\x59\x34\xec\x4d\x56\xfe\x78\xe3\xc3\x72\x05\x2b\xff\x72\x3e\x9e\xf8\x70\x71\xb5\x98\x6c\x66\xcb\x05\xe4\x3b\x21\x0b\x64\xc9\x70\x87\x32\x28\x15\x28\x30\x22\x75\xa3\x28\x06\x89\x5f\x64\xbf\xb7\xf2\x37\x57\xab\xc5\x1a\xfe\x11\x59\x7a\xd3\x9b\x8f\x17\xef\xae\xc6\xef\x7c\xc8\xe3\x7c\x27\x3e\xc7\xbd\xf5\x66\xfc\x76\xee\xf7\xd6\xfe\x06\x04\xb2\x22\xdc\x07\x39\x93\x7b\x18\x41\xbe\x0b\x42\x26\x59\x9c\xed\xbc\x7c\x17\x48\x4c\xf2\xde\x78\x0d\xcf\x9f\xf7\xa6\xfe\x64\x3e\x5e\xf9\x3d\x00\x30\xe7\x0c\xe8\x90\x6a\xbb\xf3\xfa\x68\xca\x12\xac\x8d\xd2\x63\x90\xb3\x42\x0a\x35\xf8\xf1\x93\x5e\x5c\x60\x0c\x05\x86\x59\x11\xe9\x67\x73\x02\x4d\x2f\x9c\x8d\xc0\xf9\xf8\xc9\x39\x3b\x53\x8f\xe7\xbd\xb7\xfe\xbb\xd9\xe2\x68\xeb\xb3\x11\xb8\x05\xee\xf0\x4b\x1e\x24\x4c\x86\x7b\x3a\xbe\x07\xce\x75\xb2\x5c\x5c\x8b\x97\xee\xf0\xc5\x9b\x3e\xfd\xff\xe6\x6c\xb3\xfc\x7a\xb1\x5a\x7e\xe8\x5f\x0b\xc7\x03\x87\x0b\xa7\xdf\xff\xf8\xca\x10\x32\xbb\x68\xe0\x9c\xad\x61\x71\x35\x9f\xc3\xe6\xbd\xaf\x37\xa4\x5f\xcd\x4c\x35\xa1\x81\xfc\xc5\x14\x66\x17\xe7\x3d\xf5\x30\x18\xc0\x98\x20\x88\xa3\x6b\x98\x2d\x60\x3d\x79\xef\x7f\x18\x2b\x1d\x37\x5f\xad\x12\x1b\x05\xce\x9a\xba\xd0\x45\xc7\xff\xbc\x00\xe7\x6f\x77\x3c\x9f\x5f\x8b\x97\xd7\xf7\x2f\xaf\xc5\xcb\x19\x1d\xaa\xff\x46\x63\xbc\x16\x4e\x93\xc4\x8b\xe5\xca\xc2\x13\xc7\xc1\xb0\xcb\xfe\xae\xfd\xb9\x3f\xd9\x80\x2c\x78\xe2\x8a\x3e\x10\x37\xc0\xb0\x4e\xe4\x31\x97\x81\xcc\x02\x49\xa6\xe9\x36\xc0\xe8\xcf\x2c\x2b\x30\x8f\x59\x88\x6e\x8d\x48\xef\x1b\x24\xbe\x24\x6e\xd3\x3f\xee\xf4\xbd\x23\xbc\x8e\xe7\xbe\x19\xb9\x6f\xce\x3e\xfe\xfd\xec\xd3\x8b\x67\xfa\xb3\xff\x42\xfd\xff\xbc\xef\x34\x96\xf7\x41\x94\xcf\xf3\xe5\xf2\xb2\x31\x59\xe9\x46\xfd\xa7\xa6\x76\x67\x23\xc8\x59\x21\x30\xe0\x11\xa6\xd2\x1e\x80\x16\xf4\xcf\x1b\x70\xfe\x9f\x13\xff\x52\x99\xd9\x1f\xef\xfd\x05\x2c\x37\xef\xfd\xd5\xba\xc9\x66\xfb\x33\x59\x2e\x36\xb3\xc5\x95\xdf\x42\xb0\x98\x36\x07\xac\x56\x9f\x8d\xca\xaf\x5f\xbf\x6a\x1d\x0f\x6e\x0e\x3c\x8e\x8c\xe5\x1e\x73\xdd\x91\x0f\x39\x12\xef\xb4\x73\x70\x3a\xf8\xa7\x8e\xc3\xe5\x83\xe3\xc1\x36\x2b\x12\x26\x5d\xe7\xa7\x99\xe3\xd5\x8e\xfe\xf1\xd5\xa7\x2e\xc6\x5b\x94\xcd\x95\x8d\x75\x35\xce\x90\xae\x13\xd3\xcf\xbb\xcc\x61\x76\xe1\x9a\x83\x79\x75\x8b\xed\x77\x9b\xc9\x32\x8d\x1f\xaa\xb0\xa7\x9c\x62\xcd\x6b\x17\x08\x85\x76\xe8\xd1\x39\x6c\x0f\x69\xa8\xc6\x3d\x20\x4e\xe8\xd8\xa8\x42\x84\xb0\xd8\xc8\x19\xd7\x03\x4f\x76\x90\x96\xcb\x5d\x16\xf5\x1f\xda\xa4\x94\xe3\x53\x6a\xfa\xf1\x6f\xf7\x5a\x7c\x7a\xe9\x5e\x8b\x17\xde\xb5\x78\x61\x1e\xfb\x2f\x9e\x3b\xdf\x63\xfd\x27\x0c\xef\x07\x8d\xee\xb4\xc1\x59\xfa\x1f\xb5\xad\x27\xda\x95\xb5\xa9\x86\x3d\x1d\xdb\x52\xa5\x22\x4f\xb3\xa3\x27\xda\xd0\xb1\xfd\x28\xdb\x29\x9f\x74\x82\x51\x06\xe5\xe2\x90\x0a\x25\x63\x60\xb5\x08\x65\x42\x17\x50\xe8\x52\x31\xfa\x90\x7e\x3e\xb0\x98\x6f\x39\x46\x75\x4c\x74\x06\xad\x5f\x71\x96\xdd\x62\x04\x87\x1c\x78\xaa\xa2\xf7\x41\x60\x61\xdd\xb0\x07\x79\x81\x5b\x2c\x0a\x9e\xee\x20\x3f\xdc\xc4\x3c\x6c\x8b\x32\x1c\x66\x3c\xf2\x20\x1c\x16\x18\x13\x56\xf3\xf5\x96\xa7\x91\x07\xe9\x30\x15\xb9\xd1\x81\xcd\x92\xf2\x91\x12\x5c\xf9\x5b\x22\x3c\x66\x42\x40\x85\xf6\xd7\xe5\x8c\x62\xba\x62\xa2\xc8\x59\x88\x90\xc2\x72\x01\x29\x6d\x03\xa3\x6a\x1f\x35\x57\x42\xfd\xf1\xde\x5f\xf9\xd5\x24\x8c\xea\x86\xcc\x8a\x82\x3d\x04\x31\xa6\x3b\xb9\x77\xab\x71\x0f\x5e\xf5\xeb\x26\x3e\x5e\x4c\x2b\xda\x29\x68\xb9\x4e\x41\xea\x94\xd3\xc7\x1d\x7d\x24\xf4\xb1\xa6\x8f\xad\xd3\x6f\x41\x4e\xc6\x6b\xbf\x36\x64\x88\x5a\xc0\x89\xcd\xe1\x35\xbc\x52\x76\x55\xe3\xd3\x53\x09\x87\x01\xb4\xfc\x13\x80\x3f\x5f\xfb\xb0\x58\x6e\xaa\x94\x88\x8b\x40\x3c\x08\x89\x49\xa0\x05\xea\x96\x1b\xd5\xc9\xf7\x17\xd3\xf2\x69\xb9\x9a\xfa\x2b\x78\xfb\x57\x83\x24\x47\x8b\xde\x81\xa9\xbf\x9e\xd4\xa4\x5a\x42\xcd\x67\x1f\x66\x1b\x78\x55\xd3\xd6\xd9\x85\xa2\xe4\x62\x79\xb5\x98\x3e\x51\xd7\x2b\x07\xf2\x78\x9c\x70\xbf\x15\x28\x6c\x90\x20\x71\x90\xbe\x59\x79\x36\x16\x95\xc2\x71\xee\xb4\x63\x03\xe7\x8e\xe3\xbd\xf3\xc8\xa2\xc4\x2e\x4a\x98\xc4\x82\xb3\x98\xff\x27\x46\x70\x0a\x62\x6d\x21\x04\x7e\x3e\x60\x1a\xe2\x63\x0b\xb7\x76\xe1\x36\x2b\x90\xef\x52\x50\xce\xef\x78\xb5\x12\xad\xd3\x31\xe9\x2f\xa6\x5e\xef\xdb\x31\x70\xa8\xc2\x20\xf1\xc3\x88\xce\xb3\xcc\x21\x11\xb7\xdd\x65\x19\x0d\xeb\x00\xcd\x25\x19\x8f\xcc\x3c\x99\x7f\x73\xce\xaa\x5f\xa0\xd6\x74\x18\x86\x39\x7d\x4d\x3c\x0d\x73\xeb\x1f\x2b\x8c\xfd\x71\x8d\xd7\xe1\xd1\xd9\x19\xe5\xce\xd6\x8d\x18\x7d\x57\x0c\x0a\x78\x24\x68\x83\x95\x0f\xda\x67\x18\x2a\xfb\xdd\x5c\xa5\x20\xd6\x98\xa9\x9b\x43\x9f\x94\xee\xe8\x04\x4f\xa0\xbe\xa2\x89\x22\x95\xa6\x2b\x41\xc9\x22\x26\x99\xdb\x49\x90\x22\xc6\xf9\xaf\xff\xb6\x09\x43\x39\xe9\x2f\xa6\xb5\xe4\x81\x82\x93\x31\x93\x27\x25\x1d\x2a\x88\x3c\x7f\x7e\xde\x7b\xd2\x1d\x8a\x92\x87\x20\x8a\x62\xb7\x6f\x62\xfc\x5a\x5f\x07\x03\x13\x76\xd4\xbe\x47\x57\x27\x1a\x5c\xfb\x93\xab\xd5\x6c\xf3\x17\xdd\xc4\x67\x0b\x7f\xa5\x56\xfe\xe0\x65\xaa\xc0\xd0\x38\xac\xf6\xd5\x29\x8a\xe2\x80\x2e\xe5\x94\xfa\x34\x47\x73\xf6\x10\x67\x2c\x02\x73\x3d\xa2\xe1\xb6\xf7\x83\x9b\x2c\x8b\x91\xa5\x7a\xd6\xde\xc7\x8d\x1b\x11\x75\xc8\xec\xe6\x9f\x40\x5f\xc5\xca\x1b\x59\x95\x07\x0c\x06\xb0\xbe\xe5\x39\xf0\x2d\x84\x59\xba\xe5\xbb\x43\x81\x11\xc8\x4c\xd5\x02\x60\x3a\x9d\x83\x2c\x58\x78\xcb\xd3\x9d\xcd\xb9\xdc\xea\xd4\xc3\xf0\x50\x14\x98\xca\x40\xa0\x94\x3c\xdd\xb9\xa5\xb5\x0c\x09\x3c\x88\xb3\x1d\xc5\x97\xcd\xea\xca\x77\xfa\x30\x2a\xbf\x35\x2c\x42\x8b\xa6\x23\xf3\x1a\x0c\x60\xc2\x72\x79\xa0\x3b\x39\x95\x1c\x42\x79\x60\xb1\x22\xc9\x32\xed\x06\x29\x94\xe3\x17\x0c\x0f\x12\xa3\x23\x9e\x9e\x8d\xc0\x12\xf8\xf9\x80\xc5\x83\xdb\xaf\x30\x4f\x51\x62\x91\xf0\x14\xe1\x7e\xcf\xc3\xbd\xc9\x11\x40\xee\xb9\x30\xcc\xb4\xe5\x9a\x38\xc6\x50\x02\x8b\xe3\x92\xc9\x47\x59\xe8\x2e\x50\x7a\x45\x27\x14\x9f\xe3\x20\x2a\xb2\xbc\x95\x63\x0e\x06\x70\x91\x15\x60\x67\x3d\xc8\x28\x55\x26\x05\xa5\xdc\x1e\x64\x71\x40\x98\xae\x96\x97\x60\x68\x17\xe0\xaa\x47\x95\x0d\x7a\x7a\x6a\xb6\x98\xfa\x7f\x7a\x80\x32\x1c\x56\xc6\x66\xe5\xa7\x0b\x47\x25\xb4\xdc\x33\x09\x7b\x96\xe7\x98\x92\x30\x69\x4f\x10\x59\x82\x72\x4f\x1c\x73\x63\x7e\x8b\x06\x44\xed\xa0\x37\x98\x2c\xe7\x57\x1f\x16\x0d\xdc\x37\x18\xb2\x83\xc0\x3a\x5b\x03\x4c\x29\x1f\x8f\x63\xd8\xb3\x34\x8a\x49\x38\x99\x40\xc5\xac\xbc\xc8\xee\x78\x84\x70\x83\x52\x62\x01\xd6\x45\x94\x08\xe9\x2e\xbe\x0b\x24\xdb\xa9\x70\x3a\x9f\xfd\xe6\x83\xa3\x76\xfe\xa9\xc5\xae\xb6\x5e\x1c\xe9\x86\x21\x8f\x78\xaa\x10\x28\xfe\x0b\x0f\x76\x28\xad\x28\x95\xb1\xb5\x45\x48\x8c\xc8\x5b\x12\xac\xe5\x7f\x75\x3b\x35\x49\x5e\xc3\x78\x8f\x12\xbe\x86\x3f\x09\x0c\x76\x6b\x84\x6e\xff\x44\x4e\x41\xda\x6d\x08\x53\xba\x40\x99\xea\x60\xcb\x42\x92\x4f\x8b\x4a\x70\xf1\x4b\x18\x1f\x22\x04\x9e\x4a\x2c\x52\x16\xc3\xe5\x3b\x3b\xd9\x10\xd7\x05\x8f\x89\xf1\x37\x0f\xa5\x3e\x67\x60\x61\x75\xee\x54\xe1\xa4\xf2\x4f\xc6\xe8\xd2\x51\x73\x63\xc7\xda\x35\x4b\x35\xb8\xb5\xec\x80\x47\x5a\x65\x29\x0a\x08\x53\x27\x2b\x23\x55\xc2\xf2\xdc\xfa\x0a\x83\xe0\x4a\x20\x38\x59\xc1\x77\x3c\x65\xb1\x03\x5b\x8e\x71\x54\xa7\xcb\x12\xa4\x54\x56\x95\x21\x79\x92\xc7\x3c\xe4\x32\x7e\x28\x39\xc1\x04\x44\x98\x63\x1a\x61\x1a\x72\x3c\x92\x9b\xf2\x76\x01\xdb\xed\xdc\xe9\x6c\xbd\x99\x2d\x26\x9b\x72\x05\xfd\x7d\xcf\x85\xdc\xec\x38\xd4\xeb\x02\xca\xc0\x4e\xdf\xcd\x5b\x00\x76\xe6\xd4\xbd\xdc\x82\xd4\x34\xab\x63\xb9\xce\x4a\x6a\xe8\xdb\xb9\x49\x57\x7e\xb2\x1c\xcf\xfd\xf5\xc4\x77\xcb\xe4\x61\x68\xb3\x0b\x4f\xc5\xd6\x4a\xb6\xf4\xdb\xef\x1d\x7f\x53\x7a\xdf\x8e\x27\xe5\xac\x4a\x51\x9a\xdc\x33\x32\xb0\x9c\xa7\x0b\x8e\x89\xdf\x9a\x7b\x46\xe5\x4a\xce\x54\x5b\xd9\x9f\x53\xab\x3d\xa3\xca\x9a\x4b\x34\x4b\x37\x34\xab\x4f\x0d\x54\xdf\x6b\x94\x26\xfb\x59\xf9\x25\x3a\x18\x69\x57\x5c\x16\x33\x0c\x10\xe0\x97\x52\x25\x89\x8e\x88\xee\x97\x75\x7f\x5d\xe2\xec\x5b\x81\x95\x23\x73\xff\x62\x63\x2f\x82\x47\x99\xdd\x72\xd1\x14\x30\x11\x50\x4a\x8e\x12\x3e\xba\xc7\xd5\x56\x58\x36\xc1\xe8\x28\x81\xd6\x27\xe9\x50\x2d\xe5\x6c\x55\x4a\x6a\x4d\x9e\x82\x72\x65\xf4\xf4\xc4\x53\x9d\x5e\xf3\x2c\x35\xfe\xee\xe8\x4e\xf8\x18\x66\xed\xc6\x4d\xfe\xf3\x93\xf3\xdd\x50\x44\xd0\x69\xd8\xfa\xb9\xcb\xb3\x44\xb8\x65\x87\x58\xc2\x1d\x8b\x0f\xe8\xd8\xc8\x6e\x7c\xce\x84\x32\x23\x2a\x03\x54\x9c\x26\x9f\x65\x10\x1a\xdf\xd5\x11\x96\x46\x26\x1e\xa9\xa0\xd8\x11\x93\xa6\xfe\xdc\xdf\xf8\xda\xfb\x3f\xb3\xe2\x7c\x36\x7c\x56\x6e\xf3\xac\x4b\xb9\xb8\xce\xa9\x1b\x53\x35\xc3\x51\x2a\x7d\x34\xf9\x23\xca\x5c\xdb\xf3\xa4\xaa\x74\x54\x00\xcb\xc8\xea\xcf\xd7\xad\x94\xa6\x15\xfb\xbb\x33\x1b\x9d\x8f\xff\xac\x73\x8a\xa7\x46\xe3\xb0\x40\x26\x31\xfa\x99\x51\xd4\xfa\xf7\xa2\x72\x45\xff\x8f\x87\xe4\x7f\x31\x14\x37\xa0\xaa\xc8\x4a\xcc\x52\x72\x83\x30\x8b\x0f\x49\x2a\x3c\xc3\x2f\x7a\xef\xb5\x45\x19\xee\x9b\x31\xb9\x33\x10\xab\xb4\x8c\x9b\x10\xae\xf1\x40\x84\x92\xf1\xf8\x44\x0c\xfd\xe1\xd8\x19\x26\x0d\x63\xed\x88\x56\xd6\xab\x37\x17\x97\xbe\xfe\x44\xcc\x24\xdc\x35\x99\x77\xe0\xd6\xf1\x32\x4c\x9e\x1c\x2b\xbb\xee\xf2\xc6\x84\x16\x75\xf2\xe8\x74\xca\x82\x8d\x1d\x79\xe0\xd4\x05\xd3\xbe\xd0\xb4\x7f\xca\x98\xfc\xe8\x0a\xfa\xeb\x08\xd8\x27\xd7\xdb\xb3\x0c\xb5\x52\xd8\x5b\xb9\xce\xbf\xdc\x92\x0b\x7d\x8d\xeb\x51\x54\xfd\x5e\xd7\x70\x77\x2d\xc1\xf8\x8a\xc6\x58\x77\x4d\xe1\xc7\xb9\x78\xb2\xd4\x50\x9d\xea\x68\xb7\x47\x0b\x0e\x6d\xa2\xff\x9f\x25\x3a\x3c\x0d\xf0\x8b\xc4\x54\xf0\x2c\xed\x3d\x21\x3e\x74\xfb\xba\x3e\x09\xe2\xc9\x19\x49\x98\x9c\xca\x46\xcc\xec\x93\x44\xda\x2e\x69\x27\xff\x46\x62\x12\x26\xa7\xd2\x8b\xae\xc4\x22\x4c\x7e\x2c\x21\xa1\x75\x04\xfb\x88\x50\xba\xb8\xf3\x58\xbe\x52\x03\xa3\x7e\x11\x1b\x54\xcc\xcd\xc7\x38\xfe\x22\xbb\x37\x6f\xc7\xdc\x3d\xbb\x43\xfa\x5e\x60\xcc\x23\x78\x0d\xbf\xf4\xe1\xe6\x20\xe1\x16\x31\xd7\x71\x2a\xc2\x2d\x4f\xa9\x74\x43\xeb\x5b\x44\x11\x11\x6e\x9b\xb2\x91\x71\xdb\x6a\x85\xff\xe7\x6c\xbd\x59\x1f\xe5\x28\x46\xe3\x5f\x95\xda\xa6\x20\xdb\xde\xe4\x91\xb7\x1c\x72\x28\x1f\xf2\x6a\x6c\xa4\x5f\x7b\xb4\x60\x75\xba\x52\xd5\xe7\xbf\x7e\x05\x67\xe8\x90\x53\x29\xc1\x61\x54\xe7\xaa\x35\x97\x16\x1e\x2d\x20\x39\xac\xb3\xa8\xb6\xa4\x5f\x2b\x3a\x96\xa9\x8e\xed\xc0\x51\x41\xd2\x74\x92\x1c\xf5\xd9\x58\x07\x61\xcc\xb5\xd6\x32\x53\x7f\xa1\x69\xf1\x1d\xb7\x8d\xe8\x5e\x98\x5a\x1b\x88\xee\xd8\x81\x2d\x2f\x84\x54\x8d\x31\xdc\x36\x8b\x50\xe7\x4d\x47\x49\xa9\x66\xd3\x54\x7b\x71\xf4\x49\x75\x1d\x45\xa9\x96\xea\x86\x21\x63\xd1\x67\x68\xc7\xa3\xb6\x8b\x53\x2f\xfa\xbe\xd9\x67\x62\xb7\xac\xe5\x89\xad\xb4\x8b\xf0\xb4\x71\x0f\x5e\xff\x32\x78\xfd\xda\x46\xed\x6e\x9e\x97\x5d\x33\x9d\x7d\x51\xb5\xee\x24\xdd\x24\x14\x66\x87\x38\x22\xa1\xdc\x54\xec\xa6\x97\x7d\x16\x5d\xfb\x15\x31\x33\xe0\x1e\x1c\xd2\x18\x85\xa8\x84\xc5\x6e\x9a\xf3\xba\xf2\xc7\xd5\x8a\xd4\xa2\xb3\xa2\x00\x26\xba\x3a\x9d\x4a\x09\xb5\x99\x61\x1b\x4b\xbe\x25\x1c\x0f\x9c\xc7\x9a\xbc\x48\x84\x93\xe5\x87\x0f\xfe\x62\xd3\x96\xa1\x31\xc4\x96\x0d\xfc\x1f\x66\xc3\x9d\xd7\x06\x23\xc6\x13\x29\xf3\x23\x8c\x58\x6e\x2a\x66\x3c\xfe\xfe\xae\x05\xd9\x7f\x7a\x0d\xd0\x46\xfc\xa3\x32\x28\xdf\x42\x9a\x19\x51\x81\x76\x15\xf4\xb6\x98\x52\x66\xbe\x05\x2e\x1d\xea\x1e\x33\xe9\xb7\x5e\x55\xa2\x38\x2a\xb0\x9f\x8d\xbe\x83\xf6\xf3\x6f\xb1\x84\xd8\xb1\x5c\x1d\x97\xf1\xbf\xef\xcc\xe6\xbb\x55\xd6\xb7\x94\x96\x2b\x35\xfd\x75\x4d\xdd\x63\xe6\xb5\x01\x99\x82\x2a\x94\xd7\xa2\xa7\x05\xa1\xa6\xb8\x7a\x0d\x9d\x4b\x81\xb1\x2a\xfe\x4b\xc6\xa9\xb9\x2e\x8e\x15\x42\x73\x4b\x38\xb7\x7e\x0f\xa8\x41\xc1\x56\x78\x45\xb3\xb6\x5b\x7f\x65\x71\x36\x3a\x79\x65\x70\xa2\x28\x76\xbc\x7a\x3d\xd9\xeb\xb5\x92\x7d\xa5\x0c\x8e\xd7\xe6\x62\x6d\x9d\x81\x24\x23\x73\x3c\x63\x6d\xb5\x69\x43\x71\xbd\x1c\xd6\xf6\x53\xcd\x6e\x96\x9e\xb9\x10\x5b\x1e\xa9\xf6\x4d\x69\x19\xa5\x9c\x14\xb9\x03\x46\xef\x42\x52\xc1\x54\xe3\x0a\x8b\x21\xce\x76\x3c\x64\x31\x24\x28\x04\xdb\x95\x0e\x89\x38\xec\x50\x19\xc9\xa1\xbe\x24\x96\xd0\x9b\x06\x48\xd8\x2d\x75\x25\xd2\xeb\x85\x06\x12\x0f\x30\x15\x07\xdd\x92\x50\x64\x39\x16\x90\x15\x11\xaa\x67\x25\x45\xca\x7b\xad\x9f\xac\x6f\x40\xad\x0c\xfc\x4b\x75\xa5\x19\x12\x5f\x81\x0b\x4a\x06\x54\x79\xd5\x28\xbf\x6a\xa7\x14\x68\x69\x54\x3d\x39\x74\xb2\x30\x4b\xc5\x21\xa1\xe6\x08\x1e\x69\xca\x2f\xfd\xd5\xc5\x72\xa5\xa2\xbc\x39\x58\x40\xae\x35\x30\x90\x95\x08\xe9\x68\x35\x6e\x37\x28\xa8\xc6\x6b\x3a\x51\xbb\x82\xd4\xdf\xe1\xfd\xef\x00\x4d\x80\xfb\x75\x28\x2b\x00\x00")
+
+func _6_emit_privilege_and_comment_ddlUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__6_emit_privilege_and_comment_ddlUpSql,
+		"6_emit_privilege_and_comment_ddl.up.sql",
+	)
+}
+
+func _6_emit_privilege_and_comment_ddlUpSql() (*asset, error) {
+	bytes, err := _6_emit_privilege_and_comment_ddlUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "6_emit_privilege_and_comment_ddl.up.sql", size: 11048, mode: os.FileMode(420), modTime: time.Unix(1792349593, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -351,6 +393,8 @@ var _bindata = map[string]func() (*asset, error){
 	"4_fix_duplicate_ddl_emission.up.sql":       _4_fix_duplicate_ddl_emissionUpSql,
 	"5_handle_duplicate_table_mapping.down.sql":  _5_handle_duplicate_table_mappingDownSql,
 	"5_handle_duplicate_table_mapping.up.sql":    _5_handle_duplicate_table_mappingUpSql,
+	"6_emit_privilege_and_comment_ddl.down.sql":  _6_emit_privilege_and_comment_ddlDownSql,
+	"6_emit_privilege_and_comment_ddl.up.sql":    _6_emit_privilege_and_comment_ddlUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -404,6 +448,8 @@ var _bintree = &bintree{nil, map[string]*bintree{
 	"4_fix_duplicate_ddl_emission.up.sql":       &bintree{_4_fix_duplicate_ddl_emissionUpSql, map[string]*bintree{}},
 	"5_handle_duplicate_table_mapping.down.sql":  &bintree{_5_handle_duplicate_table_mappingDownSql, map[string]*bintree{}},
 	"5_handle_duplicate_table_mapping.up.sql":    &bintree{_5_handle_duplicate_table_mappingUpSql, map[string]*bintree{}},
+	"6_emit_privilege_and_comment_ddl.down.sql":  &bintree{_6_emit_privilege_and_comment_ddlDownSql, map[string]*bintree{}},
+	"6_emit_privilege_and_comment_ddl.up.sql":    &bintree{_6_emit_privilege_and_comment_ddlUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/xataio/pgstream/internal/json"
+)
+
+// A snapshot archive is a directory with the following layout:
+//
+//	manifest.json
+//	schema/pre_data.sql
+//	schema/post_data.sql
+//	data/<schema>/<table>.<txt|csv>
+//
+// The pre data schema file contains the schema objects to be restored before
+// the data, and the post data one the sequence values, indices, constraints
+// and views, restored once the data has been loaded. The manifest lists all
+// the files with their checksums.
+const (
+	ManifestFile       = "manifest.json"
+	PreDataSchemaFile  = "schema/pre_data.sql"
+	PostDataSchemaFile = "schema/post_data.sql"
+
+	manifestVersion = 1
+	dataDir         = "data"
+)
+
+type SchemaPhase string
+
+const (
+	PreDataPhase  SchemaPhase = "pre_data"
+	PostDataPhase SchemaPhase = "post_data"
+)
+
+type Manifest struct {
+	Version   int           `json:"version"`
+	Format    Format        `json:"format"`
+	CreatedAt time.Time     `json:"created_at"`
+	Schema    []*SchemaFile `json:"schema"`
+	Tables    []*TableFile  `json:"tables"`
+}
+
+type SchemaFile struct {
+	Phase  SchemaPhase `json:"phase"`
+	Path   string      `json:"path"`
+	SHA256 string      `json:"sha256"`
+	Bytes  int64       `json:"bytes"`
+}
+
+type TableFile struct {
+	Schema  string   `json:"schema"`
+	Table   string   `json:"table"`
+	Columns []Column `json:"columns"`
+	Rows    int64    `json:"rows"`
+	Path    string   `json:"path"`
+	SHA256  string   `json:"sha256"`
+	Bytes   int64    `json:"bytes"`
+}
+
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported snapshot archive version")
+	ErrChecksumMismatch   = errors.New("snapshot archive checksum mismatch")
+	ErrInvalidPath        = errors.New("invalid snapshot archive path")
+)
+
+// NewManifest returns an empty manifest for an archive with data files in the
+// format on input.
+func NewManifest(format Format) *Manifest {
+	return &Manifest{
+		Version:   manifestVersion,
+		Format:    format,
+		CreatedAt: time.Now().UTC(),
+		Schema:    []*SchemaFile{},
+		Tables:    []*TableFile{},
+	}
+}
+
+// SchemaFilePath returns the path of the schema file for the phase on input,
+// relative to the archive directory.
+func SchemaFilePath(phase SchemaPhase) string {
+	if phase == PostDataPhase {
+		return PostDataSchemaFile
+	}
+	return PreDataSchemaFile
+}
+
+// TableFilePath returns the path of the data file for the table on input,
+// relative to the archive directory. The schema and table names are escaped
+// so that they're valid file names.
+func TableFilePath(schema, table string, format Format) string {
+	return path.Join(dataDir, url.PathEscape(schema), url.PathEscape(table)+format.Extension())
+}
+
+// ReadManifest reads the manifest of the archive in the directory on input.
+func ReadManifest(dir string) (*Manifest, error) {
+	b, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot archive manifest: %w", err)
+	}
+
+	m := &Manifest{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("parsing snapshot archive manifest: %w", err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, m.Version)
+	}
+	if _, err := ParseFormat(string(m.Format)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Write writes the manifest in the archive directory on input.
+func (m *Manifest) Write(dir string) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("marshaling snapshot archive manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), b, 0o644); err != nil {
+		return fmt.Errorf("writing snapshot archive manifest: %w", err)
+	}
+	return nil
+}
+
+// SchemaFile returns the schema file for the phase on input, or nil if the
+// archive doesn't have one.
+func (m *Manifest) SchemaFile(phase SchemaPhase) *SchemaFile {
+	for _, f := range m.Schema {
+		if f.Phase == phase {
+			return f
+		}
+	}
+	return nil
+}
+
+// Verify checks that all the files listed in the manifest are within the
+// archive directory and match their size and checksum.
+func (m *Manifest) Verify(dir string) error {
+	verify := func(p, wantSHA256 string, wantBytes int64) error {
+		sha, size, err := HashFile(dir, p)
+		if err != nil {
+			return err
+		}
+		if sha != wantSHA256 || size != wantBytes {
+			return fmt.Errorf("%w: %s", ErrChecksumMismatch, p)
+		}
+		return nil
+	}
+
+	for _, f := range m.Schema {
+		if err := verify(f.Path, f.SHA256, f.Bytes); err != nil {
+			return err
+		}
+	}
+	for _, f := range m.Tables {
+		if err := verify(f.Path, f.SHA256, f.Bytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open opens the file on input, relative to the archive directory, making
+// sure it doesn't point outside of it.
+func Open(dir, p string) (*os.File, error) {
+	if !filepath.IsLocal(filepath.FromSlash(p)) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPath, p)
+	}
+	return os.Open(filepath.Join(dir, filepath.FromSlash(p)))
+}
+
+// HashFile returns the hex encoded SHA-256 checksum and the size of the file
+// on input, relative to the archive directory.
+func HashFile(dir, p string) (string, int64, error) {
+	f, err := Open(dir, p)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("hashing %s: %w", p, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableFilePath(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "data/public/users.txt", TableFilePath("public", "users", FormatText))
+	require.Equal(t, "data/My%20Schema/a%2Fb.csv", TableFilePath("My Schema", "a/b", FormatCSV))
+}
+
+func TestManifest_Verify(t *testing.T) {
+	t.Parallel()
+
+	writeArchive := func(t *testing.T) (string, *Manifest) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "schema"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, PreDataSchemaFile), []byte("CREATE TABLE users(id int);\n"), 0o644))
+
+		sha, size, err := HashFile(dir, PreDataSchemaFile)
+		require.NoError(t, err)
+
+		m := NewManifest(FormatText)
+		m.Schema = append(m.Schema, &SchemaFile{Phase: PreDataPhase, Path: PreDataSchemaFile, SHA256: sha, Bytes: size})
+		require.NoError(t, m.Write(dir))
+		return dir, m
+	}
+
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, dir string, m *Manifest)
+
+		wantErr error
+	}{
+		{
+			name:   "ok",
+			modify: func(t *testing.T, dir string, m *Manifest) {},
+
+			wantErr: nil,
+		},
+		{
+			name: "modified file",
+			modify: func(t *testing.T, dir string, m *Manifest) {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, PreDataSchemaFile), []byte("CREATE TABLE users(id bigint);\n"), 0o644))
+			},
+
+			wantErr: ErrChecksumMismatch,
+		},
+		{
+			name: "missing file",
+			modify: func(t *testing.T, dir string, m *Manifest) {
+				require.NoError(t, os.Remove(filepath.Join(dir, PreDataSchemaFile)))
+			},
+
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name: "path outside of the archive",
+			modify: func(t *testing.T, dir string, m *Manifest) {
+				m.Schema[0].Path = "../" + PreDataSchemaFile
+			},
+
+			wantErr: ErrInvalidPath,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir, m := writeArchive(t)
+			readManifest, err := ReadManifest(dir)
+			require.NoError(t, err)
+			require.Equal(t, m.Schema, readManifest.Schema)
+			require.Equal(t, m.Schema[0], readManifest.SchemaFile(PreDataPhase))
+			require.Nil(t, readManifest.SchemaFile(PostDataPhase))
+
+			tc.modify(t, dir, readManifest)
+			require.ErrorIs(t, readManifest.Verify(dir), tc.wantErr)
+		})
+	}
+}
+
+func TestReadManifest_unsupportedVersion(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), []byte(`{"version":2,"format":"text"}`), 0o644))
+
+	_, err := ReadManifest(dir)
+	require.ErrorIs(t, err, ErrUnsupportedVersion)
+}
@@ -0,0 +1,302 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package archive
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format is the format of the table data files. Both formats match the ones
+// supported by the postgres COPY command, with the values in their postgres
+// text representation.
+type Format string
+
+const (
+	// FormatText is the COPY text format: tab separated values, with
+	// backslash escapes and \N for NULL values.
+	FormatText Format = "text"
+	// FormatCSV is the COPY CSV format with a header row. All values are
+	// quoted, and NULL values are unquoted empty fields.
+	FormatCSV Format = "csv"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported snapshot archive format")
+
+// ParseFormat returns the format on input, defaulting to text if empty.
+func ParseFormat(f string) (Format, error) {
+	switch Format(f) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedFormat, f)
+	}
+}
+
+// Extension returns the file extension for the data files in this format.
+func (f Format) Extension() string {
+	if f == FormatCSV {
+		return ".csv"
+	}
+	return ".txt"
+}
+
+// RowWriter writes rows to a data file. A nil value represents NULL.
+type RowWriter struct {
+	w      *bufio.Writer
+	format Format
+}
+
+// RowReader reads the rows of a data file. A nil value represents NULL.
+type RowReader struct {
+	r             *bufio.Reader
+	format        Format
+	headerSkipped bool
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"\b", `\b`,
+	"\f", `\f`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+	"\v", `\v`,
+)
+
+const textNull = `\N`
+
+func NewRowWriter(w io.Writer, format Format) *RowWriter {
+	return &RowWriter{
+		w:      bufio.NewWriter(w),
+		format: format,
+	}
+}
+
+// WriteHeader writes the header row with the column names on input, if the
+// format has one.
+func (rw *RowWriter) WriteHeader(columns []string) error {
+	if rw.format != FormatCSV {
+		return nil
+	}
+	values := make([]*string, 0, len(columns))
+	for i := range columns {
+		values = append(values, &columns[i])
+	}
+	return rw.WriteRow(values)
+}
+
+func (rw *RowWriter) WriteRow(values []*string) error {
+	for i, v := range values {
+		if i > 0 {
+			if err := rw.w.WriteByte(rw.separator()); err != nil {
+				return err
+			}
+		}
+		if err := rw.writeValue(v); err != nil {
+			return err
+		}
+	}
+	return rw.w.WriteByte('\n')
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (rw *RowWriter) Flush() error {
+	return rw.w.Flush()
+}
+
+func (rw *RowWriter) separator() byte {
+	if rw.format == FormatCSV {
+		return ','
+	}
+	return '\t'
+}
+
+func (rw *RowWriter) writeValue(v *string) error {
+	var err error
+	switch {
+	case rw.format == FormatCSV && v == nil:
+		// NULL is an unquoted empty field
+	case rw.format == FormatCSV:
+		_, err = rw.w.WriteString(`"` + strings.ReplaceAll(*v, `"`, `""`) + `"`)
+	case v == nil:
+		_, err = rw.w.WriteString(textNull)
+	default:
+		_, err = textEscaper.WriteString(rw.w, *v)
+	}
+	return err
+}
+
+func NewRowReader(r io.Reader, format Format) *RowReader {
+	return &RowReader{
+		r:      bufio.NewReader(r),
+		format: format,
+	}
+}
+
+// ReadRow returns the next row in the data file, skipping the header row if
+// the format has one. It returns io.EOF once there are no more rows.
+func (rr *RowReader) ReadRow() ([]*string, error) {
+	if rr.format != FormatCSV {
+		return rr.readTextRow()
+	}
+
+	if !rr.headerSkipped {
+		rr.headerSkipped = true
+		if _, err := rr.readCSVRow(); err != nil {
+			return nil, err
+		}
+	}
+	return rr.readCSVRow()
+}
+
+func (rr *RowReader) readTextRow() ([]*string, error) {
+	line, err := rr.r.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\n")
+
+	fields := strings.Split(line, "\t")
+	values := make([]*string, 0, len(fields))
+	for _, field := range fields {
+		if field == textNull {
+			values = append(values, nil)
+			continue
+		}
+		v := unescapeText(field)
+		values = append(values, &v)
+	}
+	return values, nil
+}
+
+// unescapeText reverses the COPY text format escaping, including the octal
+// and hexadecimal escapes that postgres accepts.
+func unescapeText(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	b := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case 'x':
+			v, n := parseDigits(s[i+1:], 16, 2)
+			if n == 0 {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteByte(v)
+			i += n
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			v, n := parseDigits(s[i:], 8, 3)
+			b.WriteByte(v)
+			i += n - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func parseDigits(s string, base, maxDigits int) (byte, int) {
+	v, n := 0, 0
+	for ; n < len(s) && n < maxDigits; n++ {
+		d := strings.IndexByte("0123456789abcdef", lower(s[n]))
+		if d < 0 || d >= base {
+			break
+		}
+		v = v*base + d
+	}
+	return byte(v), n
+}
+
+func lower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func (rr *RowReader) readCSVRow() ([]*string, error) {
+	values := []*string{}
+	field := strings.Builder{}
+	quoted, inQuotes, started := false, false, false
+
+	endField := func() {
+		if !quoted && field.Len() == 0 {
+			values = append(values, nil)
+		} else {
+			v := field.String()
+			values = append(values, &v)
+		}
+		field.Reset()
+		quoted = false
+	}
+
+	for {
+		c, err := rr.r.ReadByte()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			if inQuotes {
+				return nil, fmt.Errorf("unterminated CSV quoted field: %w", io.ErrUnexpectedEOF)
+			}
+			if !started {
+				return nil, io.EOF
+			}
+			endField()
+			return values, nil
+		}
+		started = true
+
+		switch {
+		case inQuotes && c == '"':
+			// a doubled quote is an escaped quote within the quoted field
+			if next, err := rr.r.Peek(1); err == nil && next[0] == '"' {
+				if _, err := rr.r.Discard(1); err != nil {
+					return nil, err
+				}
+				field.WriteByte('"')
+				continue
+			}
+			inQuotes = false
+		case inQuotes:
+			field.WriteByte(c)
+		case c == '"':
+			inQuotes, quoted = true, true
+		case c == ',':
+			endField()
+		case c == '\n':
+			endField()
+			return values, nil
+		case c == '\r':
+			// tolerate CRLF line endings
+		default:
+			field.WriteByte(c)
+		}
+	}
+}
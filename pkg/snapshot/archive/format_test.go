@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowWriter_RowReader(t *testing.T) {
+	t.Parallel()
+
+	ptr := func(s string) *string { return &s }
+
+	columns := []string{"id", "name", "notes"}
+	rows := [][]*string{
+		{ptr("1"), ptr("alice"), nil},
+		{ptr("2"), ptr(""), ptr("tab\there, new\nline and \\ backslash")},
+		{ptr("3"), ptr(`quoted "name", with comma`), ptr(`\N`)},
+	}
+
+	tests := []struct {
+		name   string
+		format Format
+
+		wantData string
+	}{
+		{
+			name:   "text",
+			format: FormatText,
+
+			wantData: "1\talice\t\\N\n" +
+				"2\t\ttab\\there, new\\nline and \\\\ backslash\n" +
+				"3\tquoted \"name\", with comma\t\\\\N\n",
+		},
+		{
+			name:   "csv",
+			format: FormatCSV,
+
+			wantData: `"id","name","notes"` + "\n" +
+				`"1","alice",` + "\n" +
+				`"2","","tab` + "\t" + `here, new` + "\n" + `line and \ backslash"` + "\n" +
+				`"3","quoted ""name"", with comma","\N"` + "\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			rw := NewRowWriter(buf, tc.format)
+			require.NoError(t, rw.WriteHeader(columns))
+			for _, row := range rows {
+				require.NoError(t, rw.WriteRow(row))
+			}
+			require.NoError(t, rw.Flush())
+			require.Equal(t, tc.wantData, buf.String())
+
+			rr := NewRowReader(strings.NewReader(buf.String()), tc.format)
+			for _, row := range rows {
+				got, err := rr.ReadRow()
+				require.NoError(t, err)
+				require.Equal(t, row, got)
+			}
+			_, err := rr.ReadRow()
+			require.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestRowReader_ReadRow(t *testing.T) {
+	t.Parallel()
+
+	ptr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name   string
+		format Format
+		data   string
+
+		wantRows [][]*string
+		wantErr  error
+	}{
+		{
+			name:   "text with octal and hex escapes and no trailing new line",
+			format: FormatText,
+			data:   "\\101\\x42\\q\t\\N",
+
+			wantRows: [][]*string{{ptr("ABq"), nil}},
+		},
+		{
+			name:   "csv with crlf line endings",
+			format: FormatCSV,
+			data:   "\"a\",\"b\"\r\n\"1\",\r\n",
+
+			wantRows: [][]*string{{ptr("1"), nil}},
+		},
+		{
+			name:   "csv with unterminated quoted field",
+			format: FormatCSV,
+			data:   "\"a\"\n\"1",
+
+			wantErr: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rr := NewRowReader(strings.NewReader(tc.data), tc.format)
+			rows := [][]*string{}
+			for {
+				row, err := rr.ReadRow()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					require.ErrorIs(t, err, tc.wantErr)
+					return
+				}
+				rows = append(rows, row)
+			}
+			require.NoError(t, tc.wantErr)
+			require.Equal(t, tc.wantRows, rows)
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		format string
+
+		wantFormat Format
+		wantErr    error
+	}{
+		{
+			name:       "default",
+			format:     "",
+			wantFormat: FormatText,
+		},
+		{
+			name:       "csv",
+			format:     "csv",
+			wantFormat: FormatCSV,
+		},
+		{
+			name:    "parquet",
+			format:  "parquet",
+			wantErr: ErrUnsupportedFormat,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			format, err := ParseFormat(tc.format)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantFormat, format)
+		})
+	}
+}
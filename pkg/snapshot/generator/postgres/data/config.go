@@ -2,6 +2,8 @@
 
 package postgres
 
+import "time"
+
 type Config struct {
 	// Postgres connection URL. Required.
 	URL string
@@ -22,6 +24,98 @@ type Config struct {
 	// snapshot generator can open to Postgres. This setting is optional.
 	// Defaults to 50
 	MaxConnections uint
+	// Throttle limits the load the snapshot puts on the source database. This
+	// setting is optional, snapshots are not throttled by default.
+	Throttle *ThrottleConfig
+	// Subset limits the snapshot to a referentially consistent subset of the
+	// source database. This setting is optional, tables are snapshotted in
+	// full by default.
+	Subset *SubsetConfig
+	// Reader is the default method used to read the table rows. Defaults to
+	// auto.
+	Reader TableReader
+	// TableReaders overrides the reader of specific tables, keyed by their
+	// schema qualified name. If not schema qualified, the public schema is
+	// assumed.
+	TableReaders map[string]TableReaderConfig
+}
+
+// TableReader is the method used to read the rows of a table.
+type TableReader string
+
+const (
+	// TableReaderAuto uses the ctid reader for regular tables, unless they're
+	// heavily bloated and have a unique key, and the keyset reader for views,
+	// materialized views and foreign tables. Partitioned tables are read via
+	// their partitions.
+	TableReaderAuto TableReader = "auto"
+	// TableReaderCtid splits the table in physical page ranges, read
+	// concurrently using ctid range scans. Only supported for regular tables.
+	TableReaderCtid TableReader = "ctid"
+	// TableReaderKeyset splits the table in ranges of its primary key or unique
+	// index, read concurrently using index range scans.
+	TableReaderKeyset TableReader = "keyset"
+)
+
+type TableReaderConfig struct {
+	// Reader is the method used to read the table rows.
+	Reader TableReader
+	// Index is the name of the unique index used by the keyset reader.
+	// Defaults to the primary key, or the unique index with the fewest
+	// columns if there's none.
+	Index string
+	// Columns are the unique, non nullable columns used by the keyset reader,
+	// for relations without indexes, such as views. Takes precedence over
+	// the Index.
+	Columns []string
+}
+
+type SubsetConfig struct {
+	// Rules select the rows of the subset root tables. The rows of the tables
+	// related to them through foreign keys are selected accordingly.
+	Rules []SubsetRule
+	// TransformedColumns are the transformer rules applied to the snapshot
+	// columns, keyed by the schema qualified column name (schema.table.column).
+	// They're used to validate that the subset references are still valid once
+	// transformed.
+	TransformedColumns map[string]string
+}
+
+type SubsetRule struct {
+	// Table is the root table name. If not schema qualified, the public schema
+	// is assumed.
+	Table string
+	// Where is the SQL condition selecting the table rows.
+	Where string
+	// Percent is the percentage of the table rows to select, between 0 and
+	// 100. If combined with Where, it applies to the rows matching the
+	// condition.
+	Percent float64
+}
+
+type ThrottleConfig struct {
+	// RowsPerSecond is the maximum number of rows per second read across all
+	// the snapshot workers. Unlimited if not set.
+	RowsPerSecond uint
+	// BytesPerSecond is the maximum number of bytes per second read across
+	// all the snapshot workers, estimated from the average row size of each
+	// table. Unlimited if not set.
+	BytesPerSecond uint64
+	// MaxReplicationLag pauses the snapshot reads while the replay lag of any
+	// of the source standbys is above it. Disabled if not set.
+	MaxReplicationLag time.Duration
+	// MaxActiveConnections pauses the snapshot reads while the number of
+	// active client connections on the source, including the snapshot ones,
+	// is above it. Disabled if not set.
+	MaxActiveConnections uint
+	// ProbeQuery is a custom query returning a single numeric value. The
+	// snapshot reads are paused while the value is above the ProbeThreshold.
+	// Disabled if not set.
+	ProbeQuery     string
+	ProbeThreshold float64
+	// ProbeInterval is how often the source load signals are checked.
+	// Defaults to 5s.
+	ProbeInterval time.Duration
 }
 
 const (
@@ -30,6 +124,10 @@ const (
 	defaultSnapshotWorkers = 1
 	defaultBatchBytes      = 80 * 1024 * 1024 // 80 MiB
 	defaultMaxConnections  = 50
+	defaultProbeInterval   = 5 * time.Second
+	// defaultKeysetBatchRows is the number of rows per key range when the
+	// average row size of the relation is unknown, such as for views.
+	defaultKeysetBatchRows = 100000
 )
 
 func (c *Config) batchBytes() uint64 {
@@ -66,3 +164,22 @@ func (c *Config) maxConnections() uint {
 	}
 	return defaultMaxConnections
 }
+
+func (c *Config) reader() TableReader {
+	if c.Reader != "" {
+		return c.Reader
+	}
+	return TableReaderAuto
+}
+
+func (c *ThrottleConfig) isEmpty() bool {
+	return c.RowsPerSecond == 0 && c.BytesPerSecond == 0 && c.MaxReplicationLag == 0 &&
+		c.MaxActiveConnections == 0 && c.ProbeQuery == ""
+}
+
+func (c *ThrottleConfig) probeInterval() time.Duration {
+	if c.ProbeInterval > 0 {
+		return c.ProbeInterval
+	}
+	return defaultProbeInterval
+}
@@ -4,9 +4,11 @@ package postgres
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/xataio/pgstream/pkg/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -30,3 +32,80 @@ func (i *instrumentedTableSnapshotGenerator) snapshotTable(ctx context.Context,
 	defer otel.CloseSpan(span, err)
 	return i.snapshotTableFn(ctx, snapshotID, table)
 }
+
+// snapshotMetrics tracks the data snapshot throughput, which can be derived
+// from the rate of the rows and bytes counters.
+type snapshotMetrics struct {
+	rows      metric.Int64Counter
+	bytes     metric.Int64Counter
+	throttled metric.Int64ObservableGauge
+	// subsetRows is the number of rows included in the subset of each table
+	subsetRows metric.Int64Gauge
+}
+
+func newSnapshotMetrics(meter metric.Meter, isThrottled func() bool) (*snapshotMetrics, error) {
+	m := &snapshotMetrics{}
+	var err error
+	m.rows, err = meter.Int64Counter("pgstream.snapshot.generator.rows",
+		metric.WithUnit("rows"),
+		metric.WithDescription("Number of rows read from the source tables by the data snapshot"))
+	if err != nil {
+		return nil, err
+	}
+
+	m.bytes, err = meter.Int64Counter("pgstream.snapshot.generator.bytes",
+		metric.WithUnit("bytes"),
+		metric.WithDescription("Estimated number of bytes read from the source tables by the data snapshot"))
+	if err != nil {
+		return nil, err
+	}
+
+	m.subsetRows, err = meter.Int64Gauge("pgstream.snapshot.generator.subset.rows",
+		metric.WithUnit("rows"),
+		metric.WithDescription("Number of rows included in the data snapshot subset of the table"))
+	if err != nil {
+		return nil, err
+	}
+
+	m.throttled, err = meter.Int64ObservableGauge("pgstream.snapshot.generator.throttled",
+		metric.WithDescription("Whether the data snapshot reads are paused due to high source load (1) or not (0)"))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		throttled := int64(0)
+		if isThrottled() {
+			throttled = 1
+		}
+		o.ObserveInt64(m.throttled, throttled)
+		return nil
+	}, m.throttled)
+	if err != nil {
+		return nil, fmt.Errorf("registering data snapshot metric callbacks: %w", err)
+	}
+
+	return m, nil
+}
+
+func (m *snapshotMetrics) recordRows(ctx context.Context, table *table, rowCount int64) {
+	if m == nil {
+		return
+	}
+	attrs := metric.WithAttributes(
+		attribute.String("schema", table.schema),
+		attribute.String("table", table.name),
+	)
+	m.rows.Add(ctx, rowCount, attrs)
+	m.bytes.Add(ctx, rowCount*table.rowSize, attrs)
+}
+
+func (m *snapshotMetrics) recordSubsetRows(ctx context.Context, table *table, rowCount int64) {
+	if m == nil {
+		return
+	}
+	m.subsetRows.Record(ctx, rowCount, metric.WithAttributes(
+		attribute.String("schema", table.schema),
+		attribute.String("table", table.name),
+	))
+}
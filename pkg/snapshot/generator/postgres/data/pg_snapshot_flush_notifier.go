@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/checkpointer"
+)
+
+// FlushNotifier notifies the snapshot generator when the processor has flushed
+// the rows of a page range, so that the range is only checkpointed once its
+// rows have been written. Once a range has been read, the generator sends a
+// marker position through the processor, which is passed to the processor
+// checkpointer once all the events sent before it have been flushed.
+type FlushNotifier struct {
+	mutex     sync.Mutex
+	next      uint64
+	callbacks map[wal.CommitPosition]func(context.Context)
+	// checkpoint receives the positions that are not markers, if set
+	checkpoint checkpointer.Checkpoint
+}
+
+const flushMarkerPrefix = "pgstream-snapshot-flush/"
+
+// NewFlushNotifier returns a flush notifier that forwards the positions that
+// are not flush markers to the checkpointer on input, if any.
+func NewFlushNotifier(checkpoint checkpointer.Checkpoint) *FlushNotifier {
+	return &FlushNotifier{
+		callbacks:  map[wal.CommitPosition]func(context.Context){},
+		checkpoint: checkpoint,
+	}
+}
+
+// Checkpoint runs the callbacks of the flushed markers, and forwards the rest
+// of the positions to the wrapped checkpointer. It's meant to be used as the
+// checkpointer of the snapshot processor.
+func (n *FlushNotifier) Checkpoint(ctx context.Context, positions []wal.CommitPosition) error {
+	forward := make([]wal.CommitPosition, 0, len(positions))
+	for _, pos := range positions {
+		if !isFlushMarker(pos) {
+			forward = append(forward, pos)
+			continue
+		}
+
+		n.mutex.Lock()
+		callback, found := n.callbacks[pos]
+		delete(n.callbacks, pos)
+		n.mutex.Unlock()
+		if found {
+			callback(ctx)
+		}
+	}
+
+	if n.checkpoint == nil || len(forward) == 0 {
+		return nil
+	}
+	return n.checkpoint(ctx, forward)
+}
+
+// marker returns a new marker position, which runs the callback on input once
+// it's been flushed.
+func (n *FlushNotifier) marker(callback func(context.Context)) wal.CommitPosition {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.next++
+	pos := wal.CommitPosition(fmt.Sprintf("%s%d", flushMarkerPrefix, n.next))
+	n.callbacks[pos] = callback
+	return pos
+}
+
+func isFlushMarker(pos wal.CommitPosition) bool {
+	return strings.HasPrefix(string(pos), flushMarkerPrefix)
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestFlushNotifier_Checkpoint(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name          string
+		checkpointErr error
+		noCheckpoint  bool
+
+		wantForwarded []wal.CommitPosition
+		wantErr       error
+	}{
+		{
+			name: "ok - markers notified and positions forwarded",
+
+			wantForwarded: []wal.CommitPosition{"0/1", "0/2"},
+		},
+		{
+			name:         "ok - no checkpointer",
+			noCheckpoint: true,
+		},
+		{
+			name:          "error - forwarding positions",
+			checkpointErr: errTest,
+
+			wantForwarded: []wal.CommitPosition{"0/1", "0/2"},
+			wantErr:       errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var forwarded []wal.CommitPosition
+			var checkpoint func(context.Context, []wal.CommitPosition) error
+			if !tc.noCheckpoint {
+				checkpoint = func(ctx context.Context, positions []wal.CommitPosition) error {
+					forwarded = append(forwarded, positions...)
+					return tc.checkpointErr
+				}
+			}
+			n := NewFlushNotifier(checkpoint)
+
+			notified := []int{}
+			first := n.marker(func(context.Context) { notified = append(notified, 1) })
+			second := n.marker(func(context.Context) { notified = append(notified, 2) })
+			require.NotEqual(t, first, second)
+
+			err := n.Checkpoint(context.Background(), []wal.CommitPosition{"0/1", second, "0/2"})
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, []int{2}, notified)
+			require.Equal(t, tc.wantForwarded, forwarded)
+
+			// markers are only notified once
+			err = n.Checkpoint(context.Background(), []wal.CommitPosition{first, second})
+			require.NoError(t, err)
+			require.Equal(t, []int{2, 1}, notified)
+		})
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 	pglib "github.com/xataio/pgstream/internal/postgres"
@@ -16,7 +19,10 @@ import (
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/otel"
 	"github.com/xataio/pgstream/pkg/snapshot"
+	snapshotstore "github.com/xataio/pgstream/pkg/snapshot/store"
+	"github.com/xataio/pgstream/pkg/wal"
 	"github.com/xataio/pgstream/pkg/wal/processor"
+	pgreplication "github.com/xataio/pgstream/pkg/wal/replication/postgres"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -40,6 +46,30 @@ type SnapshotGenerator struct {
 	progressTracking   bool
 	progressBars       *synclib.Map[string, progress.Bar]
 	progressBarBuilder func(totalBytes int64, description string) progress.Bar
+
+	// checkpointStore keeps the completed page ranges per table, so that an
+	// interrupted snapshot can be resumed with only the missing ranges.
+	checkpointStore snapshotstore.CheckpointStore
+	tableProgress   *synclib.Map[string, *tableProgress]
+	// flushNotifier notifies when the rows of a page range have been flushed
+	// by the processor, so that the range can be checkpointed. If not set,
+	// the checkpoints are only saved once the processor has been closed.
+	flushNotifier *FlushNotifier
+	// statsStore keeps the row and byte counts of the latest snapshot of
+	// each table.
+	statsStore snapshotstore.TableStatsStore
+
+	// throttler limits the read rate and pauses the reads when the source is
+	// under load. It's nil when throttling is not configured.
+	throttler *throttler
+	metrics   *snapshotMetrics
+
+	subsetCfg *SubsetConfig
+
+	// reader is the default table reader, and tableReaders the table specific
+	// overrides.
+	reader       TableReader
+	tableReaders map[qualifiedTable]TableReaderConfig
 }
 
 type mapper interface {
@@ -47,10 +77,12 @@ type mapper interface {
 }
 
 type tableInfo struct {
-	pageCount     int
-	avgPageBytes  int64
-	avgRowBytes   int64
-	batchPageSize uint
+	relkind        string
+	deadTupleRatio float64
+	pageCount      int
+	avgPageBytes   int64
+	avgRowBytes    int64
+	batchPageSize  uint
 }
 
 type pageRange struct {
@@ -58,15 +90,46 @@ type pageRange struct {
 	end   uint
 }
 
+// tableProgress keeps track of the page ranges of a table that have been
+// snapshotted, including the ones loaded from its checkpoint.
+type tableProgress struct {
+	schema    string
+	table     string
+	mutex     sync.Mutex
+	completed []pageRange
+	done      bool
+	// lsn is the replication start position stored with the checkpoint. It's
+	// kept from the run that completed the first ranges, since the changes
+	// made to them are only replayed from there.
+	lsn string
+}
+
 type schemaTables struct {
 	schema string
 	tables []string
+	subset *subset
+	start  *replicationStart
+}
+
+// replicationStart keeps the position replication is started from once the
+// snapshot is completed. It's lowered to the position of the table
+// checkpoints the snapshot resumes from, so that the changes made to the
+// ranges completed by the previous runs are replayed.
+type replicationStart struct {
+	mutex sync.Mutex
+	lsn   string
 }
 
 type table struct {
 	schema  string
 	name    string
 	rowSize int64
+	// filter selects the rows of the table that are part of the snapshot
+	// subset. All the rows are selected if empty.
+	filter string
+	// rowCount is the number of rows snapshotted.
+	rowCount atomic.Uint64
+	start    *replicationStart
 }
 
 type snapshotTableFn func(ctx context.Context, snapshotID string, table *table) error
@@ -87,6 +150,13 @@ func NewSnapshotGenerator(ctx context.Context, cfg *Config, processor processor.
 		tableWorkers:    cfg.tableWorkers(),
 		schemaWorkers:   cfg.schemaWorkers(),
 		snapshotWorkers: cfg.snapshotWorkers(),
+		subsetCfg:       cfg.Subset,
+		reader:          cfg.reader(),
+	}
+
+	sg.tableReaders, err = parseTableReaders(cfg.TableReaders)
+	if err != nil {
+		return nil, err
 	}
 
 	sg.tableSnapshotGenerator = sg.snapshotTable
@@ -96,6 +166,8 @@ func NewSnapshotGenerator(ctx context.Context, cfg *Config, processor processor.
 	}
 
 	sg.adapter = newAdapter(pglib.NewMapper(conn), sg.logger)
+	sg.throttler = newThrottler(cfg.Throttle, sg.conn, sg.logger)
+	sg.throttler.start(ctx)
 
 	return sg, nil
 }
@@ -119,6 +191,23 @@ func WithInstrumentation(i *otel.Instrumentation) Option {
 
 		ig := newInstrumentedTableSnapshotGenerator(sg.tableSnapshotGenerator, i)
 		sg.tableSnapshotGenerator = ig.snapshotTable
+
+		if i.Meter != nil {
+			sg.metrics, err = newSnapshotMetrics(i.Meter, func() bool { return sg.throttler.isPaused() })
+			if err != nil {
+				sg.logger.Error(err, "initialising data snapshot metrics")
+			}
+		}
+	}
+}
+
+// WithFlushNotifier checkpoints the page ranges of the tables as soon as
+// their rows have been flushed by the processor, as reported by the notifier
+// on input, instead of only once the processor has been closed. The notifier
+// needs to be set as the checkpointer of the processor.
+func WithFlushNotifier(notifier *FlushNotifier) Option {
+	return func(sg *SnapshotGenerator) {
+		sg.flushNotifier = notifier
 	}
 }
 
@@ -130,6 +219,24 @@ func WithProgressTracking() Option {
 	}
 }
 
+// WithCheckpointStore enables resumable snapshots. The page ranges completed
+// for each table are persisted in the store on input once they've been
+// processed, and only the missing ranges are snapshotted on the next run.
+func WithCheckpointStore(store snapshotstore.CheckpointStore) Option {
+	return func(sg *SnapshotGenerator) {
+		sg.checkpointStore = store
+		sg.tableProgress = synclib.NewMap[string, *tableProgress]()
+	}
+}
+
+// WithTableStatsStore records the row and byte counts, along with the timings,
+// of the snapshot of each table in the store on input.
+func WithTableStatsStore(store snapshotstore.TableStatsStore) Option {
+	return func(sg *SnapshotGenerator) {
+		sg.statsStore = store
+	}
+}
+
 func (sg *SnapshotGenerator) CreateSnapshot(ctx context.Context, ss *snapshot.Snapshot) (err error) {
 	defer func() {
 		// make sure we close the processor once the snapshot is completed.
@@ -140,9 +247,31 @@ func (sg *SnapshotGenerator) CreateSnapshot(ctx context.Context, ss *snapshot.Sn
 			} else {
 				err = errors.Join(err, closeErr)
 			}
+			return
+		}
+		// the final checkpoints are only saved once the processor has been
+		// closed, since that guarantees the rows for the completed ranges
+		// have been written. The checkpoints of the completed tables are kept
+		// if the snapshot failed, so that the replication start position of
+		// this run is not lost when it's resumed.
+		if checkpointErr := sg.saveCheckpoints(err != nil); checkpointErr != nil {
+			sg.logger.Error(checkpointErr, "saving snapshot table checkpoints")
 		}
 	}()
 
+	start := &replicationStart{lsn: ss.LSN}
+	defer func() {
+		ss.LSN = start.getLSN()
+	}()
+
+	var ssSubset *subset
+	if sg.subsetCfg != nil {
+		ssSubset, err = sg.buildSubset(ctx, ss.SchemaTables)
+		if err != nil {
+			return fmt.Errorf("building snapshot subset: %w", err)
+		}
+	}
+
 	// parallelise the snapshot creation for each schema as configured by the snapshot workers.
 	errGroup, ctx := errgroup.WithContext(ctx)
 	schemaTablesChan := make(chan *schemaTables)
@@ -167,6 +296,8 @@ func (sg *SnapshotGenerator) CreateSnapshot(ctx context.Context, ss *snapshot.Sn
 		schemaTablesChan <- &schemaTables{
 			schema: schema,
 			tables: tables,
+			subset: ssSubset,
+			start:  start,
 		}
 	}
 	close(schemaTablesChan)
@@ -180,6 +311,7 @@ func (sg *SnapshotGenerator) CreateSnapshot(ctx context.Context, ss *snapshot.Sn
 }
 
 func (sg *SnapshotGenerator) Close() error {
+	sg.throttler.close()
 	return sg.conn.Close(context.Background())
 }
 
@@ -220,6 +352,8 @@ func (sg *SnapshotGenerator) createSchemaSnapshot(ctx context.Context, schemaTab
 			tableChan <- &table{
 				schema: schemaTables.schema,
 				name:   tableName,
+				filter: schemaTables.subset.filter(schemaTables.schema, tableName),
+				start:  schemaTables.start,
 			}
 		}
 
@@ -236,13 +370,16 @@ func (sg *SnapshotGenerator) createSnapshotWorker(ctx context.Context, wg *sync.
 		logFields := loglib.Fields{"schema": t.schema, "table": t.name, "snapshotID": snapshotID}
 		sg.logger.Debug("snapshotting table", logFields)
 
+		startedAt := time.Now()
 		if err := sg.tableSnapshotGenerator(ctx, snapshotID, t); err != nil {
 			sg.logger.Error(err, "snapshotting table", logFields)
 			// errors will get notified unless the table doesn't exist
-			if !errors.Is(err, pglib.ErrNoRows) {
-				tableErrMap[t.name] = err
+			if errors.Is(err, pglib.ErrNoRows) {
+				continue
 			}
+			tableErrMap[t.name] = err
 		}
+		sg.saveTableStats(t, startedAt)
 		sg.logger.Debug("table snapshot completed", logFields)
 	}
 }
@@ -285,46 +422,121 @@ func (sg *SnapshotGenerator) collectSchemaErrors(workerSchemaErrs map[string]err
 }
 
 func (sg *SnapshotGenerator) snapshotTable(ctx context.Context, snapshotID string, table *table) error {
+	tp, err := sg.loadTableProgress(ctx, table)
+	if err != nil {
+		return err
+	}
+
 	tableInfo, err := sg.getTableInfo(ctx, table.schema, table.name, snapshotID)
 	if err != nil {
 		return err
 	}
+
+	reader, err := sg.tableReader(table, tableInfo, len(tp.completedRanges()) > 0)
+	if err != nil {
+		return err
+	}
+	switch reader {
+	case TableReaderKeyset:
+		table.rowSize = tableInfo.avgRowBytes
+		return sg.snapshotTableKeyRanges(ctx, snapshotID, table, tableInfo, tp)
+	case TableReaderCtid:
+		return sg.snapshotTablePageRanges(ctx, snapshotID, table, tableInfo, tp)
+	default:
+		sg.logger.Debug("skipping table, rows read via its partitions", loglib.Fields{"schema": table.schema, "table": table.name})
+		tp.markDone()
+		return nil
+	}
+}
+
+// snapshotTablePageRanges snapshots the table splitting it in ranges of its
+// physical pages, which are processed concurrently by the table workers.
+func (sg *SnapshotGenerator) snapshotTablePageRanges(ctx context.Context, snapshotID string, table *table, tableInfo *tableInfo, tp *tableProgress) error {
 	if tableInfo.isEmpty() {
+		tp.markDone()
 		return nil
 	}
 	table.rowSize = tableInfo.avgRowBytes
 
+	pageRanges := tableInfo.pageRanges(tp.completedRanges())
+	if len(pageRanges) == 0 {
+		sg.logger.Info("table already snapshotted, skipping", loglib.Fields{"schema": table.schema, "table": table.name})
+		tp.markDone()
+		return nil
+	}
+
 	// If one page range fails, we abort the entire table snapshot. The
 	// snapshot relies on the transaction snapshot id to ensure all workers
 	// have the same table view, which allows us to use the ctid to
 	// parallelise the work.
-	rangeChan := make(chan pageRange, tableInfo.pageCount)
+	rangeChan := make(chan pageRange, len(pageRanges))
 	errGroup, ctx := errgroup.WithContext(ctx)
 	for i := uint(0); i < sg.tableWorkers; i++ {
 		errGroup.Go(func() error {
-			return sg.snapshotTableRangeWorker(ctx, snapshotID, table, rangeChan)
+			return sg.snapshotTableRangeWorker(ctx, snapshotID, table, rangeChan, tp)
 		})
 	}
 
-	// page count returned by postgres starts at 0, so we need to include it
-	// when creating the page ranges.
-	for start := uint(0); start <= uint(tableInfo.pageCount); start += tableInfo.batchPageSize {
-		rangeChan <- pageRange{
-			start: start,
-			end:   start + tableInfo.batchPageSize,
-		}
+	for _, pageRange := range pageRanges {
+		rangeChan <- pageRange
 	}
 
 	// wait for all table ranges to complete
 	close(rangeChan)
-	return errGroup.Wait()
+	if err := errGroup.Wait(); err != nil {
+		return err
+	}
+	tp.markDone()
+	sg.reportSubsetRows(ctx, table)
+	return nil
 }
 
-func (sg *SnapshotGenerator) snapshotTableRangeWorker(ctx context.Context, snapshotID string, table *table, pageRangeChan <-chan pageRange) error {
+// reportSubsetRows logs the size of the table subset, and exposes it in the
+// subset rows metric.
+func (sg *SnapshotGenerator) reportSubsetRows(ctx context.Context, table *table) {
+	if table.filter == "" {
+		return
+	}
+	rowCount := table.rowCount.Load()
+	sg.metrics.recordSubsetRows(ctx, table, int64(rowCount))
+	sg.logger.Info("table subset snapshotted", loglib.Fields{
+		"schema": table.schema, "table": table.name, "rows": rowCount, "bytes": int64(rowCount) * table.rowSize,
+	})
+}
+
+func (sg *SnapshotGenerator) snapshotTableRangeWorker(ctx context.Context, snapshotID string, table *table, pageRangeChan <-chan pageRange, tp *tableProgress) error {
 	for pageRange := range pageRangeChan {
 		if err := sg.snapshotTableRange(ctx, snapshotID, table, pageRange); err != nil {
 			return err
 		}
+		if err := sg.completeRange(ctx, tp, pageRange); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// completeRange records the page range on input as completed once its rows
+// have been read. With a flush notifier, the range is only recorded and
+// checkpointed once the processor has flushed its rows, by sending a marker
+// through the processor after them.
+func (sg *SnapshotGenerator) completeRange(ctx context.Context, tp *tableProgress, r pageRange) error {
+	if tp == nil || sg.flushNotifier == nil {
+		tp.addCompleted(r)
+		return nil
+	}
+
+	marker := sg.flushNotifier.marker(func(context.Context) {
+		tp.addCompleted(r)
+		// make sure the checkpoint is saved regardless of context cancelations
+		ctx, cancel := context.WithTimeout(context.Background(), checkpointTimeout)
+		defer cancel()
+		if err := sg.saveCompletedRanges(ctx, tp); err != nil {
+			sg.logger.Error(err, "saving snapshot table checkpoint", loglib.Fields{"schema": tp.schema, "table": tp.table})
+		}
+	})
+	if err := sg.processor.ProcessWALEvent(ctx, &wal.Event{CommitPosition: marker}); err != nil {
+		return fmt.Errorf("processing snapshot flush marker: %w", err)
 	}
 	return nil
 }
@@ -338,49 +550,182 @@ func (sg *SnapshotGenerator) snapshotTableRange(ctx context.Context, snapshotID
 		})
 
 		query := fmt.Sprintf(pageRangeQuery, pglib.QuoteQualifiedIdentifier(table.schema, table.name), pageRange.start, pageRange.end)
-		rows, err := tx.Query(ctx, query)
-		if err != nil {
-			return fmt.Errorf("querying table rows: %w", err)
+		if table.filter != "" {
+			query = fmt.Sprintf("%s AND (%s)", query, table.filter)
 		}
-		defer rows.Close()
-
-		fieldDescriptions := rows.FieldDescriptions()
-		rowCount := uint(0)
-		for rows.Next() {
-			rowCount++
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-				values, err := rows.Values()
-				if err != nil {
-					return fmt.Errorf("retrieving rows values: %w", err)
-				}
+		return sg.snapshotRows(ctx, snapshotID, tx, table, query)
+	})
+}
 
-				event := sg.adapter.rowToWalEvent(ctx, table.schema, table.name, fieldDescriptions, values)
-				if event == nil {
-					continue
-				}
+// snapshotRows processes the table rows returned by the query on input.
+func (sg *SnapshotGenerator) snapshotRows(ctx context.Context, snapshotID string, tx pglib.Tx, table *table, query string, args ...any) error {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("querying table rows: %w", err)
+	}
+	defer rows.Close()
+
+	fieldDescriptions := rows.FieldDescriptions()
+	rowCount := uint(0)
+	for rows.Next() {
+		rowCount++
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			if err := sg.throttler.wait(ctx, table.rowSize); err != nil {
+				return err
+			}
 
-				if err := sg.processor.ProcessWALEvent(ctx, event); err != nil {
-					return fmt.Errorf("processing snapshot row: %w", err)
-				}
+			values, err := rows.Values()
+			if err != nil {
+				return fmt.Errorf("retrieving rows values: %w", err)
 			}
-		}
 
-		if sg.progressTracking {
-			bar, found := sg.progressBars.Get(table.schema)
-			if found {
-				bar.Add64(int64(rowCount) * table.rowSize)
+			event := sg.adapter.rowToWalEvent(ctx, table.schema, table.name, fieldDescriptions, values)
+			if event == nil {
+				continue
+			}
+
+			if err := sg.processor.ProcessWALEvent(ctx, event); err != nil {
+				return fmt.Errorf("processing snapshot row: %w", err)
 			}
 		}
+	}
 
-		sg.logger.Debug(fmt.Sprintf("%d rows processed", rowCount), loglib.Fields{
-			"schema": table.schema, "table": table.name, "snapshotID": snapshotID,
-		})
+	if sg.progressTracking {
+		bar, found := sg.progressBars.Get(table.schema)
+		if found {
+			bar.Add64(int64(rowCount) * table.rowSize)
+		}
+	}
+	sg.metrics.recordRows(ctx, table, int64(rowCount))
+	table.rowCount.Add(uint64(rowCount))
 
-		return rows.Err()
+	sg.logger.Debug(fmt.Sprintf("%d rows processed", rowCount), loglib.Fields{
+		"schema": table.schema, "table": table.name, "snapshotID": snapshotID,
 	})
+
+	return rows.Err()
+}
+
+// saveTableStats records the row and byte counts of the table snapshot. Errors
+// are only logged, since the stats are informative.
+func (sg *SnapshotGenerator) saveTableStats(table *table, startedAt time.Time) {
+	if sg.statsStore == nil {
+		return
+	}
+
+	// make sure the stats are recorded regardless of context cancelations
+	ctx, cancel := context.WithTimeout(context.Background(), checkpointTimeout)
+	defer cancel()
+
+	rowCount := int64(table.rowCount.Load())
+	stats := &snapshot.TableStats{
+		Schema:     table.schema,
+		Table:      table.name,
+		Rows:       rowCount,
+		Bytes:      rowCount * table.rowSize,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+	}
+	if err := sg.statsStore.SaveTableStats(ctx, stats); err != nil {
+		sg.logger.Error(err, "saving table snapshot stats", loglib.Fields{"schema": table.schema, "table": table.name})
+	}
+}
+
+// loadTableProgress returns the progress of the table on input, initialised
+// with the page ranges from its checkpoint if any. If checkpoints are not
+// enabled, the progress is not tracked.
+func (sg *SnapshotGenerator) loadTableProgress(ctx context.Context, table *table) (*tableProgress, error) {
+	if sg.checkpointStore == nil {
+		return nil, nil
+	}
+
+	tp := &tableProgress{schema: table.schema, table: table.name, lsn: table.start.getLSN()}
+	checkpoint, err := sg.checkpointStore.GetTableCheckpoint(ctx, table.schema, table.name)
+	if err != nil {
+		return nil, fmt.Errorf("getting table checkpoint: %w", err)
+	}
+	if checkpoint != nil {
+		for _, r := range checkpoint.CompletedRanges {
+			tp.completed = append(tp.completed, pageRange{start: r.Start, end: r.End})
+		}
+		if checkpoint.LSN != "" {
+			tp.lsn = checkpoint.LSN
+			if err := table.start.lower(checkpoint.LSN); err != nil {
+				return nil, fmt.Errorf("table checkpoint lsn: %w", err)
+			}
+		}
+		sg.logger.Info("resuming table snapshot from checkpoint", loglib.Fields{
+			"schema": table.schema, "table": table.name, "completed_ranges": len(tp.completed), "lsn": tp.lsn,
+		})
+	}
+	sg.tableProgress.Set(pglib.QuoteQualifiedIdentifier(table.schema, table.name), tp)
+	return tp, nil
+}
+
+const checkpointTimeout = time.Minute
+
+// saveCheckpoints persists the completed page ranges of the tables that were
+// not fully snapshotted, and removes the checkpoints of the ones that were,
+// unless keepCompleted is set.
+func (sg *SnapshotGenerator) saveCheckpoints(keepCompleted bool) error {
+	if sg.checkpointStore == nil {
+		return nil
+	}
+
+	// use a separate context, since the snapshot one might have been
+	// cancelled, which is when the checkpoints are most useful
+	ctx, cancel := context.WithTimeout(context.Background(), checkpointTimeout)
+	defer cancel()
+
+	var errs error
+	for key, tp := range sg.tableProgress.GetMap() {
+		if err := sg.saveCheckpoint(ctx, tp, keepCompleted); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("table %s: %w", key, err))
+		}
+		sg.tableProgress.Delete(key)
+	}
+	return errs
+}
+
+func (sg *SnapshotGenerator) saveCheckpoint(ctx context.Context, tp *tableProgress, keepCompleted bool) error {
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+
+	if tp.done && !keepCompleted {
+		return sg.checkpointStore.DeleteTableCheckpoint(ctx, tp.schema, tp.table)
+	}
+	return sg.storeCompletedRanges(ctx, tp)
+}
+
+// saveCompletedRanges persists the page ranges of the table completed so far,
+// even if the table has been fully snapshotted, since the rows of its ranges
+// might not have been written yet.
+func (sg *SnapshotGenerator) saveCompletedRanges(ctx context.Context, tp *tableProgress) error {
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+	return sg.storeCompletedRanges(ctx, tp)
+}
+
+// storeCompletedRanges persists the completed page ranges of the table. The
+// caller must hold the table progress lock.
+func (sg *SnapshotGenerator) storeCompletedRanges(ctx context.Context, tp *tableProgress) error {
+	completed := mergePageRanges(tp.completed)
+	if len(completed) == 0 {
+		return nil
+	}
+	checkpoint := &snapshot.TableCheckpoint{
+		Schema:          tp.schema,
+		Table:           tp.table,
+		CompletedRanges: make([]snapshot.PageRange, 0, len(completed)),
+		LSN:             tp.lsn,
+	}
+	for _, r := range completed {
+		checkpoint.CompletedRanges = append(checkpoint.CompletedRanges, snapshot.PageRange{Start: r.start, End: r.end})
+	}
+	return sg.checkpointStore.SaveTableCheckpoint(ctx, checkpoint)
 }
 
 func (sg *SnapshotGenerator) addProgressBar(ctx context.Context, snapshotID string, schemaTables *schemaTables) error {
@@ -405,21 +750,25 @@ func (sg *SnapshotGenerator) markProgressBarCompleted(schema string) {
 const (
 	// use pg_table_size instead of pg_total_relation_size since we only care about the size of the table itself and toast tables, not indices.
 	// pg_relation_size will return only the size of the table itself, without toast tables.
+	// The dead tuple ratio is used to detect bloated tables.
 	tableInfoQuery = `SELECT
-  (pg_table_size(c.oid) / COALESCE(NULLIF(c.relpages, 0),1)) AS avg_page_size_bytes,
+  c.relkind::text,
+  (COALESCE(pg_table_size(c.oid), 0) / COALESCE(NULLIF(c.relpages, 0),1)) AS avg_page_size_bytes,
   CASE
 	WHEN c.reltuples > 0 THEN
-		ROUND(pg_table_size(c.oid) / c.reltuples)
+		ROUND(COALESCE(pg_table_size(c.oid), 0) / c.reltuples)
 	ELSE
 		0
-  END AS avg_row_size
+  END AS avg_row_size,
+  COALESCE(s.n_dead_tup::float8 / NULLIF(s.n_live_tup + s.n_dead_tup, 0), 0) AS dead_tuple_ratio
 FROM
   pg_class c
   JOIN pg_namespace n ON n.oid = c.relnamespace
+  LEFT JOIN pg_stat_all_tables s ON s.relid = c.oid
 WHERE
   c.relname = $1
   AND n.nspname = $2
-  AND c.relkind = 'r';`
+  AND c.relkind IN ('r', 'p', 'v', 'm', 'f');`
 
 	// select the max page for the relation instead of using pg_class.relpages, it may not contain an accurate value if
 	// the table is small, the table has active inserts, or the database has not been vacuumed/analyzed recently.
@@ -432,7 +781,7 @@ func (sg *SnapshotGenerator) getTableInfo(ctx context.Context, schemaName, table
 		// make sure the schema and table names are unquoted since the system
 		// catalogs store unquoted names
 		err := tx.QueryRow(ctx,
-			[]any{&tableInfo.avgPageBytes, &tableInfo.avgRowBytes},
+			[]any{&tableInfo.relkind, &tableInfo.avgPageBytes, &tableInfo.avgRowBytes, &tableInfo.deadTupleRatio},
 			tableInfoQuery,
 			pglib.UnquoteIdentifier(tableName),
 			pglib.UnquoteIdentifier(schemaName))
@@ -440,6 +789,11 @@ func (sg *SnapshotGenerator) getTableInfo(ctx context.Context, schemaName, table
 			return fmt.Errorf("getting page information for table %s.%s: %w", schemaName, tableName, err)
 		}
 
+		// only regular tables have physical pages that can be read by ctid
+		if tableInfo.relkind != relkindTable {
+			return nil
+		}
+
 		var ctid pgtype.TID
 		if err := tx.QueryRow(ctx, []any{&ctid}, fmt.Sprintf(maxPageQuery, pglib.QuoteQualifiedIdentifier(schemaName, tableName))); err != nil {
 			return fmt.Errorf("getting max page for table %s.%s: %w", schemaName, tableName, err)
@@ -460,7 +814,7 @@ func (sg *SnapshotGenerator) getTableInfo(ctx context.Context, schemaName, table
 	return tableInfo, nil
 }
 
-const tablesBytesQuery = `SELECT SUM(pg_table_size(c.oid)) FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = $1 AND c.relname = ANY($2) AND c.relkind = 'r';`
+const tablesBytesQuery = `SELECT SUM(pg_table_size(c.oid)) FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = $1 AND c.relname = ANY($2) AND c.relkind IN ('r', 'm');`
 
 func (sg *SnapshotGenerator) getSnapshotSchemaTotalBytes(ctx context.Context, snapshotID, schema string, tables []string) (int64, error) {
 	totalBytes := int64(0)
@@ -553,3 +907,114 @@ func (t *tableInfo) calculateBatchPageSize(bytes uint64) {
 func (t *tableInfo) isEmpty() bool {
 	return t.pageCount < 0
 }
+
+// pageRanges returns the page ranges of the table that still need to be
+// snapshotted, given the ones already completed. Missing ranges are split in
+// batches of the table batch page size.
+func (t *tableInfo) pageRanges(completed []pageRange) []pageRange {
+	// page count returned by postgres starts at 0, so we need to include it
+	// when creating the page ranges.
+	lastPage := uint(t.pageCount)
+	ranges := []pageRange{}
+	next := uint(0)
+	for _, c := range mergePageRanges(completed) {
+		for start := next; start < c.start && start <= lastPage; start += t.batchPageSize {
+			ranges = append(ranges, pageRange{
+				start: start,
+				end:   min(start+t.batchPageSize, c.start),
+			})
+		}
+		next = c.end
+	}
+
+	for start := next; start <= lastPage; start += t.batchPageSize {
+		ranges = append(ranges, pageRange{
+			start: start,
+			end:   start + t.batchPageSize,
+		})
+	}
+	return ranges
+}
+
+func (p *tableProgress) completedRanges() []pageRange {
+	if p == nil {
+		return nil
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return slices.Clone(p.completed)
+}
+
+func (p *tableProgress) addCompleted(r pageRange) {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.completed = append(p.completed, r)
+}
+
+func (p *tableProgress) markDone() {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.done = true
+}
+
+func (r *replicationStart) getLSN() string {
+	if r == nil {
+		return ""
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.lsn
+}
+
+// lower sets the replication start position to the LSN on input if it's
+// earlier than the current one. Nothing is done when replication is not
+// started after the snapshot.
+func (r *replicationStart) lower(lsn string) error {
+	if r == nil {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.lsn == "" {
+		return nil
+	}
+
+	lsnParser := pgreplication.NewLSNParser()
+	current, err := lsnParser.FromString(r.lsn)
+	if err != nil {
+		return err
+	}
+	candidate, err := lsnParser.FromString(lsn)
+	if err != nil {
+		return err
+	}
+	if candidate < current {
+		r.lsn = lsn
+	}
+	return nil
+}
+
+// mergePageRanges returns the page ranges on input sorted, with the
+// overlapping and contiguous ones merged together.
+func mergePageRanges(ranges []pageRange) []pageRange {
+	sorted := slices.Clone(ranges)
+	slices.SortFunc(sorted, func(a, b pageRange) int {
+		return int(a.start) - int(b.start)
+	})
+
+	merged := []pageRange{}
+	for _, r := range sorted {
+		if last := len(merged) - 1; last >= 0 && r.start <= merged[last].end {
+			merged[last].end = max(merged[last].end, r.end)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
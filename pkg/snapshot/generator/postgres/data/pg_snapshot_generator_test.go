@@ -23,6 +23,7 @@ import (
 	synclib "github.com/xataio/pgstream/internal/sync"
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/snapshot"
+	snapshotstoremocks "github.com/xataio/pgstream/pkg/snapshot/store/mocks"
 	"github.com/xataio/pgstream/pkg/wal"
 	"github.com/xataio/pgstream/pkg/wal/processor"
 	processormocks "github.com/xataio/pgstream/pkg/wal/processor/mocks"
@@ -72,13 +73,18 @@ func TestSnapshotGenerator_CreateSnapshot(t *testing.T) {
 	}
 
 	validTableInfoScanFn := func(args ...any) error {
-		require.Len(t, args, 2)
-		pageAvgBytes, ok := args[0].(*int64)
-		require.True(t, ok, fmt.Sprintf("pageAvgBytes, expected *int64, got %T", args[0]))
+		require.Len(t, args, 4)
+		relkind, ok := args[0].(*string)
+		require.True(t, ok, fmt.Sprintf("relkind, expected *string, got %T", args[0]))
+		*relkind = relkindTable
+		pageAvgBytes, ok := args[1].(*int64)
+		require.True(t, ok, fmt.Sprintf("pageAvgBytes, expected *int64, got %T", args[1]))
 		*pageAvgBytes = testPageAvgBytes
-		rowAvgBytes, ok := args[1].(*int64)
-		require.True(t, ok, fmt.Sprintf("rowAvgBytes, expected *int64, got %T", args[1]))
+		rowAvgBytes, ok := args[2].(*int64)
+		require.True(t, ok, fmt.Sprintf("rowAvgBytes, expected *int64, got %T", args[2]))
 		*rowAvgBytes = testRowBytes
+		_, ok = args[3].(*float64)
+		require.True(t, ok, fmt.Sprintf("deadTupleRatio, expected *float64, got %T", args[3]))
 		return nil
 	}
 
@@ -114,8 +120,10 @@ func TestSnapshotGenerator_CreateSnapshot(t *testing.T) {
 		schemaWorkers     uint
 		progressBar       *progressmocks.Bar
 		processorCloseErr error
+		checkpointStore   *snapshotstoremocks.Store
 
 		wantEvents []*wal.Event
+		wantLSN    string
 		wantErr    error
 	}{
 		{
@@ -622,6 +630,109 @@ func TestSnapshotGenerator_CreateSnapshot(t *testing.T) {
 			wantErr:    nil,
 			wantEvents: []*wal.Event{},
 		},
+		{
+			name: "ok - resumed from checkpoint",
+			querier: &pgmocks.Querier{
+				ExecInTxWithOptionsFn: func(_ context.Context, i uint, f func(tx pglib.Tx) error, to pglib.TxOptions) error {
+					require.Equal(t, txOptions, to)
+					switch i {
+					case 1:
+						mockTx := pgmocks.Tx{
+							QueryRowFn: func(_ context.Context, dest []any, query string, args ...any) error {
+								require.Equal(t, exportSnapshotQuery, query)
+								require.Len(t, dest, 1)
+								snapshotID, ok := dest[0].(*string)
+								require.True(t, ok, fmt.Sprintf("snapshotID, expected *string, got %T", dest[0]))
+								*snapshotID = testSnapshotID
+								return nil
+							},
+						}
+						return f(&mockTx)
+					case 2:
+						mockTx := pgmocks.Tx{
+							ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+								require.Equal(t, fmt.Sprintf("SET TRANSACTION SNAPSHOT '%s'", testSnapshotID), query)
+								require.Len(t, args, 0)
+								return pglib.CommandTag{}, nil
+							},
+							QueryRowFn: func(_ context.Context, dest []any, query string, args ...any) error {
+								if query == tableInfoQuery {
+									return validTableInfoScanFn(dest...)
+								}
+								if isMaxPageQuery(query) {
+									require.Len(t, dest, 1)
+									ctid, ok := dest[0].(*pgtype.TID)
+									require.True(t, ok, fmt.Sprintf("ctid, expected *pgtype.TID, got %T", dest[0]))
+									ctid.BlockNumber = 1
+									return nil
+								}
+								return fmt.Errorf("unexpected query %s", query)
+							},
+						}
+						return f(&mockTx)
+					case 3:
+						mockTx := pgmocks.Tx{
+							ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+								require.Equal(t, fmt.Sprintf("SET TRANSACTION SNAPSHOT '%s'", testSnapshotID), query)
+								require.Len(t, args, 0)
+								return pglib.CommandTag{}, nil
+							},
+							QueryFn: func(ctx context.Context, query string, args ...any) (pglib.Rows, error) {
+								require.Equal(t, fmt.Sprintf(pageRangeQuery, quotedSchemaTable1, 1, 2), query)
+								require.Len(t, args, 0)
+								return &pgmocks.Rows{
+									CloseFn:             func() {},
+									NextFn:              func(i uint) bool { return i == 0 },
+									FieldDescriptionsFn: func() []pgconn.FieldDescription { return []pgconn.FieldDescription{} },
+									ValuesFn:            func() ([]any, error) { return []any{}, nil },
+									ErrFn:               func() error { return nil },
+								}, nil
+							},
+						}
+						return f(&mockTx)
+					default:
+						return fmt.Errorf("unexpected call to ExecInTxWithOptions: %d", i)
+					}
+				},
+			},
+
+			checkpointStore: &snapshotstoremocks.Store{
+				GetTableCheckpointFn: func(ctx context.Context, schema, table string) (*snapshot.TableCheckpoint, error) {
+					require.Equal(t, testSchema, schema)
+					require.Equal(t, testTable1, table)
+					return &snapshot.TableCheckpoint{
+						Schema:          testSchema,
+						Table:           testTable1,
+						CompletedRanges: []snapshot.PageRange{{Start: 0, End: 1}},
+						LSN:             "0/1",
+					}, nil
+				},
+				DeleteTableCheckpointFn: func(ctx context.Context, schema, table string) error {
+					require.Equal(t, testSchema, schema)
+					require.Equal(t, testTable1, table)
+					return nil
+				},
+				SaveTableStatsFn: func(ctx context.Context, stats *snapshot.TableStats) error {
+					require.Equal(t, testSchema, stats.Schema)
+					require.Equal(t, testTable1, stats.Table)
+					require.Zero(t, stats.Rows)
+					require.Zero(t, stats.Bytes)
+					require.False(t, stats.FinishedAt.Before(stats.StartedAt))
+					return nil
+				},
+			},
+
+			snapshot: &snapshot.Snapshot{
+				SchemaTables: map[string][]string{
+					testSchema: {testTable1},
+				},
+				LSN: "0/2",
+			},
+
+			wantErr:    nil,
+			wantEvents: []*wal.Event{},
+			wantLSN:    "0/1",
+		},
 		{
 			name: "ok - no data",
 			querier: &pgmocks.Querier{
@@ -1158,18 +1269,23 @@ func TestSnapshotGenerator_CreateSnapshot(t *testing.T) {
 				},
 			}
 			sg.tableSnapshotGenerator = sg.snapshotTable
+			if tc.checkpointStore != nil {
+				WithCheckpointStore(tc.checkpointStore)(&sg)
+				WithTableStatsStore(tc.checkpointStore)(&sg)
+			}
 
 			if tc.schemaWorkers != 0 {
 				sg.schemaWorkers = tc.schemaWorkers
 			}
 
-			s := testSnapshot
+			s := *testSnapshot
 			if tc.snapshot != nil {
-				s = tc.snapshot
+				s = *tc.snapshot
 			}
 
-			err := sg.CreateSnapshot(context.Background(), s)
+			err := sg.CreateSnapshot(context.Background(), &s)
 			require.Equal(t, tc.wantErr, err)
+			require.Equal(t, tc.wantLSN, s.LSN)
 			close(eventChan)
 
 			events := []*wal.Event{}
@@ -1258,6 +1374,252 @@ func TestTablePageInfo_calculateBatchPageSize(t *testing.T) {
 	}
 }
 
+func TestTableInfo_pageRanges(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		tableInfo *tableInfo
+		completed []pageRange
+
+		wantRanges []pageRange
+	}{
+		{
+			name:      "no completed ranges",
+			tableInfo: &tableInfo{pageCount: 4, batchPageSize: 2},
+			completed: nil,
+
+			wantRanges: []pageRange{{start: 0, end: 2}, {start: 2, end: 4}, {start: 4, end: 6}},
+		},
+		{
+			name:      "completed ranges",
+			tableInfo: &tableInfo{pageCount: 9, batchPageSize: 3},
+			completed: []pageRange{{start: 6, end: 7}, {start: 0, end: 3}, {start: 3, end: 4}},
+
+			wantRanges: []pageRange{{start: 4, end: 6}, {start: 7, end: 10}},
+		},
+		{
+			name:      "all ranges completed",
+			tableInfo: &tableInfo{pageCount: 3, batchPageSize: 2},
+			completed: []pageRange{{start: 2, end: 4}, {start: 0, end: 2}},
+
+			wantRanges: []pageRange{},
+		},
+		{
+			name:      "completed ranges beyond the last page",
+			tableInfo: &tableInfo{pageCount: 2, batchPageSize: 1},
+			completed: []pageRange{{start: 0, end: 1}, {start: 5, end: 6}},
+
+			wantRanges: []pageRange{{start: 1, end: 2}, {start: 2, end: 3}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.wantRanges, tc.tableInfo.pageRanges(tc.completed))
+		})
+	}
+}
+
+func TestSnapshotGenerator_saveCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name          string
+		progress      *tableProgress
+		keepCompleted bool
+		saveErr       error
+
+		wantSaved   *snapshot.TableCheckpoint
+		wantDeleted bool
+		wantErr     error
+	}{
+		{
+			name: "ok - table in progress",
+			progress: &tableProgress{
+				schema:    "public",
+				table:     "users",
+				completed: []pageRange{{start: 2, end: 4}, {start: 0, end: 2}, {start: 6, end: 8}},
+				lsn:       "0/1",
+			},
+
+			wantSaved: &snapshot.TableCheckpoint{
+				Schema:          "public",
+				Table:           "users",
+				CompletedRanges: []snapshot.PageRange{{Start: 0, End: 4}, {Start: 6, End: 8}},
+				LSN:             "0/1",
+			},
+		},
+		{
+			name: "ok - table completed",
+			progress: &tableProgress{
+				schema:    "public",
+				table:     "users",
+				completed: []pageRange{{start: 0, end: 2}},
+				done:      true,
+			},
+
+			wantDeleted: true,
+		},
+		{
+			name: "ok - table completed in a failed snapshot",
+			progress: &tableProgress{
+				schema:    "public",
+				table:     "users",
+				completed: []pageRange{{start: 0, end: 2}},
+				done:      true,
+				lsn:       "0/1",
+			},
+			keepCompleted: true,
+
+			wantSaved: &snapshot.TableCheckpoint{
+				Schema:          "public",
+				Table:           "users",
+				CompletedRanges: []snapshot.PageRange{{Start: 0, End: 2}},
+				LSN:             "0/1",
+			},
+			wantDeleted: false,
+		},
+		{
+			name: "ok - no completed ranges",
+			progress: &tableProgress{
+				schema: "public",
+				table:  "users",
+			},
+		},
+		{
+			name: "error - saving checkpoint",
+			progress: &tableProgress{
+				schema:    "public",
+				table:     "users",
+				completed: []pageRange{{start: 0, end: 2}},
+			},
+			saveErr: errTest,
+
+			wantSaved: &snapshot.TableCheckpoint{
+				Schema:          "public",
+				Table:           "users",
+				CompletedRanges: []snapshot.PageRange{{Start: 0, End: 2}},
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var saved *snapshot.TableCheckpoint
+			deleted := false
+			sg := SnapshotGenerator{}
+			WithCheckpointStore(&snapshotstoremocks.Store{
+				SaveTableCheckpointFn: func(ctx context.Context, checkpoint *snapshot.TableCheckpoint) error {
+					saved = checkpoint
+					return tc.saveErr
+				},
+				DeleteTableCheckpointFn: func(ctx context.Context, schema, table string) error {
+					require.Equal(t, tc.progress.schema, schema)
+					require.Equal(t, tc.progress.table, table)
+					deleted = true
+					return nil
+				},
+			})(&sg)
+			sg.tableProgress.Set("public.users", tc.progress)
+
+			err := sg.saveCheckpoints(tc.keepCompleted)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantSaved, saved)
+			require.Equal(t, tc.wantDeleted, deleted)
+			require.Empty(t, sg.tableProgress.GetMap())
+		})
+	}
+}
+
+func TestSnapshotGenerator_completeRange(t *testing.T) {
+	t.Parallel()
+
+	testRange := pageRange{start: 0, end: 4}
+
+	tests := []struct {
+		name          string
+		flushNotifier bool
+
+		wantMarker      bool
+		wantBeforeFlush []pageRange
+		wantSaved       *snapshot.TableCheckpoint
+	}{
+		{
+			name:          "with flush notifier - range checkpointed once flushed",
+			flushNotifier: true,
+
+			wantMarker: true,
+			wantSaved: &snapshot.TableCheckpoint{
+				Schema:          "public",
+				Table:           "users",
+				CompletedRanges: []snapshot.PageRange{{Start: 0, End: 4}},
+			},
+		},
+		{
+			name: "without flush notifier - range completed once read",
+
+			wantBeforeFlush: []pageRange{testRange},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var markers []wal.CommitPosition
+			var saved *snapshot.TableCheckpoint
+			forwarded := []wal.CommitPosition{}
+			notifier := NewFlushNotifier(func(ctx context.Context, positions []wal.CommitPosition) error {
+				forwarded = append(forwarded, positions...)
+				return nil
+			})
+
+			sg := SnapshotGenerator{
+				logger: loglib.NewNoopLogger(),
+				processor: &processormocks.Processor{
+					ProcessWALEventFn: func(ctx context.Context, e *wal.Event) error {
+						require.Nil(t, e.Data)
+						markers = append(markers, e.CommitPosition)
+						return nil
+					},
+				},
+			}
+			WithCheckpointStore(&snapshotstoremocks.Store{
+				SaveTableCheckpointFn: func(ctx context.Context, checkpoint *snapshot.TableCheckpoint) error {
+					saved = checkpoint
+					return nil
+				},
+				DeleteTableCheckpointFn: func(ctx context.Context, schema, table string) error {
+					return errors.New("unexpected call to DeleteTableCheckpointFn")
+				},
+			})(&sg)
+			if tc.flushNotifier {
+				WithFlushNotifier(notifier)(&sg)
+			}
+
+			// the table might be fully read before its rows are flushed
+			tp := &tableProgress{schema: "public", table: "users", done: true}
+			err := sg.completeRange(context.Background(), tp, testRange)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantBeforeFlush, tp.completedRanges())
+			require.Equal(t, tc.wantMarker, len(markers) == 1)
+
+			err = notifier.Checkpoint(context.Background(), append(markers, "0/1"))
+			require.NoError(t, err)
+			require.Equal(t, []wal.CommitPosition{"0/1"}, forwarded)
+			require.Equal(t, tc.wantSaved, saved)
+			require.Equal(t, []pageRange{testRange}, tp.completedRanges())
+		})
+	}
+}
+
 func TestSnapshotGenerator_snapshotTableRange(t *testing.T) {
 	t.Parallel()
 
@@ -1340,6 +1702,46 @@ func TestSnapshotGenerator_snapshotTableRange(t *testing.T) {
 			wantEvents: []*wal.Event{testEvent},
 			wantErr:    nil,
 		},
+		{
+			name: "ok - subset table",
+			querier: &pgmocks.Querier{
+				ExecInTxWithOptionsFn: func(_ context.Context, i uint, f func(tx pglib.Tx) error, to pglib.TxOptions) error {
+					mockTx := pgmocks.Tx{
+						ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+							require.Equal(t, fmt.Sprintf("SET TRANSACTION SNAPSHOT '%s'", testSnapshotID), query)
+							return pglib.CommandTag{}, nil
+						},
+						QueryFn: func(ctx context.Context, query string, args ...any) (pglib.Rows, error) {
+							require.Equal(t, fmt.Sprintf(pageRangeQuery, quotedSchemaTable, 0, 5)+" AND (name = 'alice')", query)
+							return &pgmocks.Rows{
+								CloseFn: func() {},
+								NextFn:  func(i uint) bool { return i == 1 },
+								FieldDescriptionsFn: func() []pgconn.FieldDescription {
+									return []pgconn.FieldDescription{
+										{Name: "id", DataTypeOID: pgtype.UUIDOID},
+										{Name: "name", DataTypeOID: pgtype.TextOID},
+									}
+								},
+								ValuesFn: func() ([]any, error) {
+									return []any{testUUID, "alice"}, nil
+								},
+								ErrFn: func() error { return nil },
+							}, nil
+						},
+					}
+					return f(&mockTx)
+				},
+			},
+			table: &table{
+				schema:  testSchema,
+				name:    testTable,
+				rowSize: 512,
+				filter:  "name = 'alice'",
+			},
+			pageRange:  testPageRange,
+			wantEvents: []*wal.Event{testEvent},
+			wantErr:    nil,
+		},
 		{
 			name: "ok - multiple rows",
 			querier: &pgmocks.Querier{
@@ -1699,3 +2101,52 @@ func TestSnapshotGenerator_snapshotTableRange(t *testing.T) {
 		})
 	}
 }
+
+func TestReplicationStart_lower(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		start *replicationStart
+		lsn   string
+
+		wantLSN string
+		wantErr bool
+	}{
+		{
+			name:    "earlier lsn",
+			start:   &replicationStart{lsn: "0/15D6A88"},
+			lsn:     "0/15D6A00",
+			wantLSN: "0/15D6A00",
+		},
+		{
+			name:    "later lsn",
+			start:   &replicationStart{lsn: "0/15D6A88"},
+			lsn:     "1/0",
+			wantLSN: "0/15D6A88",
+		},
+		{
+			name:    "no replication",
+			start:   &replicationStart{},
+			lsn:     "0/15D6A00",
+			wantLSN: "",
+		},
+		{
+			name:    "invalid lsn",
+			start:   &replicationStart{lsn: "0/15D6A88"},
+			lsn:     "invalid",
+			wantLSN: "0/15D6A88",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.start.lower(tc.lsn)
+			require.Equal(t, tc.wantErr, err != nil)
+			require.Equal(t, tc.wantLSN, tc.start.getLSN())
+		})
+	}
+}
@@ -0,0 +1,296 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// keyRange is a range of the table unique key values, with an inclusive lower
+// bound and an exclusive upper bound. Nil bounds are unbounded.
+type keyRange struct {
+	lower []any
+	upper []any
+}
+
+const (
+	relkindTable            = "r"
+	relkindPartitionedTable = "p"
+
+	// tables with a higher ratio of dead tuples are read with the keyset
+	// reader when the reader is automatically selected, since their page
+	// ranges can be very uneven.
+	bloatedTableDeadTupleRatio = 0.5
+
+	// tableKeyQuery returns the columns of the primary key of the table, or of
+	// the unique index with the fewest columns if there's none. Partial,
+	// expression and nullable indexes are ignored, since they don't identify
+	// all the rows. The index name can be provided to select a specific one.
+	tableKeyQuery = `SELECT array_agg(a.attname ORDER BY k.n)
+	FROM pg_index i
+	JOIN pg_class c ON c.oid = i.indrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	JOIN pg_class ic ON ic.oid = i.indexrelid
+	CROSS JOIN LATERAL unnest(i.indkey::int2[]) WITH ORDINALITY AS k(attnum, n)
+	JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = k.attnum
+	WHERE n.nspname = $1 AND c.relname = $2
+	AND i.indisunique AND i.indpred IS NULL AND i.indexprs IS NULL
+	AND k.n <= i.indnkeyatts
+	AND ($3::text = '' OR ic.relname = $3)
+	GROUP BY i.indexrelid, i.indisprimary
+	HAVING bool_and(a.attnotnull)
+	ORDER BY i.indisprimary DESC, count(*), i.indexrelid
+	LIMIT 1`
+
+	// keyBoundariesQuery returns the key values starting each batch of rows,
+	// in key order.
+	keyBoundariesQuery = "SELECT %[1]s FROM (SELECT %[1]s, row_number() OVER (ORDER BY %[1]s) AS rn FROM %[2]s%[3]s) k WHERE (rn - 1) %% $1 = 0 ORDER BY %[1]s"
+)
+
+var (
+	errUnsupportedTableReader = errors.New("unsupported table reader")
+	errTableKeyNotFound       = errors.New("unique key not found")
+)
+
+// tableReader returns the reader for the table on input, as configured or
+// automatically selected based on its relation kind and bloat. An empty
+// reader is returned when the table rows don't need to be read. Tables with a
+// page range checkpoint are resumed with the ctid reader when it's selected
+// automatically, since the keyset reader doesn't resume from checkpoints.
+func (sg *SnapshotGenerator) tableReader(table *table, info *tableInfo, checkpointed bool) (TableReader, error) {
+	reader := sg.tableReaderConfig(table).Reader
+	if reader == "" {
+		reader = sg.reader
+	}
+
+	switch reader {
+	case TableReaderCtid:
+		if info.relkind != relkindTable {
+			return "", fmt.Errorf("%w: ctid reader for relation kind %q", errUnsupportedTableReader, info.relkind)
+		}
+		return TableReaderCtid, nil
+	case TableReaderKeyset:
+		return TableReaderKeyset, nil
+	case TableReaderAuto, "":
+		switch info.relkind {
+		case relkindTable:
+			if info.deadTupleRatio > bloatedTableDeadTupleRatio && !checkpointed {
+				return TableReaderKeyset, nil
+			}
+			return TableReaderCtid, nil
+		case relkindPartitionedTable:
+			// the rows of the partitioned tables are snapshotted via their
+			// partitions
+			return "", nil
+		default:
+			return TableReaderKeyset, nil
+		}
+	default:
+		return "", fmt.Errorf("%w: %s", errUnsupportedTableReader, reader)
+	}
+}
+
+func (sg *SnapshotGenerator) tableReaderConfig(table *table) TableReaderConfig {
+	return sg.tableReaders[newQualifiedTable(table.schema, table.name)]
+}
+
+// snapshotTableKeyRanges snapshots the table splitting it in ranges of its
+// unique key, which are processed concurrently by the table workers. If the
+// table has no unique key, regular tables are split in page ranges instead,
+// and any other relation is read in a single range.
+func (sg *SnapshotGenerator) snapshotTableKeyRanges(ctx context.Context, snapshotID string, table *table, info *tableInfo, tp *tableProgress) error {
+	if len(tp.completedRanges()) > 0 {
+		sg.logger.Warn(nil, "table checkpoint ignored by keyset reader, snapshotting all rows", loglib.Fields{"schema": table.schema, "table": table.name})
+	}
+
+	key, err := sg.getTableKey(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	keyRanges := []keyRange{{}}
+	switch {
+	case len(key) > 0:
+		keyRanges, err = sg.getKeyRanges(ctx, snapshotID, table, key, info.batchRows(sg.batchBytes))
+		if err != nil {
+			return err
+		}
+	case info.relkind == relkindTable:
+		// page ranges still allow for concurrent reads of regular tables
+		sg.logger.Info("no unique key found, reading table page ranges", loglib.Fields{"schema": table.schema, "table": table.name})
+		return sg.snapshotTablePageRanges(ctx, snapshotID, table, info, tp)
+	default:
+		sg.logger.Info("no unique key found, reading table in a single range", loglib.Fields{"schema": table.schema, "table": table.name})
+	}
+
+	rangeChan := make(chan keyRange, len(keyRanges))
+	errGroup, ctx := errgroup.WithContext(ctx)
+	for i := uint(0); i < sg.tableWorkers; i++ {
+		errGroup.Go(func() error {
+			for keyRange := range rangeChan {
+				if err := sg.snapshotTableKeyRange(ctx, snapshotID, table, key, keyRange); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	for _, keyRange := range keyRanges {
+		rangeChan <- keyRange
+	}
+
+	close(rangeChan)
+	if err := errGroup.Wait(); err != nil {
+		return err
+	}
+	tp.markDone()
+	sg.reportSubsetRows(ctx, table)
+	return nil
+}
+
+func (sg *SnapshotGenerator) snapshotTableKeyRange(ctx context.Context, snapshotID string, table *table, key []string, keyRange keyRange) error {
+	return sg.execInSnapshotTx(ctx, snapshotID, func(tx pglib.Tx) error {
+		sg.logger.Debug("querying table key range", loglib.Fields{
+			"schema": table.schema, "table": table.name, "snapshotID": snapshotID, "lower": keyRange.lower, "upper": keyRange.upper,
+		})
+
+		query, args := keyRangeQuery(table, key, keyRange)
+		return sg.snapshotRows(ctx, snapshotID, tx, table, query, args...)
+	})
+}
+
+// getTableKey returns the unique key columns of the table, as configured or
+// discovered from its indexes. Returns no columns if the table has no unique
+// key.
+func (sg *SnapshotGenerator) getTableKey(ctx context.Context, table *table) ([]string, error) {
+	cfg := sg.tableReaderConfig(table)
+	if len(cfg.Columns) > 0 {
+		return cfg.Columns, nil
+	}
+
+	var key []string
+	err := sg.conn.QueryRow(ctx, []any{&key}, tableKeyQuery, pglib.UnquoteIdentifier(table.schema), pglib.UnquoteIdentifier(table.name), cfg.Index)
+	switch {
+	case errors.Is(err, pglib.ErrNoRows):
+		if cfg.Index != "" {
+			return nil, fmt.Errorf("%w: index %s", errTableKeyNotFound, cfg.Index)
+		}
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("getting unique key for table %s.%s: %w", table.schema, table.name, err)
+	}
+	return key, nil
+}
+
+// getKeyRanges returns the key ranges of the table, with batchRows rows each.
+// The boundaries are computed within the snapshot transaction, so that they
+// split the same rows the workers read.
+func (sg *SnapshotGenerator) getKeyRanges(ctx context.Context, snapshotID string, table *table, key []string, batchRows int64) ([]keyRange, error) {
+	boundaries := [][]any{}
+	err := sg.execInSnapshotTx(ctx, snapshotID, func(tx pglib.Tx) error {
+		where := ""
+		if table.filter != "" {
+			where = " WHERE " + table.filter
+		}
+		rows, err := tx.Query(ctx, fmt.Sprintf(keyBoundariesQuery, quoteColumns(key), pglib.QuoteQualifiedIdentifier(table.schema, table.name), where), batchRows)
+		if err != nil {
+			return fmt.Errorf("querying table key ranges: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			values, err := rows.Values()
+			if err != nil {
+				return fmt.Errorf("retrieving key range values: %w", err)
+			}
+			boundaries = append(boundaries, values)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the first boundary is the lowest key, so the first range is left
+	// unbounded instead
+	ranges := make([]keyRange, 0, len(boundaries))
+	var lower []any
+	for _, boundary := range boundaries[min(1, len(boundaries)):] {
+		ranges = append(ranges, keyRange{lower: lower, upper: boundary})
+		lower = boundary
+	}
+	ranges = append(ranges, keyRange{lower: lower})
+
+	sg.logger.Debug(fmt.Sprintf("table key ranges: %d, batch rows: %d", len(ranges), batchRows), loglib.Fields{
+		"schema": table.schema, "table": table.name, "snapshotID": snapshotID,
+	})
+	return ranges, nil
+}
+
+// keyRangeQuery returns the query selecting the table rows within the key
+// range, along with its arguments.
+func keyRangeQuery(table *table, key []string, keyRange keyRange) (string, []any) {
+	columns := quoteColumns(key)
+	conditions := []string{}
+	args := []any{}
+	bound := func(operator string, values []any) {
+		placeholders := make([]string, 0, len(values))
+		for _, v := range values {
+			args = append(args, v)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s) %s (%s)", columns, operator, strings.Join(placeholders, ", ")))
+	}
+	if keyRange.lower != nil {
+		bound(">=", keyRange.lower)
+	}
+	if keyRange.upper != nil {
+		bound("<", keyRange.upper)
+	}
+	if table.filter != "" {
+		conditions = append(conditions, "("+table.filter+")")
+	}
+
+	query := "SELECT * FROM " + pglib.QuoteQualifiedIdentifier(table.schema, table.name)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if len(key) > 0 {
+		query += " ORDER BY " + columns
+	}
+	return query, args
+}
+
+// batchRows returns the number of rows per key range, given the batch bytes.
+func (t *tableInfo) batchRows(bytes uint64) int64 {
+	if bytes == 0 {
+		return math.MaxInt64
+	}
+	if t.avgRowBytes <= 0 {
+		return defaultKeysetBatchRows
+	}
+	return max(1, int64(bytes)/t.avgRowBytes)
+}
+
+// parseTableReaders returns the table reader configuration keyed by the
+// qualified table.
+func parseTableReaders(tableReaders map[string]TableReaderConfig) (map[qualifiedTable]TableReaderConfig, error) {
+	readers := make(map[qualifiedTable]TableReaderConfig, len(tableReaders))
+	for name, cfg := range tableReaders {
+		table, err := parseQualifiedTable(name)
+		if err != nil {
+			return nil, fmt.Errorf("parsing table reader %s: %w", name, err)
+		}
+		readers[table] = cfg
+	}
+	return readers, nil
+}
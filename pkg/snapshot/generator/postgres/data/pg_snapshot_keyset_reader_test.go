@@ -0,0 +1,361 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	loglib "github.com/xataio/pgstream/pkg/log"
+)
+
+func TestSnapshotGenerator_tableReader(t *testing.T) {
+	t.Parallel()
+
+	testTable := &table{schema: "public", name: "test-table"}
+
+	tests := []struct {
+		name         string
+		reader       TableReader
+		tableReaders map[qualifiedTable]TableReaderConfig
+		info         *tableInfo
+		checkpointed bool
+
+		wantReader TableReader
+		wantErr    error
+	}{
+		{
+			name: "auto - regular table",
+			info: &tableInfo{relkind: "r", deadTupleRatio: 0.1},
+
+			wantReader: TableReaderCtid,
+		},
+		{
+			name:   "auto - bloated regular table",
+			reader: TableReaderAuto,
+			info:   &tableInfo{relkind: "r", deadTupleRatio: 0.7},
+
+			wantReader: TableReaderKeyset,
+		},
+		{
+			name:         "auto - bloated regular table with checkpoint",
+			reader:       TableReaderAuto,
+			info:         &tableInfo{relkind: "r", deadTupleRatio: 0.7},
+			checkpointed: true,
+
+			wantReader: TableReaderCtid,
+		},
+		{
+			name:         "keyset - regular table with checkpoint",
+			reader:       TableReaderKeyset,
+			info:         &tableInfo{relkind: "r"},
+			checkpointed: true,
+
+			wantReader: TableReaderKeyset,
+		},
+		{
+			name:   "auto - view",
+			reader: TableReaderAuto,
+			info:   &tableInfo{relkind: "v"},
+
+			wantReader: TableReaderKeyset,
+		},
+		{
+			name:   "auto - materialized view",
+			reader: TableReaderAuto,
+			info:   &tableInfo{relkind: "m"},
+
+			wantReader: TableReaderKeyset,
+		},
+		{
+			name:   "auto - partitioned table",
+			reader: TableReaderAuto,
+			info:   &tableInfo{relkind: "p"},
+
+			wantReader: "",
+		},
+		{
+			name:   "keyset - partitioned table",
+			reader: TableReaderAuto,
+			tableReaders: map[qualifiedTable]TableReaderConfig{
+				{schema: "public", name: "test-table"}: {Reader: TableReaderKeyset},
+			},
+			info: &tableInfo{relkind: "p"},
+
+			wantReader: TableReaderKeyset,
+		},
+		{
+			name:   "ctid - regular table",
+			reader: TableReaderKeyset,
+			tableReaders: map[qualifiedTable]TableReaderConfig{
+				{schema: "public", name: "test-table"}: {Reader: TableReaderCtid},
+			},
+			info: &tableInfo{relkind: "r"},
+
+			wantReader: TableReaderCtid,
+		},
+		{
+			name:   "error - ctid view",
+			reader: TableReaderCtid,
+			info:   &tableInfo{relkind: "v"},
+
+			wantErr: errUnsupportedTableReader,
+		},
+		{
+			name:   "error - unknown reader",
+			reader: "invalid",
+			info:   &tableInfo{relkind: "r"},
+
+			wantErr: errUnsupportedTableReader,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sg := &SnapshotGenerator{
+				reader:       tc.reader,
+				tableReaders: tc.tableReaders,
+			}
+
+			reader, err := sg.tableReader(testTable, tc.info, tc.checkpointed)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantReader, reader)
+		})
+	}
+}
+
+func TestSnapshotGenerator_getTableKey(t *testing.T) {
+	t.Parallel()
+
+	testTable := &table{schema: "public", name: "test-table"}
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name         string
+		tableReaders map[qualifiedTable]TableReaderConfig
+		key          []string
+		queryErr     error
+
+		wantKey []string
+		wantErr error
+	}{
+		{
+			name: "ok - discovered key",
+			key:  []string{"tenant_id", "id"},
+
+			wantKey: []string{"tenant_id", "id"},
+		},
+		{
+			name: "ok - configured columns",
+			tableReaders: map[qualifiedTable]TableReaderConfig{
+				{schema: "public", name: "test-table"}: {Columns: []string{"id"}},
+			},
+			queryErr: errTest,
+
+			wantKey: []string{"id"},
+		},
+		{
+			name:     "ok - no unique key",
+			queryErr: pglib.ErrNoRows,
+
+			wantKey: nil,
+		},
+		{
+			name: "error - configured index not found",
+			tableReaders: map[qualifiedTable]TableReaderConfig{
+				{schema: "public", name: "test-table"}: {Index: "test_idx"},
+			},
+			queryErr: pglib.ErrNoRows,
+
+			wantErr: errTableKeyNotFound,
+		},
+		{
+			name:     "error - querying key",
+			queryErr: errTest,
+
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sg := &SnapshotGenerator{
+				conn: &pgmocks.Querier{
+					QueryRowFn: func(ctx context.Context, dest []any, query string, args ...any) error {
+						require.Equal(t, tableKeyQuery, query)
+						require.Equal(t, []any{"public", "test-table", tc.tableReaders[qualifiedTable{schema: "public", name: "test-table"}].Index}, args)
+						if tc.queryErr != nil {
+							return tc.queryErr
+						}
+						require.Len(t, dest, 1)
+						*(dest[0].(*[]string)) = tc.key
+						return nil
+					},
+				},
+				tableReaders: tc.tableReaders,
+			}
+
+			key, err := sg.getTableKey(context.Background(), testTable)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantKey, key)
+		})
+	}
+}
+
+func TestSnapshotGenerator_getKeyRanges(t *testing.T) {
+	t.Parallel()
+
+	testSnapshotID := "test-snapshot-id"
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name       string
+		table      *table
+		boundaries [][]any
+		queryErr   error
+
+		wantQuery  string
+		wantRanges []keyRange
+		wantErr    error
+	}{
+		{
+			name:       "ok",
+			table:      &table{schema: "public", name: "test-table"},
+			boundaries: [][]any{{int64(1)}, {int64(100)}, {int64(200)}},
+
+			wantQuery: `SELECT "id" FROM (SELECT "id", row_number() OVER (ORDER BY "id") AS rn FROM "public"."test-table") k WHERE (rn - 1) % $1 = 0 ORDER BY "id"`,
+			wantRanges: []keyRange{
+				{upper: []any{int64(100)}},
+				{lower: []any{int64(100)}, upper: []any{int64(200)}},
+				{lower: []any{int64(200)}},
+			},
+		},
+		{
+			name:  "ok - empty subset table",
+			table: &table{schema: "public", name: "test-table", filter: "id < 10"},
+
+			wantQuery: `SELECT "id" FROM (SELECT "id", row_number() OVER (ORDER BY "id") AS rn FROM "public"."test-table" WHERE id < 10) k WHERE (rn - 1) % $1 = 0 ORDER BY "id"`,
+			wantRanges: []keyRange{
+				{},
+			},
+		},
+		{
+			name:     "error - querying boundaries",
+			table:    &table{schema: "public", name: "test-table"},
+			queryErr: errTest,
+
+			wantQuery: `SELECT "id" FROM (SELECT "id", row_number() OVER (ORDER BY "id") AS rn FROM "public"."test-table") k WHERE (rn - 1) % $1 = 0 ORDER BY "id"`,
+			wantErr:   errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sg := &SnapshotGenerator{
+				logger: loglib.NewNoopLogger(),
+				conn: &pgmocks.Querier{
+					ExecInTxWithOptionsFn: func(ctx context.Context, i uint, f func(tx pglib.Tx) error, to pglib.TxOptions) error {
+						return f(&pgmocks.Tx{
+							ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+								require.Equal(t, fmt.Sprintf("SET TRANSACTION SNAPSHOT '%s'", testSnapshotID), query)
+								return pglib.CommandTag{}, nil
+							},
+							QueryFn: func(ctx context.Context, query string, args ...any) (pglib.Rows, error) {
+								require.Equal(t, tc.wantQuery, query)
+								require.Equal(t, []any{int64(1000)}, args)
+								if tc.queryErr != nil {
+									return nil, tc.queryErr
+								}
+								next := uint(0)
+								return &pgmocks.Rows{
+									CloseFn: func() {},
+									NextFn: func(i uint) bool {
+										next = i
+										return i <= uint(len(tc.boundaries))
+									},
+									ValuesFn: func() ([]any, error) { return tc.boundaries[next-1], nil },
+									ErrFn:    func() error { return nil },
+								}, nil
+							},
+						})
+					},
+				},
+			}
+
+			ranges, err := sg.getKeyRanges(context.Background(), testSnapshotID, tc.table, []string{"id"}, 1000)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantRanges, ranges)
+		})
+	}
+}
+
+func TestKeyRangeQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		table    *table
+		key      []string
+		keyRange keyRange
+
+		wantQuery string
+		wantArgs  []any
+	}{
+		{
+			name:     "bounded range",
+			table:    &table{schema: "public", name: "test-table"},
+			key:      []string{"tenant_id", "id"},
+			keyRange: keyRange{lower: []any{1, 10}, upper: []any{2, 5}},
+
+			wantQuery: `SELECT * FROM "public"."test-table" WHERE ("tenant_id", "id") >= ($1, $2) AND ("tenant_id", "id") < ($3, $4) ORDER BY "tenant_id", "id"`,
+			wantArgs:  []any{1, 10, 2, 5},
+		},
+		{
+			name:     "unbounded lower with subset filter",
+			table:    &table{schema: "public", name: "test-table", filter: "id < 10"},
+			key:      []string{"id"},
+			keyRange: keyRange{upper: []any{5}},
+
+			wantQuery: `SELECT * FROM "public"."test-table" WHERE ("id") < ($1) AND (id < 10) ORDER BY "id"`,
+			wantArgs:  []any{5},
+		},
+		{
+			name:  "no key",
+			table: &table{schema: "public", name: "test-view"},
+
+			wantQuery: `SELECT * FROM "public"."test-view"`,
+			wantArgs:  []any{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			query, args := keyRangeQuery(tc.table, tc.key, tc.keyRange)
+			require.Equal(t, tc.wantQuery, query)
+			require.Equal(t, tc.wantArgs, args)
+		})
+	}
+}
+
+func TestTableInfo_batchRows(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, int64(100), (&tableInfo{avgRowBytes: 10}).batchRows(1000))
+	require.Equal(t, int64(1), (&tableInfo{avgRowBytes: 2000}).batchRows(1000))
+	require.Equal(t, int64(defaultKeysetBatchRows), (&tableInfo{}).batchRows(1000))
+	require.Equal(t, int64(math.MaxInt64), (&tableInfo{avgRowBytes: 10}).batchRows(0))
+}
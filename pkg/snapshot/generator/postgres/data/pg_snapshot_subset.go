@@ -0,0 +1,716 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+)
+
+// subset keeps the filter condition of each table of the snapshot that is
+// part of a referentially consistent subset of the database. Tables without a
+// filter are snapshotted in full.
+//
+// The subset starts from the root tables, with the rows selected by their
+// rules, and follows the foreign key relationships between the snapshot
+// tables:
+//   - the rows referencing the included rows of the root tables and their
+//     dependents are included (e.g. the orders of the included customers, and
+//     the line items of those orders).
+//   - the rows referenced by any included row are included (e.g. the products
+//     of the included line items), so that all references are valid.
+//   - the rows of any other table referencing the subset tables are only
+//     included if all their references are (e.g. the reviews of the included
+//     products by included customers).
+//
+// Self references and relationship cycles (e.g. the managers of employees, or
+// the parents of categories) are followed until no more rows are added, using
+// recursive queries.
+type subset struct {
+	filters map[qualifiedTable]string
+}
+
+type qualifiedTable struct {
+	schema string
+	name   string
+}
+
+type foreignKey struct {
+	table      qualifiedTable
+	columns    []string
+	refTable   qualifiedTable
+	refColumns []string
+}
+
+// subsetBuilder computes the filters of the subset tables from the foreign key
+// relationships between them.
+type subsetBuilder struct {
+	roots map[qualifiedTable]string
+	// parentKeys are the foreign keys of each table, and childKeys the
+	// foreign keys referencing each table
+	parentKeys map[qualifiedTable][]*foreignKey
+	childKeys  map[qualifiedTable][]*foreignKey
+	// dependents are the root tables and the tables referencing them, directly
+	// or indirectly. included are the dependents and all the tables they
+	// reference, directly or indirectly.
+	dependents map[qualifiedTable]bool
+	included   map[qualifiedTable]bool
+	// filtered are all the tables with a filter, which are the included ones
+	// and the ones referencing them.
+	filtered map[qualifiedTable]bool
+
+	// the tables whose filters depend on each other through a relationship
+	// cycle, for each kind of filter, and the filters computed so far.
+	dependentCycles   map[qualifiedTable][]qualifiedTable
+	includedCycles    map[qualifiedTable][]qualifiedTable
+	referencingCycles map[qualifiedTable][]qualifiedTable
+	dependentFilters  map[qualifiedTable]string
+	includedFilters   map[qualifiedTable]string
+	referencingFilter map[qualifiedTable]string
+}
+
+// closureEdge is a foreign key relationship followed within a relationship
+// cycle: the rows of the to table matching the rows of the from table already
+// selected on the column values on input are selected as well.
+type closureEdge struct {
+	from     qualifiedTable
+	to       qualifiedTable
+	fromCols []string
+	toCols   []string
+}
+
+const (
+	publicSchema = "public"
+
+	foreignKeysQuery = `SELECT cn.nspname, c.relname, array_agg(ca.attname ORDER BY k.n), pn.nspname, p.relname, array_agg(pa.attname ORDER BY k.n)
+	FROM pg_constraint con
+	JOIN pg_class c ON c.oid = con.conrelid
+	JOIN pg_namespace cn ON cn.oid = c.relnamespace
+	JOIN pg_class p ON p.oid = con.confrelid
+	JOIN pg_namespace pn ON pn.oid = p.relnamespace
+	CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, refattnum, n)
+	JOIN pg_attribute ca ON ca.attrelid = con.conrelid AND ca.attnum = k.attnum
+	JOIN pg_attribute pa ON pa.attrelid = con.confrelid AND pa.attnum = k.refattnum
+	WHERE con.contype = 'f'
+	GROUP BY con.oid, cn.nspname, c.relname, pn.nspname, p.relname
+	ORDER BY cn.nspname, c.relname, con.oid`
+
+	// rows are sampled using a hash of their ctid, which is stable within the
+	// snapshot transaction, so that the same rows are selected by every query
+	// filtering on the root table.
+	percentCondition = "abs(hashtext(ctid::text)::bigint) %% 10000 < %d"
+)
+
+var (
+	errSubsetRootNotInSnapshot   = errors.New("subset root table is not part of the snapshot")
+	errInvalidSubsetRule         = errors.New("subset rules require a where condition and/or a percent between 0 and 100")
+	errKeyTransformersMismatched = errors.New("transformed key columns must use the same transformer as the columns they reference, otherwise the subset references break")
+)
+
+// buildSubset returns the subset of the snapshot tables on input that
+// satisfies the configured rules.
+func (sg *SnapshotGenerator) buildSubset(ctx context.Context, schemaTables map[string][]string) (*subset, error) {
+	foreignKeys, err := sg.getForeignKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := map[qualifiedTable]bool{}
+	for schema, tableNames := range schemaTables {
+		for _, name := range tableNames {
+			tables[newQualifiedTable(schema, name)] = true
+		}
+	}
+
+	roots := make(map[qualifiedTable]string, len(sg.subsetCfg.Rules))
+	for _, rule := range sg.subsetCfg.Rules {
+		root, condition, err := rule.parse()
+		if err != nil {
+			return nil, err
+		}
+		if !tables[root] {
+			return nil, fmt.Errorf("%w: %s", errSubsetRootNotInSnapshot, root)
+		}
+		roots[root] = condition
+	}
+
+	b := newSubsetBuilder(roots, tables, foreignKeys)
+	if err := b.validateTransformedKeys(sg.subsetCfg.TransformedColumns); err != nil {
+		return nil, err
+	}
+	return b.build(), nil
+}
+
+func (sg *SnapshotGenerator) getForeignKeys(ctx context.Context) ([]*foreignKey, error) {
+	rows, err := sg.conn.Query(ctx, foreignKeysQuery)
+	if err != nil {
+		return nil, fmt.Errorf("getting foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	foreignKeys := []*foreignKey{}
+	for rows.Next() {
+		fk := &foreignKey{}
+		if err := rows.Scan(&fk.table.schema, &fk.table.name, &fk.columns, &fk.refTable.schema, &fk.refTable.name, &fk.refColumns); err != nil {
+			return nil, fmt.Errorf("scanning foreign key: %w", err)
+		}
+		foreignKeys = append(foreignKeys, fk)
+	}
+	return foreignKeys, rows.Err()
+}
+
+// filter returns the condition selecting the rows of the table on input that
+// are part of the subset, or an empty string if the table is not filtered.
+func (s *subset) filter(schema, table string) string {
+	if s == nil {
+		return ""
+	}
+	return s.filters[newQualifiedTable(schema, table)]
+}
+
+// parse returns the root table of the rule and the condition selecting its
+// rows.
+func (r *SubsetRule) parse() (qualifiedTable, string, error) {
+	if r.Percent < 0 || r.Percent > 100 || (r.Where == "" && r.Percent == 0) {
+		return qualifiedTable{}, "", fmt.Errorf("%w: %s", errInvalidSubsetRule, r.Table)
+	}
+
+	table, err := parseQualifiedTable(r.Table)
+	if err != nil {
+		return qualifiedTable{}, "", fmt.Errorf("parsing subset table %s: %w", r.Table, err)
+	}
+
+	conditions := []string{}
+	if r.Where != "" {
+		conditions = append(conditions, "("+r.Where+")")
+	}
+	if r.Percent > 0 && r.Percent < 100 {
+		conditions = append(conditions, fmt.Sprintf(percentCondition, int(r.Percent*100)))
+	}
+	if len(conditions) == 0 {
+		return table, "true", nil
+	}
+	return table, strings.Join(conditions, " AND "), nil
+}
+
+func newSubsetBuilder(roots map[qualifiedTable]string, tables map[qualifiedTable]bool, foreignKeys []*foreignKey) *subsetBuilder {
+	b := &subsetBuilder{
+		roots:             roots,
+		parentKeys:        map[qualifiedTable][]*foreignKey{},
+		childKeys:         map[qualifiedTable][]*foreignKey{},
+		dependents:        map[qualifiedTable]bool{},
+		included:          map[qualifiedTable]bool{},
+		filtered:          map[qualifiedTable]bool{},
+		dependentFilters:  map[qualifiedTable]string{},
+		includedFilters:   map[qualifiedTable]string{},
+		referencingFilter: map[qualifiedTable]string{},
+	}
+
+	// only the relationships between the snapshot tables are followed
+	for _, fk := range foreignKeys {
+		if !tables[fk.table] || !tables[fk.refTable] {
+			continue
+		}
+		b.parentKeys[fk.table] = append(b.parentKeys[fk.table], fk)
+		b.childKeys[fk.refTable] = append(b.childKeys[fk.refTable], fk)
+	}
+
+	b.walk(mapKeys(roots), b.dependents, func(t qualifiedTable) []qualifiedTable {
+		return keyTables(b.childKeys[t], func(fk *foreignKey) qualifiedTable { return fk.table })
+	})
+	b.walk(mapKeys(b.dependents), b.included, func(t qualifiedTable) []qualifiedTable {
+		return keyTables(b.parentKeys[t], func(fk *foreignKey) qualifiedTable { return fk.refTable })
+	})
+	b.walk(mapKeys(b.included), b.filtered, func(t qualifiedTable) []qualifiedTable {
+		return keyTables(b.childKeys[t], func(fk *foreignKey) qualifiedTable { return fk.table })
+	})
+
+	b.dependentCycles = relationshipCycles(b.dependents, func(t qualifiedTable) []qualifiedTable {
+		return keyTables(b.dependentKeys(t), func(fk *foreignKey) qualifiedTable { return fk.refTable })
+	})
+	b.includedCycles = relationshipCycles(b.included, func(t qualifiedTable) []qualifiedTable {
+		return keyTables(b.includedKeys(t), func(fk *foreignKey) qualifiedTable { return fk.table })
+	})
+	referencing := map[qualifiedTable]bool{}
+	for t := range b.filtered {
+		if !b.included[t] {
+			referencing[t] = true
+		}
+	}
+	b.referencingCycles = relationshipCycles(referencing, func(t qualifiedTable) []qualifiedTable {
+		tables := []qualifiedTable{}
+		for _, fk := range b.referencingKeys(t) {
+			if !b.included[fk.refTable] {
+				tables = append(tables, fk.refTable)
+			}
+		}
+		return tables
+	})
+
+	return b
+}
+
+// dependentKeys are the foreign keys of the dependent table on input to other
+// dependent tables.
+func (b *subsetBuilder) dependentKeys(t qualifiedTable) []*foreignKey {
+	return filterKeys(b.parentKeys[t], func(fk *foreignKey) bool { return b.dependents[fk.refTable] })
+}
+
+// includedKeys are the foreign keys of other included tables to the included
+// table on input.
+func (b *subsetBuilder) includedKeys(t qualifiedTable) []*foreignKey {
+	return filterKeys(b.childKeys[t], func(fk *foreignKey) bool { return b.included[fk.table] })
+}
+
+// referencingKeys are the foreign keys of the referencing table on input to
+// the filtered tables.
+func (b *subsetBuilder) referencingKeys(t qualifiedTable) []*foreignKey {
+	return filterKeys(b.parentKeys[t], func(fk *foreignKey) bool { return b.filtered[fk.refTable] })
+}
+
+// walk adds the tables on input, and the ones reachable from them using the
+// next function, to the visited set.
+func (b *subsetBuilder) walk(pending []qualifiedTable, visited map[qualifiedTable]bool, next func(qualifiedTable) []qualifiedTable) {
+	for len(pending) > 0 {
+		t := pending[0]
+		pending = pending[1:]
+		if visited[t] {
+			continue
+		}
+		visited[t] = true
+		pending = append(pending, next(t)...)
+	}
+}
+
+func (b *subsetBuilder) build() *subset {
+	s := &subset{filters: make(map[qualifiedTable]string, len(b.filtered))}
+	for t := range b.filtered {
+		if b.included[t] {
+			s.filters[t] = b.includedFilter(t)
+			continue
+		}
+		s.filters[t] = b.referencingFilterOf(t)
+	}
+	return s
+}
+
+// dependentFilter returns the condition selecting the rows of the dependent
+// table on input that are selected by its root rule or reference a selected
+// row of another dependent table.
+func (b *subsetBuilder) dependentFilter(t qualifiedTable) string {
+	if filter, found := b.dependentFilters[t]; found {
+		return filter
+	}
+
+	cycle := b.dependentCycles[t]
+	seeds := make(map[qualifiedTable]string, len(cycle))
+	edges := []closureEdge{}
+	for _, member := range cycleMembers(t, cycle) {
+		conditions := []string{}
+		if condition, found := b.roots[member]; found {
+			conditions = append(conditions, condition)
+		}
+		for _, fk := range b.dependentKeys(member) {
+			if slices.Contains(cycle, fk.refTable) {
+				// the rows referencing the selected rows of the cycle
+				edges = append(edges, closureEdge{from: fk.refTable, to: fk.table, fromCols: fk.refColumns, toCols: fk.columns})
+				continue
+			}
+			conditions = append(conditions, referencesCondition(fk, b.dependentFilter(fk.refTable)))
+		}
+		seeds[member] = or(conditions)
+	}
+
+	b.setCycleFilters(b.dependentFilters, t, cycle, seeds, edges, false)
+	return b.dependentFilters[t]
+}
+
+// includedFilter returns the condition selecting the rows of the included
+// table on input, which are its dependent rows and the rows referenced by
+// other included rows.
+func (b *subsetBuilder) includedFilter(t qualifiedTable) string {
+	if filter, found := b.includedFilters[t]; found {
+		return filter
+	}
+
+	cycle := b.includedCycles[t]
+	seeds := make(map[qualifiedTable]string, len(cycle))
+	edges := []closureEdge{}
+	for _, member := range cycleMembers(t, cycle) {
+		conditions := []string{}
+		if b.dependents[member] {
+			conditions = append(conditions, b.dependentFilter(member))
+		}
+		for _, fk := range b.includedKeys(member) {
+			if slices.Contains(cycle, fk.table) {
+				// the rows referenced by the selected rows of the cycle
+				edges = append(edges, closureEdge{from: fk.table, to: fk.refTable, fromCols: fk.columns, toCols: fk.refColumns})
+				continue
+			}
+			conditions = append(conditions, referencedCondition(fk, b.includedFilter(fk.table)))
+		}
+		seeds[member] = or(conditions)
+	}
+
+	b.setCycleFilters(b.includedFilters, t, cycle, seeds, edges, false)
+	return b.includedFilters[t]
+}
+
+// referencingFilterOf returns the condition selecting the rows of a table that
+// references the subset without being part of it, which are the ones whose
+// references to the filtered tables are all part of the subset. Within a
+// relationship cycle, the rows referencing rows that are not selected are
+// excluded until no more rows are.
+func (b *subsetBuilder) referencingFilterOf(t qualifiedTable) string {
+	if filter, found := b.referencingFilter[t]; found {
+		return filter
+	}
+
+	cycle := b.referencingCycles[t]
+	seeds := make(map[qualifiedTable]string, len(cycle))
+	edges := []closureEdge{}
+	for _, member := range cycleMembers(t, cycle) {
+		conditions := []string{}
+		for _, fk := range b.referencingKeys(member) {
+			refFilter := ""
+			switch {
+			case b.included[fk.refTable]:
+				refFilter = b.includedFilter(fk.refTable)
+			case slices.Contains(cycle, fk.refTable):
+				// the rows referencing the excluded rows of the cycle
+				edges = append(edges, closureEdge{from: fk.refTable, to: fk.table, fromCols: fk.refColumns, toCols: fk.columns})
+				continue
+			default:
+				refFilter = b.referencingFilterOf(fk.refTable)
+			}
+			// rows with null references don't need to match any row
+			nullConditions := make([]string, 0, len(fk.columns)+1)
+			for _, col := range fk.columns {
+				nullConditions = append(nullConditions, pglib.QuoteIdentifier(col)+" IS NULL")
+			}
+			nullConditions = append(nullConditions, referencesCondition(fk, refFilter))
+			conditions = append(conditions, or(nullConditions))
+		}
+		condition := "true"
+		if len(conditions) > 0 {
+			condition = strings.Join(conditions, " AND ")
+		}
+		seeds[member] = condition
+	}
+
+	if len(edges) == 0 {
+		b.referencingFilter[t] = seeds[t]
+		return seeds[t]
+	}
+
+	// the excluded rows are the ones not matching their conditions, and the
+	// ones referencing excluded rows
+	for member, condition := range seeds {
+		seeds[member] = fmt.Sprintf("NOT coalesce(%s, false)", condition)
+	}
+	b.setCycleFilters(b.referencingFilter, t, cycle, seeds, edges, true)
+	return b.referencingFilter[t]
+}
+
+// setCycleFilters stores the filters of the table on input and the other
+// tables of its relationship cycle, if any. Without a cycle, the filter is the
+// table seed condition. Otherwise, the filters select the rows in the closure
+// of the seed rows of the cycle tables through the cycle edges, or the rows
+// outside of it when negated.
+func (b *subsetBuilder) setCycleFilters(filters map[qualifiedTable]string, t qualifiedTable, cycle []qualifiedTable, seeds map[qualifiedTable]string, edges []closureEdge, negate bool) {
+	if len(edges) == 0 {
+		filters[t] = seeds[t]
+		return
+	}
+	for _, member := range cycle {
+		filter := closureCondition(cycle, seeds, edges, member)
+		if negate {
+			filter = "NOT " + filter
+		}
+		filters[member] = filter
+	}
+}
+
+// relationshipCycles returns the tables on input that are part of a
+// relationship cycle, following the next function, along with all the tables
+// of their cycle, sorted by name. Self references are single table cycles.
+func relationshipCycles(tables map[qualifiedTable]bool, next func(qualifiedTable) []qualifiedTable) map[qualifiedTable][]qualifiedTable {
+	// Tarjan's strongly connected components algorithm
+	index := map[qualifiedTable]int{}
+	lowLink := map[qualifiedTable]int{}
+	onStack := map[qualifiedTable]bool{}
+	stack := []qualifiedTable{}
+	cycles := map[qualifiedTable][]qualifiedTable{}
+
+	var visit func(t qualifiedTable)
+	visit = func(t qualifiedTable) {
+		index[t] = len(index)
+		lowLink[t] = index[t]
+		stack = append(stack, t)
+		onStack[t] = true
+
+		selfReference := false
+		for _, n := range next(t) {
+			if !tables[n] {
+				continue
+			}
+			if n == t {
+				selfReference = true
+			}
+			if _, visited := index[n]; !visited {
+				visit(n)
+				lowLink[t] = min(lowLink[t], lowLink[n])
+			} else if onStack[n] {
+				lowLink[t] = min(lowLink[t], index[n])
+			}
+		}
+
+		if lowLink[t] != index[t] {
+			return
+		}
+		component := []qualifiedTable{}
+		for {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[n] = false
+			component = append(component, n)
+			if n == t {
+				break
+			}
+		}
+		if len(component) == 1 && !selfReference {
+			return
+		}
+		slices.SortFunc(component, func(a, b qualifiedTable) int { return strings.Compare(a.String(), b.String()) })
+		for _, n := range component {
+			cycles[n] = component
+		}
+	}
+
+	for _, t := range sortedTables(tables) {
+		if _, visited := index[t]; !visited {
+			visit(t)
+		}
+	}
+	return cycles
+}
+
+// cycleMembers returns the tables of the cycle on input, or the table itself
+// if it's not part of one.
+func cycleMembers(t qualifiedTable, cycle []qualifiedTable) []qualifiedTable {
+	if len(cycle) == 0 {
+		return []qualifiedTable{t}
+	}
+	return cycle
+}
+
+// closureCondition returns the condition selecting the rows of the table on
+// input that are in the closure of the seed rows of the cycle tables through
+// the cycle edges. The closure is computed with a recursive query, which stops
+// once no more rows are added.
+func closureCondition(cycle []qualifiedTable, seeds map[qualifiedTable]string, edges []closureEdge, t qualifiedTable) string {
+	if len(cycle) == 1 {
+		return selfClosureCondition(t, seeds[t], edges)
+	}
+
+	// the rows of the cycle tables are identified by the position of their
+	// table in the cycle and their ctid, along with the values of the columns
+	// of the edges
+	columns := map[qualifiedTable][]string{}
+	for _, e := range edges {
+		columns[e.from] = appendMissing(columns[e.from], e.fromCols...)
+		columns[e.to] = appendMissing(columns[e.to], e.toCols...)
+	}
+	position := make(map[qualifiedTable]int, len(cycle))
+	seedQueries := make([]string, 0, len(cycle))
+	rowQueries := make([]string, 0, len(cycle))
+	for i, table := range cycle {
+		position[table] = i
+		seed := seeds[table]
+		if seed == "" {
+			seed = "false"
+		}
+		values := closureValues(columns[table])
+		seedQueries = append(seedQueries, fmt.Sprintf("SELECT %d, ctid, %s FROM %s WHERE %s", i, values, table, seed))
+		rowQueries = append(rowQueries, fmt.Sprintf("SELECT %d AS tbl, ctid AS row_id, %s AS vals FROM %s", i, values, table))
+	}
+
+	edgeConditions := make([]string, 0, len(edges))
+	for _, e := range edges {
+		conditions := []string{fmt.Sprintf("c.tbl = %d", position[e.from]), fmt.Sprintf("r.tbl = %d", position[e.to])}
+		for i, col := range e.toCols {
+			conditions = append(conditions, fmt.Sprintf("r.vals->%s = c.vals->%s", pglib.QuoteLiteral(col), pglib.QuoteLiteral(e.fromCols[i])))
+		}
+		edgeConditions = append(edgeConditions, "("+strings.Join(conditions, " AND ")+")")
+	}
+
+	return fmt.Sprintf("ctid IN (WITH RECURSIVE closure(tbl, row_id, vals) AS (%s UNION SELECT r.tbl, r.row_id, r.vals FROM closure c JOIN (%s) r ON %s) SELECT row_id FROM closure WHERE tbl = %d)",
+		strings.Join(seedQueries, " UNION "), strings.Join(rowQueries, " UNION ALL "), strings.Join(edgeConditions, " OR "), position[t])
+}
+
+// selfClosureCondition returns the closure condition of a table that
+// references itself.
+func selfClosureCondition(t qualifiedTable, seed string, edges []closureEdge) string {
+	columns := []string{}
+	edgeConditions := make([]string, 0, len(edges))
+	for _, e := range edges {
+		columns = appendMissing(columns, e.fromCols...)
+		edgeConditions = append(edgeConditions, fmt.Sprintf("(%s) = (%s)", qualifyColumns("r", e.toCols), qualifyColumns("c", e.fromCols)))
+	}
+	return fmt.Sprintf("ctid IN (WITH RECURSIVE closure AS (SELECT ctid AS pgstream_row_id, %[2]s FROM %[1]s WHERE %[3]s UNION SELECT r.ctid, %[4]s FROM %[1]s r JOIN closure c ON %[5]s) SELECT pgstream_row_id FROM closure)",
+		t, quoteColumns(columns), seed, qualifyColumns("r", columns), strings.Join(edgeConditions, " OR "))
+}
+
+// closureValues returns the expression building the JSON object with the
+// values of the columns on input. Null values are left out, so that they
+// don't match any other value.
+func closureValues(columns []string) string {
+	pairs := make([]string, 0, len(columns)*2)
+	for _, col := range columns {
+		pairs = append(pairs, pglib.QuoteLiteral(col), pglib.QuoteIdentifier(col))
+	}
+	return fmt.Sprintf("jsonb_strip_nulls(jsonb_build_object(%s))", strings.Join(pairs, ", "))
+}
+
+// validateTransformedKeys checks that the columns of the followed foreign
+// keys are transformed in the same way as the columns they reference, so that
+// the transformed references are still valid.
+func (b *subsetBuilder) validateTransformedKeys(transformedColumns map[string]string) error {
+	if len(transformedColumns) == 0 {
+		return nil
+	}
+	for t := range b.filtered {
+		for _, fk := range b.parentKeys[t] {
+			if !b.filtered[fk.refTable] {
+				continue
+			}
+			for i, col := range fk.columns {
+				column := qualifiedColumn(fk.table, col)
+				refColumn := qualifiedColumn(fk.refTable, fk.refColumns[i])
+				if transformedColumns[column] != transformedColumns[refColumn] {
+					return fmt.Errorf("%w: %s references %s", errKeyTransformersMismatched, column, refColumn)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// referencesCondition returns the condition selecting the rows of the foreign
+// key table that reference the rows of the referenced table selected by the
+// filter on input.
+func referencesCondition(fk *foreignKey, refFilter string) string {
+	return fmt.Sprintf("(%s) IN (SELECT %s FROM %s WHERE %s)",
+		quoteColumns(fk.columns), quoteColumns(fk.refColumns), fk.refTable, refFilter)
+}
+
+// referencedCondition returns the condition selecting the rows of the
+// referenced table that are referenced by the rows of the foreign key table
+// selected by the filter on input.
+func referencedCondition(fk *foreignKey, filter string) string {
+	return fmt.Sprintf("(%s) IN (SELECT %s FROM %s WHERE %s)",
+		quoteColumns(fk.refColumns), quoteColumns(fk.columns), fk.table, filter)
+}
+
+func newQualifiedTable(schema, name string) qualifiedTable {
+	return qualifiedTable{
+		schema: pglib.UnquoteIdentifier(schema),
+		name:   pglib.UnquoteIdentifier(name),
+	}
+}
+
+// parseQualifiedTable returns the qualified table for the name on input. If
+// not schema qualified, the public schema is assumed.
+func parseQualifiedTable(name string) (qualifiedTable, error) {
+	qn, err := pglib.NewQualifiedName(name)
+	if err != nil {
+		return qualifiedTable{}, err
+	}
+	schema := qn.Schema()
+	if schema == "" {
+		schema = publicSchema
+	}
+	return newQualifiedTable(schema, qn.Name()), nil
+}
+
+func (t qualifiedTable) String() string {
+	return pglib.QuoteQualifiedIdentifier(t.schema, t.name)
+}
+
+func qualifiedColumn(t qualifiedTable, column string) string {
+	return t.schema + "." + t.name + "." + column
+}
+
+func mapKeys[V any](m map[qualifiedTable]V) []qualifiedTable {
+	keys := make([]qualifiedTable, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func keyTables(fks []*foreignKey, table func(*foreignKey) qualifiedTable) []qualifiedTable {
+	tables := make([]qualifiedTable, 0, len(fks))
+	for _, fk := range fks {
+		tables = append(tables, table(fk))
+	}
+	return tables
+}
+
+func filterKeys(fks []*foreignKey, keep func(*foreignKey) bool) []*foreignKey {
+	filtered := make([]*foreignKey, 0, len(fks))
+	for _, fk := range fks {
+		if keep(fk) {
+			filtered = append(filtered, fk)
+		}
+	}
+	return filtered
+}
+
+func sortedTables(tables map[qualifiedTable]bool) []qualifiedTable {
+	sorted := mapKeys(tables)
+	slices.SortFunc(sorted, func(a, b qualifiedTable) int { return strings.Compare(a.String(), b.String()) })
+	return sorted
+}
+
+func appendMissing(columns []string, cols ...string) []string {
+	for _, col := range cols {
+		if !slices.Contains(columns, col) {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+func qualifyColumns(alias string, columns []string) string {
+	qualified := make([]string, 0, len(columns))
+	for _, col := range columns {
+		qualified = append(qualified, alias+"."+pglib.QuoteIdentifier(col))
+	}
+	return strings.Join(qualified, ", ")
+}
+
+func quoteColumns(columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, col := range columns {
+		quoted = append(quoted, pglib.QuoteIdentifier(col))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func or(conditions []string) string {
+	conditions = slices.Compact(conditions)
+	switch len(conditions) {
+	case 0:
+		return "false"
+	case 1:
+		return conditions[0]
+	default:
+		return "(" + strings.Join(conditions, " OR ") + ")"
+	}
+}
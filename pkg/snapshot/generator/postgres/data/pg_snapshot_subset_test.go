@@ -0,0 +1,325 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+)
+
+func TestSnapshotGenerator_buildSubset(t *testing.T) {
+	t.Parallel()
+
+	customers := qualifiedTable{schema: "public", name: "customers"}
+	orders := qualifiedTable{schema: "public", name: "orders"}
+	lineItems := qualifiedTable{schema: "public", name: "line_items"}
+	products := qualifiedTable{schema: "public", name: "products"}
+	reviews := qualifiedTable{schema: "public", name: "reviews"}
+	countries := qualifiedTable{schema: "public", name: "countries"}
+
+	foreignKeys := []*foreignKey{
+		{table: lineItems, columns: []string{"order_id"}, refTable: orders, refColumns: []string{"id"}},
+		{table: lineItems, columns: []string{"product_id"}, refTable: products, refColumns: []string{"id"}},
+		{table: orders, columns: []string{"customer_id"}, refTable: customers, refColumns: []string{"id"}},
+		{table: reviews, columns: []string{"product_id"}, refTable: products, refColumns: []string{"id"}},
+	}
+
+	schemaTables := map[string][]string{
+		"public": {"customers", "orders", "line_items", "products", "reviews", "countries", "employees", "comments", "teams", "users"},
+	}
+
+	customersRoot := "(country = 'ES')"
+	ordersDependent := fmt.Sprintf(`("customer_id") IN (SELECT "id" FROM "public"."customers" WHERE %s)`, customersRoot)
+	lineItemsDependent := fmt.Sprintf(`("order_id") IN (SELECT "id" FROM "public"."orders" WHERE %s)`, ordersDependent)
+	ordersIncluded := fmt.Sprintf(`(%s OR ("id") IN (SELECT "order_id" FROM "public"."line_items" WHERE %s))`, ordersDependent, lineItemsDependent)
+	customersIncluded := fmt.Sprintf(`(%s OR ("id") IN (SELECT "customer_id" FROM "public"."orders" WHERE %s))`, customersRoot, ordersIncluded)
+	productsIncluded := fmt.Sprintf(`("id") IN (SELECT "product_id" FROM "public"."line_items" WHERE %s)`, lineItemsDependent)
+	reviewsReferencing := fmt.Sprintf(`("product_id" IS NULL OR ("product_id") IN (SELECT "id" FROM "public"."products" WHERE %s))`, productsIncluded)
+
+	employees := qualifiedTable{schema: "public", name: "employees"}
+	employeesRoot := "(id < 10)"
+	employeesDependent := fmt.Sprintf(`ctid IN (WITH RECURSIVE closure AS (SELECT ctid AS pgstream_row_id, "id" FROM "public"."employees" WHERE %s UNION SELECT r.ctid, r."id" FROM "public"."employees" r JOIN closure c ON (r."manager_id") = (c."id")) SELECT pgstream_row_id FROM closure)`, employeesRoot)
+	employeesIncluded := fmt.Sprintf(`ctid IN (WITH RECURSIVE closure AS (SELECT ctid AS pgstream_row_id, "manager_id" FROM "public"."employees" WHERE %s UNION SELECT r.ctid, r."manager_id" FROM "public"."employees" r JOIN closure c ON (r."id") = (c."manager_id")) SELECT pgstream_row_id FROM closure)`, employeesDependent)
+	comments := qualifiedTable{schema: "public", name: "comments"}
+	commentsSeed := fmt.Sprintf(`NOT coalesce(("product_id" IS NULL OR ("product_id") IN (SELECT "id" FROM "public"."products" WHERE %s)), false)`, productsIncluded)
+	commentsReferencing := fmt.Sprintf(`NOT ctid IN (WITH RECURSIVE closure AS (SELECT ctid AS pgstream_row_id, "id" FROM "public"."comments" WHERE %s UNION SELECT r.ctid, r."id" FROM "public"."comments" r JOIN closure c ON (r."reply_to") = (c."id")) SELECT pgstream_row_id FROM closure)`, commentsSeed)
+
+	teams := qualifiedTable{schema: "public", name: "teams"}
+	users := qualifiedTable{schema: "public", name: "users"}
+	cycleClosure := func(seeds, values [2]string, edgeConditions string, table int) string {
+		return fmt.Sprintf(`ctid IN (WITH RECURSIVE closure(tbl, row_id, vals) AS (`+
+			`SELECT 0, ctid, %[3]s FROM "public"."teams" WHERE %[1]s UNION `+
+			`SELECT 1, ctid, %[4]s FROM "public"."users" WHERE %[2]s UNION `+
+			`SELECT r.tbl, r.row_id, r.vals FROM closure c JOIN (`+
+			`SELECT 0 AS tbl, ctid AS row_id, %[3]s AS vals FROM "public"."teams" UNION ALL `+
+			`SELECT 1 AS tbl, ctid AS row_id, %[4]s AS vals FROM "public"."users") r ON %[5]s) `+
+			`SELECT row_id FROM closure WHERE tbl = %[6]d)`, seeds[0], seeds[1], values[0], values[1], edgeConditions, table)
+	}
+	// the dependent rows reference selected rows, the included rows are
+	// referenced by selected rows
+	dependentValues := [2]string{
+		`jsonb_strip_nulls(jsonb_build_object('owner_id', "owner_id", 'id', "id"))`,
+		`jsonb_strip_nulls(jsonb_build_object('id', "id", 'team_id', "team_id"))`,
+	}
+	dependentEdges := `(c.tbl = 1 AND r.tbl = 0 AND r.vals->'owner_id' = c.vals->'id') OR (c.tbl = 0 AND r.tbl = 1 AND r.vals->'team_id' = c.vals->'id')`
+	dependentSeeds := [2]string{"false", "(active)"}
+	includedValues := [2]string{
+		`jsonb_strip_nulls(jsonb_build_object('id', "id", 'owner_id', "owner_id"))`,
+		`jsonb_strip_nulls(jsonb_build_object('team_id', "team_id", 'id', "id"))`,
+	}
+	includedEdges := `(c.tbl = 1 AND r.tbl = 0 AND r.vals->'id' = c.vals->'team_id') OR (c.tbl = 0 AND r.tbl = 1 AND r.vals->'id' = c.vals->'owner_id')`
+	includedSeeds := [2]string{
+		cycleClosure(dependentSeeds, dependentValues, dependentEdges, 0),
+		cycleClosure(dependentSeeds, dependentValues, dependentEdges, 1),
+	}
+
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name        string
+		cfg         *SubsetConfig
+		foreignKeys []*foreignKey
+		queryErr    error
+
+		wantFilters map[qualifiedTable]string
+		wantErr     error
+	}{
+		{
+			name: "ok",
+			cfg: &SubsetConfig{
+				Rules: []SubsetRule{{Table: "customers", Where: "country = 'ES'"}},
+			},
+			foreignKeys: foreignKeys,
+
+			wantFilters: map[qualifiedTable]string{
+				customers: customersIncluded,
+				orders:    ordersIncluded,
+				lineItems: lineItemsDependent,
+				products:  productsIncluded,
+				reviews:   reviewsReferencing,
+			},
+		},
+		{
+			name: "ok - transformed keys with the same transformer",
+			cfg: &SubsetConfig{
+				Rules: []SubsetRule{{Table: "public.customers", Where: "country = 'ES'"}},
+				TransformedColumns: map[string]string{
+					"public.customers.id":       "hash",
+					"public.orders.customer_id": "hash",
+					"public.customers.name":     "masking",
+				},
+			},
+			foreignKeys: foreignKeys[2:3],
+
+			wantFilters: map[qualifiedTable]string{
+				customers: fmt.Sprintf(`(%s OR ("id") IN (SELECT "customer_id" FROM "public"."orders" WHERE %s))`, customersRoot, ordersDependent),
+				orders:    ordersDependent,
+			},
+		},
+		{
+			name: "ok - relationships with tables outside of the snapshot are ignored",
+			cfg: &SubsetConfig{
+				Rules: []SubsetRule{{Table: "public.countries", Percent: 10}},
+			},
+			foreignKeys: []*foreignKey{
+				{table: qualifiedTable{schema: "other", name: "cities"}, columns: []string{"country_id"}, refTable: countries, refColumns: []string{"id"}},
+			},
+
+			wantFilters: map[qualifiedTable]string{
+				countries: "abs(hashtext(ctid::text)::bigint) % 10000 < 1000",
+			},
+		},
+		{
+			name: "ok - self references",
+			cfg: &SubsetConfig{
+				Rules: []SubsetRule{{Table: "public.employees", Where: "id < 10"}},
+			},
+			foreignKeys: []*foreignKey{
+				{table: employees, columns: []string{"manager_id"}, refTable: employees, refColumns: []string{"id"}},
+			},
+
+			wantFilters: map[qualifiedTable]string{
+				employees: employeesIncluded,
+			},
+		},
+		{
+			name: "ok - self references of referencing tables",
+			cfg: &SubsetConfig{
+				Rules: []SubsetRule{{Table: "customers", Where: "country = 'ES'"}},
+			},
+			foreignKeys: append(slices.Clone(foreignKeys),
+				&foreignKey{table: comments, columns: []string{"product_id"}, refTable: products, refColumns: []string{"id"}},
+				&foreignKey{table: comments, columns: []string{"reply_to"}, refTable: comments, refColumns: []string{"id"}},
+			),
+
+			wantFilters: map[qualifiedTable]string{
+				customers: customersIncluded,
+				orders:    ordersIncluded,
+				lineItems: lineItemsDependent,
+				products:  productsIncluded,
+				reviews:   reviewsReferencing,
+				comments:  commentsReferencing,
+			},
+		},
+		{
+			name: "ok - relationship cycles",
+			cfg: &SubsetConfig{
+				Rules: []SubsetRule{{Table: "public.users", Where: "active"}},
+			},
+			foreignKeys: []*foreignKey{
+				{table: teams, columns: []string{"owner_id"}, refTable: users, refColumns: []string{"id"}},
+				{table: users, columns: []string{"team_id"}, refTable: teams, refColumns: []string{"id"}},
+			},
+
+			wantFilters: map[qualifiedTable]string{
+				teams: cycleClosure(includedSeeds, includedValues, includedEdges, 0),
+				users: cycleClosure(includedSeeds, includedValues, includedEdges, 1),
+			},
+		},
+		{
+			name: "error - transformed keys with different transformers",
+			cfg: &SubsetConfig{
+				Rules: []SubsetRule{{Table: "public.customers", Where: "country = 'ES'"}},
+				TransformedColumns: map[string]string{
+					"public.customers.id": "hash",
+				},
+			},
+			foreignKeys: foreignKeys,
+
+			wantErr: errKeyTransformersMismatched,
+		},
+		{
+			name: "error - root table not in snapshot",
+			cfg: &SubsetConfig{
+				Rules: []SubsetRule{{Table: "public.accounts", Percent: 1}},
+			},
+			foreignKeys: foreignKeys,
+
+			wantErr: errSubsetRootNotInSnapshot,
+		},
+		{
+			name: "error - invalid rule",
+			cfg: &SubsetConfig{
+				Rules: []SubsetRule{{Table: "public.customers"}},
+			},
+			foreignKeys: foreignKeys,
+
+			wantErr: errInvalidSubsetRule,
+		},
+		{
+			name:     "error - getting foreign keys",
+			cfg:      &SubsetConfig{},
+			queryErr: errTest,
+
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sg := &SnapshotGenerator{
+				conn: &pgmocks.Querier{
+					QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+						require.Equal(t, foreignKeysQuery, query)
+						if tc.queryErr != nil {
+							return nil, tc.queryErr
+						}
+						return &pgmocks.Rows{
+							CloseFn: func() {},
+							NextFn:  func(i uint) bool { return i <= uint(len(tc.foreignKeys)) },
+							ScanFn: func(i uint, dest ...any) error {
+								require.Len(t, dest, 6)
+								fk := tc.foreignKeys[i-1]
+								*(dest[0].(*string)) = fk.table.schema
+								*(dest[1].(*string)) = fk.table.name
+								*(dest[2].(*[]string)) = fk.columns
+								*(dest[3].(*string)) = fk.refTable.schema
+								*(dest[4].(*string)) = fk.refTable.name
+								*(dest[5].(*[]string)) = fk.refColumns
+								return nil
+							},
+							ErrFn: func() error { return nil },
+						}, nil
+					},
+				},
+				subsetCfg: tc.cfg,
+			}
+
+			s, err := sg.buildSubset(context.Background(), schemaTables)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr != nil {
+				require.Nil(t, s)
+				return
+			}
+			require.Equal(t, tc.wantFilters, s.filters)
+		})
+	}
+}
+
+func TestSubsetRule_parse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		rule SubsetRule
+
+		wantTable     qualifiedTable
+		wantCondition string
+		wantErr       error
+	}{
+		{
+			name: "where",
+			rule: SubsetRule{Table: "public.customers", Where: "id < 100"},
+
+			wantTable:     qualifiedTable{schema: "public", name: "customers"},
+			wantCondition: "(id < 100)",
+		},
+		{
+			name: "percent",
+			rule: SubsetRule{Table: `"Sales"."Customers"`, Percent: 0.5},
+
+			wantTable:     qualifiedTable{schema: "Sales", name: "Customers"},
+			wantCondition: "abs(hashtext(ctid::text)::bigint) % 10000 < 50",
+		},
+		{
+			name: "where and percent",
+			rule: SubsetRule{Table: "customers", Where: "active", Percent: 1},
+
+			wantTable:     qualifiedTable{schema: "public", name: "customers"},
+			wantCondition: "(active) AND abs(hashtext(ctid::text)::bigint) % 10000 < 100",
+		},
+		{
+			name: "all rows",
+			rule: SubsetRule{Table: "customers", Percent: 100},
+
+			wantTable:     qualifiedTable{schema: "public", name: "customers"},
+			wantCondition: "true",
+		},
+		{
+			name: "error - percent out of range",
+			rule: SubsetRule{Table: "customers", Percent: 150},
+
+			wantErr: errInvalidSubsetRule,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			table, condition, err := tc.rule.parse()
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantTable, table)
+			require.Equal(t, tc.wantCondition, condition)
+		})
+	}
+}
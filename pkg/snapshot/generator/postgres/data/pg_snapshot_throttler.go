@@ -0,0 +1,225 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"golang.org/x/time/rate"
+)
+
+// throttler limits the rate at which the snapshot rows are read from the
+// source database, and pauses the reads while the source load signals are
+// above their configured thresholds.
+type throttler struct {
+	logger loglib.Logger
+	conn   pglib.Querier
+	rows   *rate.Limiter
+	bytes  *rate.Limiter
+	probes []loadProbe
+
+	probeInterval time.Duration
+
+	mutex sync.Mutex
+	// resume is not nil while the reads are paused, and is closed when they
+	// can be resumed
+	resume chan struct{}
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// loadProbe checks a source load signal, returning a description of the
+// exceeded threshold if the signal is above it, or an empty string otherwise.
+type loadProbe func(ctx context.Context, conn pglib.Querier) (string, error)
+
+const (
+	replicationLagQuery    = "SELECT COALESCE(EXTRACT(EPOCH FROM MAX(replay_lag)), 0)::float8 FROM pg_stat_replication"
+	activeConnectionsQuery = "SELECT count(*) FROM pg_stat_activity WHERE state = 'active' AND backend_type = 'client backend'"
+)
+
+func newThrottler(cfg *ThrottleConfig, conn pglib.Querier, logger loglib.Logger) *throttler {
+	if cfg == nil || cfg.isEmpty() {
+		return nil
+	}
+
+	t := &throttler{
+		logger:        logger,
+		conn:          conn,
+		probeInterval: cfg.probeInterval(),
+	}
+	if cfg.RowsPerSecond > 0 {
+		t.rows = rate.NewLimiter(rate.Limit(cfg.RowsPerSecond), int(cfg.RowsPerSecond))
+	}
+	if cfg.BytesPerSecond > 0 {
+		t.bytes = rate.NewLimiter(rate.Limit(cfg.BytesPerSecond), int(cfg.BytesPerSecond))
+	}
+	if cfg.MaxReplicationLag > 0 {
+		t.probes = append(t.probes, replicationLagProbe(cfg.MaxReplicationLag))
+	}
+	if cfg.MaxActiveConnections > 0 {
+		t.probes = append(t.probes, activeConnectionsProbe(cfg.MaxActiveConnections))
+	}
+	if cfg.ProbeQuery != "" {
+		t.probes = append(t.probes, customProbe(cfg.ProbeQuery, cfg.ProbeThreshold))
+	}
+	return t
+}
+
+// start checks the source load signals periodically in the background until
+// the throttler is closed, pausing the reads while any of them is above its
+// threshold.
+func (t *throttler) start(ctx context.Context) {
+	if t == nil || len(t.probes) == 0 {
+		return
+	}
+
+	ctx, t.cancel = context.WithCancel(ctx)
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(t.probeInterval)
+		defer ticker.Stop()
+		for {
+			t.checkLoad(ctx)
+			select {
+			case <-ctx.Done():
+				t.setPaused(false)
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// wait blocks until the row on input, of the size on input, can be read
+// without exceeding the configured limits.
+func (t *throttler) wait(ctx context.Context, rowBytes int64) error {
+	if t == nil {
+		return nil
+	}
+
+	t.mutex.Lock()
+	resume := t.resume
+	t.mutex.Unlock()
+	if resume != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-resume:
+		}
+	}
+
+	if t.rows != nil {
+		if err := t.rows.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if t.bytes != nil && rowBytes > 0 {
+		// rows bigger than the burst would never be allowed, so they consume
+		// the full burst instead
+		if err := t.bytes.WaitN(ctx, int(min(rowBytes, int64(t.bytes.Burst())))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *throttler) isPaused() bool {
+	if t == nil {
+		return false
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.resume != nil
+}
+
+func (t *throttler) close() {
+	if t == nil || t.cancel == nil {
+		return
+	}
+	t.cancel()
+	t.wg.Wait()
+}
+
+func (t *throttler) checkLoad(ctx context.Context) {
+	for _, probe := range t.probes {
+		reason, err := probe(ctx, t.conn)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				t.logger.Error(err, "checking source load")
+			}
+			// keep the current state if the load can't be checked
+			return
+		}
+		if reason != "" {
+			if !t.isPaused() {
+				t.logger.Warn(nil, "source load is high, pausing snapshot reads", loglib.Fields{"reason": reason})
+			}
+			t.setPaused(true)
+			return
+		}
+	}
+
+	if t.isPaused() {
+		t.logger.Info("source load is back to normal, resuming snapshot reads")
+	}
+	t.setPaused(false)
+}
+
+func (t *throttler) setPaused(paused bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	switch {
+	case paused && t.resume == nil:
+		t.resume = make(chan struct{})
+	case !paused && t.resume != nil:
+		close(t.resume)
+		t.resume = nil
+	}
+}
+
+func replicationLagProbe(maxLag time.Duration) loadProbe {
+	return func(ctx context.Context, conn pglib.Querier) (string, error) {
+		var lagSeconds float64
+		if err := conn.QueryRow(ctx, []any{&lagSeconds}, replicationLagQuery); err != nil {
+			return "", fmt.Errorf("getting standby replication lag: %w", err)
+		}
+		if lag := time.Duration(lagSeconds * float64(time.Second)); lag > maxLag {
+			return fmt.Sprintf("standby replication lag %s above %s", lag, maxLag), nil
+		}
+		return "", nil
+	}
+}
+
+func activeConnectionsProbe(maxConnections uint) loadProbe {
+	return func(ctx context.Context, conn pglib.Querier) (string, error) {
+		var active int64
+		if err := conn.QueryRow(ctx, []any{&active}, activeConnectionsQuery); err != nil {
+			return "", fmt.Errorf("getting active connections: %w", err)
+		}
+		if active > int64(maxConnections) {
+			return fmt.Sprintf("%d active connections above %d", active, maxConnections), nil
+		}
+		return "", nil
+	}
+}
+
+func customProbe(query string, threshold float64) loadProbe {
+	return func(ctx context.Context, conn pglib.Querier) (string, error) {
+		var value float64
+		if err := conn.QueryRow(ctx, []any{&value}, query); err != nil {
+			return "", fmt.Errorf("running load probe query: %w", err)
+		}
+		if value > threshold {
+			return fmt.Sprintf("load probe value %v above %v", value, threshold), nil
+		}
+		return "", nil
+	}
+}
@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	loglib "github.com/xataio/pgstream/pkg/log"
+)
+
+func TestThrottler_checkLoad(t *testing.T) {
+	t.Parallel()
+
+	const probeQuery = "SELECT load FROM stats"
+	errTest := errors.New("oh noes")
+
+	cfg := &ThrottleConfig{
+		MaxReplicationLag:    10 * time.Second,
+		MaxActiveConnections: 50,
+		ProbeQuery:           probeQuery,
+		ProbeThreshold:       0.8,
+	}
+
+	tests := []struct {
+		name              string
+		replicationLag    float64
+		activeConnections int64
+		probeValue        float64
+		queryErr          error
+		paused            bool
+
+		wantPaused bool
+	}{
+		{
+			name:              "ok - load below thresholds",
+			replicationLag:    1.5,
+			activeConnections: 10,
+			probeValue:        0.5,
+
+			wantPaused: false,
+		},
+		{
+			name:              "ok - load back below thresholds resumes reads",
+			replicationLag:    1.5,
+			activeConnections: 10,
+			probeValue:        0.5,
+			paused:            true,
+
+			wantPaused: false,
+		},
+		{
+			name:              "replication lag above threshold",
+			replicationLag:    12,
+			activeConnections: 10,
+			probeValue:        0.5,
+
+			wantPaused: true,
+		},
+		{
+			name:              "active connections above threshold",
+			replicationLag:    1.5,
+			activeConnections: 51,
+			probeValue:        0.5,
+
+			wantPaused: true,
+		},
+		{
+			name:              "probe value above threshold",
+			replicationLag:    1.5,
+			activeConnections: 10,
+			probeValue:        0.9,
+
+			wantPaused: true,
+		},
+		{
+			name:     "error checking load keeps reads paused",
+			queryErr: errTest,
+			paused:   true,
+
+			wantPaused: true,
+		},
+		{
+			name:     "error checking load keeps reads running",
+			queryErr: errTest,
+
+			wantPaused: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			querier := &pgmocks.Querier{
+				QueryRowFn: func(ctx context.Context, dest []any, query string, args ...any) error {
+					if tc.queryErr != nil {
+						return tc.queryErr
+					}
+					require.Len(t, dest, 1)
+					switch query {
+					case replicationLagQuery:
+						*(dest[0].(*float64)) = tc.replicationLag
+					case activeConnectionsQuery:
+						*(dest[0].(*int64)) = tc.activeConnections
+					case probeQuery:
+						*(dest[0].(*float64)) = tc.probeValue
+					default:
+						return fmt.Errorf("unexpected query: %s", query)
+					}
+					return nil
+				},
+			}
+
+			th := newThrottler(cfg, querier, loglib.NewNoopLogger())
+			th.setPaused(tc.paused)
+			resume := th.resume
+
+			th.checkLoad(context.Background())
+			require.Equal(t, tc.wantPaused, th.isPaused())
+			if tc.paused && !tc.wantPaused {
+				// the paused reads have been released
+				_, open := <-resume
+				require.False(t, open)
+			}
+		})
+	}
+}
+
+func TestThrottler_wait(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil throttler", func(t *testing.T) {
+		t.Parallel()
+
+		var th *throttler
+		require.NoError(t, th.wait(context.Background(), 100))
+		require.False(t, th.isPaused())
+		th.close()
+	})
+
+	t.Run("paused reads wait until resumed", func(t *testing.T) {
+		t.Parallel()
+
+		th := newThrottler(&ThrottleConfig{MaxActiveConnections: 10}, &pgmocks.Querier{}, loglib.NewNoopLogger())
+		th.setPaused(true)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, th.wait(ctx, 100), context.DeadlineExceeded)
+
+		errChan := make(chan error)
+		go func() {
+			errChan <- th.wait(context.Background(), 100)
+		}()
+		th.setPaused(false)
+		require.NoError(t, <-errChan)
+	})
+
+	t.Run("rows and bytes limits", func(t *testing.T) {
+		t.Parallel()
+
+		th := newThrottler(&ThrottleConfig{RowsPerSecond: 1000, BytesPerSecond: 1000}, &pgmocks.Querier{}, loglib.NewNoopLogger())
+		// rows bigger than the bytes burst consume the full burst instead of
+		// blocking forever
+		require.NoError(t, th.wait(context.Background(), 5000))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.Error(t, th.wait(ctx, 500))
+	})
+}
+
+func TestNewThrottler(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, newThrottler(nil, &pgmocks.Querier{}, loglib.NewNoopLogger()))
+	require.Nil(t, newThrottler(&ThrottleConfig{ProbeInterval: time.Second}, &pgmocks.Querier{}, loglib.NewNoopLogger()))
+
+	th := newThrottler(&ThrottleConfig{RowsPerSecond: 10, MaxReplicationLag: time.Second}, &pgmocks.Querier{}, loglib.NewNoopLogger())
+	require.NotNil(t, th.rows)
+	require.Nil(t, th.bytes)
+	require.Len(t, th.probes, 1)
+	require.Equal(t, defaultProbeInterval, th.probeInterval)
+}
@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package pgdumprestore
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// indexBuildPlan splits the indices and constraints dump into the phases of
+// the parallel index build:
+//   - indexes and non foreign key constraints, built in parallel per table
+//   - foreign keys, added NOT VALID so that the referencing rows are not
+//     checked while holding the lock on both tables
+//   - foreign key validations, run in parallel per table
+//   - any remaining statements, such as triggers, comments or replica
+//     identities, which depend on the objects created in the previous phases
+type indexBuildPlan struct {
+	connectBlocks []string
+	builds        *tableStatements
+	foreignKeys   []string
+	validations   *tableStatements
+	remaining     []string
+}
+
+// tableStatements groups statements per table, keeping the dump order. The
+// statements for the same table are run sequentially, which avoids lock waits
+// between them and allows the snapshot tracker to track a single index
+// creation per table.
+type tableStatements struct {
+	tables     []string
+	statements map[string][]string
+}
+
+const (
+	identPattern          = `(?:"(?:[^"]|"")*"|[^\s."(]+)`
+	qualifiedIdentPattern = identPattern + `(?:\.` + identPattern + `)?`
+)
+
+var (
+	createIndexRegex   = regexp.MustCompile(`^CREATE (?:UNIQUE )?INDEX ` + identPattern + ` ON (?:ONLY )?(` + qualifiedIdentPattern + `) `)
+	addConstraintRegex = regexp.MustCompile(`(?s)^ALTER TABLE (ONLY )?(` + qualifiedIdentPattern + `)\s+ADD CONSTRAINT (` + identPattern + `) (.*);$`)
+)
+
+func newIndexBuildPlan(dump []byte) *indexBuildPlan {
+	plan := &indexBuildPlan{
+		builds:      newTableStatements(),
+		validations: newTableStatements(),
+	}
+	for _, block := range strings.Split(string(dump), "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+
+		if strings.Contains(block, `\connect`) {
+			plan.connectBlocks = append(plan.connectBlocks, block)
+			continue
+		}
+
+		if matches := createIndexRegex.FindStringSubmatch(block); matches != nil {
+			plan.builds.add(matches[1], block)
+			continue
+		}
+
+		matches := addConstraintRegex.FindStringSubmatch(block)
+		switch {
+		case matches == nil:
+			plan.remaining = append(plan.remaining, block)
+		case !strings.HasPrefix(matches[4], "FOREIGN KEY"):
+			plan.builds.add(matches[2], block)
+		case matches[1] == "" || strings.HasSuffix(matches[4], "NOT VALID"):
+			// foreign keys on partitioned tables (no ONLY clause) can't be
+			// added NOT VALID, and the ones not valid on the source must not
+			// be validated.
+			plan.foreignKeys = append(plan.foreignKeys, block)
+		default:
+			table, constraint := matches[2], matches[3]
+			plan.foreignKeys = append(plan.foreignKeys, strings.TrimSuffix(block, ";")+" NOT VALID;")
+			plan.validations.add(table, fmt.Sprintf("ALTER TABLE ONLY %s VALIDATE CONSTRAINT %s;", table, constraint))
+		}
+	}
+	return plan
+}
+
+func newTableStatements() *tableStatements {
+	return &tableStatements{
+		statements: map[string][]string{},
+	}
+}
+
+func (t *tableStatements) add(table, stmt string) {
+	if _, found := t.statements[table]; !found {
+		t.tables = append(t.tables, table)
+	}
+	t.statements[table] = append(t.statements[table], stmt)
+}
+
+// buildIndicesAndConstraints restores the indices and constraints dump on
+// input following the parallel index build plan, using the configured number
+// of index build workers.
+func (s *SnapshotGenerator) buildIndicesAndConstraints(ctx context.Context, dump []byte) error {
+	plan := newIndexBuildPlan(dump)
+
+	s.logger.Info("building indices and constraints", loglib.Fields{"tables": len(plan.builds.tables), "workers": s.indexBuildWorkers})
+	if err := s.restoreTableStatements(ctx, plan.connectBlocks, plan.builds); err != nil {
+		return err
+	}
+
+	s.logger.Info("adding foreign keys", loglib.Fields{"foreign_keys": len(plan.foreignKeys)})
+	if err := s.restoreDump(ctx, joinDumpBlocks(plan.connectBlocks, plan.foreignKeys)); err != nil {
+		return err
+	}
+
+	s.logger.Info("validating foreign keys", loglib.Fields{"tables": len(plan.validations.tables), "workers": s.indexBuildWorkers})
+	if err := s.restoreTableStatements(ctx, plan.connectBlocks, plan.validations); err != nil {
+		return err
+	}
+
+	return s.restoreDump(ctx, joinDumpBlocks(plan.connectBlocks, plan.remaining))
+}
+
+func (s *SnapshotGenerator) restoreTableStatements(ctx context.Context, connectBlocks []string, ts *tableStatements) error {
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(int(s.indexBuildWorkers))
+	for _, table := range ts.tables {
+		stmts := ts.statements[table]
+		eg.Go(func() error {
+			if err := s.restoreDump(ctx, joinDumpBlocks(connectBlocks, stmts)); err != nil {
+				return fmt.Errorf("restoring indices and constraints for table %s: %w", table, err)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
@@ -0,0 +1,169 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package pgdumprestore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	"github.com/xataio/pgstream/pkg/log"
+)
+
+const indexBuildTestDump = `\connect test
+
+CREATE INDEX orders_status_idx ON public.orders USING btree (status);
+
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_pkey PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX "Customers_email_idx" ON ONLY public."Customers" USING btree (email);
+
+ALTER TABLE ONLY public."Customers"
+    ADD CONSTRAINT "Customers_pkey" PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT "orders customer fkey" FOREIGN KEY (customer_id) REFERENCES public."Customers"(id);
+
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_parent_fkey FOREIGN KEY (parent_id) REFERENCES public.orders(id) NOT VALID;
+
+ALTER TABLE public.events
+    ADD CONSTRAINT events_customer_fkey FOREIGN KEY (customer_id) REFERENCES public."Customers"(id);
+
+ALTER TABLE ONLY public.orders REPLICA IDENTITY USING INDEX orders_pkey;
+
+CREATE TRIGGER orders_audit AFTER INSERT ON public.orders FOR EACH ROW EXECUTE FUNCTION public.audit();
+
+`
+
+func TestNewIndexBuildPlan(t *testing.T) {
+	t.Parallel()
+
+	plan := newIndexBuildPlan([]byte(indexBuildTestDump))
+
+	require.Equal(t, &indexBuildPlan{
+		connectBlocks: []string{`\connect test`},
+		builds: &tableStatements{
+			tables: []string{"public.orders", `public."Customers"`},
+			statements: map[string][]string{
+				"public.orders": {
+					"CREATE INDEX orders_status_idx ON public.orders USING btree (status);",
+					"ALTER TABLE ONLY public.orders\n    ADD CONSTRAINT orders_pkey PRIMARY KEY (id);",
+				},
+				`public."Customers"`: {
+					`CREATE UNIQUE INDEX "Customers_email_idx" ON ONLY public."Customers" USING btree (email);`,
+					"ALTER TABLE ONLY public.\"Customers\"\n    ADD CONSTRAINT \"Customers_pkey\" PRIMARY KEY (id);",
+				},
+			},
+		},
+		foreignKeys: []string{
+			"ALTER TABLE ONLY public.orders\n    ADD CONSTRAINT \"orders customer fkey\" FOREIGN KEY (customer_id) REFERENCES public.\"Customers\"(id) NOT VALID;",
+			"ALTER TABLE ONLY public.orders\n    ADD CONSTRAINT orders_parent_fkey FOREIGN KEY (parent_id) REFERENCES public.orders(id) NOT VALID;",
+			"ALTER TABLE public.events\n    ADD CONSTRAINT events_customer_fkey FOREIGN KEY (customer_id) REFERENCES public.\"Customers\"(id);",
+		},
+		validations: &tableStatements{
+			tables: []string{"public.orders"},
+			statements: map[string][]string{
+				"public.orders": {`ALTER TABLE ONLY public.orders VALIDATE CONSTRAINT "orders customer fkey";`},
+			},
+		},
+		remaining: []string{
+			"ALTER TABLE ONLY public.orders REPLICA IDENTITY USING INDEX orders_pkey;",
+			"CREATE TRIGGER orders_audit AFTER INSERT ON public.orders FOR EACH ROW EXECUTE FUNCTION public.audit();",
+		},
+	}, plan)
+}
+
+func TestSnapshotGenerator_buildIndicesAndConstraints(t *testing.T) {
+	t.Parallel()
+
+	ordersBuild := "\\connect test\n\nCREATE INDEX orders_status_idx ON public.orders USING btree (status);\n\nALTER TABLE ONLY public.orders\n    ADD CONSTRAINT orders_pkey PRIMARY KEY (id);\n\n"
+	customersBuild := "\\connect test\n\nCREATE UNIQUE INDEX \"Customers_email_idx\" ON ONLY public.\"Customers\" USING btree (email);\n\nALTER TABLE ONLY public.\"Customers\"\n    ADD CONSTRAINT \"Customers_pkey\" PRIMARY KEY (id);\n\n"
+	foreignKeys := "\\connect test\n\nALTER TABLE ONLY public.orders\n    ADD CONSTRAINT \"orders customer fkey\" FOREIGN KEY (customer_id) REFERENCES public.\"Customers\"(id) NOT VALID;\n\nALTER TABLE ONLY public.orders\n    ADD CONSTRAINT orders_parent_fkey FOREIGN KEY (parent_id) REFERENCES public.orders(id) NOT VALID;\n\nALTER TABLE public.events\n    ADD CONSTRAINT events_customer_fkey FOREIGN KEY (customer_id) REFERENCES public.\"Customers\"(id);\n\n"
+	validations := "\\connect test\n\nALTER TABLE ONLY public.orders VALIDATE CONSTRAINT \"orders customer fkey\";\n\n"
+	remaining := "\\connect test\n\nALTER TABLE ONLY public.orders REPLICA IDENTITY USING INDEX orders_pkey;\n\nCREATE TRIGGER orders_audit AFTER INSERT ON public.orders FOR EACH ROW EXECUTE FUNCTION public.audit();\n\n"
+
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name       string
+		restoreErr map[string]error
+
+		wantPhases [][]string
+		wantErr    error
+	}{
+		{
+			name: "ok",
+
+			wantPhases: [][]string{
+				{ordersBuild, customersBuild},
+				{foreignKeys},
+				{validations},
+				{remaining},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "error building table indices",
+			restoreErr: map[string]error{
+				customersBuild: errTest,
+			},
+
+			wantPhases: [][]string{
+				{ordersBuild, customersBuild},
+			},
+			wantErr: errTest,
+		},
+		{
+			name: "ignored restore errors",
+			restoreErr: map[string]error{
+				validations: &pglib.PGRestoreErrors{},
+			},
+
+			wantPhases: [][]string{
+				{ordersBuild, customersBuild},
+				{foreignKeys},
+				{validations},
+				{remaining},
+			},
+			wantErr: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mu := sync.Mutex{}
+			restored := []string{}
+			sg := SnapshotGenerator{
+				logger:            log.NewNoopLogger(),
+				optionGenerator:   &optionGenerator{targetURL: "target-url"},
+				indexBuildWorkers: 2,
+				pgRestoreFn: func(_ context.Context, _ pglib.PGRestoreOptions, dump []byte) (string, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					restored = append(restored, string(dump))
+					return "", tc.restoreErr[string(dump)]
+				},
+			}
+
+			err := sg.buildIndicesAndConstraints(context.Background(), []byte(indexBuildTestDump))
+			require.ErrorIs(t, err, tc.wantErr)
+
+			// the statements within a phase are restored in parallel, so
+			// their order is not deterministic
+			for _, phase := range tc.wantPhases {
+				require.GreaterOrEqual(t, len(restored), len(phase), strings.Join(restored, "\n---\n"))
+				require.ElementsMatch(t, phase, restored[:len(phase)])
+				restored = restored[len(phase):]
+			}
+			require.Empty(t, restored)
+		})
+	}
+}
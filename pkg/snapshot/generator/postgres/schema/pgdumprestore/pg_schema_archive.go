@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package pgdumprestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	"github.com/xataio/pgstream/pkg/snapshot/archive"
+)
+
+// schemaArchiveWriter writes the dumps to the schema files of a snapshot
+// archive instead of restoring them into a postgres target. The dumps restored
+// before the data snapshot are appended to the pre data schema file, and the
+// ones restored after it to the post data schema file, so that they can be
+// loaded in the same order.
+type schemaArchiveWriter struct {
+	dir string
+
+	mu      sync.Mutex
+	phase   archive.SchemaPhase
+	written map[archive.SchemaPhase]bool
+}
+
+func newSchemaArchiveWriter(dir string) *schemaArchiveWriter {
+	return &schemaArchiveWriter{
+		dir:     dir,
+		phase:   archive.PreDataPhase,
+		written: map[archive.SchemaPhase]bool{},
+	}
+}
+
+func (w *schemaArchiveWriter) restore(_ context.Context, _ pglib.PGRestoreOptions, dump []byte) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	path := filepath.Join(w.dir, filepath.FromSlash(archive.SchemaFilePath(w.phase)))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("creating schema archive directory: %w", err)
+	}
+
+	// the file is truncated on the first write, in case there's a leftover
+	// one from a previous snapshot that didn't complete
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !w.written[w.phase] {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("opening schema archive file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(dump); err != nil {
+		return "", fmt.Errorf("writing schema archive file: %w", err)
+	}
+	w.written[w.phase] = true
+	return "", f.Close()
+}
+
+// dataRestored switches the writer to the post data schema file.
+func (w *schemaArchiveWriter) dataRestored() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.phase = archive.PostDataPhase
+}
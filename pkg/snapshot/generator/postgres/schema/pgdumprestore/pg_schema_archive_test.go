@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package pgdumprestore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	"github.com/xataio/pgstream/pkg/snapshot/archive"
+)
+
+func TestSchemaArchiveWriter_restore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	// leftover files from a previous snapshot are overwritten
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "schema"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, archive.PreDataSchemaFile), []byte("leftover\n"), 0o644))
+
+	w := newSchemaArchiveWriter(dir)
+	ctx := context.Background()
+	restore := func(dump string) {
+		_, err := w.restore(ctx, pglib.PGRestoreOptions{}, []byte(dump))
+		require.NoError(t, err)
+	}
+
+	restore("CREATE SCHEMA test;\n\n")
+	restore("CREATE TABLE test.users(id int);\n\n")
+	w.dataRestored()
+	restore("ALTER TABLE ONLY test.users ADD CONSTRAINT users_pkey PRIMARY KEY (id);\n\n")
+
+	preData, err := os.ReadFile(filepath.Join(dir, archive.PreDataSchemaFile))
+	require.NoError(t, err)
+	require.Equal(t, "CREATE SCHEMA test;\n\nCREATE TABLE test.users(id int);\n\n", string(preData))
+
+	postData, err := os.ReadFile(filepath.Join(dir, archive.PostDataSchemaFile))
+	require.NoError(t, err)
+	require.Equal(t, "ALTER TABLE ONLY test.users ADD CONSTRAINT users_pkey PRIMARY KEY (id);\n\n", string(postData))
+}
@@ -39,11 +39,19 @@ type SnapshotGenerator struct {
 	roleSQLParser          *roleSQLParser
 	optionGenerator        *optionGenerator
 	snapshotTracker        snapshotProgressTracker
+	tableRouter            *pglib.TableRouter
 	// restoreConflictTargetsBeforeData restores constraints/indexes that can
 	// be used as INSERT ... ON CONFLICT targets before the wrapped data snapshot
 	// generator runs. Other indexes and constraints, such as foreign keys, are
 	// still restored after data is inserted.
 	restoreConflictTargetsBeforeData bool
+	// indexBuildWorkers is the number of workers building the indices and
+	// constraints in parallel once the data has been restored. If 0, they're
+	// restored sequentially.
+	indexBuildWorkers uint
+	// schemaArchive is set when the schema is written to a snapshot archive
+	// directory instead of being restored into a postgres target.
+	schemaArchive *schemaArchiveWriter
 }
 
 type snapshotProgressTracker interface {
@@ -72,6 +80,22 @@ type Config struct {
 	DumpDebugFile string
 	// if set, security label providers that will be excluded from the dump
 	ExcludedSecurityLabels []string
+	// TableRoutes maps source table patterns to target table names. The
+	// restored schema objects will be renamed accordingly. Wildcards "*" and
+	// the {schema} and {table} placeholders are supported.
+	TableRoutes map[string]string
+	// if set to true, the schema dump and restore will be produced from the
+	// system catalog instead of using the pg_dump, pg_dumpall and pg_restore
+	// binaries.
+	NativeDump bool
+	// if set, the indices and constraints restored after the data will be
+	// built in parallel per table by this number of workers, adding the
+	// foreign keys NOT VALID and validating them afterwards.
+	IndexBuildWorkers uint
+	// if set, the schema will be written to the schema files of the snapshot
+	// archive in this directory instead of being restored into the target
+	// postgres database.
+	TargetDir string
 }
 
 type Option func(s *SnapshotGenerator)
@@ -95,6 +119,11 @@ const (
 // NewSnapshotGenerator will return a postgres schema snapshot generator that
 // uses pg_dump and pg_restore to sync the schema of two postgres databases
 func NewSnapshotGenerator(ctx context.Context, c *Config, opts ...Option) (*SnapshotGenerator, error) {
+	tableRouter, err := pglib.NewTableRouter(pglib.TableRoutesFromMap(c.TableRoutes))
+	if err != nil {
+		return nil, err
+	}
+
 	sourceConnPool, err := pglib.NewConnPool(ctx, c.SourcePGURL)
 	if err != nil {
 		return nil, err
@@ -112,6 +141,21 @@ func NewSnapshotGenerator(ctx context.Context, c *Config, opts ...Option) (*Snap
 		roleSQLParser:          &roleSQLParser{},
 		sourceQuerier:          sourceConnPool,
 		optionGenerator:        newOptionGenerator(sourceConnPool, c),
+		tableRouter:            tableRouter,
+		indexBuildWorkers:      c.IndexBuildWorkers,
+	}
+
+	if c.NativeDump {
+		sg.pgDumpFn = pglib.RunNativePGDump
+		sg.pgDumpAllFn = pglib.RunNativePGDumpAll
+		sg.pgRestoreFn = pglib.RunNativePGRestore
+	}
+
+	if c.TargetDir != "" {
+		sg.schemaArchive = newSchemaArchiveWriter(c.TargetDir)
+		sg.pgRestoreFn = sg.schemaArchive.restore
+		// the dumps are appended to the archive files in order
+		sg.indexBuildWorkers = 0
 	}
 
 	for _, opt := range opts {
@@ -164,6 +208,8 @@ func WithProgressTracking(ctx context.Context) Option {
 func WithRestoreToWAL(processor processor.Processor) Option {
 	return func(sg *SnapshotGenerator) {
 		sg.pgRestoreFn = newPGSnapshotWALRestore(processor, sg.sourceQuerier).restoreToWAL
+		// the DDL events are processed in order by the WAL processor
+		sg.indexBuildWorkers = 0
 	}
 }
 
@@ -261,6 +307,10 @@ func (s *SnapshotGenerator) CreateSnapshot(ctx context.Context, ss *snapshot.Sna
 		}
 	}
 
+	if s.schemaArchive != nil {
+		s.schemaArchive.dataRestored()
+	}
+
 	// apply the sequences, indices and constraints when the wrapped generator has finished
 	s.logger.Info("restoring sequence data", loglib.Fields{"schemaTables": ss.SchemaTables})
 	if err := s.restoreDump(ctx, sequenceDump); err != nil {
@@ -326,10 +376,14 @@ func isConflictTargetConstraint(block string) bool {
 
 func (s *SnapshotGenerator) restoreIndicesAndConstraints(ctx context.Context, dump []byte, ss *snapshot.Snapshot) error {
 	s.logger.Info("restoring schema indices and constraints", loglib.Fields{"schemaTables": ss.SchemaTables})
+	restore := s.restoreDump
+	if s.indexBuildWorkers > 0 {
+		restore = s.buildIndicesAndConstraints
+	}
 	if s.snapshotTracker != nil {
-		return s.restoreIndicesWithTracking(ctx, dump)
+		return s.restoreIndicesWithTracking(ctx, dump, restore)
 	}
-	return s.restoreDump(ctx, dump)
+	return restore(ctx, dump)
 }
 
 func (s *SnapshotGenerator) Close() error {
@@ -453,19 +507,50 @@ func (s *SnapshotGenerator) dumpRoles(ctx context.Context, rolesInSchemaDump map
 
 // if we use table filtering in the pg_dump command, the schema creation will
 // not be dumped, so it needs to be created explicitly (except for public
-// schema)
+// schema). When table routes are configured, the target schemas are created
+// instead.
 func (s *SnapshotGenerator) restoreSchemas(ctx context.Context, schemaTables map[string][]string) error {
-	schemaDump := strings.Builder{}
+	targetSchemas := map[string]struct{}{}
 	for schema, tables := range schemaTables {
-		if len(tables) > 0 && schema != publicSchema && schema != wildcard {
-			fmt.Fprintf(&schemaDump, "CREATE SCHEMA IF NOT EXISTS %s;\n", pglib.QuoteIdentifier(schema))
+		if schema == wildcard {
+			continue
+		}
+		for _, table := range tables {
+			targetSchema := s.tableRouter.RouteSchema(schema)
+			if table != wildcard {
+				targetSchema, _ = s.tableRouter.Route(schema, table)
+			}
+			targetSchemas[targetSchema] = struct{}{}
+		}
+	}
+
+	schemas := make([]string, 0, len(targetSchemas))
+	for schema := range targetSchemas {
+		if schema != publicSchema {
+			schemas = append(schemas, schema)
 		}
 	}
+	slices.Sort(schemas)
 
-	return s.restoreDump(ctx, []byte(schemaDump.String()))
+	schemaDump := strings.Builder{}
+	for _, schema := range schemas {
+		fmt.Fprintf(&schemaDump, "CREATE SCHEMA IF NOT EXISTS %s;\n", pglib.QuoteIdentifier(schema))
+	}
+
+	// the schema names are already routed
+	return s.restoreRoutedDump(ctx, []byte(schemaDump.String()))
 }
 
+// restoreDump restores the dump on input, renaming the objects as per the
+// configured table routes.
 func (s *SnapshotGenerator) restoreDump(ctx context.Context, dump []byte) error {
+	if s.tableRouter != nil && len(dump) > 0 {
+		dump = []byte(s.tableRouter.RewriteSQL(string(dump)))
+	}
+	return s.restoreRoutedDump(ctx, dump)
+}
+
+func (s *SnapshotGenerator) restoreRoutedDump(ctx context.Context, dump []byte) error {
 	if len(dump) == 0 {
 		return nil
 	}
@@ -753,7 +838,7 @@ func (s *SnapshotGenerator) getDumpFileName(suffix string) string {
 	return baseName + suffix + fileExtension
 }
 
-func (s *SnapshotGenerator) restoreIndicesWithTracking(ctx context.Context, dump []byte) error {
+func (s *SnapshotGenerator) restoreIndicesWithTracking(ctx context.Context, dump []byte, restore func(context.Context, []byte) error) error {
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 	trackingCtx, cancel := context.WithCancel(ctx)
@@ -762,7 +847,7 @@ func (s *SnapshotGenerator) restoreIndicesWithTracking(ctx context.Context, dump
 		defer wg.Done()
 		s.snapshotTracker.trackIndexesCreation(trackingCtx)
 	}()
-	err := s.restoreDump(ctx, dump)
+	err := restore(ctx, dump)
 	// wait for the tracking to finish once the restore is done
 	cancel()
 	wg.Wait()
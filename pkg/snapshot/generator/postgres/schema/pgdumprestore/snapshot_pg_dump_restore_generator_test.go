@@ -1374,6 +1374,70 @@ CREATE INDEX test_table_value_idx ON public.test_table USING btree (value);
 	require.Equal(t, []string{"schema", "conflict targets", "data", "remaining constraints"}, calls)
 }
 
+func TestSnapshotGenerator_CreateSnapshot_tableRouting(t *testing.T) {
+	t.Parallel()
+
+	schemaDump := []byte(`CREATE TABLE public.test_table (
+    id integer DEFAULT nextval('public.test_table_id_seq'::regclass) NOT NULL
+);
+
+CREATE INDEX test_table_id_idx ON public.test_table USING btree (id);
+`)
+
+	conn := &mocks.Querier{
+		QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+			return &mocks.Rows{
+				CloseFn: func() {},
+				NextFn:  func(i uint) bool { return false },
+				ErrFn:   func() error { return nil },
+			}, nil
+		},
+	}
+
+	tableRouter, err := pglib.NewTableRouter([]pglib.TableRoute{
+		{Source: "public.*", Target: "replica_public.*"},
+	})
+	require.NoError(t, err)
+
+	restoredDumps := []string{}
+	sg := SnapshotGenerator{
+		sourceURL:     "source-url",
+		targetURL:     "target-url",
+		sourceQuerier: conn,
+		pgDumpFn: newMockPgdump(func(_ context.Context, i uint, po pglib.PGDumpOptions) ([]byte, error) {
+			return schemaDump, nil
+		}),
+		pgRestoreFn: newMockPgrestore(func(_ context.Context, i uint, po pglib.PGRestoreOptions, dump []byte) (string, error) {
+			restoredDumps = append(restoredDumps, strings.TrimSpace(string(dump)))
+			return "", nil
+		}),
+		logger:        log.NewNoopLogger(),
+		roleSQLParser: &roleSQLParser{},
+		optionGenerator: &optionGenerator{
+			sourceURL:         "source-url",
+			targetURL:         "target-url",
+			noOwner:           true,
+			rolesSnapshotMode: roleSnapshotDisabled,
+			querier:           conn,
+		},
+		tableRouter: tableRouter,
+	}
+
+	err = sg.CreateSnapshot(context.Background(), &snapshot.Snapshot{
+		SchemaTables: map[string][]string{
+			publicSchema: {"test_table"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`CREATE SCHEMA IF NOT EXISTS "replica_public";`,
+		`CREATE TABLE "replica_public"."test_table" (
+    id integer DEFAULT nextval('"replica_public"."test_table_id_seq"'::regclass) NOT NULL
+);`,
+		`CREATE INDEX test_table_id_idx ON "replica_public"."test_table" USING btree (id);`,
+	}, restoredDumps)
+}
+
 func TestSnapshotGenerator_parseDump(t *testing.T) {
 	t.Parallel()
 
@@ -22,6 +22,10 @@ type SnapshotSchemaTableFinder struct {
 	conn              pglib.Querier
 	tableDiscoveryFn  tableDiscoveryFn
 	schemaDiscoveryFn schemaDiscoveryFn
+	// partition discovery functions, only set when the partitions are
+	// published via their root table
+	partitionRootFn   partitionRootFn
+	partitionLeavesFn partitionLeavesFn
 }
 
 type Option func(*SnapshotSchemaTableFinder)
@@ -30,6 +34,10 @@ type schemaDiscoveryFn func(ctx context.Context, conn pglib.Querier) ([]string,
 
 type tableDiscoveryFn func(ctx context.Context, conn pglib.Querier, schema string) ([]string, error)
 
+type partitionRootFn func(ctx context.Context, conn pglib.Querier, schema, table string) (*pglib.QualifiedName, error)
+
+type partitionLeavesFn func(ctx context.Context, conn pglib.Querier, schema, table string) ([]*pglib.QualifiedName, error)
+
 const wildcard = "*"
 
 // NewSnapshotSchemaTableFinder will return the generator on input wrapped with a
@@ -68,6 +76,17 @@ func WithInstrumentation(i *otel.Instrumentation) Option {
 	}
 }
 
+// WithPartitionsViaRoot makes the finder resolve the partitioned tables in the
+// snapshot request to their leaf partitions, which hold the data. Partitions
+// are skipped when their root table is excluded or already requested, so that
+// the rows are snapshotted once and can be mapped back to the root table.
+func WithPartitionsViaRoot() Option {
+	return func(stf *SnapshotSchemaTableFinder) {
+		stf.partitionRootFn = pglib.GetPartitionRoot
+		stf.partitionLeavesFn = pglib.DiscoverPartitionLeaves
+	}
+}
+
 func (s *SnapshotSchemaTableFinder) CreateSnapshot(ctx context.Context, ss *snapshot.Snapshot) error {
 	tablenames, wildcardSchemaFound := ss.SchemaTables[wildcard]
 	if wildcardSchemaFound {
@@ -95,6 +114,12 @@ func (s *SnapshotSchemaTableFinder) CreateSnapshot(ctx context.Context, ss *snap
 		}
 	}
 
+	if s.partitionLeavesFn != nil {
+		if err := s.resolvePartitions(ctx, ss); err != nil {
+			return err
+		}
+	}
+
 	if len(ss.SchemaExcludedTables) == 0 {
 		// No excluded tables, return early
 		return s.wrapped.CreateSnapshot(ctx, ss)
@@ -122,6 +147,48 @@ func (s *SnapshotSchemaTableFinder) CreateSnapshot(ctx context.Context, ss *snap
 	return s.wrapped.CreateSnapshot(ctx, ss)
 }
 
+// resolvePartitions replaces the partitioned tables of the snapshot request by
+// their leaf partitions. Partitions of excluded root tables are removed, as
+// well as duplicates of partitions already requested via their root table.
+func (s *SnapshotSchemaTableFinder) resolvePartitions(ctx context.Context, ss *snapshot.Snapshot) error {
+	schemaTables := map[string][]string{}
+	add := func(schema, table string) {
+		if !slices.Contains(schemaTables[schema], table) {
+			schemaTables[schema] = append(schemaTables[schema], table)
+		}
+	}
+
+	for schema, tables := range ss.SchemaTables {
+		for _, table := range tables {
+			root, err := s.partitionRootFn(ctx, s.conn, schema, table)
+			if err != nil {
+				return err
+			}
+			if root != nil && slices.Contains(ss.SchemaExcludedTables[root.Schema()], root.Name()) {
+				continue
+			}
+
+			leaves, err := s.partitionLeavesFn(ctx, s.conn, schema, table)
+			if err != nil {
+				return err
+			}
+			if len(leaves) == 0 {
+				add(schema, table)
+				continue
+			}
+			for _, leaf := range leaves {
+				add(leaf.Schema(), leaf.Name())
+			}
+		}
+	}
+
+	for _, tables := range schemaTables {
+		slices.Sort(tables)
+	}
+	ss.SchemaTables = schemaTables
+	return nil
+}
+
 func (s *SnapshotSchemaTableFinder) Close() error {
 	return s.conn.Close(context.Background())
 }
@@ -347,3 +347,119 @@ func TestSnapshotTableFinder_CreateSnapshot(t *testing.T) {
 		})
 	}
 }
+
+func TestSnapshotTableFinder_CreateSnapshot_partitionsViaRoot(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("oh noes")
+
+	newQualifiedName := func(name string) *pglib.QualifiedName {
+		qn, err := pglib.NewQualifiedName(name)
+		require.NoError(t, err)
+		return qn
+	}
+
+	// public.orders is partitioned into public.orders_2024 and
+	// archive.orders_2023, public.events into public.events_1
+	testPartitionRootFn := func(ctx context.Context, conn pglib.Querier, schema, table string) (*pglib.QualifiedName, error) {
+		switch schema + "." + table {
+		case "public.orders_2024", "archive.orders_2023":
+			return newQualifiedName("public.orders"), nil
+		case "public.events_1":
+			return newQualifiedName("public.events"), nil
+		default:
+			return nil, nil
+		}
+	}
+	testPartitionLeavesFn := func(ctx context.Context, conn pglib.Querier, schema, table string) ([]*pglib.QualifiedName, error) {
+		switch schema + "." + table {
+		case "public.orders":
+			return []*pglib.QualifiedName{newQualifiedName("archive.orders_2023"), newQualifiedName("public.orders_2024")}, nil
+		case "public.events":
+			return []*pglib.QualifiedName{newQualifiedName("public.events_1")}, nil
+		default:
+			return []*pglib.QualifiedName{}, nil
+		}
+	}
+
+	tests := []struct {
+		name              string
+		snapshot          *snapshot.Snapshot
+		partitionLeavesFn partitionLeavesFn
+
+		wantSnapshot *snapshot.Snapshot
+		wantErr      error
+	}{
+		{
+			name: "ok - root tables resolved to partitions",
+			snapshot: &snapshot.Snapshot{
+				SchemaTables: map[string][]string{
+					"public": {"users", "orders", "orders_2024"},
+				},
+			},
+			partitionLeavesFn: testPartitionLeavesFn,
+
+			wantSnapshot: &snapshot.Snapshot{
+				SchemaTables: map[string][]string{
+					"public":  {"orders_2024", "users"},
+					"archive": {"orders_2023"},
+				},
+			},
+		},
+		{
+			name: "ok - partitions of excluded root tables removed",
+			snapshot: &snapshot.Snapshot{
+				SchemaTables: map[string][]string{
+					"public": {"users", "orders", "events_1"},
+				},
+				SchemaExcludedTables: map[string][]string{
+					"public": {"events"},
+				},
+			},
+			partitionLeavesFn: testPartitionLeavesFn,
+
+			wantSnapshot: &snapshot.Snapshot{
+				SchemaTables: map[string][]string{
+					"public":  {"orders_2024", "users"},
+					"archive": {"orders_2023"},
+				},
+				SchemaExcludedTables: map[string][]string{
+					"public": {"events"},
+				},
+			},
+		},
+		{
+			name: "error - discovering partitions",
+			snapshot: &snapshot.Snapshot{
+				SchemaTables: map[string][]string{
+					"public": {"orders"},
+				},
+			},
+			partitionLeavesFn: func(ctx context.Context, conn pglib.Querier, schema, table string) ([]*pglib.QualifiedName, error) {
+				return nil, errTest
+			},
+
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tableFinder := SnapshotSchemaTableFinder{
+				conn: &pgmocks.Querier{},
+				wrapped: &mocks.Generator{
+					CreateSnapshotFn: func(ctx context.Context, snapshot *snapshot.Snapshot) error {
+						require.Equal(t, tc.wantSnapshot, snapshot)
+						return nil
+					},
+				},
+				partitionRootFn:   testPartitionRootFn,
+				partitionLeavesFn: tc.partitionLeavesFn,
+			}
+			err := tableFinder.CreateSnapshot(context.Background(), tc.snapshot)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
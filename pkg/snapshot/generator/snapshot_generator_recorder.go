@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/xataio/pgstream/pkg/snapshot"
@@ -19,12 +20,16 @@ type SnapshotRecorder struct {
 	wrapped             SnapshotGenerator
 	store               snapshotstore.Store
 	repeatableSnapshots bool
+	retryFailed         bool
 	schemaWorkers       uint
 }
 
 type Config struct {
 	RepeatableSnapshots bool
-	SnapshotWorkers     uint
+	// RetryFailed limits the snapshot to the tables whose latest snapshot
+	// request failed, regardless of the repeatable snapshots setting.
+	RetryFailed     bool
+	SnapshotWorkers uint
 }
 
 const (
@@ -40,6 +45,7 @@ func NewSnapshotRecorder(cfg *Config, store snapshotstore.Store, generator Snaps
 		wrapped:             generator,
 		store:               store,
 		repeatableSnapshots: cfg.RepeatableSnapshots,
+		retryFailed:         cfg.RetryFailed,
 		schemaWorkers:       cfg.snapshotWorkers(),
 	}
 }
@@ -148,7 +154,7 @@ func (s *SnapshotRecorder) markSnapshotCompleted(ctx context.Context, requests [
 
 func (s *SnapshotRecorder) filterOutExistingSnapshots(ctx context.Context, schemaTables map[string][]string) (map[string][]string, error) {
 	// if we want to be able to repeat snapshots, we don't filter out existing ones
-	if s.repeatableSnapshots {
+	if s.repeatableSnapshots && !s.retryFailed {
 		return schemaTables, nil
 	}
 
@@ -171,6 +177,10 @@ func (s *SnapshotRecorder) filterOutExistingSchemaTables(ctx context.Context, sc
 		return nil, fmt.Errorf("retrieving existing snapshots for schema: %w", err)
 	}
 
+	if s.retryFailed {
+		return filterFailedTables(tables, snapshotRequests), nil
+	}
+
 	if len(snapshotRequests) == 0 {
 		return tables, nil
 	}
@@ -206,6 +216,52 @@ func (s *SnapshotRecorder) filterOutExistingSchemaTables(ctx context.Context, sc
 	return filteredTables, nil
 }
 
+// filterFailedTables returns the tables on input whose latest snapshot request
+// failed. The snapshot requests are expected to be sorted by creation.
+func filterFailedTables(tables []string, snapshotRequests []*snapshot.Request) []string {
+	const wildcard = "*"
+	failed := map[string]bool{}
+	for _, req := range snapshotRequests {
+		if req.Status != snapshot.StatusCompleted {
+			continue
+		}
+		for _, table := range req.Tables {
+			switch {
+			case table != wildcard:
+				failed[table] = req.HasFailedForTable(table)
+			case req.Errors.IsGlobalError():
+				failed[wildcard] = true
+			default:
+				// a wildcard request covers all the tables of the schema
+				clear(failed)
+				for _, t := range req.Errors.GetFailedTables() {
+					failed[t] = true
+				}
+			}
+		}
+	}
+
+	filteredTables := []string{}
+	for _, table := range tables {
+		if table != wildcard {
+			if failed[table] || failed[wildcard] {
+				filteredTables = append(filteredTables, table)
+			}
+			continue
+		}
+		if failed[wildcard] {
+			return []string{wildcard}
+		}
+		for t, tableFailed := range failed {
+			if tableFailed {
+				filteredTables = append(filteredTables, t)
+			}
+		}
+	}
+	slices.Sort(filteredTables)
+	return slices.Compact(filteredTables)
+}
+
 func (c *Config) snapshotWorkers() uint {
 	if c.SnapshotWorkers <= 0 {
 		return defaultSnapshotWorkers
@@ -410,6 +410,7 @@ func TestSnapshotRecorder_filterOutExistingSnapshots(t *testing.T) {
 		store               snapshotstore.Store
 		tables              []string
 		repeatableSnapshots bool
+		retryFailed         bool
 
 		wantTables map[string][]string
 		wantErr    error
@@ -568,6 +569,92 @@ func TestSnapshotRecorder_filterOutExistingSnapshots(t *testing.T) {
 				testSchema: {"table2"},
 			},
 		},
+		{
+			name: "ok - retry failed tables with repeatable snapshots",
+			store: &snapshotstoremocks.Store{
+				GetSnapshotRequestsBySchemaFn: func(ctx context.Context, s string) ([]*snapshot.Request, error) {
+					return []*snapshot.Request{
+						{
+							Schema: testSchema,
+							Tables: testTables,
+							Errors: &snapshot.SchemaErrors{
+								Schema:      testSchema,
+								TableErrors: map[string]string{"table2": errTest.Error()},
+							},
+							Status: snapshot.StatusCompleted,
+						},
+					}, nil
+				},
+			},
+			repeatableSnapshots: true,
+			retryFailed:         true,
+			wantTables: map[string][]string{
+				testSchema: {"table2"},
+			},
+		},
+		{
+			name: "ok - retry failed tables with wildcard",
+			store: &snapshotstoremocks.Store{
+				GetSnapshotRequestsBySchemaFn: func(ctx context.Context, s string) ([]*snapshot.Request, error) {
+					return []*snapshot.Request{
+						{
+							Schema: testSchema,
+							Tables: []string{"*"},
+							Errors: &snapshot.SchemaErrors{
+								Schema:      testSchema,
+								TableErrors: map[string]string{"table1": errTest.Error(), "table2": errTest.Error()},
+							},
+							Status: snapshot.StatusCompleted,
+						},
+						{
+							Schema: testSchema,
+							Tables: []string{"table2"},
+							Status: snapshot.StatusCompleted,
+						},
+						{
+							Schema: testSchema,
+							Tables: []string{"table3"},
+							Status: snapshot.StatusInProgress,
+						},
+					}, nil
+				},
+			},
+			tables:      []string{"*"},
+			retryFailed: true,
+			wantTables: map[string][]string{
+				testSchema: {"table1"},
+			},
+		},
+		{
+			name: "ok - retry failed wildcard snapshot",
+			store: &snapshotstoremocks.Store{
+				GetSnapshotRequestsBySchemaFn: func(ctx context.Context, s string) ([]*snapshot.Request, error) {
+					return []*snapshot.Request{
+						{
+							Schema: testSchema,
+							Tables: []string{"*"},
+							Errors: snapshot.NewSchemaErrors(testSchema, errTest),
+							Status: snapshot.StatusCompleted,
+						},
+					}, nil
+				},
+			},
+			tables:      []string{"*"},
+			retryFailed: true,
+			wantTables: map[string][]string{
+				testSchema: {"*"},
+			},
+		},
+		{
+			name: "ok - retry failed without failed tables",
+			store: &snapshotstoremocks.Store{
+				GetSnapshotRequestsBySchemaFn: func(ctx context.Context, s string) ([]*snapshot.Request, error) {
+					return []*snapshot.Request{}, nil
+				},
+			},
+			retryFailed: true,
+			wantTables:  map[string][]string{},
+		},
 		{
 			name: "error - retrieving existing snapshots",
 			store: &snapshotstoremocks.Store{
@@ -587,6 +674,7 @@ func TestSnapshotRecorder_filterOutExistingSnapshots(t *testing.T) {
 			sr := SnapshotRecorder{
 				store:               tc.store,
 				repeatableSnapshots: tc.repeatableSnapshots,
+				retryFailed:         tc.retryFailed,
 			}
 
 			tables := testTables
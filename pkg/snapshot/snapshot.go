@@ -2,16 +2,58 @@
 
 package snapshot
 
+import "time"
+
 type Snapshot struct {
 	SchemaTables         map[string][]string
 	SchemaExcludedTables map[string][]string
+	// LSN is the position replication is started from once the snapshot is
+	// completed, if any. Resumed data snapshots lower it to the position
+	// stored with their table checkpoints, so that the changes made to the
+	// ranges snapshotted by the previous runs are replayed.
+	LSN string
 }
 
 type Request struct {
-	Schema string
-	Tables []string
-	Status Status
-	Errors *SchemaErrors
+	ID        int64
+	Schema    string
+	Tables    []string
+	Status    Status
+	Errors    *SchemaErrors
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableStats keeps the row and byte counts of the latest snapshot of a table,
+// along with its timings.
+type TableStats struct {
+	Schema     string
+	Table      string
+	Rows       int64
+	Bytes      int64
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// UpdatedAt is the time the stats were recorded, as set by the store.
+	UpdatedAt time.Time
+}
+
+// TableCheckpoint keeps track of the page ranges of a table that have already
+// been snapshotted, so that an interrupted snapshot can be resumed with only
+// the missing ranges.
+type TableCheckpoint struct {
+	Schema          string
+	Table           string
+	CompletedRanges []PageRange
+	// LSN is the replication start position of the snapshot run that
+	// completed the first ranges of the table, if any.
+	LSN string
+}
+
+// PageRange is a range of table pages, from start (inclusive) to end
+// (exclusive).
+type PageRange struct {
+	Start uint `json:"start"`
+	End   uint `json:"end"`
 }
 
 type Status string
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// StatusFailed is only used to report the tables of completed snapshot
+// requests that failed. It's never stored.
+const StatusFailed = Status("failed")
+
+// TableStatus is the status of the snapshot of a table for a given request,
+// along with the row and byte counts when available.
+type TableStatus struct {
+	RequestID   int64      `json:"request_id"`
+	Schema      string     `json:"schema"`
+	Table       string     `json:"table"`
+	Status      Status     `json:"status"`
+	RequestedAt time.Time  `json:"requested_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	Rows        int64      `json:"rows"`
+	Bytes       int64      `json:"bytes"`
+	Errors      []string   `json:"errors,omitempty"`
+}
+
+const wildcard = "*"
+
+var ErrInvalidStatus = errors.New("invalid snapshot status, must be one of 'requested', 'in progress', 'completed' or 'failed'")
+
+// ParseStatus returns the snapshot status for the string on input. An empty
+// string is returned as is, meaning any status.
+func ParseStatus(s string) (Status, error) {
+	switch status := Status(s); status {
+	case "", StatusRequested, StatusInProgress, StatusCompleted, StatusFailed:
+		return status, nil
+	default:
+		return "", ErrInvalidStatus
+	}
+}
+
+// NewTableStatuses returns the status of each table of the snapshot requests
+// on input, in the same order. The stats of a table are only associated to a
+// request if they were recorded while the request was being processed, since
+// only the latest stats are kept for each table. Wildcard requests are
+// expanded into the tables with stats or errors for the request, if any.
+func NewTableStatuses(requests []*Request, stats []*TableStats) []*TableStatus {
+	schemaStats := make(map[string][]*TableStats, len(stats))
+	for _, s := range stats {
+		schemaStats[s.Schema] = append(schemaStats[s.Schema], s)
+	}
+
+	statuses := []*TableStatus{}
+	for _, req := range requests {
+		reqStats := make(map[string]*TableStats, len(req.Tables))
+		for _, s := range schemaStats[req.Schema] {
+			if req.processedStats(s) {
+				reqStats[s.Table] = s
+			}
+		}
+
+		for _, table := range req.tables(reqStats) {
+			status := &TableStatus{
+				RequestID:   req.ID,
+				Schema:      req.Schema,
+				Table:       table,
+				Status:      req.Status,
+				RequestedAt: req.CreatedAt,
+				UpdatedAt:   req.UpdatedAt,
+				Errors:      req.Errors.tableErrors(table),
+			}
+			if req.Status == StatusCompleted && req.Errors.IsTableError(table) {
+				status.Status = StatusFailed
+			}
+			if s, found := reqStats[table]; found {
+				status.Rows = s.Rows
+				status.Bytes = s.Bytes
+				status.StartedAt = &s.StartedAt
+				status.FinishedAt = &s.FinishedAt
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// FilterTableStatuses returns the table statuses on input with the given
+// status. All of them are returned if the status is empty.
+func FilterTableStatuses(statuses []*TableStatus, status Status) []*TableStatus {
+	if status == "" {
+		return statuses
+	}
+	filtered := []*TableStatus{}
+	for _, s := range statuses {
+		if s.Status == status {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+// processedStats returns true if the table stats on input were recorded while
+// the request was being processed.
+func (r *Request) processedStats(s *TableStats) bool {
+	if s.UpdatedAt.Before(r.CreatedAt) {
+		return false
+	}
+	return r.Status != StatusCompleted || !s.UpdatedAt.After(r.UpdatedAt)
+}
+
+// tables returns the tables of the request, with the wildcard expanded into
+// the tables with stats or errors, if any.
+func (r *Request) tables(stats map[string]*TableStats) []string {
+	tables := []string{}
+	for _, table := range r.Tables {
+		if table != wildcard {
+			tables = append(tables, table)
+			continue
+		}
+
+		expanded := r.Errors.GetFailedTables()
+		for t := range stats {
+			if !slices.Contains(expanded, t) {
+				expanded = append(expanded, t)
+			}
+		}
+		if len(expanded) == 0 {
+			tables = append(tables, table)
+			continue
+		}
+		slices.Sort(expanded)
+		for _, t := range expanded {
+			if !slices.Contains(tables, t) && !slices.Contains(r.Tables, t) {
+				tables = append(tables, t)
+			}
+		}
+	}
+	return tables
+}
+
+// tableErrors returns the global errors and the errors of the table on input.
+func (e *SchemaErrors) tableErrors(table string) []string {
+	if e == nil {
+		return nil
+	}
+	errs := slices.Clone(e.GlobalErrors)
+	if tableErr, found := e.TableErrors[table]; found {
+		errs = append(errs, tableErr)
+	}
+	return errs
+}
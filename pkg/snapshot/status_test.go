@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTableStatuses(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	start := now.Add(-time.Hour)
+	end := now.Add(-time.Minute)
+	errTest := errors.New("oh noes")
+
+	stats := func(schema, table string, updatedAt time.Time) *TableStats {
+		return &TableStats{
+			Schema:     schema,
+			Table:      table,
+			Rows:       10,
+			Bytes:      100,
+			StartedAt:  updatedAt.Add(-time.Second),
+			FinishedAt: updatedAt,
+			UpdatedAt:  updatedAt,
+		}
+	}
+	withStats := func(status *TableStatus, s *TableStats) *TableStatus {
+		status.Rows = s.Rows
+		status.Bytes = s.Bytes
+		status.StartedAt = &s.StartedAt
+		status.FinishedAt = &s.FinishedAt
+		return status
+	}
+
+	usersStats := stats("public", "users", start.Add(time.Second))
+	ordersStats := stats("public", "orders", start.Add(2*time.Second))
+
+	tests := []struct {
+		name     string
+		requests []*Request
+		stats    []*TableStats
+
+		wantStatuses []*TableStatus
+	}{
+		{
+			name: "completed request with stats",
+			requests: []*Request{
+				{ID: 1, Schema: "public", Tables: []string{"users"}, Status: StatusCompleted, CreatedAt: start, UpdatedAt: end},
+			},
+			stats: []*TableStats{usersStats},
+
+			wantStatuses: []*TableStatus{
+				withStats(&TableStatus{RequestID: 1, Schema: "public", Table: "users", Status: StatusCompleted, RequestedAt: start, UpdatedAt: end}, usersStats),
+			},
+		},
+		{
+			name: "failed table and stats recorded after the request",
+			requests: []*Request{
+				{
+					ID: 1, Schema: "public", Tables: []string{"users", "orders"}, Status: StatusCompleted, CreatedAt: start, UpdatedAt: end,
+					Errors: &SchemaErrors{Schema: "public", TableErrors: map[string]string{"orders": errTest.Error()}},
+				},
+			},
+			stats: []*TableStats{stats("public", "users", now)},
+
+			wantStatuses: []*TableStatus{
+				{RequestID: 1, Schema: "public", Table: "users", Status: StatusCompleted, RequestedAt: start, UpdatedAt: end},
+				{RequestID: 1, Schema: "public", Table: "orders", Status: StatusFailed, RequestedAt: start, UpdatedAt: end, Errors: []string{errTest.Error()}},
+			},
+		},
+		{
+			name: "wildcard request expanded",
+			requests: []*Request{
+				{
+					ID: 2, Schema: "public", Tables: []string{"*"}, Status: StatusCompleted, CreatedAt: start, UpdatedAt: end,
+					Errors: &SchemaErrors{Schema: "public", TableErrors: map[string]string{"products": errTest.Error()}},
+				},
+			},
+			stats: []*TableStats{usersStats, ordersStats, stats("other", "users", start.Add(time.Second))},
+
+			wantStatuses: []*TableStatus{
+				withStats(&TableStatus{RequestID: 2, Schema: "public", Table: "orders", Status: StatusCompleted, RequestedAt: start, UpdatedAt: end}, ordersStats),
+				{RequestID: 2, Schema: "public", Table: "products", Status: StatusFailed, RequestedAt: start, UpdatedAt: end, Errors: []string{errTest.Error()}},
+				withStats(&TableStatus{RequestID: 2, Schema: "public", Table: "users", Status: StatusCompleted, RequestedAt: start, UpdatedAt: end}, usersStats),
+			},
+		},
+		{
+			name: "wildcard request in progress without stats",
+			requests: []*Request{
+				{ID: 3, Schema: "public", Tables: []string{"*"}, Status: StatusInProgress, CreatedAt: start, UpdatedAt: start},
+			},
+			stats: []*TableStats{},
+
+			wantStatuses: []*TableStatus{
+				{RequestID: 3, Schema: "public", Table: "*", Status: StatusInProgress, RequestedAt: start, UpdatedAt: start},
+			},
+		},
+		{
+			name: "global error",
+			requests: []*Request{
+				{
+					ID: 4, Schema: "public", Tables: []string{"users"}, Status: StatusCompleted, CreatedAt: start, UpdatedAt: end,
+					Errors: NewSchemaErrors("public", errTest),
+				},
+			},
+			stats: []*TableStats{},
+
+			wantStatuses: []*TableStatus{
+				{RequestID: 4, Schema: "public", Table: "users", Status: StatusFailed, RequestedAt: start, UpdatedAt: end, Errors: []string{errTest.Error()}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			statuses := NewTableStatuses(tc.requests, tc.stats)
+			require.Equal(t, tc.wantStatuses, statuses)
+			require.Equal(t, tc.wantStatuses, FilterTableStatuses(statuses, ""))
+		})
+	}
+}
+
+func TestFilterTableStatuses(t *testing.T) {
+	t.Parallel()
+
+	statuses := []*TableStatus{
+		{Table: "users", Status: StatusCompleted},
+		{Table: "orders", Status: StatusFailed},
+	}
+
+	require.Equal(t, statuses[1:], FilterTableStatuses(statuses, StatusFailed))
+	require.Empty(t, FilterTableStatuses(statuses, StatusInProgress))
+}
+
+func TestParseStatus(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"", "requested", "in progress", "completed", "failed"} {
+		status, err := ParseStatus(s)
+		require.NoError(t, err)
+		require.Equal(t, Status(s), status)
+	}
+
+	_, err := ParseStatus("unknown")
+	require.ErrorIs(t, err, ErrInvalidStatus)
+}
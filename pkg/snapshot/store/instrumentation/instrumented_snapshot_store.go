@@ -59,6 +59,48 @@ func (i *Store) GetSnapshotRequestsBySchema(ctx context.Context, schema string)
 	return i.inner.GetSnapshotRequestsBySchema(ctx, schema)
 }
 
+func (i *Store) GetSnapshotRequests(ctx context.Context) (req []*snapshot.Request, err error) {
+	ctx, span := otel.StartSpan(ctx, i.tracer, "snapshotStore.GetSnapshotRequests")
+	defer otel.CloseSpan(span, err)
+
+	return i.inner.GetSnapshotRequests(ctx)
+}
+
+func (i *Store) GetTableCheckpoint(ctx context.Context, schema, table string) (checkpoint *snapshot.TableCheckpoint, err error) {
+	ctx, span := otel.StartSpan(ctx, i.tracer, "snapshotStore.GetTableCheckpoint", trace.WithAttributes(i.tableAttributes(schema, table)...))
+	defer otel.CloseSpan(span, err)
+
+	return i.inner.GetTableCheckpoint(ctx, schema, table)
+}
+
+func (i *Store) SaveTableCheckpoint(ctx context.Context, checkpoint *snapshot.TableCheckpoint) (err error) {
+	ctx, span := otel.StartSpan(ctx, i.tracer, "snapshotStore.SaveTableCheckpoint", trace.WithAttributes(i.tableAttributes(checkpoint.Schema, checkpoint.Table)...))
+	defer otel.CloseSpan(span, err)
+
+	return i.inner.SaveTableCheckpoint(ctx, checkpoint)
+}
+
+func (i *Store) DeleteTableCheckpoint(ctx context.Context, schema, table string) (err error) {
+	ctx, span := otel.StartSpan(ctx, i.tracer, "snapshotStore.DeleteTableCheckpoint", trace.WithAttributes(i.tableAttributes(schema, table)...))
+	defer otel.CloseSpan(span, err)
+
+	return i.inner.DeleteTableCheckpoint(ctx, schema, table)
+}
+
+func (i *Store) SaveTableStats(ctx context.Context, stats *snapshot.TableStats) (err error) {
+	ctx, span := otel.StartSpan(ctx, i.tracer, "snapshotStore.SaveTableStats", trace.WithAttributes(i.tableAttributes(stats.Schema, stats.Table)...))
+	defer otel.CloseSpan(span, err)
+
+	return i.inner.SaveTableStats(ctx, stats)
+}
+
+func (i *Store) GetTableStats(ctx context.Context) (stats []*snapshot.TableStats, err error) {
+	ctx, span := otel.StartSpan(ctx, i.tracer, "snapshotStore.GetTableStats")
+	defer otel.CloseSpan(span, err)
+
+	return i.inner.GetTableStats(ctx)
+}
+
 func (i *Store) Close() error {
 	return i.inner.Close()
 }
@@ -70,3 +112,10 @@ func (i *Store) requestAttributes(req *snapshot.Request) []attribute.KeyValue {
 		{Key: "status", Value: attribute.StringValue(string(req.Status))},
 	}
 }
+
+func (i *Store) tableAttributes(schema, table string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		{Key: "schema", Value: attribute.StringValue(schema)},
+		{Key: "table", Value: attribute.StringValue(table)},
+	}
+}
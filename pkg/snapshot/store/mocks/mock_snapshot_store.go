@@ -13,6 +13,12 @@ type Store struct {
 	UpdateSnapshotRequestFn       func(context.Context, uint, *snapshot.Request) error
 	GetSnapshotRequestsByStatusFn func(ctx context.Context, status snapshot.Status) ([]*snapshot.Request, error)
 	GetSnapshotRequestsBySchemaFn func(ctx context.Context, s string) ([]*snapshot.Request, error)
+	GetSnapshotRequestsFn         func(ctx context.Context) ([]*snapshot.Request, error)
+	GetTableCheckpointFn          func(ctx context.Context, schema, table string) (*snapshot.TableCheckpoint, error)
+	SaveTableCheckpointFn         func(ctx context.Context, checkpoint *snapshot.TableCheckpoint) error
+	DeleteTableCheckpointFn       func(ctx context.Context, schema, table string) error
+	SaveTableStatsFn              func(ctx context.Context, stats *snapshot.TableStats) error
+	GetTableStatsFn               func(ctx context.Context) ([]*snapshot.TableStats, error)
 	updateSnapshotRequestCalls    uint
 }
 
@@ -33,6 +39,30 @@ func (m *Store) GetSnapshotRequestsBySchema(ctx context.Context, s string) ([]*s
 	return m.GetSnapshotRequestsBySchemaFn(ctx, s)
 }
 
+func (m *Store) GetSnapshotRequests(ctx context.Context) ([]*snapshot.Request, error) {
+	return m.GetSnapshotRequestsFn(ctx)
+}
+
+func (m *Store) GetTableCheckpoint(ctx context.Context, schema, table string) (*snapshot.TableCheckpoint, error) {
+	return m.GetTableCheckpointFn(ctx, schema, table)
+}
+
+func (m *Store) SaveTableCheckpoint(ctx context.Context, checkpoint *snapshot.TableCheckpoint) error {
+	return m.SaveTableCheckpointFn(ctx, checkpoint)
+}
+
+func (m *Store) DeleteTableCheckpoint(ctx context.Context, schema, table string) error {
+	return m.DeleteTableCheckpointFn(ctx, schema, table)
+}
+
+func (m *Store) SaveTableStats(ctx context.Context, stats *snapshot.TableStats) error {
+	return m.SaveTableStatsFn(ctx, stats)
+}
+
+func (m *Store) GetTableStats(ctx context.Context) ([]*snapshot.TableStats, error) {
+	return m.GetTableStatsFn(ctx)
+}
+
 func (m *Store) Close() error {
 	return nil
 }
@@ -4,6 +4,8 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/lib/pq"
@@ -106,6 +108,106 @@ func (s *Store) GetSnapshotRequestsBySchema(ctx context.Context, schema string)
 	return snapshotRequests, nil
 }
 
+func (s *Store) GetSnapshotRequests(ctx context.Context) ([]*snapshot.Request, error) {
+	query := fmt.Sprintf(`SELECT req_id,schema_name,table_names,status,errors,created_at,updated_at FROM %s
+	ORDER BY req_id DESC LIMIT %d`, snapshotsTable(), queryLimit)
+	rows, err := s.conn.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting snapshot requests: %w", err)
+	}
+	defer rows.Close()
+
+	snapshotRequests := []*snapshot.Request{}
+	for rows.Next() {
+		req := &snapshot.Request{}
+		if err := rows.Scan(&req.ID, &req.Schema, &req.Tables, &req.Status, &req.Errors, &req.CreatedAt, &req.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning snapshot request row: %w", err)
+		}
+
+		snapshotRequests = append(snapshotRequests, req)
+	}
+
+	return snapshotRequests, rows.Err()
+}
+
+func (s *Store) GetTableCheckpoint(ctx context.Context, schema, table string) (*snapshot.TableCheckpoint, error) {
+	query := fmt.Sprintf(`SELECT completed_ranges, COALESCE(snapshot_lsn, '') FROM %s WHERE schema_name = $1 AND table_name = $2`, checkpointsTable())
+	var completedRanges []byte
+	var lsn string
+	if err := s.conn.QueryRow(ctx, []any{&completedRanges, &lsn}, query, schema, table); err != nil {
+		if errors.Is(err, postgres.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting table snapshot checkpoint: %w", err)
+	}
+
+	checkpoint := &snapshot.TableCheckpoint{
+		Schema: schema,
+		Table:  table,
+		LSN:    lsn,
+	}
+	if err := json.Unmarshal(completedRanges, &checkpoint.CompletedRanges); err != nil {
+		return nil, fmt.Errorf("error unmarshaling table snapshot checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+func (s *Store) SaveTableCheckpoint(ctx context.Context, checkpoint *snapshot.TableCheckpoint) error {
+	completedRanges, err := json.Marshal(checkpoint.CompletedRanges)
+	if err != nil {
+		return fmt.Errorf("error marshaling table snapshot checkpoint: %w", err)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (schema_name, table_name, completed_ranges, snapshot_lsn, updated_at)
+	VALUES($1, $2, $3, NULLIF($4, ''), now())
+	ON CONFLICT (schema_name, table_name) DO UPDATE SET completed_ranges = EXCLUDED.completed_ranges, snapshot_lsn = EXCLUDED.snapshot_lsn, updated_at = now()`, checkpointsTable())
+	if _, err := s.conn.Exec(ctx, query, checkpoint.Schema, checkpoint.Table, completedRanges, checkpoint.LSN); err != nil {
+		return fmt.Errorf("error saving table snapshot checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) DeleteTableCheckpoint(ctx context.Context, schema, table string) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE schema_name = $1 AND table_name = $2`, checkpointsTable())
+	if _, err := s.conn.Exec(ctx, query, schema, table); err != nil {
+		return fmt.Errorf("error deleting table snapshot checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) SaveTableStats(ctx context.Context, stats *snapshot.TableStats) error {
+	query := fmt.Sprintf(`INSERT INTO %s (schema_name, table_name, row_count, byte_count, started_at, finished_at, updated_at)
+	VALUES($1, $2, $3, $4, $5, $6, now())
+	ON CONFLICT (schema_name, table_name) DO UPDATE SET row_count = EXCLUDED.row_count, byte_count = EXCLUDED.byte_count,
+	started_at = EXCLUDED.started_at, finished_at = EXCLUDED.finished_at, updated_at = now()`, tableStatsTable())
+	if _, err := s.conn.Exec(ctx, query, stats.Schema, stats.Table, stats.Rows, stats.Bytes, stats.StartedAt, stats.FinishedAt); err != nil {
+		return fmt.Errorf("error saving table snapshot stats: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetTableStats(ctx context.Context) ([]*snapshot.TableStats, error) {
+	query := fmt.Sprintf(`SELECT schema_name,table_name,row_count,byte_count,started_at,finished_at,updated_at FROM %s
+	ORDER BY schema_name, table_name`, tableStatsTable())
+	rows, err := s.conn.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting table snapshot stats: %w", err)
+	}
+	defer rows.Close()
+
+	tableStats := []*snapshot.TableStats{}
+	for rows.Next() {
+		stats := &snapshot.TableStats{}
+		if err := rows.Scan(&stats.Schema, &stats.Table, &stats.Rows, &stats.Bytes, &stats.StartedAt, &stats.FinishedAt, &stats.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning table snapshot stats row: %w", err)
+		}
+
+		tableStats = append(tableStats, stats)
+	}
+
+	return tableStats, rows.Err()
+}
+
 func (s *Store) createTable(ctx context.Context) error {
 	createSchemaQuery := fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s`, store.SchemaName)
 	_, err := s.conn.Exec(ctx, createSchemaQuery)
@@ -133,9 +235,43 @@ func (s *Store) createTable(ctx context.Context) error {
 		return fmt.Errorf("error creating unique index on snapshots postgres table: %w", err)
 	}
 
+	createCheckpointsTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s(
+	schema_name TEXT,
+	table_name TEXT,
+	completed_ranges JSONB,
+	snapshot_lsn TEXT,
+	updated_at TIMESTAMP WITH TIME ZONE,
+	PRIMARY KEY (schema_name, table_name) )`, checkpointsTable())
+	_, err = s.conn.Exec(ctx, createCheckpointsTableQuery)
+	if err != nil {
+		return fmt.Errorf("error creating snapshot checkpoints postgres table: %w", err)
+	}
+
+	createTableStatsTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s(
+	schema_name TEXT,
+	table_name TEXT,
+	row_count BIGINT,
+	byte_count BIGINT,
+	started_at TIMESTAMP WITH TIME ZONE,
+	finished_at TIMESTAMP WITH TIME ZONE,
+	updated_at TIMESTAMP WITH TIME ZONE,
+	PRIMARY KEY (schema_name, table_name) )`, tableStatsTable())
+	_, err = s.conn.Exec(ctx, createTableStatsTableQuery)
+	if err != nil {
+		return fmt.Errorf("error creating snapshot table stats postgres table: %w", err)
+	}
+
 	return err
 }
 
 func snapshotsTable() string {
 	return postgres.QuoteQualifiedIdentifier(store.SchemaName, store.TableName)
 }
+
+func checkpointsTable() string {
+	return postgres.QuoteQualifiedIdentifier(store.SchemaName, store.CheckpointsTableName)
+}
+
+func tableStatsTable() string {
+	return postgres.QuoteQualifiedIdentifier(store.SchemaName, store.TableStatsTableName)
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
@@ -440,6 +441,28 @@ func TestStore_createTable(t *testing.T) {
 	ON %s(schema_name,table_names) WHERE status != 'completed'`, snapshotsTable())
 						require.Equal(t, wantQuery, s)
 						require.Empty(t, a)
+					case 4:
+						wantQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s(
+	schema_name TEXT,
+	table_name TEXT,
+	completed_ranges JSONB,
+	snapshot_lsn TEXT,
+	updated_at TIMESTAMP WITH TIME ZONE,
+	PRIMARY KEY (schema_name, table_name) )`, checkpointsTable())
+						require.Equal(t, wantQuery, s)
+						require.Empty(t, a)
+					case 5:
+						wantQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s(
+	schema_name TEXT,
+	table_name TEXT,
+	row_count BIGINT,
+	byte_count BIGINT,
+	started_at TIMESTAMP WITH TIME ZONE,
+	finished_at TIMESTAMP WITH TIME ZONE,
+	updated_at TIMESTAMP WITH TIME ZONE,
+	PRIMARY KEY (schema_name, table_name) )`, tableStatsTable())
+						require.Equal(t, wantQuery, s)
+						require.Empty(t, a)
 					default:
 						return pglib.CommandTag{}, fmt.Errorf("unexpected Exec call: %d", i)
 					}
@@ -497,6 +520,38 @@ func TestStore_createTable(t *testing.T) {
 			},
 			wantErr: errTest,
 		},
+		{
+			name: "error - creating checkpoints table",
+			querier: &postgresmocks.Querier{
+				ExecFn: func(ctx context.Context, i uint, s string, a ...any) (pglib.CommandTag, error) {
+					switch i {
+					case 1, 2, 3:
+						return pglib.CommandTag{}, nil
+					case 4:
+						return pglib.CommandTag{}, errTest
+					default:
+						return pglib.CommandTag{}, fmt.Errorf("unexpected Exec call: %d", i)
+					}
+				},
+			},
+			wantErr: errTest,
+		},
+		{
+			name: "error - creating table stats table",
+			querier: &postgresmocks.Querier{
+				ExecFn: func(ctx context.Context, i uint, s string, a ...any) (pglib.CommandTag, error) {
+					switch i {
+					case 1, 2, 3, 4:
+						return pglib.CommandTag{}, nil
+					case 5:
+						return pglib.CommandTag{}, errTest
+					default:
+						return pglib.CommandTag{}, fmt.Errorf("unexpected Exec call: %d", i)
+					}
+				},
+			},
+			wantErr: errTest,
+		},
 	}
 
 	for _, tc := range tests {
@@ -511,3 +566,368 @@ func TestStore_createTable(t *testing.T) {
 		})
 	}
 }
+
+func TestStore_GetTableCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	testSchema := "test-schema"
+	testTable := "test-table"
+	errTest := errors.New("oh noes")
+	wantQuery := fmt.Sprintf(`SELECT completed_ranges, COALESCE(snapshot_lsn, '') FROM %s WHERE schema_name = $1 AND table_name = $2`, checkpointsTable())
+
+	tests := []struct {
+		name    string
+		querier pglib.Querier
+
+		wantCheckpoint *snapshot.TableCheckpoint
+		wantErr        error
+	}{
+		{
+			name: "ok",
+			querier: &postgresmocks.Querier{
+				QueryRowFn: func(ctx context.Context, dest []any, query string, args ...any) error {
+					require.Equal(t, wantQuery, query)
+					require.Equal(t, []any{testSchema, testTable}, args)
+					require.Len(t, dest, 2)
+					completedRanges, ok := dest[0].(*[]byte)
+					require.True(t, ok)
+					*completedRanges = []byte(`[{"start":0,"end":10},{"start":20,"end":30}]`)
+					lsn, ok := dest[1].(*string)
+					require.True(t, ok)
+					*lsn = "0/15D6A88"
+					return nil
+				},
+			},
+
+			wantCheckpoint: &snapshot.TableCheckpoint{
+				Schema:          testSchema,
+				Table:           testTable,
+				CompletedRanges: []snapshot.PageRange{{Start: 0, End: 10}, {Start: 20, End: 30}},
+				LSN:             "0/15D6A88",
+			},
+			wantErr: nil,
+		},
+		{
+			name: "ok - no checkpoint",
+			querier: &postgresmocks.Querier{
+				QueryRowFn: func(ctx context.Context, dest []any, query string, args ...any) error {
+					return pglib.ErrNoRows
+				},
+			},
+
+			wantCheckpoint: nil,
+			wantErr:        nil,
+		},
+		{
+			name: "error - querying",
+			querier: &postgresmocks.Querier{
+				QueryRowFn: func(ctx context.Context, dest []any, query string, args ...any) error {
+					return errTest
+				},
+			},
+
+			wantCheckpoint: nil,
+			wantErr:        errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := Store{
+				conn: tc.querier,
+			}
+			checkpoint, err := store.GetTableCheckpoint(context.Background(), testSchema, testTable)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantCheckpoint, checkpoint)
+		})
+	}
+}
+
+func TestStore_SaveTableCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	testCheckpoint := &snapshot.TableCheckpoint{
+		Schema:          "test-schema",
+		Table:           "test-table",
+		CompletedRanges: []snapshot.PageRange{{Start: 0, End: 10}},
+		LSN:             "0/15D6A88",
+	}
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name    string
+		querier pglib.Querier
+
+		wantErr error
+	}{
+		{
+			name: "ok",
+			querier: &postgresmocks.Querier{
+				ExecFn: func(ctx context.Context, _ uint, s string, a ...any) (pglib.CommandTag, error) {
+					wantQuery := fmt.Sprintf(`INSERT INTO %s (schema_name, table_name, completed_ranges, snapshot_lsn, updated_at)
+	VALUES($1, $2, $3, NULLIF($4, ''), now())
+	ON CONFLICT (schema_name, table_name) DO UPDATE SET completed_ranges = EXCLUDED.completed_ranges, snapshot_lsn = EXCLUDED.snapshot_lsn, updated_at = now()`, checkpointsTable())
+					require.Equal(t, wantQuery, s)
+					require.Equal(t, []any{"test-schema", "test-table", []byte(`[{"start":0,"end":10}]`), "0/15D6A88"}, a)
+					return pglib.CommandTag{}, nil
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "error - saving checkpoint",
+			querier: &postgresmocks.Querier{
+				ExecFn: func(ctx context.Context, _ uint, s string, a ...any) (pglib.CommandTag, error) {
+					return pglib.CommandTag{}, errTest
+				},
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := Store{
+				conn: tc.querier,
+			}
+			err := store.SaveTableCheckpoint(context.Background(), testCheckpoint)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestStore_GetSnapshotRequests(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("oh noes")
+	now := time.Now()
+	testSnapshotRequest := &snapshot.Request{
+		ID:        1,
+		Schema:    "test-schema",
+		Tables:    []string{"test-table"},
+		Status:    snapshot.StatusCompleted,
+		Errors:    snapshot.NewSchemaErrors("test-schema", errTest),
+		CreatedAt: now.Add(-time.Minute),
+		UpdatedAt: now,
+	}
+	wantQuery := fmt.Sprintf(`SELECT req_id,schema_name,table_names,status,errors,created_at,updated_at FROM %s
+	ORDER BY req_id DESC LIMIT %d`, snapshotsTable(), queryLimit)
+
+	tests := []struct {
+		name    string
+		querier pglib.Querier
+
+		wantRequests []*snapshot.Request
+		wantErr      error
+	}{
+		{
+			name: "ok",
+			querier: &postgresmocks.Querier{
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					require.Equal(t, wantQuery, query)
+					require.Empty(t, args)
+					return &postgresmocks.Rows{
+						CloseFn: func() {},
+						NextFn:  func(i uint) bool { return i == 1 },
+						ScanFn: func(i uint, dest ...any) error {
+							require.Len(t, dest, 7)
+							*dest[0].(*int64) = testSnapshotRequest.ID
+							*dest[1].(*string) = testSnapshotRequest.Schema
+							*dest[2].(*[]string) = testSnapshotRequest.Tables
+							*dest[3].(*snapshot.Status) = testSnapshotRequest.Status
+							*dest[4].(**snapshot.SchemaErrors) = testSnapshotRequest.Errors
+							*dest[5].(*time.Time) = testSnapshotRequest.CreatedAt
+							*dest[6].(*time.Time) = testSnapshotRequest.UpdatedAt
+							return nil
+						},
+						ErrFn: func() error { return nil },
+					}, nil
+				},
+			},
+
+			wantRequests: []*snapshot.Request{testSnapshotRequest},
+			wantErr:      nil,
+		},
+		{
+			name: "error - querying",
+			querier: &postgresmocks.Querier{
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					return nil, errTest
+				},
+			},
+
+			wantRequests: nil,
+			wantErr:      errTest,
+		},
+		{
+			name: "error - scanning row",
+			querier: &postgresmocks.Querier{
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					return &postgresmocks.Rows{
+						CloseFn: func() {},
+						NextFn:  func(i uint) bool { return i == 1 },
+						ScanFn:  func(i uint, dest ...any) error { return errTest },
+					}, nil
+				},
+			},
+
+			wantRequests: nil,
+			wantErr:      errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := Store{
+				conn: tc.querier,
+			}
+			requests, err := store.GetSnapshotRequests(context.Background())
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantRequests, requests)
+		})
+	}
+}
+
+func TestStore_SaveTableStats(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	testStats := &snapshot.TableStats{
+		Schema:     "test-schema",
+		Table:      "test-table",
+		Rows:       10,
+		Bytes:      100,
+		StartedAt:  now.Add(-time.Second),
+		FinishedAt: now,
+	}
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name    string
+		querier pglib.Querier
+
+		wantErr error
+	}{
+		{
+			name: "ok",
+			querier: &postgresmocks.Querier{
+				ExecFn: func(ctx context.Context, _ uint, s string, a ...any) (pglib.CommandTag, error) {
+					wantQuery := fmt.Sprintf(`INSERT INTO %s (schema_name, table_name, row_count, byte_count, started_at, finished_at, updated_at)
+	VALUES($1, $2, $3, $4, $5, $6, now())
+	ON CONFLICT (schema_name, table_name) DO UPDATE SET row_count = EXCLUDED.row_count, byte_count = EXCLUDED.byte_count,
+	started_at = EXCLUDED.started_at, finished_at = EXCLUDED.finished_at, updated_at = now()`, tableStatsTable())
+					require.Equal(t, wantQuery, s)
+					require.Equal(t, []any{"test-schema", "test-table", int64(10), int64(100), testStats.StartedAt, testStats.FinishedAt}, a)
+					return pglib.CommandTag{}, nil
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "error - saving stats",
+			querier: &postgresmocks.Querier{
+				ExecFn: func(ctx context.Context, _ uint, s string, a ...any) (pglib.CommandTag, error) {
+					return pglib.CommandTag{}, errTest
+				},
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := Store{
+				conn: tc.querier,
+			}
+			err := store.SaveTableStats(context.Background(), testStats)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestStore_GetTableStats(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	testStats := &snapshot.TableStats{
+		Schema:     "test-schema",
+		Table:      "test-table",
+		Rows:       10,
+		Bytes:      100,
+		StartedAt:  now.Add(-time.Second),
+		FinishedAt: now,
+		UpdatedAt:  now,
+	}
+	errTest := errors.New("oh noes")
+	wantQuery := fmt.Sprintf(`SELECT schema_name,table_name,row_count,byte_count,started_at,finished_at,updated_at FROM %s
+	ORDER BY schema_name, table_name`, tableStatsTable())
+
+	tests := []struct {
+		name    string
+		querier pglib.Querier
+
+		wantStats []*snapshot.TableStats
+		wantErr   error
+	}{
+		{
+			name: "ok",
+			querier: &postgresmocks.Querier{
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					require.Equal(t, wantQuery, query)
+					require.Empty(t, args)
+					return &postgresmocks.Rows{
+						CloseFn: func() {},
+						NextFn:  func(i uint) bool { return i == 1 },
+						ScanFn: func(i uint, dest ...any) error {
+							require.Len(t, dest, 7)
+							*dest[0].(*string) = testStats.Schema
+							*dest[1].(*string) = testStats.Table
+							*dest[2].(*int64) = testStats.Rows
+							*dest[3].(*int64) = testStats.Bytes
+							*dest[4].(*time.Time) = testStats.StartedAt
+							*dest[5].(*time.Time) = testStats.FinishedAt
+							*dest[6].(*time.Time) = testStats.UpdatedAt
+							return nil
+						},
+						ErrFn: func() error { return nil },
+					}, nil
+				},
+			},
+
+			wantStats: []*snapshot.TableStats{testStats},
+			wantErr:   nil,
+		},
+		{
+			name: "error - querying",
+			querier: &postgresmocks.Querier{
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					return nil, errTest
+				},
+			},
+
+			wantStats: nil,
+			wantErr:   errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := Store{
+				conn: tc.querier,
+			}
+			stats, err := store.GetTableStats(context.Background())
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantStats, stats)
+		})
+	}
+}
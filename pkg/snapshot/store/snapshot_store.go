@@ -13,10 +13,48 @@ type Store interface {
 	UpdateSnapshotRequest(context.Context, *snapshot.Request) error
 	GetSnapshotRequestsByStatus(ctx context.Context, status snapshot.Status) ([]*snapshot.Request, error)
 	GetSnapshotRequestsBySchema(ctx context.Context, schema string) ([]*snapshot.Request, error)
+	// GetSnapshotRequests returns the latest snapshot requests, most recent
+	// first.
+	GetSnapshotRequests(ctx context.Context) ([]*snapshot.Request, error)
+	CheckpointStore
+	TableStatsStore
 	Close() error
 }
 
+// CheckpointStore keeps track of the progress of the table data snapshots, so
+// that they can be resumed after a failure.
+type CheckpointStore interface {
+	// GetTableCheckpoint returns the checkpoint of the table on input, or nil
+	// if there is none.
+	GetTableCheckpoint(ctx context.Context, schema, table string) (*snapshot.TableCheckpoint, error)
+	SaveTableCheckpoint(ctx context.Context, checkpoint *snapshot.TableCheckpoint) error
+	DeleteTableCheckpoint(ctx context.Context, schema, table string) error
+}
+
+// TableStatsStore keeps the row and byte counts of the latest snapshot of each
+// table.
+type TableStatsStore interface {
+	SaveTableStats(ctx context.Context, stats *snapshot.TableStats) error
+	GetTableStats(ctx context.Context) ([]*snapshot.TableStats, error)
+}
+
+// GetTableStatuses returns the status of the tables of the latest snapshot
+// requests, most recent first, filtered by the status on input if not empty.
+func GetTableStatuses(ctx context.Context, s Store, status snapshot.Status) ([]*snapshot.TableStatus, error) {
+	requests, err := s.GetSnapshotRequests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.GetTableStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.FilterTableStatuses(snapshot.NewTableStatuses(requests, stats), status), nil
+}
+
 const (
-	SchemaName = "pgstream"
-	TableName  = "snapshot_requests"
+	SchemaName           = "pgstream"
+	TableName            = "snapshot_requests"
+	CheckpointsTableName = "snapshot_table_checkpoints"
+	TableStatsTableName  = "snapshot_table_stats"
 )
@@ -3,6 +3,7 @@
 package stream
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -10,14 +11,20 @@ import (
 
 	"github.com/xataio/pgstream/pkg/backoff"
 	"github.com/xataio/pgstream/pkg/kafka"
+	"github.com/xataio/pgstream/pkg/snapshot/generator/postgres/schema/pgdumprestore"
 	kafkacheckpoint "github.com/xataio/pgstream/pkg/wal/checkpointer/kafka"
 	snapshotbuilder "github.com/xataio/pgstream/pkg/wal/listener/snapshot/builder"
+	"github.com/xataio/pgstream/pkg/wal/processor/ddlpolicy"
+	filewriter "github.com/xataio/pgstream/pkg/wal/processor/file"
 	"github.com/xataio/pgstream/pkg/wal/processor/filter"
+	"github.com/xataio/pgstream/pkg/wal/processor/incremental"
 	"github.com/xataio/pgstream/pkg/wal/processor/injector"
 	kafkaprocessor "github.com/xataio/pgstream/pkg/wal/processor/kafka"
+	"github.com/xataio/pgstream/pkg/wal/processor/merger"
 	"github.com/xataio/pgstream/pkg/wal/processor/postgres"
 	"github.com/xataio/pgstream/pkg/wal/processor/search"
 	"github.com/xataio/pgstream/pkg/wal/processor/search/store"
+	"github.com/xataio/pgstream/pkg/wal/processor/toast"
 	"github.com/xataio/pgstream/pkg/wal/processor/transformer"
 	"github.com/xataio/pgstream/pkg/wal/processor/webhook/notifier"
 	"github.com/xataio/pgstream/pkg/wal/processor/webhook/subscription/server"
@@ -39,6 +46,9 @@ type PostgresListenerConfig struct {
 	Replication pgreplication.Config
 	RetryPolicy backoff.Config
 	Snapshot    *snapshotbuilder.SnapshotListenerConfig
+	// PublishViaPartitionRoot maps the changes of the partitions of
+	// partitioned tables to their root table
+	PublishViaPartitionRoot bool
 }
 
 type KafkaListenerConfig struct {
@@ -55,15 +65,27 @@ type ProcessorConfig struct {
 	Search      *SearchProcessorConfig
 	Webhook     *WebhookProcessorConfig
 	Postgres    *PostgresProcessorConfig
+	Audit       *AuditProcessorConfig
 	Stdout      *StdoutProcessorConfig
+	File        *FileProcessorConfig
 	Injector    *injector.Config
 	Transformer *transformer.Config
 	Filter      *filter.Config
 	Sanitize    *SanitizeConfig
+	Merger      *merger.Config
+	DDLPolicy   *ddlpolicy.Config
+	Toast       *toast.Config
+	// IncrementalSnapshot enables the incremental snapshots of tables while
+	// replicating, requested through the source signals table
+	IncrementalSnapshot *incremental.Config
 }
 
 type StdoutProcessorConfig struct{}
 
+type FileProcessorConfig struct {
+	Writer filewriter.Config
+}
+
 type KafkaProcessorConfig struct {
 	Writer *kafkaprocessor.Config
 }
@@ -84,6 +106,10 @@ type PostgresProcessorConfig struct {
 	BatchWriter postgres.Config
 }
 
+type AuditProcessorConfig struct {
+	Writer postgres.AuditConfig
+}
+
 type WebhookSubscriptionStoreConfig struct {
 	URL                  string
 	CacheEnabled         bool
@@ -127,6 +153,9 @@ func (c *ProcessorConfig) IsValid() error {
 	if c.Postgres != nil {
 		processorCount++
 	}
+	if c.Audit != nil {
+		processorCount++
+	}
 	if c.Search != nil {
 		processorCount++
 	}
@@ -136,6 +165,9 @@ func (c *ProcessorConfig) IsValid() error {
 	if c.Stdout != nil {
 		processorCount++
 	}
+	if c.File != nil {
+		processorCount++
+	}
 
 	switch processorCount {
 	case 0:
@@ -156,6 +188,19 @@ func (c *Config) SourcePostgresURL() string {
 	return ""
 }
 
+// SnapshotStoreURL returns the URL of the database where the snapshot requests
+// are recorded, if the snapshot recorder is configured.
+func (c *Config) SnapshotStoreURL() string {
+	if c.Listener.Postgres != nil && c.Listener.Postgres.Snapshot != nil && c.Listener.Postgres.Snapshot.Recorder != nil {
+		return c.Listener.Postgres.Snapshot.Recorder.SnapshotStoreURL
+	}
+	return ""
+}
+
+func (c *Config) publishViaPartitionRoot() bool {
+	return c.Listener.Postgres != nil && c.Listener.Postgres.PublishViaPartitionRoot
+}
+
 func (c *Config) PostgresReplicationSlot() string {
 	if c.Listener.Postgres != nil {
 		return c.Listener.Postgres.Replication.ReplicationSlotName
@@ -163,6 +208,65 @@ func (c *Config) PostgresReplicationSlot() string {
 	return ""
 }
 
+// incrementalSnapshotConfig returns the incremental snapshot configuration,
+// with the replication scope and slot the automatic snapshots of new tables
+// rely on.
+func (c *Config) incrementalSnapshotConfig() *incremental.Config {
+	cfg := *c.Processor.IncrementalSnapshot
+	if !cfg.AutoSnapshotNewTables {
+		return &cfg
+	}
+	if c.Processor.Filter != nil {
+		cfg.IncludeTables = c.Processor.Filter.IncludeTables
+		cfg.ExcludeTables = c.Processor.Filter.ExcludeTables
+	}
+	if cfg.ReplicationSlotName == "" {
+		cfg.ReplicationSlotName = c.PostgresReplicationSlot()
+	}
+	return &cfg
+}
+
+// incrementalSchemaSnapshotConfig returns the configuration used to restore the
+// schema of the tables that come into the replication scope while the
+// pipeline is stopped, before they're snapshotted. It follows the schema
+// snapshot configuration of the listener if any, or restores into the
+// postgres target otherwise. It returns nil if there's no schema to restore.
+func (c *Config) incrementalSchemaSnapshotConfig() *pgdumprestore.Config {
+	if c.Processor.IncrementalSnapshot == nil || !c.Processor.IncrementalSnapshot.AutoSnapshotNewTables {
+		return nil
+	}
+
+	if c.Listener.Postgres != nil && c.Listener.Postgres.Snapshot != nil &&
+		c.Listener.Postgres.Snapshot.Schema != nil && c.Listener.Postgres.Snapshot.Schema.DumpRestore != nil {
+		cfg := *c.Listener.Postgres.Snapshot.Schema.DumpRestore
+		// only the new tables are restored, into the existing target
+		cfg.CleanTargetDB = false
+		cfg.CreateTargetDB = false
+		cfg.TargetDir = ""
+		return &cfg
+	}
+
+	if c.Processor.Postgres != nil {
+		return &pgdumprestore.Config{
+			SourcePGURL: c.Processor.IncrementalSnapshot.URL,
+			TargetPGURL: c.Processor.Postgres.BatchWriter.URL,
+			TableRoutes: c.Processor.Postgres.BatchWriter.TableRoutes,
+		}
+	}
+	return nil
+}
+
+// replicationConfig returns the postgres replication configuration. The
+// transaction boundaries are included when the postgres target applies the
+// changes exactly once, since it records its progress by commit LSN.
+func (c *Config) replicationConfig() pgreplication.Config {
+	cfg := c.Listener.Postgres.Replication
+	if c.Processor.Postgres != nil && c.Processor.Postgres.BatchWriter.ExactlyOnce {
+		cfg.PluginArguments.IncludeTransaction = true
+	}
+	return cfg
+}
+
 func (c *Config) isInjectorEnabled() bool {
 	return c.Processor.Injector != nil && c.Processor.Injector.URL != ""
 }
@@ -171,15 +275,56 @@ func (c *Config) isInjectorEnabled() bool {
 // restore primary keys, unique constraints and unique indexes before the data
 // snapshot runs. This is required when the postgres batch writer emits
 // INSERT ... ON CONFLICT DO UPDATE, since the target table needs a matching
-// conflict target at insert time.
+// conflict target at insert time, and when bulk ingest runs in merge mode,
+// since the staged rows are matched to the target rows by primary key.
 func (c *Config) restoreConflictTargetsBeforeData() bool {
 	if c.Processor.Postgres == nil {
 		return false
 	}
 	bw := c.Processor.Postgres.BatchWriter
+	if bulkMergeEnabled, _ := bw.IsBulkMergeEnabled(); bulkMergeEnabled {
+		return true
+	}
 	return !bw.BulkIngestEnabled && strings.EqualFold(bw.OnConflictAction, "update")
 }
 
+// snapshotConfig returns the snapshot listener configuration. When the data
+// snapshot is subset, the configured transformer rules are added to it, so
+// that it can validate the subset references remain valid once the rows are
+// transformed. The configuration is copied, so that the stream configuration
+// is left untouched.
+func (c *Config) snapshotConfig() *snapshotbuilder.SnapshotListenerConfig {
+	snapshotCfg := c.Listener.Postgres.Snapshot
+	if snapshotCfg.Data == nil || snapshotCfg.Data.Subset == nil || c.Processor.Transformer.HasNoRules() {
+		return snapshotCfg
+	}
+
+	transformedColumns := map[string]string{}
+	for _, tableRules := range c.Processor.Transformer.TransformerRules {
+		schema := tableRules.Schema
+		if schema == "" {
+			schema = "public"
+		}
+		for column, rules := range tableRules.ColumnRules {
+			// the rules are compared by their JSON representation. The
+			// standard library encoder is used since it sorts the map keys
+			rulesJSON, err := json.Marshal(rules)
+			if err != nil {
+				rulesJSON = []byte(rules.Name)
+			}
+			transformedColumns[schema+"."+tableRules.Table+"."+column] = string(rulesJSON)
+		}
+	}
+
+	subsetCfg := *snapshotCfg.Data.Subset
+	subsetCfg.TransformedColumns = transformedColumns
+	dataCfg := *snapshotCfg.Data
+	dataCfg.Subset = &subsetCfg
+	cfg := *snapshotCfg
+	cfg.Data = &dataCfg
+	return &cfg
+}
+
 func (c *Config) GetInitConfig(opts ...InitOption) *InitConfig {
 	initConfig := &InitConfig{
 		PostgresURL:               c.SourcePostgresURL(),
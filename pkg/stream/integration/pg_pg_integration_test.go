@@ -14,6 +14,8 @@ import (
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	"github.com/xataio/pgstream/internal/testcontainers"
 	"github.com/xataio/pgstream/pkg/stream"
+	"github.com/xataio/pgstream/pkg/wal/processor/filter"
+	"github.com/xataio/pgstream/pkg/wal/processor/incremental"
 )
 
 type informationSchemaColumn struct {
@@ -1073,3 +1075,184 @@ func Test_PostgresToPostgres_LargeIntegerPrecisionWAL(t *testing.T) {
 		})
 	})
 }
+
+func Test_PostgresToPostgres_CreateTableAs(t *testing.T) {
+	if os.Getenv("PGSTREAM_INTEGRATION_TESTS") == "" {
+		t.Skip("skipping integration test...")
+	}
+
+	processorCfg := testPostgresProcessorCfg()
+	processorCfg.IncrementalSnapshot = &incremental.Config{
+		URL:                   pgurl,
+		AutoSnapshotNewTables: true,
+	}
+	cfg := &stream.Config{
+		Listener:  testPostgresListenerCfg(),
+		Processor: processorCfg,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runStream(t, ctx, cfg)
+
+	testTable := "pg2pg_ctas_source"
+	copyTable := "pg2pg_ctas_copy"
+
+	targetConn, err := pglib.NewConn(ctx, targetPGURL)
+	require.NoError(t, err)
+
+	countRows := func(table string) int {
+		var count int
+		if err := targetConn.QueryRow(ctx, []any{&count}, fmt.Sprintf("SELECT count(*) FROM %s", table)); err != nil {
+			return -1
+		}
+		return count
+	}
+
+	waitForRows := func(table string, want int) {
+		t.Helper()
+		timer := time.NewTimer(20 * time.Second)
+		defer timer.Stop()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-timer.C:
+				cancel()
+				t.Fatalf("timeout waiting for %d rows in %s", want, table)
+			case <-ticker.C:
+				if countRows(table) == want {
+					return
+				}
+			}
+		}
+	}
+
+	execQuery(t, ctx, fmt.Sprintf("CREATE TABLE %s(id serial primary key, name text)", testTable))
+	execQuery(t, ctx, fmt.Sprintf("INSERT INTO %s(name) VALUES('a'),('b'),('c')", testTable))
+	waitForRows(testTable, 3)
+
+	// the rows of the tables created with data are part of the replication
+	// stream, so they're not snapshotted again
+	execQuery(t, ctx, fmt.Sprintf("CREATE TABLE %s AS SELECT * FROM %s", copyTable, testTable))
+	waitForRows(copyTable, 3)
+
+	// give the automatic incremental snapshot time to run if it was requested
+	time.Sleep(5 * time.Second)
+	require.Equal(t, 3, countRows(copyTable))
+
+	sourceConn, err := pglib.NewConn(ctx, pgurl)
+	require.NoError(t, err)
+	var tracked bool
+	err = sourceConn.QueryRow(ctx, []any{&tracked},
+		fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM pgstream.%s WHERE table_name = $1)", incremental.TrackedTablesTableName), copyTable)
+	require.NoError(t, err)
+	require.True(t, tracked)
+}
+
+func Test_PostgresToPostgres_ExcludedTableGrants(t *testing.T) {
+	if os.Getenv("PGSTREAM_INTEGRATION_TESTS") == "" {
+		t.Skip("skipping integration test...")
+	}
+
+	includedTable := "pg2pg_grants_included"
+	excludedTable := "pg2pg_grants_excluded"
+	testRole := "pg2pg_grants_role"
+
+	processorCfg := testPostgresProcessorCfg()
+	processorCfg.Filter = &filter.Config{
+		ExcludeTables: []string{excludedTable},
+	}
+	cfg := &stream.Config{
+		Listener:  testPostgresListenerCfg(),
+		Processor: processorCfg,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runStream(t, ctx, cfg)
+
+	for _, url := range []string{pgurl, targetPGURL} {
+		execQueryWithURL(t, ctx, url, fmt.Sprintf("CREATE ROLE %s", testRole))
+	}
+
+	targetConn, err := pglib.NewConn(ctx, targetPGURL)
+	require.NoError(t, err)
+
+	execQuery(t, ctx, fmt.Sprintf("CREATE TABLE %s(id serial primary key, name text)", includedTable))
+	execQuery(t, ctx, fmt.Sprintf("CREATE TABLE %s(id serial primary key, name text)", excludedTable))
+	// the grant on the excluded table is filtered out, since the table
+	// doesn't exist on the target
+	execQuery(t, ctx, fmt.Sprintf("GRANT SELECT ON %s TO %s", excludedTable, testRole))
+	execQuery(t, ctx, fmt.Sprintf("GRANT SELECT ON %s TO %s", includedTable, testRole))
+	execQuery(t, ctx, fmt.Sprintf("INSERT INTO %s(name) VALUES('a')", includedTable))
+
+	timer := time.NewTimer(20 * time.Second)
+	defer timer.Stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-timer.C:
+			cancel()
+			t.Fatalf("timeout waiting for the grant on %s to be replicated", includedTable)
+		case <-ticker.C:
+			var hasPrivilege bool
+			err := targetConn.QueryRow(ctx, []any{&hasPrivilege},
+				"SELECT has_table_privilege($1, $2, 'SELECT')", testRole, includedTable)
+			if err == nil && hasPrivilege {
+				return
+			}
+		}
+	}
+}
+
+func Test_PostgresToPostgres_PartitionsAndComments(t *testing.T) {
+	if os.Getenv("PGSTREAM_INTEGRATION_TESTS") == "" {
+		t.Skip("skipping integration test...")
+	}
+
+	cfg := &stream.Config{
+		Listener:  testPostgresListenerCfg(),
+		Processor: testPostgresProcessorCfg(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runStream(t, ctx, cfg)
+
+	targetConn, err := pglib.NewConn(ctx, targetPGURL)
+	require.NoError(t, err)
+	defer targetConn.Close(ctx)
+
+	suffix := time.Now().UnixNano()
+	parentTable := fmt.Sprintf("pg2pg_events_%d", suffix)
+	partitionTable := fmt.Sprintf("pg2pg_events_2024_%d", suffix)
+
+	defer execQuery(t, ctx, fmt.Sprintf("drop table if exists %s cascade", parentTable))
+	defer execQuery(t, ctx, fmt.Sprintf("drop table if exists %s cascade", partitionTable))
+
+	execQuery(t, ctx, fmt.Sprintf("create table %s (id int, year int) partition by list (year)", parentTable))
+	execQuery(t, ctx, fmt.Sprintf("create table %s (id int, year int)", partitionTable))
+	execQuery(t, ctx, fmt.Sprintf("alter table %s attach partition %s for values in (2024)", parentTable, partitionTable))
+	execQuery(t, ctx, fmt.Sprintf("comment on table %s is 'events of 2024'", partitionTable))
+	execQuery(t, ctx, fmt.Sprintf("comment on column %s.year is 'event year'", parentTable))
+
+	require.Eventually(t, func() bool {
+		var attached bool
+		if err := targetConn.QueryRow(ctx, []any{&attached},
+			"SELECT EXISTS(SELECT 1 FROM pg_inherits WHERE inhrelid = to_regclass($1) AND inhparent = to_regclass($2))",
+			partitionTable, parentTable); err != nil || !attached {
+			return false
+		}
+
+		var tableComment, columnComment *string
+		if err := targetConn.QueryRow(ctx, []any{&tableComment, &columnComment},
+			"SELECT obj_description(to_regclass($1), 'pg_class'), col_description(to_regclass($2), 2)",
+			partitionTable, parentTable); err != nil {
+			return false
+		}
+		return tableComment != nil && *tableComment == "events of 2024" &&
+			columnComment != nil && *columnComment == "event year"
+	}, 20*time.Second, 200*time.Millisecond)
+}
@@ -6,7 +6,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	pgsnapshotgenerator "github.com/xataio/pgstream/pkg/snapshot/generator/postgres/data"
+	snapshotbuilder "github.com/xataio/pgstream/pkg/wal/listener/snapshot/builder"
 	pgwriter "github.com/xataio/pgstream/pkg/wal/processor/postgres"
+	"github.com/xataio/pgstream/pkg/wal/processor/transformer"
 )
 
 func TestConfig_restoreConflictTargetsBeforeData(t *testing.T) {
@@ -16,6 +19,7 @@ func TestConfig_restoreConflictTargetsBeforeData(t *testing.T) {
 		name             string
 		onConflictAction string
 		bulkIngest       bool
+		bulkIngestMode   string
 		noPostgres       bool
 
 		want bool
@@ -30,6 +34,12 @@ func TestConfig_restoreConflictTargetsBeforeData(t *testing.T) {
 			onConflictAction: "update",
 			bulkIngest:       true,
 		},
+		{
+			name:           "bulk ingest in merge mode restores constraints before data",
+			bulkIngest:     true,
+			bulkIngestMode: "merge",
+			want:           true,
+		},
 		{
 			name:             "do nothing keeps default order",
 			onConflictAction: "nothing",
@@ -55,6 +65,7 @@ func TestConfig_restoreConflictTargetsBeforeData(t *testing.T) {
 					BatchWriter: pgwriter.Config{
 						OnConflictAction:  tc.onConflictAction,
 						BulkIngestEnabled: tc.bulkIngest,
+						BulkIngestMode:    tc.bulkIngestMode,
 					},
 				}
 			}
@@ -63,3 +74,67 @@ func TestConfig_restoreConflictTargetsBeforeData(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_snapshotConfig(t *testing.T) {
+	t.Parallel()
+
+	subsetRules := []pgsnapshotgenerator.SubsetRule{{Table: "users", Percent: 10}}
+	newSnapshotConfig := func() *snapshotbuilder.SnapshotListenerConfig {
+		return &snapshotbuilder.SnapshotListenerConfig{
+			Data: &pgsnapshotgenerator.Config{
+				Subset: &pgsnapshotgenerator.SubsetConfig{Rules: subsetRules},
+			},
+		}
+	}
+	transformerConfig := &transformer.Config{
+		TransformerRules: []transformer.TableRules{
+			{
+				Table: "users",
+				ColumnRules: map[string]transformer.TransformerRules{
+					"email": {Name: "neosync_email"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		snapshotCfg *snapshotbuilder.SnapshotListenerConfig
+		transformer *transformer.Config
+
+		wantTransformedColumns map[string]string
+	}{
+		{
+			name:        "subset with transformer rules",
+			snapshotCfg: newSnapshotConfig(),
+			transformer: transformerConfig,
+
+			wantTransformedColumns: map[string]string{
+				"public.users.email": `{"Name":"neosync_email","Parameters":null,"DynamicParameters":null}`,
+			},
+		},
+		{
+			name:        "subset without transformer rules",
+			snapshotCfg: newSnapshotConfig(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := &Config{
+				Listener: ListenerConfig{
+					Postgres: &PostgresListenerConfig{Snapshot: tc.snapshotCfg},
+				},
+				Processor: ProcessorConfig{Transformer: tc.transformer},
+			}
+
+			got := config.snapshotConfig()
+			require.Equal(t, tc.wantTransformedColumns, got.Data.Subset.TransformedColumns)
+			require.Equal(t, subsetRules, got.Data.Subset.Rules)
+			// the stream configuration is left untouched
+			require.Equal(t, newSnapshotConfig(), tc.snapshotCfg)
+		})
+	}
+}
@@ -9,19 +9,26 @@ import (
 
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/otel"
+	"github.com/xataio/pgstream/pkg/snapshot/generator/postgres/schema/pgdumprestore"
 	"github.com/xataio/pgstream/pkg/transformers/builder"
 	"github.com/xataio/pgstream/pkg/wal/checkpointer"
 	"github.com/xataio/pgstream/pkg/wal/processor"
+	"github.com/xataio/pgstream/pkg/wal/processor/ddlpolicy"
+	filewriter "github.com/xataio/pgstream/pkg/wal/processor/file"
 	"github.com/xataio/pgstream/pkg/wal/processor/filter"
+	"github.com/xataio/pgstream/pkg/wal/processor/incremental"
 	"github.com/xataio/pgstream/pkg/wal/processor/injector"
 	processinstrumentation "github.com/xataio/pgstream/pkg/wal/processor/instrumentation"
 	kafkaprocessor "github.com/xataio/pgstream/pkg/wal/processor/kafka"
+	"github.com/xataio/pgstream/pkg/wal/processor/merger"
+	"github.com/xataio/pgstream/pkg/wal/processor/partition"
 	pgwriter "github.com/xataio/pgstream/pkg/wal/processor/postgres"
 	"github.com/xataio/pgstream/pkg/wal/processor/sanitizer"
 	"github.com/xataio/pgstream/pkg/wal/processor/search"
 	searchinstrumentation "github.com/xataio/pgstream/pkg/wal/processor/search/instrumentation"
 	"github.com/xataio/pgstream/pkg/wal/processor/search/store"
 	stdoutwriter "github.com/xataio/pgstream/pkg/wal/processor/stdout"
+	"github.com/xataio/pgstream/pkg/wal/processor/toast"
 	"github.com/xataio/pgstream/pkg/wal/processor/transformer"
 	webhooknotifier "github.com/xataio/pgstream/pkg/wal/processor/webhook/notifier"
 	subscriptionserver "github.com/xataio/pgstream/pkg/wal/processor/webhook/subscription/server"
@@ -154,14 +161,29 @@ func buildProcessor(ctx context.Context, logger loglib.Logger, config *Processor
 			opts = append(opts, pgwriter.WithInstrumentation(instrumentation))
 		}
 
-		if processorType == processorTypeSnapshot && config.Postgres.BatchWriter.BulkIngestEnabled {
+		bulkMergeEnabled, err := config.Postgres.BatchWriter.IsBulkMergeEnabled()
+		if err != nil {
+			return nil, fmt.Errorf("target postgres: %w", err)
+		}
+
+		switch {
+		case bulkMergeEnabled:
+			logger.Info("postgres bulk merge writer enabled")
+			opts := append(opts, pgwriter.WithCheckpoint(checkpoint))
+			bulkMergeWriter, err := pgwriter.NewBulkMergeWriter(ctx, &config.Postgres.BatchWriter, opts...)
+			if err != nil {
+				return nil, fmt.Errorf("target postgres: %w", err)
+			}
+			processor = bulkMergeWriter
+		case processorType == processorTypeSnapshot && config.Postgres.BatchWriter.BulkIngestEnabled:
 			logger.Info("postgres bulk ingest writer enabled")
+			opts := append(opts, pgwriter.WithCheckpoint(checkpoint))
 			bulkIngestWriter, err := pgwriter.NewBulkIngestWriter(ctx, &config.Postgres.BatchWriter, opts...)
 			if err != nil {
 				return nil, err
 			}
 			processor = bulkIngestWriter
-		} else {
+		default:
 			opts := append(opts, pgwriter.WithCheckpoint(checkpoint))
 			pgBatchWriter, err := pgwriter.NewBatchWriter(ctx, &config.Postgres.BatchWriter, opts...)
 			if err != nil {
@@ -171,6 +193,23 @@ func buildProcessor(ctx context.Context, logger loglib.Logger, config *Processor
 			processor = pgBatchWriter
 		}
 
+	case config.Audit != nil:
+		logger.Info("postgres audit processor configured")
+
+		opts := []pgwriter.WriterOption{
+			pgwriter.WithLogger(logger),
+			pgwriter.WithCheckpoint(checkpoint),
+		}
+		if instrumentation.IsEnabled() {
+			opts = append(opts, pgwriter.WithInstrumentation(instrumentation))
+		}
+
+		auditWriter, err := pgwriter.NewAuditWriter(ctx, &config.Audit.Writer, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("target audit: %w", err)
+		}
+		processor = auditWriter
+
 	case config.Stdout != nil:
 		logger.Info("stdout processor configured")
 		processor = stdoutwriter.NewWriter(
@@ -178,6 +217,18 @@ func buildProcessor(ctx context.Context, logger loglib.Logger, config *Processor
 			stdoutwriter.WithCheckpoint(checkpoint),
 		)
 
+	case config.File != nil:
+		logger.Info("file processor configured")
+		// the snapshot archive only holds the snapshot inserts
+		if processorType != processorTypeSnapshot {
+			return nil, errors.New("target file: only supported for snapshots")
+		}
+		fileWriter, err := filewriter.NewWriter(&config.File.Writer, filewriter.WithLogger(logger))
+		if err != nil {
+			return nil, fmt.Errorf("target file: %w", err)
+		}
+		processor = fileWriter
+
 	default:
 		return nil, errors.New("no supported processor found")
 	}
@@ -185,10 +236,20 @@ func buildProcessor(ctx context.Context, logger loglib.Logger, config *Processor
 	return processor, nil
 }
 
-func addProcessorModifiers(ctx context.Context, config *Config, logger loglib.Logger, processor processor.Processor, instrumentation *otel.Instrumentation) (processor.Processor, closerFn, error) {
+func addProcessorModifiers(ctx context.Context, config *Config, logger loglib.Logger, processor processor.Processor, keepAlive func(context.Context) error, processorType processorType, instrumentation *otel.Instrumentation) (processor.Processor, closerFn, error) {
 	closerAgg := &closerAggregator{}
 	var err error
 
+	// the merger needs to be the innermost layer, since the rest of modifiers
+	// are configured with the source table names
+	if config.Processor.Merger != nil {
+		logger.Info("adding table merging to processor...")
+		processor, err = merger.New(processor, config.Processor.Merger, merger.WithLogger(logger))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating processor merger layer: %w", err)
+		}
+	}
+
 	if config.Processor.Sanitize != nil && config.Processor.Sanitize.StripNullCharBytes {
 		logger.Info("adding null byte sanitizer to processor...")
 		processor = sanitizer.New(processor, sanitizer.WithLogger(logger))
@@ -251,6 +312,28 @@ func addProcessorModifiers(ctx context.Context, config *Config, logger loglib.Lo
 		}
 	}
 
+	// the unchanged TOAST columns need to be handled before the injector and
+	// the transformer, so that the fetched values are processed too
+	if config.Processor.Toast != nil {
+		logger.Info("adding unchanged TOAST handling to processor...")
+		processor, err = toast.New(ctx, processor, config.Processor.Toast, toast.WithLogger(logger))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating processor toast layer: %w", err)
+		}
+	}
+
+	if config.Processor.DDLPolicy != nil {
+		logger.Info("adding DDL policy to processor...")
+		opts := []ddlpolicy.Option{ddlpolicy.WithLogger(logger)}
+		if keepAlive != nil {
+			opts = append(opts, ddlpolicy.WithKeepAlive(keepAlive))
+		}
+		processor, err = ddlpolicy.New(ctx, processor, config.Processor.DDLPolicy, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating processor DDL policy layer: %w", err)
+		}
+	}
+
 	if config.Processor.Filter != nil {
 		logger.Info("adding filtering to processor...")
 		var err error
@@ -261,6 +344,44 @@ func addProcessorModifiers(ctx context.Context, config *Config, logger loglib.Lo
 		}
 	}
 
+	// the incremental snapshots need to receive the signals and watermarks
+	// before they're filtered out, and emit the snapshotted rows through the
+	// rest of modifiers. They rely on the replication stream, so they're not
+	// added to the snapshot processor.
+	if config.Processor.IncrementalSnapshot != nil && processorType == processorTypeReplication {
+		logger.Info("adding incremental snapshots to processor...")
+		opts := []incremental.Option{incremental.WithLogger(logger)}
+		if schemaCfg := config.incrementalSchemaSnapshotConfig(); schemaCfg != nil {
+			schemaOpts := []pgdumprestore.Option{pgdumprestore.WithLogger(logger)}
+			if schemaCfg.TargetPGURL == "" {
+				// without a postgres target, the schema is restored as DDL
+				// events through the processor
+				schemaOpts = append(schemaOpts, pgdumprestore.WithRestoreToWAL(processor))
+			}
+			schemaGenerator, err := pgdumprestore.NewSnapshotGenerator(ctx, schemaCfg, schemaOpts...)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error creating incremental snapshot schema generator: %w", err)
+			}
+			opts = append(opts, incremental.WithSchemaSnapshotGenerator(schemaGenerator))
+		}
+		processor, err = incremental.New(ctx, processor, config.incrementalSnapshotConfig(), opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating processor incremental snapshot layer: %w", err)
+		}
+	}
+
+	// the partitions need to be mapped to their root table before any other
+	// modifier, so that they all see the root table
+	if config.publishViaPartitionRoot() {
+		logger.Info("adding partition mapping to processor...")
+		processor, err = partition.New(ctx, processor, &partition.Config{
+			URL: config.SourcePostgresURL(),
+		}, partition.WithLogger(logger))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating processor partition mapping layer: %w", err)
+		}
+	}
+
 	if processor != nil && instrumentation.IsEnabled() {
 		var err error
 		processor, err = processinstrumentation.NewProcessor(processor, instrumentation)
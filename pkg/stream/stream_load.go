@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/otel"
+	"github.com/xataio/pgstream/pkg/snapshot/archive"
+	"github.com/xataio/pgstream/pkg/wal"
+	pgwriter "github.com/xataio/pgstream/pkg/wal/processor/postgres"
+)
+
+// LoadConfig is the configuration to load a snapshot archive into a postgres
+// target.
+type LoadConfig struct {
+	// Dir is the snapshot archive directory
+	Dir string
+	// Postgres is the configuration of the bulk ingest writer used to load the
+	// table data into the target
+	Postgres pgwriter.Config
+}
+
+var errLoadedRowsMismatch = errors.New("loaded rows don't match the snapshot archive")
+
+// Load restores the snapshot archive in the configured directory into the
+// postgres target. The archive checksums are verified before anything is
+// restored. The pre data schema is restored first, then the table data using
+// the bulk ingest writer, and finally the post data schema, with the sequence
+// values, indices, constraints and views.
+func Load(ctx context.Context, logger loglib.Logger, config *LoadConfig, instrumentation *otel.Instrumentation) error {
+	if config.Postgres.URL == "" {
+		return errors.New("target postgres URL required to load a snapshot archive")
+	}
+
+	manifest, err := archive.ReadManifest(config.Dir)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("verifying snapshot archive", loglib.Fields{"dir": config.Dir, "tables": len(manifest.Tables)})
+	if err := manifest.Verify(config.Dir); err != nil {
+		return err
+	}
+
+	if err := loadSchema(ctx, logger, config, manifest, archive.PreDataPhase); err != nil {
+		return err
+	}
+
+	if err := loadData(ctx, logger, config, manifest, instrumentation); err != nil {
+		return err
+	}
+
+	return loadSchema(ctx, logger, config, manifest, archive.PostDataPhase)
+}
+
+func loadSchema(ctx context.Context, logger loglib.Logger, config *LoadConfig, manifest *archive.Manifest, phase archive.SchemaPhase) error {
+	schemaFile := manifest.SchemaFile(phase)
+	if schemaFile == nil {
+		return nil
+	}
+
+	f, err := archive.Open(config.Dir, schemaFile.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dump, err := io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", schemaFile.Path, err)
+	}
+
+	logger.Info("loading schema", loglib.Fields{"phase": phase, "file": schemaFile.Path})
+	_, err = pglib.RunNativePGRestore(ctx, pglib.PGRestoreOptions{ConnectionString: config.Postgres.URL}, dump)
+	if err != nil {
+		pgrestoreErr := &pglib.PGRestoreErrors{}
+		if !errors.As(err, &pgrestoreErr) || pgrestoreErr.HasCriticalErrors() {
+			return fmt.Errorf("loading %s: %w", schemaFile.Path, err)
+		}
+		ignoredErrors := pgrestoreErr.GetIgnoredErrors()
+		logger.Warn(err, fmt.Sprintf("load schema: %d errors ignored", len(ignoredErrors)), loglib.Fields{"errors_ignored": ignoredErrors})
+	}
+	return nil
+}
+
+func loadData(ctx context.Context, logger loglib.Logger, config *LoadConfig, manifest *archive.Manifest, instrumentation *otel.Instrumentation) error {
+	if len(manifest.Tables) == 0 {
+		return nil
+	}
+
+	conn, err := pglib.NewConnPool(ctx, config.Postgres.URL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	// the rows are counted before and after loading them, so that any batch
+	// that failed to be written is not silently lost
+	rowsBefore := make([]int64, 0, len(manifest.Tables))
+	for _, table := range manifest.Tables {
+		rows, err := countTableRows(ctx, conn, table)
+		if err != nil {
+			return err
+		}
+		rowsBefore = append(rowsBefore, rows)
+	}
+
+	opts := []pgwriter.WriterOption{
+		pgwriter.WithLogger(logger),
+		pgwriter.WithTextCopy(),
+	}
+	if instrumentation.IsEnabled() {
+		opts = append(opts, pgwriter.WithInstrumentation(instrumentation))
+	}
+	writer, err := pgwriter.NewBulkIngestWriter(ctx, &config.Postgres, opts...)
+	if err != nil {
+		return fmt.Errorf("target postgres: %w", err)
+	}
+
+	for _, table := range manifest.Tables {
+		logger.Info("loading table data", loglib.Fields{"schema": table.Schema, "table": table.Table, "rows": table.Rows})
+		if err := loadTable(ctx, writer, config.Dir, manifest.Format, table); err != nil {
+			writer.Close()
+			return err
+		}
+	}
+
+	// closing the writer flushes the pending batches
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	for i, table := range manifest.Tables {
+		rows, err := countTableRows(ctx, conn, table)
+		if err != nil {
+			return err
+		}
+		if loaded := rows - rowsBefore[i]; loaded != table.Rows {
+			return fmt.Errorf("%w: table %s.%s has %d new rows, expected %d", errLoadedRowsMismatch, table.Schema, table.Table, loaded, table.Rows)
+		}
+	}
+
+	return nil
+}
+
+func loadTable(ctx context.Context, writer *pgwriter.BulkIngestWriter, dir string, format archive.Format, table *archive.TableFile) error {
+	f, err := archive.Open(dir, table.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := archive.NewRowReader(f, format)
+	for {
+		values, err := reader.ReadRow()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("reading %s: %w", table.Path, err)
+		}
+
+		event, err := tableRowEvent(table, values)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", table.Path, err)
+		}
+		if err := writer.ProcessWALEvent(ctx, event); err != nil {
+			return fmt.Errorf("loading %s: %w", table.Path, err)
+		}
+	}
+}
+
+// tableRowEvent returns the insert event for the data file row on input. The
+// column values are kept in their postgres text representation.
+func tableRowEvent(table *archive.TableFile, values []*string) (*wal.Event, error) {
+	if len(values) != len(table.Columns) {
+		return nil, fmt.Errorf("row has %d values, expected %d", len(values), len(table.Columns))
+	}
+
+	columns := make([]wal.Column, 0, len(values))
+	for i, v := range values {
+		column := wal.Column{
+			Name: table.Columns[i].Name,
+			Type: table.Columns[i].Type,
+		}
+		if v != nil {
+			column.Value = *v
+		}
+		columns = append(columns, column)
+	}
+
+	return &wal.Event{
+		Data: &wal.Data{
+			Action:  "I",
+			Schema:  table.Schema,
+			Table:   table.Table,
+			Columns: columns,
+		},
+		CommitPosition: wal.CommitPosition(wal.ZeroLSN),
+	}, nil
+}
+
+func countTableRows(ctx context.Context, conn pglib.Querier, table *archive.TableFile) (int64, error) {
+	var rows int64
+	query := fmt.Sprintf("SELECT count(*) FROM %s", pglib.QuoteQualifiedIdentifier(table.Schema, table.Table))
+	if err := conn.QueryRow(ctx, []any{&rows}, query); err != nil {
+		return 0, fmt.Errorf("counting rows of table %s.%s: %w", table.Schema, table.Table, err)
+	}
+	return rows, nil
+}
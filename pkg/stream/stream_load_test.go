@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/snapshot/archive"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestTableRowEvent(t *testing.T) {
+	t.Parallel()
+
+	table := &archive.TableFile{
+		Schema: "public",
+		Table:  "users",
+		Columns: []archive.Column{
+			{Name: "id", Type: "int4"},
+			{Name: "name", Type: "text"},
+		},
+	}
+	id := "1"
+
+	tests := []struct {
+		name   string
+		values []*string
+
+		wantEvent *wal.Event
+		wantErr   bool
+	}{
+		{
+			name:   "ok",
+			values: []*string{&id, nil},
+
+			wantEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "I",
+					Schema: "public",
+					Table:  "users",
+					Columns: []wal.Column{
+						{Name: "id", Type: "int4", Value: "1"},
+						{Name: "name", Type: "text", Value: nil},
+					},
+				},
+				CommitPosition: wal.CommitPosition(wal.ZeroLSN),
+			},
+		},
+		{
+			name:   "error - unexpected number of values",
+			values: []*string{&id},
+
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			event, err := tableRowEvent(table, tc.values)
+			require.Equal(t, tc.wantErr, err != nil)
+			require.Equal(t, tc.wantEvent, event)
+		})
+	}
+}
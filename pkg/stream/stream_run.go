@@ -11,6 +11,7 @@ import (
 	kafkainstrumentation "github.com/xataio/pgstream/pkg/kafka/instrumentation"
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/otel"
+	pgsnapshotgenerator "github.com/xataio/pgstream/pkg/snapshot/generator/postgres/data"
 	"github.com/xataio/pgstream/pkg/wal/checkpointer"
 	kafkacheckpoint "github.com/xataio/pgstream/pkg/wal/checkpointer/kafka"
 	pgcheckpoint "github.com/xataio/pgstream/pkg/wal/checkpointer/postgres"
@@ -42,14 +43,18 @@ func Run(ctx context.Context, logger loglib.Logger, config *Config, init bool, i
 	eg, ctx := errgroup.WithContext(ctx)
 
 	var replicationHandler replication.Handler
+	// keepAlive keeps the replication connection alive while the processing
+	// is paused, when replicating from postgres
+	var keepAlive func(context.Context) error
 	if config.Listener.Postgres != nil && config.Listener.Postgres.Replication.PostgresURL != "" {
-		var err error
-		replicationHandler, err = pgreplication.NewHandler(ctx,
-			config.Listener.Postgres.Replication,
+		pgReplicationHandler, err := pgreplication.NewHandler(ctx,
+			config.replicationConfig(),
 			pgreplication.WithLogger(logger))
 		if err != nil {
 			return fmt.Errorf("error setting up postgres replication handler: %w", err)
 		}
+		replicationHandler = pgReplicationHandler
+		keepAlive = pgReplicationHandler.KeepAlive
 		defer replicationHandler.Close()
 		// if retries are not explicitly disabled, wrap the replication handler
 		// with a retrier, which will apply default retry policy if none is set
@@ -111,7 +116,7 @@ func Run(ctx context.Context, logger loglib.Logger, config *Config, init bool, i
 
 	// Processor
 
-	processor, closer, err := newProcessor(ctx, logger, config, checkpoint, processorTypeReplication, instrumentation)
+	processor, closer, err := newProcessor(ctx, logger, config, checkpoint, keepAlive, processorTypeReplication, instrumentation)
 	defer closer()
 	if err != nil {
 		return err
@@ -131,7 +136,10 @@ func Run(ctx context.Context, logger loglib.Logger, config *Config, init bool, i
 			// use a dedicated processor for the snapshot phase, to be able to
 			// close it and make sure the snapshot is complete before starting
 			// to process the WAL replication events.
-			snapshotProcessor, snapshotCloser, err := newProcessor(ctx, logger, config, checkpoint, processorTypeSnapshot, instrumentation)
+			// the snapshot processor notifies when the snapshot rows have
+			// been flushed, so that the snapshot progress can be checkpointed
+			flushNotifier := pgsnapshotgenerator.NewFlushNotifier(checkpoint)
+			snapshotProcessor, snapshotCloser, err := newProcessor(ctx, logger, config, flushNotifier.Checkpoint, nil, processorTypeSnapshot, instrumentation)
 			defer snapshotCloser()
 			if err != nil {
 				return fmt.Errorf("error creating snapshot processor: %w", err)
@@ -139,8 +147,9 @@ func Run(ctx context.Context, logger loglib.Logger, config *Config, init bool, i
 
 			snapshotGenerator, err := snapshotbuilder.NewSnapshotGenerator(
 				ctx,
-				config.Listener.Postgres.Snapshot,
+				config.snapshotConfig(),
 				snapshotProcessor,
+				flushNotifier,
 				logger,
 				instrumentation,
 				config.restoreConflictTargetsBeforeData())
@@ -188,14 +197,14 @@ var noopCloser func() error = func() error {
 	return nil
 }
 
-func newProcessor(ctx context.Context, logger loglib.Logger, config *Config, checkpoint checkpointer.Checkpoint, processorType processorType, instrumentation *otel.Instrumentation) (processor.Processor, closerFn, error) {
+func newProcessor(ctx context.Context, logger loglib.Logger, config *Config, checkpoint checkpointer.Checkpoint, keepAlive func(context.Context) error, processorType processorType, instrumentation *otel.Instrumentation) (processor.Processor, closerFn, error) {
 	processor, err := buildProcessor(ctx, logger, &config.Processor, checkpoint, processorType, instrumentation)
 	if err != nil {
 		return nil, noopCloser, err
 	}
 	var closerAgg closerAggregator
 	var closer closerFn
-	processor, closer, err = addProcessorModifiers(ctx, config, logger, processor, instrumentation)
+	processor, closer, err = addProcessorModifiers(ctx, config, logger, processor, keepAlive, processorType, instrumentation)
 	if err != nil {
 		return nil, noopCloser, err
 	}
@@ -9,8 +9,10 @@ import (
 
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/otel"
+	pgsnapshotgenerator "github.com/xataio/pgstream/pkg/snapshot/generator/postgres/data"
 	snapshotlistener "github.com/xataio/pgstream/pkg/wal/listener/snapshot"
 	snapshotbuilder "github.com/xataio/pgstream/pkg/wal/listener/snapshot/builder"
+	filewriter "github.com/xataio/pgstream/pkg/wal/processor/file"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -27,14 +29,21 @@ func Snapshot(ctx context.Context, logger loglib.Logger, config *Config, instrum
 
 	// Processor
 
-	processor, err := buildProcessor(ctx, logger, &config.Processor, nil, processorTypeSnapshot, instrumentation)
+	// the processor notifies when the snapshot rows have been flushed, so that
+	// the snapshot progress can be checkpointed
+	flushNotifier := pgsnapshotgenerator.NewFlushNotifier(nil)
+	processor, err := buildProcessor(ctx, logger, &config.Processor, flushNotifier.Checkpoint, processorTypeSnapshot, instrumentation)
 	if err != nil {
 		return err
 	}
 	defer processor.Close()
 
+	// the snapshot archive manifest is only written once the snapshot has
+	// completed successfully
+	fileWriter, _ := processor.(*filewriter.Writer)
+
 	var closer closerFn
-	processor, closer, err = addProcessorModifiers(ctx, config, logger, processor, instrumentation)
+	processor, closer, err = addProcessorModifiers(ctx, config, logger, processor, nil, processorTypeSnapshot, instrumentation)
 	if err != nil {
 		return err
 	}
@@ -44,8 +53,9 @@ func Snapshot(ctx context.Context, logger loglib.Logger, config *Config, instrum
 
 	snapshotGenerator, err := snapshotbuilder.NewSnapshotGenerator(
 		ctx,
-		config.Listener.Postgres.Snapshot,
+		config.snapshotConfig(),
 		processor,
+		flushNotifier,
 		logger,
 		instrumentation,
 		config.restoreConflictTargetsBeforeData())
@@ -65,6 +75,11 @@ func Snapshot(ctx context.Context, logger loglib.Logger, config *Config, instrum
 		if !errors.Is(err, context.Canceled) {
 			return err
 		}
+		return nil
+	}
+
+	if fileWriter != nil {
+		return fileWriter.WriteManifest()
 	}
 
 	return nil
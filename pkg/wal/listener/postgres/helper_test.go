@@ -52,9 +52,9 @@ func newMockLSNParser() *replicationmocks.LSNParser {
 }
 
 type mockGenerator struct {
-	createSnapshotFn func(context.Context) error
+	createSnapshotFn func(ctx context.Context, lsn string) (string, error)
 }
 
-func (m *mockGenerator) CreateSnapshot(ctx context.Context) error {
-	return m.createSnapshotFn(ctx)
+func (m *mockGenerator) CreateSnapshotFromLSN(ctx context.Context, lsn string) (string, error) {
+	return m.createSnapshotFn(ctx, lsn)
 }
@@ -25,8 +25,23 @@ type Listener struct {
 	processEvent listenerProcessWalEvent
 
 	walDataDeserialiser func([]byte, any) error
+
+	// commitLSN is the commit LSN of the transaction being received, when
+	// the transaction boundaries are included in the replication stream
+	commitLSN string
+}
+
+// transactionBegin is the begin message of a transaction, sent when the
+// transaction boundaries are included in the replication stream.
+type transactionBegin struct {
+	NextLSN string `json:"nextlsn"`
 }
 
+const (
+	beginAction  = "B"
+	commitAction = "C"
+)
+
 type replicationHandler interface {
 	StartReplication(ctx context.Context) error
 	StartReplicationFromLSN(ctx context.Context, lsn replication.LSN) error
@@ -37,7 +52,9 @@ type replicationHandler interface {
 }
 
 type snapshotGenerator interface {
-	CreateSnapshot(context.Context) error
+	// CreateSnapshotFromLSN creates the snapshot ahead of replicating from the
+	// LSN on input, and returns the LSN the replication needs to start from.
+	CreateSnapshotFromLSN(ctx context.Context, lsn string) (string, error)
 }
 
 // listenerProcessWalEvent is the function type callback to process WAL events.
@@ -102,9 +119,17 @@ func (l *Listener) snapshotAndListen(ctx context.Context) error {
 		return err
 	}
 
-	if err := l.snapshotGenerator.CreateSnapshot(ctx); err != nil {
+	startLSN, err := l.snapshotGenerator.CreateSnapshotFromLSN(ctx, l.lsnParser.ToString(lsn))
+	if err != nil {
 		return err
 	}
+	// a resumed snapshot might need replication to start from the position
+	// of its first run, so that the changes made since are replayed
+	if startLSN != "" {
+		if lsn, err = l.lsnParser.FromString(startLSN); err != nil {
+			return fmt.Errorf("parsing snapshot start LSN %s: %w", startLSN, err)
+		}
+	}
 
 	if err := l.replicationHandler.StartReplicationFromLSN(ctx, lsn); err != nil {
 		return fmt.Errorf("start replication from LSN %s: %w", l.lsnParser.ToString(lsn), err)
@@ -157,6 +182,22 @@ func (l *Listener) processWALEvent(ctx context.Context, msg *replication.Message
 		if err := l.walDataDeserialiser(msg.Data, event.Data); err != nil {
 			return fmt.Errorf("error unmarshaling wal data: %w", err)
 		}
+
+		// the transaction boundaries are only used to stamp the changes with
+		// their commit LSN, they're not processed
+		switch event.Data.Action {
+		case beginAction:
+			begin := &transactionBegin{}
+			if err := l.walDataDeserialiser(msg.Data, begin); err != nil {
+				return fmt.Errorf("error unmarshaling transaction begin: %w", err)
+			}
+			l.commitLSN = begin.NextLSN
+			return nil
+		case commitAction:
+			l.commitLSN = ""
+			return nil
+		}
+		event.Data.CommitLSN = l.commitLSN
 	}
 	event.CommitPosition = wal.CommitPosition(l.lsnParser.ToString(msg.LSN))
 
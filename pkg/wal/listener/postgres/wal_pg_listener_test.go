@@ -4,6 +4,7 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
@@ -52,6 +53,7 @@ func TestListener_Listen(t *testing.T) {
 		processEventFn     listenerProcessWalEvent
 		generator          func(doneChan chan struct{}) snapshotGenerator
 		deserialiser       func([]byte, any) error
+		lsnParser          replication.LSNParser
 
 		wantErr error
 	}{
@@ -107,7 +109,7 @@ func TestListener_Listen(t *testing.T) {
 			processEventFn: okProcessEvent,
 			generator: func(_ chan struct{}) snapshotGenerator {
 				return &mockGenerator{
-					createSnapshotFn: func(ctx context.Context) error { return nil },
+					createSnapshotFn: func(ctx context.Context, lsn string) (string, error) { return lsn, nil },
 				}
 			},
 
@@ -238,7 +240,9 @@ func TestListener_Listen(t *testing.T) {
 			processEventFn: okProcessEvent,
 			generator: func(doneChan chan struct{}) snapshotGenerator {
 				return &mockGenerator{
-					createSnapshotFn: func(ctx context.Context) error { return errors.New("createSnapshotFn: should not be called") },
+					createSnapshotFn: func(ctx context.Context, lsn string) (string, error) {
+						return "", errors.New("createSnapshotFn: should not be called")
+					},
 				}
 			},
 
@@ -262,15 +266,45 @@ func TestListener_Listen(t *testing.T) {
 			processEventFn: okProcessEvent,
 			generator: func(doneChan chan struct{}) snapshotGenerator {
 				return &mockGenerator{
-					createSnapshotFn: func(ctx context.Context) error {
+					createSnapshotFn: func(ctx context.Context, lsn string) (string, error) {
 						defer func() { doneChan <- struct{}{} }()
-						return errTest
+						return "", errTest
 					},
 				}
 			},
 
 			wantErr: errTest,
 		},
+		{
+			name: "ok - with resumed initial snapshot",
+			replicationHandler: func(doneChan chan struct{}) *replicationmocks.Handler {
+				h := newMockReplicationHandler()
+				h.StartReplicationFromLSNFn = func(ctx context.Context, lsn replication.LSN) error {
+					defer func() { doneChan <- struct{}{} }()
+					require.Equal(t, replication.LSN(1), lsn)
+					return nil
+				}
+				return h
+			},
+			processEventFn: okProcessEvent,
+			generator: func(_ chan struct{}) snapshotGenerator {
+				return &mockGenerator{
+					createSnapshotFn: func(ctx context.Context, lsn string) (string, error) {
+						require.Equal(t, testLSNStr, lsn)
+						return "0/1", nil
+					},
+				}
+			},
+			lsnParser: &replicationmocks.LSNParser{
+				ToStringFn: func(replication.LSN) string { return testLSNStr },
+				FromStringFn: func(s string) (replication.LSN, error) {
+					require.Equal(t, "0/1", s)
+					return replication.LSN(1), nil
+				},
+			},
+
+			wantErr: context.Canceled,
+		},
 		{
 			name: "error - starting replication from LSN after initial snapshot",
 			replicationHandler: func(doneChan chan struct{}) *replicationmocks.Handler {
@@ -289,7 +323,7 @@ func TestListener_Listen(t *testing.T) {
 			},
 			processEventFn: okProcessEvent,
 			generator: func(doneChan chan struct{}) snapshotGenerator {
-				return &mockGenerator{createSnapshotFn: func(ctx context.Context) error { return nil }}
+				return &mockGenerator{createSnapshotFn: func(ctx context.Context, lsn string) (string, error) { return lsn, nil }}
 			},
 
 			wantErr: errTest,
@@ -351,6 +385,9 @@ func TestListener_Listen(t *testing.T) {
 			l := New(replicationHandler, tc.processEventFn, opts...)
 			l.walDataDeserialiser = testDeserialiser
 			l.lsnParser = newMockLSNParser()
+			if tc.lsnParser != nil {
+				l.lsnParser = tc.lsnParser
+			}
 			defer l.Close()
 
 			if tc.deserialiser != nil {
@@ -385,3 +422,36 @@ func TestListener_Listen(t *testing.T) {
 		})
 	}
 }
+
+func TestListener_processWALEvent_transactionBoundaries(t *testing.T) {
+	t.Parallel()
+
+	events := []*wal.Event{}
+	l := &Listener{
+		logger:              log.NewNoopLogger(),
+		lsnParser:           newMockLSNParser(),
+		walDataDeserialiser: json.Unmarshal,
+		processEvent: func(_ context.Context, e *wal.Event) error {
+			events = append(events, e)
+			return nil
+		},
+	}
+
+	msgs := []string{
+		`{"action":"B","nextlsn":"0/16B3800"}`,
+		`{"action":"I","schema":"public","table":"users"}`,
+		`{"action":"C","nextlsn":"0/16B3800"}`,
+		`{"action":"I","schema":"public","table":"orders"}`,
+	}
+	for _, msg := range msgs {
+		err := l.processWALEvent(context.Background(), &replication.Message{LSN: testLSN, Data: []byte(msg)})
+		require.NoError(t, err)
+	}
+
+	// the transaction boundaries are not processed, and only the changes
+	// within them are stamped with the commit LSN
+	require.Equal(t, []*wal.Event{
+		{Data: &wal.Data{Action: "I", Schema: "public", Table: "users", CommitLSN: "0/16B3800"}, CommitPosition: testLSNStr},
+		{Data: &wal.Data{Action: "I", Schema: "public", Table: "orders"}, CommitPosition: testLSNStr},
+	}, events)
+}
@@ -44,7 +44,16 @@ func WithLogger(logger loglib.Logger) Option {
 	}
 }
 
-func (s *SnapshotGeneratorAdapter) CreateSnapshot(ctx context.Context) (err error) {
+func (s *SnapshotGeneratorAdapter) CreateSnapshot(ctx context.Context) error {
+	_, err := s.CreateSnapshotFromLSN(ctx, "")
+	return err
+}
+
+// CreateSnapshotFromLSN creates the snapshot ahead of replicating from the LSN
+// on input, and returns the LSN replication needs to be started from. It's
+// earlier than the one on input when the data snapshot is resumed from table
+// checkpoints recorded by a previous run.
+func (s *SnapshotGeneratorAdapter) CreateSnapshotFromLSN(ctx context.Context, lsn string) (_ string, err error) {
 	startTime := time.Now()
 	defer func() {
 		s.logger.Info("snapshot generation completed", loglib.Fields{"err": err, "duration": time.Since(startTime).String()})
@@ -53,13 +62,14 @@ func (s *SnapshotGeneratorAdapter) CreateSnapshot(ctx context.Context) (err erro
 	snapshot := &snapshot.Snapshot{
 		SchemaTables:         s.schemaTables,
 		SchemaExcludedTables: s.schemaExcludedTables,
+		LSN:                  lsn,
 	}
 	if err := s.generator.CreateSnapshot(ctx, snapshot); err != nil {
 		s.logger.Error(err, "creating snapshot", loglib.Fields{"schemas": snapshot.GetSchemas(), "tables": snapshot.GetTables()})
-		return err
+		return "", err
 	}
 
-	return nil
+	return snapshot.LSN, nil
 }
 
 func (s *SnapshotGeneratorAdapter) Close() error {
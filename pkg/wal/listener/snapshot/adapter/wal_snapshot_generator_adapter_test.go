@@ -75,3 +75,72 @@ func TestSnapshotGeneratorAdapter_CreateSnapshot(t *testing.T) {
 		})
 	}
 }
+
+func TestSnapshotGeneratorAdapter_CreateSnapshotFromLSN(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name      string
+		generator generator.SnapshotGenerator
+
+		wantLSN string
+		wantErr error
+	}{
+		{
+			name: "ok",
+			generator: &generatormocks.Generator{
+				CreateSnapshotFn: func(ctx context.Context, ss *snapshot.Snapshot) error {
+					require.Equal(t, "0/2", ss.LSN)
+					return nil
+				},
+			},
+
+			wantLSN: "0/2",
+			wantErr: nil,
+		},
+		{
+			name: "ok - resumed from an earlier lsn",
+			generator: &generatormocks.Generator{
+				CreateSnapshotFn: func(ctx context.Context, ss *snapshot.Snapshot) error {
+					ss.LSN = "0/1"
+					return nil
+				},
+			},
+
+			wantLSN: "0/1",
+			wantErr: nil,
+		},
+		{
+			name: "error",
+			generator: &generatormocks.Generator{
+				CreateSnapshotFn: func(ctx context.Context, ss *snapshot.Snapshot) error {
+					return errTest
+				},
+			},
+
+			wantLSN: "",
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ga := SnapshotGeneratorAdapter{
+				logger:    log.NewNoopLogger(),
+				generator: tc.generator,
+				schemaTables: map[string][]string{
+					publicSchema: {"*"},
+				},
+			}
+			defer ga.Close()
+
+			lsn, err := ga.CreateSnapshotFromLSN(context.Background(), "0/2")
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantLSN, lsn)
+		})
+	}
+}
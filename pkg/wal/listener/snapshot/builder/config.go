@@ -14,6 +14,9 @@ type SnapshotListenerConfig struct {
 	Recorder                *SnapshotRecorderConfig
 	Schema                  *SchemaSnapshotConfig
 	DisableProgressTracking bool
+	// PublishViaPartitionRoot snapshots the partitioned tables through their
+	// leaf partitions, so that the rows can be mapped to the root table
+	PublishViaPartitionRoot bool
 }
 
 type SchemaSnapshotConfig struct {
@@ -22,6 +25,7 @@ type SchemaSnapshotConfig struct {
 
 type SnapshotRecorderConfig struct {
 	RepeatableSnapshots bool
+	RetryFailed         bool
 	SnapshotWorkers     uint
 	SnapshotStoreURL    string
 }
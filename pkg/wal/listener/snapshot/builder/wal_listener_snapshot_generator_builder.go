@@ -39,16 +39,39 @@ import (
 // │ │         │  │         │  │        │  │         │ │
 // │ └─────────┘  └─────────┘  └────────┘  └─────────┘ │
 // └───────────────────────────────────────────────────┘
-
-func NewSnapshotGenerator(ctx context.Context, cfg *SnapshotListenerConfig, p listener.Processor, logger loglib.Logger, instrumentation *otel.Instrumentation, restoreConflictTargetsBeforeData bool) (listenersnapshot.Generator, error) {
+//
+// The flush notifier, if any, must be set as the checkpointer of the processor
+// on input, so that the data snapshot progress is checkpointed as soon as the
+// rows are written.
+func NewSnapshotGenerator(ctx context.Context, cfg *SnapshotListenerConfig, p listener.Processor, flushNotifier *pgsnapshotgenerator.FlushNotifier, logger loglib.Logger, instrumentation *otel.Instrumentation, restoreConflictTargetsBeforeData bool) (listenersnapshot.Generator, error) {
 	var g generator.SnapshotGenerator
 	var err error
 
+	// the snapshot store is shared by the activity recorder and the data
+	// snapshot generator, which uses it to checkpoint the table progress and
+	// record the table stats
+	var snapshotStore snapshotstore.Store
+	if cfg.Recorder != nil && cfg.Recorder.SnapshotStoreURL != "" {
+		snapshotStore, err = pgsnapshotstore.New(ctx, cfg.Recorder.SnapshotStoreURL)
+		if err != nil {
+			return nil, fmt.Errorf("create postgres snapshot store: %w", err)
+		}
+		if instrumentation.IsEnabled() {
+			snapshotStore = snapshotstoreinstrumentation.NewStore(snapshotStore, instrumentation)
+		}
+	}
+
 	// postgres data snapshot generator layer
 	if cfg.Data != nil {
 		opts := []pgsnapshotgenerator.Option{
 			pgsnapshotgenerator.WithLogger(logger),
 		}
+		if snapshotStore != nil {
+			opts = append(opts, pgsnapshotgenerator.WithCheckpointStore(snapshotStore), pgsnapshotgenerator.WithTableStatsStore(snapshotStore))
+			if flushNotifier != nil {
+				opts = append(opts, pgsnapshotgenerator.WithFlushNotifier(flushNotifier))
+			}
+		}
 		if !cfg.DisableProgressTracking {
 			opts = append(opts, pgsnapshotgenerator.WithProgressTracking())
 		}
@@ -65,6 +88,9 @@ func NewSnapshotGenerator(ctx context.Context, cfg *SnapshotListenerConfig, p li
 		if instrumentation.IsEnabled() {
 			finderOpts = append(finderOpts, pgtablefinder.WithInstrumentation(instrumentation))
 		}
+		if cfg.PublishViaPartitionRoot {
+			finderOpts = append(finderOpts, pgtablefinder.WithPartitionsViaRoot())
+		}
 
 		g, err = pgtablefinder.NewSnapshotSchemaTableFinder(ctx, cfg.Data.URL, g, finderOpts...)
 		if err != nil {
@@ -80,18 +106,11 @@ func NewSnapshotGenerator(ctx context.Context, cfg *SnapshotListenerConfig, p li
 		}
 	}
 
-	if cfg.Recorder != nil && cfg.Recorder.SnapshotStoreURL != "" {
+	if snapshotStore != nil {
 		// snapshot activity recorder layer
-		var snapshotStore snapshotstore.Store
-		snapshotStore, err := pgsnapshotstore.New(ctx, cfg.Recorder.SnapshotStoreURL)
-		if err != nil {
-			return nil, fmt.Errorf("create postgres snapshot store: %w", err)
-		}
-		if instrumentation.IsEnabled() {
-			snapshotStore = snapshotstoreinstrumentation.NewStore(snapshotStore, instrumentation)
-		}
 		g = generator.NewSnapshotRecorder(&generator.Config{
 			RepeatableSnapshots: cfg.Recorder.RepeatableSnapshots,
+			RetryFailed:         cfg.Recorder.RetryFailed,
 			SnapshotWorkers:     cfg.Recorder.SnapshotWorkers,
 		}, snapshotStore, g)
 	}
@@ -112,15 +131,17 @@ func newSchemaSnapshotGenerator(ctx context.Context, cfg *SchemaSnapshotConfig,
 		pgdumprestoregenerator.WithLogger(logger),
 		pgdumprestoregenerator.WithSnapshotGenerator(g),
 	}
-	if progressTracking {
+	// when the schema is written to a snapshot archive directory there's no
+	// target database to track the progress on
+	if progressTracking && cfg.DumpRestore.TargetDir == "" {
 		opts = append(opts, pgdumprestoregenerator.WithProgressTracking(ctx))
 	}
 	if instrumentation.IsEnabled() {
 		opts = append(opts, pgdumprestoregenerator.WithInstrumentation(instrumentation))
 	}
-	if cfg.DumpRestore.TargetPGURL == "" {
-		// if no target postgres is provided, use WAL restore instead of
-		// direct pgrestore
+	if cfg.DumpRestore.TargetPGURL == "" && cfg.DumpRestore.TargetDir == "" {
+		// if no target postgres or directory is provided, use WAL restore
+		// instead of direct pgrestore
 		opts = append(opts, pgdumprestoregenerator.WithRestoreToWAL(processor))
 	}
 	if restoreConflictTargetsBeforeData {
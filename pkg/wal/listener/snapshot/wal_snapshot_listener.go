@@ -8,6 +8,9 @@ import (
 
 type Generator interface {
 	CreateSnapshot(context.Context) error
+	// CreateSnapshotFromLSN creates the snapshot ahead of replicating from the
+	// LSN on input, and returns the LSN the replication needs to start from.
+	CreateSnapshotFromLSN(ctx context.Context, lsn string) (string, error)
 	Close() error
 }
 
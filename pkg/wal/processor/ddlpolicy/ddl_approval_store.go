@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package ddlpolicy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// ApprovalStore keeps track of the DDL changes waiting for an operator
+// approval in the pgstream.ddl_approvals table.
+type ApprovalStore struct {
+	conn pglib.Querier
+}
+
+// Approval is a DDL change paused by the policy until an operator decides
+// whether it should be replicated.
+type Approval struct {
+	ID          int64          `json:"id"`
+	Position    string         `json:"position"`
+	CommandTag  string         `json:"command_tag"`
+	SchemaName  string         `json:"schema_name"`
+	DDL         string         `json:"ddl"`
+	Status      ApprovalStatus `json:"status"`
+	RequestedAt time.Time      `json:"requested_at"`
+	DecidedAt   *time.Time     `json:"decided_at,omitempty"`
+}
+
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+)
+
+const (
+	approvalsTable   = `"pgstream"."ddl_approvals"`
+	approvalsColumns = `"id", "position", "command_tag", "schema_name", "ddl", "status", "requested_at", "decided_at"`
+)
+
+var ErrApprovalNotPending = errors.New("DDL approval not found or already decided")
+
+// NewApprovalStore returns an approval store using the Postgres database on
+// input, creating the approvals table if it doesn't exist.
+func NewApprovalStore(ctx context.Context, url string) (*ApprovalStore, error) {
+	conn, err := pglib.NewConnPool(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	s := newApprovalStore(conn)
+	if err := s.createTable(ctx); err != nil {
+		conn.Close(ctx)
+		return nil, err
+	}
+	return s, nil
+}
+
+func newApprovalStore(conn pglib.Querier) *ApprovalStore {
+	return &ApprovalStore{conn: conn}
+}
+
+func (s *ApprovalStore) createTable(ctx context.Context) error {
+	queries := []string{
+		`CREATE SCHEMA IF NOT EXISTS "pgstream"`,
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	"id" bigserial PRIMARY KEY,
+	"position" text NOT NULL,
+	"ddl_hash" text NOT NULL,
+	"command_tag" text NOT NULL,
+	"schema_name" text,
+	"ddl" text NOT NULL,
+	"status" text NOT NULL DEFAULT 'pending',
+	"requested_at" timestamptz NOT NULL DEFAULT now(),
+	"decided_at" timestamptz,
+	UNIQUE ("position", "ddl_hash"))`, approvalsTable),
+	}
+	for _, q := range queries {
+		if _, err := s.conn.Exec(ctx, q); err != nil {
+			return fmt.Errorf("creating DDL approvals table: %w", err)
+		}
+	}
+	return nil
+}
+
+// Request registers the DDL event on input as pending approval. Requesting
+// the approval of an event already registered returns the existing approval,
+// so that decisions are kept when the event is received again.
+func (s *ApprovalStore) Request(ctx context.Context, position wal.CommitPosition, ddlEvent *wal.DDLEvent) (*Approval, error) {
+	hash := sha256.Sum256([]byte(ddlEvent.DDL))
+	query := fmt.Sprintf(`INSERT INTO %s("position", "ddl_hash", "command_tag", "schema_name", "ddl") VALUES($1, $2, $3, $4, $5)`+
+		` ON CONFLICT ("position", "ddl_hash") DO UPDATE SET "position" = EXCLUDED."position" RETURNING %s`, approvalsTable, approvalsColumns)
+
+	approval := &Approval{}
+	if err := s.conn.QueryRow(ctx, approval.scanDest(), query,
+		string(position), hex.EncodeToString(hash[:]), ddlEvent.CommandTag, ddlEvent.SchemaName, ddlEvent.DDL); err != nil {
+		return nil, fmt.Errorf("requesting DDL approval: %w", err)
+	}
+	return approval, nil
+}
+
+// Get returns the approval with the id on input.
+func (s *ApprovalStore) Get(ctx context.Context, id int64) (*Approval, error) {
+	approval := &Approval{}
+	if err := s.conn.QueryRow(ctx, approval.scanDest(),
+		fmt.Sprintf(`SELECT %s FROM %s WHERE "id" = $1`, approvalsColumns, approvalsTable), id); err != nil {
+		return nil, fmt.Errorf("retrieving DDL approval %d: %w", id, err)
+	}
+	return approval, nil
+}
+
+// List returns the approvals with the status on input, or all of them if no
+// status is provided, in order of request.
+func (s *ApprovalStore) List(ctx context.Context, status ApprovalStatus) ([]*Approval, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s`, approvalsColumns, approvalsTable)
+	args := []any{}
+	if status != "" {
+		query += ` WHERE "status" = $1`
+		args = append(args, string(status))
+	}
+	query += ` ORDER BY "id"`
+
+	rows, err := s.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing DDL approvals: %w", err)
+	}
+	defer rows.Close()
+
+	approvals := []*Approval{}
+	for rows.Next() {
+		approval := &Approval{}
+		if err := rows.Scan(approval.scanDest()...); err != nil {
+			return nil, fmt.Errorf("scanning DDL approval: %w", err)
+		}
+		approvals = append(approvals, approval)
+	}
+	return approvals, rows.Err()
+}
+
+// Approve allows the pending DDL change with the id on input to be
+// replicated.
+func (s *ApprovalStore) Approve(ctx context.Context, id int64) error {
+	return s.decide(ctx, id, ApprovalApproved)
+}
+
+// Reject skips the pending DDL change with the id on input.
+func (s *ApprovalStore) Reject(ctx context.Context, id int64) error {
+	return s.decide(ctx, id, ApprovalRejected)
+}
+
+func (s *ApprovalStore) decide(ctx context.Context, id int64, status ApprovalStatus) error {
+	tag, err := s.conn.Exec(ctx, fmt.Sprintf(`UPDATE %s SET "status" = $1, "decided_at" = now() WHERE "id" = $2 AND "status" = $3`, approvalsTable),
+		string(status), id, string(ApprovalPending))
+	if err != nil {
+		return fmt.Errorf("updating DDL approval %d: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %d", ErrApprovalNotPending, id)
+	}
+	return nil
+}
+
+func (s *ApprovalStore) Close(ctx context.Context) error {
+	return s.conn.Close(ctx)
+}
+
+func (a *Approval) scanDest() []any {
+	return []any{&a.ID, &a.Position, &a.CommandTag, &a.SchemaName, &a.DDL, &a.Status, &a.RequestedAt, &a.DecidedAt}
+}
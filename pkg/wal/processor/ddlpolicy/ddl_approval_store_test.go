@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package ddlpolicy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestApprovalStore_Request(t *testing.T) {
+	t.Parallel()
+
+	ddlEvent := &wal.DDLEvent{
+		DDL:        "DROP TABLE public.users",
+		SchemaName: "public",
+		CommandTag: "DROP TABLE",
+	}
+	hash := sha256.Sum256([]byte(ddlEvent.DDL))
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name       string
+		queryRowFn func(ctx context.Context, dest []any, query string, args ...any) error
+
+		wantApproval *Approval
+		wantErr      error
+	}{
+		{
+			name: "ok",
+			queryRowFn: func(ctx context.Context, dest []any, query string, args ...any) error {
+				require.Equal(t, []any{"0/1", hex.EncodeToString(hash[:]), "DROP TABLE", "public", "DROP TABLE public.users"}, args)
+				require.Len(t, dest, 8)
+				*(dest[0].(*int64)) = 1
+				*(dest[5].(*ApprovalStatus)) = ApprovalPending
+				return nil
+			},
+			wantApproval: &Approval{ID: 1, Status: ApprovalPending},
+		},
+		{
+			name: "error",
+			queryRowFn: func(ctx context.Context, dest []any, query string, args ...any) error {
+				return errTest
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newApprovalStore(&pgmocks.Querier{QueryRowFn: tc.queryRowFn})
+			approval, err := s.Request(context.Background(), wal.CommitPosition("0/1"), ddlEvent)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantApproval, approval)
+		})
+	}
+}
+
+func TestApprovalStore_List(t *testing.T) {
+	t.Parallel()
+
+	s := newApprovalStore(&pgmocks.Querier{
+		QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+			require.Equal(t, `SELECT `+approvalsColumns+` FROM `+approvalsTable+` WHERE "status" = $1 ORDER BY "id"`, query)
+			require.Equal(t, []any{"pending"}, args)
+			return &pgmocks.Rows{
+				CloseFn: func() {},
+				NextFn:  func(i uint) bool { return i <= 2 },
+				ScanFn: func(i uint, dest ...any) error {
+					*(dest[0].(*int64)) = int64(i)
+					*(dest[5].(*ApprovalStatus)) = ApprovalPending
+					return nil
+				},
+				ErrFn: func() error { return nil },
+			}, nil
+		},
+	})
+
+	approvals, err := s.List(context.Background(), ApprovalPending)
+	require.NoError(t, err)
+	require.Equal(t, []*Approval{
+		{ID: 1, Status: ApprovalPending},
+		{ID: 2, Status: ApprovalPending},
+	}, approvals)
+}
+
+func TestApprovalStore_Approve(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name   string
+		execFn func(context.Context, uint, string, ...any) (pglib.CommandTag, error)
+
+		wantErr error
+	}{
+		{
+			name: "ok",
+			execFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+				require.Equal(t, []any{"approved", int64(1), "pending"}, args)
+				return pglib.CommandTag{CommandTag: pgconn.NewCommandTag("UPDATE 1")}, nil
+			},
+		},
+		{
+			name: "error - not pending",
+			execFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+				return pglib.CommandTag{CommandTag: pgconn.NewCommandTag("UPDATE 0")}, nil
+			},
+			wantErr: ErrApprovalNotPending,
+		},
+		{
+			name: "error - updating",
+			execFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+				return pglib.CommandTag{}, errTest
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newApprovalStore(&pgmocks.Querier{ExecFn: tc.execFn})
+			err := s.Approve(context.Background(), 1)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,324 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package ddlpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+)
+
+// Policy is a processor wrapper that decides what happens to the DDL events
+// based on the configured rules. DDL changes can be replicated, skipped, fail
+// the pipeline or be paused until an operator approves or rejects them.
+type Policy struct {
+	processor    processor.Processor
+	rules        []*rule
+	approvals    approvalStore
+	pollInterval time.Duration
+	// keepAlive is called periodically while a DDL event is paused, since
+	// the events are processed synchronously and the source would otherwise
+	// time out the replication connection
+	keepAlive          func(context.Context) error
+	keepAliveInterval  time.Duration
+	logger             loglib.Logger
+	walEventToDDLEvent func(*wal.Data) (*wal.DDLEvent, error)
+}
+
+type Config struct {
+	// List of DDL rules. When a DDL event matches multiple rules, the first
+	// one applies. DDL events that don't match any rule are allowed.
+	Rules []RuleConfig
+	// URL of the Postgres database where the changes pending approval are
+	// stored. Required when any rule pauses the changes.
+	ApprovalURL string
+	// Interval at which the pending approvals are checked. Defaults to 5s.
+	ApprovalPollInterval time.Duration
+}
+
+type RuleConfig struct {
+	// Command tag of the DDL event (for example "DROP TABLE"). Matches all the
+	// commands if empty or "*".
+	CommandTag string
+	// Type of the objects affected by the DDL event (for example "table" or
+	// "index"). Matches all the objects if empty or "*".
+	ObjectType string
+	// Regular expression matched against the DDL statement, to target
+	// changes within a command (for example "ALTER COLUMN .* TYPE"). Optional.
+	Statement string
+	// Action to take for the matching DDL events. One of allow, skip, fail or
+	// pause.
+	Action string
+}
+
+type Option func(*Policy)
+
+type action string
+
+const (
+	actionAllow action = "allow"
+	actionSkip  action = "skip"
+	actionFail  action = "fail"
+	actionPause action = "pause"
+
+	wildcard = "*"
+
+	defaultApprovalPollInterval = 5 * time.Second
+	// keep alives need to be sent well within the source wal_sender_timeout,
+	// which defaults to 60s
+	defaultKeepAliveInterval = 10 * time.Second
+)
+
+type rule struct {
+	commandTag string
+	objectType string
+	statement  *regexp.Regexp
+	action     action
+}
+
+type approvalStore interface {
+	Request(ctx context.Context, position wal.CommitPosition, ddlEvent *wal.DDLEvent) (*Approval, error)
+	Get(ctx context.Context, id int64) (*Approval, error)
+	Close(ctx context.Context) error
+}
+
+var (
+	ErrDDLBlocked = errors.New("DDL change blocked by policy")
+
+	errMissingRules       = errors.New("missing DDL policy rules")
+	errInvalidAction      = errors.New("invalid DDL policy action, must be one of allow, skip, fail or pause")
+	errMissingApprovalURL = errors.New("DDL policy approval URL required to pause changes")
+)
+
+// New will return a DDL policy processor wrapper that applies the configured
+// rules to the DDL events before passing them on to the wrapped processor.
+func New(ctx context.Context, processor processor.Processor, cfg *Config, opts ...Option) (*Policy, error) {
+	if len(cfg.Rules) == 0 {
+		return nil, errMissingRules
+	}
+
+	p := &Policy{
+		processor:          processor,
+		rules:              make([]*rule, 0, len(cfg.Rules)),
+		pollInterval:       cfg.ApprovalPollInterval,
+		keepAliveInterval:  defaultKeepAliveInterval,
+		logger:             loglib.NewNoopLogger(),
+		walEventToDDLEvent: wal.WalDataToDDLEvent,
+	}
+	if p.pollInterval == 0 {
+		p.pollInterval = defaultApprovalPollInterval
+	}
+
+	needsApproval := false
+	for _, ruleCfg := range cfg.Rules {
+		r, err := newRule(ruleCfg)
+		if err != nil {
+			return nil, err
+		}
+		needsApproval = needsApproval || r.action == actionPause
+		p.rules = append(p.rules, r)
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if needsApproval && p.approvals == nil {
+		if cfg.ApprovalURL == "" {
+			return nil, errMissingApprovalURL
+		}
+		store, err := NewApprovalStore(ctx, cfg.ApprovalURL)
+		if err != nil {
+			return nil, fmt.Errorf("creating DDL approval store: %w", err)
+		}
+		p.approvals = store
+	}
+
+	return p, nil
+}
+
+func newRule(cfg RuleConfig) (*rule, error) {
+	r := &rule{
+		commandTag: strings.ToUpper(strings.TrimSpace(cfg.CommandTag)),
+		objectType: strings.ToLower(strings.TrimSpace(cfg.ObjectType)),
+		action:     action(strings.ToLower(cfg.Action)),
+	}
+
+	switch r.action {
+	case actionAllow, actionSkip, actionFail, actionPause:
+	default:
+		return nil, fmt.Errorf("%w: %q", errInvalidAction, cfg.Action)
+	}
+
+	if cfg.Statement != "" {
+		var err error
+		if r.statement, err = regexp.Compile("(?is)" + cfg.Statement); err != nil {
+			return nil, fmt.Errorf("invalid DDL policy statement %q: %w", cfg.Statement, err)
+		}
+	}
+
+	return r, nil
+}
+
+func WithLogger(logger loglib.Logger) Option {
+	return func(p *Policy) {
+		p.logger = loglib.NewLogger(logger).WithFields(loglib.Fields{
+			loglib.ModuleField: "wal_ddl_policy",
+		})
+	}
+}
+
+// WithKeepAlive sets the function called periodically while a DDL event is
+// paused pending approval, to keep the source replication connection alive.
+func WithKeepAlive(keepAlive func(context.Context) error) Option {
+	return func(p *Policy) {
+		p.keepAlive = keepAlive
+	}
+}
+
+func (p *Policy) ProcessWALEvent(ctx context.Context, event *wal.Event) error {
+	if event == nil || event.Data == nil || !event.Data.IsDDLEvent() {
+		return p.processor.ProcessWALEvent(ctx, event)
+	}
+
+	ddlEvent, err := p.walEventToDDLEvent(event.Data)
+	if err != nil {
+		// if we can't determine the DDL event, let the processor handle it
+		p.logger.Error(err, "failed to convert WAL data to DDL event", loglib.Fields{"data": event.Data})
+		return p.processor.ProcessWALEvent(ctx, event)
+	}
+
+	logFields := loglib.Fields{"command_tag": ddlEvent.CommandTag, "schema": ddlEvent.SchemaName, "ddl": ddlEvent.DDL}
+	switch p.actionFor(ddlEvent) {
+	case actionSkip:
+		p.logger.Info("skipping DDL event", logFields)
+		return nil
+	case actionFail:
+		p.logger.Error(ErrDDLBlocked, "blocking DDL event", logFields)
+		return fmt.Errorf("%w: %s", ErrDDLBlocked, ddlEvent.CommandTag)
+	case actionPause:
+		approved, err := p.waitForApproval(ctx, event.CommitPosition, ddlEvent)
+		if err != nil {
+			return err
+		}
+		if !approved {
+			p.logger.Info("skipping rejected DDL event", logFields)
+			return nil
+		}
+	}
+
+	return p.processor.ProcessWALEvent(ctx, event)
+}
+
+func (p *Policy) Name() string {
+	return p.processor.Name()
+}
+
+func (p *Policy) Close() error {
+	if p.approvals != nil {
+		if err := p.approvals.Close(context.Background()); err != nil {
+			p.logger.Error(err, "closing DDL approval store")
+		}
+	}
+	return p.processor.Close()
+}
+
+// actionFor returns the action of the first rule matching the DDL event on
+// input.
+func (p *Policy) actionFor(ddlEvent *wal.DDLEvent) action {
+	for _, r := range p.rules {
+		if r.matches(ddlEvent) {
+			return r.action
+		}
+	}
+	return actionAllow
+}
+
+// waitForApproval registers the DDL event as pending approval and blocks until
+// an operator approves or rejects it. It returns true if the change was
+// approved. The approval is kept across restarts, so a change received again
+// after being decided isn't paused twice.
+func (p *Policy) waitForApproval(ctx context.Context, position wal.CommitPosition, ddlEvent *wal.DDLEvent) (bool, error) {
+	approval, err := p.approvals.Request(ctx, position, ddlEvent)
+	if err != nil {
+		return false, err
+	}
+
+	logged := false
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	var keepAliveChan <-chan time.Time
+	if p.keepAlive != nil {
+		keepAliveTicker := time.NewTicker(p.keepAliveInterval)
+		defer keepAliveTicker.Stop()
+		keepAliveChan = keepAliveTicker.C
+	}
+
+	for {
+		switch approval.Status {
+		case ApprovalApproved:
+			p.logger.Info("DDL event approved", loglib.Fields{"approval_id": approval.ID})
+			return true, nil
+		case ApprovalRejected:
+			return false, nil
+		}
+
+		if !logged {
+			p.logger.Warn(nil, "DDL event paused pending approval", loglib.Fields{
+				"approval_id": approval.ID, "command_tag": ddlEvent.CommandTag, "ddl": ddlEvent.DDL,
+			})
+			logged = true
+		}
+
+		if err := p.waitForNextPoll(ctx, ticker.C, keepAliveChan); err != nil {
+			return false, err
+		}
+
+		if approval, err = p.approvals.Get(ctx, approval.ID); err != nil {
+			return false, err
+		}
+	}
+}
+
+// waitForNextPoll blocks until the next approval poll, sending the keep
+// alives in the meantime.
+func (p *Policy) waitForNextPoll(ctx context.Context, pollChan, keepAliveChan <-chan time.Time) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-pollChan:
+			return nil
+		case <-keepAliveChan:
+			if err := p.keepAlive(ctx); err != nil {
+				return fmt.Errorf("sending keep alive while DDL event is paused: %w", err)
+			}
+		}
+	}
+}
+
+func (r *rule) matches(ddlEvent *wal.DDLEvent) bool {
+	if r.commandTag != "" && r.commandTag != wildcard && r.commandTag != strings.ToUpper(ddlEvent.CommandTag) {
+		return false
+	}
+	if r.statement != nil && !r.statement.MatchString(ddlEvent.DDL) {
+		return false
+	}
+	if r.objectType == "" || r.objectType == wildcard {
+		return true
+	}
+	for _, obj := range ddlEvent.Objects {
+		if strings.EqualFold(obj.Type, r.objectType) {
+			return true
+		}
+	}
+	return false
+}
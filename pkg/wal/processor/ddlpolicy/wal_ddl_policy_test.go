@@ -0,0 +1,445 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package ddlpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/mocks"
+)
+
+type mockApprovalStore struct {
+	requestFn func(ctx context.Context, position wal.CommitPosition, ddlEvent *wal.DDLEvent) (*Approval, error)
+	getFn     func(ctx context.Context, id int64, i uint64) (*Approval, error)
+	getCalls  atomic.Uint64
+}
+
+func (m *mockApprovalStore) Request(ctx context.Context, position wal.CommitPosition, ddlEvent *wal.DDLEvent) (*Approval, error) {
+	return m.requestFn(ctx, position, ddlEvent)
+}
+
+func (m *mockApprovalStore) Get(ctx context.Context, id int64) (*Approval, error) {
+	return m.getFn(ctx, id, m.getCalls.Add(1))
+}
+
+func (m *mockApprovalStore) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	store := &mockApprovalStore{}
+
+	tests := []struct {
+		name   string
+		config *Config
+		opts   []Option
+
+		wantErr error
+	}{
+		{
+			name: "ok",
+			config: &Config{
+				Rules: []RuleConfig{
+					{CommandTag: "DROP TABLE", Action: "fail"},
+					{ObjectType: "index", Action: "skip"},
+				},
+			},
+		},
+		{
+			name:   "ok - pause with approval store",
+			config: &Config{Rules: []RuleConfig{{CommandTag: "DROP TABLE", Action: "pause"}}},
+			opts: []Option{func(p *Policy) {
+				p.approvals = store
+			}},
+		},
+		{
+			name:    "error - missing rules",
+			config:  &Config{},
+			wantErr: errMissingRules,
+		},
+		{
+			name:    "error - invalid action",
+			config:  &Config{Rules: []RuleConfig{{CommandTag: "DROP TABLE", Action: "ignore"}}},
+			wantErr: errInvalidAction,
+		},
+		{
+			name:    "error - missing approval url",
+			config:  &Config{Rules: []RuleConfig{{CommandTag: "DROP TABLE", Action: "pause"}}},
+			wantErr: errMissingApprovalURL,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := New(context.Background(), &mocks.Processor{}, tc.config, tc.opts...)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr == nil {
+				require.Len(t, p.rules, len(tc.config.Rules))
+				require.Equal(t, defaultApprovalPollInterval, p.pollInterval)
+			}
+		})
+	}
+}
+
+func TestPolicy_ProcessWALEvent(t *testing.T) {
+	t.Parallel()
+
+	newDDLEvent := func(ddlEvent *wal.DDLEvent) *wal.Event {
+		content, err := json.Marshal(ddlEvent)
+		require.NoError(t, err)
+		return &wal.Event{
+			CommitPosition: wal.CommitPosition("0/1"),
+			Data: &wal.Data{
+				Action:  wal.LogicalMessageAction,
+				Prefix:  wal.DDLPrefix,
+				Content: string(content),
+			},
+		}
+	}
+
+	dropTableEvent := newDDLEvent(&wal.DDLEvent{
+		DDL:        "DROP TABLE public.users",
+		SchemaName: "public",
+		CommandTag: "DROP TABLE",
+		Objects:    []wal.DDLObject{{Type: "table", Identity: "public.users", Schema: "public"}},
+	})
+	alterColumnTypeEvent := newDDLEvent(&wal.DDLEvent{
+		DDL:        "ALTER TABLE public.users ALTER COLUMN age TYPE bigint",
+		SchemaName: "public",
+		CommandTag: "ALTER TABLE",
+		Objects:    []wal.DDLObject{{Type: "table", Identity: "public.users", Schema: "public"}},
+	})
+	createIndexEvent := newDDLEvent(&wal.DDLEvent{
+		DDL:        "CREATE INDEX users_name_idx ON public.users(name)",
+		SchemaName: "public",
+		CommandTag: "CREATE INDEX",
+		Objects:    []wal.DDLObject{{Type: "index", Identity: "public.users_name_idx", Schema: "public"}},
+	})
+	dataEvent := &wal.Event{
+		Data: &wal.Data{Action: "I", Schema: "public", Table: "users"},
+	}
+
+	rules := []RuleConfig{
+		{CommandTag: "drop table", Action: "fail"},
+		{CommandTag: "ALTER TABLE", Statement: `ALTER\s+COLUMN\s+\S+\s+TYPE`, Action: "pause"},
+		{ObjectType: "index", Action: "skip"},
+	}
+
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name      string
+		event     *wal.Event
+		approvals approvalStore
+
+		wantProcessCalls uint
+		wantErr          error
+	}{
+		{
+			name:             "non DDL event",
+			event:            dataEvent,
+			wantProcessCalls: 1,
+		},
+		{
+			name:             "fail",
+			event:            dropTableEvent,
+			wantProcessCalls: 0,
+			wantErr:          ErrDDLBlocked,
+		},
+		{
+			name:             "skip",
+			event:            createIndexEvent,
+			wantProcessCalls: 0,
+		},
+		{
+			name: "allow - no matching rule",
+			event: newDDLEvent(&wal.DDLEvent{
+				DDL:        "ALTER TABLE public.users ADD COLUMN email text",
+				SchemaName: "public",
+				CommandTag: "ALTER TABLE",
+				Objects:    []wal.DDLObject{{Type: "table", Identity: "public.users", Schema: "public"}},
+			}),
+			wantProcessCalls: 1,
+		},
+		{
+			name:  "pause - approved after waiting",
+			event: alterColumnTypeEvent,
+			approvals: &mockApprovalStore{
+				requestFn: func(ctx context.Context, position wal.CommitPosition, ddlEvent *wal.DDLEvent) (*Approval, error) {
+					require.Equal(t, wal.CommitPosition("0/1"), position)
+					require.Equal(t, "ALTER TABLE", ddlEvent.CommandTag)
+					return &Approval{ID: 1, Status: ApprovalPending}, nil
+				},
+				getFn: func(ctx context.Context, id int64, i uint64) (*Approval, error) {
+					require.Equal(t, int64(1), id)
+					if i < 2 {
+						return &Approval{ID: 1, Status: ApprovalPending}, nil
+					}
+					return &Approval{ID: 1, Status: ApprovalApproved}, nil
+				},
+			},
+			wantProcessCalls: 1,
+		},
+		{
+			name:  "pause - already rejected",
+			event: alterColumnTypeEvent,
+			approvals: &mockApprovalStore{
+				requestFn: func(ctx context.Context, position wal.CommitPosition, ddlEvent *wal.DDLEvent) (*Approval, error) {
+					return &Approval{ID: 1, Status: ApprovalRejected}, nil
+				},
+				getFn: func(ctx context.Context, id int64, i uint64) (*Approval, error) {
+					return nil, errors.New("getFn: should not be called")
+				},
+			},
+			wantProcessCalls: 0,
+		},
+		{
+			name:  "error - requesting approval",
+			event: alterColumnTypeEvent,
+			approvals: &mockApprovalStore{
+				requestFn: func(ctx context.Context, position wal.CommitPosition, ddlEvent *wal.DDLEvent) (*Approval, error) {
+					return nil, errTest
+				},
+			},
+			wantProcessCalls: 0,
+			wantErr:          errTest,
+		},
+		{
+			name:  "error - getting approval",
+			event: alterColumnTypeEvent,
+			approvals: &mockApprovalStore{
+				requestFn: func(ctx context.Context, position wal.CommitPosition, ddlEvent *wal.DDLEvent) (*Approval, error) {
+					return &Approval{ID: 1, Status: ApprovalPending}, nil
+				},
+				getFn: func(ctx context.Context, id int64, i uint64) (*Approval, error) {
+					return nil, errTest
+				},
+			},
+			wantProcessCalls: 0,
+			wantErr:          errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockProcessor := &mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error {
+					require.Equal(t, tc.event, walEvent)
+					return nil
+				},
+			}
+
+			approvals := tc.approvals
+			if approvals == nil {
+				approvals = &mockApprovalStore{}
+			}
+
+			p, err := New(context.Background(), mockProcessor, &Config{
+				Rules:                rules,
+				ApprovalPollInterval: time.Millisecond,
+			}, func(p *Policy) { p.approvals = approvals })
+			require.NoError(t, err)
+
+			err = p.ProcessWALEvent(context.Background(), tc.event)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantProcessCalls, mockProcessor.GetProcessCalls())
+		})
+	}
+}
+
+func TestPolicy_ProcessWALEvent_pauseCancelled(t *testing.T) {
+	t.Parallel()
+
+	p, err := New(context.Background(), &mocks.Processor{}, &Config{
+		Rules:                []RuleConfig{{CommandTag: "DROP TABLE", Action: "pause"}},
+		ApprovalPollInterval: time.Hour,
+	}, func(p *Policy) {
+		p.approvals = &mockApprovalStore{
+			requestFn: func(ctx context.Context, position wal.CommitPosition, ddlEvent *wal.DDLEvent) (*Approval, error) {
+				return &Approval{ID: 1, Status: ApprovalPending}, nil
+			},
+		}
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = p.ProcessWALEvent(ctx, &wal.Event{
+		Data: &wal.Data{
+			Action:  wal.LogicalMessageAction,
+			Prefix:  wal.DDLPrefix,
+			Content: `{"ddl":"DROP TABLE public.users","command_tag":"DROP TABLE"}`,
+		},
+	})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPolicy_ProcessWALEvent_pauseKeepAlive(t *testing.T) {
+	t.Parallel()
+
+	// the approval takes longer than the replication connection timeout, so
+	// the connection has to be kept alive while the DDL event is paused
+	const (
+		connTimeout   = 100 * time.Millisecond
+		approvalDelay = 5 * connTimeout
+	)
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name         string
+		keepAliveErr error
+
+		wantProcessed bool
+		wantErr       error
+	}{
+		{
+			name: "ok - connection kept alive until approved",
+
+			wantProcessed: true,
+		},
+		{
+			name:         "error - sending keep alive",
+			keepAliveErr: errTest,
+
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			requestedAt := time.Now()
+			processed := false
+			var keepAlives []time.Time
+			p, err := New(context.Background(), &mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error {
+					processed = true
+					return nil
+				},
+			}, &Config{
+				Rules:                []RuleConfig{{CommandTag: "DROP TABLE", Action: "pause"}},
+				ApprovalPollInterval: 2 * connTimeout,
+			}, WithKeepAlive(func(ctx context.Context) error {
+				keepAlives = append(keepAlives, time.Now())
+				return tc.keepAliveErr
+			}), func(p *Policy) {
+				p.keepAliveInterval = connTimeout / 4
+				p.approvals = &mockApprovalStore{
+					requestFn: func(ctx context.Context, position wal.CommitPosition, ddlEvent *wal.DDLEvent) (*Approval, error) {
+						return &Approval{ID: 1, Status: ApprovalPending}, nil
+					},
+					getFn: func(ctx context.Context, id int64, i uint64) (*Approval, error) {
+						if time.Since(requestedAt) < approvalDelay {
+							return &Approval{ID: 1, Status: ApprovalPending}, nil
+						}
+						return &Approval{ID: 1, Status: ApprovalApproved}, nil
+					},
+				}
+			})
+			require.NoError(t, err)
+
+			err = p.ProcessWALEvent(context.Background(), &wal.Event{
+				Data: &wal.Data{
+					Action:  wal.LogicalMessageAction,
+					Prefix:  wal.DDLPrefix,
+					Content: `{"ddl":"DROP TABLE public.users","command_tag":"DROP TABLE"}`,
+				},
+			})
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantProcessed, processed)
+			if tc.wantErr != nil {
+				return
+			}
+
+			// the connection never went longer than its timeout without a
+			// keep alive
+			require.GreaterOrEqual(t, time.Since(requestedAt), approvalDelay)
+			last := requestedAt
+			for _, keepAlive := range keepAlives {
+				require.Less(t, keepAlive.Sub(last), connTimeout)
+				last = keepAlive
+			}
+			require.Less(t, time.Since(last), connTimeout)
+		})
+	}
+}
+
+func TestRule_matches(t *testing.T) {
+	t.Parallel()
+
+	ddlEvent := &wal.DDLEvent{
+		DDL:        "ALTER TABLE public.users ALTER COLUMN age TYPE bigint",
+		CommandTag: "ALTER TABLE",
+		Objects: []wal.DDLObject{
+			{Type: "table", Identity: "public.users"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		rule RuleConfig
+
+		wantMatch bool
+	}{
+		{
+			name:      "wildcard",
+			rule:      RuleConfig{CommandTag: "*", ObjectType: "*", Action: "allow"},
+			wantMatch: true,
+		},
+		{
+			name:      "command tag, case insensitive",
+			rule:      RuleConfig{CommandTag: "alter table", Action: "allow"},
+			wantMatch: true,
+		},
+		{
+			name:      "command tag mismatch",
+			rule:      RuleConfig{CommandTag: "DROP TABLE", Action: "allow"},
+			wantMatch: false,
+		},
+		{
+			name:      "object type",
+			rule:      RuleConfig{ObjectType: "TABLE", Action: "allow"},
+			wantMatch: true,
+		},
+		{
+			name:      "object type mismatch",
+			rule:      RuleConfig{ObjectType: "index", Action: "allow"},
+			wantMatch: false,
+		},
+		{
+			name:      "statement",
+			rule:      RuleConfig{CommandTag: "ALTER TABLE", Statement: `alter column .* type`, Action: "allow"},
+			wantMatch: true,
+		},
+		{
+			name:      "statement mismatch",
+			rule:      RuleConfig{CommandTag: "ALTER TABLE", Statement: `DROP COLUMN`, Action: "allow"},
+			wantMatch: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := newRule(tc.rule)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantMatch, r.matches(ddlEvent))
+		})
+	}
+}
@@ -0,0 +1,10 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+type Config struct {
+	// Dir is the directory where the snapshot archive will be written.
+	Dir string
+	// Format of the table data files. One of text or csv. Defaults to text.
+	Format string
+}
@@ -0,0 +1,340 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/snapshot/archive"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// Writer is a processor that writes the snapshot insert events to per table
+// data files in a snapshot archive directory. The schema files of the archive
+// are written by the schema snapshot generator, and the manifest once the
+// snapshot has completed.
+type Writer struct {
+	logger loglib.Logger
+	dir    string
+	format archive.Format
+
+	mu     sync.Mutex
+	tables map[string]*tableFile
+}
+
+type tableFile struct {
+	mu       sync.Mutex
+	manifest *archive.TableFile
+	file     *os.File
+	rows     *archive.RowWriter
+	hash     hash.Hash
+	size     *sizeCounter
+	// the type map is not safe for concurrent use, so each table keeps its own
+	typeMap *pgtype.Map
+}
+
+type sizeCounter struct {
+	n int64
+}
+
+type Option func(*Writer)
+
+var (
+	ErrArchiveExists     = errors.New("directory already contains a snapshot archive")
+	errMissingDir        = errors.New("file target directory required")
+	errUnexpectedColumns = errors.New("unexpected columns for table")
+	errUnsupportedColumn = errors.New("unsupported column value")
+)
+
+func NewWriter(cfg *Config, opts ...Option) (*Writer, error) {
+	if cfg.Dir == "" {
+		return nil, errMissingDir
+	}
+
+	format, err := archive.ParseFormat(cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating snapshot archive directory: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.Dir, archive.ManifestFile)); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrArchiveExists, cfg.Dir)
+	}
+
+	w := &Writer{
+		logger: loglib.NewNoopLogger(),
+		dir:    cfg.Dir,
+		format: format,
+		tables: map[string]*tableFile{},
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w, nil
+}
+
+func WithLogger(l loglib.Logger) Option {
+	return func(w *Writer) {
+		w.logger = loglib.NewLogger(l).WithFields(loglib.Fields{
+			loglib.ModuleField: "file_writer",
+		})
+	}
+}
+
+// ProcessWALEvent writes the insert event on input to its table data file. It
+// can be called concurrently.
+func (w *Writer) ProcessWALEvent(ctx context.Context, walEvent *wal.Event) (retErr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Panic("[PANIC] Panic while processing replication event", loglib.Fields{
+				"wal_data":    walEvent,
+				"panic":       r,
+				"stack_trace": debug.Stack(),
+			})
+			retErr = fmt.Errorf("file writer: understanding event: %v", r)
+		}
+	}()
+
+	if walEvent.Data == nil {
+		return nil
+	}
+
+	if !walEvent.Data.IsInsert() {
+		w.logger.Warn(nil, "skipping non-insert event", loglib.Fields{"severity": "DATALOSS", "action": walEvent.Data.Action})
+		return nil
+	}
+
+	tf, err := w.getTableFile(walEvent.Data)
+	if err != nil {
+		return err
+	}
+
+	return tf.writeRow(walEvent.Data.Columns)
+}
+
+func (w *Writer) Name() string {
+	return "file-writer"
+}
+
+// WriteManifest closes the table data files and writes the archive manifest,
+// with the checksums of all the files. It must only be called once the
+// snapshot has completed successfully, since the manifest is what makes the
+// archive loadable.
+func (w *Writer) WriteManifest() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	manifest := archive.NewManifest(w.format)
+	for _, phase := range []archive.SchemaPhase{archive.PreDataPhase, archive.PostDataPhase} {
+		path := archive.SchemaFilePath(phase)
+		sha, size, err := archive.HashFile(w.dir, path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		manifest.Schema = append(manifest.Schema, &archive.SchemaFile{
+			Phase:  phase,
+			Path:   path,
+			SHA256: sha,
+			Bytes:  size,
+		})
+	}
+
+	if err := w.closeTableFiles(); err != nil {
+		return err
+	}
+
+	for _, tf := range w.tables {
+		manifest.Tables = append(manifest.Tables, tf.manifest)
+	}
+	slices.SortFunc(manifest.Tables, func(a, b *archive.TableFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	w.logger.Info("writing snapshot archive manifest", loglib.Fields{"dir": w.dir, "tables": len(manifest.Tables)})
+	return manifest.Write(w.dir)
+}
+
+// Close closes any open table data files. The manifest is not written, so an
+// archive for a snapshot that didn't complete can't be loaded.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeTableFiles()
+}
+
+func (w *Writer) closeTableFiles() error {
+	var errs error
+	for _, tf := range w.tables {
+		if err := tf.close(); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("closing %s: %w", tf.manifest.Path, err))
+		}
+	}
+	return errs
+}
+
+func (w *Writer) getTableFile(d *wal.Data) (*tableFile, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	key := pglib.QuoteQualifiedIdentifier(d.Schema, d.Table)
+	if tf, found := w.tables[key]; found {
+		return tf, nil
+	}
+
+	tf, err := w.newTableFile(d)
+	if err != nil {
+		return nil, fmt.Errorf("creating data file for table %s: %w", key, err)
+	}
+	w.tables[key] = tf
+	return tf, nil
+}
+
+func (w *Writer) newTableFile(d *wal.Data) (*tableFile, error) {
+	path := archive.TableFilePath(d.Schema, d.Table, w.format)
+	fullPath := filepath.Join(w.dir, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]archive.Column, 0, len(d.Columns))
+	columnNames := make([]string, 0, len(d.Columns))
+	for _, c := range d.Columns {
+		columns = append(columns, archive.Column{Name: c.Name, Type: c.Type})
+		columnNames = append(columnNames, c.Name)
+	}
+
+	tf := &tableFile{
+		manifest: &archive.TableFile{
+			Schema:  d.Schema,
+			Table:   d.Table,
+			Columns: columns,
+			Path:    path,
+		},
+		file:    f,
+		hash:    sha256.New(),
+		size:    &sizeCounter{},
+		typeMap: pgtype.NewMap(),
+	}
+	tf.rows = archive.NewRowWriter(io.MultiWriter(f, tf.hash, tf.size), w.format)
+
+	if err := tf.rows.WriteHeader(columnNames); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return tf, nil
+}
+
+func (tf *tableFile) writeRow(columns []wal.Column) error {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	if tf.file == nil {
+		return fmt.Errorf("writing row to %s: %w", tf.manifest.Path, os.ErrClosed)
+	}
+
+	if len(columns) != len(tf.manifest.Columns) {
+		return fmt.Errorf("%w %s.%s: got %d columns, expected %d", errUnexpectedColumns, tf.manifest.Schema, tf.manifest.Table, len(columns), len(tf.manifest.Columns))
+	}
+
+	values := make([]*string, 0, len(columns))
+	for i, c := range columns {
+		if c.Name != tf.manifest.Columns[i].Name {
+			return fmt.Errorf("%w %s.%s: got column %q, expected %q", errUnexpectedColumns, tf.manifest.Schema, tf.manifest.Table, c.Name, tf.manifest.Columns[i].Name)
+		}
+		v, err := tf.encodeValue(c)
+		if err != nil {
+			return fmt.Errorf("encoding column %q of table %s.%s: %w", c.Name, tf.manifest.Schema, tf.manifest.Table, err)
+		}
+		values = append(values, v)
+	}
+
+	if err := tf.rows.WriteRow(values); err != nil {
+		return fmt.Errorf("writing row to %s: %w", tf.manifest.Path, err)
+	}
+	tf.manifest.Rows++
+	return nil
+}
+
+// encodeValue returns the postgres text representation of the column value,
+// or nil if it's NULL. Values of types unknown to pgx, such as enums or
+// extension types, are already in their text representation.
+func (tf *tableFile) encodeValue(c wal.Column) (*string, error) {
+	if c.Value == nil {
+		return nil, nil
+	}
+
+	if dataType, found := tf.typeMap.TypeForName(c.Type); found {
+		buf, err := tf.typeMap.Encode(dataType.OID, pgtype.TextFormatCode, c.Value, nil)
+		if err != nil {
+			return nil, err
+		}
+		if buf == nil {
+			return nil, nil
+		}
+		s := string(buf)
+		return &s, nil
+	}
+
+	switch v := c.Value.(type) {
+	case string:
+		return &v, nil
+	case []byte:
+		s := string(v)
+		return &s, nil
+	case fmt.Stringer:
+		s := v.String()
+		return &s, nil
+	default:
+		return nil, fmt.Errorf("%w: %T for type %s", errUnsupportedColumn, c.Value, c.Type)
+	}
+}
+
+func (tf *tableFile) close() error {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	if tf.file == nil {
+		return nil
+	}
+
+	flushErr := tf.rows.Flush()
+	closeErr := tf.file.Close()
+	tf.file = nil
+	if err := errors.Join(flushErr, closeErr); err != nil {
+		return err
+	}
+
+	tf.manifest.SHA256 = hex.EncodeToString(tf.hash.Sum(nil))
+	tf.manifest.Bytes = tf.size.n
+	return nil
+}
+
+func (c *sizeCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
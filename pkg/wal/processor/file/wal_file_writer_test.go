@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/snapshot/archive"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	insertEvent := func(id int32, name any, tags any) *wal.Event {
+		return &wal.Event{
+			Data: &wal.Data{
+				Action: "I",
+				Schema: "public",
+				Table:  "users",
+				Columns: []wal.Column{
+					{Name: "id", Type: "int4", Value: id},
+					{Name: "name", Type: "text", Value: name},
+					{Name: "tags", Type: "_text", Value: tags},
+					{Name: "mood", Type: "mood", Value: "happy"},
+					{Name: "created_at", Type: "timestamptz", Value: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+				},
+			},
+			CommitPosition: wal.CommitPosition(wal.ZeroLSN),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		format string
+
+		wantData string
+	}{
+		{
+			name:   "text",
+			format: "",
+
+			wantData: "1\talice\t{a,\"b,c\"}\thappy\t2024-01-02 03:04:05Z\n" +
+				"2\t\\N\t\\N\thappy\t2024-01-02 03:04:05Z\n",
+		},
+		{
+			name:   "csv",
+			format: "csv",
+
+			wantData: `"id","name","tags","mood","created_at"` + "\n" +
+				`"1","alice","{a,""b,c""}","happy","2024-01-02 03:04:05Z"` + "\n" +
+				`"2",,,"happy","2024-01-02 03:04:05Z"` + "\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			w, err := NewWriter(&Config{Dir: dir, Format: tc.format})
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			require.NoError(t, w.ProcessWALEvent(ctx, insertEvent(1, "alice", []string{"a", "b,c"})))
+			require.NoError(t, w.ProcessWALEvent(ctx, insertEvent(2, nil, nil)))
+			// non insert events and keep alives are skipped
+			require.NoError(t, w.ProcessWALEvent(ctx, &wal.Event{Data: &wal.Data{Action: "D", Schema: "public", Table: "users"}}))
+			require.NoError(t, w.ProcessWALEvent(ctx, &wal.Event{CommitPosition: wal.CommitPosition(wal.ZeroLSN)}))
+
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "schema"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, archive.PreDataSchemaFile), []byte("CREATE TABLE public.users();\n"), 0o644))
+
+			require.NoError(t, w.WriteManifest())
+			require.NoError(t, w.Close())
+
+			format, err := archive.ParseFormat(tc.format)
+			require.NoError(t, err)
+			dataPath := archive.TableFilePath("public", "users", format)
+			data, err := os.ReadFile(filepath.Join(dir, dataPath))
+			require.NoError(t, err)
+			require.Equal(t, tc.wantData, string(data))
+
+			manifest, err := archive.ReadManifest(dir)
+			require.NoError(t, err)
+			require.NoError(t, manifest.Verify(dir))
+			require.Equal(t, format, manifest.Format)
+			require.Len(t, manifest.Schema, 1)
+			require.Equal(t, archive.PreDataPhase, manifest.Schema[0].Phase)
+			require.Len(t, manifest.Tables, 1)
+			require.Equal(t, "public", manifest.Tables[0].Schema)
+			require.Equal(t, "users", manifest.Tables[0].Table)
+			require.Equal(t, dataPath, manifest.Tables[0].Path)
+			require.Equal(t, int64(2), manifest.Tables[0].Rows)
+			require.Equal(t, int64(len(tc.wantData)), manifest.Tables[0].Bytes)
+			require.Equal(t, []archive.Column{
+				{Name: "id", Type: "int4"},
+				{Name: "name", Type: "text"},
+				{Name: "tags", Type: "_text"},
+				{Name: "mood", Type: "mood"},
+				{Name: "created_at", Type: "timestamptz"},
+			}, manifest.Tables[0].Columns)
+
+			// the directory already contains an archive
+			_, err = NewWriter(&Config{Dir: dir, Format: tc.format})
+			require.ErrorIs(t, err, ErrArchiveExists)
+		})
+	}
+}
+
+func TestWriter_ProcessWALEvent_errors(t *testing.T) {
+	t.Parallel()
+
+	event := func(columns ...wal.Column) *wal.Event {
+		return &wal.Event{
+			Data: &wal.Data{Action: "I", Schema: "public", Table: "users", Columns: columns},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		event *wal.Event
+
+		wantErr error
+	}{
+		{
+			name:  "different column count",
+			event: event(wal.Column{Name: "id", Type: "int4", Value: 1}),
+
+			wantErr: errUnexpectedColumns,
+		},
+		{
+			name:  "different column names",
+			event: event(wal.Column{Name: "id", Type: "int4", Value: 1}, wal.Column{Name: "email", Type: "text", Value: "a"}),
+
+			wantErr: errUnexpectedColumns,
+		},
+		{
+			name:  "unsupported value for unknown type",
+			event: event(wal.Column{Name: "id", Type: "int4", Value: 1}, wal.Column{Name: "name", Type: "custom", Value: 1.5}),
+
+			wantErr: errUnsupportedColumn,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w, err := NewWriter(&Config{Dir: t.TempDir()})
+			require.NoError(t, err)
+			defer w.Close()
+
+			ctx := context.Background()
+			require.NoError(t, w.ProcessWALEvent(ctx, event(wal.Column{Name: "id", Type: "int4", Value: 1}, wal.Column{Name: "name", Type: "text", Value: "a"})))
+			require.ErrorIs(t, w.ProcessWALEvent(ctx, tc.event), tc.wantErr)
+		})
+	}
+}
+
+func TestNewWriter_errors(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWriter(&Config{})
+	require.ErrorIs(t, err, errMissingDir)
+
+	_, err = NewWriter(&Config{Dir: t.TempDir(), Format: "parquet"})
+	require.ErrorIs(t, err, archive.ErrUnsupportedFormat)
+}
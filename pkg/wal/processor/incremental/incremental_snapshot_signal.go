@@ -0,0 +1,208 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package incremental
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xataio/pgstream/internal/json"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// The signals table is used to request incremental snapshots to a running
+// pipeline. The signals are inserted in the source database, and received by
+// the pipeline through the replication slot. The progress of each pipeline
+// with the requests it has received is kept in the signal row, keyed by its
+// replication slot name, so that the requests are resumed on restart.
+const (
+	SignalsSchemaName = "pgstream"
+	SignalsTableName  = "snapshot_signals"
+
+	signalTypeIncrementalSnapshot = "incremental_snapshot"
+
+	signalStatusRequested  = "requested"
+	signalStatusInProgress = "in progress"
+	signalStatusCompleted  = "completed"
+
+	// acceptSignalQuery records the signal as requested for the pipeline,
+	// unless it already was, in which case the signal has been received
+	// before and no rows are updated.
+	acceptSignalQuery = `UPDATE %s SET progress = progress || jsonb_build_object($2::text, $3::jsonb)
+	WHERE id = $1::text::bigint AND NOT progress ? $2::text`
+	updateSignalProgressQuery = `UPDATE %s SET progress = progress || jsonb_build_object($2::text, $3::jsonb)
+	WHERE id = $1::text::bigint`
+	pendingSignalsQuery = `SELECT id::text, data::text, (progress->$1::text)::text FROM %s
+	WHERE type = $2 AND progress ? $1::text AND progress->$1::text->>'status' <> 'completed' ORDER BY id`
+)
+
+type signalData struct {
+	Tables []string `json:"tables"`
+}
+
+// signalProgress is the progress of a pipeline with an incremental snapshot
+// request. The table is the one being snapshotted, and the last key the
+// primary key of the last chunk completed for it, if any.
+type signalProgress struct {
+	Status  string   `json:"status"`
+	Table   string   `json:"table,omitempty"`
+	LastKey []string `json:"last_key,omitempty"`
+}
+
+var errNoTables = errors.New("at least one table is required for an incremental snapshot")
+
+// RequestSnapshot requests an incremental snapshot of the tables on input to
+// the pipelines replicating from the source database on input, and returns the
+// id of the request. Tables are expected to be schema qualified, and if not,
+// the `public` schema will be assumed.
+func RequestSnapshot(ctx context.Context, url string, tables []string) (string, error) {
+	if len(tables) == 0 {
+		return "", errNoTables
+	}
+
+	conn, err := pglib.NewConn(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close(context.Background())
+
+	if err := createSignalsTable(ctx, conn); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(&signalData{Tables: tables})
+	if err != nil {
+		return "", err
+	}
+
+	var id int64
+	query := fmt.Sprintf("INSERT INTO %s(type, data) VALUES($1, $2) RETURNING id", signalsTable())
+	if err := conn.QueryRow(ctx, []any{&id}, query, signalTypeIncrementalSnapshot, string(data)); err != nil {
+		return "", fmt.Errorf("inserting incremental snapshot signal: %w", err)
+	}
+	return fmt.Sprint(id), nil
+}
+
+func createSignalsTable(ctx context.Context, conn pglib.Querier) error {
+	if _, err := conn.Exec(ctx, fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", SignalsSchemaName)); err != nil {
+		return fmt.Errorf("error creating signals schema: %w", err)
+	}
+
+	createTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s(
+	id BIGSERIAL PRIMARY KEY,
+	type TEXT NOT NULL,
+	data JSONB NOT NULL,
+	progress JSONB NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT now() )`, signalsTable())
+	if _, err := conn.Exec(ctx, createTableQuery); err != nil {
+		return fmt.Errorf("error creating signals table: %w", err)
+	}
+	return nil
+}
+
+func signalsTable() string {
+	return pglib.QuoteQualifiedIdentifier(SignalsSchemaName, SignalsTableName)
+}
+
+func isSignal(d *wal.Data) bool {
+	return d.Schema == SignalsSchemaName && d.Table == SignalsTableName
+}
+
+// signalToRequest returns the incremental snapshot request for the signal on
+// input, or nil if the signal is of a different type.
+func signalToRequest(d *wal.Data) (*request, error) {
+	req := &request{}
+	var signalType, data string
+	for _, col := range d.Columns {
+		switch col.Name {
+		case "id":
+			req.id = toText(col.Value)
+		case "type":
+			signalType = toText(col.Value)
+		case "data":
+			data = toText(col.Value)
+		}
+	}
+
+	if signalType != signalTypeIncrementalSnapshot {
+		return nil, nil
+	}
+
+	signal := &signalData{}
+	if err := json.Unmarshal([]byte(data), signal); err != nil {
+		return nil, fmt.Errorf("parsing signal %s data: %w", req.id, err)
+	}
+	if len(signal.Tables) == 0 {
+		return nil, fmt.Errorf("signal %s: %w", req.id, errNoTables)
+	}
+	req.tables = signal.Tables
+	req.signal = true
+	return req, nil
+}
+
+// acceptSignal records the incremental snapshot request on input as
+// requested by the pipeline. It returns false if the request had already been
+// received, for example when the signal is replayed after a restart, since
+// those are resumed on start.
+func (s *Snapshotter) acceptSignal(ctx context.Context, req *request) (bool, error) {
+	progress, err := json.Marshal(&signalProgress{Status: signalStatusRequested})
+	if err != nil {
+		return false, err
+	}
+	tag, err := s.conn.Exec(ctx, fmt.Sprintf(acceptSignalQuery, signalsTable()), req.id, s.slotName, string(progress))
+	if err != nil {
+		return false, fmt.Errorf("accepting incremental snapshot signal %s: %w", req.id, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// saveProgress persists the progress of the pipeline with the request on
+// input, if it was received through the signals table. Errors are only
+// logged, since they only affect the point the request is resumed from.
+func (s *Snapshotter) saveProgress(ctx context.Context, req *request, progress *signalProgress) {
+	if !req.signal {
+		return
+	}
+	content, err := json.Marshal(progress)
+	if err == nil {
+		_, err = s.conn.Exec(ctx, fmt.Sprintf(updateSignalProgressQuery, signalsTable()), req.id, s.slotName, string(content))
+	}
+	if err != nil {
+		s.logger.Error(err, "saving incremental snapshot progress", loglib.Fields{"snapshot_id": req.id, "status": progress.Status})
+	}
+}
+
+// getPendingSignals returns the incremental snapshot requests received by the
+// pipeline that haven't been completed, along with their progress.
+func (s *Snapshotter) getPendingSignals(ctx context.Context) ([]*request, error) {
+	rows, err := s.conn.Query(ctx, fmt.Sprintf(pendingSignalsQuery, signalsTable()), s.slotName, signalTypeIncrementalSnapshot)
+	if err != nil {
+		return nil, fmt.Errorf("getting pending incremental snapshot signals: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []*request{}
+	for rows.Next() {
+		req := &request{signal: true}
+		var data, progressData string
+		if err := rows.Scan(&req.id, &data, &progressData); err != nil {
+			return nil, fmt.Errorf("scanning incremental snapshot signal: %w", err)
+		}
+		signal := &signalData{}
+		if err := json.Unmarshal([]byte(data), signal); err != nil {
+			return nil, fmt.Errorf("parsing signal %s data: %w", req.id, err)
+		}
+		progress := &signalProgress{}
+		if err := json.Unmarshal([]byte(progressData), progress); err != nil {
+			return nil, fmt.Errorf("parsing signal %s progress: %w", req.id, err)
+		}
+		req.tables = signal.Tables
+		req.table = progress.Table
+		req.lastKey = progress.LastKey
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
@@ -0,0 +1,241 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package incremental
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/snapshot"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// The tracked tables table keeps the tables whose data is known to be
+// replicated by each pipeline, identified by its replication slot. Tables are
+// tracked by OID, so that renaming a table doesn't make it look new. It's used
+// on startup to find the tables that came into the replication scope while the
+// pipeline was stopped.
+const TrackedTablesTableName = "replicated_tables"
+
+const (
+	trackTableQuery = `INSERT INTO %s(slot_name, table_oid, schema_name, table_name)
+	SELECT $1, c.oid, n.nspname, c.relname FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE c.oid = $2::regclass
+	ON CONFLICT (slot_name, table_oid) DO UPDATE SET schema_name = EXCLUDED.schema_name, table_name = EXCLUDED.table_name`
+	untrackTablesQuery = `DELETE FROM %s WHERE slot_name = $1 AND NOT table_oid = ANY($2::oid[])`
+	trackedTablesQuery = `SELECT table_oid FROM %s WHERE slot_name = $1`
+
+	// scopeTablesQuery returns the user tables of the source database.
+	// Partitions are snapshotted through their root table.
+	scopeTablesQuery = `SELECT c.oid, n.nspname, c.relname FROM pg_class c
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE c.relkind IN ('r', 'p') AND NOT c.relispartition
+	AND n.nspname NOT IN ('pg_catalog', 'information_schema', 'pgstream')
+	AND n.nspname NOT LIKE 'pg_toast%' AND n.nspname NOT LIKE 'pg_temp%'
+	ORDER BY n.nspname, c.relname`
+
+	currentDatabaseQuery = `SELECT current_database()`
+)
+
+// ddlCommandTagsCreateTable are the commands that create a table. The rows of
+// the tables created with data (CREATE TABLE AS, SELECT INTO) are decoded as
+// any other insert, so they're part of the replication stream too.
+var ddlCommandTagsCreateTable = []string{"CREATE TABLE", "CREATE TABLE AS", "SELECT INTO"}
+
+type scopeTable struct {
+	oid    uint32
+	schema string
+	name   string
+}
+
+func createTrackedTablesTable(ctx context.Context, conn pglib.Querier) error {
+	createTableQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s(
+	slot_name TEXT NOT NULL,
+	table_oid OID NOT NULL,
+	schema_name TEXT NOT NULL,
+	table_name TEXT NOT NULL,
+	tracked_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+	PRIMARY KEY (slot_name, table_oid) )`, trackedTablesTable())
+	if _, err := conn.Exec(ctx, createTableQuery); err != nil {
+		return fmt.Errorf("error creating tracked tables table: %w", err)
+	}
+	return nil
+}
+
+func trackedTablesTable() string {
+	return pglib.QuoteQualifiedIdentifier(SignalsSchemaName, TrackedTablesTableName)
+}
+
+// initAutoSnapshot sets up the replication scope and the tracked tables for
+// the automatic snapshots, and requests the snapshot of the tables that came
+// into scope since the last run.
+func (s *Snapshotter) initAutoSnapshot(ctx context.Context, cfg *Config) error {
+	var err error
+	if len(cfg.IncludeTables) > 0 {
+		if s.includeTables, err = pglib.NewSchemaTableMap(cfg.IncludeTables); err != nil {
+			return err
+		}
+	}
+	if len(cfg.ExcludeTables) > 0 {
+		if s.excludeTables, err = pglib.NewSchemaTableMap(cfg.ExcludeTables); err != nil {
+			return err
+		}
+	}
+
+	if err := createTrackedTablesTable(ctx, s.conn); err != nil {
+		return err
+	}
+	return s.snapshotNewTables(ctx)
+}
+
+// snapshotNewTables compares the tables in the replication scope with the
+// tracked ones, and requests the incremental snapshot of the tables that
+// aren't tracked yet. The tables no longer in scope stop being tracked, so
+// that they're snapshotted again if they come back into scope. The schema of
+// the new tables is restored before their data is snapshotted, since their DDL
+// events were filtered out while they were out of scope. On the first run, all
+// the tables in scope are tracked without being snapshotted, since their data
+// is either part of the initial snapshot or not wanted.
+func (s *Snapshotter) snapshotNewTables(ctx context.Context) error {
+	scope, err := s.getScopeTables(ctx)
+	if err != nil {
+		return err
+	}
+	tracked, err := s.getTrackedTables(ctx)
+	if err != nil {
+		return err
+	}
+
+	oids := make([]uint32, 0, len(scope))
+	newTables := []string{}
+	newSchemaTables := map[string][]string{}
+	for _, t := range scope {
+		oids = append(oids, t.oid)
+		if !slices.Contains(tracked, t.oid) {
+			newTables = append(newTables, pglib.QuoteQualifiedIdentifier(t.schema, t.name))
+			newSchemaTables[t.schema] = append(newSchemaTables[t.schema], t.name)
+		}
+	}
+
+	if _, err := s.conn.Exec(ctx, fmt.Sprintf(untrackTablesQuery, trackedTablesTable()), s.slotName, oids); err != nil {
+		return fmt.Errorf("untracking tables out of scope: %w", err)
+	}
+
+	if len(tracked) == 0 {
+		s.logger.Info("tracking replicated tables", loglib.Fields{"tables": len(newTables)})
+		for _, table := range newTables {
+			if err := s.trackTable(ctx, table); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(newTables) == 0 {
+		return nil
+	}
+
+	if s.schemaSnapshotter != nil {
+		s.logger.Info("restoring schema of tables new in replication scope", loglib.Fields{"tables": newTables})
+		if err := s.schemaSnapshotter.CreateSnapshot(ctx, &snapshot.Snapshot{SchemaTables: newSchemaTables}); err != nil {
+			return fmt.Errorf("restoring schema of tables new in replication scope: %w", err)
+		}
+	}
+
+	s.requestAutoSnapshot(newTables)
+	return nil
+}
+
+// handleDDL tracks the tables created in the replication scope. Their rows are
+// replicated from the start, including the ones they're created with, so they
+// don't need to be snapshotted.
+func (s *Snapshotter) handleDDL(ctx context.Context, d *wal.Data) {
+	ddlEvent, err := wal.WalDataToDDLEvent(d)
+	if err != nil {
+		s.logger.Error(err, "parsing DDL event for automatic incremental snapshot")
+		return
+	}
+	if !slices.Contains(ddlCommandTagsCreateTable, ddlEvent.CommandTag) {
+		return
+	}
+
+	for _, obj := range ddlEvent.GetTableObjects() {
+		if !s.inScope(obj.Schema, obj.GetTable()) {
+			continue
+		}
+		table := pglib.QuoteQualifiedIdentifier(obj.Schema, obj.GetTable())
+		if err := s.trackTable(ctx, table); err != nil {
+			s.logger.Error(err, "tracking new table", loglib.Fields{"table": table})
+		}
+	}
+}
+
+func (s *Snapshotter) requestAutoSnapshot(tables []string) {
+	s.enqueueRequest(&request{
+		id:     fmt.Sprintf("auto-%d", time.Now().UnixNano()),
+		tables: tables,
+	})
+}
+
+func (s *Snapshotter) trackTable(ctx context.Context, qualifiedTable string) error {
+	if _, err := s.conn.Exec(ctx, fmt.Sprintf(trackTableQuery, trackedTablesTable()), s.slotName, qualifiedTable); err != nil {
+		return fmt.Errorf("tracking table %s: %w", qualifiedTable, err)
+	}
+	return nil
+}
+
+func (s *Snapshotter) getTrackedTables(ctx context.Context) ([]uint32, error) {
+	rows, err := s.conn.Query(ctx, fmt.Sprintf(trackedTablesQuery, trackedTablesTable()), s.slotName)
+	if err != nil {
+		return nil, fmt.Errorf("getting tracked tables: %w", err)
+	}
+	defer rows.Close()
+
+	oids := []uint32{}
+	for rows.Next() {
+		var oid uint32
+		if err := rows.Scan(&oid); err != nil {
+			return nil, fmt.Errorf("scanning tracked table: %w", err)
+		}
+		oids = append(oids, oid)
+	}
+	return oids, rows.Err()
+}
+
+// getScopeTables returns the tables of the source database in the replication
+// scope.
+func (s *Snapshotter) getScopeTables(ctx context.Context) ([]*scopeTable, error) {
+	rows, err := s.conn.Query(ctx, scopeTablesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("getting tables in replication scope: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []*scopeTable{}
+	for rows.Next() {
+		t := &scopeTable{}
+		if err := rows.Scan(&t.oid, &t.schema, &t.name); err != nil {
+			return nil, fmt.Errorf("scanning table in replication scope: %w", err)
+		}
+		if s.inScope(t.schema, t.name) {
+			tables = append(tables, t)
+		}
+	}
+	return tables, rows.Err()
+}
+
+// inScope returns true if the table on input is replicated, following the
+// same rules as the filter processor.
+func (s *Snapshotter) inScope(schema, table string) bool {
+	if len(s.includeTables) > 0 {
+		return s.includeTables.ContainsSchemaTable(schema, table)
+	}
+	if len(s.excludeTables) > 0 {
+		return !s.excludeTables.ContainsSchemaTable(schema, table)
+	}
+	return true
+}
@@ -0,0 +1,303 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package incremental
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/snapshot"
+	generatormocks "github.com/xataio/pgstream/pkg/snapshot/generator/mocks"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/mocks"
+)
+
+func TestSnapshotter_snapshotNewTables(t *testing.T) {
+	t.Parallel()
+
+	testSlot := "pgstream_test_slot"
+	scopeRows := func(tables []*scopeTable) *pgmocks.Rows {
+		return &pgmocks.Rows{
+			CloseFn: func() {},
+			NextFn:  func(n uint) bool { return int(n) <= len(tables) },
+			ScanFn: func(n uint, dest ...any) error {
+				*(dest[0].(*uint32)) = tables[n-1].oid
+				*(dest[1].(*string)) = tables[n-1].schema
+				*(dest[2].(*string)) = tables[n-1].name
+				return nil
+			},
+			ErrFn: func() error { return nil },
+		}
+	}
+	trackedRows := func(oids []uint32) *pgmocks.Rows {
+		return &pgmocks.Rows{
+			CloseFn: func() {},
+			NextFn:  func(n uint) bool { return int(n) <= len(oids) },
+			ScanFn: func(n uint, dest ...any) error {
+				*(dest[0].(*uint32)) = oids[n-1]
+				return nil
+			},
+			ErrFn: func() error { return nil },
+		}
+	}
+
+	sourceTables := []*scopeTable{
+		{oid: 1, schema: "public", name: "users"},
+		{oid: 2, schema: "public", name: "orders"},
+		{oid: 3, schema: "other", name: "items"},
+	}
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name          string
+		includeTables []string
+		tracked       []uint32
+		execErr       error
+		schemaErr     error
+
+		wantUntracked []uint32
+		wantTracked   []string
+		wantSchema    map[string][]string
+		wantRequest   []string
+		wantErr       error
+	}{
+		{
+			name:    "first run - all tables in scope tracked",
+			tracked: []uint32{},
+
+			wantUntracked: []uint32{1, 2, 3},
+			wantTracked:   []string{`"public"."users"`, `"public"."orders"`, `"other"."items"`},
+		},
+		{
+			name:          "new tables in scope snapshotted",
+			includeTables: []string{"public.*"},
+			tracked:       []uint32{1, 3},
+
+			wantUntracked: []uint32{1, 2},
+			wantTracked:   []string{},
+			wantSchema:    map[string][]string{"public": {"orders"}},
+			wantRequest:   []string{`"public"."orders"`},
+		},
+		{
+			name:    "new tables in several schemas snapshotted",
+			tracked: []uint32{1},
+
+			wantUntracked: []uint32{1, 2, 3},
+			wantTracked:   []string{},
+			wantSchema:    map[string][]string{"public": {"orders"}, "other": {"items"}},
+			wantRequest:   []string{`"public"."orders"`, `"other"."items"`},
+		},
+		{
+			name:    "no new tables",
+			tracked: []uint32{1, 2, 3},
+
+			wantUntracked: []uint32{1, 2, 3},
+			wantTracked:   []string{},
+		},
+		{
+			name:      "error - restoring schema",
+			tracked:   []uint32{1, 3},
+			schemaErr: errTest,
+
+			wantUntracked: []uint32{1, 2, 3},
+			wantTracked:   []string{},
+			wantSchema:    map[string][]string{"public": {"orders"}},
+			wantErr:       errTest,
+		},
+		{
+			name:    "error - untracking tables",
+			tracked: []uint32{1},
+			execErr: errTest,
+
+			wantUntracked: []uint32{1, 2, 3},
+			wantTracked:   []string{},
+			wantErr:       errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var untracked []uint32
+			tracked := []string{}
+			var schemaTables map[string][]string
+			s := &Snapshotter{
+				logger:   loglib.NewNoopLogger(),
+				slotName: testSlot,
+				requests: newRequestQueue(),
+				conn: &pgmocks.Querier{
+					QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+						switch i {
+						case 1:
+							require.Equal(t, scopeTablesQuery, query)
+							return scopeRows(sourceTables), nil
+						case 2:
+							require.Equal(t, fmt.Sprintf(trackedTablesQuery, trackedTablesTable()), query)
+							require.Equal(t, []any{testSlot}, args)
+							return trackedRows(tc.tracked), nil
+						default:
+							return nil, fmt.Errorf("unexpected call to QueryFn: %d", i)
+						}
+					},
+					ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+						if i == 1 {
+							require.Equal(t, fmt.Sprintf(untrackTablesQuery, trackedTablesTable()), query)
+							require.Equal(t, testSlot, args[0])
+							untracked = args[1].([]uint32)
+							return pglib.CommandTag{}, tc.execErr
+						}
+						require.Equal(t, fmt.Sprintf(trackTableQuery, trackedTablesTable()), query)
+						require.Equal(t, testSlot, args[0])
+						tracked = append(tracked, args[1].(string))
+						return pglib.CommandTag{}, nil
+					},
+				},
+				schemaSnapshotter: &generatormocks.Generator{
+					CreateSnapshotFn: func(ctx context.Context, ss *snapshot.Snapshot) error {
+						schemaTables = ss.SchemaTables
+						return tc.schemaErr
+					},
+				},
+			}
+			if len(tc.includeTables) > 0 {
+				var err error
+				s.includeTables, err = pglib.NewSchemaTableMap(tc.includeTables)
+				require.NoError(t, err)
+			}
+
+			err := s.snapshotNewTables(context.Background())
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantUntracked, untracked)
+			require.Equal(t, tc.wantTracked, tracked)
+			require.Equal(t, tc.wantSchema, schemaTables)
+
+			var reqTables []string
+			if req := s.requests.pop(); req != nil {
+				reqTables = req.tables
+			}
+			require.Equal(t, tc.wantRequest, reqTables)
+		})
+	}
+}
+
+func TestSnapshotter_ProcessWALEvent_ddl(t *testing.T) {
+	t.Parallel()
+
+	ddlEvent := func(commandTag string, tables ...string) *wal.Event {
+		objects := []wal.DDLObject{}
+		for _, table := range tables {
+			objects = append(objects, wal.DDLObject{Type: "table", Identity: "public." + table, Schema: "public"})
+		}
+		content, err := json.Marshal(&wal.DDLEvent{CommandTag: commandTag, SchemaName: "public", Objects: objects})
+		require.NoError(t, err)
+		return &wal.Event{
+			Data: &wal.Data{
+				Action:  wal.LogicalMessageAction,
+				Prefix:  wal.DDLPrefix,
+				Content: string(content),
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		event        *wal.Event
+		autoSnapshot bool
+
+		wantTracked []string
+		wantRequest []string
+	}{
+		{
+			name:         "create table as - table tracked",
+			event:        ddlEvent("CREATE TABLE AS", "users_copy"),
+			autoSnapshot: true,
+
+			wantTracked: []string{`"public"."users_copy"`},
+		},
+		{
+			name:         "select into - table tracked",
+			event:        ddlEvent("SELECT INTO", "users_copy"),
+			autoSnapshot: true,
+
+			wantTracked: []string{`"public"."users_copy"`},
+		},
+		{
+			name:         "create table - table tracked",
+			event:        ddlEvent("CREATE TABLE", "users"),
+			autoSnapshot: true,
+
+			wantTracked: []string{`"public"."users"`},
+		},
+		{
+			name:         "create table as - table out of scope",
+			event:        ddlEvent("CREATE TABLE AS", "excluded"),
+			autoSnapshot: true,
+
+			wantTracked: []string{},
+		},
+		{
+			name:         "alter table - ignored",
+			event:        ddlEvent("ALTER TABLE", "users"),
+			autoSnapshot: true,
+
+			wantTracked: []string{},
+		},
+		{
+			name:  "create table as - automatic snapshots disabled",
+			event: ddlEvent("CREATE TABLE AS", "users_copy"),
+
+			wantTracked: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			excludeTables, err := pglib.NewSchemaTableMap([]string{"public.excluded"})
+			require.NoError(t, err)
+
+			tracked := []string{}
+			processed := 0
+			s := &Snapshotter{
+				logger: loglib.NewNoopLogger(),
+				processor: &mocks.Processor{
+					ProcessWALEventFn: func(ctx context.Context, e *wal.Event) error {
+						processed++
+						return nil
+					},
+				},
+				conn: &pgmocks.Querier{
+					ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+						require.Equal(t, fmt.Sprintf(trackTableQuery, trackedTablesTable()), query)
+						tracked = append(tracked, args[1].(string))
+						return pglib.CommandTag{}, nil
+					},
+				},
+				autoSnapshot:  tc.autoSnapshot,
+				excludeTables: excludeTables,
+				requests:      newRequestQueue(),
+			}
+
+			err = s.ProcessWALEvent(context.Background(), tc.event)
+			require.NoError(t, err)
+			// DDL events are always replicated
+			require.Equal(t, 1, processed)
+			require.Equal(t, tc.wantTracked, tracked)
+
+			var reqTables []string
+			if req := s.requests.pop(); req != nil {
+				reqTables = req.tables
+			}
+			require.Equal(t, tc.wantRequest, reqTables)
+		})
+	}
+}
@@ -0,0 +1,758 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package incremental
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/xataio/pgstream/internal/json"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/snapshot/generator"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+)
+
+// Snapshotter is a processor wrapper that performs incremental snapshots of
+// tables while the replication is ongoing, following the watermark based
+// approach from DBLog. Tables are read in chunks ordered by primary key, and
+// each chunk is read in between a low and a high watermark logical message.
+// The rows of the chunk modified by WAL events received in between the
+// watermarks are discarded, since those events carry a more recent version of
+// the row. The remaining rows are emitted at the position of the high
+// watermark, so that they're ordered consistently with the WAL events.
+//
+// Incremental snapshots are requested by inserting a signal in the signals
+// table of the source database (see RequestSnapshot), or automatically for the
+// tables that come into the replication scope when enabled.
+type Snapshotter struct {
+	logger    loglib.Logger
+	processor processor.Processor
+	conn      pglib.Querier
+	mapper    mapper
+	chunkSize uint
+
+	// autoSnapshot enables the incremental snapshot of the tables that come
+	// into the replication scope, defined by the include/exclude tables.
+	autoSnapshot  bool
+	includeTables pglib.SchemaTableMap
+	excludeTables pglib.SchemaTableMap
+	slotName      string
+	// schemaSnapshotter restores the schema of the tables that came into the
+	// replication scope while the pipeline was stopped, since their DDL
+	// events were filtered out. Optional.
+	schemaSnapshotter generator.SnapshotGenerator
+
+	// requests is not bounded, since blocking the WAL processing until the
+	// ongoing snapshot is completed would also block its watermarks.
+	requests *requestQueue
+
+	// current is the chunk being snapshotted. It's set by the snapshot worker
+	// and completed when its high watermark is processed.
+	current *chunk
+	mutex   sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type Config struct {
+	// URL of the source Postgres database, used to read the table chunks and
+	// emit the watermarks.
+	URL string
+	// ChunkSize is the number of rows read per chunk. Defaults to 1024.
+	ChunkSize uint
+	// AutoSnapshotNewTables enables the incremental snapshot of the tables
+	// that come into the replication scope while the pipeline is stopped, for
+	// example when they're added to the include tables. Tables already
+	// replicated are not snapshotted again.
+	AutoSnapshotNewTables bool
+	// IncludeTables and ExcludeTables define the replication scope for the
+	// automatic snapshots, and are expected to match the filter configuration.
+	// All tables are in scope if none are provided.
+	IncludeTables []string
+	ExcludeTables []string
+	// ReplicationSlotName identifies the pipeline the replicated tables and
+	// the progress of the snapshot requests are tracked for. Defaults to the
+	// pgstream replication slot name for the source database.
+	ReplicationSlotName string
+}
+
+type Option func(*Snapshotter)
+
+type mapper interface {
+	TypeForOID(context.Context, uint32) (string, error)
+}
+
+type request struct {
+	id     string
+	tables []string
+	// signal is set for the requests received through the signals table,
+	// whose progress is persisted so that they're resumed on restart
+	signal bool
+	// table and lastKey are the table and the primary key of its last
+	// completed chunk the request is resumed from, if any
+	table   string
+	lastKey []string
+}
+
+// requestQueue keeps the incremental snapshot requests waiting to be
+// processed, and notifies when new ones are added.
+type requestQueue struct {
+	mutex    sync.Mutex
+	requests []*request
+	notify   chan struct{}
+}
+
+type keyColumn struct {
+	name     string
+	dataType string
+}
+
+type chunk struct {
+	snapshotID string
+	number     uint
+	schema     string
+	table      string
+	keyColumns []keyColumn
+
+	// open is set once the low watermark has been processed
+	open bool
+	// rows read for the chunk, by primary key, in the order they were read
+	rows []*chunkRow
+	// modified keeps the primary keys of the rows modified by WAL events
+	// received while the chunk window is open
+	modified map[string]struct{}
+	done     chan struct{}
+}
+
+type chunkRow struct {
+	key   string
+	event *wal.Event
+}
+
+type watermark struct {
+	SnapshotID string `json:"snapshot_id"`
+	Table      string `json:"table"`
+	Chunk      uint   `json:"chunk"`
+	Type       string `json:"type"`
+}
+
+const (
+	watermarkPrefix = "pgstream.watermark"
+	lowWatermark    = "low"
+	highWatermark   = "high"
+
+	defaultChunkSize = 1024
+
+	emitWatermarkQuery = `SELECT pg_logical_emit_message(true, $1, $2)`
+
+	primaryKeyColumnsQuery = `SELECT a.attname, format_type(a.atttypid, a.atttypmod) FROM pg_index i
+	JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+	WHERE i.indrelid = $1::regclass AND i.indisprimary
+	ORDER BY array_position(i.indkey::int2[], a.attnum)`
+)
+
+var (
+	errMissingURL   = errors.New("source postgres URL required for incremental snapshots")
+	errNoPrimaryKey = errors.New("incremental snapshots require a primary key")
+)
+
+// New will return a processor wrapper that performs the incremental snapshots
+// requested through the signals table, emitting the snapshotted rows to the
+// wrapped processor. If automatic snapshots are enabled, the tables that came
+// into the replication scope since the last run are snapshotted on start.
+func New(ctx context.Context, processor processor.Processor, cfg *Config, opts ...Option) (*Snapshotter, error) {
+	if cfg.URL == "" {
+		return nil, errMissingURL
+	}
+
+	conn, err := pglib.NewConnPool(ctx, cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := createSignalsTable(ctx, conn); err != nil {
+		conn.Close(ctx)
+		return nil, err
+	}
+
+	s := &Snapshotter{
+		logger:       loglib.NewNoopLogger(),
+		processor:    processor,
+		conn:         conn,
+		mapper:       pglib.NewMapper(conn),
+		chunkSize:    cfg.chunkSize(),
+		autoSnapshot: cfg.AutoSnapshotNewTables,
+		slotName:     cfg.ReplicationSlotName,
+		requests:     newRequestQueue(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := s.init(ctx, cfg); err != nil {
+		if s.schemaSnapshotter != nil {
+			s.schemaSnapshotter.Close()
+		}
+		conn.Close(ctx)
+		return nil, err
+	}
+
+	s.start(ctx)
+
+	return s, nil
+}
+
+func WithLogger(logger loglib.Logger) Option {
+	return func(s *Snapshotter) {
+		s.logger = loglib.NewLogger(logger).WithFields(loglib.Fields{
+			loglib.ModuleField: "wal_incremental_snapshotter",
+		})
+	}
+}
+
+// WithSchemaSnapshotGenerator sets the generator used to restore the schema of
+// the tables that come into the replication scope before their data is
+// snapshotted.
+func WithSchemaSnapshotGenerator(g generator.SnapshotGenerator) Option {
+	return func(s *Snapshotter) {
+		s.schemaSnapshotter = g
+	}
+}
+
+func (s *Snapshotter) ProcessWALEvent(ctx context.Context, event *wal.Event) error {
+	if event.Data != nil {
+		switch {
+		case isSignal(event.Data):
+			// signals are not replicated
+			if event.Data.IsInsert() {
+				return s.handleSignal(ctx, event.Data)
+			}
+			return nil
+		case isWatermark(event.Data):
+			return s.handleWatermark(ctx, event)
+		case event.Data.IsDDLEvent():
+			if s.autoSnapshot {
+				s.handleDDL(ctx, event.Data)
+			}
+		default:
+			s.trackModifiedRow(event.Data)
+		}
+	}
+
+	return s.processor.ProcessWALEvent(ctx, event)
+}
+
+func (s *Snapshotter) Name() string {
+	return s.processor.Name()
+}
+
+func (s *Snapshotter) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	if s.schemaSnapshotter != nil {
+		if err := s.schemaSnapshotter.Close(); err != nil {
+			s.logger.Error(err, "closing incremental snapshotter schema generator")
+		}
+	}
+	if err := s.conn.Close(context.Background()); err != nil {
+		s.logger.Error(err, "closing incremental snapshotter connection")
+	}
+	return s.processor.Close()
+}
+
+// init resolves the replication slot name the progress is tracked for, and
+// queues the requests received before the last restart that were not
+// completed, along with the ones for the tables that came into the
+// replication scope if automatic snapshots are enabled.
+func (s *Snapshotter) init(ctx context.Context, cfg *Config) error {
+	if s.slotName == "" {
+		var dbName string
+		if err := s.conn.QueryRow(ctx, []any{&dbName}, currentDatabaseQuery); err != nil {
+			return fmt.Errorf("getting source database name: %w", err)
+		}
+		s.slotName = pglib.DefaultReplicationSlotName(dbName)
+	}
+
+	pending, err := s.getPendingSignals(ctx)
+	if err != nil {
+		return err
+	}
+	for _, req := range pending {
+		s.logger.Info("resuming incremental snapshot", loglib.Fields{"snapshot_id": req.id, "tables": req.tables, "table": req.table})
+		s.requests.push(req)
+	}
+
+	if s.autoSnapshot {
+		return s.initAutoSnapshot(ctx, cfg)
+	}
+	return nil
+}
+
+// start processes the incremental snapshot requests in the background, one
+// at a time, until the snapshotter is closed.
+func (s *Snapshotter) start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.requests.notify:
+				for req := s.requests.pop(); req != nil && ctx.Err() == nil; req = s.requests.pop() {
+					s.processRequest(ctx, req)
+				}
+			}
+		}
+	}()
+}
+
+func (s *Snapshotter) processRequest(ctx context.Context, req *request) {
+	for _, table := range req.tables {
+		var lastKey []string
+		if req.table != "" {
+			// skip the tables completed before the request was resumed
+			if table != req.table {
+				continue
+			}
+			lastKey = req.lastKey
+			req.table = ""
+		}
+
+		logFields := loglib.Fields{"snapshot_id": req.id, "table": table}
+		s.logger.Info("starting incremental snapshot", logFields)
+		s.saveProgress(ctx, req, &signalProgress{Status: signalStatusInProgress, Table: table, LastKey: lastKey})
+		start := time.Now()
+		rows, err := s.snapshotTable(ctx, req, table, lastKey)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Error(err, "incremental snapshot", logFields)
+			continue
+		}
+		logFields["rows"] = rows
+		logFields["duration"] = time.Since(start).String()
+		s.logger.Info("incremental snapshot completed", logFields)
+
+		if s.autoSnapshot {
+			s.trackSnapshottedTable(ctx, table)
+		}
+	}
+	s.saveProgress(ctx, req, &signalProgress{Status: signalStatusCompleted})
+}
+
+// trackSnapshottedTable tracks the table on input once its data has been
+// snapshotted, if it's in the replication scope, so that it's not snapshotted
+// again automatically.
+func (s *Snapshotter) trackSnapshottedTable(ctx context.Context, qualifiedTable string) {
+	schema, table, err := parseTable(qualifiedTable)
+	if err != nil || !s.inScope(schema, table) {
+		return
+	}
+	if err := s.trackTable(ctx, pglib.QuoteQualifiedIdentifier(schema, table)); err != nil {
+		s.logger.Error(err, "tracking snapshotted table", loglib.Fields{"table": qualifiedTable})
+	}
+}
+
+// snapshotTable snapshots the table on input chunk by chunk, starting after
+// the last key on input if any, and waiting for the high watermark of each
+// chunk to be processed before reading the next one. It returns the number of
+// rows read.
+func (s *Snapshotter) snapshotTable(ctx context.Context, req *request, qualifiedTable string, lastKey []string) (uint, error) {
+	schema, table, err := parseTable(qualifiedTable)
+	if err != nil {
+		return 0, err
+	}
+
+	keyColumns, err := s.getPrimaryKeyColumns(ctx, schema, table)
+	if err != nil {
+		return 0, err
+	}
+
+	defer s.setCurrentChunk(nil)
+
+	totalRows := uint(0)
+	for number := uint(1); ; number++ {
+		c := &chunk{
+			snapshotID: req.id,
+			number:     number,
+			schema:     schema,
+			table:      table,
+			keyColumns: keyColumns,
+			modified:   map[string]struct{}{},
+			done:       make(chan struct{}),
+		}
+		s.setCurrentChunk(c)
+
+		if err := s.emitWatermark(ctx, c, lowWatermark); err != nil {
+			return totalRows, err
+		}
+
+		rows, chunkLastKey, err := s.readChunk(ctx, c, lastKey)
+		if err != nil {
+			return totalRows, err
+		}
+		s.mutex.Lock()
+		c.rows = rows
+		s.mutex.Unlock()
+
+		if err := s.emitWatermark(ctx, c, highWatermark); err != nil {
+			return totalRows, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return totalRows, ctx.Err()
+		case <-c.done:
+		}
+
+		totalRows += uint(len(rows))
+		if uint(len(rows)) < s.chunkSize {
+			return totalRows, nil
+		}
+		lastKey = chunkLastKey
+		s.saveProgress(ctx, req, &signalProgress{Status: signalStatusInProgress, Table: qualifiedTable, LastKey: lastKey})
+	}
+}
+
+func (s *Snapshotter) setCurrentChunk(c *chunk) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.current = c
+}
+
+func (s *Snapshotter) emitWatermark(ctx context.Context, c *chunk, watermarkType string) error {
+	content, err := json.Marshal(&watermark{
+		SnapshotID: c.snapshotID,
+		Table:      c.qualifiedTable(),
+		Chunk:      c.number,
+		Type:       watermarkType,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := s.conn.Exec(ctx, emitWatermarkQuery, watermarkPrefix, string(content)); err != nil {
+		return fmt.Errorf("emitting %s watermark: %w", watermarkType, err)
+	}
+	return nil
+}
+
+// readChunk reads the rows of the chunk following the last key on input, and
+// returns them along with the key of the last row read.
+func (s *Snapshotter) readChunk(ctx context.Context, c *chunk, lastKey []string) ([]*chunkRow, []string, error) {
+	args := make([]any, 0, len(lastKey))
+	for _, v := range lastKey {
+		args = append(args, v)
+	}
+
+	rows, err := s.conn.Query(ctx, chunkQuery(c, len(lastKey) > 0, s.chunkSize), args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading chunk %d of table %s.%s: %w", c.number, c.schema, c.table, err)
+	}
+	defer rows.Close()
+
+	// the primary key columns are selected as text at the end of each row,
+	// so that they can be compared with the WAL event values
+	keyCount := len(c.keyColumns)
+	fieldDescriptions := rows.FieldDescriptions()
+	fieldDescriptions = fieldDescriptions[:len(fieldDescriptions)-keyCount]
+
+	chunkRows := []*chunkRow{}
+	var key []string
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, nil, fmt.Errorf("retrieving rows values: %w", err)
+		}
+
+		key = make([]string, 0, keyCount)
+		for _, v := range values[len(values)-keyCount:] {
+			key = append(key, toText(v))
+		}
+
+		chunkRows = append(chunkRows, &chunkRow{
+			key:   rowKey(key),
+			event: s.rowToWalEvent(ctx, c, fieldDescriptions, values[:len(values)-keyCount]),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return chunkRows, key, nil
+}
+
+func (s *Snapshotter) rowToWalEvent(ctx context.Context, c *chunk, fieldDescriptions []pgconn.FieldDescription, values []any) *wal.Event {
+	columns := make([]wal.Column, 0, len(values))
+	for i, value := range values {
+		dataType, err := s.mapper.TypeForOID(ctx, fieldDescriptions[i].DataTypeOID)
+		if err != nil {
+			s.logger.Warn(err, "unknown data type OID", loglib.Fields{"data_type_oid": fieldDescriptions[i].DataTypeOID})
+			continue
+		}
+		columns = append(columns, wal.Column{
+			Name:  fieldDescriptions[i].Name,
+			Type:  dataType,
+			Value: value,
+		})
+	}
+
+	return &wal.Event{
+		Data: &wal.Data{
+			Action:    "I",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Schema:    c.schema,
+			Table:     c.table,
+			Columns:   columns,
+		},
+	}
+}
+
+// handleSignal queues the incremental snapshot requested by the signal on
+// input. The request is persisted before the signal is acknowledged, so an
+// error is returned if that fails, rather than losing the request.
+func (s *Snapshotter) handleSignal(ctx context.Context, d *wal.Data) error {
+	req, err := signalToRequest(d)
+	if err != nil {
+		s.logger.Error(err, "invalid incremental snapshot signal")
+		return nil
+	}
+	if req == nil {
+		return nil
+	}
+
+	accepted, err := s.acceptSignal(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		s.logger.Debug("incremental snapshot signal already received", loglib.Fields{"snapshot_id": req.id})
+		return nil
+	}
+	s.enqueueRequest(req)
+	return nil
+}
+
+func (s *Snapshotter) enqueueRequest(req *request) {
+	s.requests.push(req)
+	s.logger.Info("incremental snapshot requested", loglib.Fields{"snapshot_id": req.id, "tables": req.tables})
+}
+
+// handleWatermark opens the window of the current chunk on its low watermark,
+// and emits the rows of the chunk that haven't been modified in the window on
+// its high watermark. Watermarks that don't belong to the current chunk (for
+// example from a previous run) are ignored.
+func (s *Snapshotter) handleWatermark(ctx context.Context, event *wal.Event) error {
+	w := &watermark{}
+	if err := json.Unmarshal([]byte(event.Data.Content), w); err != nil {
+		s.logger.Error(err, "invalid watermark", loglib.Fields{"content": event.Data.Content})
+		return nil
+	}
+
+	s.mutex.Lock()
+	c := s.current
+	if c == nil || c.snapshotID != w.SnapshotID || c.qualifiedTable() != w.Table || c.number != w.Chunk {
+		s.mutex.Unlock()
+		return nil
+	}
+
+	if w.Type == lowWatermark {
+		c.open = true
+		s.mutex.Unlock()
+		return nil
+	}
+
+	// the chunk is completed, the snapshot worker will move on to the next
+	s.current = nil
+	events := c.unmodifiedEvents()
+	s.mutex.Unlock()
+	defer close(c.done)
+
+	for _, e := range events {
+		e.CommitPosition = event.CommitPosition
+		e.Data.LSN = event.Data.LSN
+		if err := s.processor.ProcessWALEvent(ctx, e); err != nil {
+			return fmt.Errorf("processing incremental snapshot row: %w", err)
+		}
+	}
+	return nil
+}
+
+// trackModifiedRow keeps track of the primary key of the rows of the current
+// chunk table modified while the chunk window is open.
+func (s *Snapshotter) trackModifiedRow(d *wal.Data) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c := s.current
+	if c == nil || !c.open || d.Schema != c.schema || d.Table != c.table {
+		return
+	}
+
+	for _, columns := range [][]wal.Column{d.Columns, d.Identity} {
+		if key, found := c.keyFromColumns(columns); found {
+			c.modified[key] = struct{}{}
+		}
+	}
+}
+
+func newRequestQueue() *requestQueue {
+	return &requestQueue{notify: make(chan struct{}, 1)}
+}
+
+func (q *requestQueue) push(req *request) {
+	q.mutex.Lock()
+	q.requests = append(q.requests, req)
+	q.mutex.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+		// a notification is already pending
+	}
+}
+
+// pop returns the oldest request in the queue, or nil if it's empty.
+func (q *requestQueue) pop() *request {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.requests) == 0 {
+		return nil
+	}
+	req := q.requests[0]
+	q.requests = q.requests[1:]
+	return req
+}
+
+func (c *chunk) qualifiedTable() string {
+	return pglib.QuoteQualifiedIdentifier(c.schema, c.table)
+}
+
+func (c *chunk) unmodifiedEvents() []*wal.Event {
+	events := make([]*wal.Event, 0, len(c.rows))
+	for _, row := range c.rows {
+		if _, found := c.modified[row.key]; found {
+			continue
+		}
+		events = append(events, row.event)
+	}
+	return events
+}
+
+func (c *chunk) keyFromColumns(columns []wal.Column) (string, bool) {
+	if len(columns) == 0 {
+		return "", false
+	}
+	key := make([]string, 0, len(c.keyColumns))
+	for _, keyCol := range c.keyColumns {
+		idx := slices.IndexFunc(columns, func(col wal.Column) bool { return col.Name == keyCol.name })
+		if idx < 0 {
+			return "", false
+		}
+		key = append(key, toText(columns[idx].Value))
+	}
+	return rowKey(key), true
+}
+
+func (s *Snapshotter) getPrimaryKeyColumns(ctx context.Context, schema, table string) ([]keyColumn, error) {
+	qualifiedTable := pglib.QuoteQualifiedIdentifier(schema, table)
+	rows, err := s.conn.Query(ctx, primaryKeyColumnsQuery, qualifiedTable)
+	if err != nil {
+		return nil, fmt.Errorf("getting primary key columns for table %s: %w", qualifiedTable, err)
+	}
+	defer rows.Close()
+
+	columns := []keyColumn{}
+	for rows.Next() {
+		var col keyColumn
+		if err := rows.Scan(&col.name, &col.dataType); err != nil {
+			return nil, fmt.Errorf("scanning primary key column: %w", err)
+		}
+		columns = append(columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s: %w", qualifiedTable, errNoPrimaryKey)
+	}
+	return columns, nil
+}
+
+// chunkQuery returns the query that reads the rows of the chunk ordered by
+// primary key, starting after the last key read if any. The last key values
+// are passed as text and cast to the key column types.
+func chunkQuery(c *chunk, afterLastKey bool, limit uint) string {
+	keyNames := make([]string, 0, len(c.keyColumns))
+	keyText := make([]string, 0, len(c.keyColumns))
+	keyArgs := make([]string, 0, len(c.keyColumns))
+	for i, col := range c.keyColumns {
+		name := pglib.QuoteIdentifier(col.name)
+		keyNames = append(keyNames, name)
+		keyText = append(keyText, fmt.Sprintf("%s::text", name))
+		keyArgs = append(keyArgs, fmt.Sprintf("$%d::text::%s", i+1, col.dataType))
+	}
+
+	where := ""
+	if afterLastKey {
+		where = fmt.Sprintf(" WHERE (%s) > (%s)", strings.Join(keyNames, ", "), strings.Join(keyArgs, ", "))
+	}
+
+	return fmt.Sprintf("SELECT *, %s FROM %s%s ORDER BY %s LIMIT %d",
+		strings.Join(keyText, ", "), c.qualifiedTable(), where, strings.Join(keyNames, ", "), limit)
+}
+
+// parseTable returns the schema and name of the table on input, assuming the
+// public schema if it's not qualified.
+func parseTable(qualifiedTable string) (string, string, error) {
+	qualifiedName, err := pglib.NewQualifiedName(qualifiedTable)
+	if err != nil {
+		return "", "", err
+	}
+	schema := qualifiedName.Schema()
+	if schema == "" {
+		schema = "public"
+	}
+	return schema, qualifiedName.Name(), nil
+}
+
+func isWatermark(d *wal.Data) bool {
+	return d.Action == wal.LogicalMessageAction && d.Prefix == watermarkPrefix
+}
+
+// toText returns the text representation of the value on input, which is how
+// the primary key values of the WAL events and the chunk rows are compared.
+func toText(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func rowKey(values []string) string {
+	return strings.Join(values, "\x00")
+}
+
+func (c *Config) chunkSize() uint {
+	if c.ChunkSize == 0 {
+		return defaultChunkSize
+	}
+	return c.ChunkSize
+}
@@ -0,0 +1,681 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package incremental
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/mocks"
+)
+
+type mockMapper func(oid uint32) (string, error)
+
+func (m mockMapper) TypeForOID(_ context.Context, oid uint32) (string, error) {
+	return m(oid)
+}
+
+var testMapper = mockMapper(func(oid uint32) (string, error) {
+	switch oid {
+	case pgtype.Int4OID:
+		return "integer", nil
+	case pgtype.TextOID:
+		return "text", nil
+	default:
+		return "", fmt.Errorf("unexpected oid %d", oid)
+	}
+})
+
+func newWatermarkEvent(t *testing.T, c *chunk, watermarkType, lsn string) *wal.Event {
+	content, err := json.Marshal(&watermark{SnapshotID: c.snapshotID, Table: c.qualifiedTable(), Chunk: c.number, Type: watermarkType})
+	require.NoError(t, err)
+	return &wal.Event{
+		CommitPosition: wal.CommitPosition(lsn),
+		Data: &wal.Data{
+			Action:  wal.LogicalMessageAction,
+			LSN:     lsn,
+			Prefix:  watermarkPrefix,
+			Content: string(content),
+		},
+	}
+}
+
+func newRowEvent(id int64, name string) *wal.Event {
+	return &wal.Event{
+		Data: &wal.Data{
+			Action: "I",
+			Schema: "public",
+			Table:  "users",
+			Columns: []wal.Column{
+				{Name: "id", Type: "integer", Value: id},
+				{Name: "name", Type: "text", Value: name},
+			},
+		},
+	}
+}
+
+func TestSnapshotter_ProcessWALEvent(t *testing.T) {
+	t.Parallel()
+
+	newChunk := func() *chunk {
+		return &chunk{
+			snapshotID: "1",
+			number:     1,
+			schema:     "public",
+			table:      "users",
+			keyColumns: []keyColumn{{name: "id", dataType: "integer"}},
+			modified:   map[string]struct{}{},
+			done:       make(chan struct{}),
+			rows: []*chunkRow{
+				{key: "1", event: newRowEvent(1, "alice")},
+				{key: "2", event: newRowEvent(2, "bob")},
+				{key: "3", event: newRowEvent(3, "charlie")},
+			},
+		}
+	}
+
+	updateEvent := &wal.Event{
+		CommitPosition: "0/2",
+		Data: &wal.Data{
+			Action:   "U",
+			Schema:   "public",
+			Table:    "users",
+			Columns:  []wal.Column{{Name: "id", Value: int64(1)}, {Name: "name", Value: "alice2"}},
+			Identity: []wal.Column{{Name: "id", Value: int64(1)}},
+		},
+	}
+	deleteEvent := &wal.Event{
+		CommitPosition: "0/3",
+		Data: &wal.Data{
+			Action:   "D",
+			Schema:   "public",
+			Table:    "users",
+			Identity: []wal.Column{{Name: "id", Value: int64(3)}},
+		},
+	}
+	otherTableEvent := &wal.Event{
+		CommitPosition: "0/4",
+		Data: &wal.Data{
+			Action:  "I",
+			Schema:  "public",
+			Table:   "orders",
+			Columns: []wal.Column{{Name: "id", Value: int64(2)}},
+		},
+	}
+
+	withPosition := func(e *wal.Event, lsn string) *wal.Event {
+		e.CommitPosition = wal.CommitPosition(lsn)
+		e.Data.LSN = lsn
+		return e
+	}
+
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name         string
+		events       func(c *chunk) []*wal.Event
+		processorErr error
+
+		wantEvents []*wal.Event
+		wantErr    error
+	}{
+		{
+			name: "ok - rows modified in the window are discarded",
+			events: func(c *chunk) []*wal.Event {
+				return []*wal.Event{
+					newWatermarkEvent(t, c, lowWatermark, "0/1"),
+					updateEvent,
+					deleteEvent,
+					otherTableEvent,
+					newWatermarkEvent(t, c, highWatermark, "0/5"),
+				}
+			},
+
+			wantEvents: []*wal.Event{
+				updateEvent,
+				deleteEvent,
+				otherTableEvent,
+				withPosition(newRowEvent(2, "bob"), "0/5"),
+			},
+		},
+		{
+			name: "ok - rows modified before the window are kept",
+			events: func(c *chunk) []*wal.Event {
+				return []*wal.Event{
+					updateEvent,
+					newWatermarkEvent(t, c, lowWatermark, "0/3"),
+					newWatermarkEvent(t, c, highWatermark, "0/5"),
+				}
+			},
+
+			wantEvents: []*wal.Event{
+				updateEvent,
+				withPosition(newRowEvent(1, "alice"), "0/5"),
+				withPosition(newRowEvent(2, "bob"), "0/5"),
+				withPosition(newRowEvent(3, "charlie"), "0/5"),
+			},
+		},
+		{
+			name: "ok - watermarks from other chunks are ignored",
+			events: func(c *chunk) []*wal.Event {
+				stale := &chunk{snapshotID: c.snapshotID, schema: c.schema, table: c.table, number: 2}
+				return []*wal.Event{
+					newWatermarkEvent(t, stale, lowWatermark, "0/1"),
+					updateEvent,
+					newWatermarkEvent(t, stale, highWatermark, "0/3"),
+				}
+			},
+
+			wantEvents: []*wal.Event{
+				updateEvent,
+			},
+		},
+		{
+			name: "error - processing snapshot row",
+			events: func(c *chunk) []*wal.Event {
+				return []*wal.Event{
+					newWatermarkEvent(t, c, lowWatermark, "0/1"),
+					newWatermarkEvent(t, c, highWatermark, "0/5"),
+				}
+			},
+			processorErr: errTest,
+
+			wantEvents: []*wal.Event{
+				withPosition(newRowEvent(1, "alice"), "0/5"),
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			events := []*wal.Event{}
+			c := newChunk()
+			s := &Snapshotter{
+				logger: loglib.NewNoopLogger(),
+				processor: &mocks.Processor{
+					ProcessWALEventFn: func(ctx context.Context, e *wal.Event) error {
+						events = append(events, e)
+						return tc.processorErr
+					},
+				},
+				current: c,
+			}
+
+			var err error
+			for _, event := range tc.events(c) {
+				if err = s.ProcessWALEvent(context.Background(), event); err != nil {
+					break
+				}
+			}
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantEvents, events)
+		})
+	}
+}
+
+func TestSnapshotter_ProcessWALEvent_signal(t *testing.T) {
+	t.Parallel()
+
+	signalEvent := func(action, signalType, data string) *wal.Event {
+		return &wal.Event{
+			Data: &wal.Data{
+				Action: action,
+				Schema: SignalsSchemaName,
+				Table:  SignalsTableName,
+				Columns: []wal.Column{
+					{Name: "id", Value: int64(7)},
+					{Name: "type", Value: signalType},
+					{Name: "data", Value: data},
+				},
+			},
+		}
+	}
+
+	testSlot := "pgstream_test_slot"
+	errTest := errors.New("oh noes")
+	acceptedTag := pglib.CommandTag{CommandTag: pgconn.NewCommandTag("UPDATE 1")}
+
+	tests := []struct {
+		name    string
+		event   *wal.Event
+		execTag pglib.CommandTag
+		execErr error
+
+		wantRequest *request
+		wantErr     error
+	}{
+		{
+			name:    "incremental snapshot signal",
+			event:   signalEvent("I", signalTypeIncrementalSnapshot, `{"tables": ["public.users", "orders"]}`),
+			execTag: acceptedTag,
+
+			wantRequest: &request{id: "7", tables: []string{"public.users", "orders"}, signal: true},
+		},
+		{
+			name:    "incremental snapshot signal already received",
+			event:   signalEvent("I", signalTypeIncrementalSnapshot, `{"tables": ["public.users"]}`),
+			execTag: pglib.CommandTag{CommandTag: pgconn.NewCommandTag("UPDATE 0")},
+		},
+		{
+			name:    "error - accepting incremental snapshot signal",
+			event:   signalEvent("I", signalTypeIncrementalSnapshot, `{"tables": ["public.users"]}`),
+			execErr: errTest,
+
+			wantErr: errTest,
+		},
+		{
+			name:  "unknown signal type",
+			event: signalEvent("I", "unknown", `{}`),
+		},
+		{
+			name:  "invalid signal data",
+			event: signalEvent("I", signalTypeIncrementalSnapshot, `{"tables": []}`),
+		},
+		{
+			name:  "signal update",
+			event: signalEvent("U", signalTypeIncrementalSnapshot, `{"tables": ["public.users"]}`),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Snapshotter{
+				logger: loglib.NewNoopLogger(),
+				processor: &mocks.Processor{
+					ProcessWALEventFn: func(ctx context.Context, e *wal.Event) error {
+						return errors.New("ProcessWALEventFn: should not be called")
+					},
+				},
+				conn: &pgmocks.Querier{
+					ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+						require.Equal(t, fmt.Sprintf(acceptSignalQuery, signalsTable()), query)
+						require.Equal(t, []any{"7", testSlot, `{"status":"requested"}`}, args)
+						return tc.execTag, tc.execErr
+					},
+				},
+				slotName: testSlot,
+				requests: newRequestQueue(),
+			}
+
+			err := s.ProcessWALEvent(context.Background(), tc.event)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantRequest, s.requests.pop())
+		})
+	}
+}
+
+func TestSnapshotter_snapshotTable(t *testing.T) {
+	t.Parallel()
+
+	wantPKQuery := primaryKeyColumnsQuery
+	c := &chunk{
+		schema:     "public",
+		table:      "users",
+		keyColumns: []keyColumn{{name: "id", dataType: "integer"}},
+	}
+
+	chunkRows := func(rows [][]any) *pgmocks.Rows {
+		i := 0
+		return &pgmocks.Rows{
+			CloseFn: func() {},
+			NextFn:  func(n uint) bool { return int(n) <= len(rows) },
+			FieldDescriptionsFn: func() []pgconn.FieldDescription {
+				return []pgconn.FieldDescription{
+					{Name: "id", DataTypeOID: pgtype.Int4OID},
+					{Name: "name", DataTypeOID: pgtype.TextOID},
+					{Name: "id", DataTypeOID: pgtype.TextOID},
+				}
+			},
+			ValuesFn: func() ([]any, error) {
+				i++
+				return rows[i-1], nil
+			},
+			ErrFn: func() error { return nil },
+		}
+	}
+
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name     string
+		querier  func(s *Snapshotter) *pgmocks.Querier
+		modified map[uint][]*wal.Event
+
+		wantRows   uint
+		wantEvents []*wal.Event
+		wantErr    error
+	}{
+		{
+			name: "ok",
+			querier: func(s *Snapshotter) *pgmocks.Querier {
+				return &pgmocks.Querier{
+					QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+						switch i {
+						case 1:
+							require.Equal(t, wantPKQuery, query)
+							require.Equal(t, []any{`"public"."users"`}, args)
+							return &pgmocks.Rows{
+								CloseFn: func() {},
+								NextFn:  func(n uint) bool { return n == 1 },
+								ScanFn: func(_ uint, dest ...any) error {
+									*(dest[0].(*string)) = "id"
+									*(dest[1].(*string)) = "integer"
+									return nil
+								},
+								ErrFn: func() error { return nil },
+							}, nil
+						case 2:
+							require.Equal(t, chunkQuery(c, false, 2), query)
+							require.Empty(t, args)
+							return chunkRows([][]any{{int32(1), "alice", "1"}, {int32(2), "bob", "2"}}), nil
+						case 3:
+							require.Equal(t, chunkQuery(c, true, 2), query)
+							require.Equal(t, []any{"2"}, args)
+							return chunkRows([][]any{{int32(3), "charlie", "3"}}), nil
+						default:
+							return nil, fmt.Errorf("unexpected call to QueryFn: %d", i)
+						}
+					},
+					ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+						require.Equal(t, emitWatermarkQuery, query)
+						require.Len(t, args, 2)
+						require.Equal(t, watermarkPrefix, args[0])
+						// simulate the watermark being received through the
+						// replication slot
+						content, ok := args[1].(string)
+						require.True(t, ok)
+						lsn := fmt.Sprintf("0/%d", i)
+						err := s.ProcessWALEvent(ctx, &wal.Event{
+							CommitPosition: wal.CommitPosition(lsn),
+							Data:           &wal.Data{Action: wal.LogicalMessageAction, LSN: lsn, Prefix: watermarkPrefix, Content: content},
+						})
+						return pglib.CommandTag{}, err
+					},
+				}
+			},
+
+			wantRows: 3,
+			wantEvents: []*wal.Event{
+				{CommitPosition: "0/2", Data: &wal.Data{Action: "I", LSN: "0/2", Schema: "public", Table: "users", Columns: []wal.Column{{Name: "id", Type: "integer", Value: int32(1)}, {Name: "name", Type: "text", Value: "alice"}}}},
+				{CommitPosition: "0/2", Data: &wal.Data{Action: "I", LSN: "0/2", Schema: "public", Table: "users", Columns: []wal.Column{{Name: "id", Type: "integer", Value: int32(2)}, {Name: "name", Type: "text", Value: "bob"}}}},
+				{CommitPosition: "0/4", Data: &wal.Data{Action: "I", LSN: "0/4", Schema: "public", Table: "users", Columns: []wal.Column{{Name: "id", Type: "integer", Value: int32(3)}, {Name: "name", Type: "text", Value: "charlie"}}}},
+			},
+		},
+		{
+			name: "error - no primary key",
+			querier: func(s *Snapshotter) *pgmocks.Querier {
+				return &pgmocks.Querier{
+					QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+						return &pgmocks.Rows{
+							CloseFn: func() {},
+							NextFn:  func(n uint) bool { return false },
+							ErrFn:   func() error { return nil },
+						}, nil
+					},
+				}
+			},
+
+			wantEvents: []*wal.Event{},
+			wantErr:    errNoPrimaryKey,
+		},
+		{
+			name: "error - emitting watermark",
+			querier: func(s *Snapshotter) *pgmocks.Querier {
+				return &pgmocks.Querier{
+					QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+						return &pgmocks.Rows{
+							CloseFn: func() {},
+							NextFn:  func(n uint) bool { return n == 1 },
+							ScanFn: func(_ uint, dest ...any) error {
+								*(dest[0].(*string)) = "id"
+								*(dest[1].(*string)) = "integer"
+								return nil
+							},
+							ErrFn: func() error { return nil },
+						}, nil
+					},
+					ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+						return pglib.CommandTag{}, errTest
+					},
+				}
+			},
+
+			wantEvents: []*wal.Event{},
+			wantErr:    errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			events := []*wal.Event{}
+			s := &Snapshotter{
+				logger: loglib.NewNoopLogger(),
+				processor: &mocks.Processor{
+					ProcessWALEventFn: func(ctx context.Context, e *wal.Event) error {
+						e.Data.Timestamp = ""
+						events = append(events, e)
+						return nil
+					},
+				},
+				mapper:    testMapper,
+				chunkSize: 2,
+			}
+			s.conn = tc.querier(s)
+
+			rows, err := s.snapshotTable(context.Background(), &request{id: "1"}, "public.users", nil)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantRows, rows)
+			require.Equal(t, tc.wantEvents, events)
+			require.Nil(t, s.current)
+		})
+	}
+}
+
+func TestChunkQuery(t *testing.T) {
+	t.Parallel()
+
+	c := &chunk{
+		schema: "public",
+		table:  "order_items",
+		keyColumns: []keyColumn{
+			{name: "order_id", dataType: "bigint"},
+			{name: "item_id", dataType: "uuid"},
+		},
+	}
+
+	require.Equal(t,
+		`SELECT *, "order_id"::text, "item_id"::text FROM "public"."order_items" ORDER BY "order_id", "item_id" LIMIT 100`,
+		chunkQuery(c, false, 100))
+	require.Equal(t,
+		`SELECT *, "order_id"::text, "item_id"::text FROM "public"."order_items" WHERE ("order_id", "item_id") > ($1::text::bigint, $2::text::uuid) ORDER BY "order_id", "item_id" LIMIT 100`,
+		chunkQuery(c, true, 100))
+}
+
+func TestSnapshotter_processRequest_resumed(t *testing.T) {
+	t.Parallel()
+
+	testSlot := "pgstream_test_slot"
+	c := &chunk{
+		schema:     "public",
+		table:      "users",
+		keyColumns: []keyColumn{{name: "id", dataType: "integer"}},
+	}
+
+	events := []*wal.Event{}
+	progress := []string{}
+	s := &Snapshotter{
+		logger: loglib.NewNoopLogger(),
+		processor: &mocks.Processor{
+			ProcessWALEventFn: func(ctx context.Context, e *wal.Event) error {
+				e.Data.Timestamp = ""
+				events = append(events, e)
+				return nil
+			},
+		},
+		mapper:    testMapper,
+		chunkSize: 2,
+		slotName:  testSlot,
+	}
+	s.conn = &pgmocks.Querier{
+		QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+			switch i {
+			case 1:
+				require.Equal(t, primaryKeyColumnsQuery, query)
+				require.Equal(t, []any{`"public"."users"`}, args)
+				return &pgmocks.Rows{
+					CloseFn: func() {},
+					NextFn:  func(n uint) bool { return n == 1 },
+					ScanFn: func(_ uint, dest ...any) error {
+						*(dest[0].(*string)) = "id"
+						*(dest[1].(*string)) = "integer"
+						return nil
+					},
+					ErrFn: func() error { return nil },
+				}, nil
+			case 2:
+				// the snapshot is resumed after the last key
+				require.Equal(t, chunkQuery(c, true, 2), query)
+				require.Equal(t, []any{"2"}, args)
+				return &pgmocks.Rows{
+					CloseFn: func() {},
+					NextFn:  func(n uint) bool { return n == 1 },
+					FieldDescriptionsFn: func() []pgconn.FieldDescription {
+						return []pgconn.FieldDescription{
+							{Name: "id", DataTypeOID: pgtype.Int4OID},
+							{Name: "name", DataTypeOID: pgtype.TextOID},
+							{Name: "id", DataTypeOID: pgtype.TextOID},
+						}
+					},
+					ValuesFn: func() ([]any, error) { return []any{int32(3), "charlie", "3"}, nil },
+					ErrFn:    func() error { return nil },
+				}, nil
+			default:
+				return nil, fmt.Errorf("unexpected call to QueryFn: %d", i)
+			}
+		},
+		ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+			if query == fmt.Sprintf(updateSignalProgressQuery, signalsTable()) {
+				require.Equal(t, "7", args[0])
+				require.Equal(t, testSlot, args[1])
+				progress = append(progress, args[2].(string))
+				return pglib.CommandTag{}, nil
+			}
+			require.Equal(t, emitWatermarkQuery, query)
+			lsn := fmt.Sprintf("0/%d", i)
+			err := s.ProcessWALEvent(ctx, &wal.Event{
+				CommitPosition: wal.CommitPosition(lsn),
+				Data:           &wal.Data{Action: wal.LogicalMessageAction, LSN: lsn, Prefix: watermarkPrefix, Content: args[1].(string)},
+			})
+			return pglib.CommandTag{}, err
+		},
+	}
+
+	s.processRequest(context.Background(), &request{
+		id:      "7",
+		tables:  []string{"public.orders", "public.users"},
+		signal:  true,
+		table:   "public.users",
+		lastKey: []string{"2"},
+	})
+
+	require.Equal(t, []string{
+		`{"status":"in progress","table":"public.users","last_key":["2"]}`,
+		`{"status":"completed"}`,
+	}, progress)
+	require.Equal(t, []*wal.Event{
+		{CommitPosition: "0/3", Data: &wal.Data{Action: "I", LSN: "0/3", Schema: "public", Table: "users", Columns: []wal.Column{{Name: "id", Type: "integer", Value: int32(3)}, {Name: "name", Type: "text", Value: "charlie"}}}},
+	}, events)
+}
+
+func TestSnapshotter_init(t *testing.T) {
+	t.Parallel()
+
+	testSlot := "pgstream_test_slot"
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name     string
+		queryErr error
+
+		wantRequests []*request
+		wantErr      error
+	}{
+		{
+			name: "ok - pending signals resumed",
+			wantRequests: []*request{
+				{id: "7", tables: []string{"public.users"}, signal: true},
+				{id: "8", tables: []string{"public.orders", "public.users"}, signal: true, table: "public.users", lastKey: []string{"2"}},
+			},
+		},
+		{
+			name:     "error - getting pending signals",
+			queryErr: errTest,
+			wantErr:  errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			signals := [][]string{
+				{"7", `{"tables": ["public.users"]}`, `{"status": "requested"}`},
+				{"8", `{"tables": ["public.orders", "public.users"]}`, `{"status": "in progress", "table": "public.users", "last_key": ["2"]}`},
+			}
+			s := &Snapshotter{
+				logger:   loglib.NewNoopLogger(),
+				slotName: testSlot,
+				requests: newRequestQueue(),
+				conn: &pgmocks.Querier{
+					QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+						require.Equal(t, fmt.Sprintf(pendingSignalsQuery, signalsTable()), query)
+						require.Equal(t, []any{testSlot, signalTypeIncrementalSnapshot}, args)
+						if tc.queryErr != nil {
+							return nil, tc.queryErr
+						}
+						return &pgmocks.Rows{
+							CloseFn: func() {},
+							NextFn:  func(n uint) bool { return int(n) <= len(signals) },
+							ScanFn: func(n uint, dest ...any) error {
+								for i, v := range signals[n-1] {
+									*(dest[i].(*string)) = v
+								}
+								return nil
+							},
+							ErrFn: func() error { return nil },
+						}, nil
+					},
+				},
+			}
+
+			err := s.init(context.Background(), &Config{})
+			require.ErrorIs(t, err, tc.wantErr)
+
+			requests := []*request{}
+			for req := s.requests.pop(); req != nil; req = s.requests.pop() {
+				requests = append(requests, req)
+			}
+			if tc.wantRequests == nil {
+				tc.wantRequests = []*request{}
+			}
+			require.Equal(t, tc.wantRequests, requests)
+		})
+	}
+}
@@ -0,0 +1,250 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package merger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xataio/pgstream/internal/json"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+)
+
+// Merger is a processor wrapper that merges sharded source tables (for
+// example one schema per tenant) into a single target table, adding a
+// discriminator column with the source schema name to every row.
+type Merger struct {
+	processor          processor.Processor
+	rules              []*mergeRule
+	logger             loglib.Logger
+	walEventToDDLEvent func(*wal.Data) (*wal.DDLEvent, error)
+	marshaler          func(any) ([]byte, error)
+}
+
+type Config struct {
+	// List of merge rules. When a table matches multiple rules, the first one
+	// applies.
+	Tables []TableConfig
+}
+
+type TableConfig struct {
+	// Source table pattern. Should be schema qualified, if no schema is
+	// provided, the public schema will be assumed. Wildcards "*" are supported
+	// (for example "tenant_*.orders").
+	Source string
+	// Target table the matching source tables are merged into. Should be
+	// schema qualified, if no schema is provided, the public schema will be
+	// assumed.
+	Target string
+	// Name of the column added to the target table to identify the source
+	// schema of each row. Defaults to "tenant".
+	DiscriminatorColumn string
+}
+
+type Option func(*Merger)
+
+type mergeRule struct {
+	router              *pglib.TableRouter
+	discriminatorColumn string
+}
+
+const (
+	defaultDiscriminatorColumn = "tenant"
+	discriminatorColumnType    = "text"
+	discriminatorColumnIDTag   = "discriminator"
+
+	tableObjectType       = "table"
+	tableColumnObjectType = "table column"
+)
+
+var (
+	errMissingMergeConfig = errors.New("missing table merge configuration")
+	errInvalidMergeTarget = errors.New("invalid merge target table, wildcards and placeholders are not supported")
+)
+
+// New will return a merger processor wrapper that will merge the WAL events of
+// the source tables into their configured target table.
+func New(processor processor.Processor, cfg *Config, opts ...Option) (*Merger, error) {
+	if len(cfg.Tables) == 0 {
+		return nil, errMissingMergeConfig
+	}
+
+	m := &Merger{
+		processor:          processor,
+		rules:              make([]*mergeRule, 0, len(cfg.Tables)),
+		logger:             loglib.NewNoopLogger(),
+		walEventToDDLEvent: wal.WalDataToDDLEvent,
+		marshaler:          json.Marshal,
+	}
+
+	for _, tableCfg := range cfg.Tables {
+		if strings.ContainsAny(tableCfg.Target, "*{}") {
+			return nil, fmt.Errorf("%w: %q", errInvalidMergeTarget, tableCfg.Target)
+		}
+		router, err := pglib.NewTableRouter([]pglib.TableRoute{
+			{Source: tableCfg.Source, Target: tableCfg.Target},
+		})
+		if err != nil {
+			return nil, err
+		}
+		discriminatorColumn := tableCfg.DiscriminatorColumn
+		if discriminatorColumn == "" {
+			discriminatorColumn = defaultDiscriminatorColumn
+		}
+		m.rules = append(m.rules, &mergeRule{
+			router:              router,
+			discriminatorColumn: discriminatorColumn,
+		})
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m, nil
+}
+
+func WithLogger(logger loglib.Logger) Option {
+	return func(m *Merger) {
+		m.logger = loglib.NewLogger(logger).WithFields(loglib.Fields{
+			loglib.ModuleField: "wal_merger",
+		})
+	}
+}
+
+func (m *Merger) ProcessWALEvent(ctx context.Context, event *wal.Event) error {
+	switch {
+	case event == nil || event.Data == nil:
+		// nothing to do, pass it along to the internal processor
+	case event.Data.IsDDLEvent():
+		if err := m.mergeDDLEvent(event.Data); err != nil {
+			return err
+		}
+	case event.Data.Action == wal.LogicalMessageAction:
+		// non DDL logical messages are not table specific
+	default:
+		m.mergeDataEvent(event.Data)
+	}
+
+	return m.processor.ProcessWALEvent(ctx, event)
+}
+
+func (m *Merger) Name() string {
+	return m.processor.Name()
+}
+
+func (m *Merger) Close() error {
+	return m.processor.Close()
+}
+
+func (m *Merger) mergeDataEvent(d *wal.Data) {
+	rule, targetSchema, targetTable, found := m.lookup(d.Schema, d.Table)
+	if !found {
+		return
+	}
+
+	discriminator := wal.Column{
+		ID:    discriminatorColumnID(rule.discriminatorColumn),
+		Name:  rule.discriminatorColumn,
+		Type:  discriminatorColumnType,
+		Value: d.Schema,
+	}
+	d.Schema, d.Table = targetSchema, targetTable
+
+	if d.Action == "T" {
+		// truncating a source table must only remove its own rows from the
+		// merged table
+		d.Action = "D"
+		d.Columns = nil
+		d.Identity = []wal.Column{discriminator}
+		d.Metadata.InternalColIDs = nil
+		return
+	}
+
+	// the discriminator becomes part of the row identity, so that rows from
+	// different sources with the same primary key don't conflict
+	if len(d.Columns) > 0 {
+		d.Columns = append([]wal.Column{discriminator}, d.Columns...)
+	}
+	if len(d.Identity) > 0 {
+		d.Identity = append([]wal.Column{discriminator}, d.Identity...)
+	}
+	if len(d.Metadata.InternalColIDs) > 0 {
+		d.Metadata.InternalColIDs = append([]string{discriminator.ID}, d.Metadata.InternalColIDs...)
+	}
+}
+
+func (m *Merger) mergeDDLEvent(d *wal.Data) error {
+	ddlEvent, err := m.walEventToDDLEvent(d)
+	if err != nil {
+		// if we can't determine the DDL event, pass it along unchanged
+		m.logger.Error(err, "failed to convert WAL data to DDL event", loglib.Fields{"data": d})
+		return nil
+	}
+
+	merged := false
+	for i, obj := range ddlEvent.Objects {
+		if obj.Type != tableObjectType && obj.Type != tableColumnObjectType {
+			continue
+		}
+		rule, targetSchema, targetTable, found := m.lookup(obj.Schema, pglib.UnquoteIdentifier(obj.GetTable()))
+		if !found {
+			continue
+		}
+		merged = true
+		ddlEvent.Objects[i] = mergeDDLObject(obj, rule, targetSchema, targetTable)
+		ddlEvent.SchemaName = targetSchema
+	}
+
+	if !merged {
+		return nil
+	}
+
+	ddlEvent.Merged = true
+	content, err := m.marshaler(ddlEvent)
+	if err != nil {
+		return fmt.Errorf("marshaling merged DDL event: %w", err)
+	}
+	d.Content = string(content)
+	return nil
+}
+
+func mergeDDLObject(obj wal.DDLObject, rule *mergeRule, targetSchema, targetTable string) wal.DDLObject {
+	identity := pglib.QuoteQualifiedIdentifier(targetSchema, targetTable)
+	if obj.Type == tableColumnObjectType {
+		identity = fmt.Sprintf("%s.%s", identity, obj.GetName())
+	}
+	obj.Identity = identity
+	obj.Schema = targetSchema
+
+	if len(obj.Columns) > 0 {
+		obj.Columns = append([]wal.DDLColumn{
+			{
+				Name: rule.discriminatorColumn,
+				Type: discriminatorColumnType,
+			},
+		}, obj.Columns...)
+	}
+	if len(obj.PrimaryKeyColumns) > 0 {
+		obj.PrimaryKeyColumns = append([]string{rule.discriminatorColumn}, obj.PrimaryKeyColumns...)
+	}
+	return obj
+}
+
+func (m *Merger) lookup(schema, table string) (*mergeRule, string, string, bool) {
+	for _, rule := range m.rules {
+		if targetSchema, targetTable, found := rule.router.Lookup(schema, table); found {
+			return rule, targetSchema, targetTable, true
+		}
+	}
+	return nil, "", "", false
+}
+
+func discriminatorColumnID(name string) string {
+	return fmt.Sprintf("%s-%s", discriminatorColumnIDTag, name)
+}
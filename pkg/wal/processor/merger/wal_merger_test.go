@@ -0,0 +1,260 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package merger
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/internal/json"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/mocks"
+)
+
+func Test_New(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config *Config
+
+		wantErr bool
+	}{
+		{
+			name: "ok",
+			config: &Config{
+				Tables: []TableConfig{
+					{Source: "tenant_*.orders", Target: "public.orders"},
+				},
+			},
+		},
+		{
+			name:    "error - missing config",
+			config:  &Config{},
+			wantErr: true,
+		},
+		{
+			name: "error - wildcard target",
+			config: &Config{
+				Tables: []TableConfig{
+					{Source: "tenant_*.orders", Target: "merged.*"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "error - invalid source",
+			config: &Config{
+				Tables: []TableConfig{
+					{Source: "a.b.c", Target: "public.orders"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := New(&mocks.Processor{}, tc.config)
+			require.Equal(t, tc.wantErr, err != nil, err)
+			if !tc.wantErr {
+				require.Len(t, m.rules, len(tc.config.Tables))
+			}
+		})
+	}
+}
+
+func TestMerger_ProcessWALEvent(t *testing.T) {
+	t.Parallel()
+
+	testConfig := &Config{
+		Tables: []TableConfig{
+			{Source: "tenant_*.orders", Target: "public.orders"},
+			{Source: "tenant_*.items", Target: "public.items", DiscriminatorColumn: "shard"},
+		},
+	}
+
+	discriminator := func(name, value string) wal.Column {
+		return wal.Column{ID: "discriminator-" + name, Name: name, Type: "text", Value: value}
+	}
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name  string
+		event *wal.Event
+		err   error
+
+		wantEvent *wal.Event
+		wantErr   error
+	}{
+		{
+			name:      "nil data",
+			event:     &wal.Event{},
+			wantEvent: &wal.Event{},
+		},
+		{
+			name: "insert on unmerged table",
+			event: &wal.Event{
+				Data: &wal.Data{
+					Action: "I", Schema: "public", Table: "users",
+					Columns: []wal.Column{{ID: "1", Name: "id", Value: 1}},
+				},
+			},
+			wantEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "I", Schema: "public", Table: "users",
+					Columns: []wal.Column{{ID: "1", Name: "id", Value: 1}},
+				},
+			},
+		},
+		{
+			name: "insert on merged table",
+			event: &wal.Event{
+				Data: &wal.Data{
+					Action: "I", Schema: "tenant_a", Table: "orders",
+					Columns:  []wal.Column{{ID: "1", Name: "id", Value: 1}},
+					Metadata: wal.Metadata{InternalColIDs: []string{"1"}},
+				},
+			},
+			wantEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "I", Schema: "public", Table: "orders",
+					Columns: []wal.Column{
+						discriminator("tenant", "tenant_a"),
+						{ID: "1", Name: "id", Value: 1},
+					},
+					Metadata: wal.Metadata{InternalColIDs: []string{"discriminator-tenant", "1"}},
+				},
+			},
+		},
+		{
+			name: "delete on merged table with custom discriminator",
+			event: &wal.Event{
+				Data: &wal.Data{
+					Action: "D", Schema: "tenant_b", Table: "items",
+					Identity: []wal.Column{{ID: "1", Name: "id", Value: 1}},
+				},
+			},
+			wantEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "D", Schema: "public", Table: "items",
+					Identity: []wal.Column{
+						discriminator("shard", "tenant_b"),
+						{ID: "1", Name: "id", Value: 1},
+					},
+				},
+			},
+		},
+		{
+			name: "truncate on merged table",
+			event: &wal.Event{
+				Data: &wal.Data{
+					Action: "T", Schema: "tenant_a", Table: "orders",
+				},
+			},
+			wantEvent: &wal.Event{
+				Data: &wal.Data{
+					Action: "D", Schema: "public", Table: "orders",
+					Identity: []wal.Column{discriminator("tenant", "tenant_a")},
+				},
+			},
+		},
+		{
+			name: "error - processing event",
+			event: &wal.Event{
+				Data: &wal.Data{Action: "I", Schema: "public", Table: "users"},
+			},
+			err: errTest,
+
+			wantEvent: &wal.Event{
+				Data: &wal.Data{Action: "I", Schema: "public", Table: "users"},
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := New(&mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error {
+					require.Equal(t, tc.wantEvent, walEvent)
+					return tc.err
+				},
+			}, testConfig)
+			require.NoError(t, err)
+
+			err = m.ProcessWALEvent(context.Background(), tc.event)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestMerger_ProcessWALEvent_ddl(t *testing.T) {
+	t.Parallel()
+
+	m, err := New(&mocks.Processor{
+		ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error {
+			ddlEvent, err := wal.WalDataToDDLEvent(walEvent.Data)
+			require.NoError(t, err)
+			require.Equal(t, &wal.DDLEvent{
+				DDL:        "ALTER TABLE tenant_a.orders ADD COLUMN total numeric",
+				SchemaName: "public",
+				CommandTag: "ALTER TABLE",
+				Merged:     true,
+				Objects: []wal.DDLObject{
+					{
+						Type:     "table",
+						Identity: `"public"."orders"`,
+						Schema:   "public",
+						Columns: []wal.DDLColumn{
+							{Name: "tenant", Type: "text"},
+							{Attnum: 1, Name: "id", Type: "integer"},
+							{Attnum: 2, Name: "total", Type: "numeric", Nullable: true},
+						},
+						PrimaryKeyColumns: []string{"tenant", "id"},
+					},
+				},
+			}, ddlEvent)
+			return nil
+		},
+	}, &Config{
+		Tables: []TableConfig{
+			{Source: "tenant_*.orders", Target: "public.orders"},
+		},
+	})
+	require.NoError(t, err)
+
+	content, err := json.Marshal(&wal.DDLEvent{
+		DDL:        "ALTER TABLE tenant_a.orders ADD COLUMN total numeric",
+		SchemaName: "tenant_a",
+		CommandTag: "ALTER TABLE",
+		Objects: []wal.DDLObject{
+			{
+				Type:     "table",
+				Identity: "tenant_a.orders",
+				Schema:   "tenant_a",
+				Columns: []wal.DDLColumn{
+					{Attnum: 1, Name: "id", Type: "integer"},
+					{Attnum: 2, Name: "total", Type: "numeric", Nullable: true},
+				},
+				PrimaryKeyColumns: []string{"id"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	err = m.ProcessWALEvent(context.Background(), &wal.Event{
+		Data: &wal.Data{
+			Action:  wal.LogicalMessageAction,
+			Prefix:  wal.DDLPrefix,
+			Content: string(content),
+		},
+	})
+	require.NoError(t, err)
+}
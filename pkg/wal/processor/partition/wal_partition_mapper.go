@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package partition
+
+import (
+	"context"
+	"errors"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	synclib "github.com/xataio/pgstream/internal/sync"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+)
+
+// Mapper is a processor wrapper that maps the data events of the leaf
+// partitions of a partitioned table to its root table, so that the rest of the
+// pipeline sees a single logical table instead of one per partition. Tables
+// are resolved on first use, so partitions created after the start of the
+// replication are picked up automatically.
+type Mapper struct {
+	logger    loglib.Logger
+	processor processor.Processor
+	conn      pglib.Querier
+	// roots is a map of quoted schema.table to the root table of the
+	// partition, or nil if the table is not a partition
+	roots              *synclib.Map[string, *pglib.QualifiedName]
+	partitionRootFn    partitionRootFn
+	walEventToDDLEvent func(*wal.Data) (*wal.DDLEvent, error)
+}
+
+type Config struct {
+	// URL of the source Postgres database, used to resolve the root table of
+	// the partitions.
+	URL string
+}
+
+type Option func(*Mapper)
+
+type partitionRootFn func(ctx context.Context, conn pglib.Querier, schema, table string) (*pglib.QualifiedName, error)
+
+var errMissingURL = errors.New("source postgres URL required to map partitions to their root table")
+
+// New will return a processor wrapper that maps the events of partitions to
+// their root table before passing them on to the wrapped processor.
+func New(ctx context.Context, processor processor.Processor, cfg *Config, opts ...Option) (*Mapper, error) {
+	if cfg.URL == "" {
+		return nil, errMissingURL
+	}
+
+	conn, err := pglib.NewConnPool(ctx, cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mapper{
+		logger:             loglib.NewNoopLogger(),
+		processor:          processor,
+		conn:               conn,
+		roots:              synclib.NewMap[string, *pglib.QualifiedName](),
+		partitionRootFn:    pglib.GetPartitionRoot,
+		walEventToDDLEvent: wal.WalDataToDDLEvent,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m, nil
+}
+
+func WithLogger(logger loglib.Logger) Option {
+	return func(m *Mapper) {
+		m.logger = loglib.NewLogger(logger).WithFields(loglib.Fields{
+			loglib.ModuleField: "wal_partition_mapper",
+		})
+	}
+}
+
+func (m *Mapper) ProcessWALEvent(ctx context.Context, event *wal.Event) error {
+	if event.Data != nil {
+		switch {
+		case event.Data.IsDDLEvent():
+			m.invalidateTables(event.Data)
+		case m.isMappable(event.Data):
+			root, err := m.getPartitionRoot(ctx, event.Data.Schema, event.Data.Table)
+			if err != nil {
+				return err
+			}
+			if root != nil {
+				event.Data.Schema = root.Schema()
+				event.Data.Table = root.Name()
+			}
+		}
+	}
+
+	return m.processor.ProcessWALEvent(ctx, event)
+}
+
+func (m *Mapper) Name() string {
+	return m.processor.Name()
+}
+
+func (m *Mapper) Close() error {
+	if err := m.conn.Close(context.Background()); err != nil {
+		m.logger.Error(err, "closing partition mapper connection")
+	}
+	return m.processor.Close()
+}
+
+// isMappable returns true for the row change events. Truncates are not mapped,
+// since truncating the root table would remove the rows of all its
+// partitions.
+func (m *Mapper) isMappable(d *wal.Data) bool {
+	switch d.Action {
+	case "I", "U", "D":
+		return d.Schema != "" && d.Table != ""
+	default:
+		return false
+	}
+}
+
+// getPartitionRoot returns the root table of the partition on input, or nil
+// if the table is not a partition. If the value is not in the internal cache,
+// it will query postgres.
+func (m *Mapper) getPartitionRoot(ctx context.Context, schema, table string) (*pglib.QualifiedName, error) {
+	key := pglib.QuoteQualifiedIdentifier(schema, table)
+	if root, found := m.roots.Get(key); found {
+		return root, nil
+	}
+
+	root, err := m.partitionRootFn(ctx, m.conn, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	m.roots.Set(key, root)
+	return root, nil
+}
+
+// invalidateTables removes the tables affected by the DDL event from the
+// cache, so that partitions attached or detached are resolved again.
+func (m *Mapper) invalidateTables(d *wal.Data) {
+	ddlEvent, err := m.walEventToDDLEvent(d)
+	if err != nil {
+		m.logger.Error(err, "failed to convert WAL data to DDL event")
+		return
+	}
+	tables := append(ddlEvent.GetTableObjects(), ddlEvent.GetTableColumnObjects()...)
+	if len(tables) == 0 {
+		return
+	}
+	// attaching or detaching a partition only reports the parent table, so
+	// all the cached partitions need to be resolved again
+	for key := range m.roots.GetMap() {
+		m.roots.Delete(key)
+	}
+}
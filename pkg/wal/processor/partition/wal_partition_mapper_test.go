@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package partition
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	synclib "github.com/xataio/pgstream/internal/sync"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/mocks"
+)
+
+func TestMapper_ProcessWALEvent(t *testing.T) {
+	t.Parallel()
+
+	root, err := pglib.NewQualifiedName("public.orders")
+	require.NoError(t, err)
+
+	testPartitionRootFn := func(ctx context.Context, conn pglib.Querier, schema, table string) (*pglib.QualifiedName, error) {
+		if schema == "public" && table == "orders_2024" {
+			return root, nil
+		}
+		return nil, nil
+	}
+
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name            string
+		event           *wal.Event
+		partitionRootFn partitionRootFn
+
+		wantEvent *wal.Event
+		wantErr   error
+	}{
+		{
+			name:            "ok - partition insert mapped to root table",
+			event:           &wal.Event{Data: &wal.Data{Action: "I", Schema: "public", Table: "orders_2024"}},
+			partitionRootFn: testPartitionRootFn,
+
+			wantEvent: &wal.Event{Data: &wal.Data{Action: "I", Schema: "public", Table: "orders"}},
+		},
+		{
+			name:            "ok - partition delete mapped to root table",
+			event:           &wal.Event{Data: &wal.Data{Action: "D", Schema: "public", Table: "orders_2024"}},
+			partitionRootFn: testPartitionRootFn,
+
+			wantEvent: &wal.Event{Data: &wal.Data{Action: "D", Schema: "public", Table: "orders"}},
+		},
+		{
+			name:            "ok - not a partition",
+			event:           &wal.Event{Data: &wal.Data{Action: "U", Schema: "public", Table: "users"}},
+			partitionRootFn: testPartitionRootFn,
+
+			wantEvent: &wal.Event{Data: &wal.Data{Action: "U", Schema: "public", Table: "users"}},
+		},
+		{
+			name:  "ok - partition truncate not mapped",
+			event: &wal.Event{Data: &wal.Data{Action: "T", Schema: "public", Table: "orders_2024"}},
+			partitionRootFn: func(ctx context.Context, conn pglib.Querier, schema, table string) (*pglib.QualifiedName, error) {
+				return nil, errors.New("partitionRootFn: should not be called")
+			},
+
+			wantEvent: &wal.Event{Data: &wal.Data{Action: "T", Schema: "public", Table: "orders_2024"}},
+		},
+		{
+			name:  "ok - keep alive",
+			event: &wal.Event{CommitPosition: wal.CommitPosition("0/1")},
+			partitionRootFn: func(ctx context.Context, conn pglib.Querier, schema, table string) (*pglib.QualifiedName, error) {
+				return nil, errors.New("partitionRootFn: should not be called")
+			},
+
+			wantEvent: &wal.Event{CommitPosition: wal.CommitPosition("0/1")},
+		},
+		{
+			name:  "error - getting partition root",
+			event: &wal.Event{Data: &wal.Data{Action: "I", Schema: "public", Table: "orders_2024"}},
+			partitionRootFn: func(ctx context.Context, conn pglib.Querier, schema, table string) (*pglib.QualifiedName, error) {
+				return nil, errTest
+			},
+
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockProcessor := &mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error {
+					require.Equal(t, tc.wantEvent, walEvent)
+					return nil
+				},
+			}
+
+			m := &Mapper{
+				logger:             loglib.NewNoopLogger(),
+				processor:          mockProcessor,
+				conn:               &pgmocks.Querier{},
+				roots:              synclib.NewMap[string, *pglib.QualifiedName](),
+				partitionRootFn:    tc.partitionRootFn,
+				walEventToDDLEvent: wal.WalDataToDDLEvent,
+			}
+
+			err := m.ProcessWALEvent(context.Background(), tc.event)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr == nil {
+				require.Equal(t, uint(1), mockProcessor.GetProcessCalls())
+			}
+		})
+	}
+}
+
+func TestMapper_ProcessWALEvent_newPartitions(t *testing.T) {
+	t.Parallel()
+
+	root, err := pglib.NewQualifiedName("public.orders")
+	require.NoError(t, err)
+
+	// the partition is attached after the first event, and reported by the
+	// following DDL event
+	attached := false
+	rootCalls := 0
+	m := &Mapper{
+		logger: loglib.NewNoopLogger(),
+		processor: &mocks.Processor{
+			ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error { return nil },
+		},
+		conn:  &pgmocks.Querier{},
+		roots: synclib.NewMap[string, *pglib.QualifiedName](),
+		partitionRootFn: func(ctx context.Context, conn pglib.Querier, schema, table string) (*pglib.QualifiedName, error) {
+			rootCalls++
+			if attached {
+				return root, nil
+			}
+			return nil, nil
+		},
+		walEventToDDLEvent: func(d *wal.Data) (*wal.DDLEvent, error) {
+			return &wal.DDLEvent{
+				CommandTag: "ALTER TABLE",
+				Objects:    []wal.DDLObject{{Type: "table", Identity: "public.orders", Schema: "public"}},
+			}, nil
+		},
+	}
+
+	newEvent := func() *wal.Event {
+		return &wal.Event{Data: &wal.Data{Action: "I", Schema: "public", Table: "orders_2025"}}
+	}
+
+	event := newEvent()
+	require.NoError(t, m.ProcessWALEvent(context.Background(), event))
+	require.Equal(t, "orders_2025", event.Data.Table)
+
+	// cached lookup
+	event = newEvent()
+	require.NoError(t, m.ProcessWALEvent(context.Background(), event))
+	require.Equal(t, "orders_2025", event.Data.Table)
+	require.Equal(t, 1, rootCalls)
+
+	attached = true
+	require.NoError(t, m.ProcessWALEvent(context.Background(), &wal.Event{
+		Data: &wal.Data{Action: wal.LogicalMessageAction, Prefix: wal.DDLPrefix},
+	}))
+
+	event = newEvent()
+	require.NoError(t, m.ProcessWALEvent(context.Background(), event))
+	require.Equal(t, "orders", event.Data.Table)
+	require.Equal(t, 2, rootCalls)
+}
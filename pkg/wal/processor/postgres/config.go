@@ -3,6 +3,8 @@
 package postgres
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/xataio/pgstream/pkg/backoff"
@@ -15,10 +17,149 @@ type Config struct {
 	DisableTriggers   bool
 	OnConflictAction  string
 	BulkIngestEnabled bool
-	RetryPolicy       backoff.Config
-	IgnoreDDL         bool
+	// BulkIngestMode defines which events are bulk written when bulk ingest
+	// is enabled. One of "copy" (default), which only writes insert events
+	// using COPY and is used for snapshots, or "merge", which copies batches
+	// of insert, update and delete events into unlogged staging tables and
+	// applies them with set based queries, for both snapshots and
+	// replication. The merge mode requires primary keys on the target tables.
+	BulkIngestMode string
+	RetryPolicy    backoff.Config
+	IgnoreDDL      bool
+	// ConcurrentDDL builds and drops indexes and detaches partitions
+	// concurrently on the target, so that the DDL doesn't block the
+	// replicated changes.
+	ConcurrentDDL bool
+	// TableRoutes maps source table patterns to target table names, so that
+	// the target doesn't need to mirror the source names. Wildcards "*" are
+	// supported on both sides (for example "public.*" -> "replica_public.*"),
+	// as well as the {schema} and {table} placeholders on the target. Routes
+	// apply to DML and DDL events.
+	TableRoutes map[string]string
+	// ApplyMode defines how changes are applied to the target tables. One of
+	// "mirror" (default), which keeps an exact copy of the source tables,
+	// "soft_delete", which sets a deleted_at column instead of deleting rows,
+	// or "history", which inserts a new version row with valid_from, valid_to
+	// and source_lsn columns on every change. Target tables for the soft delete
+	// and history modes are created and evolved from the DDL events. Existing
+	// target tables, such as the ones restored by the schema snapshot, get the
+	// mode columns (and lose their primary key in history mode) before they're
+	// first written to.
+	ApplyMode string
+	// ReplicationOrigin is the name of the replication origin set on the
+	// target sessions. Changes applied by pgstream are tagged with it, so that
+	// a pipeline replicating in the opposite direction can skip them (see the
+	// wal2json filter-origins plugin argument) and avoid replication loops.
+	ReplicationOrigin string
+	// ConflictResolution defines how concurrent changes to the same rows on
+	// both sides of a bidirectional replication are resolved. One of
+	// "last_writer_wins", which applies a change only if it's newer than the
+	// target row by commit timestamp, "source_priority", which always applies
+	// the source changes, upserting updated rows that were deleted on the
+	// target, or "target_priority", which doesn't overwrite rows last modified
+	// locally on the target. last_writer_wins and target_priority require a
+	// replication origin and track_commit_timestamp to be enabled on the
+	// target. Empty by default, which applies all changes as configured by
+	// OnConflictAction.
+	ConflictResolution string
+	// ApplyWorkers is the number of workers applying the changes of each batch
+	// in parallel, each on its own connection. Changes are assigned to the
+	// workers by table and primary key, so that the changes to a row are
+	// applied in order. DDL, truncate and primary key update events act as
+	// barriers. Changes are applied serially when not greater than 1. Not
+	// supported with a replication origin, since only one session can use it
+	// at a time.
+	ApplyWorkers int
+	// ExactlyOnce records the position of the applied changes in the
+	// pgstream.apply_progress table of the target, in the same transaction as
+	// the changes, so that changes received again after a restart are skipped
+	// instead of being applied twice. Not supported with parallel apply.
+	ExactlyOnce bool
+	// ApplyProgressName identifies the pipeline in the apply progress table,
+	// so that multiple pipelines can write to the same target. Defaults to
+	// "default".
+	ApplyProgressName string
+	// TableConflictPolicies overrides how inserts conflicting with existing
+	// rows are handled for specific target tables, keyed by qualified table
+	// name ("schema.table", or "schema.*" for all the tables of a schema).
+	TableConflictPolicies map[string]TableConflictPolicy
+	// SequenceSync copies the values of the sequences used by the replicated
+	// tables from the source to the target, since logical decoding doesn't
+	// replicate sequence advances. Disabled when no source URL is set.
+	SequenceSync SequenceSyncConfig
+	// RoleSync replicates the roles of the source database and their
+	// memberships to the target, since role changes are not captured by the
+	// DDL event triggers. Disabled when no source URL is set.
+	RoleSync RoleSyncConfig
+}
+
+// SequenceSyncConfig defines how the target sequences are kept in sync with
+// the source ones.
+type SequenceSyncConfig struct {
+	// SourceURL is the URL of the source database the sequence values are
+	// read from.
+	SourceURL string
+	// Interval at which the sequences are synced. The sequences are also
+	// synced on shutdown. Defaults to 1 minute.
+	Interval time.Duration
+	// Margin is added to the source sequence values, so that the target
+	// sequences stay ahead of the values used on the source since the last
+	// sync.
+	Margin int64
+}
+
+// RoleSyncConfig defines how the target roles are kept in sync with the
+// source ones.
+type RoleSyncConfig struct {
+	// SourceURL is the URL of the source database the roles are read from.
+	SourceURL string
+	// Mode matches the roles snapshot mode used for the schema snapshot. One
+	// of "no_passwords" (default), which doesn't sync the role passwords, or
+	// "enabled", which syncs them as well and requires superuser privileges
+	// on the source.
+	Mode string
+	// Interval at which the roles are synced. Roles are also synced before
+	// applying privilege and ownership changes. Defaults to 1 minute.
+	Interval time.Duration
+}
+
+// TableConflictPolicy defines how inserts conflicting with existing rows of a
+// table are handled.
+type TableConflictPolicy struct {
+	// OnConflictAction overrides the global on conflict action for the table.
+	// One of "update", "nothing" or "error".
+	OnConflictAction string
+	// ConflictColumns are the columns of the unique index used as conflict
+	// target instead of the primary key.
+	ConflictColumns []string
+	// ConflictConstraint is the name of the unique constraint used as conflict
+	// target instead of the primary key. Takes precedence over the conflict
+	// columns.
+	ConflictConstraint string
+	// UpdateColumns restricts the columns updated on conflict. All the
+	// inserted columns are updated by default.
+	UpdateColumns []string
 }
 
+const (
+	// BulkIngestModeCopy only bulk writes insert events using COPY.
+	BulkIngestModeCopy = "copy"
+	// BulkIngestModeMerge bulk writes insert, update and delete events through
+	// staging tables.
+	BulkIngestModeMerge = "merge"
+)
+
+var errUnsupportedBulkIngestMode = errors.New("unsupported bulk ingest mode")
+
+const (
+	// RoleSyncModeEnabled syncs the roles including their passwords.
+	RoleSyncModeEnabled = "enabled"
+	// RoleSyncModeNoPasswords syncs the roles without their passwords.
+	RoleSyncModeNoPasswords = "no_passwords"
+)
+
+var errUnsupportedRoleSyncMode = errors.New("unsupported role sync mode")
+
 const (
 	defaultInitialInterval = 500 * time.Millisecond
 	defaultMaxInterval     = 30 * time.Second
@@ -35,3 +176,32 @@ func (c *Config) retryPolicy() backoff.Config {
 		},
 	}
 }
+
+// IsBulkMergeEnabled returns true if bulk ingest is enabled in merge mode. It
+// returns an error if the bulk ingest mode is not supported.
+func (c *Config) IsBulkMergeEnabled() (bool, error) {
+	switch c.BulkIngestMode {
+	case "", BulkIngestModeCopy:
+		return false, nil
+	case BulkIngestModeMerge:
+		return c.BulkIngestEnabled, nil
+	default:
+		return false, fmt.Errorf("%w: %q", errUnsupportedBulkIngestMode, c.BulkIngestMode)
+	}
+}
+
+func (c *RoleSyncConfig) mode() string {
+	if c.Mode == "" {
+		return RoleSyncModeNoPasswords
+	}
+	return c.Mode
+}
+
+func (c *RoleSyncConfig) validate() error {
+	switch c.mode() {
+	case RoleSyncModeEnabled, RoleSyncModeNoPasswords:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", errUnsupportedRoleSyncMode, c.Mode)
+	}
+}
@@ -32,6 +32,8 @@ type mockSchemaObserver struct {
 	getGeneratedColumnNamesFn      func(ctx context.Context, schema, table string) (map[string]struct{}, error)
 	getAlwaysIdentityColumnNamesFn func(ctx context.Context, schema, table string) (map[string]struct{}, error)
 	getSequenceColumnsFn           func(ctx context.Context, schema, table string) (map[string]string, error)
+	getSequencesFn                 func() []string
+	getForeignKeyTablesFn          func(ctx context.Context, schema, table string) (map[string]struct{}, error)
 	isMaterializedViewFn           func(schema, table string) bool
 	updateFn                       func(ddlEvent *wal.DDLEvent)
 	closeFn                        func() error
@@ -52,6 +54,20 @@ func (m *mockSchemaObserver) getSequenceColumns(ctx context.Context, schema, tab
 	return m.getSequenceColumnsFn(ctx, schema, table)
 }
 
+func (m *mockSchemaObserver) getSequences() []string {
+	if m.getSequencesFn == nil {
+		return nil
+	}
+	return m.getSequencesFn()
+}
+
+func (m *mockSchemaObserver) getForeignKeyTables(ctx context.Context, schema, table string) (map[string]struct{}, error) {
+	if m.getForeignKeyTablesFn == nil {
+		return nil, nil
+	}
+	return m.getForeignKeyTablesFn(ctx, schema, table)
+}
+
 func (m *mockSchemaObserver) isMaterializedView(ctx context.Context, schema, table string) bool {
 	return m.isMaterializedViewFn(schema, table)
 }
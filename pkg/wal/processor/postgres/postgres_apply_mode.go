@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// applyMode defines how the source changes are applied to the target tables.
+type applyMode uint
+
+const (
+	// applyModeMirror keeps the target tables as an exact copy of the source.
+	applyModeMirror applyMode = iota
+	// applyModeSoftDelete keeps the deleted rows on the target, marking them
+	// with a deletion timestamp instead.
+	applyModeSoftDelete
+	// applyModeHistory keeps every version of the rows on the target (slowly
+	// changing dimension type 2), with the validity range of each version.
+	applyModeHistory
+)
+
+const (
+	deletedAtColumn = "deleted_at"
+	validFromColumn = "valid_from"
+	validToColumn   = "valid_to"
+	lsnColumn       = "source_lsn"
+
+	timestampColumnType = "timestamptz"
+	lsnColumnType       = "pg_lsn"
+)
+
+var errUnsupportedApplyMode = errors.New("unsupported apply mode")
+
+func parseApplyMode(mode string) (applyMode, error) {
+	switch mode {
+	case "", "mirror":
+		return applyModeMirror, nil
+	case "soft_delete":
+		return applyModeSoftDelete, nil
+	case "history":
+		return applyModeHistory, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", errUnsupportedApplyMode, mode)
+	}
+}
+
+// columns returns the columns the apply mode requires on the target
+// tables, on top of the source ones.
+func (m applyMode) columns() []wal.DDLColumn {
+	switch m {
+	case applyModeSoftDelete:
+		return []wal.DDLColumn{
+			{Name: deletedAtColumn, Type: timestampColumnType, Nullable: true},
+		}
+	case applyModeHistory:
+		return []wal.DDLColumn{
+			{Name: validFromColumn, Type: timestampColumnType, Nullable: true},
+			{Name: validToColumn, Type: timestampColumnType, Nullable: true},
+			{Name: lsnColumn, Type: lsnColumnType, Nullable: true},
+		}
+	default:
+		return nil
+	}
+}
+
+// prepareTableDDL returns the DDL that makes an existing target table suitable
+// for the apply mode, or an empty string if there's nothing to prepare. It's
+// needed for the tables that weren't created from the DDL events, such as the
+// ones restored by the schema snapshot or created before the pipeline started.
+// The mode columns are added if they don't exist, and history tables have
+// their primary key and unique constraints dropped, since they hold multiple
+// versions of each row. The DDL is idempotent, and a no-op if the table
+// doesn't exist.
+func (m applyMode) prepareTableDDL(schema, table string) string {
+	modeColumns := m.columns()
+	if len(modeColumns) == 0 {
+		return ""
+	}
+
+	tableName := quotedTableName(schema, table)
+	tableLiteral := pglib.QuoteLiteral(tableName)
+	dropConstraints := ""
+	if m == applyModeHistory {
+		dropConstraints = fmt.Sprintf(`
+	FOR c IN SELECT conname FROM pg_constraint WHERE conrelid = %[1]s::regclass AND contype IN ('p', 'u') LOOP
+		EXECUTE format('ALTER TABLE %%s DROP CONSTRAINT %%I CASCADE', %[1]s::regclass, c);
+	END LOOP;`, tableLiteral)
+	}
+
+	return fmt.Sprintf(`DO $pgstream$
+DECLARE c name;
+BEGIN
+	IF to_regclass(%s) IS NULL THEN
+		RETURN;
+	END IF;
+	%s%s
+END $pgstream$`, tableLiteral, addColumnsDDL(tableName, modeColumns), dropConstraints)
+}
+
+// buildSoftDeleteQuery marks the rows identified by the delete event as
+// deleted.
+func (a *dmlAdapter) buildSoftDeleteQuery(d *wal.Data) (*query, error) {
+	whereQuery, whereValues, err := a.buildWhereQuery(d, 1)
+	if err != nil {
+		return nil, fmt.Errorf("building soft delete query: %w", err)
+	}
+	return &query{
+		table:  d.Table,
+		schema: d.Schema,
+		sql: fmt.Sprintf("UPDATE %s SET %s = $1 %s AND %s IS NULL",
+			quotedTableName(d.Schema, d.Table), pglib.QuoteIdentifier(deletedAtColumn),
+			whereQuery, pglib.QuoteIdentifier(deletedAtColumn)),
+		args: append([]any{eventTimestamp(d)}, whereValues...),
+	}, nil
+}
+
+// buildSoftTruncateQuery marks all the rows in the table as deleted.
+func (a *dmlAdapter) buildSoftTruncateQuery(d *wal.Data) *query {
+	return &query{
+		table:  d.Table,
+		schema: d.Schema,
+		sql: fmt.Sprintf("UPDATE %[1]s SET %[2]s = $1 WHERE %[2]s IS NULL",
+			quotedTableName(d.Schema, d.Table), pglib.QuoteIdentifier(deletedAtColumn)),
+		args: []any{eventTimestamp(d)},
+	}
+}
+
+// buildHistoryQueries closes the current version of the row affected by the
+// event, and inserts a new version for inserts and updates.
+func (a *dmlAdapter) buildHistoryQueries(d *wal.Data, schemaInfo schemaInfo) ([]*query, error) {
+	ts := eventTimestamp(d)
+	switch d.Action {
+	case "T":
+		return []*query{
+			{
+				table:  d.Table,
+				schema: d.Schema,
+				sql: fmt.Sprintf("UPDATE %[1]s SET %[2]s = $1 WHERE %[2]s IS NULL",
+					quotedTableName(d.Schema, d.Table), pglib.QuoteIdentifier(validToColumn)),
+				args: []any{ts},
+			},
+		}, nil
+	case "D":
+		q, err := a.buildCloseVersionQuery(d, ts)
+		if err != nil {
+			return nil, err
+		}
+		return []*query{q}, nil
+	case "I":
+		return a.buildInsertQueries(withVersionColumns(d, ts), schemaInfo), nil
+	case "U":
+		q, err := a.buildCloseVersionQuery(d, ts)
+		if err != nil {
+			return nil, err
+		}
+		if d.HasUnchangedToastColumns() {
+			insert, err := a.buildUnchangedToastVersionQuery(d, schemaInfo, ts)
+			if err != nil {
+				return nil, err
+			}
+			return []*query{q, insert}, nil
+		}
+		return append([]*query{q}, a.buildInsertQueries(withVersionColumns(d, ts), schemaInfo)...), nil
+	default:
+		return []*query{}, nil
+	}
+}
+
+func (a *dmlAdapter) buildCloseVersionQuery(d *wal.Data, ts time.Time) (*query, error) {
+	whereQuery, whereValues, err := a.buildWhereQuery(d, 1)
+	if err != nil {
+		return nil, fmt.Errorf("building history query: %w", err)
+	}
+	return &query{
+		table:  d.Table,
+		schema: d.Schema,
+		sql: fmt.Sprintf("UPDATE %s SET %s = $1 %s AND %s IS NULL",
+			quotedTableName(d.Schema, d.Table), pglib.QuoteIdentifier(validToColumn),
+			whereQuery, pglib.QuoteIdentifier(validToColumn)),
+		args: append([]any{ts}, whereValues...),
+	}, nil
+}
+
+// buildUnchangedToastVersionQuery inserts the new version of a row updated
+// without the value of its unchanged TOAST columns, copying them from the
+// version closed by the update.
+func (a *dmlAdapter) buildUnchangedToastVersionQuery(d *wal.Data, schemaInfo schemaInfo, ts time.Time) (*query, error) {
+	names, _, values := a.filterRowColumnsWithTypes(withVersionColumns(d, ts).Columns, schemaInfo)
+	placeholders := make([]string, 0, len(values)+len(d.UnchangedToastColumns))
+	for i := range values {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+	}
+
+	whereQuery, whereValues, err := a.buildWhereQuery(d, len(values))
+	if err != nil {
+		return nil, fmt.Errorf("building history query: %w", err)
+	}
+	tsPlaceholder := len(values) + len(whereValues) + 1
+
+	table := quotedTableName(d.Schema, d.Table)
+	for _, col := range d.UnchangedToastColumns {
+		quoted := pglib.QuoteIdentifier(col)
+		if _, found := schemaInfo.generatedColumns[quoted]; found {
+			continue
+		}
+		names = append(names, quoted)
+		placeholders = append(placeholders, fmt.Sprintf("(SELECT %s FROM %s %s AND %s = $%d LIMIT 1)",
+			quoted, table, whereQuery, pglib.QuoteIdentifier(validToColumn), tsPlaceholder))
+	}
+
+	args := append(values, whereValues...)
+	return &query{
+		table:  d.Table,
+		schema: d.Schema,
+		sql: fmt.Sprintf("INSERT INTO %s(%s) OVERRIDING SYSTEM VALUE VALUES(%s)",
+			table, strings.Join(names, ", "), strings.Join(placeholders, ", ")),
+		args: append(args, ts),
+	}, nil
+}
+
+// withVersionColumns returns a copy of the wal data with the history version
+// columns added to the row.
+func withVersionColumns(d *wal.Data, ts time.Time) *wal.Data {
+	versioned := *d
+	versioned.Columns = make([]wal.Column, 0, len(d.Columns)+3)
+	versioned.Columns = append(versioned.Columns, d.Columns...)
+	var lsn any
+	if d.LSN != "" {
+		lsn = d.LSN
+	}
+	versioned.Columns = append(versioned.Columns,
+		wal.Column{Name: validFromColumn, Type: timestampColumnType, Value: ts},
+		wal.Column{Name: validToColumn, Type: timestampColumnType, Value: nil},
+		wal.Column{Name: lsnColumn, Type: lsnColumnType, Value: lsn},
+	)
+	return &versioned
+}
+
+// eventTimestamp returns the commit timestamp of the event, defaulting to the
+// current time for events without one (for example snapshot rows).
+func eventTimestamp(d *wal.Data) time.Time {
+	if d.Timestamp != "" {
+		if ts, err := d.GetTimestamp(); err == nil {
+			return ts
+		}
+	}
+	return time.Now().UTC()
+}
@@ -0,0 +1,521 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func Test_parseApplyMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		mode string
+
+		wantMode applyMode
+		wantErr  error
+	}{
+		{mode: "", wantMode: applyModeMirror},
+		{mode: "mirror", wantMode: applyModeMirror},
+		{mode: "soft_delete", wantMode: applyModeSoftDelete},
+		{mode: "history", wantMode: applyModeHistory},
+		{mode: "invalid", wantErr: errUnsupportedApplyMode},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.mode, func(t *testing.T) {
+			t.Parallel()
+
+			mode, err := parseApplyMode(tc.mode)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantMode, mode)
+		})
+	}
+}
+
+func TestDMLAdapter_walDataToQueries_applyModes(t *testing.T) {
+	t.Parallel()
+
+	testSchema := "test"
+	testTable := "table"
+	quotedTestTable := quotedTableName(testSchema, testTable)
+	testTimestamp := "2024-01-02 03:04:05.000006+00"
+	ts, err := (&wal.Data{Timestamp: testTimestamp}).GetTimestamp()
+	require.NoError(t, err)
+
+	newWalData := func(action string) *wal.Data {
+		return &wal.Data{
+			Action:    action,
+			Schema:    testSchema,
+			Table:     testTable,
+			Timestamp: testTimestamp,
+			LSN:       "0/15D6A28",
+			Columns: []wal.Column{
+				{ID: "1", Name: "id", Value: 1},
+				{ID: "2", Name: "name", Value: "alice"},
+			},
+			Identity: []wal.Column{
+				{ID: "1", Name: "id", Value: 1},
+			},
+		}
+	}
+	historyInsertQuery := &query{
+		schema:      testSchema,
+		table:       testTable,
+		columnNames: []string{`"id"`, `"name"`, `"valid_from"`, `"valid_to"`, `"source_lsn"`},
+		sql:         fmt.Sprintf(`INSERT INTO %s("id", "name", "valid_from", "valid_to", "source_lsn") OVERRIDING SYSTEM VALUE VALUES($1, $2, $3, $4, $5)`, quotedTestTable),
+		args:        []any{1, "alice", ts, nil, "0/15D6A28"},
+		// pg_lsn values are not supported by binary COPY
+		needsTextCopy: true,
+	}
+	closeVersionQuery := &query{
+		schema: testSchema,
+		table:  testTable,
+		sql:    fmt.Sprintf(`UPDATE %s SET "valid_to" = $1 WHERE "id" = $2 AND "valid_to" IS NULL`, quotedTestTable),
+		args:   []any{ts, 1},
+	}
+
+	tests := []struct {
+		name      string
+		walData   *wal.Data
+		applyMode applyMode
+		action    onConflictAction
+
+		wantQueries []*query
+	}{
+		{
+			name:      "soft delete - delete",
+			walData:   newWalData("D"),
+			applyMode: applyModeSoftDelete,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    fmt.Sprintf(`UPDATE %s SET "deleted_at" = $1 WHERE "id" = $2 AND "deleted_at" IS NULL`, quotedTestTable),
+					args:   []any{ts, 1},
+				},
+			},
+		},
+		{
+			name:      "soft delete - truncate",
+			walData:   newWalData("T"),
+			applyMode: applyModeSoftDelete,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    fmt.Sprintf(`UPDATE %s SET "deleted_at" = $1 WHERE "deleted_at" IS NULL`, quotedTestTable),
+					args:   []any{ts},
+				},
+			},
+		},
+		{
+			name: "soft delete - insert on conflict update",
+			walData: func() *wal.Data {
+				d := newWalData("I")
+				d.Identity = nil
+				d.Metadata.InternalColIDs = []string{"1"}
+				return d
+			}(),
+			applyMode: applyModeSoftDelete,
+			action:    onConflictUpdate,
+			wantQueries: []*query{
+				{
+					schema:      testSchema,
+					table:       testTable,
+					columnNames: []string{`"id"`, `"name"`},
+					sql:         fmt.Sprintf(`INSERT INTO %s("id", "name") OVERRIDING SYSTEM VALUE VALUES($1, $2) ON CONFLICT ("id") DO UPDATE SET "id" = EXCLUDED."id", "name" = EXCLUDED."name", "deleted_at" = NULL`, quotedTestTable),
+					args:        []any{1, "alice"},
+				},
+			},
+		},
+		{
+			name: "soft delete - insert on conflict do nothing",
+			walData: func() *wal.Data {
+				d := newWalData("I")
+				d.Identity = nil
+				d.Metadata.InternalColIDs = []string{"1"}
+				return d
+			}(),
+			applyMode: applyModeSoftDelete,
+			action:    onConflictDoNothing,
+			wantQueries: []*query{
+				{
+					schema:      testSchema,
+					table:       testTable,
+					columnNames: []string{`"id"`, `"name"`},
+					sql:         fmt.Sprintf(`INSERT INTO %[1]s("id", "name") OVERRIDING SYSTEM VALUE VALUES($1, $2) ON CONFLICT ("id") DO UPDATE SET "id" = EXCLUDED."id", "name" = EXCLUDED."name", "deleted_at" = NULL WHERE %[1]s."deleted_at" IS NOT NULL`, quotedTestTable),
+					args:        []any{1, "alice"},
+				},
+			},
+		},
+		{
+			name: "soft delete - insert on conflict error",
+			walData: func() *wal.Data {
+				d := newWalData("I")
+				d.Identity = nil
+				d.Metadata.InternalColIDs = []string{"1"}
+				return d
+			}(),
+			applyMode: applyModeSoftDelete,
+			action:    onConflictError,
+			wantQueries: []*query{
+				{
+					schema:      testSchema,
+					table:       testTable,
+					columnNames: []string{`"id"`, `"name"`},
+					sql:         fmt.Sprintf(`INSERT INTO %[1]s("id", "name") OVERRIDING SYSTEM VALUE VALUES($1, $2) ON CONFLICT ("id") DO UPDATE SET "id" = EXCLUDED."id", "name" = EXCLUDED."name", "deleted_at" = NULL WHERE %[1]s."deleted_at" IS NOT NULL`, quotedTestTable),
+					args:        []any{1, "alice"},
+				},
+			},
+		},
+		{
+			name:      "soft delete - insert on conflict do nothing without primary key",
+			walData:   newWalData("I"),
+			applyMode: applyModeSoftDelete,
+			action:    onConflictDoNothing,
+			wantQueries: []*query{
+				{
+					schema:      testSchema,
+					table:       testTable,
+					columnNames: []string{`"id"`, `"name"`},
+					sql:         fmt.Sprintf(`INSERT INTO %s("id", "name") OVERRIDING SYSTEM VALUE VALUES($1, $2) ON CONFLICT DO NOTHING`, quotedTestTable),
+					args:        []any{1, "alice"},
+				},
+			},
+		},
+		{
+			name:        "history - insert",
+			walData:     newWalData("I"),
+			applyMode:   applyModeHistory,
+			action:      onConflictUpdate,
+			wantQueries: []*query{historyInsertQuery},
+		},
+		{
+			name:        "history - update",
+			walData:     newWalData("U"),
+			applyMode:   applyModeHistory,
+			wantQueries: []*query{closeVersionQuery, historyInsertQuery},
+		},
+		{
+			name: "history - update with unchanged toast columns",
+			walData: func() *wal.Data {
+				d := newWalData("U")
+				d.UnchangedToastColumns = []string{"bio"}
+				return d
+			}(),
+			applyMode: applyModeHistory,
+			wantQueries: []*query{
+				closeVersionQuery,
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    fmt.Sprintf(`INSERT INTO %s("id", "name", "valid_from", "valid_to", "source_lsn", "bio") OVERRIDING SYSTEM VALUE VALUES($1, $2, $3, $4, $5, (SELECT "bio" FROM %s WHERE "id" = $6 AND "valid_to" = $7 LIMIT 1))`, quotedTestTable, quotedTestTable),
+					args:   []any{1, "alice", ts, nil, "0/15D6A28", 1, ts},
+				},
+			},
+		},
+		{
+			name:        "history - delete",
+			walData:     newWalData("D"),
+			applyMode:   applyModeHistory,
+			wantQueries: []*query{closeVersionQuery},
+		},
+		{
+			name:      "history - truncate",
+			walData:   newWalData("T"),
+			applyMode: applyModeHistory,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    fmt.Sprintf(`UPDATE %s SET "valid_to" = $1 WHERE "valid_to" IS NULL`, quotedTestTable),
+					args:   []any{ts},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := &dmlAdapter{
+				logger:           log.NewNoopLogger(),
+				onConflictAction: tc.action,
+				applyMode:        tc.applyMode,
+				pgTypeMap:        pgtype.NewMap(),
+			}
+			queries, err := a.walDataToQueries(tc.walData, schemaInfo{})
+			require.NoError(t, err)
+			require.Equal(t, tc.wantQueries, queries)
+		})
+	}
+}
+
+func TestDDLAdapter_walDataToQueries_applyModes(t *testing.T) {
+	t.Parallel()
+
+	createTableContent := `{
+		"ddl": "CREATE TABLE public.users (id int PRIMARY KEY, name text);",
+		"schema_name": "public",
+		"command_tag": "CREATE TABLE",
+		"objects": [{
+			"type": "table", "identity": "public.users", "schema": "public",
+			"columns": [
+				{"attnum": 1, "name": "id", "type": "integer", "nullable": false},
+				{"attnum": 2, "name": "name", "type": "text", "nullable": true}
+			],
+			"primary_key_columns": ["id"]
+		}]
+	}`
+	newDDLData := func(content string) *wal.Data {
+		return &wal.Data{
+			Action:  wal.LogicalMessageAction,
+			Prefix:  wal.DDLPrefix,
+			Content: content,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		walData   *wal.Data
+		applyMode applyMode
+
+		wantQueries []*query
+	}{
+		{
+			name:      "soft delete - create table",
+			walData:   newDDLData(createTableContent),
+			applyMode: applyModeSoftDelete,
+			wantQueries: []*query{
+				{
+					schema: "public",
+					table:  "users",
+					sql:    "CREATE TABLE public.users (id int PRIMARY KEY, name text);",
+					isDDL:  true,
+				},
+				{
+					schema: "public",
+					table:  "users",
+					sql:    `ALTER TABLE "public"."users" ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz;`,
+					isDDL:  true,
+				},
+			},
+		},
+		{
+			name:      "history - create table",
+			walData:   newDDLData(createTableContent),
+			applyMode: applyModeHistory,
+			wantQueries: []*query{
+				{
+					schema: "public",
+					table:  "users",
+					sql: "CREATE TABLE IF NOT EXISTS \"public\".\"users\" (\"id\" integer NOT NULL, \"name\" text, \"valid_from\" timestamptz, \"valid_to\" timestamptz, \"source_lsn\" pg_lsn);\n" +
+						"ALTER TABLE \"public\".\"users\" ADD COLUMN IF NOT EXISTS \"id\" integer, ADD COLUMN IF NOT EXISTS \"name\" text, ADD COLUMN IF NOT EXISTS \"valid_from\" timestamptz, ADD COLUMN IF NOT EXISTS \"valid_to\" timestamptz, ADD COLUMN IF NOT EXISTS \"source_lsn\" pg_lsn;",
+					isDDL: true,
+				},
+			},
+		},
+		{
+			name: "history - drop table",
+			walData: newDDLData(`{
+				"ddl": "DROP TABLE public.users;",
+				"schema_name": "public",
+				"command_tag": "DROP TABLE",
+				"objects": [{"type": "table", "identity": "public.users", "schema": "public"}]
+			}`),
+			applyMode:   applyModeHistory,
+			wantQueries: []*query{},
+		},
+		{
+			name: "history - create index",
+			walData: newDDLData(`{
+				"ddl": "CREATE UNIQUE INDEX users_name_idx ON public.users(name);",
+				"schema_name": "public",
+				"command_tag": "CREATE INDEX",
+				"objects": [{"type": "index", "identity": "public.users_name_idx", "schema": "public"}]
+			}`),
+			applyMode:   applyModeHistory,
+			wantQueries: []*query{},
+		},
+		{
+			name: "history - create non unique index",
+			walData: newDDLData(`{
+				"ddl": "CREATE INDEX users_name_idx ON public.users(name);",
+				"schema_name": "public",
+				"command_tag": "CREATE INDEX",
+				"objects": [{"type": "index", "identity": "public.users_name_idx", "schema": "public"}]
+			}`),
+			applyMode: applyModeHistory,
+			wantQueries: []*query{
+				{
+					schema: "public",
+					sql:    "CREATE INDEX IF NOT EXISTS users_name_idx ON public.users(name);",
+					isDDL:  true,
+				},
+			},
+		},
+		{
+			name: "history - alter index",
+			walData: newDDLData(`{
+				"ddl": "ALTER INDEX public.users_name_idx RENAME TO users_name_key;",
+				"schema_name": "public",
+				"command_tag": "ALTER INDEX",
+				"objects": [{"type": "index", "identity": "public.users_name_key", "schema": "public"}]
+			}`),
+			applyMode: applyModeHistory,
+			wantQueries: []*query{
+				{
+					schema: "public",
+					sql:    "ALTER INDEX IF EXISTS public.users_name_idx RENAME TO users_name_key;",
+					isDDL:  true,
+				},
+			},
+		},
+		{
+			name: "soft delete - comment on table",
+			walData: newDDLData(`{
+				"ddl": "COMMENT ON TABLE public.users IS 'application users';",
+				"schema_name": "public",
+				"command_tag": "COMMENT",
+				"objects": [{"type": "table", "identity": "public.users", "schema": "public"}]
+			}`),
+			applyMode: applyModeSoftDelete,
+			wantQueries: []*query{
+				{
+					schema: "public",
+					table:  "users",
+					sql:    "COMMENT ON TABLE public.users IS 'application users';",
+					isDDL:  true,
+				},
+			},
+		},
+		{
+			name: "history - comment on table",
+			walData: newDDLData(`{
+				"ddl": "COMMENT ON TABLE public.users IS 'application users';",
+				"schema_name": "public",
+				"command_tag": "COMMENT",
+				"objects": [{"type": "table", "identity": "public.users", "schema": "public"}]
+			}`),
+			applyMode: applyModeHistory,
+			wantQueries: []*query{
+				{
+					schema: "public",
+					table:  "users",
+					sql:    "COMMENT ON TABLE public.users IS 'application users';",
+					isDDL:  true,
+				},
+			},
+		},
+		{
+			name: "history - grant on table",
+			walData: newDDLData(`{
+				"ddl": "GRANT SELECT ON public.users TO reader;",
+				"schema_name": "public",
+				"command_tag": "GRANT",
+				"objects": [{"type": "table", "identity": "public.users", "schema": "public"}]
+			}`),
+			applyMode: applyModeHistory,
+			wantQueries: []*query{
+				{
+					schema: "public",
+					table:  "users",
+					sql:    "GRANT SELECT ON public.users TO reader;",
+					isDDL:  true,
+				},
+			},
+		},
+		{
+			name: "history - create type",
+			walData: newDDLData(`{
+				"ddl": "CREATE TYPE public.mood AS ENUM ('happy', 'sad');",
+				"schema_name": "public",
+				"command_tag": "CREATE TYPE",
+				"objects": [{"type": "type", "identity": "public.mood", "schema": "public"}]
+			}`),
+			applyMode: applyModeHistory,
+			wantQueries: []*query{
+				{
+					schema: "public",
+					sql:    "CREATE TYPE public.mood AS ENUM ('happy', 'sad');",
+					isDDL:  true,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			adapter := newDDLAdapter(nil, tc.applyMode, false)
+			queries, err := adapter.walDataToQueries(context.Background(), tc.walData)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantQueries, queries)
+		})
+	}
+}
+
+func TestApplyMode_prepareTableDDL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		mode applyMode
+
+		wantDDL string
+	}{
+		{
+			name: "mirror",
+			mode: applyModeMirror,
+
+			wantDDL: "",
+		},
+		{
+			name: "soft delete",
+			mode: applyModeSoftDelete,
+
+			wantDDL: `DO $pgstream$
+DECLARE c name;
+BEGIN
+	IF to_regclass('"test"."table"') IS NULL THEN
+		RETURN;
+	END IF;
+	ALTER TABLE "test"."table" ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz;
+END $pgstream$`,
+		},
+		{
+			name: "history",
+			mode: applyModeHistory,
+
+			wantDDL: `DO $pgstream$
+DECLARE c name;
+BEGIN
+	IF to_regclass('"test"."table"') IS NULL THEN
+		RETURN;
+	END IF;
+	ALTER TABLE "test"."table" ADD COLUMN IF NOT EXISTS "valid_from" timestamptz, ADD COLUMN IF NOT EXISTS "valid_to" timestamptz, ADD COLUMN IF NOT EXISTS "source_lsn" pg_lsn;
+	FOR c IN SELECT conname FROM pg_constraint WHERE conrelid = '"test"."table"'::regclass AND contype IN ('p', 'u') LOOP
+		EXECUTE format('ALTER TABLE %s DROP CONSTRAINT %I CASCADE', '"test"."table"'::regclass, c);
+	END LOOP;
+END $pgstream$`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.wantDDL, tc.mode.prepareTableDDL("test", "table"))
+		})
+	}
+}
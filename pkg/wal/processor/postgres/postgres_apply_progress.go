@@ -0,0 +1,238 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pglogrepl"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	"github.com/xataio/pgstream/pkg/kafka"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// applyProgress keeps track of the position of the last change applied to the
+// target, per input stream (the WAL for a Postgres source, or each topic
+// partition for a Kafka source). The positions are stored in the
+// pgstream.apply_progress table, in the same transaction as the changes, so
+// that changes already applied can be skipped when they're replayed after a
+// restart.
+//
+// WAL positions are made of the commit LSN of the transaction and the LSN of
+// the change within it, since the change LSNs of interleaved transactions
+// don't follow the order in which the transactions are streamed.
+type applyProgress struct {
+	name         string
+	offsetParser kafka.OffsetParser
+
+	mu sync.Mutex
+	// replayed keeps the positions applied before the restart, per stream.
+	// They're only used to skip the replayed changes, and removed once the
+	// stream goes past them.
+	replayed map[string]progressPosition
+}
+
+// progressPosition is a position within an input stream.
+type progressPosition struct {
+	stream string
+	// commit is the commit LSN of the transaction of WAL positions, and zero
+	// for Kafka offsets
+	commit uint64
+	value  uint64
+	raw    wal.CommitPosition
+}
+
+const (
+	applyProgressTable = `"pgstream"."apply_progress"`
+
+	defaultApplyProgressName = "default"
+
+	// commitSeparator separates the commit LSN from the change LSN in WAL
+	// positions
+	commitSeparator = ":"
+)
+
+var (
+	errParallelApplyWithExactlyOnce = errors.New("exactly once apply is not supported with parallel apply")
+	errConcurrentDDLWithExactlyOnce = errors.New("exactly once apply is not supported with concurrent DDL, since it can't run in a transaction")
+)
+
+func newApplyProgress(ctx context.Context, conn pglib.Querier, name string) (*applyProgress, error) {
+	if name == "" {
+		name = defaultApplyProgressName
+	}
+
+	p := &applyProgress{
+		name:         name,
+		offsetParser: kafka.NewOffsetParser(),
+		replayed:     map[string]progressPosition{},
+	}
+
+	if err := createApplyProgressTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	if err := p.load(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func createApplyProgressTable(ctx context.Context, conn pglib.Querier) error {
+	queries := []string{
+		`CREATE SCHEMA IF NOT EXISTS "pgstream"`,
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	"name" text NOT NULL,
+	"stream" text NOT NULL,
+	"position" text NOT NULL,
+	"updated_at" timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY ("name", "stream"))`, applyProgressTable),
+	}
+	for _, q := range queries {
+		if _, err := conn.Exec(ctx, q); err != nil {
+			return fmt.Errorf("creating apply progress table: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *applyProgress) load(ctx context.Context, conn pglib.Querier) error {
+	rows, err := conn.Query(ctx, fmt.Sprintf(`SELECT "position" FROM %s WHERE "name" = $1`, applyProgressTable), p.name)
+	if err != nil {
+		return fmt.Errorf("retrieving apply progress: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var position string
+		if err := rows.Scan(&position); err != nil {
+			return fmt.Errorf("scanning apply progress: %w", err)
+		}
+		pos, err := p.parse(wal.CommitPosition(position))
+		if err != nil {
+			return fmt.Errorf("parsing apply progress position: %w", err)
+		}
+		p.replayed[pos.stream] = pos
+	}
+
+	return rows.Err()
+}
+
+// position returns the position of the event on input in its input stream.
+// For WAL events, it's made of the commit LSN of the transaction and the
+// position of the change, when the commit LSN is available.
+func (p *applyProgress) position(event *wal.Event) wal.CommitPosition {
+	if event.Data == nil || event.Data.CommitLSN == "" || isKafkaPosition(event.CommitPosition) {
+		return event.CommitPosition
+	}
+	return wal.CommitPosition(event.Data.CommitLSN + commitSeparator + string(event.CommitPosition))
+}
+
+// isApplied returns true if the position on input was applied to the target
+// before the restart. Once a stream goes past the position applied before the
+// restart, none of its positions are skipped anymore.
+func (p *applyProgress) isApplied(position wal.CommitPosition) bool {
+	if position == "" {
+		return false
+	}
+	pos, err := p.parse(position)
+	if err != nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	applied, found := p.replayed[pos.stream]
+	if !found {
+		return false
+	}
+	if pos.after(applied) {
+		delete(p.replayed, pos.stream)
+		return false
+	}
+	return true
+}
+
+// record stores the latest position per stream of the messages on input as
+// part of the transaction on input. The messages are expected in stream
+// order.
+func (p *applyProgress) record(ctx context.Context, tx pglib.Tx, msgs []*walMessage) error {
+	latest := map[string]progressPosition{}
+	streams := []string{}
+	for _, msg := range msgs {
+		if msg.position == "" {
+			continue
+		}
+		pos, err := p.parse(msg.position)
+		if err != nil {
+			return err
+		}
+		if _, found := latest[pos.stream]; !found {
+			streams = append(streams, pos.stream)
+		}
+		latest[pos.stream] = pos
+	}
+
+	for _, stream := range streams {
+		pos := latest[stream]
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`INSERT INTO %s("name", "stream", "position", "updated_at") VALUES($1, $2, $3, now())`+
+			` ON CONFLICT ("name", "stream") DO UPDATE SET "position" = EXCLUDED."position", "updated_at" = EXCLUDED."updated_at"`, applyProgressTable),
+			p.name, pos.stream, string(pos.raw)); err != nil {
+			return fmt.Errorf("recording apply progress: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// after returns true if the position is after the one on input in the stream.
+func (p progressPosition) after(other progressPosition) bool {
+	if p.commit != other.commit {
+		return p.commit > other.commit
+	}
+	return p.value > other.value
+}
+
+// parse returns the stream and value of the position on input, which can be a
+// Postgres LSN, optionally prefixed by the commit LSN of its transaction, or a
+// Kafka offset.
+func (p *applyProgress) parse(position wal.CommitPosition) (progressPosition, error) {
+	if isKafkaPosition(position) {
+		offset, err := p.offsetParser.FromString(string(position))
+		if err != nil {
+			return progressPosition{}, err
+		}
+		return progressPosition{
+			stream: fmt.Sprintf("%s/%d", offset.Topic, offset.Partition),
+			value:  uint64(offset.Offset),
+			raw:    position,
+		}, nil
+	}
+
+	commitStr, changeStr, found := strings.Cut(string(position), commitSeparator)
+	if !found {
+		// positions without commit LSN only order the changes by their LSN
+		changeStr = commitStr
+	}
+	commit, err := pglogrepl.ParseLSN(commitStr)
+	if err != nil {
+		return progressPosition{}, err
+	}
+	change, err := pglogrepl.ParseLSN(changeStr)
+	if err != nil {
+		return progressPosition{}, err
+	}
+	return progressPosition{commit: uint64(commit), value: uint64(change), raw: position}, nil
+}
+
+// isKafkaPosition returns true if the position is a Kafka offset. WAL
+// positions with a commit LSN have the same number of slashes, but Kafka
+// topic names can't contain the commit separator.
+func isKafkaPosition(position wal.CommitPosition) bool {
+	return strings.Count(string(position), "/") == 2 && !strings.Contains(string(position), commitSeparator)
+}
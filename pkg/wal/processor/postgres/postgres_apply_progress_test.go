@@ -0,0 +1,431 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	"github.com/xataio/pgstream/pkg/kafka"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+	batchmocks "github.com/xataio/pgstream/pkg/wal/processor/batch/mocks"
+)
+
+func newTestApplyProgress(positions ...wal.CommitPosition) *applyProgress {
+	p := &applyProgress{
+		name:         "test",
+		offsetParser: kafka.NewOffsetParser(),
+		replayed:     map[string]progressPosition{},
+	}
+	for _, position := range positions {
+		pos, err := p.parse(position)
+		if err != nil {
+			panic(err)
+		}
+		p.replayed[pos.stream] = pos
+	}
+	return p
+}
+
+func Test_newApplyProgress(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		progress string
+		querier  *pgmocks.Querier
+
+		wantPositions map[string]progressPosition
+		wantErr       error
+	}{
+		{
+			name:     "ok - default name",
+			progress: "",
+			querier: &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, i uint, s string, a ...any) (pglib.CommandTag, error) {
+					return pglib.CommandTag{}, nil
+				},
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					require.Equal(t, []any{defaultApplyProgressName}, args)
+					return &pgmocks.Rows{
+						NextFn: func(i uint) bool { return i <= 2 },
+						ScanFn: func(i uint, dest ...any) error {
+							position, ok := dest[0].(*string)
+							require.True(t, ok)
+							*position = []string{"0/16B3800:0/16B3748", "orders/1/42"}[i-1]
+							return nil
+						},
+						ErrFn: func() error { return nil },
+					}, nil
+				},
+			},
+
+			wantPositions: map[string]progressPosition{
+				"":         {stream: "", commit: 0x16B3800, value: 0x16B3748, raw: "0/16B3800:0/16B3748"},
+				"orders/1": {stream: "orders/1", value: 42, raw: "orders/1/42"},
+			},
+		},
+		{
+			name:     "error - creating table",
+			progress: "test",
+			querier: &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, i uint, s string, a ...any) (pglib.CommandTag, error) {
+					return pglib.CommandTag{}, errTest
+				},
+			},
+
+			wantErr: errTest,
+		},
+		{
+			name:     "error - querying progress",
+			progress: "test",
+			querier: &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, i uint, s string, a ...any) (pglib.CommandTag, error) {
+					return pglib.CommandTag{}, nil
+				},
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					return nil, errTest
+				},
+			},
+
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := newApplyProgress(context.Background(), tc.querier, tc.progress)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr != nil {
+				return
+			}
+			require.Equal(t, tc.wantPositions, p.replayed)
+		})
+	}
+}
+
+func TestApplyProgress_isApplied(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		position wal.CommitPosition
+
+		wantApplied bool
+	}{
+		{name: "empty position", position: ""},
+		{name: "invalid position", position: "invalid"},
+		{name: "change applied", position: "0/16B3800:0/16B3748", wantApplied: true},
+		{name: "previous change of the same transaction", position: "0/16B3800:0/16B3700", wantApplied: true},
+		{name: "next change of the same transaction", position: "0/16B3800:0/16B3750"},
+		{name: "transaction committed before with a later change lsn", position: "0/16B3780:0/16B3790", wantApplied: true},
+		{name: "transaction committed after with an earlier change lsn", position: "0/16B3900:0/16B3700"},
+		{name: "lsn without commit lsn", position: "0/16B3700", wantApplied: true},
+		{name: "kafka offset applied", position: "orders/1/40", wantApplied: true},
+		{name: "next kafka offset", position: "orders/1/43"},
+		{name: "kafka offset of other partition", position: "orders/2/1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := newTestApplyProgress("0/16B3800:0/16B3748", "orders/1/42")
+			require.Equal(t, tc.wantApplied, p.isApplied(tc.position))
+		})
+	}
+}
+
+func TestApplyProgress_isApplied_liveEvents(t *testing.T) {
+	t.Parallel()
+
+	p := newTestApplyProgress("0/16B3800:0/16B3748")
+	require.True(t, p.isApplied("0/16B3800:0/16B3740"))
+	// once the stream goes past the position applied before the restart, the
+	// live events are never skipped
+	require.False(t, p.isApplied("0/16B3900:0/16B3850"))
+	require.False(t, p.isApplied("0/16B3950:0/16B3700"))
+}
+
+func TestApplyProgress_position(t *testing.T) {
+	t.Parallel()
+
+	p := newTestApplyProgress()
+	require.Equal(t, wal.CommitPosition("0/16B3800:0/16B3748"), p.position(&wal.Event{
+		Data:           &wal.Data{CommitLSN: "0/16B3800"},
+		CommitPosition: "0/16B3748",
+	}))
+	require.Equal(t, wal.CommitPosition("0/16B3748"), p.position(&wal.Event{
+		Data:           &wal.Data{},
+		CommitPosition: "0/16B3748",
+	}))
+	require.Equal(t, wal.CommitPosition("orders/1/42"), p.position(&wal.Event{
+		Data:           &wal.Data{CommitLSN: "0/16B3800"},
+		CommitPosition: "orders/1/42",
+	}))
+}
+
+func TestApplyProgress_record(t *testing.T) {
+	t.Parallel()
+
+	// the last position in stream order is recorded, even if its change lsn
+	// is lower than the previous ones
+	msgs := []*walMessage{
+		{position: "0/16B3800:0/16B3748"},
+		{position: "0/16B3800:0/16B3750"},
+		{},
+		{position: "0/16B3900:0/16B3740"},
+	}
+
+	p := newTestApplyProgress()
+	tx := &pgmocks.Tx{
+		ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+			require.Equal(t, uint(1), i)
+			require.Equal(t, []any{"test", "", "0/16B3900:0/16B3740"}, args)
+			return pglib.CommandTag{}, nil
+		},
+	}
+
+	err := p.record(context.Background(), tx, msgs)
+	require.NoError(t, err)
+
+	err = p.record(context.Background(), &pgmocks.Tx{
+		ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+			return pglib.CommandTag{}, errTest
+		},
+	}, msgs)
+	require.ErrorIs(t, err, errTest)
+}
+
+func TestBatchWriter_ProcessWALEvent_exactlyOnce(t *testing.T) {
+	t.Parallel()
+
+	newEvent := func(commitLSN string, position wal.CommitPosition) *wal.Event {
+		return &wal.Event{
+			Data:           &wal.Data{Action: "I", Schema: testSchema, Table: testTable, CommitLSN: commitLSN},
+			CommitPosition: position,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		walEvent *wal.Event
+
+		wantMsg *walMessage
+	}{
+		{
+			name:     "already applied event is skipped",
+			walEvent: newEvent("0/16B3800", "0/16B3700"),
+			wantMsg:  &walMessage{},
+		},
+		{
+			name:     "new event is tagged with its position",
+			walEvent: newEvent("0/16B3800", "0/16B3750"),
+			wantMsg: &walMessage{
+				data:     newEvent("0/16B3800", "").Data,
+				position: "0/16B3800:0/16B3750",
+			},
+		},
+		{
+			name:     "event of a transaction committed later is not skipped",
+			walEvent: newEvent("0/16B3900", "0/16B3700"),
+			wantMsg: &walMessage{
+				data:     newEvent("0/16B3900", "").Data,
+				position: "0/16B3900:0/16B3700",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			batchSender := batchmocks.NewBatchSender[*walMessage]()
+			writer := &BatchWriter{
+				Writer: &Writer{
+					logger: loglib.NewNoopLogger(),
+					adapter: &mockAdapter{
+						walEventToMessageFn: func(e *wal.Event) (*walMessage, error) {
+							return &walMessage{data: e.Data}, nil
+						},
+					},
+				},
+				batchSender:   batchSender,
+				applyProgress: newTestApplyProgress("0/16B3800:0/16B3748"),
+			}
+
+			go func() {
+				defer batchSender.Close()
+				require.NoError(t, writer.ProcessWALEvent(context.Background(), tc.walEvent))
+			}()
+
+			require.Equal(t, []*batch.WALMessage[*walMessage]{
+				batch.NewWALMessage(tc.wantMsg, tc.walEvent.CommitPosition),
+			}, batchSender.GetWALMessages())
+		})
+	}
+}
+
+func TestBatchWriter_execQueries_exactlyOnce(t *testing.T) {
+	t.Parallel()
+
+	msgs := []*walMessage{{data: &wal.Data{Action: "I"}, position: "0/16B3800:0/16B3750"}}
+	queries := []*query{{sql: "INSERT INTO test_schema.test_table(id) VALUES($1)", args: []any{1}}}
+
+	tests := []struct {
+		name   string
+		execFn func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error)
+
+		wantErr error
+	}{
+		{
+			name: "ok - progress recorded in the same transaction",
+			execFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+				switch i {
+				case 1:
+					require.Equal(t, queries[0].sql, query)
+				case 2:
+					require.Equal(t, []any{"test", "", "0/16B3800:0/16B3750"}, args)
+				default:
+					return pglib.CommandTag{}, errors.New("unexpected call to ExecFn")
+				}
+				return pglib.CommandTag{}, nil
+			},
+		},
+		{
+			name: "error - recording progress",
+			execFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+				if i == 2 {
+					return pglib.CommandTag{}, errTest
+				}
+				return pglib.CommandTag{}, nil
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := &BatchWriter{
+				Writer: &Writer{
+					logger: loglib.NewNoopLogger(),
+					pgConn: &pgmocks.Querier{
+						ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+							return f(&pgmocks.Tx{ExecFn: tc.execFn})
+						},
+					},
+				},
+				applyProgress: newTestApplyProgress(),
+			}
+
+			_, err := writer.execQueries(context.Background(), queries, msgs)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestBatchWriter_execDDL_exactlyOnce(t *testing.T) {
+	t.Parallel()
+
+	msg := &walMessage{data: &wal.Data{Action: "M", Prefix: wal.DDLPrefix}, isDDL: true, position: "0/16B3800:0/16B3750"}
+	ddlQueries := []*query{
+		{sql: "CREATE TABLE test_schema.a(id int)", isDDL: true},
+		{sql: "CREATE INDEX idx ON test_schema.a(id)", isDDL: true},
+	}
+	errAlreadyExists := &pglib.ErrRelationAlreadyExists{Details: "idx"}
+
+	tests := []struct {
+		name    string
+		execErr map[string]error
+
+		wantQueries []string
+		wantErr     error
+	}{
+		{
+			name: "ok - progress recorded in the same transaction as the DDL",
+
+			wantQueries: []string{
+				"SAVEPOINT pgstream_ddl",
+				ddlQueries[0].sql,
+				"RELEASE SAVEPOINT pgstream_ddl",
+				"SAVEPOINT pgstream_ddl",
+				ddlQueries[1].sql,
+				"RELEASE SAVEPOINT pgstream_ddl",
+				"progress",
+			},
+		},
+		{
+			name:    "ok - failed DDL query rolled back to its savepoint",
+			execErr: map[string]error{ddlQueries[1].sql: errAlreadyExists},
+
+			wantQueries: []string{
+				"SAVEPOINT pgstream_ddl",
+				ddlQueries[0].sql,
+				"RELEASE SAVEPOINT pgstream_ddl",
+				"SAVEPOINT pgstream_ddl",
+				ddlQueries[1].sql,
+				"ROLLBACK TO SAVEPOINT pgstream_ddl",
+				"RELEASE SAVEPOINT pgstream_ddl",
+				"progress",
+			},
+		},
+		{
+			name:    "error - internal error aborts the transaction",
+			execErr: map[string]error{ddlQueries[0].sql: errTest},
+
+			wantQueries: []string{
+				"SAVEPOINT pgstream_ddl",
+				ddlQueries[0].sql,
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			queries := []string{}
+			writer := &BatchWriter{
+				Writer: &Writer{
+					logger: loglib.NewNoopLogger(),
+					adapter: &mockAdapter{
+						walEventToQueriesFn: func(e *wal.Event) ([]*query, error) {
+							return ddlQueries, nil
+						},
+					},
+					pgConn: &pgmocks.Querier{
+						ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+							return f(&pgmocks.Tx{
+								ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+									if strings.Contains(query, applyProgressTable) {
+										require.Equal(t, []any{"test", "", "0/16B3800:0/16B3750"}, args)
+										query = "progress"
+									}
+									queries = append(queries, query)
+									return pglib.CommandTag{}, tc.execErr[query]
+								},
+							})
+						},
+					},
+				},
+				applyProgress: newTestApplyProgress(),
+			}
+
+			err := writer.execDDL(context.Background(), msg)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantQueries, queries)
+		})
+	}
+}
@@ -0,0 +1,478 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xataio/pgstream/internal/json"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	synclib "github.com/xataio/pgstream/internal/sync"
+	"github.com/xataio/pgstream/pkg/backoff"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+)
+
+// AuditWriter is a WAL processor implementation that appends every change into
+// an audit log table on the target Postgres instance, with the before and
+// after images of the rows as JSONB. Changes are written in bulk using the
+// COPY command.
+type AuditWriter struct {
+	*Writer
+
+	batchSender queryBatchSender
+	schema      string
+	table       string
+	partitioned bool
+	retention   time.Duration
+	// set of monthly partitions known to exist, keyed by partition name
+	partitions *synclib.Map[string, struct{}]
+	marshaler  func(any) ([]byte, error)
+	now        func() time.Time
+
+	cancelRetention context.CancelFunc
+	wg              sync.WaitGroup
+}
+
+type AuditConfig struct {
+	URL         string
+	BatchConfig batch.Config
+	RetryPolicy backoff.Config
+	// Schema qualified name of the audit log table. Defaults to
+	// "audit.changes".
+	Table string
+	// MonthlyPartitioning enables monthly range partitioning of the audit log
+	// table on the commit timestamp. Partitions are created as needed.
+	MonthlyPartitioning bool
+	// Retention is the minimum amount of time the changes are kept in the
+	// audit log. Older changes are pruned periodically, by dropping whole
+	// partitions when partitioning is enabled. Zero disables pruning.
+	Retention time.Duration
+	// RetentionCheckInterval is how often the retention pruning runs.
+	// Defaults to 1h.
+	RetentionCheckInterval time.Duration
+}
+
+const (
+	auditWriter = "postgres_audit_writer"
+
+	defaultAuditTable             = "audit.changes"
+	defaultRetentionCheckInterval = time.Hour
+
+	auditPartitionSuffixFormat = "200601"
+)
+
+var auditColumnNames = []string{
+	`"schema_name"`, `"table_name"`, `"action"`, `"lsn"`, `"xid"`,
+	`"commit_timestamp"`, `"primary_key"`, `"before"`, `"after"`,
+}
+
+var errInvalidAuditTable = errors.New("invalid audit table name")
+
+// NewAuditWriter returns a postgres processor that writes every change into the
+// configured audit log table, creating it if it doesn't exist.
+func NewAuditWriter(ctx context.Context, config *AuditConfig, opts ...WriterOption) (*AuditWriter, error) {
+	tableName := config.Table
+	if tableName == "" {
+		tableName = defaultAuditTable
+	}
+	schema, table, found := strings.Cut(tableName, ".")
+	if !found || schema == "" || table == "" || strings.Contains(table, ".") {
+		return nil, fmt.Errorf("%w: %q, must be schema qualified", errInvalidAuditTable, tableName)
+	}
+
+	// the audit writer doesn't apply the DDL events, they are recorded as
+	// changes
+	w, err := newWriter(ctx, &Config{
+		URL:         config.URL,
+		BatchConfig: config.BatchConfig,
+		RetryPolicy: config.RetryPolicy,
+		IgnoreDDL:   true,
+	}, auditWriter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	aw := &AuditWriter{
+		Writer:      w,
+		schema:      schema,
+		table:       table,
+		partitioned: config.MonthlyPartitioning,
+		retention:   config.Retention,
+		partitions:  synclib.NewMap[string, struct{}](),
+		marshaler:   json.Marshal,
+		now:         time.Now,
+	}
+
+	if err := aw.createAuditTable(ctx); err != nil {
+		aw.close()
+		return nil, err
+	}
+
+	aw.batchSender, err = batch.NewSender(ctx, &config.BatchConfig, aw.sendBatch, w.logger)
+	if err != nil {
+		aw.close()
+		return nil, err
+	}
+
+	if aw.retention > 0 {
+		interval := config.RetentionCheckInterval
+		if interval <= 0 {
+			interval = defaultRetentionCheckInterval
+		}
+		retentionCtx, cancel := context.WithCancel(context.Background())
+		aw.cancelRetention = cancel
+		aw.wg.Add(1)
+		go func() {
+			defer aw.wg.Done()
+			aw.runRetention(retentionCtx, interval)
+		}()
+	}
+
+	return aw, nil
+}
+
+// ProcessWALEvent is called on every new message from the wal. It can be called
+// concurrently.
+func (w *AuditWriter) ProcessWALEvent(ctx context.Context, walEvent *wal.Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Panic("[PANIC] Panic while processing replication event", loglib.Fields{
+				"wal_data":    walEvent,
+				"panic":       r,
+				"stack_trace": debug.Stack(),
+			})
+
+			err = fmt.Errorf("postgres audit writer: understanding event: %w:  %v", processor.ErrPanic, r)
+		}
+	}()
+
+	q, err := w.walEventToAuditQuery(walEvent)
+	if err != nil {
+		return err
+	}
+
+	// empty queries are still sent so that the commit position is checkpointed
+	return w.batchSender.SendMessage(ctx, batch.NewWALMessage(q, walEvent.CommitPosition))
+}
+
+func (w *AuditWriter) Name() string {
+	return auditWriter
+}
+
+func (w *AuditWriter) Close() error {
+	w.logger.Debug("closing audit writer")
+	w.batchSender.Close()
+	if w.cancelRetention != nil {
+		w.cancelRetention()
+	}
+	w.wg.Wait()
+	return w.close()
+}
+
+func (w *AuditWriter) sendBatch(ctx context.Context, b *batch.Batch[*query]) error {
+	queries := make([]*query, 0, len(b.GetMessages()))
+	for _, q := range b.GetMessages() {
+		if !q.IsEmpty() {
+			queries = append(queries, q)
+		}
+	}
+
+	if len(queries) > 0 {
+		if err := w.ensurePartitions(ctx, queries); err != nil {
+			return err
+		}
+
+		w.logger.Trace("writing audit batch", loglib.Fields{"batch_size": len(queries)})
+		if err := w.copyFromInsertQueries(ctx, queries); err != nil {
+			return err
+		}
+	}
+
+	if w.checkpointer != nil && len(b.GetCommitPositions()) > 0 {
+		return w.checkpointer(ctx, b.GetCommitPositions())
+	}
+
+	return nil
+}
+
+func (w *AuditWriter) walEventToAuditQuery(walEvent *wal.Event) (*query, error) {
+	d := walEvent.Data
+	if d == nil {
+		return &query{}, nil
+	}
+
+	var tableName, action string
+	var primaryKey, before, after map[string]any
+	switch {
+	case d.IsDDLEvent():
+		ddlEvent, err := wal.WalDataToDDLEvent(d)
+		if err != nil {
+			return nil, err
+		}
+		action = "DDL"
+		if tableObjects := ddlEvent.GetTableObjects(); len(tableObjects) > 0 {
+			tableName = pglib.UnquoteIdentifier(tableObjects[0].GetTable())
+		}
+		after = map[string]any{
+			"ddl":         ddlEvent.DDL,
+			"command_tag": ddlEvent.CommandTag,
+		}
+		d = &wal.Data{
+			Schema:    ddlEvent.SchemaName,
+			LSN:       d.LSN,
+			XID:       d.XID,
+			Timestamp: d.Timestamp,
+		}
+	case d.Action == wal.LogicalMessageAction:
+		// other logical messages are not table changes
+		return &query{}, nil
+	default:
+		tableName = d.Table
+		switch d.Action {
+		case "I":
+			action = "INSERT"
+			after = columnsToImage(d.Columns)
+		case "U":
+			action = "UPDATE"
+			before = columnsToImage(d.Identity)
+			after = columnsToImage(d.Columns)
+		case "D":
+			action = "DELETE"
+			before = columnsToImage(d.Identity)
+		case "T":
+			action = "TRUNCATE"
+		default:
+			return &query{}, nil
+		}
+		primaryKey = primaryKeyImage(d)
+	}
+
+	primaryKeyJSON, err := w.marshalImage(primaryKey)
+	if err != nil {
+		return nil, err
+	}
+	beforeJSON, err := w.marshalImage(before)
+	if err != nil {
+		return nil, err
+	}
+	afterJSON, err := w.marshalImage(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var lsn, xid any
+	if d.LSN != "" {
+		lsn = d.LSN
+	}
+	if d.XID != 0 {
+		xid = d.XID
+	}
+
+	ts := eventTimestamp(d)
+	placeholders := make([]string, 0, len(auditColumnNames))
+	for i := range auditColumnNames {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+	}
+
+	return &query{
+		schema: w.schema,
+		table:  w.table,
+		// the column names are modified in place by the text COPY
+		columnNames: slices.Clone(auditColumnNames),
+		sql: fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s)",
+			quotedTableName(w.schema, w.table), strings.Join(auditColumnNames, ", "), strings.Join(placeholders, ", ")),
+		args: []any{d.Schema, tableName, action, lsn, xid, ts, primaryKeyJSON, beforeJSON, afterJSON},
+		// pg_lsn values are not supported by binary COPY
+		needsTextCopy: true,
+		timestamp:     ts,
+	}, nil
+}
+
+func (w *AuditWriter) marshalImage(image map[string]any) (any, error) {
+	if image == nil {
+		return nil, nil
+	}
+	b, err := w.marshaler(image)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling audit row image: %w", err)
+	}
+	return b, nil
+}
+
+func columnsToImage(cols []wal.Column) map[string]any {
+	if len(cols) == 0 {
+		return nil
+	}
+	image := make(map[string]any, len(cols))
+	for _, col := range cols {
+		image[col.Name] = col.Value
+	}
+	return image
+}
+
+// primaryKeyImage returns the primary key values of the row affected by the
+// wal event, falling back to the replica identity.
+func primaryKeyImage(d *wal.Data) map[string]any {
+	if len(d.Metadata.InternalColIDs) > 0 {
+		cols := d.Columns
+		if len(cols) == 0 {
+			cols = d.Identity
+		}
+		pkCols := make([]wal.Column, 0, len(d.Metadata.InternalColIDs))
+		for _, col := range cols {
+			for _, id := range d.Metadata.InternalColIDs {
+				if col.ID == id {
+					pkCols = append(pkCols, col)
+				}
+			}
+		}
+		if len(pkCols) > 0 {
+			return columnsToImage(pkCols)
+		}
+	}
+	return columnsToImage(d.Identity)
+}
+
+func (w *AuditWriter) createAuditTable(ctx context.Context) error {
+	partitionClause := ""
+	if w.partitioned {
+		partitionClause = ` PARTITION BY RANGE ("commit_timestamp")`
+	}
+	sql := fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %[1]s;
+CREATE TABLE IF NOT EXISTS %[2]s (
+	"schema_name" text NOT NULL,
+	"table_name" text,
+	"action" text NOT NULL,
+	"lsn" pg_lsn,
+	"xid" bigint,
+	"commit_timestamp" timestamptz NOT NULL,
+	"primary_key" jsonb,
+	"before" jsonb,
+	"after" jsonb
+)%[3]s;
+CREATE INDEX IF NOT EXISTS %[4]s ON %[2]s ("schema_name", "table_name", "commit_timestamp");`,
+		pglib.QuoteIdentifier(w.schema), quotedTableName(w.schema, w.table), partitionClause,
+		pglib.QuoteIdentifier(w.table+"_table_commit_timestamp_idx"))
+
+	if _, err := w.pgConn.Exec(ctx, sql); err != nil {
+		return fmt.Errorf("creating audit table: %w", err)
+	}
+	return nil
+}
+
+// ensurePartitions creates the monthly partitions required by the queries on
+// input, if they don't exist yet.
+func (w *AuditWriter) ensurePartitions(ctx context.Context, queries []*query) error {
+	if !w.partitioned {
+		return nil
+	}
+
+	for _, q := range queries {
+		if q.timestamp.IsZero() {
+			continue
+		}
+		// partition bounds are in UTC, so the month must be computed in UTC
+		// too, otherwise timestamps near a month boundary miss their partition
+		ts := q.timestamp.UTC()
+		monthStart := time.Date(ts.Year(), ts.Month(), 1, 0, 0, 0, 0, time.UTC)
+		partition := w.partitionName(monthStart)
+		if _, found := w.partitions.Get(partition); found {
+			continue
+		}
+
+		sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+			quotedTableName(w.schema, partition), quotedTableName(w.schema, w.table),
+			monthStart.Format(time.RFC3339), monthStart.AddDate(0, 1, 0).Format(time.RFC3339))
+		if _, err := w.pgConn.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("creating audit partition %s: %w", partition, err)
+		}
+		w.partitions.Set(partition, struct{}{})
+	}
+	return nil
+}
+
+func (w *AuditWriter) partitionName(monthStart time.Time) string {
+	return fmt.Sprintf("%s_p%s", w.table, monthStart.Format(auditPartitionSuffixFormat))
+}
+
+func (w *AuditWriter) runRetention(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.pruneExpiredChanges(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			w.logger.Error(err, "pruning expired audit changes")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pruneExpiredChanges removes the changes older than the retention period. When
+// the audit table is partitioned, only whole partitions are dropped, so changes
+// might be kept for up to a month longer than the retention period.
+func (w *AuditWriter) pruneExpiredChanges(ctx context.Context) error {
+	cutoff := w.now().Add(-w.retention).UTC()
+	if !w.partitioned {
+		tag, err := w.pgConn.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE "commit_timestamp" < $1`, quotedTableName(w.schema, w.table)), cutoff)
+		if err != nil {
+			return fmt.Errorf("deleting expired audit changes: %w", err)
+		}
+		w.logger.Debug("pruned expired audit changes", loglib.Fields{"rows": tag.RowsAffected()})
+		return nil
+	}
+
+	rows, err := w.pgConn.Query(ctx, `SELECT c.relname FROM pg_catalog.pg_inherits i
+	JOIN pg_catalog.pg_class c ON c.oid = i.inhrelid
+	JOIN pg_catalog.pg_class p ON p.oid = i.inhparent
+	JOIN pg_catalog.pg_namespace n ON n.oid = p.relnamespace
+	WHERE n.nspname = $1 AND p.relname = $2`, w.schema, w.table)
+	if err != nil {
+		return fmt.Errorf("listing audit partitions: %w", err)
+	}
+	defer rows.Close()
+
+	expired := []string{}
+	prefix := w.table + "_p"
+	for rows.Next() {
+		var partition string
+		if err := rows.Scan(&partition); err != nil {
+			return fmt.Errorf("scanning audit partition: %w", err)
+		}
+		monthStart, err := time.Parse(auditPartitionSuffixFormat, strings.TrimPrefix(partition, prefix))
+		if !strings.HasPrefix(partition, prefix) || err != nil {
+			// not managed by pgstream
+			continue
+		}
+		if !monthStart.AddDate(0, 1, 0).After(cutoff) {
+			expired = append(expired, partition)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("listing audit partitions: %w", err)
+	}
+	rows.Close()
+
+	for _, partition := range expired {
+		if _, err := w.pgConn.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", quotedTableName(w.schema, partition))); err != nil {
+			return fmt.Errorf("dropping expired audit partition %s: %w", partition, err)
+		}
+		w.partitions.Delete(partition)
+		w.logger.Info("dropped expired audit partition", loglib.Fields{"partition": partition})
+	}
+	return nil
+}
@@ -0,0 +1,481 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/internal/json"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	synclib "github.com/xataio/pgstream/internal/sync"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+)
+
+func TestAuditWriter_walEventToAuditQuery(t *testing.T) {
+	t.Parallel()
+
+	testTimestamp := "2024-01-02 03:04:05.000006+00"
+	ts, err := (&wal.Data{Timestamp: testTimestamp}).GetTimestamp()
+	require.NoError(t, err)
+
+	auditSQL := `INSERT INTO "audit"."changes"("schema_name", "table_name", "action", "lsn", "xid", "commit_timestamp", "primary_key", "before", "after") VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	newAuditQuery := func(args ...any) *query {
+		return &query{
+			schema:        "audit",
+			table:         "changes",
+			columnNames:   auditColumnNames,
+			sql:           auditSQL,
+			args:          args,
+			needsTextCopy: true,
+			timestamp:     ts,
+		}
+	}
+	newWalData := func(action string) *wal.Data {
+		return &wal.Data{
+			Action:    action,
+			Schema:    "public",
+			Table:     "users",
+			LSN:       "0/15D6A28",
+			XID:       42,
+			Timestamp: testTimestamp,
+			Columns: []wal.Column{
+				{ID: "1", Name: "id", Value: 1},
+				{ID: "2", Name: "name", Value: "alice"},
+			},
+			Identity: []wal.Column{
+				{ID: "1", Name: "id", Value: 1},
+				{ID: "2", Name: "name", Value: "bob"},
+			},
+			Metadata: wal.Metadata{InternalColIDs: []string{"1"}},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		event     *wal.Event
+		marshaler func(any) ([]byte, error)
+
+		wantQuery *query
+		wantErr   error
+	}{
+		{
+			name:      "keep alive",
+			event:     &wal.Event{},
+			wantQuery: &query{},
+		},
+		{
+			name: "insert",
+			event: &wal.Event{
+				Data: func() *wal.Data {
+					d := newWalData("I")
+					d.Identity = nil
+					return d
+				}(),
+			},
+			wantQuery: newAuditQuery("public", "users", "INSERT", "0/15D6A28", int64(42), ts,
+				[]byte(`{"id":1}`), nil, []byte(`{"id":1,"name":"alice"}`)),
+		},
+		{
+			name:  "update",
+			event: &wal.Event{Data: newWalData("U")},
+			wantQuery: newAuditQuery("public", "users", "UPDATE", "0/15D6A28", int64(42), ts,
+				[]byte(`{"id":1}`), []byte(`{"id":1,"name":"bob"}`), []byte(`{"id":1,"name":"alice"}`)),
+		},
+		{
+			name: "delete without primary key",
+			event: &wal.Event{
+				Data: func() *wal.Data {
+					d := newWalData("D")
+					d.Columns = nil
+					d.Metadata.InternalColIDs = nil
+					d.XID = 0
+					return d
+				}(),
+			},
+			wantQuery: newAuditQuery("public", "users", "DELETE", "0/15D6A28", nil, ts,
+				[]byte(`{"id":1,"name":"bob"}`), []byte(`{"id":1,"name":"bob"}`), nil),
+		},
+		{
+			name: "truncate",
+			event: &wal.Event{
+				Data: &wal.Data{
+					Action:    "T",
+					Schema:    "public",
+					Table:     "users",
+					LSN:       "0/15D6A28",
+					XID:       42,
+					Timestamp: testTimestamp,
+				},
+			},
+			wantQuery: newAuditQuery("public", "users", "TRUNCATE", "0/15D6A28", int64(42), ts, nil, nil, nil),
+		},
+		{
+			name: "ddl",
+			event: &wal.Event{
+				Data: &wal.Data{
+					Action:    wal.LogicalMessageAction,
+					Prefix:    wal.DDLPrefix,
+					LSN:       "0/15D6A28",
+					Timestamp: testTimestamp,
+					Content: `{
+						"ddl": "ALTER TABLE public.users ADD COLUMN age int;",
+						"schema_name": "public",
+						"command_tag": "ALTER TABLE",
+						"objects": [{"type": "table", "identity": "public.users", "schema": "public"}]
+					}`,
+				},
+			},
+			wantQuery: newAuditQuery("public", "users", "DDL", "0/15D6A28", nil, ts, nil, nil,
+				[]byte(`{"command_tag":"ALTER TABLE","ddl":"ALTER TABLE public.users ADD COLUMN age int;"}`)),
+		},
+		{
+			name: "non ddl logical message",
+			event: &wal.Event{
+				Data: &wal.Data{
+					Action: wal.LogicalMessageAction,
+					Prefix: "other",
+				},
+			},
+			wantQuery: &query{},
+		},
+		{
+			name:      "error - marshaling image",
+			event:     &wal.Event{Data: newWalData("U")},
+			marshaler: func(a any) ([]byte, error) { return nil, errTest },
+			wantErr:   errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			marshaler := json.Marshal
+			if tc.marshaler != nil {
+				marshaler = tc.marshaler
+			}
+			writer := &AuditWriter{
+				schema:    "audit",
+				table:     "changes",
+				marshaler: marshaler,
+			}
+
+			q, err := writer.walEventToAuditQuery(tc.event)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantQuery == nil {
+				require.Nil(t, q)
+				return
+			}
+			require.Equal(t, tc.wantQuery.sql, q.sql)
+			require.Equal(t, tc.wantQuery.columnNames, q.columnNames)
+			require.Equal(t, tc.wantQuery.needsTextCopy, q.needsTextCopy)
+			require.Equal(t, tc.wantQuery.timestamp, q.timestamp)
+			require.Len(t, q.args, len(tc.wantQuery.args))
+			for i, arg := range tc.wantQuery.args {
+				if b, ok := arg.([]byte); ok {
+					require.JSONEq(t, string(b), string(q.args[i].([]byte)))
+					continue
+				}
+				require.Equal(t, arg, q.args[i])
+			}
+		})
+	}
+}
+
+func TestAuditWriter_sendBatch(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+	testQuery := &query{
+		schema:        "audit",
+		table:         "changes",
+		columnNames:   auditColumnNames,
+		sql:           "INSERT INTO audit.changes(...)",
+		args:          []any{"public", "users", "INSERT", "0/15D6A28", nil, ts, nil, nil, nil},
+		needsTextCopy: true,
+		timestamp:     ts,
+	}
+	// 2024-02-01 00:30 in CET is still January in UTC
+	nonUTCQuery := &query{
+		schema:        "audit",
+		table:         "changes",
+		columnNames:   auditColumnNames,
+		sql:           "INSERT INTO audit.changes(...)",
+		needsTextCopy: true,
+		timestamp:     time.Date(2024, time.February, 1, 0, 30, 0, 0, time.FixedZone("CET", 3600)),
+	}
+	testPositions := []wal.CommitPosition{"0/15D6A28"}
+
+	validCopyFn := func(ctx context.Context, f func(tx pglib.Tx) error) error {
+		return f(&pgmocks.Tx{
+			CopyFromTextFn: func(ctx context.Context, tableName string, columnNames []string, rows [][]any) (int64, error) {
+				require.Equal(t, `"audit"."changes"`, tableName)
+				require.Equal(t, [][]any{testQuery.args}, rows)
+				return 1, nil
+			},
+		})
+	}
+
+	tests := []struct {
+		name         string
+		batch        *batch.Batch[*query]
+		partitioned  bool
+		partitions   map[string]struct{}
+		pgConn       *pgmocks.Querier
+		checkpointer func(context.Context, []wal.CommitPosition) error
+
+		wantPartitions map[string]struct{}
+		wantErr        error
+	}{
+		{
+			name:  "ok - only keep alives",
+			batch: batch.NewBatch([]*query{{}}, testPositions),
+			pgConn: &pgmocks.Querier{
+				ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+					return errors.New("ExecInTxFn: should not be called")
+				},
+			},
+		},
+		{
+			name:   "ok - unpartitioned",
+			batch:  batch.NewBatch([]*query{testQuery, {}}, testPositions),
+			pgConn: &pgmocks.Querier{ExecInTxFn: validCopyFn},
+		},
+		{
+			name:        "ok - partitioned, new partition",
+			batch:       batch.NewBatch([]*query{testQuery}, testPositions),
+			partitioned: true,
+			pgConn: &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, i uint, s string, a ...any) (pglib.CommandTag, error) {
+					require.Equal(t, uint(1), i)
+					require.Equal(t, `CREATE TABLE IF NOT EXISTS "audit"."changes_p202401" PARTITION OF "audit"."changes" FOR VALUES FROM ('2024-01-01T00:00:00Z') TO ('2024-02-01T00:00:00Z')`, s)
+					return pglib.CommandTag{}, nil
+				},
+				ExecInTxFn: validCopyFn,
+			},
+			wantPartitions: map[string]struct{}{"changes_p202401": {}},
+		},
+		{
+			name:        "ok - partitioned, non UTC timestamp at month boundary",
+			batch:       batch.NewBatch([]*query{nonUTCQuery}, testPositions),
+			partitioned: true,
+			pgConn: &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, i uint, s string, a ...any) (pglib.CommandTag, error) {
+					require.Equal(t, `CREATE TABLE IF NOT EXISTS "audit"."changes_p202401" PARTITION OF "audit"."changes" FOR VALUES FROM ('2024-01-01T00:00:00Z') TO ('2024-02-01T00:00:00Z')`, s)
+					return pglib.CommandTag{}, nil
+				},
+				ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+					return f(&pgmocks.Tx{
+						CopyFromTextFn: func(ctx context.Context, tableName string, columnNames []string, rows [][]any) (int64, error) {
+							return int64(len(rows)), nil
+						},
+					})
+				},
+			},
+			wantPartitions: map[string]struct{}{"changes_p202401": {}},
+		},
+		{
+			name:        "ok - partitioned, existing partition",
+			batch:       batch.NewBatch([]*query{testQuery}, testPositions),
+			partitioned: true,
+			partitions:  map[string]struct{}{"changes_p202401": {}},
+			pgConn: &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, i uint, s string, a ...any) (pglib.CommandTag, error) {
+					return pglib.CommandTag{}, errors.New("ExecFn: should not be called")
+				},
+				ExecInTxFn: validCopyFn,
+			},
+			wantPartitions: map[string]struct{}{"changes_p202401": {}},
+		},
+		{
+			name:        "error - creating partition",
+			batch:       batch.NewBatch([]*query{testQuery}, testPositions),
+			partitioned: true,
+			pgConn: &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, i uint, s string, a ...any) (pglib.CommandTag, error) {
+					return pglib.CommandTag{}, errTest
+				},
+			},
+			wantPartitions: map[string]struct{}{},
+			wantErr:        errTest,
+		},
+		{
+			name:  "error - copying changes",
+			batch: batch.NewBatch([]*query{testQuery}, testPositions),
+			pgConn: &pgmocks.Querier{
+				ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+					return errTest
+				},
+			},
+			wantErr: errTest,
+		},
+		{
+			name:   "error - checkpointing",
+			batch:  batch.NewBatch([]*query{testQuery}, testPositions),
+			pgConn: &pgmocks.Querier{ExecInTxFn: validCopyFn},
+			checkpointer: func(ctx context.Context, positions []wal.CommitPosition) error {
+				return errTest
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			checkpointer := tc.checkpointer
+			if checkpointer == nil {
+				checkpointer = func(ctx context.Context, positions []wal.CommitPosition) error {
+					require.Equal(t, testPositions, positions)
+					return nil
+				}
+			}
+
+			partitions := synclib.NewMap[string, struct{}]()
+			for p := range tc.partitions {
+				partitions.Set(p, struct{}{})
+			}
+
+			writer := &AuditWriter{
+				Writer: &Writer{
+					logger:       loglib.NewNoopLogger(),
+					pgConn:       tc.pgConn,
+					checkpointer: checkpointer,
+				},
+				schema:      "audit",
+				table:       "changes",
+				partitioned: tc.partitioned,
+				partitions:  partitions,
+			}
+
+			err := writer.sendBatch(context.Background(), tc.batch)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantPartitions != nil {
+				require.Equal(t, tc.wantPartitions, partitions.GetMap())
+			}
+		})
+	}
+}
+
+func TestAuditWriter_pruneExpiredChanges(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, time.April, 15, 0, 0, 0, 0, time.UTC)
+	retention := 30 * 24 * time.Hour
+
+	newPartitionRows := func(partitions ...string) *pgmocks.Rows {
+		return &pgmocks.Rows{
+			NextFn: func(i uint) bool { return i <= uint(len(partitions)) },
+			ScanFn: func(i uint, dest ...any) error {
+				*dest[0].(*string) = partitions[i-1]
+				return nil
+			},
+			ErrFn: func() error { return nil },
+		}
+	}
+
+	tests := []struct {
+		name        string
+		partitioned bool
+		pgConn      *pgmocks.Querier
+
+		wantErr error
+	}{
+		{
+			name: "ok - unpartitioned",
+			pgConn: &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, i uint, s string, a ...any) (pglib.CommandTag, error) {
+					require.Equal(t, `DELETE FROM "audit"."changes" WHERE "commit_timestamp" < $1`, s)
+					require.Equal(t, []any{now.Add(-retention)}, a)
+					return pglib.CommandTag{}, nil
+				},
+			},
+		},
+		{
+			name:        "ok - partitioned",
+			partitioned: true,
+			pgConn: &pgmocks.Querier{
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					require.Equal(t, []any{"audit", "changes"}, args)
+					return newPartitionRows("changes_p202401", "changes_p202402", "changes_p202403", "changes_p202404", "changes_default"), nil
+				},
+				ExecFn: func(ctx context.Context, i uint, s string, a ...any) (pglib.CommandTag, error) {
+					// only the partitions with all changes older than the
+					// cutoff (2024-03-16) are dropped
+					switch i {
+					case 1:
+						require.Equal(t, `DROP TABLE IF EXISTS "audit"."changes_p202401"`, s)
+					case 2:
+						require.Equal(t, `DROP TABLE IF EXISTS "audit"."changes_p202402"`, s)
+					default:
+						return pglib.CommandTag{}, fmt.Errorf("unexpected call to ExecFn: %d", i)
+					}
+					return pglib.CommandTag{}, nil
+				},
+			},
+		},
+		{
+			name: "error - deleting changes",
+			pgConn: &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, i uint, s string, a ...any) (pglib.CommandTag, error) {
+					return pglib.CommandTag{}, errTest
+				},
+			},
+			wantErr: errTest,
+		},
+		{
+			name:        "error - listing partitions",
+			partitioned: true,
+			pgConn: &pgmocks.Querier{
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					return nil, errTest
+				},
+			},
+			wantErr: errTest,
+		},
+		{
+			name:        "error - dropping partition",
+			partitioned: true,
+			pgConn: &pgmocks.Querier{
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					return newPartitionRows("changes_p202401"), nil
+				},
+				ExecFn: func(ctx context.Context, i uint, s string, a ...any) (pglib.CommandTag, error) {
+					return pglib.CommandTag{}, errTest
+				},
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := &AuditWriter{
+				Writer: &Writer{
+					logger: loglib.NewNoopLogger(),
+					pgConn: tc.pgConn,
+				},
+				schema:      "audit",
+				table:       "changes",
+				partitioned: tc.partitioned,
+				retention:   retention,
+				partitions:  synclib.NewMap[string, struct{}](),
+				now:         func() time.Time { return now },
+			}
+
+			err := writer.pruneExpiredChanges(context.Background())
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
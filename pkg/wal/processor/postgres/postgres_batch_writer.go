@@ -9,6 +9,7 @@ import (
 	"runtime/debug"
 
 	pglib "github.com/xataio/pgstream/internal/postgres"
+	synclib "github.com/xataio/pgstream/internal/sync"
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/wal"
 	"github.com/xataio/pgstream/pkg/wal/processor"
@@ -23,6 +24,17 @@ type BatchWriter struct {
 
 	batchSender walMessageBatchSender
 	dmlAdapter  *dmlAdapter
+	// number of workers applying the changes of a batch in parallel. Changes
+	// are applied serially when not greater than 1.
+	applyWorkers int
+	// groups of tables linked by foreign keys, applied by the same worker
+	// with parallel apply
+	fkGroups *foreignKeyGroups
+	// tracks the position of the applied changes on the target when exactly
+	// once apply is enabled, nil otherwise
+	applyProgress *applyProgress
+	// target tables already prepared for the apply mode
+	preparedTables *synclib.Map[string, struct{}]
 }
 
 const batchWriter = "postgres_batch_writer"
@@ -35,14 +47,41 @@ func NewBatchWriter(ctx context.Context, config *Config, opts ...WriterOption) (
 		return nil, err
 	}
 
-	dml, err := newDMLAdapter(config.OnConflictAction, false, w.logger)
+	dml, err := newDMLAdapter(config.OnConflictAction, config.ApplyMode, config.ConflictResolution, false, w.logger)
 	if err != nil {
 		return nil, err
 	}
+	if dml.conflictPolicies, err = newConflictPolicies(config.TableConflictPolicies, dml.onConflictAction); err != nil {
+		return nil, err
+	}
+	if dml.conflictResolution.isConditional() && config.ReplicationOrigin == "" {
+		return nil, errMissingReplicationOrigin
+	}
+	if config.ApplyWorkers > 1 && config.ReplicationOrigin != "" {
+		return nil, errParallelApplyWithReplicationOrigin
+	}
+	if config.ApplyWorkers > 1 && config.ExactlyOnce {
+		return nil, errParallelApplyWithExactlyOnce
+	}
+	if config.ConcurrentDDL && config.ExactlyOnce {
+		return nil, errConcurrentDDLWithExactlyOnce
+	}
 
 	bw := &BatchWriter{
-		Writer:     w,
-		dmlAdapter: dml,
+		Writer:         w,
+		dmlAdapter:     dml,
+		applyWorkers:   config.ApplyWorkers,
+		preparedTables: synclib.NewMap[string, struct{}](),
+	}
+	if config.ApplyWorkers > 1 {
+		bw.fkGroups = newForeignKeyGroups(w.pgConn)
+	}
+
+	if config.ExactlyOnce {
+		bw.applyProgress, err = newApplyProgress(ctx, w.pgConn, config.ApplyProgressName)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	bw.batchSender, err = batch.NewSender(ctx, &config.BatchConfig, bw.sendBatch, w.logger)
@@ -68,10 +107,24 @@ func (w *BatchWriter) ProcessWALEvent(ctx context.Context, walEvent *wal.Event)
 		}
 	}()
 
+	var position wal.CommitPosition
+	if w.applyProgress != nil && walEvent.Data != nil {
+		position = w.applyProgress.position(walEvent)
+		if w.applyProgress.isApplied(position) {
+			// the event was applied on the target before the restart, keep
+			// its position so that it's checkpointed
+			w.logger.Debug("skipping already applied event", loglib.Fields{"commit_position": position})
+			return w.batchSender.SendMessage(ctx, batch.NewWALMessage(&walMessage{}, walEvent.CommitPosition))
+		}
+	}
+
 	walMsg, err := w.adapter.walEventToMessage(ctx, walEvent)
 	if err != nil {
 		return err
 	}
+	if !walMsg.IsEmpty() {
+		walMsg.position = position
+	}
 
 	msg := batch.NewWALMessage(walMsg, walEvent.CommitPosition)
 	if err := w.batchSender.SendMessage(ctx, msg); err != nil {
@@ -97,87 +150,198 @@ func (w *BatchWriter) sendBatch(ctx context.Context, b *batch.Batch[*walMessage]
 	if len(messages) > 0 {
 		w.logger.Debug("sending batch", loglib.Fields{"batch_size": len(messages)})
 
-		// Walk messages in order, building "runs" of consecutive
-		// same-(schema, table, action) DML events that can be coalesced.
-		var currentRun []*walMessage
-		var runSchema, runTable, runAction string
+		applyFn := w.applyMessages
+		if w.applyWorkers > 1 {
+			applyFn = w.applyMessagesInParallel
+		}
+		if err := applyFn(ctx, messages); err != nil {
+			return err
+		}
+	}
+
+	// with parallel apply, the batch is only checkpointed once all the workers
+	// have applied their share of it, so the checkpointed position is always
+	// the minimum fully applied across workers
+	if w.checkpointer != nil && len(b.GetCommitPositions()) > 0 {
+		return w.checkpointer(ctx, b.GetCommitPositions())
+	}
+
+	return nil
+}
+
+// applyMessages applies the messages on input in order, coalescing runs of
+// consecutive same-(schema, table, action) DML events.
+func (w *BatchWriter) applyMessages(ctx context.Context, messages []*walMessage) error {
+	var currentRun []*walMessage
+	var runSchema, runTable, runAction string
 
-		flushRun := func() error {
-			if len(currentRun) == 0 {
-				return nil
+	flushRun := func() error {
+		if len(currentRun) == 0 {
+			return nil
+		}
+		if err := w.prepareTable(ctx, runSchema, runTable); err != nil {
+			return err
+		}
+		queries, err := w.buildCoalescedQueries(currentRun)
+		if err != nil {
+			w.logger.Error(err, "building coalesced queries", loglib.Fields{
+				"action": runAction, "schema": runSchema, "table": runTable, "run_size": len(currentRun),
+			})
+			return err
+		}
+		if err := w.flushQueries(ctx, queries, currentRun); err != nil {
+			w.logger.Error(err, "flushing coalesced DML queries")
+			return err
+		}
+		currentRun = currentRun[:0]
+		return nil
+	}
+
+	for _, msg := range messages {
+		if msg.IsEmpty() {
+			continue
+		}
+
+		if msg.isDDL {
+			// flush any pending DML run before executing DDL
+			if err := flushRun(); err != nil {
+				return err
 			}
-			queries, err := w.buildCoalescedQueries(currentRun)
-			if err != nil {
-				w.logger.Error(err, "building coalesced queries", loglib.Fields{
-					"action": runAction, "schema": runSchema, "table": runTable, "run_size": len(currentRun),
-				})
+			if err := w.execDDL(ctx, msg); err != nil {
 				return err
 			}
-			if err := w.flushQueries(ctx, queries); err != nil {
-				w.logger.Error(err, "flushing coalesced DML queries")
+			continue
+		}
+
+		// check if this message continues the current run
+		if len(currentRun) > 0 && (msg.data.Schema != runSchema || msg.data.Table != runTable || msg.data.Action != runAction) {
+			if err := flushRun(); err != nil {
 				return err
 			}
-			currentRun = currentRun[:0]
-			return nil
 		}
 
-		for _, msg := range messages {
-			if msg.IsEmpty() {
-				continue
+		if len(currentRun) == 0 {
+			runSchema = msg.data.Schema
+			runTable = msg.data.Table
+			runAction = msg.data.Action
+		}
+		currentRun = append(currentRun, msg)
+	}
+
+	// flush any trailing run
+	return flushRun()
+}
+
+// prepareTable makes sure the target table on input is suitable for the apply
+// mode before it's first written to, since tables not created from the DDL
+// events (for example restored by the schema snapshot) lack the mode columns.
+func (w *BatchWriter) prepareTable(ctx context.Context, schema, table string) error {
+	if w.dmlAdapter.applyMode == applyModeMirror {
+		return nil
+	}
+
+	key := quotedTableName(schema, table)
+	if _, found := w.preparedTables.Get(key); found {
+		return nil
+	}
+	if _, err := w.pgConn.Exec(ctx, w.dmlAdapter.applyMode.prepareTableDDL(schema, table)); err != nil {
+		w.logger.Error(err, "preparing table for apply mode", loglib.Fields{"schema": schema, "table": table})
+		return fmt.Errorf("preparing table %s for apply mode: %w", key, err)
+	}
+	w.preparedTables.Set(key, struct{}{})
+	return nil
+}
+
+func (w *BatchWriter) execDDL(ctx context.Context, msg *walMessage) error {
+	w.syncRoles(ctx, msg.data)
+
+	ddlQueries, err := w.adapter.walEventToQueries(ctx, &wal.Event{Data: msg.data})
+	if err != nil {
+		w.logger.Error(err, "converting DDL event to queries")
+		return err
+	}
+
+	if w.applyProgress == nil {
+		for _, q := range ddlQueries {
+			queryErr, err := w.execDDLQuery(ctx, w.pgConn, q, false)
+			if queryErr != nil {
+				w.recordDDLError(ctx, q, queryErr)
+			}
+			if err != nil {
+				return err
 			}
+		}
+		return nil
+	}
 
-			if msg.isDDL {
-				// flush any pending DML run before executing DDL
-				if err := flushRun(); err != nil {
-					return err
-				}
-
-				ddlQueries, err := w.adapter.walEventToQueries(ctx, &wal.Event{Data: msg.data})
-				if err != nil {
-					w.logger.Error(err, "converting DDL event to queries")
-					return err
-				}
-				for _, q := range ddlQueries {
-					if q.IsEmpty() {
-						continue
-					}
-					if _, err := w.pgConn.Exec(ctx, q.sql, q.args...); err != nil {
-						w.logger.Error(err, "running DDL query", loglib.Fields{"query_sql": q.sql, "query_args": q.args})
-						if !w.isInternalError(err) {
-							continue
-						}
-						return err
-					}
-				}
+	// with exactly once apply, the progress is recorded in the same
+	// transaction as the DDL. Each query runs in its own savepoint, so that
+	// the failed ones can be skipped without aborting the transaction. The
+	// failed queries are recorded once the transaction is done, since the
+	// connection pool can be limited to a single connection when a
+	// replication origin is used.
+	var failures []ddlFailure
+	err = w.pgConn.ExecInTx(ctx, func(tx pglib.Tx) error {
+		failures = failures[:0]
+		for _, q := range ddlQueries {
+			if q.IsEmpty() {
 				continue
 			}
-
-			// check if this message continues the current run
-			if len(currentRun) > 0 && (msg.data.Schema != runSchema || msg.data.Table != runTable || msg.data.Action != runAction) {
-				if err := flushRun(); err != nil {
-					return err
-				}
+			if _, err := tx.Exec(ctx, "SAVEPOINT pgstream_ddl"); err != nil {
+				return err
 			}
-
-			if len(currentRun) == 0 {
-				runSchema = msg.data.Schema
-				runTable = msg.data.Table
-				runAction = msg.data.Action
+			queryErr, err := w.execDDLQuery(ctx, tx, q, true)
+			if queryErr != nil {
+				failures = append(failures, ddlFailure{query: q, err: queryErr})
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, "RELEASE SAVEPOINT pgstream_ddl"); err != nil {
+				return err
 			}
-			currentRun = append(currentRun, msg)
 		}
+		return w.applyProgress.record(ctx, tx, []*walMessage{msg})
+	})
+	for _, f := range failures {
+		w.recordDDLError(ctx, f.query, f.err)
+	}
+	return err
+}
 
-		// flush any trailing run
-		if err := flushRun(); err != nil {
-			return err
-		}
+// ddlFailure is a DDL query that failed to apply on the target.
+type ddlFailure struct {
+	query *query
+	err   error
+}
+
+// execDDLQuery runs the DDL query on input, skipping it if it fails with a
+// non internal error. When run within a savepoint, the failed query is rolled
+// back to it, so that the transaction can go on. It returns the error of the
+// query if it failed, and the error that must stop the processing if any.
+func (w *BatchWriter) execDDLQuery(ctx context.Context, conn ddlQuerier, q *query, inSavepoint bool) (queryErr error, err error) {
+	if q.IsEmpty() {
+		return nil, nil
+	}
+	_, queryErr = conn.Exec(ctx, q.sql, q.args...)
+	if queryErr == nil {
+		return nil, nil
 	}
 
-	if w.checkpointer != nil && len(b.GetCommitPositions()) > 0 {
-		return w.checkpointer(ctx, b.GetCommitPositions())
+	w.logger.Error(queryErr, "running DDL query", loglib.Fields{"query_sql": q.sql, "query_args": q.args})
+	if w.isInternalError(queryErr) {
+		return queryErr, queryErr
+	}
+	if inSavepoint {
+		if _, err := conn.Exec(ctx, "ROLLBACK TO SAVEPOINT pgstream_ddl"); err != nil {
+			return queryErr, err
+		}
 	}
+	return queryErr, nil
+}
 
-	return nil
+type ddlQuerier interface {
+	Exec(ctx context.Context, query string, args ...any) (pglib.CommandTag, error)
 }
 
 func (w *BatchWriter) buildCoalescedQueries(run []*walMessage) ([]*query, error) {
@@ -186,6 +350,11 @@ func (w *BatchWriter) buildCoalescedQueries(run []*walMessage) ([]*query, error)
 	}
 
 	action := run[0].data.Action
+	// coalesced queries are only supported when mirroring the source tables,
+	// and when changes don't need to be resolved row by row
+	if w.dmlAdapter.applyMode != applyModeMirror || w.dmlAdapter.conflictResolution != conflictResolutionNone {
+		action = ""
+	}
 	switch action {
 	case "D":
 		events := make([]*wal.Data, len(run))
@@ -213,14 +382,15 @@ func (w *BatchWriter) buildCoalescedQueries(run []*walMessage) ([]*query, error)
 	}
 }
 
-func (w *BatchWriter) flushQueries(ctx context.Context, queries []*query) error {
+// flushQueries applies the queries built from the messages on input.
+func (w *BatchWriter) flushQueries(ctx context.Context, queries []*query, msgs []*walMessage) error {
 	if len(queries) == 0 {
 		return nil
 	}
 
 	var err error
 	for {
-		queries, err = w.execQueries(ctx, queries)
+		queries, err = w.execQueries(ctx, queries, msgs)
 		if err != nil {
 			return err
 		}
@@ -231,13 +401,17 @@ func (w *BatchWriter) flushQueries(ctx context.Context, queries []*query) error
 	}
 }
 
-func (w *BatchWriter) execQueries(ctx context.Context, queries []*query) ([]*query, error) {
+func (w *BatchWriter) execQueries(ctx context.Context, queries []*query, msgs []*walMessage) ([]*query, error) {
 	retryQueries := []*query{}
 	err := w.pgConn.ExecInTx(ctx, func(tx pglib.Tx) error {
 		if err := w.setReplicationRoleToReplica(ctx, tx); err != nil {
 			return err
 		}
 
+		if err := w.setReplicationOriginPosition(ctx, tx, newOriginPosition(msgs)); err != nil {
+			return err
+		}
+
 		for i, q := range queries {
 			if _, err := tx.Exec(ctx, q.sql, q.args...); err != nil {
 				w.logger.Error(err, "executing sql query", loglib.Fields{
@@ -253,6 +427,14 @@ func (w *BatchWriter) execQueries(ctx context.Context, queries []*query) ([]*que
 			}
 		}
 
+		// record the position of the changes in the same transaction, so
+		// that they're applied exactly once
+		if w.applyProgress != nil {
+			if err := w.applyProgress.record(ctx, tx, msgs); err != nil {
+				return err
+			}
+		}
+
 		return w.resetReplicationRole(ctx, tx)
 	})
 	if err != nil && w.isInternalError(err) {
@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	synclib "github.com/xataio/pgstream/internal/sync"
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/wal"
 	"github.com/xataio/pgstream/pkg/wal/checkpointer"
@@ -545,7 +546,7 @@ func TestBatchWriter_flushQueries(t *testing.T) {
 				},
 			}
 
-			err := bw.flushQueries(context.Background(), tc.queries)
+			err := bw.flushQueries(context.Background(), tc.queries, nil)
 			require.ErrorIs(t, err, tc.wantErr)
 			require.Equal(t, tc.wantExecCalls, execCalls)
 			execCalls = 0
@@ -555,7 +556,79 @@ func TestBatchWriter_flushQueries(t *testing.T) {
 
 func mustNewDMLAdapter(t *testing.T) *dmlAdapter {
 	t.Helper()
-	a, err := newDMLAdapter("", false, loglib.NewNoopLogger())
+	a, err := newDMLAdapter("", "", "", false, loglib.NewNoopLogger())
 	require.NoError(t, err)
 	return a
 }
+
+func TestBatchWriter_prepareTable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		mode    string
+		execErr error
+
+		wantPrepared []string
+		wantErr      error
+	}{
+		{
+			name: "ok - tables prepared once",
+			mode: "history",
+
+			wantPrepared: []string{
+				applyModeHistory.prepareTableDDL(testSchema, "a"),
+				applyModeHistory.prepareTableDDL(testSchema, "b"),
+			},
+		},
+		{
+			name: "ok - mirror mode",
+			mode: "mirror",
+
+			wantPrepared: []string{},
+		},
+		{
+			name:    "error - preparing table",
+			mode:    "soft_delete",
+			execErr: errTest,
+
+			wantPrepared: []string{
+				applyModeSoftDelete.prepareTableDDL(testSchema, "a"),
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dml, err := newDMLAdapter("", tc.mode, "", false, loglib.NewNoopLogger())
+			require.NoError(t, err)
+
+			prepared := []string{}
+			writer := &BatchWriter{
+				Writer: &Writer{
+					logger: loglib.NewNoopLogger(),
+					pgConn: &pgmocks.Querier{
+						ExecFn: func(ctx context.Context, _ uint, sql string, args ...any) (pglib.CommandTag, error) {
+							prepared = append(prepared, sql)
+							return pglib.CommandTag{}, tc.execErr
+						},
+					},
+				},
+				dmlAdapter:     dml,
+				preparedTables: synclib.NewMap[string, struct{}](),
+			}
+
+			for _, table := range []string{"a", "b", "a"} {
+				err = writer.prepareTable(context.Background(), testSchema, table)
+				if err != nil {
+					break
+				}
+			}
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantPrepared, prepared)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"runtime/debug"
+	"sync"
 
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	synclib "github.com/xataio/pgstream/internal/sync"
@@ -24,6 +25,11 @@ type BulkIngestWriter struct {
 
 	batchSenderMap     *synclib.Map[string, queryBatchSender]
 	batchSenderBuilder func(ctx context.Context, schema, table string) (queryBatchSender, error)
+
+	// pendingPositions keeps the number of batch senders yet to flush each
+	// of the positions sent to all of them
+	pendingMutex     sync.Mutex
+	pendingPositions map[wal.CommitPosition]int
 }
 
 const bulkIngestWriter = "postgres_bulk_ingest_writer"
@@ -44,8 +50,9 @@ func NewBulkIngestWriter(ctx context.Context, config *Config, opts ...WriterOpti
 	}
 
 	biw := &BulkIngestWriter{
-		Writer:         w,
-		batchSenderMap: synclib.NewMap[string, queryBatchSender](),
+		Writer:           w,
+		batchSenderMap:   synclib.NewMap[string, queryBatchSender](),
+		pendingPositions: map[wal.CommitPosition]int{},
 	}
 
 	biw.batchSenderBuilder = func(ctx context.Context, schema, table string) (queryBatchSender, error) {
@@ -71,7 +78,11 @@ func (w *BulkIngestWriter) ProcessWALEvent(ctx context.Context, walEvent *wal.Ev
 		}
 	}()
 
-	if walEvent.Data != nil && !walEvent.Data.IsInsert() {
+	if walEvent.Data == nil {
+		return w.sendPosition(ctx, walEvent.CommitPosition)
+	}
+
+	if !walEvent.Data.IsInsert() {
 		w.logger.Warn(nil, "skipping non-insert event", loglib.Fields{"severity": "DATALOSS"})
 		return nil
 	}
@@ -147,60 +158,65 @@ func (w *BulkIngestWriter) getBatchSender(ctx context.Context, schema, table str
 
 func (w *BulkIngestWriter) sendBatch(ctx context.Context, batch *batch.Batch[*query]) error {
 	queries := batch.GetMessages()
-	if len(queries) == 0 {
-		return nil
+	if len(queries) > 0 {
+		w.logger.Trace("bulk writing batch", loglib.Fields{"batch_size": len(queries)})
+		if err := w.copyFromInsertQueries(ctx, queries); err != nil {
+			return err
+		}
 	}
 
-	w.logger.Trace("bulk writing batch", loglib.Fields{"batch_size": len(queries)})
-	return w.copyFromInsertQueries(ctx, queries)
+	return w.checkpointFlushed(ctx, batch.GetCommitPositions())
 }
 
-func (w *BulkIngestWriter) copyFromInsertQueries(ctx context.Context, inserts []*query) error {
-	if len(inserts) == 0 {
+// sendPosition sends the position of an event without data to all the batch
+// senders, since the events before it might be in any of them. The position
+// is checkpointed once all of them have flushed it.
+func (w *BulkIngestWriter) sendPosition(ctx context.Context, pos wal.CommitPosition) error {
+	if w.checkpointer == nil || pos == "" {
 		return nil
 	}
 
-	// Get the table and column names from the first insert query, since all the
-	// inserts in the batch will be for the same schema.table
-	query := inserts[0]
-	rows := [][]any{}
-	for _, q := range inserts {
-		rows = append(rows, q.args)
-	}
-
-	// Tables with extension types pgx has no binary codec for
-	// must round-trip through text-format COPY, otherwise
-	// the destination misreads the raw text bytes as the binary
-	// representation of the type.
-	// The dml adapter precomputes the needsTextCopy flag
-	// from the column type list when building the query.
-	copyFn := func(tx pglib.Tx) (int64, error) {
-		target := pglib.QuoteQualifiedIdentifier(query.schema, query.table)
-		if query.needsTextCopy {
-			return tx.CopyFromText(ctx, target, query.columnNames, rows)
-		}
-		return tx.CopyFrom(ctx, target, query.columnNames, rows)
+	senders := w.batchSenderMap.GetMap()
+	if len(senders) == 0 {
+		return w.checkpointer(ctx, []wal.CommitPosition{pos})
 	}
 
-	err := w.pgConn.ExecInTx(ctx, func(tx pglib.Tx) error {
-		if err := w.setReplicationRoleToReplica(ctx, tx); err != nil {
-			return err
-		}
+	w.pendingMutex.Lock()
+	w.pendingPositions[pos] = len(senders)
+	w.pendingMutex.Unlock()
 
-		rowsCopied, err := copyFn(tx)
-		if err != nil {
+	for _, sender := range senders {
+		if err := sender.SendMessage(ctx, batch.NewWALMessage(&query{}, pos)); err != nil {
 			return err
 		}
+	}
+	return nil
+}
+
+// checkpointFlushed checkpoints the positions on input that have been flushed
+// by all the batch senders they were sent to.
+func (w *BulkIngestWriter) checkpointFlushed(ctx context.Context, positions []wal.CommitPosition) error {
+	if w.checkpointer == nil || len(positions) == 0 {
+		return nil
+	}
 
-		rowsToCopy := len(inserts)
-		if rowsCopied != int64(rowsToCopy) {
-			return fmt.Errorf("%w: copied (%d), expected(%d)", errUnexpectedCopiedRows, rowsCopied, rowsToCopy)
+	flushed := []wal.CommitPosition{}
+	w.pendingMutex.Lock()
+	for _, pos := range positions {
+		pending, found := w.pendingPositions[pos]
+		switch {
+		case !found:
+		case pending > 1:
+			w.pendingPositions[pos] = pending - 1
+		default:
+			delete(w.pendingPositions, pos)
+			flushed = append(flushed, pos)
 		}
+	}
+	w.pendingMutex.Unlock()
 
-		return w.resetReplicationRole(ctx, tx)
-	})
-	if err != nil {
-		return fmt.Errorf("copy from: %w", err)
+	if len(flushed) == 0 {
+		return nil
 	}
-	return nil
+	return w.checkpointer(ctx, flushed)
 }
@@ -243,6 +243,7 @@ func TestBulkIngestWriter_sendBatch(t *testing.T) {
 		batch           *batch.Batch[*query]
 		pgConn          *pgmocks.Querier
 		disableTriggers bool
+		textCopy        bool
 
 		wantErr error
 	}{
@@ -284,6 +285,29 @@ func TestBulkIngestWriter_sendBatch(t *testing.T) {
 
 			wantErr: nil,
 		},
+		{
+			name:  "ok - text copy",
+			batch: batch.NewBatch([]*query{testQuery}, nil),
+			pgConn: &pgmocks.Querier{
+				ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+					tx := &pgmocks.Tx{
+						CopyFromFn: func(ctx context.Context, tableName string, columnNames []string, rows [][]any) (int64, error) {
+							return -1, errors.New("unexpected call to CopyFrom with text copy")
+						},
+						CopyFromTextFn: func(ctx context.Context, tableName string, columnNames []string, rows [][]any) (int64, error) {
+							require.Equal(t, pglib.QuoteQualifiedIdentifier(testSchema, testTable), tableName)
+							require.Equal(t, testQuery.columnNames, columnNames)
+							require.Equal(t, testQuery.args, rows[0])
+							return 1, nil
+						},
+					}
+					return f(tx)
+				},
+			},
+			textCopy: true,
+
+			wantErr: nil,
+		},
 		{
 			name:  "ok - disable triggers",
 			batch: batch.NewBatch([]*query{testQuery}, nil),
@@ -413,6 +437,7 @@ func TestBulkIngestWriter_sendBatch(t *testing.T) {
 					logger:          loglib.NewNoopLogger(),
 					pgConn:          tc.pgConn,
 					disableTriggers: tc.disableTriggers,
+					textCopy:        tc.textCopy,
 				},
 			}
 
@@ -423,3 +448,83 @@ func TestBulkIngestWriter_sendBatch(t *testing.T) {
 		})
 	}
 }
+
+func TestBulkIngestWriter_checkpointPositions(t *testing.T) {
+	t.Parallel()
+
+	testPosition := wal.CommitPosition("test-position")
+
+	tests := []struct {
+		name    string
+		senders []string
+
+		wantSent        map[string][]wal.CommitPosition
+		wantCheckpoints [][]wal.CommitPosition
+	}{
+		{
+			name:    "ok - position checkpointed once flushed by all senders",
+			senders: []string{"a", "b"},
+
+			wantSent: map[string][]wal.CommitPosition{
+				"a": {testPosition},
+				"b": {testPosition},
+			},
+			wantCheckpoints: [][]wal.CommitPosition{nil, {testPosition}},
+		},
+		{
+			name:    "ok - no senders",
+			senders: []string{},
+
+			wantSent:        map[string][]wal.CommitPosition{},
+			wantCheckpoints: [][]wal.CommitPosition{{testPosition}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sent := map[string][]wal.CommitPosition{}
+			senders := map[string]queryBatchSender{}
+			for _, name := range tc.senders {
+				s := batchmocks.NewBatchSender[*query]()
+				s.SendMessageFn = func(ctx context.Context, msg *batch.WALMessage[*query]) error {
+					require.True(t, msg.GetMessage().IsEmpty())
+					sent[name] = append(sent[name], msg.GetPosition())
+					return nil
+				}
+				senders[name] = s
+			}
+
+			var checkpointed []wal.CommitPosition
+			writer := &BulkIngestWriter{
+				Writer: &Writer{
+					logger: loglib.NewNoopLogger(),
+					checkpointer: func(ctx context.Context, positions []wal.CommitPosition) error {
+						checkpointed = positions
+						return nil
+					},
+				},
+				batchSenderMap:   synclib.NewMapFromMap(senders),
+				pendingPositions: map[wal.CommitPosition]int{},
+			}
+
+			err := writer.ProcessWALEvent(context.Background(), &wal.Event{CommitPosition: testPosition})
+			require.NoError(t, err)
+			require.Equal(t, tc.wantSent, sent)
+
+			checkpoints := [][]wal.CommitPosition{}
+			if len(tc.senders) == 0 {
+				checkpoints = append(checkpoints, checkpointed)
+			}
+			for range tc.senders {
+				checkpointed = nil
+				err := writer.sendBatch(context.Background(), batch.NewBatch([]*query{}, []wal.CommitPosition{testPosition}))
+				require.NoError(t, err)
+				checkpoints = append(checkpoints, checkpointed)
+			}
+			require.Equal(t, tc.wantCheckpoints, checkpoints)
+			require.Empty(t, writer.pendingPositions)
+		})
+	}
+}
@@ -0,0 +1,387 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"slices"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+)
+
+// BulkMergeWriter is a WAL processor implementation that bulk writes batches
+// of insert, update and delete events to a Postgres instance. The changes of
+// each batch are reduced to the last change per primary key and copied into a
+// temporary staging table per target table, and then applied to the target
+// table with set based queries (MERGE, or upserts and deletes for Postgres
+// versions older than 15).
+type BulkMergeWriter struct {
+	*Writer
+
+	batchSender walMessageBatchSender
+	dmlAdapter  *dmlAdapter
+	useMerge    bool
+	// foreignKeyTablesFn returns the tables linked to the table on input by a
+	// foreign key. It's only set when the target triggers are enabled, since
+	// the foreign keys are not checked otherwise.
+	foreignKeyTablesFn func(ctx context.Context, schema, table string) (map[string]struct{}, error)
+}
+
+const (
+	bulkMergeWriter = "postgres_bulk_merge_writer"
+
+	// first Postgres version with support for MERGE
+	mergeMinServerVersion = 150000
+)
+
+var (
+	errUnsupportedBulkMergeApplyMode = errors.New("bulk merge only supports the mirror apply mode")
+	errUnsupportedBulkMergeConflict  = errors.New("bulk merge doesn't support conditional conflict resolution")
+)
+
+// NewBulkMergeWriter returns a postgres processor that batches and bulk writes
+// insert, update and delete events to the configured postgres instance using
+// staging tables.
+func NewBulkMergeWriter(ctx context.Context, config *Config, opts ...WriterOption) (*BulkMergeWriter, error) {
+	w, err := newWriter(ctx, config, bulkMergeWriter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	dml, err := newDMLAdapter(config.OnConflictAction, config.ApplyMode, config.ConflictResolution, true, w.logger)
+	if err != nil {
+		return nil, err
+	}
+	if dml.applyMode != applyModeMirror {
+		return nil, errUnsupportedBulkMergeApplyMode
+	}
+	if dml.conflictResolution.isConditional() {
+		return nil, errUnsupportedBulkMergeConflict
+	}
+	if dml.conflictPolicies, err = newConflictPolicies(config.TableConflictPolicies, dml.onConflictAction); err != nil {
+		return nil, err
+	}
+
+	var serverVersion int
+	if err := w.pgConn.QueryRow(ctx, []any{&serverVersion}, "SELECT current_setting('server_version_num')::int"); err != nil {
+		return nil, fmt.Errorf("retrieving target server version: %w", err)
+	}
+
+	bmw := &BulkMergeWriter{
+		Writer:     w,
+		dmlAdapter: dml,
+		useMerge:   serverVersion >= mergeMinServerVersion,
+	}
+	if !w.disableTriggers {
+		bmw.foreignKeyTablesFn = w.schemaObserver.getForeignKeyTables
+	}
+
+	bmw.batchSender, err = batch.NewSender(ctx, &config.BatchConfig, bmw.sendBatch, w.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return bmw, nil
+}
+
+// ProcessWALEvent is called on every new message from the wal. It can be called
+// concurrently.
+func (w *BulkMergeWriter) ProcessWALEvent(ctx context.Context, walEvent *wal.Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Panic("[PANIC] Panic while processing replication event", loglib.Fields{
+				"wal_data":    walEvent,
+				"panic":       r,
+				"stack_trace": debug.Stack(),
+			})
+
+			err = fmt.Errorf("postgres writer: understanding event: %w:  %v", processor.ErrPanic, r)
+		}
+	}()
+
+	walMsg, err := w.adapter.walEventToMessage(ctx, walEvent)
+	if err != nil {
+		return err
+	}
+
+	msg := batch.NewWALMessage(walMsg, walEvent.CommitPosition)
+	return w.batchSender.SendMessage(ctx, msg)
+}
+
+func (w *BulkMergeWriter) Name() string {
+	return bulkMergeWriter
+}
+
+func (w *BulkMergeWriter) Close() error {
+	w.logger.Debug("closing bulk merge writer")
+	w.batchSender.Close()
+
+	return w.close()
+}
+
+func (w *BulkMergeWriter) sendBatch(ctx context.Context, b *batch.Batch[*walMessage]) error {
+	messages := b.GetMessages()
+	if len(messages) > 0 {
+		w.logger.Debug("sending batch", loglib.Fields{"batch_size": len(messages)})
+
+		// DML events are grouped by table, in order of first appearance. DDL
+		// and truncate events act as barriers, flushing the pending groups
+		// before being applied, as do changes that would be applied ahead of
+		// the changes to a table linked by a foreign key.
+		pending := newTableGroups()
+
+		for _, msg := range messages {
+			if msg.IsEmpty() {
+				continue
+			}
+
+			switch {
+			case msg.isDDL:
+				if err := w.flushGroups(ctx, pending); err != nil {
+					return err
+				}
+				if err := w.execDDL(ctx, msg); err != nil {
+					return err
+				}
+			case msg.data.Action == "T":
+				if err := w.flushGroups(ctx, pending); err != nil {
+					return err
+				}
+				queries, err := w.dmlAdapter.walDataToQueries(msg.data, msg.schemaInfo)
+				if err != nil {
+					return err
+				}
+				if err := w.execInTx(ctx, func(tx pglib.Tx) error {
+					return execQueries(ctx, tx, queries)
+				}); err != nil {
+					return err
+				}
+			default:
+				reordered, err := w.reordersForeignKeys(ctx, pending, msg)
+				if err != nil {
+					return err
+				}
+				if reordered {
+					if err := w.flushGroups(ctx, pending); err != nil {
+						return err
+					}
+				}
+				pending.add(msg)
+			}
+		}
+
+		if err := w.flushGroups(ctx, pending); err != nil {
+			return err
+		}
+	}
+
+	if w.checkpointer != nil && len(b.GetCommitPositions()) > 0 {
+		return w.checkpointer(ctx, b.GetCommitPositions())
+	}
+
+	return nil
+}
+
+// reordersForeignKeys returns true if adding the message on input to the
+// pending groups would apply it ahead of the changes to a table linked to its
+// own by a foreign key, which can break the foreign key (for example when
+// inserting a child row before its parent). That's the case when its table
+// group is followed by the group of a linked table.
+func (w *BulkMergeWriter) reordersForeignKeys(ctx context.Context, groups *tableGroups, msg *walMessage) (bool, error) {
+	if w.foreignKeyTablesFn == nil {
+		return false, nil
+	}
+
+	// new groups are applied after the existing ones
+	i := slices.Index(groups.keys, quotedTableName(msg.data.Schema, msg.data.Table))
+	if i < 0 || i == len(groups.keys)-1 {
+		return false, nil
+	}
+
+	linkedTables, err := w.foreignKeyTablesFn(ctx, msg.data.Schema, msg.data.Table)
+	if err != nil {
+		return false, fmt.Errorf("getting foreign key tables: %w", err)
+	}
+	for _, key := range groups.keys[i+1:] {
+		if _, found := linkedTables[key]; found {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// flushGroups applies the pending table groups in a single transaction, and
+// resets them.
+func (w *BulkMergeWriter) flushGroups(ctx context.Context, groups *tableGroups) error {
+	if groups.isEmpty() {
+		return nil
+	}
+	defer groups.reset()
+
+	// the staging tables are dropped when the transaction ends
+	stagingTables := map[string]string{}
+	return w.execInTx(ctx, func(tx pglib.Tx) error {
+		for _, key := range groups.keys {
+			msgs := groups.msgs[key]
+			events := make([]*wal.Data, 0, len(msgs))
+			for _, msg := range msgs {
+				events = append(events, msg.data)
+			}
+
+			// the changes that can't be merged are applied individually, in
+			// between the merges of the changes around them, to keep the
+			// order of the changes to the table
+			for len(events) > 0 {
+				mb, remaining := w.dmlAdapter.buildMergeBatch(events, msgs[0].schemaInfo)
+				if mb != nil {
+					if err := w.mergeBatch(ctx, tx, stagingTables, mb); err != nil {
+						return err
+					}
+				}
+				if len(remaining) == 0 {
+					break
+				}
+
+				e := remaining[0]
+				w.logger.Debug("applying change individually", loglib.Fields{"schema": e.Schema, "table": e.Table, "action": e.Action})
+				queries, err := w.dmlAdapter.walDataToQueries(e, msgs[0].schemaInfo)
+				if err != nil {
+					return err
+				}
+				if err := execQueries(ctx, tx, queries); err != nil {
+					return err
+				}
+				events = remaining[1:]
+			}
+		}
+		return nil
+	})
+}
+
+func (w *BulkMergeWriter) mergeBatch(ctx context.Context, tx pglib.Tx, stagingTables map[string]string, mb *mergeBatch) error {
+	stagingTable, err := w.prepareStagingTable(ctx, tx, stagingTables, mb.schema, mb.table)
+	if err != nil {
+		return err
+	}
+
+	// the copy functions update the column names in place
+	columnNames := append(slices.Clone(mb.columns), pglib.QuoteIdentifier(stagingOpColumn))
+	var rowsCopied int64
+	if needsTextCopy(mb.types) {
+		rowsCopied, err = tx.CopyFromText(ctx, stagingTable, columnNames, mb.rows)
+	} else {
+		rowsCopied, err = tx.CopyFrom(ctx, stagingTable, columnNames, mb.rows)
+	}
+	if err != nil {
+		return fmt.Errorf("copying into staging table %s: %w", stagingTable, err)
+	}
+	if rowsCopied != int64(len(mb.rows)) {
+		return fmt.Errorf("%w: copied (%d), expected(%d)", errUnexpectedCopiedRows, rowsCopied, len(mb.rows))
+	}
+
+	w.logger.Trace("merging staging table", loglib.Fields{"staging_table": stagingTable, "rows": len(mb.rows)})
+	return execQueries(ctx, tx, mb.buildMergeQueries(stagingTable, w.useMerge))
+}
+
+// prepareStagingTable returns the empty staging table for the target table on
+// input, creating it if it doesn't exist in the transaction yet. Staging tables
+// are temporary tables dropped on commit, so they're private to the pipeline
+// session and always match the target table columns.
+func (w *BulkMergeWriter) prepareStagingTable(ctx context.Context, tx pglib.Tx, stagingTables map[string]string, schema, table string) (string, error) {
+	target := quotedTableName(schema, table)
+	if stagingTable, found := stagingTables[target]; found {
+		if _, err := tx.Exec(ctx, fmt.Sprintf("TRUNCATE %s", stagingTable)); err != nil {
+			return "", fmt.Errorf("truncating staging table %s: %w", stagingTable, err)
+		}
+		return stagingTable, nil
+	}
+
+	stagingTable := quotedTableName(stagingSchema, stagingTableName(schema, table))
+	if _, err := tx.Exec(ctx, fmt.Sprintf("CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT t.*, NULL::text AS %s FROM %s AS t WITH NO DATA",
+		stagingTable, pglib.QuoteIdentifier(stagingOpColumn), target)); err != nil {
+		return "", fmt.Errorf("creating staging table %s: %w", stagingTable, err)
+	}
+
+	stagingTables[target] = stagingTable
+	return stagingTable, nil
+}
+
+func (w *BulkMergeWriter) execDDL(ctx context.Context, msg *walMessage) error {
+	w.syncRoles(ctx, msg.data)
+
+	ddlQueries, err := w.adapter.walEventToQueries(ctx, &wal.Event{Data: msg.data})
+	if err != nil {
+		w.logger.Error(err, "converting DDL event to queries")
+		return err
+	}
+	for _, q := range ddlQueries {
+		if q.IsEmpty() {
+			continue
+		}
+		if _, err := w.pgConn.Exec(ctx, q.sql, q.args...); err != nil {
+			w.logger.Error(err, "running DDL query", loglib.Fields{"query_sql": q.sql, "query_args": q.args})
+			w.recordDDLError(ctx, q, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *BulkMergeWriter) execInTx(ctx context.Context, fn func(tx pglib.Tx) error) error {
+	return w.pgConn.ExecInTx(ctx, func(tx pglib.Tx) error {
+		if err := w.setReplicationRoleToReplica(ctx, tx); err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return w.resetReplicationRole(ctx, tx)
+	})
+}
+
+func execQueries(ctx context.Context, tx pglib.Tx, queries []*query) error {
+	for _, q := range queries {
+		if q.IsEmpty() {
+			continue
+		}
+		if _, err := tx.Exec(ctx, q.sql, q.args...); err != nil {
+			return fmt.Errorf("executing query %q: %w", q.sql, err)
+		}
+	}
+	return nil
+}
+
+// tableGroups keeps the DML messages grouped by table, in order of first
+// appearance.
+type tableGroups struct {
+	keys []string
+	msgs map[string][]*walMessage
+}
+
+func newTableGroups() *tableGroups {
+	return &tableGroups{msgs: map[string][]*walMessage{}}
+}
+
+func (g *tableGroups) add(msg *walMessage) {
+	key := quotedTableName(msg.data.Schema, msg.data.Table)
+	if _, found := g.msgs[key]; !found {
+		g.keys = append(g.keys, key)
+	}
+	g.msgs[key] = append(g.msgs[key], msg)
+}
+
+func (g *tableGroups) isEmpty() bool {
+	return len(g.keys) == 0
+}
+
+func (g *tableGroups) reset() {
+	g.keys = nil
+	g.msgs = map[string][]*walMessage{}
+}
@@ -0,0 +1,256 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+)
+
+func TestBulkMergeWriter_sendBatch(t *testing.T) {
+	t.Parallel()
+
+	target := quotedTableName(testSchema, testTable)
+	staging := `"pg_temp"."test_schema_test_table_staging"`
+	parentTable := "parents"
+	parentTarget := quotedTableName(testSchema, parentTable)
+	parentStaging := `"pg_temp"."test_schema_parents_staging"`
+	testPositions := []wal.CommitPosition{testCommitPosition}
+
+	newTableMsg := func(table, action string, identity, cols []wal.Column) *walMessage {
+		return &walMessage{
+			data: &wal.Data{
+				Action:   action,
+				Schema:   testSchema,
+				Table:    table,
+				Identity: identity,
+				Columns:  cols,
+				Metadata: wal.Metadata{InternalColIDs: []string{"1"}},
+			},
+		}
+	}
+	newMsg := func(action string, identity, cols []wal.Column) *walMessage {
+		return newTableMsg(testTable, action, identity, cols)
+	}
+	idCol := func(id int) wal.Column { return wal.Column{ID: "1", Name: "id", Type: "integer", Value: id} }
+	nameCol := wal.Column{ID: "2", Name: "name", Type: "text", Value: "alice"}
+
+	insertMsg := newMsg("I", nil, []wal.Column{idCol(1), nameCol})
+	deleteMsg := newMsg("D", []wal.Column{idCol(2)}, nil)
+	truncateMsg := newMsg("T", nil, nil)
+	// inserts without the primary key column can't be merged
+	unkeyedMsg := newMsg("I", nil, []wal.Column{nameCol})
+	updateMsg := newMsg("U", []wal.Column{idCol(3)}, []wal.Column{idCol(3), nameCol})
+	parentInsertMsg := newTableMsg(parentTable, "I", nil, []wal.Column{idCol(4), nameCol})
+
+	createStagingQuery := func(staging, target string) string {
+		return "CREATE TEMP TABLE " + staging + ` ON COMMIT DROP AS SELECT t.*, NULL::text AS "_pgstream_op" FROM ` + target + " AS t WITH NO DATA"
+	}
+	mergeQuery := func(staging, target string) string {
+		return `MERGE INTO ` + target + ` AS t USING ` + staging + ` AS s ON t."id" = s."id"` +
+			` WHEN MATCHED AND s."_pgstream_op" = 'D' THEN DELETE` +
+			` WHEN MATCHED THEN UPDATE SET "name" = s."name"` +
+			` WHEN NOT MATCHED AND s."_pgstream_op" <> 'D' THEN INSERT ("id", "name") OVERRIDING SYSTEM VALUE VALUES (s."id", s."name")`
+	}
+	wantRows := [][]any{{1, "alice", stagingUpsertOp}, {2, nil, stagingDeleteOp}}
+	policyMergeQuery := `MERGE INTO ` + target + ` AS t USING ` + staging + ` AS s ON t."id" = s."id"` +
+		` WHEN MATCHED AND s."_pgstream_op" = 'D' THEN DELETE` +
+		` WHEN MATCHED AND s."_pgstream_op" = 'U' THEN UPDATE SET "name" = s."name"` +
+		` WHEN NOT MATCHED AND s."_pgstream_op" = 'U' THEN INSERT ("id", "name") OVERRIDING SYSTEM VALUE VALUES (s."id", s."name")`
+	policyInsertQuery := `INSERT INTO ` + target + `("id", "name") OVERRIDING SYSTEM VALUE SELECT s."id", s."name" FROM ` + staging +
+		` AS s WHERE s."_pgstream_op" = 'I' ON CONFLICT DO NOTHING`
+	linkedTables := func(ctx context.Context, schema, table string) (map[string]struct{}, error) {
+		switch table {
+		case testTable:
+			return map[string]struct{}{parentTarget: {}}, nil
+		case parentTable:
+			return map[string]struct{}{target: {}}, nil
+		default:
+			return nil, nil
+		}
+	}
+
+	tests := []struct {
+		name               string
+		batch              *batch.Batch[*walMessage]
+		policies           conflictPolicies
+		foreignKeyTablesFn func(ctx context.Context, schema, table string) (map[string]struct{}, error)
+		copyErr            error
+
+		wantQueries    []string
+		wantRows       [][]any
+		wantTxs        int
+		wantCheckpoint bool
+		wantErr        error
+	}{
+		{
+			name:           "ok",
+			batch:          batch.NewBatch([]*walMessage{insertMsg, {}, deleteMsg}, testPositions),
+			wantQueries:    []string{createStagingQuery(staging, target), mergeQuery(staging, target)},
+			wantRows:       wantRows,
+			wantTxs:        1,
+			wantCheckpoint: true,
+		},
+		{
+			name:  "ok - truncate flushes pending changes",
+			batch: batch.NewBatch([]*walMessage{insertMsg, truncateMsg, insertMsg}, testPositions),
+			wantQueries: []string{
+				createStagingQuery(staging, target), mergeQuery(staging, target),
+				"TRUNCATE " + target,
+				createStagingQuery(staging, target), mergeQuery(staging, target),
+			},
+			wantRows:       [][]any{{1, "alice", stagingUpsertOp}, {1, "alice", stagingUpsertOp}},
+			wantTxs:        3,
+			wantCheckpoint: true,
+		},
+		{
+			name:  "ok - changes that can't be merged keep their order",
+			batch: batch.NewBatch([]*walMessage{insertMsg, unkeyedMsg, updateMsg}, testPositions),
+			wantQueries: []string{
+				createStagingQuery(staging, target), mergeQuery(staging, target),
+				`INSERT INTO ` + target + `("name") OVERRIDING SYSTEM VALUE VALUES($1)`,
+				"TRUNCATE " + staging, mergeQuery(staging, target),
+			},
+			wantRows:       [][]any{{1, "alice", stagingUpsertOp}, {3, "alice", stagingUpsertOp}},
+			wantTxs:        1,
+			wantCheckpoint: true,
+		},
+		{
+			name:  "ok - inserts follow the table conflict policy",
+			batch: batch.NewBatch([]*walMessage{insertMsg, updateMsg, deleteMsg}, testPositions),
+			policies: conflictPolicies{
+				testSchema: {testTable: {action: onConflictDoNothing}},
+			},
+			wantQueries:    []string{createStagingQuery(staging, target), policyMergeQuery, policyInsertQuery},
+			wantRows:       [][]any{{1, "alice", stagingInsertOp}, {3, "alice", stagingUpsertOp}, {2, nil, stagingDeleteOp}},
+			wantTxs:        1,
+			wantCheckpoint: true,
+		},
+		{
+			name:  "ok - changes grouped by table",
+			batch: batch.NewBatch([]*walMessage{insertMsg, parentInsertMsg, updateMsg}, testPositions),
+			foreignKeyTablesFn: func(ctx context.Context, schema, table string) (map[string]struct{}, error) {
+				return map[string]struct{}{}, nil
+			},
+			wantQueries: []string{
+				createStagingQuery(staging, target), mergeQuery(staging, target),
+				createStagingQuery(parentStaging, parentTarget), mergeQuery(parentStaging, parentTarget),
+			},
+			wantRows:       [][]any{{1, "alice", stagingUpsertOp}, {3, "alice", stagingUpsertOp}, {4, "alice", stagingUpsertOp}},
+			wantTxs:        1,
+			wantCheckpoint: true,
+		},
+		{
+			name:               "ok - changes to tables linked by a foreign key keep their order",
+			batch:              batch.NewBatch([]*walMessage{insertMsg, parentInsertMsg, updateMsg, parentInsertMsg}, testPositions),
+			foreignKeyTablesFn: linkedTables,
+			wantQueries: []string{
+				createStagingQuery(staging, target), mergeQuery(staging, target),
+				createStagingQuery(parentStaging, parentTarget), mergeQuery(parentStaging, parentTarget),
+				createStagingQuery(staging, target), mergeQuery(staging, target),
+				createStagingQuery(parentStaging, parentTarget), mergeQuery(parentStaging, parentTarget),
+			},
+			wantRows: [][]any{
+				{1, "alice", stagingUpsertOp}, {4, "alice", stagingUpsertOp},
+				{3, "alice", stagingUpsertOp}, {4, "alice", stagingUpsertOp},
+			},
+			wantTxs:        2,
+			wantCheckpoint: true,
+		},
+		{
+			name:  "ok - only keep alives",
+			batch: batch.NewBatch([]*walMessage{{}}, testPositions),
+
+			wantCheckpoint: true,
+		},
+		{
+			name:  "error - getting foreign key tables",
+			batch: batch.NewBatch([]*walMessage{insertMsg, parentInsertMsg, updateMsg}, testPositions),
+			foreignKeyTablesFn: func(ctx context.Context, schema, table string) (map[string]struct{}, error) {
+				return nil, errTest
+			},
+			wantErr: errTest,
+		},
+		{
+			name:        "error - copying into staging table",
+			batch:       batch.NewBatch([]*walMessage{insertMsg, deleteMsg}, testPositions),
+			copyErr:     errTest,
+			wantQueries: []string{createStagingQuery(staging, target)},
+			wantRows:    wantRows,
+			wantTxs:     1,
+			wantErr:     errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			queries := []string{}
+			rows := [][]any{}
+			tx := &pgmocks.Tx{
+				ExecFn: func(ctx context.Context, i uint, sql string, args ...any) (pglib.CommandTag, error) {
+					queries = append(queries, sql)
+					return pglib.CommandTag{}, nil
+				},
+				CopyFromFn: func(ctx context.Context, tableName string, columnNames []string, srcRows [][]any) (int64, error) {
+					require.Contains(t, []string{staging, parentStaging}, tableName)
+					require.Equal(t, []string{`"id"`, `"name"`, `"_pgstream_op"`}, columnNames)
+					rows = append(rows, srcRows...)
+					if tc.copyErr != nil {
+						return 0, tc.copyErr
+					}
+					return int64(len(srcRows)), nil
+				},
+			}
+
+			txs := 0
+			checkpointed := false
+			w := &BulkMergeWriter{
+				Writer: &Writer{
+					logger: loglib.NewNoopLogger(),
+					pgConn: &pgmocks.Querier{
+						ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+							txs++
+							return f(tx)
+						},
+					},
+					checkpointer: func(ctx context.Context, positions []wal.CommitPosition) error {
+						require.Equal(t, testPositions, positions)
+						checkpointed = true
+						return nil
+					},
+				},
+				dmlAdapter: &dmlAdapter{
+					logger:           loglib.NewNoopLogger(),
+					forCopy:          true,
+					pgTypeMap:        pgtype.NewMap(),
+					conflictPolicies: tc.policies,
+				},
+				useMerge:           true,
+				foreignKeyTablesFn: tc.foreignKeyTablesFn,
+			}
+
+			err := w.sendBatch(context.Background(), tc.batch)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantQueries == nil {
+				tc.wantQueries = []string{}
+			}
+			require.Equal(t, tc.wantQueries, queries)
+			if tc.wantRows == nil {
+				tc.wantRows = [][]any{}
+			}
+			require.Equal(t, tc.wantRows, rows)
+			require.Equal(t, tc.wantTxs, txs)
+			require.Equal(t, tc.wantCheckpoint, checkpointed)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+)
+
+// conflictPolicy is the parsed version of a table conflict policy.
+type conflictPolicy struct {
+	action onConflictAction
+	// conflict target, either a list of columns or a constraint name, ready to
+	// be used in the ON CONFLICT clause. Empty if the primary key is used.
+	target string
+	// quoted names of the columns updated on conflict. All the inserted
+	// columns are updated when empty.
+	updateColumns []string
+}
+
+// conflictPolicies keeps the conflict policies per target table, keyed by
+// schema and table name. The "*" table applies to all the tables of a schema.
+type conflictPolicies map[string]map[string]*conflictPolicy
+
+var errInvalidConflictPolicy = errors.New("invalid table conflict policy")
+
+func newConflictPolicies(policies map[string]TableConflictPolicy, defaultAction onConflictAction) (conflictPolicies, error) {
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	parsed := make(conflictPolicies, len(policies))
+	for table, policy := range policies {
+		schemaName, tableName, err := parseConflictPolicyTable(table)
+		if err != nil {
+			return nil, err
+		}
+
+		cp := &conflictPolicy{action: defaultAction}
+		if policy.OnConflictAction != "" {
+			if cp.action, err = parseOnConflictAction(policy.OnConflictAction); err != nil {
+				return nil, fmt.Errorf("%w for table %s: %w", errInvalidConflictPolicy, table, err)
+			}
+		}
+
+		switch {
+		case policy.ConflictConstraint != "":
+			cp.target = fmt.Sprintf("ON CONSTRAINT %s", pglib.QuoteIdentifier(policy.ConflictConstraint))
+		case len(policy.ConflictColumns) > 0:
+			cp.target = fmt.Sprintf("(%s)", strings.Join(quoteIdentifiers(policy.ConflictColumns), ","))
+		}
+		cp.updateColumns = quoteIdentifiers(policy.UpdateColumns)
+
+		if _, found := parsed[schemaName]; !found {
+			parsed[schemaName] = map[string]*conflictPolicy{}
+		}
+		parsed[schemaName][tableName] = cp
+	}
+
+	return parsed, nil
+}
+
+// get returns the conflict policy for the table on input, or nil if there's
+// none.
+func (p conflictPolicies) get(schema, table string) *conflictPolicy {
+	tables, found := p[schema]
+	if !found {
+		return nil
+	}
+	if policy, found := tables[table]; found {
+		return policy
+	}
+	return tables[wildcardTable]
+}
+
+// updatedColumns returns the columns on input that are updated on conflict.
+func (p *conflictPolicy) updatedColumns(columns []string) []string {
+	if p == nil || len(p.updateColumns) == 0 {
+		return columns
+	}
+	updated := make([]string, 0, len(p.updateColumns))
+	for _, col := range columns {
+		if slices.Contains(p.updateColumns, col) {
+			updated = append(updated, col)
+		}
+	}
+	return updated
+}
+
+const wildcardTable = "*"
+
+func parseConflictPolicyTable(table string) (string, string, error) {
+	parts := strings.Split(table, ".")
+	switch len(parts) {
+	case 1:
+		return pglib.PublicSchema, parts[0], nil
+	case 2:
+		return parts[0], parts[1], nil
+	default:
+		return "", "", fmt.Errorf("%w: invalid table name %q", errInvalidConflictPolicy, table)
+	}
+}
+
+func quoteIdentifiers(names []string) []string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, pglib.QuoteIdentifier(name))
+	}
+	return quoted
+}
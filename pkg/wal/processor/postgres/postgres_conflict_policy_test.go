@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func Test_newConflictPolicies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		policies map[string]TableConflictPolicy
+
+		wantPolicies conflictPolicies
+		wantErr      error
+	}{
+		{
+			name:         "no policies",
+			wantPolicies: nil,
+		},
+		{
+			name: "ok",
+			policies: map[string]TableConflictPolicy{
+				"users": {
+					ConflictConstraint: "users_email_key",
+					ConflictColumns:    []string{"email"},
+					UpdateColumns:      []string{"name"},
+				},
+				"events.*": {
+					OnConflictAction: "nothing",
+					ConflictColumns:  []string{"event_id", "source"},
+				},
+			},
+
+			wantPolicies: conflictPolicies{
+				"public": {
+					"users": {
+						action:        onConflictUpdate,
+						target:        `ON CONSTRAINT "users_email_key"`,
+						updateColumns: []string{`"name"`},
+					},
+				},
+				"events": {
+					"*": {
+						action:        onConflictDoNothing,
+						target:        `("event_id","source")`,
+						updateColumns: []string{},
+					},
+				},
+			},
+		},
+		{
+			name: "error - invalid action",
+			policies: map[string]TableConflictPolicy{
+				"public.users": {OnConflictAction: "invalid"},
+			},
+
+			wantErr: errInvalidConflictPolicy,
+		},
+		{
+			name: "error - invalid table",
+			policies: map[string]TableConflictPolicy{
+				"db.public.users": {},
+			},
+
+			wantErr: errInvalidConflictPolicy,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			policies, err := newConflictPolicies(tc.policies, onConflictUpdate)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantPolicies, policies)
+		})
+	}
+}
+
+func TestDMLAdapter_walDataToQueries_conflictPolicies(t *testing.T) {
+	t.Parallel()
+
+	policies, err := newConflictPolicies(map[string]TableConflictPolicy{
+		"test_schema.users": {
+			ConflictConstraint: "users_email_key",
+			UpdateColumns:      []string{"name"},
+		},
+		"test_schema.events": {
+			OnConflictAction: "nothing",
+			ConflictColumns:  []string{"event_id"},
+		},
+		"test_schema.audit": {
+			UpdateColumns: []string{"created_at"},
+		},
+		"other_schema.*": {
+			OnConflictAction: "error",
+		},
+	}, onConflictUpdate)
+	require.NoError(t, err)
+
+	insert := func(schema, table string) *wal.Data {
+		return &wal.Data{
+			Action: "I",
+			Schema: schema,
+			Table:  table,
+			Columns: []wal.Column{
+				{ID: "1", Name: "id", Value: 1},
+				{ID: "2", Name: "name", Value: "alice"},
+			},
+			Metadata: wal.Metadata{
+				InternalColIDs: []string{"1"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		walData *wal.Data
+
+		wantOnConflict string
+	}{
+		{
+			name:           "constraint target with update columns",
+			walData:        insert(testSchema, "users"),
+			wantOnConflict: ` ON CONFLICT ON CONSTRAINT "users_email_key" DO UPDATE SET "name" = EXCLUDED."name"`,
+		},
+		{
+			name:           "column target with do nothing action",
+			walData:        insert(testSchema, "events"),
+			wantOnConflict: ` ON CONFLICT ("event_id") DO NOTHING`,
+		},
+		{
+			name:           "no update columns left",
+			walData:        insert(testSchema, "audit"),
+			wantOnConflict: ` ON CONFLICT ("id") DO NOTHING`,
+		},
+		{
+			name:           "schema wildcard",
+			walData:        insert("other_schema", "orders"),
+			wantOnConflict: "",
+		},
+		{
+			name:           "no policy",
+			walData:        insert(testSchema, "orders"),
+			wantOnConflict: ` ON CONFLICT ("id") DO UPDATE SET "id" = EXCLUDED."id", "name" = EXCLUDED."name"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := &dmlAdapter{
+				logger:           log.NewNoopLogger(),
+				onConflictAction: onConflictUpdate,
+				conflictPolicies: policies,
+				pgTypeMap:        pgtype.NewMap(),
+			}
+			queries, err := a.walDataToQueries(tc.walData, schemaInfo{})
+			require.NoError(t, err)
+			require.Len(t, queries, 1)
+
+			wantSQL := fmt.Sprintf(`INSERT INTO %s("id", "name") OVERRIDING SYSTEM VALUE VALUES($1, $2)%s`,
+				quotedTableName(tc.walData.Schema, tc.walData.Table), tc.wantOnConflict)
+			require.Equal(t, wantSQL, queries[0].sql)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// conflictResolution defines how changes to rows that have been concurrently
+// modified on the target are resolved, when replicating in both directions.
+type conflictResolution uint
+
+const (
+	// conflictResolutionNone applies all changes as they come.
+	conflictResolutionNone conflictResolution = iota
+	// conflictResolutionLastWriterWins only applies a change when its source
+	// commit timestamp is newer than the commit timestamp of the target row.
+	conflictResolutionLastWriterWins
+	// conflictResolutionSourcePriority always applies the source changes,
+	// overwriting the conflicting target rows and restoring the rows deleted
+	// on the target when they're updated on the source.
+	conflictResolutionSourcePriority
+	// conflictResolutionTargetPriority doesn't apply changes to target rows
+	// that were last modified locally on the target.
+	conflictResolutionTargetPriority
+)
+
+var (
+	errUnsupportedConflictResolution = errors.New("unsupported conflict resolution")
+	errMissingReplicationOrigin      = errors.New("conflict resolution requires a replication origin")
+)
+
+func parseConflictResolution(resolution string) (conflictResolution, error) {
+	switch resolution {
+	case "":
+		return conflictResolutionNone, nil
+	case "last_writer_wins":
+		return conflictResolutionLastWriterWins, nil
+	case "source_priority":
+		return conflictResolutionSourcePriority, nil
+	case "target_priority":
+		return conflictResolutionTargetPriority, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", errUnsupportedConflictResolution, resolution)
+	}
+}
+
+// isConditional returns true if changes are only applied to the target rows
+// that meet a condition. These rely on the replication origin of the target
+// rows to detect conflicts.
+func (r conflictResolution) isConditional() bool {
+	return r == conflictResolutionLastWriterWins || r == conflictResolutionTargetPriority
+}
+
+// conflictCondition returns the condition the target row needs to meet for
+// the change to be applied, along with its arguments. The commit timestamps
+// and origins of the target rows are only available with
+// track_commit_timestamp enabled on the target. Rows without that information
+// (for example rows frozen before it was enabled) are always updated.
+func (a *dmlAdapter) conflictCondition(d *wal.Data, placeholderIdx int) (string, []any) {
+	// bulk ingest is only used for snapshots, where there are no conflicts
+	if a.forCopy {
+		return "", nil
+	}
+
+	xmin := fmt.Sprintf("%s.xmin", quotedTableName(d.Schema, d.Table))
+	switch a.conflictResolution {
+	case conflictResolutionLastWriterWins:
+		return fmt.Sprintf("(pg_xact_commit_timestamp(%[1]s) IS NULL OR pg_xact_commit_timestamp(%[1]s) <= $%[2]d)", xmin, placeholderIdx),
+			[]any{eventTimestamp(d)}
+	case conflictResolutionTargetPriority:
+		// local changes on the target have no replication origin (0)
+		return fmt.Sprintf("(pg_xact_commit_timestamp_origin(%s)).roident IS DISTINCT FROM 0", xmin), nil
+	default:
+		return "", nil
+	}
+}
+
+// withConflictCondition adds the conflict resolution condition to the where
+// clause of the update or delete query on input.
+func (a *dmlAdapter) withConflictCondition(d *wal.Data, q *query) *query {
+	condition, args := a.conflictCondition(d, len(q.args)+1)
+	if condition == "" || q.IsEmpty() {
+		return q
+	}
+	q.sql = fmt.Sprintf("%s AND %s", q.sql, condition)
+	q.args = append(q.args, args...)
+	return q
+}
+
+// buildSourcePriorityUpdateQueries returns the queries applying the update
+// event on input with source priority. The update is applied as an upsert of
+// the new row, so that a row deleted on the target is restored. It returns
+// false if the update can't be upserted, either because the event doesn't
+// have the full row (unchanged TOAST columns), or because the table has no
+// primary key or the update changes it, in which case it's applied as a
+// regular update.
+func (a *dmlAdapter) buildSourcePriorityUpdateQueries(d *wal.Data, schemaInfo schemaInfo) ([]*query, bool) {
+	if d.HasUnchangedToastColumns() {
+		return nil, false
+	}
+
+	colIDs := d.Metadata.InternalColIDs
+	newKey := a.extractPrimaryKeyColumns(colIDs, d.Columns)
+	if len(newKey) == 0 {
+		return nil, false
+	}
+	if oldKey := a.extractPrimaryKeyColumns(colIDs, d.Identity); len(oldKey) > 0 && mergeKey(oldKey) != mergeKey(newKey) {
+		return nil, false
+	}
+	return a.buildInsertQueries(d, schemaInfo), true
+}
+
+// originPosition is the source position of a set of changes applied on the
+// target in a single transaction.
+type originPosition struct {
+	lsn       string
+	timestamp time.Time
+}
+
+// newOriginPosition returns the position of the latest change in the messages
+// on input, or nil if none of them has a source LSN (for example snapshot
+// rows).
+func newOriginPosition(msgs []*walMessage) *originPosition {
+	var pos *originPosition
+	for _, msg := range msgs {
+		if msg.IsEmpty() || msg.data.LSN == "" {
+			continue
+		}
+		ts := eventTimestamp(msg.data)
+		if pos == nil || ts.After(pos.timestamp) {
+			pos = &originPosition{lsn: msg.data.LSN, timestamp: ts}
+		}
+	}
+	return pos
+}
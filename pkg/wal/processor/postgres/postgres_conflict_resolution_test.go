@@ -0,0 +1,319 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func Test_parseConflictResolution(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		resolution string
+
+		wantResolution conflictResolution
+		wantErr        error
+	}{
+		{resolution: "", wantResolution: conflictResolutionNone},
+		{resolution: "last_writer_wins", wantResolution: conflictResolutionLastWriterWins},
+		{resolution: "source_priority", wantResolution: conflictResolutionSourcePriority},
+		{resolution: "target_priority", wantResolution: conflictResolutionTargetPriority},
+		{resolution: "invalid", wantErr: errUnsupportedConflictResolution},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.resolution, func(t *testing.T) {
+			t.Parallel()
+
+			resolution, err := parseConflictResolution(tc.resolution)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantResolution, resolution)
+		})
+	}
+}
+
+func TestDMLAdapter_walDataToQueries_conflictResolution(t *testing.T) {
+	t.Parallel()
+
+	testSchema := "test"
+	testTable := "table"
+	quotedTestTable := quotedTableName(testSchema, testTable)
+	testTimestamp := "2024-01-02 03:04:05.000006+00"
+	ts, err := (&wal.Data{Timestamp: testTimestamp}).GetTimestamp()
+	require.NoError(t, err)
+
+	newWalData := func(action string) *wal.Data {
+		d := &wal.Data{
+			Action:    action,
+			Schema:    testSchema,
+			Table:     testTable,
+			Timestamp: testTimestamp,
+			LSN:       "0/15D6A28",
+			Columns: []wal.Column{
+				{ID: "1", Name: "id", Value: 1},
+				{ID: "2", Name: "name", Value: "alice"},
+			},
+			Metadata: wal.Metadata{InternalColIDs: []string{"1"}},
+		}
+		if action == "D" {
+			d.Identity, d.Columns = d.Columns[:1], nil
+		}
+		return d
+	}
+
+	lwwCondition := func(placeholder int) string {
+		return fmt.Sprintf(`(pg_xact_commit_timestamp(%[1]s.xmin) IS NULL OR pg_xact_commit_timestamp(%[1]s.xmin) <= $%[2]d)`, quotedTestTable, placeholder)
+	}
+	targetPriorityCondition := fmt.Sprintf(`(pg_xact_commit_timestamp_origin(%s.xmin)).roident IS DISTINCT FROM 0`, quotedTestTable)
+	upsertSQL := fmt.Sprintf(`INSERT INTO %s("id", "name") OVERRIDING SYSTEM VALUE VALUES($1, $2) ON CONFLICT ("id") DO UPDATE SET "id" = EXCLUDED."id", "name" = EXCLUDED."name"`, quotedTestTable)
+
+	tests := []struct {
+		name       string
+		walData    *wal.Data
+		resolution conflictResolution
+		forCopy    bool
+
+		wantQueries []*query
+	}{
+		{
+			name:       "last writer wins - insert",
+			walData:    newWalData("I"),
+			resolution: conflictResolutionLastWriterWins,
+			wantQueries: []*query{
+				{
+					schema:      testSchema,
+					table:       testTable,
+					columnNames: []string{`"id"`, `"name"`},
+					sql:         fmt.Sprintf("%s WHERE %s", upsertSQL, lwwCondition(3)),
+					args:        []any{1, "alice", ts},
+				},
+			},
+		},
+		{
+			name:       "last writer wins - update",
+			walData:    newWalData("U"),
+			resolution: conflictResolutionLastWriterWins,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    fmt.Sprintf(`UPDATE %s SET "id" = $1, "name" = $2 WHERE "id" = $3 AND %s`, quotedTestTable, lwwCondition(4)),
+					args:   []any{1, "alice", 1, ts},
+				},
+			},
+		},
+		{
+			name:       "last writer wins - delete",
+			walData:    newWalData("D"),
+			resolution: conflictResolutionLastWriterWins,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    fmt.Sprintf(`DELETE FROM %s WHERE "id" = $1 AND %s`, quotedTestTable, lwwCondition(2)),
+					args:   []any{1, ts},
+				},
+			},
+		},
+		{
+			name:       "last writer wins - truncate",
+			walData:    newWalData("T"),
+			resolution: conflictResolutionLastWriterWins,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    fmt.Sprintf("TRUNCATE %s", quotedTestTable),
+				},
+			},
+		},
+		{
+			name:       "target priority - insert",
+			walData:    newWalData("I"),
+			resolution: conflictResolutionTargetPriority,
+			wantQueries: []*query{
+				{
+					schema:      testSchema,
+					table:       testTable,
+					columnNames: []string{`"id"`, `"name"`},
+					sql:         fmt.Sprintf("%s WHERE %s", upsertSQL, targetPriorityCondition),
+					args:        []any{1, "alice"},
+				},
+			},
+		},
+		{
+			name:       "target priority - delete",
+			walData:    newWalData("D"),
+			resolution: conflictResolutionTargetPriority,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    fmt.Sprintf(`DELETE FROM %s WHERE "id" = $1 AND %s`, quotedTestTable, targetPriorityCondition),
+					args:   []any{1},
+				},
+			},
+		},
+		{
+			name:       "source priority - insert",
+			walData:    newWalData("I"),
+			resolution: conflictResolutionSourcePriority,
+			wantQueries: []*query{
+				{
+					schema:      testSchema,
+					table:       testTable,
+					columnNames: []string{`"id"`, `"name"`},
+					sql:         upsertSQL,
+					args:        []any{1, "alice"},
+				},
+			},
+		},
+		{
+			name: "source priority - update",
+			walData: func() *wal.Data {
+				d := newWalData("U")
+				d.Identity = []wal.Column{{ID: "1", Name: "id", Value: 1}}
+				return d
+			}(),
+			resolution: conflictResolutionSourcePriority,
+			wantQueries: []*query{
+				{
+					schema:      testSchema,
+					table:       testTable,
+					columnNames: []string{`"id"`, `"name"`},
+					sql:         upsertSQL,
+					args:        []any{1, "alice"},
+				},
+			},
+		},
+		{
+			name: "source priority - update with unchanged toast columns",
+			walData: func() *wal.Data {
+				d := newWalData("U")
+				d.UnchangedToastColumns = []string{"bio"}
+				return d
+			}(),
+			resolution: conflictResolutionSourcePriority,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    fmt.Sprintf(`UPDATE %s SET "id" = $1, "name" = $2 WHERE "id" = $3`, quotedTestTable),
+					args:   []any{1, "alice", 1},
+				},
+			},
+		},
+		{
+			name: "source priority - primary key update",
+			walData: func() *wal.Data {
+				d := newWalData("U")
+				d.Identity = []wal.Column{{ID: "1", Name: "id", Value: 2}}
+				return d
+			}(),
+			resolution: conflictResolutionSourcePriority,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    fmt.Sprintf(`UPDATE %s SET "id" = $1, "name" = $2 WHERE "id" = $3`, quotedTestTable),
+					args:   []any{1, "alice", 2},
+				},
+			},
+		},
+		{
+			name:       "source priority - delete",
+			walData:    newWalData("D"),
+			resolution: conflictResolutionSourcePriority,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    fmt.Sprintf(`DELETE FROM %s WHERE "id" = $1`, quotedTestTable),
+					args:   []any{1},
+				},
+			},
+		},
+		{
+			name:       "last writer wins - copy",
+			walData:    newWalData("I"),
+			resolution: conflictResolutionLastWriterWins,
+			forCopy:    true,
+			wantQueries: []*query{
+				{
+					schema:      testSchema,
+					table:       testTable,
+					columnNames: []string{`"id"`, `"name"`},
+					sql:         upsertSQL,
+					args:        []any{1, "alice"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := &dmlAdapter{
+				logger:             log.NewNoopLogger(),
+				onConflictAction:   onConflictUpdate,
+				conflictResolution: tc.resolution,
+				forCopy:            tc.forCopy,
+				pgTypeMap:          pgtype.NewMap(),
+			}
+			queries, err := a.walDataToQueries(tc.walData, schemaInfo{})
+			require.NoError(t, err)
+			require.Equal(t, tc.wantQueries, queries)
+		})
+	}
+}
+
+func Test_newOriginPosition(t *testing.T) {
+	t.Parallel()
+
+	newMsg := func(lsn, timestamp string) *walMessage {
+		return &walMessage{data: &wal.Data{LSN: lsn, Timestamp: timestamp}}
+	}
+	ts, err := (&wal.Data{Timestamp: "2024-01-02 03:04:05+00"}).GetTimestamp()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		msgs []*walMessage
+
+		wantPosition *originPosition
+	}{
+		{
+			name: "no messages",
+			msgs: []*walMessage{},
+		},
+		{
+			name: "no lsn",
+			msgs: []*walMessage{{}, newMsg("", "2024-01-02 03:04:05+00")},
+		},
+		{
+			name: "latest change",
+			msgs: []*walMessage{
+				newMsg("0/1", "2024-01-02 03:04:04+00"),
+				newMsg("0/2", "2024-01-02 03:04:05+00"),
+				{},
+			},
+			wantPosition: &originPosition{lsn: "0/2", timestamp: ts},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.wantPosition, newOriginPosition(tc.msgs))
+		})
+	}
+}
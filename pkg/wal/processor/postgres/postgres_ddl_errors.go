@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+)
+
+// ddlErrorLog records the DDL statements that fail to apply on the target in
+// the pgstream.ddl_errors table, so that schema drift between source and
+// target can be inspected and fixed. The table is created on the first
+// failure.
+type ddlErrorLog struct {
+	conn pglib.Querier
+
+	mu           sync.Mutex
+	tableCreated bool
+}
+
+const ddlErrorsTable = `"pgstream"."ddl_errors"`
+
+func newDDLErrorLog(conn pglib.Querier) *ddlErrorLog {
+	return &ddlErrorLog{conn: conn}
+}
+
+// record stores the failed DDL query on input along with its error.
+func (l *ddlErrorLog) record(ctx context.Context, q *query, ddlErr error) error {
+	if err := l.createTable(ctx); err != nil {
+		return err
+	}
+
+	if _, err := l.conn.Exec(ctx, fmt.Sprintf(`INSERT INTO %s("schema_name", "table_name", "ddl", "error") VALUES($1, $2, $3, $4)`, ddlErrorsTable),
+		q.schema, q.table, q.sql, ddlErr.Error()); err != nil {
+		return fmt.Errorf("recording DDL error: %w", err)
+	}
+	return nil
+}
+
+func (l *ddlErrorLog) createTable(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tableCreated {
+		return nil
+	}
+
+	queries := []string{
+		`CREATE SCHEMA IF NOT EXISTS "pgstream"`,
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	"id" bigserial PRIMARY KEY,
+	"schema_name" text,
+	"table_name" text,
+	"ddl" text NOT NULL,
+	"error" text NOT NULL,
+	"created_at" timestamptz NOT NULL DEFAULT now())`, ddlErrorsTable),
+	}
+	for _, q := range queries {
+		if _, err := l.conn.Exec(ctx, q); err != nil {
+			return fmt.Errorf("creating DDL errors table: %w", err)
+		}
+	}
+	l.tableCreated = true
+	return nil
+}
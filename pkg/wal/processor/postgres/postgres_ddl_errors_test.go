@@ -0,0 +1,212 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestBatchWriter_execDDL_recordsErrors(t *testing.T) {
+	t.Parallel()
+
+	ddlQuery := &query{schema: testSchema, table: testTable, sql: "CREATE INDEX IF NOT EXISTS idx ON test_schema.test_table(name)", isDDL: true}
+	errAlreadyExists := &pglib.ErrRelationAlreadyExists{Details: "idx"}
+
+	tests := []struct {
+		name   string
+		ddlErr error
+		// error returned when recording the DDL error
+		recordErr error
+
+		wantErr error
+	}{
+		{
+			name:   "non internal error is recorded and skipped",
+			ddlErr: errAlreadyExists,
+		},
+		{
+			name:    "internal error is recorded and returned",
+			ddlErr:  errTest,
+			wantErr: errTest,
+		},
+		{
+			name:      "error recording is ignored",
+			ddlErr:    errAlreadyExists,
+			recordErr: errors.New("recording error"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			recorded := []any{}
+			conn := &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, _ uint, sql string, args ...any) (pglib.CommandTag, error) {
+					switch {
+					case sql == ddlQuery.sql:
+						return pglib.CommandTag{}, tc.ddlErr
+					case strings.HasPrefix(sql, "INSERT INTO "+ddlErrorsTable):
+						mu.Lock()
+						defer mu.Unlock()
+						recorded = append(recorded, args...)
+						return pglib.CommandTag{}, tc.recordErr
+					default:
+						return pglib.CommandTag{}, nil
+					}
+				},
+			}
+
+			writer := &BatchWriter{
+				Writer: &Writer{
+					logger: loglib.NewNoopLogger(),
+					pgConn: conn,
+					adapter: &mockAdapter{
+						walEventToQueriesFn: func(e *wal.Event) ([]*query, error) {
+							return []*query{ddlQuery}, nil
+						},
+					},
+					ddlErrors: newDDLErrorLog(conn),
+				},
+			}
+
+			err := writer.execDDL(context.Background(), &walMessage{data: &wal.Data{Action: "M", Prefix: wal.DDLPrefix}, isDDL: true})
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, []any{testSchema, testTable, ddlQuery.sql, tc.ddlErr.Error()}, recorded)
+		})
+	}
+}
+
+func TestBatchWriter_execDDL_recordsErrorsExactlyOnce(t *testing.T) {
+	t.Parallel()
+
+	ddlQuery := &query{schema: testSchema, table: testTable, sql: "CREATE INDEX IF NOT EXISTS idx ON test_schema.test_table(name)", isDDL: true}
+	errAlreadyExists := &pglib.ErrRelationAlreadyExists{Details: "idx"}
+	errNoConnection := errors.New("no connection available")
+
+	tests := []struct {
+		name   string
+		ddlErr error
+
+		wantErr error
+	}{
+		{
+			name:   "non internal error is recorded once the transaction is done",
+			ddlErr: errAlreadyExists,
+		},
+		{
+			name:    "internal error is recorded once the transaction is done",
+			ddlErr:  errTest,
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// the replication origin limits the pool to a single connection,
+			// which is held by the transaction until it's done
+			var mu sync.Mutex
+			inTx := false
+			recorded := []any{}
+			conn := &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, _ uint, sql string, args ...any) (pglib.CommandTag, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					if inTx {
+						return pglib.CommandTag{}, errNoConnection
+					}
+					if strings.HasPrefix(sql, "INSERT INTO "+ddlErrorsTable) {
+						recorded = append(recorded, args...)
+					}
+					return pglib.CommandTag{}, nil
+				},
+				ExecInTxFn: func(ctx context.Context, fn func(tx pglib.Tx) error) error {
+					mu.Lock()
+					inTx = true
+					mu.Unlock()
+					defer func() {
+						mu.Lock()
+						inTx = false
+						mu.Unlock()
+					}()
+					return fn(&pgmocks.Tx{
+						ExecFn: func(ctx context.Context, _ uint, sql string, args ...any) (pglib.CommandTag, error) {
+							if sql == ddlQuery.sql {
+								return pglib.CommandTag{}, tc.ddlErr
+							}
+							return pglib.CommandTag{}, nil
+						},
+					})
+				},
+			}
+
+			writer := &BatchWriter{
+				Writer: &Writer{
+					logger: loglib.NewNoopLogger(),
+					pgConn: conn,
+					adapter: &mockAdapter{
+						walEventToQueriesFn: func(e *wal.Event) ([]*query, error) {
+							return []*query{ddlQuery}, nil
+						},
+					},
+					ddlErrors: newDDLErrorLog(conn),
+				},
+				applyProgress: &applyProgress{},
+			}
+
+			err := writer.execDDL(context.Background(), &walMessage{data: &wal.Data{Action: "M", Prefix: wal.DDLPrefix}, isDDL: true})
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, []any{testSchema, testTable, ddlQuery.sql, tc.ddlErr.Error()}, recorded)
+		})
+	}
+}
+
+func TestDDLErrorLog_record(t *testing.T) {
+	t.Parallel()
+
+	q := &query{schema: testSchema, table: testTable, sql: "DROP VIEW IF EXISTS test_schema.v", isDDL: true}
+
+	t.Run("table is only created once", func(t *testing.T) {
+		t.Parallel()
+
+		creates := 0
+		log := newDDLErrorLog(&pgmocks.Querier{
+			ExecFn: func(ctx context.Context, _ uint, sql string, args ...any) (pglib.CommandTag, error) {
+				if strings.HasPrefix(sql, "CREATE TABLE") {
+					creates++
+				}
+				return pglib.CommandTag{}, nil
+			},
+		})
+
+		require.NoError(t, log.record(context.Background(), q, errTest))
+		require.NoError(t, log.record(context.Background(), q, errTest))
+		require.Equal(t, 1, creates)
+	})
+
+	t.Run("error creating table", func(t *testing.T) {
+		t.Parallel()
+
+		log := newDDLErrorLog(&pgmocks.Querier{
+			ExecFn: func(ctx context.Context, _ uint, sql string, args ...any) (pglib.CommandTag, error) {
+				return pglib.CommandTag{}, errTest
+			},
+		})
+
+		require.ErrorIs(t, log.record(context.Background(), q, errors.New("ddl error")), errTest)
+		require.False(t, log.tableCreated)
+	})
+}
@@ -0,0 +1,220 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"golang.org/x/sync/errgroup"
+)
+
+var errParallelApplyWithReplicationOrigin = errors.New("parallel apply is not supported with a replication origin")
+
+// applyMessagesInParallel applies the messages on input using the configured
+// number of workers. DML events are hashed by table and primary key onto the
+// workers, so that the changes to a row are always applied in order by the
+// same worker. Tables linked by foreign keys on the target are assigned to the
+// same worker as a whole, so that their changes are applied in order. DDL,
+// truncate and primary key update events act as barriers: all the pending
+// changes are applied before they're applied on their own.
+func (w *BatchWriter) applyMessagesInParallel(ctx context.Context, messages []*walMessage) error {
+	partitions := make([][]*walMessage, w.applyWorkers)
+	pending := 0
+
+	flushPartitions := func() error {
+		if pending == 0 {
+			return nil
+		}
+		defer func() {
+			for i := range partitions {
+				partitions[i] = nil
+			}
+			pending = 0
+		}()
+
+		eg, egCtx := errgroup.WithContext(ctx)
+		for i, partition := range partitions {
+			if len(partition) == 0 {
+				continue
+			}
+			eg.Go(func() error {
+				if err := w.applyMessages(egCtx, partition); err != nil {
+					w.logger.Error(err, "applying changes in parallel", loglib.Fields{"worker": i, "worker_batch_size": len(partition)})
+					return err
+				}
+				return nil
+			})
+		}
+		return eg.Wait()
+	}
+
+	for _, msg := range messages {
+		if msg.IsEmpty() {
+			continue
+		}
+
+		if w.isApplyBarrier(msg) {
+			if err := flushPartitions(); err != nil {
+				return err
+			}
+			if err := w.applyMessages(ctx, []*walMessage{msg}); err != nil {
+				return err
+			}
+			if msg.isDDL {
+				// the DDL might have changed the foreign keys on the target
+				w.fkGroups.reset()
+			}
+			continue
+		}
+
+		if err := w.fkGroups.load(ctx); err != nil {
+			return err
+		}
+
+		i := w.applyWorker(msg)
+		partitions[i] = append(partitions[i], msg)
+		pending++
+	}
+
+	return flushPartitions()
+}
+
+// isApplyBarrier returns true if the message on input can't be applied
+// concurrently with other changes. That's the case for DDL and truncate
+// events, which affect whole tables, and for updates to the primary key,
+// which affect the rows of two different keys.
+func (w *BatchWriter) isApplyBarrier(msg *walMessage) bool {
+	if msg.isDDL || msg.data.Action == "T" {
+		return true
+	}
+	if msg.data.Action != "U" {
+		return false
+	}
+
+	colIDs := msg.data.Metadata.InternalColIDs
+	oldKey := w.dmlAdapter.extractPrimaryKeyColumns(colIDs, msg.data.Identity)
+	if len(oldKey) == 0 {
+		return false
+	}
+	return mergeKey(oldKey) != mergeKey(w.dmlAdapter.extractPrimaryKeyColumns(colIDs, msg.data.Columns))
+}
+
+// applyWorker returns the worker the DML message on input is assigned to,
+// based on its table and primary key. Changes to tables without a primary key
+// are all assigned to the same worker, as are the changes to tables linked by
+// foreign keys.
+func (w *BatchWriter) applyWorker(msg *walMessage) int {
+	h := fnv.New32a()
+	table := quotedTableName(msg.data.Schema, msg.data.Table)
+	if group, found := w.fkGroups.group(table); found {
+		h.Write([]byte(group))
+		return int(h.Sum32() % uint32(w.applyWorkers))
+	}
+	h.Write([]byte(table))
+
+	cols := msg.data.Columns
+	if msg.data.Action == "D" {
+		cols = msg.data.Identity
+	}
+	if key := w.dmlAdapter.extractPrimaryKeyColumns(msg.data.Metadata.InternalColIDs, cols); len(key) > 0 {
+		h.Write([]byte(mergeKey(key)))
+	}
+
+	return int(h.Sum32() % uint32(w.applyWorkers))
+}
+
+const foreignKeysQuery = `SELECT cn.nspname, c.relname, rn.nspname, r.relname FROM pg_constraint k
+	JOIN pg_class c ON c.oid = k.conrelid JOIN pg_namespace cn ON cn.oid = c.relnamespace
+	JOIN pg_class r ON r.oid = k.confrelid JOIN pg_namespace rn ON rn.oid = r.relnamespace
+	WHERE k.contype = 'f'`
+
+// foreignKeyGroups keeps the groups of tables of the target linked by foreign
+// keys, directly or through other tables. The groups are loaded lazily, and
+// reloaded after a DDL change.
+type foreignKeyGroups struct {
+	conn pglib.Querier
+	// groups is a map of quoted table name to the name of its group. Nil
+	// when the groups need to be loaded.
+	groups map[string]string
+}
+
+func newForeignKeyGroups(conn pglib.Querier) *foreignKeyGroups {
+	return &foreignKeyGroups{conn: conn}
+}
+
+// group returns the name of the foreign key group of the quoted table on
+// input, if the table has any foreign key or is referenced by one.
+func (g *foreignKeyGroups) group(table string) (string, bool) {
+	if g == nil {
+		return "", false
+	}
+	group, found := g.groups[table]
+	return group, found
+}
+
+func (g *foreignKeyGroups) reset() {
+	if g == nil {
+		return
+	}
+	g.groups = nil
+}
+
+// load queries the foreign keys of the target and groups the tables they link,
+// unless they're already loaded. Each group is named after its first table in
+// alphabetical order.
+func (g *foreignKeyGroups) load(ctx context.Context) error {
+	if g == nil || g.groups != nil {
+		return nil
+	}
+
+	rows, err := g.conn.Query(ctx, foreignKeysQuery)
+	if err != nil {
+		return fmt.Errorf("querying foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	parents := map[string]string{}
+	var find func(table string) string
+	find = func(table string) string {
+		parent, found := parents[table]
+		if !found {
+			parents[table] = table
+			return table
+		}
+		if parent == table {
+			return table
+		}
+		root := find(parent)
+		parents[table] = root
+		return root
+	}
+
+	for rows.Next() {
+		var schema, table, refSchema, refTable string
+		if err := rows.Scan(&schema, &table, &refSchema, &refTable); err != nil {
+			return fmt.Errorf("scanning foreign key: %w", err)
+		}
+		root, refRoot := find(quotedTableName(schema, table)), find(quotedTableName(refSchema, refTable))
+		switch {
+		case root < refRoot:
+			parents[refRoot] = root
+		case refRoot < root:
+			parents[root] = refRoot
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading foreign keys: %w", err)
+	}
+
+	groups := make(map[string]string, len(parents))
+	for table := range parents {
+		groups[table] = find(table)
+	}
+	g.groups = groups
+	return nil
+}
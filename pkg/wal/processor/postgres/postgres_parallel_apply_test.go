@@ -0,0 +1,395 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/batch"
+	batchmocks "github.com/xataio/pgstream/pkg/wal/processor/batch/mocks"
+)
+
+func newParallelApplyMsg(action string, identity []wal.Column, cols ...wal.Column) *walMessage {
+	return &walMessage{
+		data: &wal.Data{
+			Action:   action,
+			Schema:   testSchema,
+			Table:    testTable,
+			Identity: identity,
+			Columns:  cols,
+			Metadata: wal.Metadata{InternalColIDs: []string{"1"}},
+		},
+	}
+}
+
+func parallelApplyIDCol(id int) wal.Column {
+	return wal.Column{ID: "1", Name: "id", Type: "bigint", Value: id}
+}
+
+func parallelApplyNameCol(name string) wal.Column {
+	return wal.Column{ID: "2", Name: "name", Type: "text", Value: name}
+}
+
+func TestBatchWriter_sendBatch_parallelApply(t *testing.T) {
+	t.Parallel()
+
+	updateMsg := func(id int, name string) *walMessage {
+		return newParallelApplyMsg("U", nil, parallelApplyIDCol(id), parallelApplyNameCol(name))
+	}
+	ddlMsg := &walMessage{data: &wal.Data{Action: "M", Prefix: "pgstream.ddl", Schema: testSchema, Table: testTable}, isDDL: true}
+
+	tests := []struct {
+		name    string
+		msgs    []*walMessage
+		execErr error
+
+		wantBeforeDDL  []string
+		wantAfterDDL   []string
+		wantInOrder    [][2]string
+		wantCheckpoint bool
+		wantErr        error
+	}{
+		{
+			name: "ok - changes are applied by key, DDL acts as barrier",
+			msgs: []*walMessage{
+				updateMsg(1, "a"), updateMsg(2, "c"), updateMsg(3, "d"), updateMsg(1, "b"),
+				updateMsg(4, "e"), updateMsg(5, "f"), {},
+				ddlMsg,
+				updateMsg(6, "g"),
+			},
+
+			wantBeforeDDL:  []string{"a", "b", "c", "d", "e", "f"},
+			wantAfterDDL:   []string{"g"},
+			wantInOrder:    [][2]string{{"a", "b"}},
+			wantCheckpoint: true,
+		},
+		{
+			name: "ok - primary key update acts as barrier",
+			msgs: []*walMessage{
+				updateMsg(1, "a"), updateMsg(2, "b"),
+				newParallelApplyMsg("U", []wal.Column{parallelApplyIDCol(1)}, parallelApplyIDCol(7), parallelApplyNameCol("c")),
+				updateMsg(7, "d"),
+			},
+
+			wantBeforeDDL:  []string{"a", "b", "c", "d"},
+			wantInOrder:    [][2]string{{"a", "c"}, {"b", "c"}, {"c", "d"}},
+			wantCheckpoint: true,
+		},
+		{
+			name:    "error - worker fails, batch is not checkpointed",
+			msgs:    []*walMessage{updateMsg(1, "a"), updateMsg(2, "b")},
+			execErr: errTest,
+
+			wantBeforeDDL: []string{},
+			wantErr:       errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			applied := []string{}
+			record := func(name string) {
+				mu.Lock()
+				defer mu.Unlock()
+				applied = append(applied, name)
+			}
+
+			checkpointed := false
+			writer := &BatchWriter{
+				Writer: &Writer{
+					logger: loglib.NewNoopLogger(),
+					pgConn: &pgmocks.Querier{
+						ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+							return f(&pgmocks.Tx{
+								ExecFn: func(ctx context.Context, _ uint, sql string, args ...any) (pglib.CommandTag, error) {
+									if tc.execErr != nil {
+										return pglib.CommandTag{}, tc.execErr
+									}
+									record(fmt.Sprintf("%v", args[1]))
+									return pglib.CommandTag{}, nil
+								},
+							})
+						},
+						ExecFn: func(ctx context.Context, _ uint, sql string, args ...any) (pglib.CommandTag, error) {
+							record("ddl")
+							return pglib.CommandTag{}, nil
+						},
+						CloseFn: func(ctx context.Context) error { return nil },
+					},
+					adapter: &mockAdapter{
+						walEventToQueriesFn: func(e *wal.Event) ([]*query, error) {
+							return []*query{{sql: "ALTER TABLE test_schema.test_table ADD COLUMN x text", isDDL: true}}, nil
+						},
+					},
+					checkpointer: func(ctx context.Context, positions []wal.CommitPosition) error {
+						checkpointed = true
+						return nil
+					},
+				},
+				dmlAdapter:   mustNewDMLAdapter(t),
+				applyWorkers: 4,
+				batchSender:  batchmocks.NewBatchSender[*walMessage](),
+			}
+			defer writer.Close()
+
+			err := writer.sendBatch(context.Background(), batch.NewBatch(tc.msgs, []wal.CommitPosition{testCommitPosition}))
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantCheckpoint, checkpointed)
+
+			before, after := applied, []string{}
+			if i := slices.Index(applied, "ddl"); i >= 0 {
+				before, after = applied[:i], applied[i+1:]
+			}
+			require.ElementsMatch(t, tc.wantBeforeDDL, before)
+			require.ElementsMatch(t, tc.wantAfterDDL, after)
+			for _, pair := range tc.wantInOrder {
+				require.Less(t, slices.Index(applied, pair[0]), slices.Index(applied, pair[1]), "%s applied after %s", pair[0], pair[1])
+			}
+		})
+	}
+}
+
+func foreignKeyRows(fks [][4]string) *pgmocks.Rows {
+	return &pgmocks.Rows{
+		CloseFn: func() {},
+		NextFn:  func(i uint) bool { return int(i) <= len(fks) },
+		ScanFn: func(i uint, dest ...any) error {
+			for j, name := range fks[i-1] {
+				*(dest[j].(*string)) = name
+			}
+			return nil
+		},
+		ErrFn: func() error { return nil },
+	}
+}
+
+func TestBatchWriter_sendBatch_parallelApplyForeignKeys(t *testing.T) {
+	t.Parallel()
+
+	insertMsg := func(table string, id int, name string) *walMessage {
+		msg := newParallelApplyMsg("I", nil, parallelApplyIDCol(id), parallelApplyNameCol(name))
+		msg.data.Table = table
+		return msg
+	}
+
+	msgs := []*walMessage{}
+	wantInOrder := [][2]string{}
+	for i := range 10 {
+		parent, child := fmt.Sprintf("parent-%d", i), fmt.Sprintf("child-%d", i)
+		msgs = append(msgs, insertMsg("parents", i, parent), insertMsg("children", i+100, child))
+		wantInOrder = append(wantInOrder, [2]string{parent, child})
+	}
+
+	var mu sync.Mutex
+	applied := []string{}
+	fkQueries := 0
+	conn := &pgmocks.Querier{
+		QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+			require.Equal(t, foreignKeysQuery, query)
+			fkQueries++
+			return foreignKeyRows([][4]string{{testSchema, "children", testSchema, "parents"}}), nil
+		},
+		ExecInTxFn: func(ctx context.Context, f func(tx pglib.Tx) error) error {
+			return f(&pgmocks.Tx{
+				ExecFn: func(ctx context.Context, _ uint, sql string, args ...any) (pglib.CommandTag, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					applied = append(applied, fmt.Sprintf("%v", args[1]))
+					return pglib.CommandTag{}, nil
+				},
+			})
+		},
+		CloseFn: func(ctx context.Context) error { return nil },
+	}
+
+	writer := &BatchWriter{
+		Writer: &Writer{
+			logger:  loglib.NewNoopLogger(),
+			pgConn:  conn,
+			adapter: &mockAdapter{},
+		},
+		dmlAdapter:   mustNewDMLAdapter(t),
+		applyWorkers: 8,
+		fkGroups:     newForeignKeyGroups(conn),
+		batchSender:  batchmocks.NewBatchSender[*walMessage](),
+	}
+	defer writer.Close()
+
+	err := writer.sendBatch(context.Background(), batch.NewBatch(msgs, []wal.CommitPosition{testCommitPosition}))
+	require.NoError(t, err)
+	require.Len(t, applied, len(msgs))
+	for _, pair := range wantInOrder {
+		require.Less(t, slices.Index(applied, pair[0]), slices.Index(applied, pair[1]), "%s applied after %s", pair[0], pair[1])
+	}
+	// the foreign keys are only loaded once
+	require.Equal(t, 1, fkQueries)
+}
+
+func TestForeignKeyGroups_load(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		fks      [][4]string
+		queryErr error
+
+		wantGroups map[string]string
+		wantErr    error
+	}{
+		{
+			name: "ok - tables linked directly and indirectly are grouped",
+			fks: [][4]string{
+				{"public", "orders", "public", "users"},
+				{"public", "order_items", "public", "orders"},
+				{"public", "order_items", "other", "products"},
+				{"public", "categories", "public", "categories"},
+			},
+
+			wantGroups: map[string]string{
+				`"public"."users"`:       `"other"."products"`,
+				`"public"."orders"`:      `"other"."products"`,
+				`"public"."order_items"`: `"other"."products"`,
+				`"other"."products"`:     `"other"."products"`,
+				`"public"."categories"`:  `"public"."categories"`,
+			},
+		},
+		{
+			name: "ok - no foreign keys",
+			fks:  [][4]string{},
+
+			wantGroups: map[string]string{},
+		},
+		{
+			name:     "error - querying foreign keys",
+			queryErr: errTest,
+
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			g := newForeignKeyGroups(&pgmocks.Querier{
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					if tc.queryErr != nil {
+						return nil, tc.queryErr
+					}
+					return foreignKeyRows(tc.fks), nil
+				},
+			})
+
+			err := g.load(context.Background())
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantGroups, g.groups)
+		})
+	}
+}
+
+func TestBatchWriter_applyWorker(t *testing.T) {
+	t.Parallel()
+
+	writer := &BatchWriter{
+		dmlAdapter:   mustNewDMLAdapter(t),
+		applyWorkers: 8,
+	}
+
+	insert := newParallelApplyMsg("I", nil, parallelApplyIDCol(1), parallelApplyNameCol("a"))
+	update := newParallelApplyMsg("U", nil, parallelApplyIDCol(1), parallelApplyNameCol("b"))
+	deleteMsg := newParallelApplyMsg("D", []wal.Column{parallelApplyIDCol(1)})
+
+	worker := writer.applyWorker(insert)
+	require.GreaterOrEqual(t, worker, 0)
+	require.Less(t, worker, 8)
+	require.Equal(t, worker, writer.applyWorker(update))
+	require.Equal(t, worker, writer.applyWorker(deleteMsg))
+
+	// rows of tables without primary key are all assigned to the same worker
+	noPK := func(name string) *walMessage {
+		msg := newParallelApplyMsg("I", nil, parallelApplyNameCol(name))
+		msg.data.Metadata.InternalColIDs = nil
+		return msg
+	}
+	require.Equal(t, writer.applyWorker(noPK("a")), writer.applyWorker(noPK("b")))
+
+	// rows of tables linked by foreign keys are all assigned to the same worker
+	writer.fkGroups = &foreignKeyGroups{groups: map[string]string{
+		quotedTableName(testSchema, "parents"):  quotedTableName(testSchema, "children"),
+		quotedTableName(testSchema, "children"): quotedTableName(testSchema, "children"),
+	}}
+	linked := func(table string, id int) *walMessage {
+		msg := newParallelApplyMsg("I", nil, parallelApplyIDCol(id))
+		msg.data.Table = table
+		return msg
+	}
+	worker = writer.applyWorker(linked("parents", 1))
+	for id := range 10 {
+		require.Equal(t, worker, writer.applyWorker(linked("parents", id)))
+		require.Equal(t, worker, writer.applyWorker(linked("children", id)))
+	}
+}
+
+func TestBatchWriter_isApplyBarrier(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		msg  *walMessage
+
+		wantBarrier bool
+	}{
+		{
+			name:        "DDL",
+			msg:         &walMessage{data: &wal.Data{Action: "M"}, isDDL: true},
+			wantBarrier: true,
+		},
+		{
+			name:        "truncate",
+			msg:         newParallelApplyMsg("T", nil),
+			wantBarrier: true,
+		},
+		{
+			name: "insert",
+			msg:  newParallelApplyMsg("I", nil, parallelApplyIDCol(1)),
+		},
+		{
+			name: "update",
+			msg:  newParallelApplyMsg("U", nil, parallelApplyIDCol(1), parallelApplyNameCol("a")),
+		},
+		{
+			name: "update with same key identity",
+			msg:  newParallelApplyMsg("U", []wal.Column{parallelApplyIDCol(1)}, parallelApplyIDCol(1), parallelApplyNameCol("a")),
+		},
+		{
+			name:        "primary key update",
+			msg:         newParallelApplyMsg("U", []wal.Column{parallelApplyIDCol(1)}, parallelApplyIDCol(2), parallelApplyNameCol("a")),
+			wantBarrier: true,
+		},
+		{
+			name: "delete",
+			msg:  newParallelApplyMsg("D", []wal.Column{parallelApplyIDCol(1)}),
+		},
+	}
+
+	writer := &BatchWriter{dmlAdapter: mustNewDMLAdapter(t), applyWorkers: 4}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.wantBarrier, writer.isApplyBarrier(tc.msg))
+		})
+	}
+}
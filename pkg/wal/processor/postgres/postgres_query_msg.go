@@ -2,6 +2,8 @@
 
 package postgres
 
+import "time"
+
 type query struct {
 	schema        string
 	table         string
@@ -10,6 +12,9 @@ type query struct {
 	args          []any
 	isDDL         bool
 	needsTextCopy bool
+	// timestamp is the commit timestamp of the change, used to route audit
+	// rows to their monthly partition
+	timestamp time.Time
 }
 
 const (
@@ -17,8 +22,8 @@ const (
 	stringOverhead    = 16 // string = pointer + length
 	sliceOverhead     = 24 // slice = pointer + length + capacity
 	// queryStructOverhead is the approximate size of the query struct itself
-	// (3 strings + 2 slices + 1 bool + padding)
-	queryStructOverhead = 104
+	// (3 strings + 2 slices + 2 bools + padding + 1 time)
+	queryStructOverhead = 128
 )
 
 // size returns the approximate size of the message including the SQL query and
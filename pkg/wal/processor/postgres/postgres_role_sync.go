@@ -0,0 +1,305 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// roleSyncer replicates the roles of the source database and their
+// memberships to the target. Role changes are global and don't fire event
+// triggers, so they can't be captured from the WAL. Instead, the roles are
+// synced periodically, as well as before applying the DDL events that can
+// reference them (privilege and ownership changes), so that the roles exist
+// on the target by the time they're used.
+type roleSyncer struct {
+	logger     loglib.Logger
+	sourceConn pglib.Querier
+	targetConn pglib.Querier
+	mode       string
+	interval   time.Duration
+
+	// applied keeps the last statement applied for each role, so that roles
+	// are only altered on the target when they change on the source
+	applied map[string]string
+	mutex   sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type sourceRole struct {
+	name        string
+	inherit     bool
+	createRole  bool
+	createDB    bool
+	canLogin    bool
+	connLimit   int
+	validUntil  string
+	password    string
+	hasPassword bool
+	memberOf    []string
+}
+
+const (
+	defaultRoleSyncInterval = time.Minute
+
+	// superuser roles are not synced, since the target roles can't be made
+	// superusers on managed services, and neither are the predefined ones.
+	// Role passwords are only available in pg_authid, which requires
+	// superuser privileges to be read.
+	sourceRolesQuery = `SELECT r.rolname, r.rolinherit, r.rolcreaterole, r.rolcreatedb, r.rolcanlogin, r.rolconnlimit,
+	COALESCE(r.rolvaliduntil::text, ''), %s,
+	ARRAY(SELECT g.rolname FROM pg_auth_members m JOIN pg_roles g ON g.oid = m.roleid WHERE m.member = r.oid ORDER BY g.rolname)
+	FROM %s r WHERE r.rolname !~ '^pg_' AND NOT r.rolsuper ORDER BY r.rolname`
+
+	existingRolesQuery = `SELECT rolname FROM pg_roles WHERE rolname = ANY($1)`
+)
+
+func newRoleSyncer(ctx context.Context, cfg *RoleSyncConfig, targetConn pglib.Querier, logger loglib.Logger) (*roleSyncer, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	sourceConn, err := pglib.NewConnPool(ctx, cfg.SourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("creating role sync source connection: %w", err)
+	}
+
+	s := &roleSyncer{
+		logger:     logger,
+		sourceConn: sourceConn,
+		targetConn: targetConn,
+		mode:       cfg.mode(),
+		interval:   cfg.Interval,
+		applied:    map[string]string{},
+	}
+	if s.interval <= 0 {
+		s.interval = defaultRoleSyncInterval
+	}
+	return s, nil
+}
+
+// start syncs the roles right away and then periodically in the background
+// until the syncer is closed.
+func (s *roleSyncer) start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			if err := s.sync(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				s.logger.Error(err, "syncing roles")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// syncForDDL syncs the roles before the DDL event on input is applied, if it
+// can reference roles. Errors are only logged, since the DDL can still be
+// applied if the roles it references already exist on the target.
+func (s *roleSyncer) syncForDDL(ctx context.Context, d *wal.Data) {
+	ddlEvent, err := wal.WalDataToDDLEvent(d)
+	if err != nil || !referencesRoles(ddlEvent) {
+		return
+	}
+	if err := s.sync(ctx); err != nil {
+		s.logger.Error(err, "syncing roles before DDL", loglib.Fields{"command_tag": ddlEvent.CommandTag})
+	}
+}
+
+func (s *roleSyncer) sync(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	roles, err := s.querySourceRoles(ctx)
+	if err != nil {
+		return err
+	}
+
+	synced := 0
+	var errs error
+	for _, role := range roles {
+		stmt := roleStatement(role, s.mode)
+		if s.applied[role.name] == stmt {
+			continue
+		}
+		if _, err := s.targetConn.Exec(ctx, stmt); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("syncing role %s: %w", role.name, err))
+			continue
+		}
+		s.applied[role.name] = stmt
+		synced++
+	}
+
+	dropped, err := s.dropRemovedRoles(ctx, roles)
+	errs = errors.Join(errs, err)
+
+	if synced > 0 || dropped > 0 {
+		s.logger.Debug("synced roles", loglib.Fields{"roles": synced, "dropped_roles": dropped})
+	}
+	return errs
+}
+
+// dropRemovedRoles drops the roles previously synced to the target that no
+// longer exist on the source. Only the roles synced by this syncer are
+// dropped, so that the roles created directly on the target are left alone.
+// Dropping a role fails while it still owns objects or holds privileges on
+// the target, in which case it's retried on the next sync.
+func (s *roleSyncer) dropRemovedRoles(ctx context.Context, roles []*sourceRole) (int, error) {
+	candidates := []string{}
+	for name := range s.applied {
+		if !slices.ContainsFunc(roles, func(r *sourceRole) bool { return r.name == name }) {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+	slices.Sort(candidates)
+
+	// roles that became superusers are no longer synced, but they still exist
+	existing, err := s.queryExistingSourceRoles(ctx, candidates)
+	if err != nil {
+		return 0, err
+	}
+
+	dropped := 0
+	var errs error
+	for _, name := range candidates {
+		if slices.Contains(existing, name) {
+			continue
+		}
+		if _, err := s.targetConn.Exec(ctx, fmt.Sprintf("DROP ROLE IF EXISTS %s", pglib.QuoteIdentifier(name))); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("dropping role %s: %w", name, err))
+			continue
+		}
+		delete(s.applied, name)
+		dropped++
+	}
+	return dropped, errs
+}
+
+func (s *roleSyncer) close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	return s.sourceConn.Close(context.Background())
+}
+
+func (s *roleSyncer) querySourceRoles(ctx context.Context) ([]*sourceRole, error) {
+	query := fmt.Sprintf(sourceRolesQuery, "NULL::text", "pg_roles")
+	if s.mode == RoleSyncModeEnabled {
+		query = fmt.Sprintf(sourceRolesQuery, "r.rolpassword", "pg_authid")
+	}
+
+	rows, err := s.sourceConn.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("getting source roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []*sourceRole{}
+	for rows.Next() {
+		role := &sourceRole{}
+		var password *string
+		if err := rows.Scan(&role.name, &role.inherit, &role.createRole, &role.createDB, &role.canLogin,
+			&role.connLimit, &role.validUntil, &password, &role.memberOf); err != nil {
+			return nil, fmt.Errorf("scanning source role: %w", err)
+		}
+		if password != nil {
+			role.password = *password
+			role.hasPassword = true
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (s *roleSyncer) queryExistingSourceRoles(ctx context.Context, names []string) ([]string, error) {
+	rows, err := s.sourceConn.Query(ctx, existingRolesQuery, names)
+	if err != nil {
+		return nil, fmt.Errorf("getting existing source roles: %w", err)
+	}
+	defer rows.Close()
+
+	existing := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning existing source role: %w", err)
+		}
+		existing = append(existing, name)
+	}
+	return existing, rows.Err()
+}
+
+// roleStatement returns the statement that creates the role on input on the
+// target if it doesn't exist yet, and sets its attributes and memberships.
+// Passwords are only set when the mode includes them.
+func roleStatement(role *sourceRole, mode string) string {
+	name := pglib.QuoteIdentifier(role.name)
+
+	attributes := []string{
+		attribute(role.inherit, "INHERIT"),
+		attribute(role.createRole, "CREATEROLE"),
+		attribute(role.createDB, "CREATEDB"),
+		attribute(role.canLogin, "LOGIN"),
+		fmt.Sprintf("CONNECTION LIMIT %d", role.connLimit),
+	}
+	if role.validUntil != "" {
+		attributes = append(attributes, fmt.Sprintf("VALID UNTIL %s", quoteLiteral(role.validUntil)))
+	}
+	if mode == RoleSyncModeEnabled && role.hasPassword {
+		attributes = append(attributes, fmt.Sprintf("PASSWORD %s", quoteLiteral(role.password)))
+	}
+
+	stmts := []string{
+		fmt.Sprintf("DO $$BEGIN CREATE ROLE %s; EXCEPTION WHEN duplicate_object THEN NULL; END$$", name),
+		fmt.Sprintf("ALTER ROLE %s WITH %s", name, strings.Join(attributes, " ")),
+	}
+	for _, group := range role.memberOf {
+		stmts = append(stmts, fmt.Sprintf("GRANT %s TO %s", pglib.QuoteIdentifier(group), name))
+	}
+	return strings.Join(stmts, ";\n") + ";"
+}
+
+func attribute(enabled bool, name string) string {
+	if enabled {
+		return name
+	}
+	return "NO" + name
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// referencesRoles returns true if the DDL event on input can reference roles
+// that might not exist on the target yet.
+func referencesRoles(ddlEvent *wal.DDLEvent) bool {
+	switch ddlEvent.CommandTag {
+	case "GRANT", "REVOKE", "ALTER DEFAULT PRIVILEGES", "CREATE POLICY", "ALTER POLICY":
+		return true
+	default:
+		return strings.Contains(strings.ToUpper(ddlEvent.DDL), " OWNER TO ")
+	}
+}
@@ -0,0 +1,268 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestRoleSyncer_sync(t *testing.T) {
+	t.Parallel()
+
+	password := "SCRAM-SHA-256$4096:salt$key"
+
+	sourceRows := func() *pgmocks.Rows {
+		return &pgmocks.Rows{
+			CloseFn: func() {},
+			NextFn:  func(i uint) bool { return i <= 2 },
+			ScanFn: func(i uint, dest ...any) error {
+				require.Len(t, dest, 9)
+				switch i {
+				case 1:
+					*(dest[0].(*string)) = "reader"
+					*(dest[1].(*bool)) = true
+					*(dest[3].(*bool)) = false
+					*(dest[4].(*bool)) = false
+					*(dest[5].(*int)) = -1
+					*(dest[8].(*[]string)) = []string{}
+				case 2:
+					*(dest[0].(*string)) = "app"
+					*(dest[1].(*bool)) = true
+					*(dest[4].(*bool)) = true
+					*(dest[5].(*int)) = 10
+					*(dest[6].(*string)) = "2030-01-01 00:00:00+00"
+					*(dest[7].(**string)) = &password
+					*(dest[8].(*[]string)) = []string{"reader"}
+				}
+				return nil
+			},
+			ErrFn: func() error { return nil },
+		}
+	}
+
+	existingRows := func(names []string) *pgmocks.Rows {
+		return &pgmocks.Rows{
+			CloseFn: func() {},
+			NextFn:  func(i uint) bool { return int(i) <= len(names) },
+			ScanFn: func(i uint, dest ...any) error {
+				require.Len(t, dest, 1)
+				*(dest[0].(*string)) = names[i-1]
+				return nil
+			},
+			ErrFn: func() error { return nil },
+		}
+	}
+
+	readerStmt := `DO $$BEGIN CREATE ROLE "reader"; EXCEPTION WHEN duplicate_object THEN NULL; END$$;
+ALTER ROLE "reader" WITH INHERIT NOCREATEROLE NOCREATEDB NOLOGIN CONNECTION LIMIT -1;`
+	appStmt := `DO $$BEGIN CREATE ROLE "app"; EXCEPTION WHEN duplicate_object THEN NULL; END$$;
+ALTER ROLE "app" WITH INHERIT NOCREATEROLE NOCREATEDB LOGIN CONNECTION LIMIT 10 VALID UNTIL '2030-01-01 00:00:00+00';
+GRANT "reader" TO "app";`
+	appWithPasswordStmt := `DO $$BEGIN CREATE ROLE "app"; EXCEPTION WHEN duplicate_object THEN NULL; END$$;
+ALTER ROLE "app" WITH INHERIT NOCREATEROLE NOCREATEDB LOGIN CONNECTION LIMIT 10 VALID UNTIL '2030-01-01 00:00:00+00' PASSWORD 'SCRAM-SHA-256$4096:salt$key';
+GRANT "reader" TO "app";`
+
+	tests := []struct {
+		name          string
+		mode          string
+		applied       map[string]string
+		existingRoles []string
+		sourceErr     error
+		existingErr   error
+		targetErr     error
+
+		wantQuery   string
+		wantStmts   []string
+		wantApplied map[string]string
+		wantErr     error
+	}{
+		{
+			name: "ok - no passwords",
+			mode: RoleSyncModeNoPasswords,
+
+			wantQuery:   fmt.Sprintf(sourceRolesQuery, "NULL::text", "pg_roles"),
+			wantStmts:   []string{readerStmt, appStmt},
+			wantApplied: map[string]string{"reader": readerStmt, "app": appStmt},
+		},
+		{
+			name: "ok - with passwords",
+			mode: RoleSyncModeEnabled,
+
+			wantQuery:   fmt.Sprintf(sourceRolesQuery, "r.rolpassword", "pg_authid"),
+			wantStmts:   []string{readerStmt, appWithPasswordStmt},
+			wantApplied: map[string]string{"reader": readerStmt, "app": appWithPasswordStmt},
+		},
+		{
+			name:    "ok - unchanged roles are skipped",
+			mode:    RoleSyncModeNoPasswords,
+			applied: map[string]string{"reader": readerStmt},
+
+			wantQuery:   fmt.Sprintf(sourceRolesQuery, "NULL::text", "pg_roles"),
+			wantStmts:   []string{appStmt},
+			wantApplied: map[string]string{"reader": readerStmt, "app": appStmt},
+		},
+		{
+			name:    "ok - roles removed from the source are dropped",
+			mode:    RoleSyncModeNoPasswords,
+			applied: map[string]string{"reader": readerStmt, "app": appStmt, "old": "old stmt"},
+
+			wantQuery:   fmt.Sprintf(sourceRolesQuery, "NULL::text", "pg_roles"),
+			wantStmts:   []string{`DROP ROLE IF EXISTS "old"`},
+			wantApplied: map[string]string{"reader": readerStmt, "app": appStmt},
+		},
+		{
+			name:          "ok - roles that still exist on the source are not dropped",
+			mode:          RoleSyncModeNoPasswords,
+			applied:       map[string]string{"reader": readerStmt, "app": appStmt, "admin": "admin stmt"},
+			existingRoles: []string{"admin"},
+
+			wantQuery:   fmt.Sprintf(sourceRolesQuery, "NULL::text", "pg_roles"),
+			wantStmts:   []string{},
+			wantApplied: map[string]string{"reader": readerStmt, "app": appStmt, "admin": "admin stmt"},
+		},
+		{
+			name:        "error - getting existing source roles",
+			mode:        RoleSyncModeNoPasswords,
+			applied:     map[string]string{"reader": readerStmt, "app": appStmt, "old": "old stmt"},
+			existingErr: errTest,
+
+			wantQuery:   fmt.Sprintf(sourceRolesQuery, "NULL::text", "pg_roles"),
+			wantStmts:   []string{},
+			wantApplied: map[string]string{"reader": readerStmt, "app": appStmt, "old": "old stmt"},
+			wantErr:     errTest,
+		},
+		{
+			name:      "error - dropping target roles",
+			mode:      RoleSyncModeNoPasswords,
+			applied:   map[string]string{"reader": readerStmt, "app": appStmt, "old": "old stmt"},
+			targetErr: errTest,
+
+			wantQuery:   fmt.Sprintf(sourceRolesQuery, "NULL::text", "pg_roles"),
+			wantStmts:   []string{`DROP ROLE IF EXISTS "old"`},
+			wantApplied: map[string]string{"reader": readerStmt, "app": appStmt, "old": "old stmt"},
+			wantErr:     errTest,
+		},
+		{
+			name:      "error - getting source roles",
+			mode:      RoleSyncModeNoPasswords,
+			sourceErr: errTest,
+
+			wantQuery:   fmt.Sprintf(sourceRolesQuery, "NULL::text", "pg_roles"),
+			wantStmts:   []string{},
+			wantApplied: map[string]string{},
+			wantErr:     errTest,
+		},
+		{
+			name:      "error - syncing target roles",
+			mode:      RoleSyncModeNoPasswords,
+			targetErr: errTest,
+
+			wantQuery:   fmt.Sprintf(sourceRolesQuery, "NULL::text", "pg_roles"),
+			wantStmts:   []string{readerStmt, appStmt},
+			wantApplied: map[string]string{},
+			wantErr:     errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			stmts := []string{}
+			s := &roleSyncer{
+				logger: loglib.NewNoopLogger(),
+				sourceConn: &pgmocks.Querier{
+					QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+						if query == existingRolesQuery {
+							if tc.existingErr != nil {
+								return nil, tc.existingErr
+							}
+							return existingRows(tc.existingRoles), nil
+						}
+						require.Equal(t, tc.wantQuery, query)
+						if tc.sourceErr != nil {
+							return nil, tc.sourceErr
+						}
+						return sourceRows(), nil
+					},
+				},
+				targetConn: &pgmocks.Querier{
+					ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+						stmts = append(stmts, query)
+						return pglib.CommandTag{}, tc.targetErr
+					},
+				},
+				mode:    tc.mode,
+				applied: map[string]string{},
+			}
+			for k, v := range tc.applied {
+				s.applied[k] = v
+			}
+
+			err := s.sync(context.Background())
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantStmts, stmts)
+			require.Equal(t, tc.wantApplied, s.applied)
+		})
+	}
+}
+
+func TestReferencesRoles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		ddlEvent *wal.DDLEvent
+
+		wantReferences bool
+	}{
+		{
+			name:           "grant",
+			ddlEvent:       &wal.DDLEvent{CommandTag: "GRANT", DDL: "GRANT SELECT ON public.users TO reader"},
+			wantReferences: true,
+		},
+		{
+			name:           "alter default privileges",
+			ddlEvent:       &wal.DDLEvent{CommandTag: "ALTER DEFAULT PRIVILEGES", DDL: "ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT ON TABLES TO reader"},
+			wantReferences: true,
+		},
+		{
+			name:           "ownership change",
+			ddlEvent:       &wal.DDLEvent{CommandTag: "ALTER TABLE", DDL: "alter table public.users owner to app"},
+			wantReferences: true,
+		},
+		{
+			name:           "comment",
+			ddlEvent:       &wal.DDLEvent{CommandTag: "COMMENT", DDL: "COMMENT ON TABLE public.users IS 'owner to be defined'"},
+			wantReferences: false,
+		},
+		{
+			name:           "create table",
+			ddlEvent:       &wal.DDLEvent{CommandTag: "CREATE TABLE", DDL: "CREATE TABLE public.users (id int)"},
+			wantReferences: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.wantReferences, referencesRoles(tc.ddlEvent))
+		})
+	}
+}
+
+func TestRoleSyncConfig_validate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, (&RoleSyncConfig{}).validate())
+	require.NoError(t, (&RoleSyncConfig{Mode: RoleSyncModeEnabled}).validate())
+	require.ErrorIs(t, (&RoleSyncConfig{Mode: "disabled"}).validate(), errUnsupportedRoleSyncMode)
+}
@@ -5,6 +5,8 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	synclib "github.com/xataio/pgstream/internal/sync"
@@ -29,6 +31,9 @@ type pgSchemaObserver struct {
 	materializedViews *synclib.Map[string, map[string]struct{}]
 	// columnTableSequences is a map of schema.table to a map of sequence column names.
 	columnTableSequences *synclib.Map[string, map[string]string]
+	// foreignKeyTables is a map of schema.table to the set of tables linked
+	// to it by a foreign key, in either direction.
+	foreignKeyTables *synclib.Map[string, map[string]struct{}]
 }
 
 // newPGSchemaObserver returns a postgres observer that tracks schemas,
@@ -46,6 +51,7 @@ func newPGSchemaObserver(ctx context.Context, pgURL string, logger loglib.Logger
 		alwaysIdentityTableColumns: synclib.NewMap[string, map[string]struct{}](),
 		materializedViews:          synclib.NewMap[string, map[string]struct{}](),
 		columnTableSequences:       synclib.NewMap[string, map[string]string](),
+		foreignKeyTables:           synclib.NewMap[string, map[string]struct{}](),
 		logger:                     logger,
 	}, nil
 }
@@ -131,11 +137,51 @@ func (o *pgSchemaObserver) getSequenceColumns(ctx context.Context, schema, table
 	return seqColMap, nil
 }
 
+// getSequences returns the names of the sequences used by the columns of the
+// tables discovered so far.
+func (o *pgSchemaObserver) getSequences() []string {
+	sequenceSet := map[string]struct{}{}
+	for _, colSeqMap := range o.columnTableSequences.GetMap() {
+		for _, sequence := range colSeqMap {
+			sequenceSet[sequence] = struct{}{}
+		}
+	}
+	return slices.Sorted(maps.Keys(sequenceSet))
+}
+
+// getForeignKeyTables returns the tables linked by a foreign key to the
+// schema.table on input, either referencing it or referenced by it. If the
+// value is not in the internal cache, it will query postgres.
+func (o *pgSchemaObserver) getForeignKeyTables(ctx context.Context, schema, table string) (map[string]struct{}, error) {
+	key := pglib.QuoteQualifiedIdentifier(schema, table)
+	tables, found := o.foreignKeyTables.Get(key)
+	if found {
+		return tables, nil
+	}
+
+	tables, err := o.queryForeignKeyTables(ctx, schema, table)
+	if err != nil {
+		o.logger.Error(err, "querying foreign key tables from postgres", loglib.Fields{"schema": schema, "table": table})
+		return nil, err
+	}
+
+	o.foreignKeyTables.Set(key, tables)
+	return tables, nil
+}
+
 func (o *pgSchemaObserver) update(ddlEvent *wal.DDLEvent) {
 	if ddlEvent == nil {
 		return
 	}
 
+	// foreign keys change the tables on both of their sides, so the cache is
+	// refreshed on every DDL event affecting tables
+	if len(ddlEvent.GetTableObjects()) > 0 {
+		for key := range o.foreignKeyTables.GetMap() {
+			o.foreignKeyTables.Delete(key)
+		}
+	}
+
 	tableObjects := append(ddlEvent.GetTableObjects(), ddlEvent.GetTableColumnObjects()...)
 	o.updateGeneratedColumnNames(tableObjects)
 	o.updateColumnSequences(tableObjects)
@@ -207,7 +253,9 @@ func (o *pgSchemaObserver) updateColumnSequences(tables []wal.DDLObject) {
 		seqColMap := make(map[string]string)
 		for _, col := range table.Columns {
 			if col.HasSequence() {
-				seqColMap[pglib.QuoteIdentifier(col.Name)] = col.GetSequenceName()
+				// the sequence name is rendered by regclass, which leaves
+				// it unqualified when it's in the search path
+				seqColMap[pglib.QuoteIdentifier(col.Name)] = pglib.NormaliseQualifiedIdentifier(col.GetSequenceName(), table.Schema)
 			}
 		}
 		o.columnTableSequences.Set(key, seqColMap)
@@ -297,6 +345,34 @@ func (o *pgSchemaObserver) queryMaterializedViews(ctx context.Context, schemaNam
 	return mvNames, nil
 }
 
+const foreignKeyTablesQuery = `SELECT DISTINCT n.nspname, c.relname FROM pg_constraint con
+JOIN pg_class c ON c.oid IN (con.conrelid, con.confrelid)
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE con.contype = 'f' AND to_regclass($1) IN (con.conrelid, con.confrelid) AND c.oid <> to_regclass($1)`
+
+func (o *pgSchemaObserver) queryForeignKeyTables(ctx context.Context, schemaName, tableName string) (map[string]struct{}, error) {
+	rows, err := o.pgConn.Query(ctx, foreignKeyTablesQuery, pglib.QuoteQualifiedIdentifier(schemaName, tableName))
+	if err != nil {
+		return nil, fmt.Errorf("getting foreign key tables for table %s.%s: %w", schemaName, tableName, err)
+	}
+	defer rows.Close()
+
+	tables := make(map[string]struct{})
+	for rows.Next() {
+		var schema, table string
+		if err := rows.Scan(&schema, &table); err != nil {
+			return nil, fmt.Errorf("scanning foreign key table: %w", err)
+		}
+		tables[pglib.QuoteQualifiedIdentifier(schema, table)] = struct{}{}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
 const sequenceColumnQuery = `SELECT
     a.attname AS column_name,
     s.relname AS sequence_name
@@ -840,7 +840,7 @@ func TestPGSchemaObserver_updateColumnSequences(t *testing.T) {
 
 			columnTableSequences: map[string]map[string]string{},
 			wantColumnTableSequences: map[string]map[string]string{
-				`"test_schema"."test_table"`: {`"sequence_col"`: "seq"},
+				`"test_schema"."test_table"`: {`"sequence_col"`: `"test_schema"."seq"`},
 			},
 		},
 		{
@@ -862,7 +862,7 @@ func TestPGSchemaObserver_updateColumnSequences(t *testing.T) {
 								Attnum: 2, Name: "description", Type: "text", Nullable: true, Generated: false, Unique: false,
 							},
 							{
-								Attnum: 3, Name: "new_sequence_col", Type: "bigint", Nullable: false, Generated: false, Unique: false, Default: defaultVal("new_seq"),
+								Attnum: 3, Name: "new_sequence_col", Type: "bigint", Nullable: false, Generated: false, Unique: false, Default: defaultVal("test_schema.new_seq"),
 							},
 						},
 						PrimaryKeyColumns: []string{"name"},
@@ -873,7 +873,7 @@ func TestPGSchemaObserver_updateColumnSequences(t *testing.T) {
 				`"test_schema"."test_table"`: {`"old_id"`: "old_id_seq", `"old_sequence"`: "old_sequence_seq"},
 			},
 			wantColumnTableSequences: map[string]map[string]string{
-				`"test_schema"."test_table"`: {`"new_sequence_col"`: "new_seq"},
+				`"test_schema"."test_table"`: {`"new_sequence_col"`: `"test_schema"."new_seq"`},
 			},
 		},
 	}
@@ -1064,3 +1064,129 @@ func TestPGSchemaObserver_queryTableSequences(t *testing.T) {
 		})
 	}
 }
+
+func TestPGSchemaObserver_getSequences(t *testing.T) {
+	t.Parallel()
+
+	columnTableSequences := synclib.NewMap[string, map[string]string]()
+	columnTableSequences.Set(`"public"."users"`, map[string]string{`"id"`: `"public"."users_id_seq"`, `"seq"`: `"public"."shared_seq"`})
+	columnTableSequences.Set(`"public"."orders"`, map[string]string{`"id"`: `"public"."orders_id_seq"`, `"seq"`: `"public"."shared_seq"`})
+	columnTableSequences.Set(`"public"."tags"`, map[string]string{})
+
+	o := &pgSchemaObserver{columnTableSequences: columnTableSequences}
+	require.Equal(t, []string{`"public"."orders_id_seq"`, `"public"."shared_seq"`, `"public"."users_id_seq"`}, o.getSequences())
+}
+
+func TestPGSchemaObserver_getForeignKeyTables(t *testing.T) {
+	t.Parallel()
+
+	quotedQualifiedTableName := `"test_schema"."test_table"`
+	parentTable := `"test_schema"."parents"`
+
+	tests := []struct {
+		name             string
+		foreignKeyTables map[string]map[string]struct{}
+		pgConn           pglib.Querier
+
+		wantTables           map[string]struct{}
+		wantForeignKeyTables map[string]map[string]struct{}
+		wantErr              error
+	}{
+		{
+			name: "ok - cached",
+			foreignKeyTables: map[string]map[string]struct{}{
+				quotedQualifiedTableName: {parentTable: {}},
+			},
+			pgConn: &pgmocks.Querier{
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					return nil, errors.New("QueryFn: should not be called")
+				},
+			},
+
+			wantTables: map[string]struct{}{parentTable: {}},
+			wantForeignKeyTables: map[string]map[string]struct{}{
+				quotedQualifiedTableName: {parentTable: {}},
+			},
+		},
+		{
+			name:             "ok - not cached",
+			foreignKeyTables: map[string]map[string]struct{}{},
+			pgConn: &pgmocks.Querier{
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					require.Equal(t, foreignKeyTablesQuery, query)
+					require.Equal(t, []any{quotedQualifiedTableName}, args)
+					return &pgmocks.Rows{
+						CloseFn: func() {},
+						NextFn:  func(i uint) bool { return i == 1 },
+						ScanFn: func(_ uint, dest ...any) error {
+							require.Len(t, dest, 2)
+							*(dest[0].(*string)) = testSchema
+							*(dest[1].(*string)) = "parents"
+							return nil
+						},
+						ErrFn: func() error { return nil },
+					}, nil
+				},
+			},
+
+			wantTables: map[string]struct{}{parentTable: {}},
+			wantForeignKeyTables: map[string]map[string]struct{}{
+				quotedQualifiedTableName: {parentTable: {}},
+			},
+		},
+		{
+			name:             "error - querying foreign key tables",
+			foreignKeyTables: map[string]map[string]struct{}{},
+			pgConn: &pgmocks.Querier{
+				QueryFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.Rows, error) {
+					return nil, errTest
+				},
+			},
+
+			wantForeignKeyTables: map[string]map[string]struct{}{},
+			wantErr:              errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			o := &pgSchemaObserver{
+				logger:           loglib.NewNoopLogger(),
+				pgConn:           tc.pgConn,
+				foreignKeyTables: synclib.NewMapFromMap(tc.foreignKeyTables),
+			}
+
+			tables, err := o.getForeignKeyTables(context.Background(), testSchema, testTable)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantTables, tables)
+			require.Equal(t, tc.wantForeignKeyTables, o.foreignKeyTables.GetMap())
+		})
+	}
+}
+
+func TestPGSchemaObserver_update_foreignKeyTables(t *testing.T) {
+	t.Parallel()
+
+	o := &pgSchemaObserver{
+		logger:                     loglib.NewNoopLogger(),
+		generatedTableColumns:      synclib.NewMap[string, map[string]struct{}](),
+		alwaysIdentityTableColumns: synclib.NewMap[string, map[string]struct{}](),
+		materializedViews:          synclib.NewMap[string, map[string]struct{}](),
+		columnTableSequences:       synclib.NewMap[string, map[string]string](),
+		foreignKeyTables: synclib.NewMapFromMap(map[string]map[string]struct{}{
+			`"public"."orders"`: {`"public"."users"`: {}},
+			`"public"."users"`:  {`"public"."orders"`: {}},
+		}),
+	}
+
+	// a new foreign key changes the tables on both sides
+	o.update(&wal.DDLEvent{
+		DDL:        "ALTER TABLE public.items ADD CONSTRAINT items_order_fkey FOREIGN KEY (order_id) REFERENCES public.orders(id);",
+		SchemaName: "public",
+		CommandTag: "ALTER TABLE",
+		Objects:    []wal.DDLObject{{Type: "table", Identity: "public.items", Schema: "public"}},
+	})
+	require.Empty(t, o.foreignKeyTables.GetMap())
+}
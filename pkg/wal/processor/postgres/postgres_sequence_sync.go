@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	loglib "github.com/xataio/pgstream/pkg/log"
+)
+
+// sequenceSyncer periodically copies the values of the source sequences to
+// the target, since sequence advances are not part of the logical replication
+// stream. The sequences are synced one last time when the syncer is closed, so
+// that the target is up to date at cut-over.
+type sequenceSyncer struct {
+	logger      loglib.Logger
+	sourceConn  pglib.Querier
+	targetConn  pglib.Querier
+	margin      int64
+	interval    time.Duration
+	tableRouter *pglib.TableRouter
+	// sequencesFn returns the target sequences used by the replicated tables
+	sequencesFn func() []string
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+const (
+	defaultSequenceSyncInterval = time.Minute
+
+	sequenceLastValueQuery = `SELECT pg_sequence_last_value(to_regclass($1)), (SELECT seqincrement FROM pg_sequence WHERE seqrelid = to_regclass($1))`
+	// the target sequence is only moved forward in the direction of the
+	// sequence, since it can be ahead of the source one if it's been advanced
+	// by the replicated inserts
+	sequenceSetValueQuery = `SELECT setval(seq, $2::bigint, true) FROM (SELECT to_regclass($1) AS seq) s
+	WHERE seq IS NOT NULL AND (pg_sequence_last_value(seq) IS NULL OR
+	CASE WHEN $3::bigint > 0 THEN pg_sequence_last_value(seq) < $2::bigint ELSE pg_sequence_last_value(seq) > $2::bigint END)`
+	sourceSequencesQuery = `SELECT schemaname, sequencename FROM pg_sequences
+	WHERE schemaname NOT IN ('pg_catalog', 'information_schema', 'pgstream')
+	AND (cardinality($1::text[]) = 0 OR schemaname = ANY($1::text[]))
+	ORDER BY schemaname, sequencename`
+)
+
+func newSequenceSyncer(ctx context.Context, cfg *SequenceSyncConfig, targetConn pglib.Querier, tableRouter *pglib.TableRouter, sequencesFn func() []string, logger loglib.Logger) (*sequenceSyncer, error) {
+	sourceConn, err := pglib.NewConnPool(ctx, cfg.SourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("creating sequence sync source connection: %w", err)
+	}
+
+	s := &sequenceSyncer{
+		logger:      logger,
+		sourceConn:  sourceConn,
+		targetConn:  targetConn,
+		margin:      cfg.Margin,
+		interval:    cfg.Interval,
+		tableRouter: tableRouter,
+		sequencesFn: sequencesFn,
+	}
+	if s.interval <= 0 {
+		s.interval = defaultSequenceSyncInterval
+	}
+	return s, nil
+}
+
+// start syncs the sequences periodically in the background until the syncer
+// is closed.
+func (s *sequenceSyncer) start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.sync(ctx); err != nil && !errors.Is(err, context.Canceled) {
+					s.logger.Error(err, "syncing sequences")
+				}
+			}
+		}
+	}()
+}
+
+func (s *sequenceSyncer) sync(ctx context.Context) error {
+	sequences, err := s.replicatedSequences(ctx)
+	if err != nil {
+		return err
+	}
+
+	synced, err := syncSequences(ctx, s.sourceConn, s.targetConn, sequences, s.tableRouter, s.margin)
+	if synced > 0 {
+		s.logger.Debug("synced sequences", loglib.Fields{"sequences": synced})
+	}
+	return err
+}
+
+// replicatedSequences returns the source sequences whose target sequence is
+// used by the replicated tables. The sequences are discovered on the source,
+// since the target names can be different when the tables are routed.
+func (s *sequenceSyncer) replicatedSequences(ctx context.Context) ([]string, error) {
+	targetSequences := s.sequencesFn()
+	if len(targetSequences) == 0 {
+		return nil, nil
+	}
+
+	sourceSequences, err := querySourceSequences(ctx, s.sourceConn, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the target sequence names can come from the column defaults of the DDL
+	// events, which aren't quoted the same way as the source ones
+	targetSet := make(map[string]bool, len(targetSequences))
+	for _, sequence := range targetSequences {
+		targetSet[pglib.NormaliseQualifiedIdentifier(sequence, "")] = false
+	}
+
+	sequences := make([]string, 0, len(targetSequences))
+	for _, sequence := range sourceSequences {
+		targetSequence := pglib.NormaliseQualifiedIdentifier(s.tableRouter.RouteIdentity(sequence), "")
+		if _, found := targetSet[targetSequence]; found {
+			sequences = append(sequences, sequence)
+			targetSet[targetSequence] = true
+		}
+	}
+
+	for _, sequence := range targetSequences {
+		if !targetSet[pglib.NormaliseQualifiedIdentifier(sequence, "")] {
+			s.logger.Debug("no source sequence found for target sequence, skipping sync", loglib.Fields{"sequence": sequence})
+		}
+	}
+	return sequences, nil
+}
+
+func (s *sequenceSyncer) close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+
+	if err := s.sync(context.Background()); err != nil {
+		s.logger.Error(err, "syncing sequences on shutdown")
+	}
+	return s.sourceConn.Close(context.Background())
+}
+
+// SyncSequences copies the values of the sequences of the source database to
+// the target one, adding the configured safety margin. Only the sequences in
+// the schemas on input are synced, or all of them if none are provided. It
+// returns the number of target sequences updated.
+func SyncSequences(ctx context.Context, cfg *SequenceSyncConfig, targetURL string, schemas []string) (int, error) {
+	sourceConn, err := pglib.NewConnPool(ctx, cfg.SourceURL)
+	if err != nil {
+		return 0, err
+	}
+	defer sourceConn.Close(ctx)
+
+	targetConn, err := pglib.NewConnPool(ctx, targetURL)
+	if err != nil {
+		return 0, err
+	}
+	defer targetConn.Close(ctx)
+
+	sequences, err := querySourceSequences(ctx, sourceConn, schemas)
+	if err != nil {
+		return 0, err
+	}
+
+	return syncSequences(ctx, sourceConn, targetConn, sequences, nil, cfg.Margin)
+}
+
+func querySourceSequences(ctx context.Context, conn pglib.Querier, schemas []string) ([]string, error) {
+	if schemas == nil {
+		schemas = []string{}
+	}
+	rows, err := conn.Query(ctx, sourceSequencesQuery, schemas)
+	if err != nil {
+		return nil, fmt.Errorf("getting source sequences: %w", err)
+	}
+	defer rows.Close()
+
+	sequences := []string{}
+	for rows.Next() {
+		var schema, sequence string
+		if err := rows.Scan(&schema, &sequence); err != nil {
+			return nil, fmt.Errorf("scanning source sequence: %w", err)
+		}
+		sequences = append(sequences, pglib.QuoteQualifiedIdentifier(schema, sequence))
+	}
+	return sequences, rows.Err()
+}
+
+// syncSequences sets the target sequences for the source sequences on input,
+// routed by the table router if any, to the value of the source ones plus the
+// margin, unless they're already ahead. Descending sequences have the margin
+// subtracted instead, and are ahead when their value is lower. Sequences that
+// don't exist or have never been used on the source are ignored. Errors
+// syncing a sequence don't prevent the rest from being synced.
+func syncSequences(ctx context.Context, sourceConn, targetConn pglib.Querier, sequences []string, tableRouter *pglib.TableRouter, margin int64) (int, error) {
+	synced := 0
+	var errs error
+	for _, sequence := range sequences {
+		var lastValue, increment *int64
+		if err := sourceConn.QueryRow(ctx, []any{&lastValue, &increment}, sequenceLastValueQuery, sequence); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("getting source sequence %s value: %w", sequence, err))
+			continue
+		}
+		if lastValue == nil || increment == nil {
+			continue
+		}
+
+		// descending sequences are moved forward by decreasing their value
+		value := *lastValue + margin
+		if *increment < 0 {
+			value = *lastValue - margin
+		}
+		targetSequence := tableRouter.RouteIdentity(sequence)
+		tag, err := targetConn.Exec(ctx, sequenceSetValueQuery, targetSequence, value, *increment)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("setting target sequence %s value: %w", targetSequence, err))
+			continue
+		}
+		synced += int(tag.RowsAffected())
+	}
+	return synced, errs
+}
@@ -0,0 +1,252 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	loglib "github.com/xataio/pgstream/pkg/log"
+)
+
+func TestSyncSequences(t *testing.T) {
+	t.Parallel()
+
+	int64Ptr := func(i int64) *int64 { return &i }
+
+	type sourceSequence struct {
+		lastValue *int64
+		increment *int64
+	}
+	sourceValues := map[string]sourceSequence{
+		`"public"."users_id_seq"`:  {lastValue: int64Ptr(100), increment: int64Ptr(1)},
+		`"public"."orders_id_seq"`: {increment: int64Ptr(1)},
+		`"public"."tags_id_seq"`:   {lastValue: int64Ptr(5), increment: int64Ptr(1)},
+		`"public"."desc_seq"`:      {lastValue: int64Ptr(-100), increment: int64Ptr(-1)},
+		`"public"."missing_seq"`:   {},
+	}
+
+	tests := []struct {
+		name      string
+		sequences []string
+		routes    map[string]string
+		sourceErr error
+		targetErr error
+
+		wantSetValues  map[string]int64
+		wantIncrements map[string]int64
+		wantSynced     int
+		wantErr        error
+	}{
+		{
+			name:      "ok",
+			sequences: []string{`"public"."users_id_seq"`, `"public"."orders_id_seq"`, `"public"."tags_id_seq"`},
+
+			wantSetValues: map[string]int64{
+				`"public"."users_id_seq"`: 1100,
+				`"public"."tags_id_seq"`:  1005,
+			},
+			wantSynced: 2,
+		},
+		{
+			name:      "ok - descending sequence",
+			sequences: []string{`"public"."desc_seq"`, `"public"."missing_seq"`},
+
+			wantSetValues: map[string]int64{
+				`"public"."desc_seq"`: -1100,
+			},
+			wantIncrements: map[string]int64{
+				`"public"."desc_seq"`: -1,
+			},
+			wantSynced: 1,
+		},
+		{
+			name:      "ok - routed sequences",
+			sequences: []string{`"public"."users_id_seq"`, `"public"."tags_id_seq"`},
+			routes:    map[string]string{"public.*": "replica.*"},
+
+			wantSetValues: map[string]int64{
+				`"replica"."users_id_seq"`: 1100,
+				`"replica"."tags_id_seq"`:  1005,
+			},
+			wantSynced: 2,
+		},
+		{
+			name:      "no sequences",
+			sequences: nil,
+
+			wantSetValues: map[string]int64{},
+			wantSynced:    0,
+		},
+		{
+			name:      "error - getting source value",
+			sequences: []string{`"public"."users_id_seq"`, `"public"."tags_id_seq"`},
+			sourceErr: errTest,
+
+			wantSetValues: map[string]int64{},
+			wantSynced:    0,
+			wantErr:       errTest,
+		},
+		{
+			name:      "error - setting target value",
+			sequences: []string{`"public"."users_id_seq"`, `"public"."tags_id_seq"`},
+			targetErr: errTest,
+
+			wantSetValues: map[string]int64{
+				`"public"."users_id_seq"`: 1100,
+				`"public"."tags_id_seq"`:  1005,
+			},
+			wantSynced: 0,
+			wantErr:    errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sourceConn := &pgmocks.Querier{
+				QueryRowFn: func(ctx context.Context, dest []any, query string, args ...any) error {
+					require.Equal(t, sequenceLastValueQuery, query)
+					require.Len(t, args, 1)
+					if tc.sourceErr != nil {
+						return tc.sourceErr
+					}
+					val, ok := sourceValues[args[0].(string)]
+					require.True(t, ok)
+					*(dest[0].(**int64)) = val.lastValue
+					*(dest[1].(**int64)) = val.increment
+					return nil
+				},
+			}
+
+			setValues := map[string]int64{}
+			increments := map[string]int64{}
+			targetConn := &pgmocks.Querier{
+				ExecFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.CommandTag, error) {
+					require.Equal(t, sequenceSetValueQuery, query)
+					require.Len(t, args, 3)
+					setValues[args[0].(string)] = args[1].(int64)
+					if increment := args[2].(int64); increment != 1 {
+						increments[args[0].(string)] = increment
+					}
+					if tc.targetErr != nil {
+						return pglib.CommandTag{}, tc.targetErr
+					}
+					return pglib.CommandTag{CommandTag: pgconn.NewCommandTag("SELECT 1")}, nil
+				},
+			}
+
+			tableRouter, err := pglib.NewTableRouter(pglib.TableRoutesFromMap(tc.routes))
+			require.NoError(t, err)
+
+			synced, err := syncSequences(context.Background(), sourceConn, targetConn, tc.sequences, tableRouter, 1000)
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantSynced, synced)
+			require.Equal(t, tc.wantSetValues, setValues)
+			if tc.wantIncrements == nil {
+				tc.wantIncrements = map[string]int64{}
+			}
+			require.Equal(t, tc.wantIncrements, increments)
+		})
+	}
+}
+
+func TestSequenceSyncer_replicatedSequences(t *testing.T) {
+	t.Parallel()
+
+	sourceSequences := [][]string{
+		{"public", "users_id_seq"},
+		{"public", "orders_id_seq"},
+		{"other", "items_id_seq"},
+	}
+
+	tests := []struct {
+		name            string
+		targetSequences []string
+		routes          map[string]string
+		queryErr        error
+
+		wantSequences []string
+		wantErr       error
+	}{
+		{
+			name:            "ok",
+			targetSequences: []string{`"public"."users_id_seq"`, `"other"."items_id_seq"`},
+
+			wantSequences: []string{`"public"."users_id_seq"`, `"other"."items_id_seq"`},
+		},
+		{
+			name:            "ok - routed sequences",
+			targetSequences: []string{`"replica"."users_id_seq"`, `"other"."items_id_seq"`},
+			routes:          map[string]string{"public.*": "replica.*"},
+
+			wantSequences: []string{`"public"."users_id_seq"`, `"other"."items_id_seq"`},
+		},
+		{
+			name:            "ok - unquoted target sequences",
+			targetSequences: []string{"public.users_id_seq", `other."items_id_seq"`},
+
+			wantSequences: []string{`"public"."users_id_seq"`, `"other"."items_id_seq"`},
+		},
+		{
+			name:            "ok - target sequence without source sequence",
+			targetSequences: []string{`"public"."users_id_seq"`, `"public"."unknown_seq"`},
+
+			wantSequences: []string{`"public"."users_id_seq"`},
+		},
+		{
+			name: "ok - no target sequences",
+
+			wantSequences: nil,
+		},
+		{
+			name:            "error - querying source sequences",
+			targetSequences: []string{`"public"."users_id_seq"`},
+			queryErr:        errTest,
+
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tableRouter, err := pglib.NewTableRouter(pglib.TableRoutesFromMap(tc.routes))
+			require.NoError(t, err)
+
+			s := &sequenceSyncer{
+				logger: loglib.NewNoopLogger(),
+				sourceConn: &pgmocks.Querier{
+					QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+						require.Equal(t, sourceSequencesQuery, query)
+						if tc.queryErr != nil {
+							return nil, tc.queryErr
+						}
+						return &pgmocks.Rows{
+							CloseFn: func() {},
+							NextFn:  func(n uint) bool { return int(n) <= len(sourceSequences) },
+							ScanFn: func(n uint, dest ...any) error {
+								*(dest[0].(*string)) = sourceSequences[n-1][0]
+								*(dest[1].(*string)) = sourceSequences[n-1][1]
+								return nil
+							},
+							ErrFn: func() error { return nil },
+						}, nil
+					},
+				},
+				tableRouter: tableRouter,
+				sequencesFn: func() []string { return tc.targetSequences },
+			}
+
+			sequences, err := s.replicatedSequences(context.Background())
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantSequences, sequences)
+		})
+	}
+}
@@ -5,6 +5,7 @@ package postgres
 import (
 	"context"
 
+	pglib "github.com/xataio/pgstream/internal/postgres"
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/wal"
 )
@@ -19,6 +20,8 @@ type schemaObserver interface {
 	getGeneratedColumnNames(ctx context.Context, schema, table string) (map[string]struct{}, error)
 	getAlwaysIdentityColumnNames(ctx context.Context, schema, table string) (map[string]struct{}, error)
 	getSequenceColumns(ctx context.Context, schema, table string) (map[string]string, error)
+	getSequences() []string
+	getForeignKeyTables(ctx context.Context, schema, table string) (map[string]struct{}, error)
 	isMaterializedView(ctx context.Context, schema, table string) bool
 	update(ddlEvent *wal.DDLEvent)
 	close() error
@@ -44,26 +47,27 @@ type adapter struct {
 	ddlEventAdapter ddlEventAdapter
 
 	schemaObserver schemaObserver
+	tableRouter    *pglib.TableRouter
 }
 
 type (
 	ddlEventAdapter func(*wal.Data) (*wal.DDLEvent, error)
 )
 
-func newAdapter(ctx context.Context, logger loglib.Logger, ignoreDDL bool, pgURL string, onConflictAction string, forCopy bool) (*adapter, error) {
+func newAdapter(ctx context.Context, logger loglib.Logger, ignoreDDL, concurrentDDL bool, pgURL string, onConflictAction, applyMode string, forCopy bool, tableRouter *pglib.TableRouter) (*adapter, error) {
 	schemaObserver, err := newPGSchemaObserver(ctx, pgURL, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	dmlAdapter, err := newDMLAdapter(onConflictAction, forCopy, logger)
+	dmlAdapter, err := newDMLAdapter(onConflictAction, applyMode, "", forCopy, logger)
 	if err != nil {
 		return nil, err
 	}
 
 	var ddl ddlQueryAdapter
 	if !ignoreDDL {
-		ddl = newDDLAdapter()
+		ddl = newDDLAdapter(tableRouter, dmlAdapter.applyMode, concurrentDDL)
 	}
 
 	return &adapter{
@@ -71,10 +75,12 @@ func newAdapter(ctx context.Context, logger loglib.Logger, ignoreDDL bool, pgURL
 		ddlAdapter:      ddl,
 		schemaObserver:  schemaObserver,
 		ddlEventAdapter: wal.WalDataToDDLEvent,
+		tableRouter:     tableRouter,
 	}, nil
 }
 
 func (a *adapter) walEventToQueries(ctx context.Context, e *wal.Event) ([]*query, error) {
+	e = a.routeEvent(e)
 	switch {
 	case e.Data == nil,
 		a.schemaObserver.isMaterializedView(ctx, e.Data.Schema, e.Data.Table):
@@ -86,7 +92,7 @@ func (a *adapter) walEventToQueries(ctx context.Context, e *wal.Event) ([]*query
 		if err != nil {
 			return nil, err
 		}
-		a.schemaObserver.update(ddlEvent)
+		a.schemaObserver.update(routeDDLEvent(a.tableRouter, ddlEvent))
 
 		// there's no ddl adapter, the ddl query will not be processed
 		if a.ddlAdapter == nil {
@@ -125,6 +131,7 @@ func (a *adapter) walEventToQueries(ctx context.Context, e *wal.Event) ([]*query
 }
 
 func (a *adapter) walEventToMessage(ctx context.Context, e *wal.Event) (*walMessage, error) {
+	e = a.routeEvent(e)
 	switch {
 	case e.Data == nil,
 		a.schemaObserver.isMaterializedView(ctx, e.Data.Schema, e.Data.Table):
@@ -135,7 +142,7 @@ func (a *adapter) walEventToMessage(ctx context.Context, e *wal.Event) (*walMess
 		if err != nil {
 			return nil, err
 		}
-		a.schemaObserver.update(ddlEvent)
+		a.schemaObserver.update(routeDDLEvent(a.tableRouter, ddlEvent))
 
 		if a.ddlAdapter == nil {
 			return &walMessage{}, nil
@@ -170,6 +177,27 @@ func (a *adapter) walEventToMessage(ctx context.Context, e *wal.Event) (*walMess
 	}
 }
 
+// routeEvent returns a copy of the DML event on input with the schema and
+// table names routed to their target names. DDL events are returned as they
+// are, since the routing of the DDL statement is done by the DDL adapter.
+func (a *adapter) routeEvent(e *wal.Event) *wal.Event {
+	if a.tableRouter == nil || e.Data == nil || e.Data.IsDDLEvent() {
+		return e
+	}
+
+	schema, table := a.tableRouter.Route(e.Data.Schema, e.Data.Table)
+	if schema == e.Data.Schema && table == e.Data.Table {
+		return e
+	}
+
+	data := *e.Data
+	data.Schema = schema
+	data.Table = table
+	event := *e
+	event.Data = &data
+	return &event
+}
+
 func (a *adapter) close() error {
 	return a.schemaObserver.close()
 }
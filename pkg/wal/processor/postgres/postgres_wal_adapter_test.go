@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
 	"github.com/xataio/pgstream/pkg/wal"
 )
 
@@ -464,3 +465,42 @@ func TestAdapter_walEventToQueries(t *testing.T) {
 		})
 	}
 }
+
+func TestAdapter_walEventToMessage_tableRouting(t *testing.T) {
+	t.Parallel()
+
+	router, err := pglib.NewTableRouter([]pglib.TableRoute{
+		{Source: "public.*", Target: "replica_public.*"},
+	})
+	require.NoError(t, err)
+
+	event := &wal.Event{
+		Data: &wal.Data{
+			Schema: "public",
+			Table:  "users",
+			Action: "I",
+		},
+	}
+
+	a := adapter{
+		schemaObserver: &mockSchemaObserver{
+			isMaterializedViewFn: func(schema, table string) bool { return false },
+			getGeneratedColumnNamesFn: func(ctx context.Context, schema, table string) (map[string]struct{}, error) {
+				require.Equal(t, "replica_public", schema)
+				require.Equal(t, "users", table)
+				return map[string]struct{}{}, nil
+			},
+			getSequenceColumnsFn: func(ctx context.Context, schema, table string) (map[string]string, error) {
+				return map[string]string{}, nil
+			},
+		},
+		tableRouter: router,
+	}
+
+	msg, err := a.walEventToMessage(context.Background(), event)
+	require.NoError(t, err)
+	require.Equal(t, "replica_public", msg.data.Schema)
+	require.Equal(t, "users", msg.data.Table)
+	// the original event is not modified
+	require.Equal(t, "public", event.Data.Schema)
+}
@@ -4,14 +4,28 @@ package postgres
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 
+	pglib "github.com/xataio/pgstream/internal/postgres"
 	"github.com/xataio/pgstream/pkg/wal"
 )
 
-type ddlAdapter struct{}
+type ddlAdapter struct {
+	tableRouter *pglib.TableRouter
+	applyMode   applyMode
+	// run index builds and drops and partition detaches concurrently, to
+	// avoid locking the target tables
+	concurrentDDL bool
+}
 
-func newDDLAdapter() *ddlAdapter {
-	return &ddlAdapter{}
+func newDDLAdapter(tableRouter *pglib.TableRouter, applyMode applyMode, concurrentDDL bool) *ddlAdapter {
+	return &ddlAdapter{
+		tableRouter:   tableRouter,
+		applyMode:     applyMode,
+		concurrentDDL: concurrentDDL,
+	}
 }
 
 func (a *ddlAdapter) walDataToQueries(ctx context.Context, d *wal.Data) ([]*query, error) {
@@ -20,15 +34,80 @@ func (a *ddlAdapter) walDataToQueries(ctx context.Context, d *wal.Data) ([]*quer
 		return nil, err
 	}
 
+	switch {
+	case ddlEvent.Merged:
+		return a.reconcileDDLEventQueries(routeDDLEvent(a.tableRouter, ddlEvent)), nil
+	case a.applyMode == applyModeHistory && len(ddlEvent.GetTableObjects()) > 0 && !isTableMetadataDDL(ddlEvent):
+		// history tables don't mirror the source table constraints, since
+		// they hold multiple versions of each row
+		return a.reconcileDDLEventQueries(routeDDLEvent(a.tableRouter, ddlEvent)), nil
+	case a.applyMode == applyModeHistory && isUniqueIndexDDL(ddlEvent):
+		// unique indexes would reject the row versions
+		return []*query{}, nil
+	}
+
+	schemaName := ddlEvent.SchemaName
 	tableName := ""
 	tableObjects := ddlEvent.GetTableObjects()
 	if len(tableObjects) > 0 {
 		tableName = tableObjects[0].GetTable()
 	}
 
-	return []*query{
-		a.newDDLQuery(ddlEvent.SchemaName, tableName, ddlEvent.DDL),
-	}, nil
+	sql := ddlEvent.DDL
+	searchPath := ""
+	if a.tableRouter != nil {
+		sql = a.tableRouter.RewriteSQLInSchema(sql, schemaName)
+		// unqualified names in the DDL are resolved using the search path, so
+		// make sure it points to the routed schema for the duration of the
+		// statement.
+		if routedSchema := a.tableRouter.RouteSchema(schemaName); routedSchema != schemaName {
+			searchPath = routedSchema
+		}
+		if tableName != "" {
+			schemaName, tableName = a.tableRouter.Route(schemaName, pglib.UnquoteIdentifier(tableName))
+		} else {
+			schemaName = a.tableRouter.RouteSchema(schemaName)
+		}
+	}
+
+	// setting the search path runs the statement in an implicit transaction
+	// block, so it can't run concurrently
+	sql = a.rewriteDDL(ddlEvent, sql, searchPath != "")
+	if searchPath != "" {
+		sql = fmt.Sprintf("SET LOCAL search_path TO %s;\n%s", pglib.QuoteIdentifier(searchPath), sql)
+	}
+
+	queries := []*query{
+		a.newDDLQuery(schemaName, tableName, sql),
+	}
+
+	// make sure the tables have the columns required by the apply mode
+	if modeColumns := a.applyMode.columns(); len(modeColumns) > 0 && !ddlEvent.IsDropEvent() && !isTableMetadataDDL(ddlEvent) {
+		for _, obj := range routeDDLEvent(a.tableRouter, ddlEvent).GetTableObjects() {
+			schema := obj.Schema
+			table := pglib.UnquoteIdentifier(obj.GetTable())
+			queries = append(queries, a.newDDLQuery(schema, table, addColumnsDDL(quotedTableName(schema, table), modeColumns)))
+		}
+	}
+
+	return queries, nil
+}
+
+// tableMetadataDDLTags are the DDL commands that don't change the definition of
+// the tables they apply to, so they're replayed as is in all the apply modes.
+var tableMetadataDDLTags = []string{"COMMENT", "GRANT", "REVOKE"}
+
+func isTableMetadataDDL(ddlEvent *wal.DDLEvent) bool {
+	return slices.Contains(tableMetadataDDLTags, ddlEvent.CommandTag)
+}
+
+// isUniqueIndexDDL returns true if the DDL event creates a unique index.
+func isUniqueIndexDDL(ddlEvent *wal.DDLEvent) bool {
+	if ddlEvent.CommandTag != "CREATE INDEX" {
+		return false
+	}
+	m := createIndexRegex.FindStringSubmatch(ddlEvent.DDL)
+	return m != nil && uniqueIndexRegex.MatchString(m[1])
 }
 
 func (a *ddlAdapter) newDDLQuery(schema, table, sql string) *query {
@@ -39,3 +118,107 @@ func (a *ddlAdapter) newDDLQuery(schema, table, sql string) *query {
 		isDDL:  true,
 	}
 }
+
+// reconcileDDLEventQueries reconciles the target tables affected by a DDL
+// event that can't be replayed as is, either because the target table merges
+// multiple source tables, or because the apply mode keeps rows that no longer
+// exist on the source. The changes are applied additively and idempotently:
+// new tables and columns are created if they don't exist yet, while drops and
+// type changes are not applied, since they would affect the rows the source
+// no longer tracks.
+func (a *ddlAdapter) reconcileDDLEventQueries(ddlEvent *wal.DDLEvent) []*query {
+	if ddlEvent.IsDropEvent() {
+		return []*query{}
+	}
+
+	queries := []*query{}
+	for _, obj := range ddlEvent.GetTableObjects() {
+		if len(obj.Columns) == 0 {
+			continue
+		}
+		columns := append(slices.Clone(obj.Columns), a.applyMode.columns()...)
+		primaryKeyColumns := obj.PrimaryKeyColumns
+		if a.applyMode == applyModeHistory {
+			primaryKeyColumns = nil
+		}
+		schema := obj.Schema
+		table := pglib.UnquoteIdentifier(obj.GetTable())
+		queries = append(queries, a.newDDLQuery(schema, table, reconcileTableDDL(quotedTableName(schema, table), columns, primaryKeyColumns)))
+	}
+	return queries
+}
+
+func reconcileTableDDL(tableName string, columns []wal.DDLColumn, primaryKeyColumns []string) string {
+	createColumns := make([]string, 0, len(columns)+1)
+	for _, col := range columns {
+		colDef := columnDDL(col)
+		if !col.Nullable || slices.Contains(primaryKeyColumns, col.Name) {
+			colDef = fmt.Sprintf("%s NOT NULL", colDef)
+		}
+		createColumns = append(createColumns, colDef)
+	}
+	if len(primaryKeyColumns) > 0 {
+		pkColumns := make([]string, 0, len(primaryKeyColumns))
+		for _, col := range primaryKeyColumns {
+			pkColumns = append(pkColumns, pglib.QuoteIdentifier(col))
+		}
+		createColumns = append(createColumns, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pkColumns, ", ")))
+	}
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);\n%s",
+		tableName, strings.Join(createColumns, ", "), addColumnsDDL(tableName, columns))
+}
+
+// addColumnsDDL returns the DDL to add the columns on input to the table if
+// they don't exist yet. The columns are added as nullable, since the existing
+// rows won't have a value for them.
+func addColumnsDDL(tableName string, columns []wal.DDLColumn) string {
+	addColumns := make([]string, 0, len(columns))
+	for _, col := range columns {
+		addColumns = append(addColumns, fmt.Sprintf("ADD COLUMN IF NOT EXISTS %s", columnDDL(col)))
+	}
+	return fmt.Sprintf("ALTER TABLE %s %s;", tableName, strings.Join(addColumns, ", "))
+}
+
+func columnDDL(col wal.DDLColumn) string {
+	colDef := fmt.Sprintf("%s %s", pglib.QuoteIdentifier(col.Name), col.Type)
+	// sequences are not replicated for reconciled tables, values are always
+	// provided by the source rows
+	if col.Default != nil && !strings.Contains(*col.Default, "nextval(") {
+		colDef = fmt.Sprintf("%s DEFAULT %s", colDef, *col.Default)
+	}
+	return colDef
+}
+
+// routeDDLEvent returns a copy of the DDL event on input with the affected
+// objects routed to their target names, so that any state derived from them
+// refers to the target tables.
+func routeDDLEvent(router *pglib.TableRouter, ddlEvent *wal.DDLEvent) *wal.DDLEvent {
+	if router == nil || ddlEvent == nil {
+		return ddlEvent
+	}
+
+	routed := *ddlEvent
+	routed.SchemaName = router.RouteSchema(ddlEvent.SchemaName)
+	routed.Objects = make([]wal.DDLObject, 0, len(ddlEvent.Objects))
+	for _, obj := range ddlEvent.Objects {
+		routedObj := obj
+		routedObj.Identity = router.RouteIdentity(obj.Identity)
+		if routedObj.Identity != obj.Identity {
+			routedObj.Schema = pglib.UnquoteIdentifier(routedObj.GetSchema())
+		} else {
+			routedObj.Schema = router.RouteSchema(obj.Schema)
+		}
+		routedObj.Columns = make([]wal.DDLColumn, 0, len(obj.Columns))
+		for _, col := range obj.Columns {
+			if col.Default != nil {
+				// column defaults can reference sequences by name
+				def := router.RewriteSQL(*col.Default)
+				col.Default = &def
+			}
+			routedObj.Columns = append(routedObj.Columns, col)
+		}
+		routed.Objects = append(routed.Objects, routedObj)
+	}
+	return &routed
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
 	"github.com/xataio/pgstream/pkg/wal"
 )
 
@@ -120,7 +121,7 @@ func TestDDLAdapter_walDataToQueries(t *testing.T) {
 				{
 					schema: "public",
 					table:  "",
-					sql:    "CREATE INDEX idx_test ON public.test_table(name);",
+					sql:    "CREATE INDEX IF NOT EXISTS idx_test ON public.test_table(name);",
 					isDDL:  true,
 				},
 			},
@@ -216,10 +217,228 @@ func TestDDLAdapter_walDataToQueries(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			adapter := newDDLAdapter()
+			adapter := newDDLAdapter(nil, applyModeMirror, false)
 			queries, err := adapter.walDataToQueries(context.Background(), tc.walData)
 			require.ErrorIs(t, err, tc.wantErr)
 			require.ElementsMatch(t, queries, tc.wantQueries)
 		})
 	}
 }
+
+func TestDDLAdapter_walDataToQueries_tableRouting(t *testing.T) {
+	t.Parallel()
+
+	router, err := pglib.NewTableRouter([]pglib.TableRoute{
+		{Source: "public.*", Target: "replica_public.*"},
+		{Source: "sales.orders", Target: "sales.orders_v2"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		walData *wal.Data
+
+		wantQueries []*query
+	}{
+		{
+			name: "schema route",
+			walData: &wal.Data{
+				Action: wal.LogicalMessageAction,
+				Prefix: wal.DDLPrefix,
+				Content: `{
+					"ddl": "ALTER TABLE public.test_table ADD COLUMN email text;",
+					"schema_name": "public",
+					"command_tag": "ALTER TABLE",
+					"objects": [{"type": "table", "identity": "public.test_table", "schema": "public"}]
+				}`,
+			},
+			wantQueries: []*query{
+				{
+					schema: "replica_public",
+					table:  "test_table",
+					sql:    "SET LOCAL search_path TO \"replica_public\";\nALTER TABLE \"replica_public\".\"test_table\" ADD COLUMN email text;",
+					isDDL:  true,
+				},
+			},
+		},
+		{
+			name: "table route",
+			walData: &wal.Data{
+				Action: wal.LogicalMessageAction,
+				Prefix: wal.DDLPrefix,
+				Content: `{
+					"ddl": "ALTER TABLE sales.orders ADD COLUMN total numeric;",
+					"schema_name": "sales",
+					"command_tag": "ALTER TABLE",
+					"objects": [{"type": "table", "identity": "sales.orders", "schema": "sales"}]
+				}`,
+			},
+			wantQueries: []*query{
+				{
+					schema: "sales",
+					table:  "orders_v2",
+					sql:    `ALTER TABLE "sales"."orders_v2" ADD COLUMN total numeric;`,
+					isDDL:  true,
+				},
+			},
+		},
+		{
+			name: "unqualified table route",
+			walData: &wal.Data{
+				Action: wal.LogicalMessageAction,
+				Prefix: wal.DDLPrefix,
+				Content: `{
+					"ddl": "ALTER TABLE orders ADD COLUMN total numeric;",
+					"schema_name": "sales",
+					"command_tag": "ALTER TABLE",
+					"objects": [{"type": "table", "identity": "sales.orders", "schema": "sales"}]
+				}`,
+			},
+			wantQueries: []*query{
+				{
+					schema: "sales",
+					table:  "orders_v2",
+					sql:    `ALTER TABLE "sales"."orders_v2" ADD COLUMN total numeric;`,
+					isDDL:  true,
+				},
+			},
+		},
+		{
+			name: "no route",
+			walData: &wal.Data{
+				Action: wal.LogicalMessageAction,
+				Prefix: wal.DDLPrefix,
+				Content: `{
+					"ddl": "CREATE TABLE other.t (id int);",
+					"schema_name": "other",
+					"command_tag": "CREATE TABLE",
+					"objects": [{"type": "table", "identity": "other.t", "schema": "other"}]
+				}`,
+			},
+			wantQueries: []*query{
+				{
+					schema: "other",
+					table:  "t",
+					sql:    "CREATE TABLE other.t (id int);",
+					isDDL:  true,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			adapter := newDDLAdapter(router, applyModeMirror, false)
+			queries, err := adapter.walDataToQueries(context.Background(), tc.walData)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantQueries, queries)
+		})
+	}
+}
+
+func Test_routeDDLEvent(t *testing.T) {
+	t.Parallel()
+
+	router, err := pglib.NewTableRouter([]pglib.TableRoute{
+		{Source: "public.*", Target: "replica_public.*"},
+	})
+	require.NoError(t, err)
+
+	seqDefault := "nextval('public.users_id_seq'::regclass)"
+	ddlEvent := &wal.DDLEvent{
+		SchemaName: "public",
+		Objects: []wal.DDLObject{
+			{
+				Type:     "table",
+				Identity: "public.users",
+				Schema:   "public",
+				Columns: []wal.DDLColumn{
+					{Name: "id", Default: &seqDefault},
+				},
+			},
+		},
+	}
+
+	routed := routeDDLEvent(router, ddlEvent)
+	require.Equal(t, "replica_public", routed.SchemaName)
+	require.Len(t, routed.Objects, 1)
+	require.Equal(t, "replica_public", routed.Objects[0].Schema)
+	require.Equal(t, `"replica_public"."users"`, routed.Objects[0].Identity)
+	require.Equal(t, `"replica_public"."users_id_seq"`, routed.Objects[0].Columns[0].GetSequenceName())
+	// the original event is not modified
+	require.Equal(t, "public.users", ddlEvent.Objects[0].Identity)
+	require.Equal(t, "nextval('public.users_id_seq'::regclass)", *ddlEvent.Objects[0].Columns[0].Default)
+
+	require.Equal(t, ddlEvent, routeDDLEvent(nil, ddlEvent))
+}
+
+func TestDDLAdapter_walDataToQueries_mergedTables(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		walData *wal.Data
+
+		wantQueries []*query
+	}{
+		{
+			name: "create merged table",
+			walData: &wal.Data{
+				Action: wal.LogicalMessageAction,
+				Prefix: wal.DDLPrefix,
+				Content: `{
+					"ddl": "CREATE TABLE tenant_a.orders (id serial PRIMARY KEY, total numeric DEFAULT 0);",
+					"schema_name": "public",
+					"command_tag": "CREATE TABLE",
+					"merged": true,
+					"objects": [{
+						"type": "table", "identity": "\"public\".\"orders\"", "schema": "public",
+						"columns": [
+							{"name": "tenant", "type": "text", "nullable": false},
+							{"attnum": 1, "name": "id", "type": "integer", "nullable": false, "default": "nextval('tenant_a.orders_id_seq'::regclass)"},
+							{"attnum": 2, "name": "total", "type": "numeric", "nullable": true, "default": "0"}
+						],
+						"primary_key_columns": ["tenant", "id"]
+					}]
+				}`,
+			},
+			wantQueries: []*query{
+				{
+					schema: "public",
+					table:  "orders",
+					sql: "CREATE TABLE IF NOT EXISTS \"public\".\"orders\" (\"tenant\" text NOT NULL, \"id\" integer NOT NULL, \"total\" numeric DEFAULT 0, PRIMARY KEY (\"tenant\", \"id\"));\n" +
+						"ALTER TABLE \"public\".\"orders\" ADD COLUMN IF NOT EXISTS \"tenant\" text, ADD COLUMN IF NOT EXISTS \"id\" integer, ADD COLUMN IF NOT EXISTS \"total\" numeric DEFAULT 0;",
+					isDDL: true,
+				},
+			},
+		},
+		{
+			name: "drop merged table",
+			walData: &wal.Data{
+				Action: wal.LogicalMessageAction,
+				Prefix: wal.DDLPrefix,
+				Content: `{
+					"ddl": "DROP TABLE tenant_a.orders;",
+					"schema_name": "public",
+					"command_tag": "DROP TABLE",
+					"merged": true,
+					"objects": [{"type": "table", "identity": "\"public\".\"orders\"", "schema": "public"}]
+				}`,
+			},
+			wantQueries: []*query{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			adapter := newDDLAdapter(nil, applyModeMirror, false)
+			queries, err := adapter.walDataToQueries(context.Background(), tc.walData)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantQueries, queries)
+		})
+	}
+}
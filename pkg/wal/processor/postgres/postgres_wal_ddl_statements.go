@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// The DDL statements are replayed on the target as they were run on the
+// source. Since the target can already have the objects (restored from a
+// snapshot, or created by a previous run of the same event), the statements
+// that support it are rewritten to be idempotent.
+var (
+	createIndexRegex            = regexp.MustCompile(`(?is)^(\s*CREATE\s+(?:UNIQUE\s+)?INDEX)\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(ON\s+)?`)
+	uniqueIndexRegex            = regexp.MustCompile(`(?is)\bUNIQUE\b`)
+	dropIndexRegex              = regexp.MustCompile(`(?is)^(\s*DROP\s+INDEX)\s+(?:CONCURRENTLY\s+)?(?:IF\s+EXISTS\s+)?`)
+	alterIndexRegex             = regexp.MustCompile(`(?is)^(\s*ALTER\s+INDEX)\s+(?:IF\s+EXISTS\s+)?`)
+	alterIndexAllRegex          = regexp.MustCompile(`(?is)^\s*ALTER\s+INDEX\s+ALL\s+IN\s+TABLESPACE\b`)
+	alterTypeAddValueRegex      = regexp.MustCompile(`(?is)^(\s*ALTER\s+TYPE\s+.+?\s+ADD\s+VALUE)\s+(?:IF\s+NOT\s+EXISTS\s+)?`)
+	createViewRegex             = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:OR\s+REPLACE\s+)?((?:RECURSIVE\s+)?VIEW)\s+`)
+	createMaterializedViewRegex = regexp.MustCompile(`(?is)^(\s*CREATE\s+MATERIALIZED\s+VIEW)\s+(?:IF\s+NOT\s+EXISTS\s+)?`)
+	createSequenceRegex         = regexp.MustCompile(`(?is)^(\s*CREATE\s+(?:UNLOGGED\s+)?SEQUENCE)\s+(?:IF\s+NOT\s+EXISTS\s+)?`)
+	dropObjectRegex             = regexp.MustCompile(`(?is)^(\s*DROP\s+(?:VIEW|MATERIALIZED\s+VIEW|SEQUENCE|TYPE))\s+(?:IF\s+EXISTS\s+)?`)
+	detachPartitionRegex        = regexp.MustCompile(`(?is)(DETACH\s+PARTITION\s+\S+)\s+CONCURRENTLY\b`)
+	attachPartitionRegex        = regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?\S+\s+ATTACH\s+PARTITION\s+(\S+)\s`)
+)
+
+// rewriteDDL returns the statement of the DDL event on input as it should be
+// run on the target. Statements that can run concurrently only do so when
+// enabled and when they're not combined with other statements, since they
+// can't run inside a transaction block.
+func (a *ddlAdapter) rewriteDDL(ddlEvent *wal.DDLEvent, sql string, combined bool) string {
+	concurrently := a.concurrentDDL && !combined
+
+	switch ddlEvent.CommandTag {
+	case "CREATE INDEX":
+		m := createIndexRegex.FindStringSubmatch(sql)
+		if m == nil {
+			return sql
+		}
+		prefix := m[1]
+		if concurrently {
+			prefix += " CONCURRENTLY"
+		}
+		// unnamed indexes are given a new name every time, so they can't
+		// be made idempotent
+		if m[2] == "" {
+			prefix += " IF NOT EXISTS"
+		}
+		return prefix + " " + m[2] + sql[len(m[0]):]
+	case "DROP INDEX":
+		// only one index can be dropped concurrently
+		concurrently = concurrently && len(ddlEvent.GetObjectsByType("index")) <= 1
+		prefix := "${1} IF EXISTS "
+		if concurrently {
+			prefix = "${1} CONCURRENTLY IF EXISTS "
+		}
+		return replaceFirst(dropIndexRegex, sql, prefix)
+	case "ALTER INDEX":
+		// unique indexes are not created on history tables
+		if a.applyMode == applyModeHistory && !alterIndexAllRegex.MatchString(sql) {
+			return replaceFirst(alterIndexRegex, sql, "${1} IF EXISTS ")
+		}
+		return sql
+	case "ALTER TYPE":
+		return replaceFirst(alterTypeAddValueRegex, sql, "${1} IF NOT EXISTS ")
+	case "CREATE VIEW":
+		return replaceFirst(createViewRegex, sql, "CREATE OR REPLACE ${1} ")
+	case "CREATE MATERIALIZED VIEW":
+		return replaceFirst(createMaterializedViewRegex, sql, "${1} IF NOT EXISTS ")
+	case "CREATE SEQUENCE":
+		return replaceFirst(createSequenceRegex, sql, "${1} IF NOT EXISTS ")
+	case "DROP VIEW", "DROP MATERIALIZED VIEW", "DROP SEQUENCE", "DROP TYPE":
+		return replaceFirst(dropObjectRegex, sql, "${1} IF EXISTS ")
+	case "ALTER TABLE":
+		if m := attachPartitionRegex.FindStringSubmatch(sql); m != nil {
+			return attachPartitionDDL(sql, m[1])
+		}
+		// partitions can only be detached concurrently outside of a
+		// transaction block
+		if concurrently {
+			return sql
+		}
+		return replaceFirst(detachPartitionRegex, sql, "${1}")
+	default:
+		return sql
+	}
+}
+
+// attachPartitionDDL returns the partition attach statement on input guarded
+// by a check on the partition, since attaching a table that is already a
+// partition fails.
+func attachPartitionDDL(sql, partition string) string {
+	stmt := strings.TrimRight(strings.TrimSpace(sql), ";")
+	return fmt.Sprintf("DO $pgstream$BEGIN IF NOT EXISTS (SELECT 1 FROM pg_catalog.pg_inherits WHERE inhrelid = to_regclass(%s)) THEN %s; END IF; END$pgstream$;",
+		quoteLiteral(partition), stmt)
+}
+
+func replaceFirst(re *regexp.Regexp, s, repl string) string {
+	loc := re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return s
+	}
+	return s[:loc[0]] + string(re.ExpandString(nil, repl, s, loc)) + s[loc[1]:]
+}
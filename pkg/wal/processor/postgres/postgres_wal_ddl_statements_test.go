@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestDDLAdapter_rewriteDDL(t *testing.T) {
+	t.Parallel()
+
+	indexObject := wal.DDLObject{Type: "index", Identity: "public.idx_name", Schema: "public"}
+
+	tests := []struct {
+		name          string
+		commandTag    string
+		sql           string
+		objects       []wal.DDLObject
+		concurrentDDL bool
+		combined      bool
+
+		wantSQL string
+	}{
+		{
+			name:       "create index",
+			commandTag: "CREATE INDEX",
+			sql:        "CREATE INDEX idx_name ON public.users(name);",
+			wantSQL:    "CREATE INDEX IF NOT EXISTS idx_name ON public.users(name);",
+		},
+		{
+			name:       "create unique index if not exists",
+			commandTag: "CREATE INDEX",
+			sql:        "create unique index if not exists idx_name on public.users(name);",
+			wantSQL:    "create unique index IF NOT EXISTS idx_name on public.users(name);",
+		},
+		{
+			name:       "create unnamed index",
+			commandTag: "CREATE INDEX",
+			sql:        "CREATE INDEX ON public.users(name);",
+			wantSQL:    "CREATE INDEX ON public.users(name);",
+		},
+		{
+			name:          "create index concurrently",
+			commandTag:    "CREATE INDEX",
+			sql:           "CREATE INDEX idx_name ON public.users(name);",
+			concurrentDDL: true,
+			wantSQL:       "CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_name ON public.users(name);",
+		},
+		{
+			name:       "create index concurrently on source",
+			commandTag: "CREATE INDEX",
+			sql:        "CREATE INDEX CONCURRENTLY idx_name ON public.users(name);",
+			wantSQL:    "CREATE INDEX IF NOT EXISTS idx_name ON public.users(name);",
+		},
+		{
+			name:          "create index combined with other statements",
+			commandTag:    "CREATE INDEX",
+			sql:           "CREATE INDEX CONCURRENTLY idx_name ON users(name);",
+			concurrentDDL: true,
+			combined:      true,
+			wantSQL:       "CREATE INDEX IF NOT EXISTS idx_name ON users(name);",
+		},
+		{
+			name:       "drop index",
+			commandTag: "DROP INDEX",
+			sql:        "DROP INDEX public.idx_name;",
+			objects:    []wal.DDLObject{indexObject},
+			wantSQL:    "DROP INDEX IF EXISTS public.idx_name;",
+		},
+		{
+			name:          "drop index concurrently",
+			commandTag:    "DROP INDEX",
+			sql:           "DROP INDEX IF EXISTS public.idx_name;",
+			objects:       []wal.DDLObject{indexObject},
+			concurrentDDL: true,
+			wantSQL:       "DROP INDEX CONCURRENTLY IF EXISTS public.idx_name;",
+		},
+		{
+			name:          "drop multiple indexes",
+			commandTag:    "DROP INDEX",
+			sql:           "DROP INDEX public.idx_name, public.idx_other;",
+			objects:       []wal.DDLObject{indexObject, {Type: "index", Identity: "public.idx_other", Schema: "public"}},
+			concurrentDDL: true,
+			wantSQL:       "DROP INDEX IF EXISTS public.idx_name, public.idx_other;",
+		},
+		{
+			name:       "alter type add value",
+			commandTag: "ALTER TYPE",
+			sql:        "ALTER TYPE public.mood ADD VALUE 'happy' AFTER 'ok';",
+			wantSQL:    "ALTER TYPE public.mood ADD VALUE IF NOT EXISTS 'happy' AFTER 'ok';",
+		},
+		{
+			name:       "alter type rename value",
+			commandTag: "ALTER TYPE",
+			sql:        "ALTER TYPE public.mood RENAME VALUE 'sad' TO 'blue';",
+			wantSQL:    "ALTER TYPE public.mood RENAME VALUE 'sad' TO 'blue';",
+		},
+		{
+			name:       "attach partition",
+			commandTag: "ALTER TABLE",
+			sql:        "ALTER TABLE public.events ATTACH PARTITION public.events_2024 FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');",
+			wantSQL: "DO $pgstream$BEGIN IF NOT EXISTS (SELECT 1 FROM pg_catalog.pg_inherits WHERE inhrelid = to_regclass('public.events_2024')) THEN " +
+				"ALTER TABLE public.events ATTACH PARTITION public.events_2024 FOR VALUES FROM ('2024-01-01') TO ('2025-01-01'); END IF; END$pgstream$;",
+		},
+		{
+			name:       "attach default partition with quoted name",
+			commandTag: "ALTER TABLE",
+			sql:        `alter table only events attach partition "Events_O'Default" default`,
+			wantSQL: `DO $pgstream$BEGIN IF NOT EXISTS (SELECT 1 FROM pg_catalog.pg_inherits WHERE inhrelid = to_regclass('"Events_O''Default"')) THEN ` +
+				`alter table only events attach partition "Events_O'Default" default; END IF; END$pgstream$;`,
+		},
+		{
+			name:          "attach partition with concurrent ddl",
+			commandTag:    "ALTER TABLE",
+			sql:           "ALTER TABLE public.events ATTACH PARTITION public.events_2024 FOR VALUES IN (2024)",
+			concurrentDDL: true,
+			wantSQL: "DO $pgstream$BEGIN IF NOT EXISTS (SELECT 1 FROM pg_catalog.pg_inherits WHERE inhrelid = to_regclass('public.events_2024')) THEN " +
+				"ALTER TABLE public.events ATTACH PARTITION public.events_2024 FOR VALUES IN (2024); END IF; END$pgstream$;",
+		},
+		{
+			name:       "detach partition concurrently on source",
+			commandTag: "ALTER TABLE",
+			sql:        "ALTER TABLE public.events DETACH PARTITION public.events_2024 CONCURRENTLY;",
+			wantSQL:    "ALTER TABLE public.events DETACH PARTITION public.events_2024;",
+		},
+		{
+			name:          "detach partition concurrently",
+			commandTag:    "ALTER TABLE",
+			sql:           "ALTER TABLE public.events DETACH PARTITION public.events_2024 CONCURRENTLY;",
+			concurrentDDL: true,
+			wantSQL:       "ALTER TABLE public.events DETACH PARTITION public.events_2024 CONCURRENTLY;",
+		},
+		{
+			name:       "create view",
+			commandTag: "CREATE VIEW",
+			sql:        "CREATE VIEW public.active_users AS SELECT * FROM public.users WHERE active;",
+			wantSQL:    "CREATE OR REPLACE VIEW public.active_users AS SELECT * FROM public.users WHERE active;",
+		},
+		{
+			name:       "create or replace recursive view",
+			commandTag: "CREATE VIEW",
+			sql:        "CREATE OR REPLACE RECURSIVE VIEW public.nums(n) AS VALUES (1) UNION ALL SELECT n+1 FROM nums WHERE n < 10;",
+			wantSQL:    "CREATE OR REPLACE RECURSIVE VIEW public.nums(n) AS VALUES (1) UNION ALL SELECT n+1 FROM nums WHERE n < 10;",
+		},
+		{
+			name:       "create materialized view",
+			commandTag: "CREATE MATERIALIZED VIEW",
+			sql:        "CREATE MATERIALIZED VIEW public.user_counts AS SELECT count(*) FROM public.users;",
+			wantSQL:    "CREATE MATERIALIZED VIEW IF NOT EXISTS public.user_counts AS SELECT count(*) FROM public.users;",
+		},
+		{
+			name:       "drop materialized view",
+			commandTag: "DROP MATERIALIZED VIEW",
+			sql:        "DROP MATERIALIZED VIEW public.user_counts;",
+			wantSQL:    "DROP MATERIALIZED VIEW IF EXISTS public.user_counts;",
+		},
+		{
+			name:       "create sequence",
+			commandTag: "CREATE SEQUENCE",
+			sql:        "CREATE SEQUENCE public.order_seq START 100;",
+			wantSQL:    "CREATE SEQUENCE IF NOT EXISTS public.order_seq START 100;",
+		},
+		{
+			name:       "drop sequence",
+			commandTag: "DROP SEQUENCE",
+			sql:        "DROP SEQUENCE public.order_seq;",
+			wantSQL:    "DROP SEQUENCE IF EXISTS public.order_seq;",
+		},
+		{
+			name:       "comment",
+			commandTag: "COMMENT",
+			sql:        "COMMENT ON TABLE public.users IS 'application users';",
+			wantSQL:    "COMMENT ON TABLE public.users IS 'application users';",
+		},
+		{
+			name:       "comment on column",
+			commandTag: "COMMENT",
+			sql:        "COMMENT ON COLUMN public.users.name IS NULL;",
+			wantSQL:    "COMMENT ON COLUMN public.users.name IS NULL;",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			adapter := newDDLAdapter(nil, applyModeMirror, tc.concurrentDDL)
+			ddlEvent := &wal.DDLEvent{CommandTag: tc.commandTag, Objects: tc.objects}
+			require.Equal(t, tc.wantSQL, adapter.rewriteDDL(ddlEvent, tc.sql, tc.combined))
+		})
+	}
+}
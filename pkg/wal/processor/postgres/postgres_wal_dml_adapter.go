@@ -31,43 +31,80 @@ var (
 )
 
 type dmlAdapter struct {
-	logger           loglib.Logger
-	onConflictAction onConflictAction
-	forCopy          bool
-	pgTypeMap        *pgtype.Map
+	logger             loglib.Logger
+	onConflictAction   onConflictAction
+	applyMode          applyMode
+	conflictResolution conflictResolution
+	forCopy            bool
+	pgTypeMap          *pgtype.Map
+	// per table overrides of the on conflict behaviour
+	conflictPolicies conflictPolicies
 }
 
-func newDMLAdapter(action string, forCopy bool, logger loglib.Logger) (*dmlAdapter, error) {
+func newDMLAdapter(action, mode, resolution string, forCopy bool, logger loglib.Logger) (*dmlAdapter, error) {
 	oca, err := parseOnConflictAction(action)
 	if err != nil {
 		return nil, err
 	}
+	am, err := parseApplyMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	cr, err := parseConflictResolution(resolution)
+	if err != nil {
+		return nil, err
+	}
+	// resolving conflicts requires inserts to update the existing rows
+	if cr != conflictResolutionNone {
+		oca = onConflictUpdate
+	}
 	return &dmlAdapter{
-		logger:           logger,
-		onConflictAction: oca,
-		forCopy:          forCopy,
-		pgTypeMap:        pgtype.NewMap(),
+		logger:             logger,
+		onConflictAction:   oca,
+		applyMode:          am,
+		conflictResolution: cr,
+		forCopy:            forCopy,
+		pgTypeMap:          pgtype.NewMap(),
 	}, nil
 }
 
 func (a *dmlAdapter) walDataToQueries(d *wal.Data, schemaInfo schemaInfo) ([]*query, error) {
+	if a.applyMode == applyModeHistory {
+		return a.buildHistoryQueries(d, schemaInfo)
+	}
+
 	switch d.Action {
 	case "T":
+		if a.applyMode == applyModeSoftDelete {
+			return []*query{a.buildSoftTruncateQuery(d)}, nil
+		}
 		return []*query{a.buildTruncateQuery(d)}, nil
 	case "D":
+		if a.applyMode == applyModeSoftDelete {
+			q, err := a.buildSoftDeleteQuery(d)
+			if err != nil {
+				return nil, err
+			}
+			return []*query{a.withConflictCondition(d, q)}, nil
+		}
 		q, err := a.buildDeleteQuery(d)
 		if err != nil {
 			return nil, err
 		}
-		return []*query{q}, nil
+		return []*query{a.withConflictCondition(d, q)}, nil
 	case "I":
 		return a.buildInsertQueries(d, schemaInfo), nil
 	case "U":
+		if a.conflictResolution == conflictResolutionSourcePriority {
+			if queries, ok := a.buildSourcePriorityUpdateQueries(d, schemaInfo); ok {
+				return queries, nil
+			}
+		}
 		q, err := a.buildUpdateQuery(d, schemaInfo)
 		if err != nil {
 			return nil, err
 		}
-		return []*query{q}, nil
+		return []*query{a.withConflictCondition(d, q)}, nil
 	default:
 		return []*query{}, nil
 	}
@@ -106,6 +143,7 @@ func (a *dmlAdapter) buildInsertQueries(d *wal.Data, schemaInfo schemaInfo) []*q
 		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
 	}
 
+	onConflictQuery, onConflictValues := a.buildOnConflictQuery(d, names, len(values)+1)
 	qs := []*query{
 		{
 			table:         d.Table,
@@ -115,8 +153,8 @@ func (a *dmlAdapter) buildInsertQueries(d *wal.Data, schemaInfo schemaInfo) []*q
 			sql: fmt.Sprintf("INSERT INTO %s(%s) OVERRIDING SYSTEM VALUE VALUES(%s)%s",
 				quotedTableName(d.Schema, d.Table), strings.Join(names, ", "),
 				strings.Join(placeholders, ", "),
-				a.buildOnConflictQuery(d, names)),
-			args: values,
+				onConflictQuery),
+			args: append(values, onConflictValues...),
 		},
 	}
 
@@ -176,7 +214,9 @@ func (a *dmlAdapter) buildWhereQuery(d *wal.Data, placeholderOffset int) (string
 	var cols []wal.Column
 	switch {
 	case len(d.Identity) > 0:
-		// if we have the previous values (replica identity), add them to the where query
+		// if we have the previous values (replica identity), add them to the
+		// where query. For tables without primary key that's the whole row
+		// (replica identity full).
 		cols = d.Identity
 	case len(d.Metadata.InternalColIDs) > 0:
 		// if we don't have previous values we have to rely on the primary keys
@@ -196,13 +236,18 @@ func (a *dmlAdapter) buildWhereQuery(d *wal.Data, placeholderOffset int) (string
 			whereQuery = fmt.Sprintf("%s AND", whereQuery)
 		}
 
-		if c.Value == nil {
+		switch {
+		case c.Value == nil:
 			whereQuery = fmt.Sprintf("%s %s IS NULL", whereQuery, pglib.QuoteIdentifier(c.Name))
 			continue
+		case c.Type == "json":
+			// json has no equality operator, compare it as jsonb
+			placeholderIdx++
+			whereQuery = fmt.Sprintf("%s %s::jsonb = $%d::jsonb", whereQuery, pglib.QuoteIdentifier(c.Name), placeholderIdx)
+		default:
+			placeholderIdx++
+			whereQuery = fmt.Sprintf("%s %s = $%d", whereQuery, pglib.QuoteIdentifier(c.Name), placeholderIdx)
 		}
-
-		placeholderIdx++
-		whereQuery = fmt.Sprintf("%s %s = $%d", whereQuery, pglib.QuoteIdentifier(c.Name), placeholderIdx)
 		whereValues = append(whereValues, serializeJSONBValue(c.Type, c.Value))
 
 	}
@@ -222,26 +267,76 @@ func (a *dmlAdapter) buildSetQuery(cols []wal.Column, rowColumns []string, rowVa
 	return setQuery, setValues
 }
 
-func (a *dmlAdapter) buildOnConflictQuery(d *wal.Data, filteredColumnNames []string) string {
-	switch a.onConflictAction {
+func (a *dmlAdapter) buildOnConflictQuery(d *wal.Data, filteredColumnNames []string, placeholderIdx int) (string, []any) {
+	// history tables keep multiple versions of each row, so there are no
+	// unique constraints to conflict with
+	if a.applyMode == applyModeHistory {
+		return "", nil
+	}
+
+	policy := a.conflictPolicies.get(d.Schema, d.Table)
+	action := a.onConflictAction
+	// resolving conflicts requires inserts to update the existing rows
+	if policy != nil && a.conflictResolution == conflictResolutionNone {
+		action = policy.action
+	}
+
+	conflictTarget := ""
+	if policy != nil {
+		conflictTarget = policy.target
+	}
+	if conflictTarget == "" {
+		if primaryKeyCols := a.extractPrimaryKeyColumnNames(d.Metadata.InternalColIDs, d.Columns); len(primaryKeyCols) > 0 {
+			conflictTarget = fmt.Sprintf("(%s)", strings.Join(primaryKeyCols, ","))
+		}
+	}
+
+	// a row inserted again after being soft deleted conflicts with its deleted
+	// version, which must be brought back whatever the on conflict action.
+	// Conflicts with rows that are not deleted are left to the configured
+	// action, other than errors, which can't be raised from the upsert.
+	reviveOnly := false
+	if a.applyMode == applyModeSoftDelete && action != onConflictUpdate && conflictTarget != "" {
+		action = onConflictUpdate
+		reviveOnly = true
+	}
+
+	switch action {
 	case onConflictUpdate:
 		// on conflict do update requires a conflict target. If there are no
 		// primary keys to use for the conflict target, default to error
 		// behaviour
-		primaryKeyCols := a.extractPrimaryKeyColumnNames(d.Metadata.InternalColIDs, d.Columns)
-		if len(primaryKeyCols) == 0 {
-			return ""
+		if conflictTarget == "" {
+			return "", nil
 		}
 
 		cols := make([]string, 0, len(d.Columns))
-		for _, col := range filteredColumnNames {
+		for _, col := range policy.updatedColumns(filteredColumnNames) {
 			cols = append(cols, fmt.Sprintf("%[1]s = EXCLUDED.%[1]s", col))
 		}
-		return fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(primaryKeyCols, ","), strings.Join(cols, ", "))
+		// a row inserted again after being deleted is no longer deleted
+		if a.applyMode == applyModeSoftDelete {
+			cols = append(cols, fmt.Sprintf("%s = NULL", pglib.QuoteIdentifier(deletedAtColumn)))
+		}
+		if len(cols) == 0 {
+			return fmt.Sprintf(" ON CONFLICT %s DO NOTHING", conflictTarget), nil
+		}
+		onConflictQuery := fmt.Sprintf(" ON CONFLICT %s DO UPDATE SET %s", conflictTarget, strings.Join(cols, ", "))
+		condition, conditionValues := a.conflictCondition(d, placeholderIdx)
+		if reviveOnly {
+			condition = fmt.Sprintf("%s.%s IS NOT NULL", quotedTableName(d.Schema, d.Table), pglib.QuoteIdentifier(deletedAtColumn))
+		}
+		if condition != "" {
+			onConflictQuery = fmt.Sprintf("%s WHERE %s", onConflictQuery, condition)
+		}
+		return onConflictQuery, conditionValues
 	case onConflictDoNothing:
-		return " ON CONFLICT DO NOTHING"
+		if policy != nil && policy.target != "" {
+			return fmt.Sprintf(" ON CONFLICT %s DO NOTHING", policy.target), nil
+		}
+		return " ON CONFLICT DO NOTHING", nil
 	default:
-		return ""
+		return "", nil
 	}
 }
 
@@ -412,8 +507,9 @@ func getTypedTSTZRange(value any) any {
 // can't produce correctly, so bulk ingest must fall back to text-format
 // COPY for any batch that touches one of these columns.
 var textOnlyCopyTypes = map[string]struct{}{
-	"cube":  {}, // binary header: int32 dim+flags + N×float8 — pgx writes the text rep, server misreads it as a dimension count
-	"ltree": {}, // binary format: 1-byte version + path string — pgx writes the text rep, server reads byte 0 as the version number
+	"pg_lsn": {}, // no pgx codec registered, the binary encoding of the text value is rejected by the server
+	"cube":   {}, // binary header: int32 dim+flags + N×float8 — pgx writes the text rep, server misreads it as a dimension count
+	"ltree":  {}, // binary format: 1-byte version + path string — pgx writes the text rep, server reads byte 0 as the version number
 }
 
 func needsTextCopy(columnTypes []string) bool {
@@ -56,7 +56,7 @@ func (a *dmlAdapter) getIdentityColumns(d *wal.Data) ([]wal.Column, error) {
 //
 // Single PK: DELETE FROM t WHERE col = ANY($1::type[])
 // Composite PK: DELETE FROM t WHERE (a,b) IN (($1,$2),($3,$4),...) split at maxParamsPerQuery
-// NULL identity values are handled as individual queries.
+// NULL and json identity values are handled as individual queries.
 func (a *dmlAdapter) buildBulkDeleteQuery(events []*wal.Data) ([]*query, error) {
 	if len(events) == 0 {
 		return nil, nil
@@ -71,33 +71,34 @@ func (a *dmlAdapter) buildBulkDeleteQuery(events []*wal.Data) ([]*query, error)
 	tableName := quotedTableName(events[0].Schema, events[0].Table)
 	numPKCols := len(firstCols)
 
-	// separate events with NULL identity values — they need individual queries
+	// separate events with NULL or json identity values (replica identity
+	// full) — they need individual queries
 	var normalEvents []*wal.Data
-	var nullEvents []*wal.Data
+	var individualEvents []*wal.Data
 
 	for _, e := range events {
 		cols, err := a.getIdentityColumns(e)
 		if err != nil {
 			return nil, fmt.Errorf("building bulk delete query: %w", err)
 		}
-		hasNull := false
+		individual := false
 		for _, c := range cols {
-			if c.Value == nil {
-				hasNull = true
+			if c.Value == nil || c.Type == "json" {
+				individual = true
 				break
 			}
 		}
-		if hasNull {
-			nullEvents = append(nullEvents, e)
+		if individual {
+			individualEvents = append(individualEvents, e)
 		} else {
 			normalEvents = append(normalEvents, e)
 		}
 	}
 
-	queries := make([]*query, 0, len(nullEvents)+1)
+	queries := make([]*query, 0, len(individualEvents)+1)
 
-	// handle NULL identity events individually
-	for _, e := range nullEvents {
+	// handle NULL and json identity events individually
+	for _, e := range individualEvents {
 		q, err := a.buildDeleteQuery(e)
 		if err != nil {
 			return nil, err
@@ -267,11 +268,13 @@ func (a *dmlAdapter) buildBulkInsertQueries(events []*wal.Data, si schemaInfo) [
 			}
 		}
 
+		onConflictQuery, onConflictValues := a.buildOnConflictQuery(events[0], names, paramIdx+1)
+		args = append(args, onConflictValues...)
 		sql := fmt.Sprintf("INSERT INTO %s(%s) OVERRIDING SYSTEM VALUE VALUES%s%s",
 			quotedTableName(events[0].Schema, events[0].Table),
 			strings.Join(names, ", "),
 			strings.Join(valueTuples, ","),
-			a.buildOnConflictQuery(events[0], names))
+			onConflictQuery)
 
 		queries = append(queries, &query{
 			schema:        events[0].Schema,
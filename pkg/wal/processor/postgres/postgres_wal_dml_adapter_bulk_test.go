@@ -177,6 +177,34 @@ func TestBuildBulkDeleteQuery_NullIdentity(t *testing.T) {
 	require.Contains(t, bulkQuery.sql, "ANY")
 }
 
+func TestBuildBulkDeleteQuery_JSONFullIdentity(t *testing.T) {
+	t.Parallel()
+
+	adapter := newTestDMLAdapter(t)
+
+	fullIdentityEvent := func(id float64) *wal.Data {
+		return &wal.Data{
+			Action: "D",
+			Schema: "public",
+			Table:  "t",
+			Identity: []wal.Column{
+				{Name: "id", Type: "bigint", Value: id},
+				{Name: "doc", Type: "json", Value: `{"a":1}`},
+			},
+		}
+	}
+	events := []*wal.Data{fullIdentityEvent(1), fullIdentityEvent(2)}
+
+	queries, err := adapter.buildBulkDeleteQuery(events)
+	require.NoError(t, err)
+
+	// json has no equality operator, so each row is deleted individually
+	require.Len(t, queries, 2)
+	for _, q := range queries {
+		require.Contains(t, q.sql, `"doc"::jsonb = $2::jsonb`)
+	}
+}
+
 func TestBuildBulkDeleteQuery_NoIdentity(t *testing.T) {
 	t.Parallel()
 
@@ -367,7 +395,7 @@ func TestBuildBulkInsertQueries_WithGeneratedColumns(t *testing.T) {
 func TestBuildBulkInsertQueries_OnConflictUpdate(t *testing.T) {
 	t.Parallel()
 
-	a, err := newDMLAdapter("update", false, loglib.NewNoopLogger())
+	a, err := newDMLAdapter("update", "", "", false, loglib.NewNoopLogger())
 	require.NoError(t, err)
 
 	si := schemaInfo{
@@ -444,7 +472,7 @@ func deleteEvent(schema, table, colName, colType string, colValue any) *wal.Data
 
 func newTestDMLAdapter(t *testing.T) *dmlAdapter {
 	t.Helper()
-	a, err := newDMLAdapter("", false, loglib.NewNoopLogger())
+	a, err := newDMLAdapter("", "", "", false, loglib.NewNoopLogger())
 	require.NoError(t, err)
 	return a
 }
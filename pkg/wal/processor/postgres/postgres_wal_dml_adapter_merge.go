@@ -0,0 +1,406 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"slices"
+	"strings"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+// mergeBatch is a set of changes to a single table, reduced to the last
+// change per primary key, to be copied into a staging table and applied to
+// the target table with set based queries.
+type mergeBatch struct {
+	schema string
+	table  string
+	// quoted names of the primary key columns
+	keyColumns []string
+	// quoted names of the copied columns, the operation column excluded
+	columns []string
+	types   []string
+	// quoted names of the columns updated on existing rows
+	updateColumns []string
+	// rows to be copied into the staging table, with the operation as the
+	// last value
+	rows [][]any
+	// max value per sequence, to be set after the merge
+	sequences map[string]int64
+	// conflict policy of the table, applied to the rows inserted in the
+	// batch. Nil if the table has none, in which case inserts are upserted.
+	policy *conflictPolicy
+}
+
+// mergeRow is the last change to a row in the batch.
+type mergeRow struct {
+	op     string
+	values map[string]any
+}
+
+const (
+	stagingSchema       = "pg_temp"
+	stagingOpColumn     = "_pgstream_op"
+	stagingDeleteOp     = "D"
+	stagingUpsertOp     = "U"
+	stagingInsertOp     = "I"
+	maxIdentifierLength = 63
+)
+
+// buildMergeBatch reduces the leading insert, update and delete events on
+// input, all for the same table, to the last change per primary key. It stops
+// at the first event that can't be merged, and returns it along with the
+// events that follow, so that they can be applied in order. Events can't be
+// merged when they can't be identified by a primary key, or when they're
+// updates without the value of unchanged TOAST columns that can't be completed
+// with the previous change to the row in the batch, since the staging rows
+// can't leave the columns untouched. When the table has a conflict policy,
+// rows inserted in the batch are kept apart from the upserts, so that the
+// policy can be applied to them.
+func (a *dmlAdapter) buildMergeBatch(events []*wal.Data, si schemaInfo) (*mergeBatch, []*wal.Data) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	mb := &mergeBatch{
+		schema:    events[0].Schema,
+		table:     events[0].Table,
+		sequences: map[string]int64{},
+	}
+	// resolving conflicts requires inserts to update the existing rows
+	if a.conflictResolution == conflictResolutionNone {
+		mb.policy = a.conflictPolicies.get(events[0].Schema, events[0].Table)
+	}
+	var remaining []*wal.Data
+	rows := map[string]*mergeRow{}
+	// keys in order of first change, so that the staging rows are
+	// deterministic
+	keys := []string{}
+	typesByColumn := map[string]string{}
+
+	setRow := func(key string, row *mergeRow) {
+		if _, found := rows[key]; !found {
+			keys = append(keys, key)
+		}
+		rows[key] = row
+	}
+
+events:
+	for i, e := range events {
+		newKeyCols := a.extractPrimaryKeyColumns(e.Metadata.InternalColIDs, e.Columns)
+		oldKeyCols := a.extractPrimaryKeyColumns(e.Metadata.InternalColIDs, e.Identity)
+		if len(mb.keyColumns) == 0 {
+			switch {
+			case len(newKeyCols) > 0:
+				mb.keyColumns = quotedColumnNames(newKeyCols)
+			case len(oldKeyCols) > 0:
+				mb.keyColumns = quotedColumnNames(oldKeyCols)
+			}
+		}
+
+		switch e.Action {
+		case "D":
+			if len(oldKeyCols) == 0 || len(oldKeyCols) != len(mb.keyColumns) {
+				remaining = events[i:]
+				break events
+			}
+			values := map[string]any{}
+			a.addMergeValues(values, typesByColumn, oldKeyCols, si)
+			setRow(mergeKey(oldKeyCols), &mergeRow{op: stagingDeleteOp, values: values})
+		case "I", "U":
+			if len(newKeyCols) == 0 || len(newKeyCols) != len(mb.keyColumns) {
+				remaining = events[i:]
+				break events
+			}
+			values := map[string]any{}
+			if e.HasUnchangedToastColumns() {
+				prevKey := mergeKey(newKeyCols)
+				if len(oldKeyCols) == len(newKeyCols) {
+					prevKey = mergeKey(oldKeyCols)
+				}
+				prev, found := rows[prevKey]
+				if !found || prev.op == stagingDeleteOp || !hasMergeValues(prev, e.UnchangedToastColumns) {
+					remaining = events[i:]
+					break events
+				}
+				maps.Copy(values, prev.values)
+			}
+			// an update to the primary key removes the row with the previous
+			// key
+			if len(oldKeyCols) == len(newKeyCols) && mergeKey(oldKeyCols) != mergeKey(newKeyCols) {
+				deleteValues := map[string]any{}
+				a.addMergeValues(deleteValues, typesByColumn, oldKeyCols, si)
+				setRow(mergeKey(oldKeyCols), &mergeRow{op: stagingDeleteOp, values: deleteValues})
+			}
+			a.addMergeValues(values, typesByColumn, e.Columns, si)
+			setRow(mergeKey(newKeyCols), &mergeRow{op: mb.rowOp(e, rows[mergeKey(newKeyCols)]), values: values})
+			a.trackSequenceValues(mb.sequences, e.Columns, si)
+		default:
+			remaining = events[i:]
+			break events
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, remaining
+	}
+
+	// the copied columns are the union of the columns of all the rows, in
+	// order of appearance
+	for _, e := range events[:len(events)-len(remaining)] {
+		for _, c := range append(e.Identity, e.Columns...) {
+			name := pglib.QuoteIdentifier(c.Name)
+			if _, found := typesByColumn[name]; found && !slices.Contains(mb.columns, name) {
+				mb.columns = append(mb.columns, name)
+				mb.types = append(mb.types, typesByColumn[name])
+			}
+		}
+	}
+
+	for _, col := range mb.columns {
+		if slices.Contains(mb.keyColumns, col) {
+			continue
+		}
+		if _, found := si.alwaysIdentityColumns[col]; found {
+			continue
+		}
+		mb.updateColumns = append(mb.updateColumns, col)
+	}
+
+	mb.rows = make([][]any, 0, len(keys))
+	for _, key := range keys {
+		row := rows[key]
+		values := make([]any, 0, len(mb.columns)+1)
+		for _, col := range mb.columns {
+			values = append(values, row.values[col])
+		}
+		mb.rows = append(mb.rows, append(values, row.op))
+	}
+
+	return mb, remaining
+}
+
+// rowOp returns the staging operation for the insert or update event on input,
+// given the previous change to the row in the batch, if any. Rows inserted in
+// the batch are only told apart from the upserts when there's a conflict
+// policy to apply to them. A row deleted earlier in the batch is upserted,
+// since the delete was reduced away.
+func (mb *mergeBatch) rowOp(e *wal.Data, prev *mergeRow) string {
+	if mb.policy == nil {
+		return stagingUpsertOp
+	}
+	if prev != nil {
+		if prev.op == stagingInsertOp {
+			return stagingInsertOp
+		}
+		return stagingUpsertOp
+	}
+	if e.Action == "I" {
+		return stagingInsertOp
+	}
+	return stagingUpsertOp
+}
+
+// hasInserts returns true if any of the staging rows is an insert the conflict
+// policy of the table applies to.
+func (mb *mergeBatch) hasInserts() bool {
+	return slices.ContainsFunc(mb.rows, func(row []any) bool {
+		return row[len(row)-1] == stagingInsertOp
+	})
+}
+
+func (a *dmlAdapter) addMergeValues(values map[string]any, types map[string]string, cols []wal.Column, si schemaInfo) {
+	names, colTypes, colValues := a.filterRowColumnsWithTypes(cols, si)
+	for i, name := range names {
+		values[name] = colValues[i]
+		types[name] = colTypes[i]
+	}
+}
+
+// hasMergeValues returns true if the row has a value for all the columns on
+// input.
+func hasMergeValues(row *mergeRow, columns []string) bool {
+	for _, col := range columns {
+		if _, found := row.values[pglib.QuoteIdentifier(col)]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *dmlAdapter) trackSequenceValues(sequences map[string]int64, cols []wal.Column, si schemaInfo) {
+	for _, col := range cols {
+		seqName, found := si.sequenceColumns[pglib.QuoteIdentifier(col.Name)]
+		if !found {
+			continue
+		}
+		val, ok := toInt64(col.Value)
+		if !ok {
+			continue
+		}
+		if current, exists := sequences[seqName]; !exists || val > current {
+			sequences[seqName] = val
+		}
+	}
+}
+
+// buildMergeQueries returns the queries that apply the changes copied into the
+// staging table to the target table. MERGE is used when supported (Postgres
+// 15+), otherwise the deletes and upserts are applied separately. The rows
+// inserted in the batch of a table with a conflict policy are applied last,
+// with the on conflict clause of the policy.
+func (mb *mergeBatch) buildMergeQueries(stagingTable string, useMerge bool) []*query {
+	target := quotedTableName(mb.schema, mb.table)
+	joinConditions := make([]string, 0, len(mb.keyColumns))
+	for _, col := range mb.keyColumns {
+		joinConditions = append(joinConditions, fmt.Sprintf("t.%[1]s = s.%[1]s", col))
+	}
+	sourceColumns := make([]string, 0, len(mb.columns))
+	for _, col := range mb.columns {
+		sourceColumns = append(sourceColumns, fmt.Sprintf("s.%s", col))
+	}
+	opColumn := pglib.QuoteIdentifier(stagingOpColumn)
+	// the inserts are applied separately when there's a conflict policy, so
+	// the upserts are limited to the upsert rows
+	hasInserts := mb.hasInserts()
+	upsertCondition := fmt.Sprintf("s.%s <> '%s'", opColumn, stagingDeleteOp)
+	matchedUpdateCondition := ""
+	if hasInserts {
+		upsertCondition = fmt.Sprintf("s.%s = '%s'", opColumn, stagingUpsertOp)
+		matchedUpdateCondition = fmt.Sprintf(" AND %s", upsertCondition)
+	}
+
+	var queries []*query
+	if useMerge {
+		updateClause := ""
+		if len(mb.updateColumns) > 0 {
+			setColumns := make([]string, 0, len(mb.updateColumns))
+			for _, col := range mb.updateColumns {
+				setColumns = append(setColumns, fmt.Sprintf("%[1]s = s.%[1]s", col))
+			}
+			updateClause = fmt.Sprintf(" WHEN MATCHED%s THEN UPDATE SET %s", matchedUpdateCondition, strings.Join(setColumns, ", "))
+		}
+		queries = append(queries, &query{
+			schema: mb.schema,
+			table:  mb.table,
+			sql: fmt.Sprintf("MERGE INTO %[1]s AS t USING %[2]s AS s ON %[3]s"+
+				" WHEN MATCHED AND s.%[4]s = '%[5]s' THEN DELETE"+
+				"%[6]s"+
+				" WHEN NOT MATCHED AND %[7]s THEN INSERT (%[8]s) OVERRIDING SYSTEM VALUE VALUES (%[9]s)",
+				target, stagingTable, strings.Join(joinConditions, " AND "),
+				opColumn, stagingDeleteOp,
+				updateClause, upsertCondition,
+				strings.Join(mb.columns, ", "), strings.Join(sourceColumns, ", ")),
+		})
+	} else {
+		onConflictClause := " ON CONFLICT DO NOTHING"
+		if len(mb.updateColumns) > 0 {
+			setColumns := make([]string, 0, len(mb.updateColumns))
+			for _, col := range mb.updateColumns {
+				setColumns = append(setColumns, fmt.Sprintf("%[1]s = EXCLUDED.%[1]s", col))
+			}
+			onConflictClause = fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(mb.keyColumns, ", "), strings.Join(setColumns, ", "))
+		}
+		queries = append(queries,
+			&query{
+				schema: mb.schema,
+				table:  mb.table,
+				sql: fmt.Sprintf("DELETE FROM %s AS t USING %s AS s WHERE %s AND s.%s = '%s'",
+					target, stagingTable, strings.Join(joinConditions, " AND "), opColumn, stagingDeleteOp),
+			},
+			&query{
+				schema: mb.schema,
+				table:  mb.table,
+				sql: fmt.Sprintf("INSERT INTO %s(%s) OVERRIDING SYSTEM VALUE SELECT %s FROM %s AS s WHERE %s%s",
+					target, strings.Join(mb.columns, ", "), strings.Join(sourceColumns, ", "),
+					stagingTable, upsertCondition, onConflictClause),
+			},
+		)
+	}
+
+	if hasInserts {
+		queries = append(queries, &query{
+			schema: mb.schema,
+			table:  mb.table,
+			sql: fmt.Sprintf("INSERT INTO %s(%s) OVERRIDING SYSTEM VALUE SELECT %s FROM %s AS s WHERE s.%s = '%s'%s",
+				target, strings.Join(mb.columns, ", "), strings.Join(sourceColumns, ", "),
+				stagingTable, opColumn, stagingInsertOp, mb.insertConflictClause()),
+		})
+	}
+
+	seqNames := make([]string, 0, len(mb.sequences))
+	for seqName := range mb.sequences {
+		seqNames = append(seqNames, seqName)
+	}
+	slices.Sort(seqNames)
+	for _, seqName := range seqNames {
+		queries = append(queries, &query{
+			schema: mb.schema,
+			table:  mb.table,
+			sql:    "SELECT setval($1::regclass, $2::bigint, true)",
+			args:   []any{seqName, mb.sequences[seqName]},
+		})
+	}
+
+	return queries
+}
+
+// insertConflictClause returns the on conflict clause of the inserts, as
+// defined by the conflict policy of the table.
+func (mb *mergeBatch) insertConflictClause() string {
+	switch mb.policy.action {
+	case onConflictUpdate:
+		target := mb.policy.target
+		if target == "" {
+			target = fmt.Sprintf("(%s)", strings.Join(mb.keyColumns, ", "))
+		}
+		cols := mb.policy.updatedColumns(mb.updateColumns)
+		if len(cols) == 0 {
+			return fmt.Sprintf(" ON CONFLICT %s DO NOTHING", target)
+		}
+		setColumns := make([]string, 0, len(cols))
+		for _, col := range cols {
+			setColumns = append(setColumns, fmt.Sprintf("%[1]s = EXCLUDED.%[1]s", col))
+		}
+		return fmt.Sprintf(" ON CONFLICT %s DO UPDATE SET %s", target, strings.Join(setColumns, ", "))
+	case onConflictDoNothing:
+		if mb.policy.target != "" {
+			return fmt.Sprintf(" ON CONFLICT %s DO NOTHING", mb.policy.target)
+		}
+		return " ON CONFLICT DO NOTHING"
+	default:
+		return ""
+	}
+}
+
+// stagingTableName returns the name of the temporary staging table for the
+// target table.
+func stagingTableName(schema, table string) string {
+	name := fmt.Sprintf("%s_%s_staging", schema, table)
+	if len(name) > maxIdentifierLength {
+		h := fnv.New64a()
+		h.Write([]byte(schema + "." + table))
+		name = fmt.Sprintf("staging_%x", h.Sum64())
+	}
+	return name
+}
+
+func quotedColumnNames(cols []wal.Column) []string {
+	names := make([]string, 0, len(cols))
+	for _, c := range cols {
+		names = append(names, pglib.QuoteIdentifier(c.Name))
+	}
+	return names
+}
+
+func mergeKey(cols []wal.Column) string {
+	var sb strings.Builder
+	for _, c := range cols {
+		fmt.Fprintf(&sb, "%#v|", c.Value)
+	}
+	return sb.String()
+}
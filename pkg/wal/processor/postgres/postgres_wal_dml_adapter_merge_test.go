@@ -0,0 +1,419 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+)
+
+func TestDMLAdapter_buildMergeBatch(t *testing.T) {
+	t.Parallel()
+
+	idCol := func(id int) wal.Column { return wal.Column{ID: "1", Name: "id", Type: "integer", Value: id} }
+	nameCol := func(name string) wal.Column { return wal.Column{ID: "2", Name: "name", Type: "text", Value: name} }
+	metadata := wal.Metadata{InternalColIDs: []string{"1"}}
+	newEvent := func(action string, identity, cols []wal.Column) *wal.Data {
+		return &wal.Data{
+			Action:   action,
+			Schema:   testSchema,
+			Table:    testTable,
+			Identity: identity,
+			Columns:  cols,
+			Metadata: metadata,
+		}
+	}
+	bioCol := func(bio string) wal.Column { return wal.Column{ID: "3", Name: "bio", Type: "text", Value: bio} }
+	unchangedToastEvent := func(id int, name string) *wal.Data {
+		d := newEvent("U", []wal.Column{idCol(id)}, []wal.Column{idCol(id), nameCol(name)})
+		d.UnchangedToastColumns = []string{"bio"}
+		return d
+	}
+	testSchemaInfo := schemaInfo{
+		sequenceColumns: map[string]string{`"id"`: "test_schema.test_table_id_seq"},
+	}
+	unkeyedEvent := &wal.Data{
+		Action: "U",
+		Schema: testSchema,
+		Table:  testTable,
+		Columns: []wal.Column{
+			nameCol("e"),
+		},
+	}
+
+	tests := []struct {
+		name   string
+		events []*wal.Data
+		si     schemaInfo
+
+		policies conflictPolicies
+
+		wantBatch     *mergeBatch
+		wantRemaining []*wal.Data
+	}{
+		{
+			name:          "no events",
+			events:        []*wal.Data{},
+			wantRemaining: nil,
+		},
+		{
+			name: "last change per primary key",
+			events: []*wal.Data{
+				newEvent("I", nil, []wal.Column{idCol(1), nameCol("a")}),
+				newEvent("U", nil, []wal.Column{idCol(1), nameCol("b")}),
+				newEvent("I", nil, []wal.Column{idCol(2), nameCol("c")}),
+				newEvent("D", []wal.Column{idCol(2)}, nil),
+				newEvent("U", []wal.Column{idCol(3)}, []wal.Column{idCol(4), nameCol("d")}),
+				unkeyedEvent,
+			},
+			si: testSchemaInfo,
+
+			wantBatch: &mergeBatch{
+				schema:        testSchema,
+				table:         testTable,
+				keyColumns:    []string{`"id"`},
+				columns:       []string{`"id"`, `"name"`},
+				types:         []string{"integer", "text"},
+				updateColumns: []string{`"name"`},
+				rows: [][]any{
+					{1, "b", stagingUpsertOp},
+					{2, nil, stagingDeleteOp},
+					{3, nil, stagingDeleteOp},
+					{4, "d", stagingUpsertOp},
+				},
+				sequences: map[string]int64{"test_schema.test_table_id_seq": 4},
+			},
+			wantRemaining: []*wal.Data{unkeyedEvent},
+		},
+		{
+			name: "always identity columns are not updated",
+			events: []*wal.Data{
+				newEvent("I", nil, []wal.Column{idCol(1), nameCol("a"), {ID: "3", Name: "seq", Type: "bigint", Value: 7}}),
+			},
+			si: schemaInfo{alwaysIdentityColumns: map[string]struct{}{`"seq"`: {}}},
+
+			wantBatch: &mergeBatch{
+				schema:        testSchema,
+				table:         testTable,
+				keyColumns:    []string{`"id"`},
+				columns:       []string{`"id"`, `"name"`, `"seq"`},
+				types:         []string{"integer", "text", "bigint"},
+				updateColumns: []string{`"name"`},
+				rows: [][]any{
+					{1, "a", 7, stagingUpsertOp},
+				},
+				sequences: map[string]int64{},
+			},
+			wantRemaining: nil,
+		},
+		{
+			name: "unchanged toast columns completed from previous change",
+			events: []*wal.Data{
+				newEvent("I", nil, []wal.Column{idCol(1), nameCol("a"), bioCol("long bio")}),
+				unchangedToastEvent(1, "b"),
+			},
+
+			wantBatch: &mergeBatch{
+				schema:        testSchema,
+				table:         testTable,
+				keyColumns:    []string{`"id"`},
+				columns:       []string{`"id"`, `"name"`, `"bio"`},
+				types:         []string{"integer", "text", "text"},
+				updateColumns: []string{`"name"`, `"bio"`},
+				rows: [][]any{
+					{1, "b", "long bio", stagingUpsertOp},
+				},
+				sequences: map[string]int64{},
+			},
+			wantRemaining: nil,
+		},
+		{
+			name: "unchanged toast columns without previous change",
+			events: []*wal.Data{
+				newEvent("I", nil, []wal.Column{idCol(1), nameCol("a"), bioCol("long bio")}),
+				unchangedToastEvent(2, "b"),
+				newEvent("I", nil, []wal.Column{idCol(3), nameCol("c"), bioCol("other bio")}),
+			},
+
+			wantBatch: &mergeBatch{
+				schema:        testSchema,
+				table:         testTable,
+				keyColumns:    []string{`"id"`},
+				columns:       []string{`"id"`, `"name"`, `"bio"`},
+				types:         []string{"integer", "text", "text"},
+				updateColumns: []string{`"name"`, `"bio"`},
+				rows: [][]any{
+					{1, "a", "long bio", stagingUpsertOp},
+				},
+				sequences: map[string]int64{},
+			},
+			wantRemaining: []*wal.Data{
+				unchangedToastEvent(2, "b"),
+				newEvent("I", nil, []wal.Column{idCol(3), nameCol("c"), bioCol("other bio")}),
+			},
+		},
+		{
+			name: "changes after one without primary key",
+			events: []*wal.Data{
+				newEvent("I", nil, []wal.Column{idCol(1), nameCol("a")}),
+				unkeyedEvent,
+				newEvent("U", []wal.Column{idCol(1)}, []wal.Column{idCol(1), nameCol("b")}),
+			},
+			si: testSchemaInfo,
+
+			wantBatch: &mergeBatch{
+				schema:        testSchema,
+				table:         testTable,
+				keyColumns:    []string{`"id"`},
+				columns:       []string{`"id"`, `"name"`},
+				types:         []string{"integer", "text"},
+				updateColumns: []string{`"name"`},
+				rows: [][]any{
+					{1, "a", stagingUpsertOp},
+				},
+				sequences: map[string]int64{"test_schema.test_table_id_seq": 1},
+			},
+			wantRemaining: []*wal.Data{
+				unkeyedEvent,
+				newEvent("U", []wal.Column{idCol(1)}, []wal.Column{idCol(1), nameCol("b")}),
+			},
+		},
+		{
+			name: "inserts kept apart with a conflict policy",
+			events: []*wal.Data{
+				newEvent("I", nil, []wal.Column{idCol(1), nameCol("a")}),
+				newEvent("U", []wal.Column{idCol(1)}, []wal.Column{idCol(1), nameCol("b")}),
+				newEvent("U", []wal.Column{idCol(2)}, []wal.Column{idCol(2), nameCol("c")}),
+				newEvent("D", []wal.Column{idCol(3)}, nil),
+				newEvent("I", nil, []wal.Column{idCol(3), nameCol("d")}),
+			},
+			policies: conflictPolicies{
+				testSchema: {testTable: {action: onConflictDoNothing}},
+			},
+
+			wantBatch: &mergeBatch{
+				schema:        testSchema,
+				table:         testTable,
+				keyColumns:    []string{`"id"`},
+				columns:       []string{`"id"`, `"name"`},
+				types:         []string{"integer", "text"},
+				updateColumns: []string{`"name"`},
+				rows: [][]any{
+					{1, "b", stagingInsertOp},
+					{2, "c", stagingUpsertOp},
+					{3, "d", stagingUpsertOp},
+				},
+				sequences: map[string]int64{},
+				policy:    &conflictPolicy{action: onConflictDoNothing},
+			},
+		},
+		{
+			name:   "only changes without primary key",
+			events: []*wal.Data{unkeyedEvent},
+
+			wantRemaining: []*wal.Data{unkeyedEvent},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := &dmlAdapter{
+				logger:           log.NewNoopLogger(),
+				forCopy:          true,
+				pgTypeMap:        pgtype.NewMap(),
+				conflictPolicies: tc.policies,
+			}
+			mb, remaining := a.buildMergeBatch(tc.events, tc.si)
+			require.Equal(t, tc.wantBatch, mb)
+			require.Equal(t, tc.wantRemaining, remaining)
+		})
+	}
+}
+
+func TestMergeBatch_buildMergeQueries(t *testing.T) {
+	t.Parallel()
+
+	target := quotedTableName(testSchema, testTable)
+	staging := `"pgstream"."test_schema_test_table_staging"`
+	mb := &mergeBatch{
+		schema:        testSchema,
+		table:         testTable,
+		keyColumns:    []string{`"id"`},
+		columns:       []string{`"id"`, `"name"`},
+		updateColumns: []string{`"name"`},
+		sequences:     map[string]int64{"test_schema.test_table_id_seq": 4},
+	}
+	withInserts := func(policy *conflictPolicy) *mergeBatch {
+		return &mergeBatch{
+			schema:        testSchema,
+			table:         testTable,
+			keyColumns:    []string{`"id"`},
+			columns:       []string{`"id"`, `"name"`},
+			updateColumns: []string{`"name"`},
+			rows:          [][]any{{1, "a", stagingInsertOp}, {2, "b", stagingUpsertOp}},
+			policy:        policy,
+		}
+	}
+	insertQuery := func(onConflict string) *query {
+		return &query{
+			schema: testSchema,
+			table:  testTable,
+			sql: `INSERT INTO ` + target + `("id", "name") OVERRIDING SYSTEM VALUE SELECT s."id", s."name" FROM ` + staging +
+				` AS s WHERE s."_pgstream_op" = 'I'` + onConflict,
+		}
+	}
+	policyMergeQuery := &query{
+		schema: testSchema,
+		table:  testTable,
+		sql: `MERGE INTO ` + target + ` AS t USING ` + staging + ` AS s ON t."id" = s."id"` +
+			` WHEN MATCHED AND s."_pgstream_op" = 'D' THEN DELETE` +
+			` WHEN MATCHED AND s."_pgstream_op" = 'U' THEN UPDATE SET "name" = s."name"` +
+			` WHEN NOT MATCHED AND s."_pgstream_op" = 'U' THEN INSERT ("id", "name") OVERRIDING SYSTEM VALUE VALUES (s."id", s."name")`,
+	}
+	setvalQuery := &query{
+		schema: testSchema,
+		table:  testTable,
+		sql:    "SELECT setval($1::regclass, $2::bigint, true)",
+		args:   []any{"test_schema.test_table_id_seq", int64(4)},
+	}
+
+	tests := []struct {
+		name     string
+		batch    *mergeBatch
+		useMerge bool
+
+		wantQueries []*query
+	}{
+		{
+			name:     "merge",
+			batch:    mb,
+			useMerge: true,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql: `MERGE INTO ` + target + ` AS t USING ` + staging + ` AS s ON t."id" = s."id"` +
+						` WHEN MATCHED AND s."_pgstream_op" = 'D' THEN DELETE` +
+						` WHEN MATCHED THEN UPDATE SET "name" = s."name"` +
+						` WHEN NOT MATCHED AND s."_pgstream_op" <> 'D' THEN INSERT ("id", "name") OVERRIDING SYSTEM VALUE VALUES (s."id", s."name")`,
+				},
+				setvalQuery,
+			},
+		},
+		{
+			name: "merge - only primary key columns",
+			batch: &mergeBatch{
+				schema:     testSchema,
+				table:      testTable,
+				keyColumns: []string{`"id"`},
+				columns:    []string{`"id"`},
+			},
+			useMerge: true,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql: `MERGE INTO ` + target + ` AS t USING ` + staging + ` AS s ON t."id" = s."id"` +
+						` WHEN MATCHED AND s."_pgstream_op" = 'D' THEN DELETE` +
+						` WHEN NOT MATCHED AND s."_pgstream_op" <> 'D' THEN INSERT ("id") OVERRIDING SYSTEM VALUE VALUES (s."id")`,
+				},
+			},
+		},
+		{
+			name:     "upsert and delete",
+			batch:    mb,
+			useMerge: false,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    `DELETE FROM ` + target + ` AS t USING ` + staging + ` AS s WHERE t."id" = s."id" AND s."_pgstream_op" = 'D'`,
+				},
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql: `INSERT INTO ` + target + `("id", "name") OVERRIDING SYSTEM VALUE SELECT s."id", s."name" FROM ` + staging +
+						` AS s WHERE s."_pgstream_op" <> 'D' ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+				},
+				setvalQuery,
+			},
+		},
+		{
+			name:     "merge - inserts with conflict policy",
+			batch:    withInserts(&conflictPolicy{action: onConflictUpdate}),
+			useMerge: true,
+			wantQueries: []*query{
+				policyMergeQuery,
+				insertQuery(` ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`),
+			},
+		},
+		{
+			name: "upsert and delete - inserts with conflict policy",
+			batch: withInserts(&conflictPolicy{
+				action:        onConflictUpdate,
+				target:        `ON CONSTRAINT "unique_name"`,
+				updateColumns: []string{`"other"`},
+			}),
+			useMerge: false,
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    `DELETE FROM ` + target + ` AS t USING ` + staging + ` AS s WHERE t."id" = s."id" AND s."_pgstream_op" = 'D'`,
+				},
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql: `INSERT INTO ` + target + `("id", "name") OVERRIDING SYSTEM VALUE SELECT s."id", s."name" FROM ` + staging +
+						` AS s WHERE s."_pgstream_op" = 'U' ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+				},
+				insertQuery(` ON CONFLICT ON CONSTRAINT "unique_name" DO NOTHING`),
+			},
+		},
+		{
+			name:     "inserts with do nothing conflict policy",
+			batch:    withInserts(&conflictPolicy{action: onConflictDoNothing, target: `("name")`}),
+			useMerge: true,
+			wantQueries: []*query{
+				policyMergeQuery,
+				insertQuery(` ON CONFLICT ("name") DO NOTHING`),
+			},
+		},
+		{
+			name:     "inserts with error conflict policy",
+			batch:    withInserts(&conflictPolicy{action: onConflictError}),
+			useMerge: true,
+			wantQueries: []*query{
+				policyMergeQuery,
+				insertQuery(""),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.wantQueries, tc.batch.buildMergeQueries(staging, tc.useMerge))
+		})
+	}
+}
+
+func Test_stagingTableName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "public_users_staging", stagingTableName("public", "users"))
+
+	longName := stagingTableName("public", strings.Repeat("a", 63))
+	require.LessOrEqual(t, len(longName), maxIdentifierLength)
+	require.True(t, strings.HasPrefix(longName, "staging_"))
+	require.Equal(t, longName, stagingTableName("public", strings.Repeat("a", 63)))
+	require.NotEqual(t, longName, stagingTableName("public", strings.Repeat("b", 63)))
+}
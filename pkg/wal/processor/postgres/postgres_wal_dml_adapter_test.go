@@ -151,6 +151,28 @@ func TestDMLAdapter_walDataToQueries(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "delete - full identity with json column",
+			walData: &wal.Data{
+				Action: "D",
+				Schema: testSchema,
+				Table:  testTable,
+				Identity: []wal.Column{
+					{ID: columnID(1), Name: "id", Type: "integer", Value: 1},
+					{ID: columnID(2), Name: "doc", Type: "json", Value: `{"a":1}`},
+				},
+				Metadata: wal.Metadata{},
+			},
+
+			wantQueries: []*query{
+				{
+					schema: testSchema,
+					table:  testTable,
+					sql:    fmt.Sprintf("DELETE FROM %s WHERE \"id\" = $1 AND \"doc\"::jsonb = $2::jsonb", quotedTestTable),
+					args:   []any{1, `{"a":1}`},
+				},
+			},
+		},
 		{
 			name: "error - delete",
 			walData: &wal.Data{
@@ -948,7 +970,7 @@ func Test_newDMLAdapter(t *testing.T) {
 		t.Run(tc.action, func(t *testing.T) {
 			t.Parallel()
 
-			_, err := newDMLAdapter(tc.action, false, log.NewNoopLogger())
+			_, err := newDMLAdapter(tc.action, "", "", false, log.NewNoopLogger())
 			require.ErrorIs(t, err, tc.wantErr)
 		})
 	}
@@ -11,6 +11,9 @@ type walMessage struct {
 	data       *wal.Data
 	schemaInfo schemaInfo
 	isDDL      bool
+	// position of the event in the input stream, only set when the apply
+	// progress is tracked on the target
+	position wal.CommitPosition
 }
 
 // walMessageOverhead is the approximate size of the walMessage struct itself
@@ -10,17 +10,25 @@ import (
 	pglibretrier "github.com/xataio/pgstream/internal/postgres/retrier"
 	loglib "github.com/xataio/pgstream/pkg/log"
 	"github.com/xataio/pgstream/pkg/otel"
+	"github.com/xataio/pgstream/pkg/wal"
 	"github.com/xataio/pgstream/pkg/wal/checkpointer"
 	"github.com/xataio/pgstream/pkg/wal/processor/batch"
 )
 
 type Writer struct {
-	logger          loglib.Logger
-	pgConn          pglib.Querier
-	adapter         walAdapter
-	checkpointer    checkpointer.Checkpoint
-	writerType      string
-	disableTriggers bool
+	logger            loglib.Logger
+	pgConn            pglib.Querier
+	adapter           walAdapter
+	schemaObserver    schemaObserver
+	checkpointer      checkpointer.Checkpoint
+	writerType        string
+	disableTriggers   bool
+	replicationOrigin string
+	ddlErrors         *ddlErrorLog
+	sequenceSyncer    *sequenceSyncer
+	roleSyncer        *roleSyncer
+	// textCopy forces text format COPY for all the tables
+	textCopy bool
 }
 
 type queryBatchSender interface {
@@ -37,46 +45,107 @@ type WriterOption func(*Writer)
 
 func newWriter(ctx context.Context, config *Config, writerType string, opts ...WriterOption) (*Writer, error) {
 	w := &Writer{
-		logger:          loglib.NewNoopLogger(),
-		writerType:      writerType,
-		disableTriggers: config.DisableTriggers,
+		logger:            loglib.NewNoopLogger(),
+		writerType:        writerType,
+		disableTriggers:   config.DisableTriggers,
+		replicationOrigin: config.ReplicationOrigin,
 	}
 
 	for _, opt := range opts {
 		opt(w)
 	}
 
+	poolOpts := []pglib.PoolOption{}
+	if config.ReplicationOrigin != "" {
+		poolOpts = append(poolOpts, pglib.WithReplicationOrigin(config.ReplicationOrigin))
+	}
+
 	var err error
 	if config.RetryPolicy.DisableRetries {
-		w.pgConn, err = pglib.NewConnPool(ctx, config.URL)
+		w.pgConn, err = pglib.NewConnPool(ctx, config.URL, poolOpts...)
 	} else {
 		// unless retries are disabled, wrap the Postgres querier with a retrier
 		// and apply default retry policy if none is set
 		w.pgConn, err = pglibretrier.NewQuerier(ctx, config.retryPolicy(), func(ctx context.Context) (pglib.Querier, error) {
-			return pglib.NewConnPool(ctx, config.URL)
+			return pglib.NewConnPool(ctx, config.URL, poolOpts...)
 		}, w.logger)
 	}
 	if err != nil {
 		return nil, err
 	}
+	w.ddlErrors = newDDLErrorLog(w.pgConn)
 
 	forCopy := writerType == bulkIngestWriter
 
-	w.adapter, err = newAdapter(ctx, w.logger, config.IgnoreDDL, config.URL, config.OnConflictAction, forCopy)
+	tableRouter, err := pglib.NewTableRouter(pglib.TableRoutesFromMap(config.TableRoutes))
+	if err != nil {
+		return nil, err
+	}
+
+	adapter, err := newAdapter(ctx, w.logger, config.IgnoreDDL, config.ConcurrentDDL, config.URL, config.OnConflictAction, config.ApplyMode, forCopy, tableRouter)
 	if err != nil {
 		return nil, err
 	}
+	w.adapter = adapter
+	w.schemaObserver = adapter.schemaObserver
+
+	if config.SequenceSync.SourceURL != "" {
+		w.sequenceSyncer, err = newSequenceSyncer(ctx, &config.SequenceSync, w.pgConn, tableRouter, adapter.schemaObserver.getSequences, w.logger)
+		if err != nil {
+			return nil, err
+		}
+		w.sequenceSyncer.start(ctx)
+	}
+
+	if config.RoleSync.SourceURL != "" {
+		w.roleSyncer, err = newRoleSyncer(ctx, &config.RoleSync, w.pgConn, w.logger)
+		if err != nil {
+			return nil, err
+		}
+		w.roleSyncer.start(ctx)
+	}
 
 	return w, nil
 }
 
 func (w *Writer) close() error {
+	if w.sequenceSyncer != nil {
+		if err := w.sequenceSyncer.close(); err != nil {
+			w.logger.Error(err, "closing sequence syncer")
+		}
+	}
+	if w.roleSyncer != nil {
+		if err := w.roleSyncer.close(); err != nil {
+			w.logger.Error(err, "closing role syncer")
+		}
+	}
 	if err := w.adapter.close(); err != nil {
 		w.logger.Error(err, "closing adapter")
 	}
 	return w.pgConn.Close(context.Background())
 }
 
+// syncRoles makes sure the roles referenced by the DDL event on input exist on
+// the target before it's applied, when role sync is enabled.
+func (w *Writer) syncRoles(ctx context.Context, d *wal.Data) {
+	if w.roleSyncer == nil {
+		return
+	}
+	w.roleSyncer.syncForDDL(ctx, d)
+}
+
+// recordDDLError records the DDL query that failed to apply on the target.
+// Failures to record it are only logged, so that they don't hide the original
+// error.
+func (w *Writer) recordDDLError(ctx context.Context, q *query, ddlErr error) {
+	if w.ddlErrors == nil {
+		return
+	}
+	if err := w.ddlErrors.record(ctx, q, ddlErr); err != nil {
+		w.logger.Error(err, "recording DDL error", loglib.Fields{"query_sql": q.sql})
+	}
+}
+
 func WithLogger(l loglib.Logger) WriterOption {
 	return func(w *Writer) {
 		w.logger = loglib.NewLogger(l).WithFields(loglib.Fields{
@@ -97,6 +166,15 @@ func WithInstrumentation(i *otel.Instrumentation) WriterOption {
 	}
 }
 
+// WithTextCopy makes the bulk ingest writer use text format COPY for all the
+// tables. It's required when the column values are in their postgres text
+// representation, such as the rows loaded from a snapshot archive.
+func WithTextCopy() WriterOption {
+	return func(w *Writer) {
+		w.textCopy = true
+	}
+}
+
 func (w *Writer) setReplicationRoleToReplica(ctx context.Context, tx pglib.Tx) error {
 	if !w.disableTriggers {
 		return nil
@@ -119,3 +197,68 @@ func (w *Writer) resetReplicationRole(ctx context.Context, tx pglib.Tx) error {
 	}
 	return nil
 }
+
+// setReplicationOriginPosition sets the source LSN and commit timestamp of the
+// changes applied in the transaction, so that the commit timestamp recorded on
+// the target matches the source one. It's a no-op if there's no replication
+// origin configured.
+func (w *Writer) setReplicationOriginPosition(ctx context.Context, tx pglib.Tx, pos *originPosition) error {
+	if w.replicationOrigin == "" || pos == nil {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT pg_replication_origin_xact_setup($1::pg_lsn, $2)", pos.lsn, pos.timestamp); err != nil {
+		return fmt.Errorf("setting replication origin position: %w", err)
+	}
+	return nil
+}
+
+func (w *Writer) copyFromInsertQueries(ctx context.Context, inserts []*query) error {
+	if len(inserts) == 0 {
+		return nil
+	}
+
+	// Get the table and column names from the first insert query, since all the
+	// inserts in the batch will be for the same schema.table
+	query := inserts[0]
+	rows := [][]any{}
+	for _, q := range inserts {
+		rows = append(rows, q.args)
+	}
+
+	// Tables with extension types pgx has no binary codec for
+	// must round-trip through text-format COPY, otherwise
+	// the destination misreads the raw text bytes as the binary
+	// representation of the type.
+	// The dml adapter precomputes the needsTextCopy flag
+	// from the column type list when building the query.
+	copyFn := func(tx pglib.Tx) (int64, error) {
+		target := pglib.QuoteQualifiedIdentifier(query.schema, query.table)
+		if query.needsTextCopy || w.textCopy {
+			return tx.CopyFromText(ctx, target, query.columnNames, rows)
+		}
+		return tx.CopyFrom(ctx, target, query.columnNames, rows)
+	}
+
+	err := w.pgConn.ExecInTx(ctx, func(tx pglib.Tx) error {
+		if err := w.setReplicationRoleToReplica(ctx, tx); err != nil {
+			return err
+		}
+
+		rowsCopied, err := copyFn(tx)
+		if err != nil {
+			return err
+		}
+
+		rowsToCopy := len(inserts)
+		if rowsCopied != int64(rowsToCopy) {
+			return fmt.Errorf("%w: copied (%d), expected(%d)", errUnexpectedCopiedRows, rowsCopied, rowsToCopy)
+		}
+
+		return w.resetReplicationRole(ctx, tx)
+	})
+	if err != nil {
+		return fmt.Errorf("copy from: %w", err)
+	}
+	return nil
+}
@@ -142,6 +142,9 @@ func (a *adapter) walDataToDocument(data *wal.Data) (*Document, error) {
 		// update operations to avoid version conflicts
 		if data.IsUpdate() {
 			doc.Version += 1
+			// the unchanged TOAST columns are not part of the event, so only
+			// the fields in the event are updated to keep their values
+			doc.Partial = data.HasUnchangedToastColumns()
 		}
 	}
 
@@ -221,6 +221,23 @@ func TestAdapter_walDataToDocument(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name:   "ok - update event with unchanged toast columns",
+			mapper: noopMapper,
+			data: func() *wal.Data {
+				d := newTestDataEvent("U").Data
+				d.UnchangedToastColumns = []string{"toast"}
+				return d
+			}(),
+
+			wantDoc: func() *Document {
+				doc := newTestDocument()
+				doc.Version = testLSN + 1
+				doc.Partial = true
+				return doc
+			}(),
+			wantErr: nil,
+		},
 		{
 			name: "ok - insert event with identity columns and invalid type",
 			data: func() *wal.Data {
@@ -29,6 +29,9 @@ type Document struct {
 	Data    map[string]any
 	Version int
 	Delete  bool
+	// Partial documents only update the fields in Data, keeping the rest of
+	// the fields of the existing document.
+	Partial bool
 }
 
 type DocumentError struct {
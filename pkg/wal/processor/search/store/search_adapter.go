@@ -39,9 +39,15 @@ func (a *adapter) SearchDocToBulkItem(doc search.Document) searchstore.BulkItem
 		Version:     &doc.Version,
 		VersionType: "external",
 	}
-	if doc.Delete {
+	switch {
+	case doc.Delete:
 		item.Delete = bulkIndex
-	} else {
+	case doc.Partial:
+		// partial updates don't support external versioning
+		bulkIndex.Version = nil
+		bulkIndex.VersionType = ""
+		item.Update = bulkIndex
+	default:
 		item.Index = bulkIndex
 	}
 	return item
@@ -79,6 +85,10 @@ func (a *adapter) bulkItemToSearchDocErr(item searchstore.BulkItem) search.Docum
 			doc.Document.Version = *item.Delete.Version
 		}
 		doc.Document.Delete = true
+	case item.Update != nil:
+		doc.Document.Schema = a.indexNameAdapter.IndexToSchemaName(item.Update.Index)
+		doc.Document.ID = item.Update.ID
+		doc.Document.Partial = true
 	}
 
 	doc.Severity = a.parseSeverity(item.Status, doc.Document.Delete)
@@ -0,0 +1,245 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package toast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	synclib "github.com/xataio/pgstream/internal/sync"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor"
+)
+
+// Handler is a processor wrapper that detects the TOASTed columns omitted from
+// update events because their value didn't change. wal2json only includes
+// them when the table has REPLICA IDENTITY FULL, so targets would otherwise
+// overwrite them with nothing. The unchanged columns are either flagged in
+// the event, so that the targets can keep the existing values, or fetched from
+// the source for targets that need full rows.
+type Handler struct {
+	logger         loglib.Logger
+	processor      processor.Processor
+	conn           pglib.Querier
+	fetchUnchanged bool
+	// toastableColumns is a map of schema.table to the columns of the table
+	// that can be TOASTed
+	toastableColumns *synclib.Map[string, []column]
+}
+
+type Config struct {
+	// URL of the source Postgres database, used to retrieve the table columns
+	// and the unchanged values.
+	URL string
+	// FetchUnchanged retrieves the current value of the unchanged TOAST
+	// columns from the source and adds them to the event, instead of flagging
+	// them as unchanged.
+	FetchUnchanged bool
+}
+
+type Option func(*Handler)
+
+type column struct {
+	name     string
+	dataType string
+}
+
+var errMissingURL = errors.New("source postgres URL required to handle unchanged TOAST columns")
+
+// New will return a processor wrapper that handles the unchanged TOAST
+// columns of the update events before passing them on to the wrapped
+// processor.
+func New(ctx context.Context, processor processor.Processor, cfg *Config, opts ...Option) (*Handler, error) {
+	if cfg.URL == "" {
+		return nil, errMissingURL
+	}
+
+	conn, err := pglib.NewConnPool(ctx, cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &Handler{
+		logger:           loglib.NewNoopLogger(),
+		processor:        processor,
+		conn:             conn,
+		fetchUnchanged:   cfg.FetchUnchanged,
+		toastableColumns: synclib.NewMap[string, []column](),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h, nil
+}
+
+func WithLogger(logger loglib.Logger) Option {
+	return func(h *Handler) {
+		h.logger = loglib.NewLogger(logger).WithFields(loglib.Fields{
+			loglib.ModuleField: "wal_toast_handler",
+		})
+	}
+}
+
+func (h *Handler) ProcessWALEvent(ctx context.Context, event *wal.Event) error {
+	if event.Data != nil {
+		switch {
+		case event.Data.IsDDLEvent():
+			h.invalidateTables(event.Data)
+		case event.Data.IsUpdate():
+			if err := h.handleUpdate(ctx, event.Data); err != nil {
+				return err
+			}
+		}
+	}
+
+	return h.processor.ProcessWALEvent(ctx, event)
+}
+
+func (h *Handler) Name() string {
+	return h.processor.Name()
+}
+
+func (h *Handler) Close() error {
+	if err := h.conn.Close(context.Background()); err != nil {
+		h.logger.Error(err, "closing toast handler connection")
+	}
+	return h.processor.Close()
+}
+
+func (h *Handler) handleUpdate(ctx context.Context, d *wal.Data) error {
+	columns, err := h.getToastableColumns(ctx, d.Schema, d.Table)
+	if err != nil {
+		return err
+	}
+
+	unchanged := make([]column, 0, len(columns))
+	for _, col := range columns {
+		if !slices.ContainsFunc(d.Columns, func(c wal.Column) bool { return c.Name == col.name }) {
+			unchanged = append(unchanged, col)
+		}
+	}
+	if len(unchanged) == 0 {
+		return nil
+	}
+
+	if h.fetchUnchanged {
+		values, err := h.fetchValues(ctx, d, unchanged)
+		switch {
+		case err == nil:
+			for i, col := range unchanged {
+				d.Columns = append(d.Columns, wal.Column{Name: col.name, Type: col.dataType, Value: values[i]})
+			}
+			return nil
+		case errors.Is(err, errNoRowIdentity), errors.Is(err, pglib.ErrNoRows):
+			// the row can't be found on the source, flag the columns instead
+			h.logger.Warn(err, "unable to fetch unchanged TOAST values", loglib.Fields{"schema": d.Schema, "table": d.Table})
+		default:
+			return err
+		}
+	}
+
+	d.UnchangedToastColumns = make([]string, 0, len(unchanged))
+	for _, col := range unchanged {
+		d.UnchangedToastColumns = append(d.UnchangedToastColumns, col.name)
+	}
+	return nil
+}
+
+var errNoRowIdentity = errors.New("update event has no row identity")
+
+// fetchValues retrieves the current value of the columns on input for the row
+// updated by the event. The row is identified by the identity columns of the
+// event, using their new value if they've been updated.
+func (h *Handler) fetchValues(ctx context.Context, d *wal.Data, columns []column) ([]any, error) {
+	if len(d.Identity) == 0 {
+		return nil, errNoRowIdentity
+	}
+
+	conditions := make([]string, 0, len(d.Identity))
+	args := make([]any, 0, len(d.Identity))
+	for i, identityCol := range d.Identity {
+		value := identityCol.Value
+		if idx := slices.IndexFunc(d.Columns, func(c wal.Column) bool { return c.Name == identityCol.Name }); idx >= 0 {
+			value = d.Columns[idx].Value
+		}
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", pglib.QuoteIdentifier(identityCol.Name), i+1))
+		args = append(args, value)
+	}
+
+	names := make([]string, 0, len(columns))
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i, col := range columns {
+		names = append(names, pglib.QuoteIdentifier(col.name))
+		dest[i] = &values[i]
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(names, ", "),
+		pglib.QuoteQualifiedIdentifier(d.Schema, d.Table), strings.Join(conditions, " AND "))
+	if err := h.conn.QueryRow(ctx, dest, query, args...); err != nil {
+		return nil, fmt.Errorf("fetching unchanged TOAST values for table %s.%s: %w", d.Schema, d.Table, err)
+	}
+	return values, nil
+}
+
+// getToastableColumns returns the columns of the table on input that can be
+// TOASTed. If the value is not in the internal cache, it will query postgres.
+func (h *Handler) getToastableColumns(ctx context.Context, schema, table string) ([]column, error) {
+	key := pglib.QuoteQualifiedIdentifier(schema, table)
+	if columns, found := h.toastableColumns.Get(key); found {
+		return columns, nil
+	}
+
+	columns, err := h.queryToastableColumns(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	h.toastableColumns.Set(key, columns)
+	return columns, nil
+}
+
+// variable length columns that are not stored plain can be TOASTed. Generated
+// columns are excluded, since they're not part of the replicated rows.
+const toastableColumnsQuery = `SELECT a.attname, format_type(a.atttypid, a.atttypmod) FROM pg_attribute a
+	JOIN pg_type t ON t.oid = a.atttypid
+	WHERE a.attrelid = $1::regclass AND a.attnum > 0 AND NOT a.attisdropped
+	AND t.typlen = -1 AND a.attstorage <> 'p' AND a.attgenerated = ''
+	ORDER BY a.attnum`
+
+func (h *Handler) queryToastableColumns(ctx context.Context, qualifiedTable string) ([]column, error) {
+	rows, err := h.conn.Query(ctx, toastableColumnsQuery, qualifiedTable)
+	if err != nil {
+		return nil, fmt.Errorf("getting toastable columns for table %s: %w", qualifiedTable, err)
+	}
+	defer rows.Close()
+
+	columns := []column{}
+	for rows.Next() {
+		var col column
+		if err := rows.Scan(&col.name, &col.dataType); err != nil {
+			return nil, fmt.Errorf("scanning toastable column: %w", err)
+		}
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+// invalidateTables removes the tables affected by the DDL event from the
+// cache, so that their columns are retrieved again.
+func (h *Handler) invalidateTables(d *wal.Data) {
+	ddlEvent, err := wal.WalDataToDDLEvent(d)
+	if err != nil {
+		h.logger.Error(err, "failed to convert WAL data to DDL event")
+		return
+	}
+	for _, table := range append(ddlEvent.GetTableObjects(), ddlEvent.GetTableColumnObjects()...) {
+		h.toastableColumns.Delete(pglib.QuoteQualifiedIdentifier(table.Schema, table.GetName()))
+	}
+}
@@ -0,0 +1,223 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package toast
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pglib "github.com/xataio/pgstream/internal/postgres"
+	pgmocks "github.com/xataio/pgstream/internal/postgres/mocks"
+	synclib "github.com/xataio/pgstream/internal/sync"
+	loglib "github.com/xataio/pgstream/pkg/log"
+	"github.com/xataio/pgstream/pkg/wal"
+	"github.com/xataio/pgstream/pkg/wal/processor/mocks"
+)
+
+func TestHandler_ProcessWALEvent(t *testing.T) {
+	t.Parallel()
+
+	newUpdateEvent := func() *wal.Event {
+		return &wal.Event{
+			Data: &wal.Data{
+				Action: "U",
+				Schema: "public",
+				Table:  "users",
+				Columns: []wal.Column{
+					{Name: "id", Type: "integer", Value: 1},
+					{Name: "name", Type: "text", Value: "alice"},
+				},
+				Identity: []wal.Column{
+					{Name: "id", Type: "integer", Value: 1},
+				},
+			},
+		}
+	}
+
+	toastableColumnsRows := func() *pgmocks.Rows {
+		return &pgmocks.Rows{
+			CloseFn: func() {},
+			NextFn:  func(i uint) bool { return i <= 2 },
+			ScanFn: func(i uint, dest ...any) error {
+				name, dataType := "name", "text"
+				if i == 2 {
+					name, dataType = "bio", "text"
+				}
+				*(dest[0].(*string)) = name
+				*(dest[1].(*string)) = dataType
+				return nil
+			},
+			ErrFn: func() error { return nil },
+		}
+	}
+
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name           string
+		event          *wal.Event
+		fetchUnchanged bool
+		querier        *pgmocks.Querier
+
+		wantData *wal.Data
+		wantErr  error
+	}{
+		{
+			name:  "ok - insert event",
+			event: &wal.Event{Data: &wal.Data{Action: "I", Schema: "public", Table: "users"}},
+			querier: &pgmocks.Querier{
+				QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+					return nil, errors.New("QueryFn: should not be called")
+				},
+			},
+			wantData: &wal.Data{Action: "I", Schema: "public", Table: "users"},
+		},
+		{
+			name:  "ok - unchanged columns flagged",
+			event: newUpdateEvent(),
+			querier: &pgmocks.Querier{
+				QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+					require.Equal(t, toastableColumnsQuery, query)
+					require.Equal(t, []any{`"public"."users"`}, args)
+					return toastableColumnsRows(), nil
+				},
+			},
+			wantData: func() *wal.Data {
+				d := newUpdateEvent().Data
+				d.UnchangedToastColumns = []string{"bio"}
+				return d
+			}(),
+		},
+		{
+			name:           "ok - unchanged columns fetched",
+			event:          newUpdateEvent(),
+			fetchUnchanged: true,
+			querier: &pgmocks.Querier{
+				QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+					return toastableColumnsRows(), nil
+				},
+				QueryRowFn: func(ctx context.Context, dest []any, query string, args ...any) error {
+					require.Equal(t, `SELECT "bio" FROM "public"."users" WHERE "id" = $1`, query)
+					require.Equal(t, []any{1}, args)
+					require.Len(t, dest, 1)
+					*(dest[0].(*any)) = "a very long bio"
+					return nil
+				},
+			},
+			wantData: func() *wal.Data {
+				d := newUpdateEvent().Data
+				d.Columns = append(d.Columns, wal.Column{Name: "bio", Type: "text", Value: "a very long bio"})
+				return d
+			}(),
+		},
+		{
+			name:           "ok - row not found on source, unchanged columns flagged",
+			event:          newUpdateEvent(),
+			fetchUnchanged: true,
+			querier: &pgmocks.Querier{
+				QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+					return toastableColumnsRows(), nil
+				},
+				QueryRowFn: func(ctx context.Context, dest []any, query string, args ...any) error {
+					return pglib.ErrNoRows
+				},
+			},
+			wantData: func() *wal.Data {
+				d := newUpdateEvent().Data
+				d.UnchangedToastColumns = []string{"bio"}
+				return d
+			}(),
+		},
+		{
+			name:  "error - getting toastable columns",
+			event: newUpdateEvent(),
+			querier: &pgmocks.Querier{
+				QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+					return nil, errTest
+				},
+			},
+			wantErr: errTest,
+		},
+		{
+			name:           "error - fetching unchanged values",
+			event:          newUpdateEvent(),
+			fetchUnchanged: true,
+			querier: &pgmocks.Querier{
+				QueryFn: func(ctx context.Context, i uint, query string, args ...any) (pglib.Rows, error) {
+					return toastableColumnsRows(), nil
+				},
+				QueryRowFn: func(ctx context.Context, dest []any, query string, args ...any) error {
+					return errTest
+				},
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockProcessor := &mocks.Processor{
+				ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error {
+					require.Equal(t, tc.wantData, walEvent.Data)
+					return nil
+				},
+			}
+
+			h := &Handler{
+				logger:           loglib.NewNoopLogger(),
+				processor:        mockProcessor,
+				conn:             tc.querier,
+				fetchUnchanged:   tc.fetchUnchanged,
+				toastableColumns: synclib.NewMap[string, []column](),
+			}
+
+			err := h.ProcessWALEvent(context.Background(), tc.event)
+			require.ErrorIs(t, err, tc.wantErr)
+			if tc.wantErr == nil {
+				require.Equal(t, uint(1), mockProcessor.GetProcessCalls())
+			}
+		})
+	}
+}
+
+func TestHandler_invalidateTables(t *testing.T) {
+	t.Parallel()
+
+	content, err := json.Marshal(&wal.DDLEvent{
+		DDL:        "ALTER TABLE public.users ADD COLUMN bio text",
+		SchemaName: "public",
+		CommandTag: "ALTER TABLE",
+		Objects:    []wal.DDLObject{{Type: "table", Identity: "public.users", Schema: "public"}},
+	})
+	require.NoError(t, err)
+
+	h := &Handler{
+		logger: loglib.NewNoopLogger(),
+		processor: &mocks.Processor{
+			ProcessWALEventFn: func(ctx context.Context, walEvent *wal.Event) error { return nil },
+		},
+		toastableColumns: synclib.NewMapFromMap(map[string][]column{
+			`"public"."users"`:  {{name: "name", dataType: "text"}},
+			`"public"."orders"`: {{name: "notes", dataType: "text"}},
+		}),
+	}
+
+	err = h.ProcessWALEvent(context.Background(), &wal.Event{
+		Data: &wal.Data{
+			Action:  wal.LogicalMessageAction,
+			Prefix:  wal.DDLPrefix,
+			Content: string(content),
+		},
+	})
+	require.NoError(t, err)
+
+	_, found := h.toastableColumns.Get(`"public"."users"`)
+	require.False(t, found)
+	_, found = h.toastableColumns.Get(`"public"."orders"`)
+	require.True(t, found)
+}
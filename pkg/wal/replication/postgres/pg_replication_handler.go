@@ -7,6 +7,9 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"slices"
+	"strings"
+	"sync"
 
 	pglib "github.com/xataio/pgstream/internal/postgres"
 	loglib "github.com/xataio/pgstream/pkg/log"
@@ -29,6 +32,15 @@ type Handler struct {
 	lsnParser replication.LSNParser
 
 	pluginArguments []string
+	// filterOrigins are the names of the replication origins whose changes
+	// are skipped. wal2json filters by origin id, so the names are resolved
+	// every time the replication is started.
+	filterOrigins []string
+
+	// statusMutex serialises the status updates, which can be sent by the
+	// checkpointer and the keep alives concurrently
+	statusMutex   sync.Mutex
+	lastSyncedLSN replication.LSN
 }
 
 type Config struct {
@@ -47,9 +59,18 @@ type Config struct {
 }
 
 type PluginArguments struct {
-	IncludeXIDs  bool
-	AddTables    string // wal2json add-tables option (e.g., "public.*")
-	FilterTables string // wal2json filter-tables option (e.g., "pipelines.*,private.*")
+	IncludeXIDs bool
+	// IncludeTransaction includes the transaction boundaries in the stream,
+	// which carry the commit LSN of the transaction changes. The boundaries
+	// are consumed by the listener and not processed.
+	IncludeTransaction bool
+	AddTables          string // wal2json add-tables option (e.g., "public.*")
+	FilterTables       string // wal2json filter-tables option (e.g., "pipelines.*,private.*")
+	// Comma separated list of replication origin names whose changes are
+	// skipped (e.g., the origin of the pgstream pipeline replicating in the
+	// opposite direction). They are resolved to the origin ids expected by
+	// the wal2json filter-origins option, and created if they don't exist.
+	FilterOrigins string
 }
 
 type Option func(h *Handler)
@@ -66,9 +87,14 @@ var defaultPluginArguments = []string{
 	`"format-version" '2'`,
 	`"write-in-chunks" '1'`,
 	`"include-lsn" '1'`,
-	`"include-transaction" '0'`,
+	excludeTransactionArgument,
 }
 
+const (
+	excludeTransactionArgument = `"include-transaction" '0'`
+	includeTransactionArgument = `"include-transaction" '1'`
+)
+
 // NewHandler returns a new postgres replication handler for the database on input.
 func NewHandler(ctx context.Context, cfg Config, opts ...Option) (*Handler, error) {
 	pgReplicationConn, err := pglib.NewReplicationConn(ctx, cfg.PostgresURL)
@@ -109,6 +135,14 @@ func NewHandler(ctx context.Context, cfg Config, opts ...Option) (*Handler, erro
 		pluginArguments: defaultPluginArguments,
 	}
 
+	if cfg.PluginArguments.IncludeTransaction {
+		h.pluginArguments = slices.Clone(defaultPluginArguments)
+		for i, arg := range h.pluginArguments {
+			if arg == excludeTransactionArgument {
+				h.pluginArguments[i] = includeTransactionArgument
+			}
+		}
+	}
 	if cfg.PluginArguments.IncludeXIDs {
 		h.pluginArguments = append(h.pluginArguments, `"include-xids" '1'`)
 	}
@@ -118,6 +152,11 @@ func NewHandler(ctx context.Context, cfg Config, opts ...Option) (*Handler, erro
 	if cfg.PluginArguments.FilterTables != "" {
 		h.pluginArguments = append(h.pluginArguments, fmt.Sprintf(`"filter-tables" '%s'`, cfg.PluginArguments.FilterTables))
 	}
+	for _, origin := range strings.Split(cfg.PluginArguments.FilterOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			h.filterOrigins = append(h.filterOrigins, origin)
+		}
+	}
 
 	if len(cfg.IncludeTables) > 0 {
 		h.includedTables, err = pglib.NewSchemaTableMap(cfg.IncludeTables)
@@ -189,11 +228,20 @@ func (h *Handler) StartReplicationFromLSN(ctx context.Context, lsn replication.L
 	h.logFields[logLSNPosition] = h.lsnParser.ToString(lsn)
 	h.logger.Trace("set start LSN", h.logFields)
 
+	pluginArguments := h.pluginArguments
+	if len(h.filterOrigins) > 0 {
+		originIDs, err := h.getFilterOriginIDs(ctx)
+		if err != nil {
+			return fmt.Errorf("resolving filter origins: %w", err)
+		}
+		pluginArguments = append(slices.Clone(h.pluginArguments), fmt.Sprintf(`"filter-origins" '%s'`, originIDs))
+	}
+
 	err := h.pgReplicationConn.StartReplication(
 		ctx, pglib.ReplicationConfig{
 			SlotName:        h.pgReplicationSlotName,
 			StartPos:        uint64(lsn),
-			PluginArguments: h.pluginArguments,
+			PluginArguments: pluginArguments,
 		})
 	if err != nil {
 		return fmt.Errorf("startReplication: %w", err)
@@ -228,16 +276,34 @@ func (h *Handler) ReceiveMessage(ctx context.Context) (*replication.Message, err
 
 // SyncLSN notifies Postgres how far we have processed in the WAL.
 func (h *Handler) SyncLSN(ctx context.Context, lsn replication.LSN) error {
+	h.statusMutex.Lock()
+	defer h.statusMutex.Unlock()
+
 	err := h.pgReplicationConn.SendStandbyStatusUpdate(ctx, uint64(lsn))
 	if err != nil {
 		return fmt.Errorf("syncLSN: send status update: %w", err)
 	}
+	h.lastSyncedLSN = lsn
 	h.logger.Trace("stored new LSN position", loglib.Fields{
 		logLSNPosition: h.lsnParser.ToString(lsn),
 	})
 	return nil
 }
 
+// KeepAlive sends a status update with the last synced LSN, so that Postgres
+// doesn't terminate the replication connection after the wal_sender_timeout
+// while the messages are not being received, for example while the processing
+// is paused.
+func (h *Handler) KeepAlive(ctx context.Context) error {
+	h.statusMutex.Lock()
+	defer h.statusMutex.Unlock()
+
+	if err := h.pgReplicationConn.SendStandbyStatusUpdate(ctx, uint64(h.lastSyncedLSN)); err != nil {
+		return fmt.Errorf("keep alive: send status update: %w", err)
+	}
+	return nil
+}
+
 // GetReplicationLag will return the consumer current replication lag. This
 // value is different from the postgres replication lag, which takes into
 // consideration all consumers and ongoing transactions.
@@ -329,6 +395,33 @@ func (h *Handler) getLastSyncedLSN(ctx context.Context, conn pglib.Querier) (rep
 	return h.lsnParser.FromString(confirmedFlushLSN)
 }
 
+const (
+	createFilterOriginsQuery = `SELECT pg_replication_origin_create(o.name) FROM unnest($1::text[]) AS o(name) WHERE NOT EXISTS (SELECT 1 FROM pg_replication_origin WHERE roname = o.name)`
+	filterOriginIDsQuery     = `SELECT string_agg(roident::text, ',' ORDER BY roident) FROM pg_replication_origin WHERE roname = ANY($1::text[])`
+)
+
+// getFilterOriginIDs returns the comma separated ids of the filtered
+// replication origins, as expected by the wal2json filter-origins option. The
+// origins that don't exist yet are created, so that the pipeline writing with
+// them picks up the same ids once it starts.
+func (h *Handler) getFilterOriginIDs(ctx context.Context) (string, error) {
+	conn, err := h.pgConnBuilder()
+	if err != nil {
+		return "", fmt.Errorf("creating pg connection: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, createFilterOriginsQuery, h.filterOrigins); err != nil {
+		return "", fmt.Errorf("creating replication origins: %w", err)
+	}
+
+	var originIDs string
+	if err := conn.QueryRow(ctx, []any{&originIDs}, filterOriginIDsQuery, h.filterOrigins); err != nil {
+		return "", fmt.Errorf("retrieving replication origin ids: %w", err)
+	}
+	return originIDs, nil
+}
+
 func (h *Handler) verifyReplicationSlotExists(ctx context.Context) error {
 	slotExists := false
 	conn, err := h.pgConnBuilder()
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -23,6 +24,7 @@ func TestHandler_StartReplication(t *testing.T) {
 		replicationConn pglib.ReplicationQuerier
 		connBuilder     func() (pglib.Querier, error)
 		slotName        string
+		filterOrigins   []string
 
 		wantErr error
 	}{
@@ -237,6 +239,84 @@ func TestHandler_StartReplication(t *testing.T) {
 
 			wantErr: errTest,
 		},
+		{
+			name: "ok - with filter origins",
+			replicationConn: &pgmocks.ReplicationConn{
+				StartReplicationFn: func(ctx context.Context, cfg pglib.ReplicationConfig) error {
+					require.Equal(t, testLSN, cfg.StartPos)
+					require.Equal(t, testSlot, cfg.SlotName)
+					require.Equal(t, append(slices.Clone(defaultPluginArguments), `"filter-origins" '1,3'`), cfg.PluginArguments)
+					return nil
+				},
+				SendStandbyStatusUpdateFn: func(ctx context.Context, lsn uint64) error {
+					require.Equal(t, testLSN, lsn)
+					return nil
+				},
+			},
+			connBuilder: func() (pglib.Querier, error) {
+				return &pgmocks.Querier{
+					ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+						require.Equal(t, createFilterOriginsQuery, query)
+						require.Equal(t, []any{[]string{"origin_a", "origin_b"}}, args)
+						return pglib.CommandTag{}, nil
+					},
+					QueryRowFn: func(ctx context.Context, dest []any, query string, args ...any) error {
+						require.Len(t, dest, 1)
+						switch query {
+						case "select confirmed_flush_lsn from pg_replication_slots where slot_name=$1":
+							lsn, ok := dest[0].(*string)
+							require.True(t, ok)
+							*lsn = testLSNStr
+							return nil
+						case filterOriginIDsQuery:
+							require.Equal(t, []any{[]string{"origin_a", "origin_b"}}, args)
+							ids, ok := dest[0].(*string)
+							require.True(t, ok)
+							*ids = "1,3"
+							return nil
+						default:
+							return fmt.Errorf("unexpected query: %s", query)
+						}
+					},
+					CloseFn: func(ctx context.Context) error { return nil },
+				}, nil
+			},
+			slotName:      testSlot,
+			filterOrigins: []string{"origin_a", "origin_b"},
+
+			wantErr: nil,
+		},
+		{
+			name: "error - creating filter origins",
+			replicationConn: &pgmocks.ReplicationConn{
+				StartReplicationFn: func(ctx context.Context, cfg pglib.ReplicationConfig) error {
+					return errors.New("StartReplicationFn: should not be called")
+				},
+			},
+			connBuilder: func() (pglib.Querier, error) {
+				return &pgmocks.Querier{
+					ExecFn: func(ctx context.Context, _ uint, query string, args ...any) (pglib.CommandTag, error) {
+						return pglib.CommandTag{}, errTest
+					},
+					QueryRowFn: func(ctx context.Context, dest []any, query string, args ...any) error {
+						switch query {
+						case "select confirmed_flush_lsn from pg_replication_slots where slot_name=$1":
+							lsn, ok := dest[0].(*string)
+							require.True(t, ok)
+							*lsn = testLSNStr
+							return nil
+						default:
+							return fmt.Errorf("unexpected query: %s", query)
+						}
+					},
+					CloseFn: func(ctx context.Context) error { return nil },
+				}, nil
+			},
+			slotName:      testSlot,
+			filterOrigins: []string{"origin_a"},
+
+			wantErr: errTest,
+		},
 		{
 			name: "error - syncing LSN",
 			replicationConn: &pgmocks.ReplicationConn{
@@ -293,6 +373,7 @@ func TestHandler_StartReplication(t *testing.T) {
 				lsnParser:             NewLSNParser(),
 				logFields:             log.Fields{},
 				pluginArguments:       defaultPluginArguments,
+				filterOrigins:         tc.filterOrigins,
 			}
 
 			err := h.StartReplication(context.Background())
@@ -408,6 +489,68 @@ func TestHandler_ReceiveMessage(t *testing.T) {
 	}
 }
 
+func TestHandler_KeepAlive(t *testing.T) {
+	t.Parallel()
+
+	errTest := errors.New("oh noes")
+
+	tests := []struct {
+		name      string
+		synced    []uint64
+		statusErr error
+
+		wantLSN uint64
+		wantErr error
+	}{
+		{
+			name:   "ok - last synced LSN sent",
+			synced: []uint64{testLSN, testLSN + 10},
+
+			wantLSN: testLSN + 10,
+		},
+		{
+			name: "ok - no LSN synced yet",
+
+			wantLSN: 0,
+		},
+		{
+			name:      "error - sending status update",
+			statusErr: errTest,
+
+			wantErr: errTest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var sentLSN uint64
+			h := Handler{
+				logger:    log.NewNoopLogger(),
+				lsnParser: NewLSNParser(),
+				pgReplicationConn: &pgmocks.ReplicationConn{
+					SendStandbyStatusUpdateFn: func(ctx context.Context, lsn uint64) error {
+						sentLSN = lsn
+						return nil
+					},
+				},
+			}
+			for _, lsn := range tc.synced {
+				require.NoError(t, h.SyncLSN(context.Background(), replication.LSN(lsn)))
+			}
+
+			h.pgReplicationConn.(*pgmocks.ReplicationConn).SendStandbyStatusUpdateFn = func(ctx context.Context, lsn uint64) error {
+				sentLSN = lsn
+				return tc.statusErr
+			}
+			err := h.KeepAlive(context.Background())
+			require.ErrorIs(t, err, tc.wantErr)
+			require.Equal(t, tc.wantLSN, sentLSN)
+		})
+	}
+}
+
 func TestHandler_GetReplicationLag(t *testing.T) {
 	t.Parallel()
 
@@ -27,7 +27,16 @@ type Data struct {
 	Table     string   `json:"table"`
 	Columns   []Column `json:"columns"`
 	Identity  []Column `json:"identity"`
-	XID       int64    `json:"xid,omitempty"`
+	// UnchangedToastColumns are the names of the TOASTed columns omitted from
+	// an update event because their value didn't change. They're not part of
+	// the event columns, and must be kept as they are on the target.
+	UnchangedToastColumns []string `json:"unchanged_toast_columns,omitempty"`
+	XID                   int64    `json:"xid,omitempty"`
+	// CommitLSN is the end LSN of the commit record of the transaction the
+	// change belongs to. Unlike the change LSN, it follows the order in which
+	// the transactions are streamed. Only set when the transaction boundaries
+	// are included in the replication stream.
+	CommitLSN string `json:"commit_lsn,omitempty"`
 	// For logical messages (when Action == "M")
 	Prefix  string `json:"prefix,omitempty"`
 	Content string `json:"content,omitempty"`
@@ -80,6 +89,12 @@ func (d *Data) IsInsert() bool {
 	return d.Action == "I"
 }
 
+// HasUnchangedToastColumns returns true if the event omits the value of
+// unchanged TOASTed columns.
+func (d *Data) HasUnchangedToastColumns() bool {
+	return len(d.UnchangedToastColumns) > 0
+}
+
 // IsEmpty returns true if the pgstream metadata hasn't been populated, false
 // otherwise.
 func (m Metadata) IsEmpty() bool {
@@ -16,6 +16,11 @@ type DDLEvent struct {
 	SchemaName string      `json:"schema_name"`
 	CommandTag string      `json:"command_tag"`
 	Objects    []DDLObject `json:"objects"`
+	// Merged is set when the affected tables are merged from multiple source
+	// tables into a single target table. The DDL statement can't be replayed
+	// as is in that case, so the target needs to reconcile the objects
+	// instead.
+	Merged bool `json:"merged,omitempty"`
 }
 
 // DDLObject represents an object affected by a DDL command